@@ -0,0 +1,141 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/corp"
+	"github.com/ory/kratos/x"
+)
+
+// Scope is a permission which can be granted to an admin API key.
+type Scope string
+
+const (
+	ScopeIdentitiesRead      Scope = "identities:read"
+	ScopeIdentitiesWrite     Scope = "identities:write"
+	ScopeSessionsRead        Scope = "sessions:read"
+	ScopeSessionsWrite       Scope = "sessions:write"
+	ScopeSessionsImpersonate Scope = "sessions:impersonate"
+	ScopeRecoveryWrite       Scope = "recovery:write"
+	ScopeFlowsRead           Scope = "flows:read"
+	ScopeSchemasRead         Scope = "schemas:read"
+	ScopeMetricsRead         Scope = "metrics:read"
+	ScopeAPIKeysRead         Scope = "api_keys:read"
+	ScopeAPIKeysWrite        Scope = "api_keys:write"
+)
+
+type contextKey int
+
+const callerContextKey contextKey = iota + 1
+
+// Scopes contains all scopes which can be granted to an admin API key.
+var Scopes = []Scope{
+	ScopeIdentitiesRead,
+	ScopeIdentitiesWrite,
+	ScopeSessionsRead,
+	ScopeSessionsWrite,
+	ScopeSessionsImpersonate,
+	ScopeRecoveryWrite,
+	ScopeFlowsRead,
+	ScopeSchemasRead,
+	ScopeMetricsRead,
+	ScopeAPIKeysRead,
+	ScopeAPIKeysWrite,
+}
+
+// An admin API key
+//
+// swagger:model adminAPIKey
+type APIKey struct {
+	// ID represents the API key's unique ID.
+	//
+	// required: true
+	// type: string
+	// format: uuid
+	ID uuid.UUID `json:"id" db:"id" faker:"-"`
+
+	// Name is a human-readable name of the API key, e.g. "Support Team".
+	//
+	// required: true
+	Name string `json:"name" db:"name"`
+
+	// Key is the API key. It is only returned when the API key is created and is stored hashed.
+	Key string `json:"key,omitempty" db:"key_hash"`
+
+	// Scopes are the permissions granted to the API key.
+	//
+	// required: true
+	Scopes sqlxx.StringSlicePipeDelimiter `json:"scopes" db:"scopes"`
+
+	// ExpiresAt is the time (UTC) when the API key expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" faker:"time_type" db:"expires_at"`
+
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+}
+
+func (APIKey) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "admin_api_keys")
+}
+
+func NewAPIKey(name string, scopes []Scope, lifespan time.Duration) *APIKey {
+	s := make(sqlxx.StringSlicePipeDelimiter, len(scopes))
+	for k, scope := range scopes {
+		s[k] = string(scope)
+	}
+
+	return &APIKey{
+		ID:        x.NewUUID(),
+		Name:      name,
+		Key:       randx.MustString(48, randx.AlphaNum),
+		Scopes:    s,
+		ExpiresAt: time.Now().UTC().Add(lifespan).Truncate(time.Second),
+	}
+}
+
+// IsExpired returns true if the API key has expired.
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt.Before(time.Now())
+}
+
+// HasScope returns true if the API key was granted the scope.
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if Scope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidScope returns true if the scope is known.
+func IsValidScope(scope Scope) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCaller returns a context which carries the API key that authenticated the request.
+func WithCaller(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, callerContextKey, key)
+}
+
+// CallerFromContext returns the API key that authenticated the request. It returns nil if the request was
+// authenticated using the root API key or if admin API keys are disabled.
+func CallerFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(callerContextKey).(*APIKey)
+	return key
+}
@@ -0,0 +1,213 @@
+package apikey
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const RouteBase = "/api-keys"
+
+type (
+	handlerDependencies interface {
+		PersistenceProvider
+		x.WriterProvider
+		config.Providers
+	}
+	HandlerProvider interface {
+		APIKeyHandler() *Handler
+	}
+	Handler struct {
+		r handlerDependencies
+	}
+)
+
+func NewHandler(r handlerDependencies) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteBase, h.list)
+	admin.POST(RouteBase, h.create)
+	admin.DELETE(RouteBase+"/:id", h.revoke)
+}
+
+// A list of admin API keys.
+// swagger:model adminAPIKeyList
+// nolint:deadcode,unused
+type adminAPIKeyList []APIKey
+
+// swagger:route GET /api-keys admin listAdminAPIKeys
+//
+// List Admin API Keys
+//
+// Lists all admin API keys. The keys themselves are never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: adminAPIKeyList
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	keys, err := h.r.APIKeyPersister().ListAPIKeys(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, keys)
+}
+
+// swagger:parameters createAdminAPIKey
+// nolint:deadcode,unused
+type createAdminAPIKeyParameters struct {
+	// in: body
+	Body CreateAPIKey
+}
+
+type CreateAPIKey struct {
+	// Name is a human-readable name of the API key, e.g. "Support Team".
+	//
+	// required: true
+	Name string `json:"name"`
+
+	// Scopes are the permissions granted to the API key.
+	//
+	// required: true
+	Scopes []Scope `json:"scopes"`
+
+	// ExpiresIn defines how long the API key is valid, e.g. "720h". Defaults to
+	// `serve.admin.api_keys.default_lifespan`.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// swagger:route POST /api-keys admin createAdminAPIKey
+//
+// Create an Admin API Key
+//
+// This endpoint creates an admin API key with limited permissions. The key is only returned in
+// the response of this request and is stored hashed. It must be sent in the `Authorization: Bearer <key>`
+// header when calling the admin API.
+//
+// An admin API key can only grant scopes which it has been granted itself.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: adminAPIKey
+//       400: genericError
+//       403: genericError
+//       500: genericError
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var cr CreateAPIKey
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&cr); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if cr.Name == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The API key name must not be empty.")))
+		return
+	}
+
+	if len(cr.Scopes) == 0 {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("At least one scope must be granted to the API key.")))
+		return
+	}
+
+	for _, scope := range cr.Scopes {
+		if !IsValidScope(scope) {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Scope "%s" is unknown.`, scope).WithDetail("valid_scopes", Scopes)))
+			return
+		}
+	}
+
+	if caller := CallerFromContext(r.Context()); caller != nil {
+		for _, scope := range cr.Scopes {
+			if !caller.HasScope(scope) {
+				h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReasonf(`The admin API key can not grant the scope "%s" because it has not been granted that scope itself.`, scope)))
+				return
+			}
+		}
+	}
+
+	lifespan := h.r.Configuration(r.Context()).AdminAPIKeysDefaultLifespan()
+	if cr.ExpiresIn != "" {
+		var err error
+		lifespan, err = time.ParseDuration(cr.ExpiresIn)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse expires_in: %s", err)))
+			return
+		} else if lifespan <= 0 {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The value of expires_in must be positive.")))
+			return
+		}
+	}
+
+	key := NewAPIKey(cr.Name, cr.Scopes, lifespan)
+	if err := h.r.APIKeyPersister().CreateAPIKey(r.Context(), key); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(
+			h.r.Configuration(r.Context()).SelfAdminURL(),
+			RouteBase,
+			key.ID.String(),
+		).String(),
+		key,
+	)
+}
+
+// swagger:parameters revokeAdminAPIKey
+// nolint:deadcode,unused
+type revokeAdminAPIKeyParameters struct {
+	// ID is the API key's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /api-keys/{id} admin revokeAdminAPIKey
+//
+// Revoke an Admin API Key
+//
+// Calling this endpoint irrecoverably revokes the admin API key. This action can not be undone.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.r.APIKeyPersister().RevokeAPIKey(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
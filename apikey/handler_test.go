@@ -0,0 +1,61 @@
+package apikey_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	rootKey := "a-very-secret-root-key-which-is-long-enough"
+	conf.MustSet(config.ViperKeyAdminAPIKeysEnabled, true)
+	conf.MustSet(config.ViperKeyAdminAPIKeysRootKey, rootKey)
+
+	router := x.NewRouterAdmin()
+	reg.APIKeyHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.APIKeyMiddleware().ServeHTTP(w, r, router.ServeHTTP)
+	}))
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyAdminBaseURL, ts.URL)
+
+	create := func(t *testing.T, key, body string) int {
+		req, err := http.NewRequest("POST", ts.URL+apikey.RouteBase, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	manager := apikey.NewAPIKey("manager", []apikey.Scope{apikey.ScopeAPIKeysWrite, apikey.ScopeIdentitiesRead}, time.Hour)
+	require.NoError(t, reg.APIKeyPersister().CreateAPIKey(context.Background(), manager))
+
+	t.Run("case=root key can grant any scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, create(t, rootKey, `{"name":"admin","scopes":["identities:write","api_keys:write"]}`))
+	})
+
+	t.Run("case=scoped key can grant its own scopes", func(t *testing.T) {
+		assert.Equal(t, http.StatusCreated, create(t, manager.Key, `{"name":"support","scopes":["identities:read"]}`))
+	})
+
+	t.Run("case=scoped key can not grant scopes it does not hold", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, create(t, manager.Key, `{"name":"escalated","scopes":["identities:read","identities:write"]}`))
+		assert.Equal(t, http.StatusForbidden, create(t, manager.Key, `{"name":"escalated","scopes":["sessions:impersonate"]}`))
+	})
+}
@@ -0,0 +1,148 @@
+package apikey
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	middlewareDependencies interface {
+		PersistenceProvider
+		PolicyProvider
+		x.WriterProvider
+		config.Providers
+	}
+	MiddlewareProvider interface {
+		APIKeyMiddleware() *Middleware
+	}
+	Middleware struct {
+		r middlewareDependencies
+	}
+
+	scopeRule struct {
+		prefix string
+		read   Scope
+		write  Scope
+	}
+)
+
+var unprotectedPaths = []string{
+	healthx.AliveCheckPath,
+	healthx.ReadyCheckPath,
+	healthx.VersionPath,
+}
+
+var scopeRules = []scopeRule{
+	{prefix: "/identities", read: ScopeIdentitiesRead, write: ScopeIdentitiesWrite},
+	{prefix: "/sessions/impersonate", write: ScopeSessionsImpersonate},
+	{prefix: "/sessions", read: ScopeSessionsRead, write: ScopeSessionsWrite},
+	{prefix: "/recovery", write: ScopeRecoveryWrite},
+	{prefix: "/self-service", read: ScopeFlowsRead},
+	{prefix: "/schemas", read: ScopeSchemasRead},
+	{prefix: "/metrics", read: ScopeMetricsRead},
+	{prefix: RouteBase, read: ScopeAPIKeysRead, write: ScopeAPIKeysWrite},
+}
+
+func NewMiddleware(r middlewareDependencies) *Middleware {
+	return &Middleware{r: r}
+}
+
+// RequiredScope returns the scope required to perform the request. If false is returned, the request
+// can only be performed using the root API key.
+func RequiredScope(method, path string) (Scope, bool) {
+	for _, rule := range scopeRules {
+		if path != rule.prefix && !strings.HasPrefix(path, rule.prefix+"/") {
+			continue
+		}
+
+		scope := rule.write
+		if method == http.MethodGet || method == http.MethodHead {
+			scope = rule.read
+		}
+		return scope, scope != ""
+	}
+	return "", false
+}
+
+// ServeHTTP checks that the request carries a valid admin API key which has been granted the scope
+// required by the request. It is a no-op if admin API keys are disabled.
+func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	c := m.r.Configuration(r.Context())
+	if !c.AdminAPIKeysEnabled() {
+		next(w, r)
+		return
+	}
+
+	for _, p := range unprotectedPaths {
+		if r.URL.Path == p {
+			next(w, r)
+			return
+		}
+	}
+
+	token, ok := bearerTokenFromRequest(r)
+	if !ok {
+		m.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReason("The request is missing an admin API key in the Authorization header.")))
+		return
+	}
+
+	if root := c.AdminAPIKeysRootKey(); len(root) > 0 && subtle.ConstantTimeCompare([]byte(root), []byte(token)) == 1 {
+		next(w, r.WithContext(x.WithAuditActor(r.Context(), "root")))
+		return
+	}
+
+	key, err := m.r.APIKeyPersister().GetAPIKeyByKey(r.Context(), token)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		m.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReason("The admin API key is invalid or has been revoked.")))
+		return
+	} else if err != nil {
+		m.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if key.IsExpired() {
+		m.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReasonf("The admin API key expired at %s.", key.ExpiresAt)))
+		return
+	}
+
+	scope, ok := RequiredScope(r.Method, r.URL.Path)
+	if !ok {
+		m.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed using the root admin API key.")))
+		return
+	} else if !key.HasScope(scope) {
+		m.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReasonf(`The admin API key has not been granted the scope "%s" which is required to access this endpoint.`, scope)))
+		return
+	}
+
+	var partition string
+	if p := c.IdentityPartitions(); p.Enabled {
+		partition = r.Header.Get(p.AdminHeader)
+	}
+
+	if err := m.r.APIKeyPolicy().Authorize(r.Context(), NewPolicyInput(r, key, scope, partition)); err != nil {
+		m.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	next(w, r.WithContext(WithCaller(x.WithAuditActor(r.Context(), key.ID.String()), key)))
+}
+
+func bearerTokenFromRequest(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+
+	if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+		return parts[1], true
+	}
+
+	return "", false
+}
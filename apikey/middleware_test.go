@@ -0,0 +1,164 @@
+package apikey_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+)
+
+func TestRequiredScope(t *testing.T) {
+	for _, tc := range []struct {
+		method   string
+		path     string
+		expected apikey.Scope
+		ok       bool
+	}{
+		{method: "GET", path: "/identities", expected: apikey.ScopeIdentitiesRead, ok: true},
+		{method: "GET", path: "/identities/1234", expected: apikey.ScopeIdentitiesRead, ok: true},
+		{method: "PUT", path: "/identities/1234", expected: apikey.ScopeIdentitiesWrite, ok: true},
+		{method: "DELETE", path: "/sessions/1234", expected: apikey.ScopeSessionsWrite, ok: true},
+		{method: "POST", path: "/sessions/impersonate", expected: apikey.ScopeSessionsImpersonate, ok: true},
+		{method: "POST", path: "/recovery/link", expected: apikey.ScopeRecoveryWrite, ok: true},
+		{method: "GET", path: "/self-service/login/flows", expected: apikey.ScopeFlowsRead, ok: true},
+		{method: "POST", path: "/api-keys", expected: apikey.ScopeAPIKeysWrite, ok: true},
+		{method: "GET", path: "/recovery/link"},
+		{method: "GET", path: "/identitiesfoo"},
+		{method: "GET", path: "/unknown"},
+	} {
+		t.Run("case="+tc.method+" "+tc.path, func(t *testing.T) {
+			scope, ok := apikey.RequiredScope(tc.method, tc.path)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, scope)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	rootKey := "a-very-secret-root-key-which-is-long-enough"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.APIKeyMiddleware().ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}))
+	defer ts.Close()
+
+	var doWithHeader = func(t *testing.T, method, path, key string, header http.Header) int {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		require.NoError(t, err)
+		for k := range header {
+			req.Header.Set(k, header.Get(k))
+		}
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode
+	}
+
+	var do = func(t *testing.T, method, path, key string) int {
+		return doWithHeader(t, method, path, key, nil)
+	}
+
+	t.Run("case=passes through if disabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, "GET", "/identities", ""))
+	})
+
+	conf.MustSet(config.ViperKeyAdminAPIKeysEnabled, true)
+	conf.MustSet(config.ViperKeyAdminAPIKeysRootKey, rootKey)
+
+	support := apikey.NewAPIKey("support", []apikey.Scope{apikey.ScopeIdentitiesRead}, time.Hour)
+	require.NoError(t, reg.APIKeyPersister().CreateAPIKey(context.Background(), support))
+
+	expired := apikey.NewAPIKey("expired", []apikey.Scope{apikey.ScopeIdentitiesRead}, -time.Hour)
+	require.NoError(t, reg.APIKeyPersister().CreateAPIKey(context.Background(), expired))
+
+	for _, tc := range []struct {
+		d        string
+		method   string
+		path     string
+		key      string
+		expected int
+	}{
+		{d: "health checks are not protected", method: "GET", path: healthx.AliveCheckPath, expected: http.StatusNoContent},
+		{d: "key is missing", method: "GET", path: "/identities", expected: http.StatusUnauthorized},
+		{d: "key is invalid", method: "GET", path: "/identities", key: "not-a-key", expected: http.StatusUnauthorized},
+		{d: "key is expired", method: "GET", path: "/identities", key: expired.Key, expected: http.StatusUnauthorized},
+		{d: "root key has all permissions", method: "POST", path: "/api-keys", key: rootKey, expected: http.StatusNoContent},
+		{d: "root key can access unscoped endpoints", method: "GET", path: "/unknown", key: rootKey, expected: http.StatusNoContent},
+		{d: "scoped key has permission", method: "GET", path: "/identities/1234", key: support.Key, expected: http.StatusNoContent},
+		{d: "scoped key lacks permission", method: "DELETE", path: "/identities/1234", key: support.Key, expected: http.StatusForbidden},
+		{d: "scoped key can not access unscoped endpoints", method: "GET", path: "/unknown", key: support.Key, expected: http.StatusForbidden},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			assert.Equal(t, tc.expected, do(t, tc.method, tc.path, tc.key))
+		})
+	}
+
+	t.Run("case=evaluates the policy", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyIdentityPartitionsEnabled, true)
+		conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "base64://"+base64.StdEncoding.EncodeToString([]byte(`local ctx = std.extVar('ctx');
+if ctx.principal.name == 'tenant-a' then {
+  allow: ctx.action == 'read' && ctx.resource.type == 'identities' && ctx.partition == 'tenant-a',
+  reason: 'key may only read identities in partition tenant-a',
+} else { allow: true }`)))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyIdentityPartitionsEnabled, false)
+			conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "")
+		})
+		require.NoError(t, reg.APIKeyPolicy().Validate(context.Background()))
+
+		tenant := apikey.NewAPIKey("tenant-a", []apikey.Scope{apikey.ScopeIdentitiesRead, apikey.ScopeIdentitiesWrite, apikey.ScopeSessionsRead}, time.Hour)
+		require.NoError(t, reg.APIKeyPersister().CreateAPIKey(context.Background(), tenant))
+
+		partition := func(id string) http.Header {
+			return http.Header{"X-Kratos-Partition": {id}}
+		}
+
+		assert.Equal(t, http.StatusNoContent, doWithHeader(t, "GET", "/identities/1234", tenant.Key, partition("tenant-a")))
+		assert.Equal(t, http.StatusForbidden, doWithHeader(t, "GET", "/identities/1234", tenant.Key, partition("tenant-b")))
+		assert.Equal(t, http.StatusForbidden, doWithHeader(t, "DELETE", "/identities/1234", tenant.Key, partition("tenant-a")))
+		assert.Equal(t, http.StatusForbidden, doWithHeader(t, "GET", "/sessions/1234", tenant.Key, partition("tenant-a")))
+		assert.Equal(t, http.StatusNoContent, do(t, "GET", "/identities/1234", support.Key), "other keys are allowed by the policy")
+		assert.Equal(t, http.StatusNoContent, doWithHeader(t, "DELETE", "/identities/1234", rootKey, partition("tenant-b")), "the root key is not evaluated")
+	})
+
+	t.Run("case=rejects policies which do not return a decision", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "base64://"+base64.StdEncoding.EncodeToString([]byte(`{}`)))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "")
+		})
+
+		assert.Equal(t, http.StatusInternalServerError, do(t, "GET", "/identities/1234", support.Key))
+	})
+
+	t.Run("case=fails to validate malformed policies", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "base64://"+base64.StdEncoding.EncodeToString([]byte(`{ allow: `)))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyAdminAPIKeysPolicyURL, "")
+		})
+
+		require.Error(t, reg.APIKeyPolicy().Validate(context.Background()))
+	})
+
+	t.Run("case=revoked key is rejected", func(t *testing.T) {
+		require.NoError(t, reg.APIKeyPersister().RevokeAPIKey(context.Background(), support.ID))
+		assert.Equal(t, http.StatusUnauthorized, do(t, "GET", "/identities", support.Key))
+	})
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CreateAPIKey stores the API key. The key is hashed before it is stored.
+		CreateAPIKey(ctx context.Context, key *APIKey) error
+
+		// GetAPIKey retrieves an API key by its ID.
+		GetAPIKey(ctx context.Context, id uuid.UUID) (*APIKey, error)
+
+		// GetAPIKeyByKey retrieves an API key by the (unhashed) key.
+		GetAPIKeyByKey(ctx context.Context, key string) (*APIKey, error)
+
+		// ListAPIKeys lists all API keys.
+		ListAPIKeys(ctx context.Context) ([]APIKey, error)
+
+		// RevokeAPIKey removes an API key from the store.
+		RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	}
+
+	PersistenceProvider interface {
+		APIKeyPersister() Persister
+	}
+)
+
+func TestPersister(p Persister) func(t *testing.T) {
+	ctx := context.Background()
+	return func(t *testing.T) {
+		t.Run("case=not found", func(t *testing.T) {
+			_, err := p.GetAPIKey(ctx, x.NewUUID())
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+
+			_, err = p.GetAPIKeyByKey(ctx, "does-not-exist")
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+		})
+
+		expected := NewAPIKey("support", []Scope{ScopeIdentitiesRead, ScopeSessionsWrite}, time.Hour)
+		key := expected.Key
+		t.Run("case=create and find", func(t *testing.T) {
+			require.NoError(t, p.CreateAPIKey(ctx, expected))
+			assert.Equal(t, key, expected.Key, "the plaintext key must be restored after creation")
+
+			actual, err := p.GetAPIKeyByKey(ctx, key)
+			require.NoError(t, err)
+			assert.Equal(t, expected.ID, actual.ID)
+			assert.Equal(t, expected.Name, actual.Name)
+			assert.EqualValues(t, expected.Scopes, actual.Scopes)
+			assert.Empty(t, actual.Key, "the hashed key must not be returned")
+
+			actual, err = p.GetAPIKey(ctx, expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, expected.ID, actual.ID)
+			assert.Empty(t, actual.Key)
+		})
+
+		t.Run("case=list", func(t *testing.T) {
+			actual, err := p.ListAPIKeys(ctx)
+			require.NoError(t, err)
+			require.Len(t, actual, 1)
+			assert.Equal(t, expected.ID, actual[0].ID)
+			assert.Empty(t, actual[0].Key)
+		})
+
+		t.Run("case=revoke", func(t *testing.T) {
+			require.NoError(t, p.RevokeAPIKey(ctx, expected.ID))
+
+			_, err := p.GetAPIKeyByKey(ctx, key)
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+
+			require.EqualError(t, p.RevokeAPIKey(ctx, expected.ID), sqlcon.ErrNoRows.Error())
+		})
+	}
+}
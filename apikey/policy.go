@@ -0,0 +1,192 @@
+package apikey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	policyDependencies interface {
+		config.Providers
+		x.HTTPClientProvider
+	}
+	PolicyProvider interface {
+		APIKeyPolicy() *Policy
+	}
+
+	// Policy authorizes requests made using scoped admin API keys with the Jsonnet policy located at
+	// `serve.admin.api_keys.policy_url`.
+	Policy struct {
+		r policyDependencies
+		f *fetcher.Fetcher
+
+		l       sync.Mutex
+		url     string
+		snippet string
+	}
+
+	// PolicyInput is passed to the Jsonnet policy as the external variable `ctx`.
+	PolicyInput struct {
+		// Principal is the API key which made the request. The key itself is never passed to the policy.
+		Principal PolicyPrincipal `json:"principal"`
+
+		// Action is either `read` or `write`.
+		Action string `json:"action"`
+
+		// Scope is the scope which is required by the request and which was granted to the API key.
+		Scope Scope `json:"scope"`
+
+		// Resource is the resource the request operates on.
+		Resource PolicyResource `json:"resource"`
+
+		// Partition is the identity partition requested using the partition header, or empty for the
+		// default partition.
+		Partition string `json:"partition"`
+
+		Request PolicyRequest `json:"request"`
+	}
+	PolicyPrincipal struct {
+		ID     string   `json:"id"`
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	PolicyResource struct {
+		// Type is the first segment of the path, for example `identities`.
+		Type string `json:"type"`
+
+		// ID is the second segment of the path, for example the identity's ID, or empty.
+		ID string `json:"id"`
+	}
+	PolicyRequest struct {
+		Method string              `json:"method"`
+		Path   string              `json:"path"`
+		Query  map[string][]string `json:"query"`
+	}
+)
+
+func NewPolicy(r policyDependencies) *Policy {
+	return &Policy{r: r, f: fetcher.NewFetcher(fetcher.WithClient(r.HTTPClient()))}
+}
+
+// Validate fetches the Jsonnet policy and makes sure that it can be parsed. It is a no-op if no
+// policy is configured.
+func (p *Policy) Validate(ctx context.Context) error {
+	location := p.r.Configuration(ctx).AdminAPIKeysPolicyURL()
+	if location == "" {
+		return nil
+	}
+
+	snippet, err := p.load(location)
+	if err != nil {
+		return err
+	}
+
+	if _, err := jsonnet.SnippetToAST(location, snippet); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the admin API key policy: %s", err))
+	}
+
+	return nil
+}
+
+func (p *Policy) load(location string) (string, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.url == location {
+		return p.snippet, nil
+	}
+
+	jn, err := p.f.Fetch(location)
+	if err != nil {
+		return "", err
+	}
+
+	p.url = location
+	p.snippet = jn.String()
+	return p.snippet, nil
+}
+
+// NewPolicyInput describes the request made using the API key.
+func NewPolicyInput(r *http.Request, key *APIKey, scope Scope, partition string) *PolicyInput {
+	action := "write"
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		action = "read"
+	}
+
+	var resource PolicyResource
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	resource.Type = segments[0]
+	if len(segments) > 1 {
+		resource.ID = segments[1]
+	}
+
+	return &PolicyInput{
+		Principal: PolicyPrincipal{
+			ID:     key.ID.String(),
+			Name:   key.Name,
+			Scopes: key.Scopes,
+		},
+		Action:    action,
+		Scope:     scope,
+		Resource:  resource,
+		Partition: partition,
+		Request: PolicyRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+		},
+	}
+}
+
+// Authorize evaluates the Jsonnet policy with the input as the external variable `ctx`. The policy is expected
+// to return an object with the boolean key `allow` and optionally the key `reason`, which is returned to the
+// client if the request is denied. If no policy is configured, every request is allowed.
+func (p *Policy) Authorize(ctx context.Context, in *PolicyInput) error {
+	location := p.r.Configuration(ctx).AdminAPIKeysPolicyURL()
+	if location == "" {
+		return nil
+	}
+
+	snippet, err := p.load(location)
+	if err != nil {
+		return err
+	}
+
+	var input bytes.Buffer
+	if err := json.NewEncoder(&input).Encode(in); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("ctx", input.String())
+	evaluated, err := vm.EvaluateSnippet(location, snippet)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the admin API key policy: %s", err))
+	}
+
+	allow := gjson.Get(evaluated, "allow")
+	if allow.Type != gjson.True && allow.Type != gjson.False {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("The admin API key policy did not return a boolean for key allow."))
+	} else if allow.Bool() {
+		return nil
+	}
+
+	if reason := gjson.Get(evaluated, "reason").String(); reason != "" {
+		return errors.WithStack(herodot.ErrForbidden.WithReasonf("The admin API key policy denied the request: %s", reason))
+	}
+	return errors.WithStack(herodot.ErrForbidden.WithReason("The admin API key policy denied the request."))
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ory/x/reqlog"
 
@@ -43,6 +44,9 @@ import (
 	"github.com/ory/kratos/x"
 )
 
+// eventsShutdownTimeout is how long the daemon waits for buffered self-service events to be delivered on shutdown.
+const eventsShutdownTimeout = 10 * time.Second
+
 type options struct {
 	mwf []func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc)
 }
@@ -74,18 +78,76 @@ func ServePublic(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args
 		n.UseFunc(mw)
 	}
 
+	trusted, err := c.TrustedClients()
+	if err != nil {
+		l.WithError(err).Fatal("Unable to load the trusted clients.")
+	}
+
+	if _, err := c.SessionCookiePartitioned(); err != nil {
+		l.WithError(err).Fatal("Unable to configure partitioned cookies.")
+	}
+
+	if err := c.ValidateSessionDomain(); err != nil {
+		l.WithError(err).Fatal("Unable to configure the session cookie domain.")
+	}
+
 	router := x.NewRouterPublic()
 	csrf := x.NewCSRFHandler(
 		router,
 		r.Writer(),
 		l,
 		stringsx.Coalesce(c.SelfPublicURL().Path, "/"),
-		c.SelfPublicURL().Hostname(),
+		stringsx.Coalesce(trusted.Domain, c.SelfPublicURL().Hostname()),
 		!c.IsInsecureDevMode(),
 	)
 
+	if err := r.IdentitySchemaLoader().Load(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the identity JSON Schemas.")
+	}
+
+	if err := r.SessionClaimsMapper().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the session claims mapper.")
+	}
+
+	if err := r.SessionTokenizer().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the session JSON Web Token signing key.")
+	}
+
+	if loader, ok := r.PasswordValidator().(password.BlocklistLoader); ok {
+		if err := loader.LoadBlocklist(cmd.Context()); err != nil {
+			l.WithError(err).Fatal("Unable to load the password blocklist.")
+		}
+	}
+
+	if err := r.IdentityTraitsTransformer().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the identity traits transformation.")
+	}
+
+	if err := r.APIKeyPolicy().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the admin API key policy.")
+	}
+
+	if err := r.FormTransformer().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to load the self-service form transforms.")
+	}
+
+	if err := registration.ValidateCustomFields(cmd.Context(), r); err != nil {
+		l.WithError(err).Fatal("Unable to load the custom registration fields.")
+	}
+
+	if err := settings.ValidatePrivilegedChanges(cmd.Context(), r); err != nil {
+		l.WithError(err).Fatal("Unable to load the privileged settings changes.")
+	}
+
+	n.Use(r.SecurityHeaders())
 	n.UseFunc(x.CleanPath) // Prevent double slashes from breaking CSRF.
-	r.WithCSRFHandler(csrf)
+	n.Use(r.PublicCompressor())
+	n.Use(r.MaintenanceMode())
+	n.Use(r.DatabaseBreaker())
+	n.Use(r.PublicPartitioner())
+	n.Use(r.ClientVersionGate())
+	n.Use(r.FormTransformer())
+	r.WithCSRFHandler(x.NewTrustedClientsCSRFHandler(csrf, r))
 	n.UseHandler(r.CSRFHandler())
 
 	r.RegisterPublicRoutes(router)
@@ -125,9 +187,16 @@ func ServeAdmin(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args
 		n.UseFunc(mw)
 	}
 
+	if err := r.AdminIPFilter().Validate(cmd.Context()); err != nil {
+		l.WithError(err).Fatal("Unable to parse the admin API IP filter configuration.")
+	}
+
 	router := x.NewRouterAdmin()
 	r.RegisterAdminRoutes(router)
+	n.UseFunc(x.RequestIDMiddleware)
+	n.Use(r.SecurityHeaders())
 	n.Use(reqlog.NewMiddlewareFromLogger(l, "admin#"+c.SelfPublicURL().String()))
+	n.Use(r.AdminIPFilter())
 	n.Use(sqa(cmd, r))
 	n.Use(r.PrometheusManager())
 
@@ -135,6 +204,10 @@ func ServeAdmin(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args
 		n.Use(tracer)
 	}
 
+	n.Use(r.APIKeyMiddleware())
+	n.Use(r.MaintenanceMode())
+	n.Use(r.DatabaseBreaker())
+	n.Use(r.AdminPartitioner())
 	n.UseHandler(router)
 	server := graceful.WithDefaults(&http.Server{
 		Addr:    c.AdminListenOn(),
@@ -223,6 +296,54 @@ func bgTasks(d driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args []s
 
 	ctx, cancel := cx.WithCancel(cmd.Context())
 
+	go func() {
+		d.Logger().Println("Identity deletion janitor started.")
+		if err := d.IdentityJanitor().Work(ctx); err != nil {
+			d.Logger().WithError(err).Error("Identity deletion janitor stopped unexpectedly.")
+		}
+	}()
+
+	// Workers which need to write their pending work before the process exits.
+	var draining sync.WaitGroup
+
+	draining.Add(1)
+	go func() {
+		defer draining.Done()
+		d.Logger().Println("Login attempt log worker started.")
+		if err := d.LoginAttemptLogger().Work(ctx); err != nil {
+			d.Logger().WithError(err).Error("Login attempt log worker stopped unexpectedly.")
+		}
+	}()
+
+	go func() {
+		d.Logger().Println("Asynchronous hook worker started.")
+		if err := d.AsyncHookWorker().Work(ctx); err != nil {
+			d.Logger().WithError(err).Error("Asynchronous hook worker stopped unexpectedly.")
+		}
+	}()
+
+	go func() {
+		if d.Configuration(ctx).IdentitySchemaRefreshInterval() <= 0 {
+			return
+		}
+
+		d.Logger().Println("Identity JSON Schema refresher started.")
+		if err := d.IdentitySchemaLoader().Work(ctx); err != nil {
+			d.Logger().WithError(err).Error("Identity JSON Schema refresher stopped unexpectedly.")
+		}
+	}()
+
+	go func() {
+		if !d.Configuration(ctx).HotReloadEnabled() {
+			return
+		}
+
+		d.Logger().Println("Identity JSON Schema file watcher started.")
+		if err := d.IdentitySchemaLoader().Watch(ctx); err != nil {
+			d.Logger().WithError(err).Error("Identity JSON Schema file watcher stopped unexpectedly.")
+		}
+	}()
+
 	d.Logger().Println("Courier worker started.")
 	if err := graceful.Graceful(func() error {
 		return d.Courier().Work(ctx)
@@ -234,6 +355,10 @@ func bgTasks(d driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args []s
 	}
 
 	d.Logger().Println("Courier worker was shutdown gracefully.")
+
+	cancel()
+	draining.Wait()
+	d.Logger().Println("Background workers were shutdown gracefully.")
 }
 
 func ServeAll(d driver.Registry, opts ...Option) func(cmd *cobra.Command, args []string) {
@@ -244,5 +369,14 @@ func ServeAll(d driver.Registry, opts ...Option) func(cmd *cobra.Command, args [
 		go ServeAdmin(d, &wg, cmd, args, opts...)
 		go bgTasks(d, &wg, cmd, args)
 		wg.Wait()
+
+		// The HTTP servers are shut down, so no more events are emitted.
+		ctx, cancel := cx.WithTimeout(cx.Background(), eventsShutdownTimeout)
+		defer cancel()
+		if err := d.EventEmitter().Close(ctx); err != nil {
+			d.Logger().WithError(err).Error("Unable to deliver the remaining self-service events.")
+			return
+		}
+		d.Logger().Println("Self-service event emitter was shutdown gracefully.")
 	}
 }
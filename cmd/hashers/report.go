@@ -0,0 +1,59 @@
+package hashers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+
+	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/hash"
+)
+
+const (
+	FlagBatchSize  = "batch-size"
+	FlagBatchDelay = "batch-delay"
+)
+
+func newReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Reports password hashes using outdated hashing parameters.",
+		Long: `This command iterates over all identities and counts the password credentials which were not hashed using the hashing parameters in the configuration file.
+
+Because the plaintext passwords are unknown, outdated hashes can not be upgraded by this command. They are upgraded automatically when the user signs in the next time.
+
+Identities are loaded in batches. Use --batch-size and --batch-delay to limit the load on the database:
+
+	kratos hashers report -c path/to/config.yml --batch-size 500 --batch-delay 1s`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			batchSize, err := cmd.Flags().GetInt(FlagBatchSize)
+			cmdx.Must(err, "Unable to parse flag --%s: %s", FlagBatchSize, err)
+			batchDelay, err := cmd.Flags().GetDuration(FlagBatchDelay)
+			cmdx.Must(err, "Unable to parse flag --%s: %s", FlagBatchDelay, err)
+
+			d := driver.New(cmd.Context(), configx.WithFlags(cmd.Flags()))
+			report, err := d.Rehasher().Report(cmd.Context(), hash.RehashOptions{
+				BatchSize:  batchSize,
+				BatchDelay: batchDelay,
+				Progress: func(report *hash.RehashReport) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Inspected %d identities...\n", report.Identities)
+				},
+			})
+			cmdx.Must(err, "Unable to inspect the password hashes: %s", err)
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Identities:           %d\n", report.Identities)
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Password credentials: %d\n", report.Credentials)
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Outdated hashes:      %d\n", report.Outdated)
+		},
+	}
+
+	configx.RegisterFlags(cmd.PersistentFlags())
+	cmd.Flags().Int(FlagBatchSize, 100, "The number of identities loaded per batch.")
+	cmd.Flags().Duration(FlagBatchDelay, 100*time.Millisecond, "The pause between two batches.")
+	return cmd
+}
@@ -15,4 +15,5 @@ func RegisterCommandRecursive(parent *cobra.Command) {
 	parent.AddCommand(rootCmd)
 
 	argon2.RegisterCommandRecursive(rootCmd)
+	rootCmd.AddCommand(newReportCmd())
 }
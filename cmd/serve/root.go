@@ -41,6 +41,14 @@ DON'T DO THIS IN PRODUCTION!
 `)
 		}
 
+		if d.Configuration(cmd.Context()).IsConfigDumpEnabled() {
+			c, err := d.Configuration(cmd.Context()).RedactedConfig()
+			if err != nil {
+				d.Logger().WithError(err).Fatal("Unable to dump the effective configuration.")
+			}
+			d.Logger().WithField("config", string(c)).Info("Loaded the effective configuration.")
+		}
+
 		configVersion := d.Configuration(cmd.Context()).ConfigVersion()
 		if configVersion == config.UnknownVersion {
 			d.Logger().Warn("The config has no version specified. Add the version to improve your development experience.")
@@ -62,4 +70,5 @@ func init() {
 
 	serveCmd.PersistentFlags().Bool("sqa-opt-out", false, "Disable anonymized telemetry reports - for more information please visit https://www.ory.sh/docs/ecosystem/sqa")
 	serveCmd.PersistentFlags().Bool("dev", false, "Disables critical security features to make development easier")
+	serveCmd.PersistentFlags().Bool("dump-config", false, "Logs the effective configuration, with secrets redacted, on startup")
 }
@@ -54,8 +54,9 @@ func NewContainer(name string, o managerOptions) *Container {
 	}
 }
 
-func (c *Container) Valid(identity uuid.UUID) error {
-	if c.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the container expired more than clockSkew ago or belongs to another identity.
+func (c *Container) Valid(identity uuid.UUID, clockSkew time.Duration) error {
+	if c.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You must restart the flow because the resumable session has expired."))
 	}
 
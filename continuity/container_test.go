@@ -14,9 +14,10 @@ import (
 func TestContainer(t *testing.T) {
 	id := x.NewUUID()
 	for k, tc := range []struct {
-		c    *Container
-		i    uuid.UUID
-		pass bool
+		c         *Container
+		i         uuid.UUID
+		clockSkew time.Duration
+		pass      bool
 	}{
 		{
 			c: &Container{
@@ -53,9 +54,16 @@ func TestContainer(t *testing.T) {
 			i:    id,
 			pass: true,
 		},
+		{
+			c: &Container{
+				ExpiresAt: time.Now().Add(-time.Second),
+			},
+			clockSkew: 5 * time.Second,
+			pass:      true,
+		},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
-			err := tc.c.Valid(tc.i)
+			err := tc.c.Valid(tc.i, tc.clockSkew)
 			if tc.pass {
 				require.NoError(t, err)
 			} else {
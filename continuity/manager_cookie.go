@@ -12,6 +12,7 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/x/sqlcon"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -24,6 +25,7 @@ const cookieName = "ory_kratos_continuity"
 type (
 	managerCookieDependencies interface {
 		PersistenceProvider
+		config.Providers
 		x.CookieProvider
 		session.ManagementProvider
 	}
@@ -71,7 +73,7 @@ func (m *ManagerCookie) Continue(ctx context.Context, w http.ResponseWriter, r *
 		return nil, err
 	}
 
-	if err := container.Valid(o.iid); err != nil {
+	if err := container.Valid(o.iid, m.d.Configuration(ctx).SelfServiceClockSkewTolerance()); err != nil {
 		return nil, err
 	}
 
@@ -2,9 +2,10 @@ package courier
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
+	"net/mail"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -15,7 +16,9 @@ import (
 
 	gomail "github.com/ory/mail/v3"
 
+	"github.com/ory/kratos/courier/template"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/metrics/prometheus"
 	"github.com/ory/kratos/x"
 )
 
@@ -23,6 +26,7 @@ type (
 	smtpDependencies interface {
 		PersistenceProvider
 		x.LoggingProvider
+		PrometheusManager() *prometheus.MetricsManager
 	}
 	Courier struct {
 		Dialer *gomail.Dialer
@@ -40,12 +44,24 @@ func NewSMTP(d smtpDependencies, c *config.Provider) *Courier {
 	port, _ := strconv.ParseInt(uri.Port(), 10, 64)
 
 	var ssl bool
-	var tlsConfig *tls.Config
+	var sslSkipVerify bool
 	if uri.Scheme == "smtps" {
 		ssl = true
-		sslSkipVerify, _ := strconv.ParseBool(uri.Query().Get("skip_ssl_verify"))
-		// #nosec G402 This is ok (and required!) because it is configurable and disabled by default.
-		tlsConfig = &tls.Config{InsecureSkipVerify: sslSkipVerify, ServerName: uri.Hostname()}
+		sslSkipVerify, _ = strconv.ParseBool(uri.Query().Get("skip_ssl_verify"))
+	}
+
+	tlsConfig, err := NewSMTPTLSConfig(c, uri.Hostname(), sslSkipVerify)
+	if err != nil {
+		d.Logger().WithError(err).Fatal("Unable to load the TLS configuration of the SMTP courier.")
+	}
+
+	if err := validateSenders(c); err != nil {
+		d.Logger().WithError(err).Fatal("Unable to load the sender configuration of the SMTP courier.")
+	}
+
+	startTLSPolicy := gomail.OpportunisticStartTLS
+	if c.CourierSMTPTLS().RequireStartTLS {
+		startTLSPolicy = gomail.MandatoryStartTLS
 	}
 
 	return &Courier{
@@ -53,18 +69,30 @@ func NewSMTP(d smtpDependencies, c *config.Provider) *Courier {
 		c: c,
 		Dialer: &gomail.Dialer{
 			/* #nosec we need to support SMTP servers without TLS */
-			TLSConfig:    tlsConfig,
-			Host:         uri.Hostname(),
-			Port:         int(port),
-			Username:     uri.User.Username(),
-			Password:     password,
-			SSL:          ssl,
-			Timeout:      time.Second * 10,
-			RetryFailure: true,
+			TLSConfig:      tlsConfig,
+			StartTLSPolicy: startTLSPolicy,
+			Host:           uri.Hostname(),
+			Port:           int(port),
+			Username:       uri.User.Username(),
+			Password:       password,
+			SSL:            ssl,
+			Timeout:        time.Second * 10,
+			RetryFailure:   true,
 		},
 	}
 }
 
+// validateSenders checks that the global sender and the senders of all message types are valid email addresses.
+func validateSenders(c *config.Provider) error {
+	for _, t := range []template.Type{"", template.TypeRecovery, template.TypeVerification, template.TypeAccountDeletion} {
+		sender := c.CourierSMTPSender(string(t))
+		if _, err := mail.ParseAddress(sender.FromAddress); err != nil {
+			return errors.Errorf("sender address %q of message type %q is invalid: %s", sender.FromAddress, t, err)
+		}
+	}
+	return nil
+}
+
 func (m *Courier) QueueEmail(ctx context.Context, t EmailTemplate) (uuid.UUID, error) {
 	body, err := t.EmailBody()
 	if err != nil {
@@ -82,11 +110,12 @@ func (m *Courier) QueueEmail(ctx context.Context, t EmailTemplate) (uuid.UUID, e
 	}
 
 	message := &Message{
-		Status:    MessageStatusQueued,
-		Type:      MessageTypeEmail,
-		Body:      body,
-		Subject:   subject,
-		Recipient: recipient,
+		Status:       MessageStatusQueued,
+		Type:         MessageTypeEmail,
+		Body:         body,
+		Subject:      subject,
+		Recipient:    recipient,
+		TemplateType: t.TemplateType(),
 	}
 	if err := m.d.CourierPersister().AddMessage(ctx, message); err != nil {
 		return uuid.Nil, err
@@ -99,6 +128,8 @@ func (m *Courier) Work(ctx context.Context) error {
 	defer close(errChan)
 
 	go m.watchMessages(ctx, errChan)
+	go m.watchQueueDepth(ctx)
+	go m.watchTemplates(ctx)
 
 	select {
 	case <-ctx.Done():
@@ -112,66 +143,157 @@ func (m *Courier) Work(ctx context.Context) error {
 }
 
 func (m *Courier) watchMessages(ctx context.Context, errChan chan error) {
+	concurrency := m.c.CourierDispatchMaxConcurrency()
+	perSecond, burst := m.c.CourierDispatchRateLimit()
+	limiter := newRateLimiter(perSecond, burst)
+	m.d.PrometheusManager().SetCourierDispatchLimits(concurrency, perSecond)
+	batchSize := m.c.CourierDispatchBatchSize()
+
 	for {
 		if err := backoff.Retry(func() error {
-			if len(m.Dialer.Host) == 0 {
-				return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Courier tried to deliver an email but courier.smtp_url is not set!"))
-			}
+			return m.dispatchBatch(ctx, limiter, concurrency, batchSize)
+		}, backoff.NewExponentialBackOff()); err != nil {
+			errChan <- err
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
 
-			messages, err := m.d.CourierPersister().NextMessages(ctx, 10)
-			if err != nil {
-				if errors.Is(err, ErrQueueEmpty) {
-					return nil
-				}
-				return err
-			}
-			for k := range messages {
-				var msg = messages[k]
-
-				switch msg.Type {
-				case MessageTypeEmail:
-					from := m.c.CourierSMTPFrom()
-					gm := gomail.NewMessage()
-					gm.SetHeader("From", from)
-					gm.SetHeader("To", msg.Recipient)
-					gm.SetHeader("Subject", msg.Subject)
-					gm.SetBody("text/plain", msg.Body)
-					gm.AddAlternative("text/html", msg.Body)
-
-					if err := m.Dialer.DialAndSend(ctx, gm); err != nil {
-						m.d.Logger().
-							WithError(err).
-							WithField("smtp_server", fmt.Sprintf("%s:%d", m.Dialer.Host, m.Dialer.Port)).
-							WithField("smtp_ssl_enabled", m.Dialer.SSL).
-							// WithField("email_to", msg.Recipient).
-							WithField("message_from", from).
-							Error("Unable to send email using SMTP connection.")
-						continue
-					}
-
-					if err := m.d.CourierPersister().SetMessageStatus(ctx, msg.ID, MessageStatusSent); err != nil {
-						m.d.Logger().
-							WithError(err).
-							WithField("message_id", msg.ID).
-							Error(`Unable to set the message status to "sent".`)
-						return err
-					}
-
-					m.d.Logger().
-						WithField("message_id", msg.ID).
-						WithField("message_type", msg.Type).
-						WithField("message_subject", msg.Subject).
-						Debug("Courier sent out message.")
-				default:
-					return errors.Errorf("received unexpected message type: %d", msg.Type)
+// dispatchBatch sends the next batch of queued messages with at most concurrency messages being sent at the same
+// time. Each message waits for the rate limiter before it is sent.
+func (m *Courier) dispatchBatch(ctx context.Context, limiter *rateLimiter, concurrency int, batchSize uint8) error {
+	if len(m.Dialer.Host) == 0 {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Courier tried to deliver an email but courier.smtp_url is not set!"))
+	}
+
+	messages, err := m.d.CourierPersister().NextMessages(ctx, batchSize)
+	if err != nil {
+		if errors.Is(err, ErrQueueEmpty) {
+			return nil
+		}
+		return err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for k := range messages {
+		if err := limiter.Wait(ctx); err != nil {
+			// The context was cancelled, the remaining messages stay queued.
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(msg Message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := m.dispatch(ctx, msg); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
 				}
+				mu.Unlock()
 			}
+		}(messages[k])
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// dispatch sends a single message. Messages which could not be sent stay queued and are retried with the next batch.
+func (m *Courier) dispatch(ctx context.Context, msg Message) error {
+	m.d.PrometheusManager().CourierDispatchStarted()
+	defer m.d.PrometheusManager().CourierDispatchFinished()
 
+	switch msg.Type {
+	case MessageTypeEmail:
+		from := m.c.CourierSMTPSender(string(msg.TemplateType))
+		gm := gomail.NewMessage()
+		gm.SetAddressHeader("From", from.FromAddress, from.FromName)
+		gm.SetHeader("To", msg.Recipient)
+		gm.SetHeader("Subject", msg.Subject)
+		gm.SetBody("text/plain", msg.Body)
+		gm.AddAlternative("text/html", msg.Body)
+
+		if err := m.Dialer.DialAndSend(ctx, gm); err != nil {
+			m.d.Logger().
+				WithError(err).
+				WithField("smtp_server", fmt.Sprintf("%s:%d", m.Dialer.Host, m.Dialer.Port)).
+				WithField("smtp_ssl_enabled", m.Dialer.SSL).
+				// WithField("email_to", msg.Recipient).
+				WithField("message_from", from.FromAddress).
+				Error("Unable to send email using SMTP connection.")
+			m.d.PrometheusManager().CourierMessageFailed()
 			return nil
-		}, backoff.NewExponentialBackOff()); err != nil {
-			errChan <- err
+		}
+
+		if err := m.d.CourierPersister().SetMessageStatus(ctx, msg.ID, MessageStatusSent); err != nil {
+			m.d.Logger().
+				WithError(err).
+				WithField("message_id", msg.ID).
+				Error(`Unable to set the message status to "sent".`)
+			return err
+		}
+
+		m.d.PrometheusManager().CourierMessageSent()
+		m.d.Logger().
+			WithField("message_id", msg.ID).
+			WithField("message_type", msg.Type).
+			WithField("message_subject", msg.Subject).
+			Debug("Courier sent out message.")
+		return nil
+	default:
+		return errors.Errorf("received unexpected message type: %d", msg.Type)
+	}
+}
+
+func (m *Courier) watchQueueDepth(ctx context.Context) {
+	ticker := time.NewTicker(m.c.CourierMetricsSamplingInterval())
+	defer ticker.Stop()
+
+	for {
+		count, err := m.d.CourierPersister().CountQueuedMessages(ctx)
+		if err != nil {
+			m.d.Logger().
+				WithError(err).
+				Warn("Unable to count the queued courier messages.")
+		} else {
+			m.d.PrometheusManager().SetCourierQueueDepth(count)
+		}
+
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
 		}
-		time.Sleep(time.Second)
+	}
+}
+
+// watchTemplates reloads changed templates periodically if `hot_reload.enabled` is set.
+func (m *Courier) watchTemplates(ctx context.Context) {
+	if !m.c.HotReloadEnabled() {
+		return
+	}
+
+	ticker := time.NewTicker(m.c.HotReloadInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		template.Reload(m.d.Logger())
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ory/kratos/corp"
+	"github.com/ory/kratos/courier/template"
 
 	"github.com/gofrs/uuid"
 )
@@ -30,6 +31,9 @@ type Message struct {
 	Body      string        `json:"-" db:"body"`
 	Subject   string        `json:"-" db:"subject"`
 
+	// TemplateType is the type of the template the message was rendered from. It decides which sender is used.
+	TemplateType template.Type `json:"-" db:"template_type"`
+
 	// CreatedAt is a helper struct field for gobuffalo.pop.
 	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
 	// UpdatedAt is a helper struct field for gobuffalo.pop.
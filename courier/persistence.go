@@ -25,6 +25,8 @@ type (
 		SetMessageStatus(context.Context, uuid.UUID, MessageStatus) error
 
 		LatestQueuedMessage(ctx context.Context) (*Message, error)
+
+		CountQueuedMessages(ctx context.Context) (int, error)
 	}
 
 	PersistenceProvider interface {
@@ -42,6 +44,10 @@ func TestPersister(p Persister) func(t *testing.T) {
 
 			_, err = p.LatestQueuedMessage(ctx)
 			require.EqualError(t, err, ErrQueueEmpty.Error())
+
+			count, err := p.CountQueuedMessages(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, count)
 		})
 
 		messages := make([]Message, 5)
@@ -53,6 +59,12 @@ func TestPersister(p Persister) func(t *testing.T) {
 			}
 		})
 
+		t.Run("case=count messages in queue", func(t *testing.T) {
+			count, err := p.CountQueuedMessages(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, len(messages), count)
+		})
+
 		t.Run("case=latest message in queue", func(t *testing.T) {
 			expected, err := p.LatestQueuedMessage(ctx)
 			require.NoError(t, err)
@@ -76,6 +88,7 @@ func TestPersister(p Persister) func(t *testing.T) {
 					assert.Equal(t, expected.Status, actual.Status)
 					assert.Equal(t, expected.Type, actual.Type)
 					assert.Equal(t, expected.Recipient, actual.Recipient)
+					assert.Equal(t, expected.TemplateType, actual.TemplateType)
 
 					require.NoError(t, p.SetMessageStatus(ctx, actual.ID, MessageStatusSent))
 				})
@@ -83,6 +96,10 @@ func TestPersister(p Persister) func(t *testing.T) {
 
 			_, err := p.NextMessages(ctx, 10)
 			require.EqualError(t, err, ErrQueueEmpty.Error())
+
+			count, err := p.CountQueuedMessages(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 0, count)
 		})
 
 		t.Run("case=setting message status", func(t *testing.T) {
@@ -0,0 +1,56 @@
+package courier
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket which allows bursts of up to burst messages and refills at perSecond
+// messages per second.
+type rateLimiter struct {
+	sync.Mutex
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		perSecond: perSecond,
+		burst:     float64(burst),
+		tokens:    float64(burst),
+		last:      time.Now(),
+	}
+}
+
+// Wait blocks until a message may be sent or the context is cancelled. It never blocks if the rate is zero.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.perSecond <= 0 {
+		return nil
+	}
+
+	for {
+		l.Lock()
+		now := time.Now()
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((1 - l.tokens) / l.perSecond * float64(time.Second))
+		l.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package courier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("case=allows bursts and then limits the rate", func(t *testing.T) {
+		l := newRateLimiter(20, 3)
+
+		start := time.Now()
+		for k := 0; k < 3; k++ {
+			require.NoError(t, l.Wait(context.Background()))
+		}
+		assert.Less(t, int64(time.Since(start)), int64(25*time.Millisecond), "the burst must not be delayed")
+
+		start = time.Now()
+		for k := 0; k < 4; k++ {
+			require.NoError(t, l.Wait(context.Background()))
+		}
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(150*time.Millisecond), "four messages at 20/s take at least 200ms")
+	})
+
+	t.Run("case=never blocks if disabled", func(t *testing.T) {
+		l := newRateLimiter(0, 1)
+
+		start := time.Now()
+		for k := 0; k < 100; k++ {
+			require.NoError(t, l.Wait(context.Background()))
+		}
+		assert.Less(t, int64(time.Since(start)), int64(25*time.Millisecond))
+	})
+
+	t.Run("case=stops waiting when the context is cancelled", func(t *testing.T) {
+		l := newRateLimiter(0.1, 1)
+		require.NoError(t, l.Wait(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx))
+	})
+}
@@ -0,0 +1,74 @@
+package courier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+var smtpTLSVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewSMTPTLSConfig returns the TLS configuration used to connect to the SMTP server. An error is returned
+// if the configuration is invalid, for example if an unknown or insecure cipher suite is configured.
+func NewSMTPTLSConfig(c *config.Provider, serverName string, skipVerify bool) (*tls.Config, error) {
+	conf := c.CourierSMTPTLS()
+
+	minVersion, ok := smtpTLSVersions[conf.MinVersion]
+	if !ok {
+		return nil, errors.Errorf("unknown minimum TLS version %s configured for the SMTP courier", conf.MinVersion)
+	}
+
+	// #nosec G402 This is ok (and required!) because it is configurable and disabled by default.
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: skipVerify,
+		MinVersion:         minVersion,
+	}
+
+	if len(conf.CipherSuites) > 0 {
+		supported := map[string]uint16{}
+		for _, cs := range tls.CipherSuites() {
+			supported[cs.Name] = cs.ID
+		}
+
+		for _, name := range conf.CipherSuites {
+			id, ok := supported[name]
+			if !ok {
+				return nil, errors.Errorf("cipher suite %s configured for the SMTP courier is unknown or insecure", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	if conf.CAPath != "" {
+		ca, err := ioutil.ReadFile(conf.CAPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("unable to load any PEM encoded certificates from CA bundle %s", conf.CAPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertPath != "" || conf.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertPath, conf.KeyPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
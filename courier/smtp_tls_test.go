@@ -0,0 +1,60 @@
+package courier_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+)
+
+func TestNewSMTPTLSConfig(t *testing.T) {
+	t.Run("case=uses secure defaults", func(t *testing.T) {
+		conf := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+		c, err := courier.NewSMTPTLSConfig(conf, "mail.ory.sh", false)
+		require.NoError(t, err)
+		assert.EqualValues(t, tls.VersionTLS12, c.MinVersion)
+		assert.Equal(t, "mail.ory.sh", c.ServerName)
+		assert.False(t, c.InsecureSkipVerify)
+		assert.Empty(t, c.CipherSuites)
+		assert.Nil(t, c.RootCAs)
+	})
+
+	t.Run("case=applies min version and cipher suites", func(t *testing.T) {
+		conf := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+		conf.MustSet(config.ViperKeyCourierSMTPTLSMinVersion, "1.3")
+		conf.MustSet(config.ViperKeyCourierSMTPTLSCipherSuites, []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"})
+
+		c, err := courier.NewSMTPTLSConfig(conf, "mail.ory.sh", false)
+		require.NoError(t, err)
+		assert.EqualValues(t, tls.VersionTLS13, c.MinVersion)
+		assert.EqualValues(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, c.CipherSuites)
+	})
+
+	for _, tc := range []struct {
+		d   string
+		key string
+		v   interface{}
+	}{
+		{d: "unknown min version", key: config.ViperKeyCourierSMTPTLSMinVersion, v: "1.4"},
+		{d: "unknown cipher suite", key: config.ViperKeyCourierSMTPTLSCipherSuites, v: []string{"TLS_FOO"}},
+		{d: "insecure cipher suite", key: config.ViperKeyCourierSMTPTLSCipherSuites, v: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+		{d: "missing ca bundle", key: config.ViperKeyCourierSMTPTLSCAPath, v: "stub/does-not-exist.pem"},
+		{d: "missing client certificate", key: config.ViperKeyCourierSMTPTLSCertPath, v: "stub/does-not-exist.pem"},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			conf := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+			conf.MustSet(tc.key, tc.v)
+
+			_, err := courier.NewSMTPTLSConfig(conf, "mail.ory.sh", false)
+			require.Error(t, err)
+		})
+	}
+}
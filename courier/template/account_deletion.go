@@ -0,0 +1,40 @@
+package template
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	AccountDeletion struct {
+		c *config.Provider
+		m *AccountDeletionModel
+	}
+	AccountDeletionModel struct {
+		To string
+		// DeleteAfter is nil if the account is deleted immediately.
+		DeleteAfter *time.Time
+	}
+)
+
+func NewAccountDeletion(c *config.Provider, m *AccountDeletionModel) *AccountDeletion {
+	return &AccountDeletion{c: c, m: m}
+}
+
+func (t *AccountDeletion) TemplateType() Type {
+	return TypeAccountDeletion
+}
+
+func (t *AccountDeletion) EmailRecipient() (string, error) {
+	return t.m.To, nil
+}
+
+func (t *AccountDeletion) EmailSubject() (string, error) {
+	return loadTextTemplate(filepath.Join(t.c.CourierTemplatesRoot(), "account_deletion/email.subject.gotmpl"), t.m)
+}
+
+func (t *AccountDeletion) EmailBody() (string, error) {
+	return loadTextTemplate(filepath.Join(t.c.CourierTemplatesRoot(), "account_deletion/email.body.gotmpl"), t.m)
+}
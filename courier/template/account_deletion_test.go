@@ -0,0 +1,35 @@
+package template_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/internal"
+)
+
+func TestAccountDeletion(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+
+	deleteAfter := time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		m        *template.AccountDeletionModel
+		contains string
+	}{
+		{m: &template.AccountDeletionModel{DeleteAfter: &deleteAfter}, contains: "March 4, 2021"},
+		{m: &template.AccountDeletionModel{}, contains: "deleted permanently."},
+	} {
+		tpl := template.NewAccountDeletion(conf, tc.m)
+
+		rendered, err := tpl.EmailBody()
+		require.NoError(t, err)
+		assert.Contains(t, rendered, tc.contains)
+
+		rendered, err = tpl.EmailSubject()
+		require.NoError(t, err)
+		assert.NotEmpty(t, rendered)
+	}
+}
@@ -0,0 +1,34 @@
+package template
+
+import "time"
+
+type (
+	// Context is available to the recovery and verification email templates as `.Context`.
+	Context struct {
+		// FlowType is either `recovery` or `verification`.
+		FlowType string
+
+		// ExpiresAt is the time the link in the email expires. It is zero if the email contains no link.
+		ExpiresAt time.Time
+
+		// Identity is the identity the address belongs to. It is nil if the address is unknown.
+		Identity *ContextIdentity
+
+		// Request describes the client which requested the email. It is nil unless
+		// `courier.template_context.request_metadata` is enabled.
+		Request *ContextRequest
+	}
+	ContextIdentity struct {
+		ID string
+
+		// Traits are nil unless `courier.template_context.identity_traits` is enabled.
+		Traits map[string]interface{}
+	}
+	ContextRequest struct {
+		IP string
+
+		// Country and Region are empty unless `session.geolocation` is enabled and the IP address is known.
+		Country string
+		Region  string
+	}
+)
@@ -10,49 +10,88 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/markbates/pkger"
 	"github.com/pkg/errors"
+
+	"github.com/ory/x/logrusx"
 )
 
 var _ = pkger.Dir("github.com/ory/kratos:/courier/template/templates")
 
 var cache, _ = lru.New(16)
 
+type cachedTemplate struct {
+	t      *template.Template
+	source string
+}
+
 func loadTextTemplate(path string, model interface{}) (string, error) {
-	var b bytes.Buffer
+	if c, found := cache.Get(path); found {
+		return executeTemplate(c.(*cachedTemplate).t, path, model)
+	}
+
+	c, err := readTemplate(path)
+	if err != nil {
+		return "", err
+	}
+
+	_ = cache.Add(path, c)
+	return executeTemplate(c.t, path, model)
+}
+
+// Reload reads the cached templates again. If a template was changed and can be parsed, it replaces the cached
+// template. Otherwise the error is logged and the previously loaded template continues to be used.
+func Reload(l *logrusx.Logger) {
+	for _, key := range cache.Keys() {
+		path := key.(string)
 
-	if t, found := cache.Get(path); found {
-		var tb bytes.Buffer
-		if err := t.(*template.Template).ExecuteTemplate(&tb, path, model); err != nil {
-			return "", errors.WithStack(err)
+		previous, found := cache.Peek(path)
+		if !found {
+			continue
+		}
+
+		c, err := readTemplate(path)
+		if err != nil {
+			l.WithError(err).WithField("path", path).Error("Unable to reload courier template, the previously loaded template will be used.")
+			continue
+		}
+
+		if c.source != previous.(*cachedTemplate).source {
+			_ = cache.Add(path, c)
+			l.WithField("path", path).Info("Courier template was changed and has been reloaded.")
 		}
-		return tb.String(), nil
 	}
+}
+
+func readTemplate(path string) (*cachedTemplate, error) {
+	var b bytes.Buffer
 
 	if file, err := pkger.Open(path); err == nil {
 		defer file.Close()
 		if _, err := io.Copy(&b, file); err != nil {
-			return "", errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 	} else {
 		file, err := os.Open(path)
 		if err != nil {
-			return "", errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 		defer file.Close()
 		if _, err := io.Copy(&b, file); err != nil {
-			return "", errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 	}
 
 	t, err := template.New(path).Funcs(sprig.TxtFuncMap()).Parse(b.String())
 	if err != nil {
-		return "", errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
-	_ = cache.Add(path, t)
+	return &cachedTemplate{t: t, source: b.String()}, nil
+}
+
+func executeTemplate(t *template.Template, path string, model interface{}) (string, error) {
 	var tb bytes.Buffer
 	if err := t.ExecuteTemplate(&tb, path, model); err != nil {
 		return "", errors.WithStack(err)
 	}
-
 	return tb.String(), nil
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/x/logrusx"
+
 	"github.com/ory/kratos/x"
 )
 
@@ -33,4 +35,24 @@ func TestLoadTextTemplate(t *testing.T) {
 		require.NoError(t, os.RemoveAll(fp))
 		assert.Contains(t, executeTemplate(t, fp), "cached stub body")
 	})
+
+	t.Run("method=reload replaces changed templates", func(t *testing.T) {
+		fp := filepath.Join(os.TempDir(), x.NewUUID().String()) + ".body.gotmpl"
+		require.NoError(t, ioutil.WriteFile(fp, bytes.NewBufferString("stub body v1")))
+		assert.Contains(t, executeTemplate(t, fp), "stub body v1")
+
+		require.NoError(t, ioutil.WriteFile(fp, bytes.NewBufferString("stub body v2")))
+		assert.Contains(t, executeTemplate(t, fp), "stub body v1")
+
+		Reload(logrusx.New("", ""))
+		assert.Contains(t, executeTemplate(t, fp), "stub body v2")
+
+		require.NoError(t, ioutil.WriteFile(fp, bytes.NewBufferString("stub body {{ .Broken ")))
+		Reload(logrusx.New("", ""))
+		assert.Contains(t, executeTemplate(t, fp), "stub body v2")
+
+		require.NoError(t, os.RemoveAll(fp))
+		Reload(logrusx.New("", ""))
+		assert.Contains(t, executeTemplate(t, fp), "stub body v2")
+	})
 }
@@ -12,7 +12,8 @@ type (
 		m *RecoveryInvalidModel
 	}
 	RecoveryInvalidModel struct {
-		To string
+		To      string
+		Context Context
 	}
 )
 
@@ -20,6 +21,10 @@ func NewRecoveryInvalid(c *config.Provider, m *RecoveryInvalidModel) *RecoveryIn
 	return &RecoveryInvalid{c: c, m: m}
 }
 
+func (t *RecoveryInvalid) TemplateType() Type {
+	return TypeRecovery
+}
+
 func (t *RecoveryInvalid) EmailRecipient() (string, error) {
 	return t.m.To, nil
 }
@@ -14,6 +14,7 @@ type (
 	RecoveryValidModel struct {
 		To          string
 		RecoveryURL string
+		Context     Context
 	}
 )
 
@@ -21,6 +22,10 @@ func NewRecoveryValid(c *config.Provider, m *RecoveryValidModel) *RecoveryValid
 	return &RecoveryValid{c: c, m: m}
 }
 
+func (t *RecoveryValid) TemplateType() Type {
+	return TypeRecovery
+}
+
 func (t *RecoveryValid) EmailRecipient() (string, error) {
 	return t.m.To, nil
 }
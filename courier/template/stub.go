@@ -21,6 +21,10 @@ func NewTestStub(c *config.Provider, m *TestStubModel) *TestStub {
 	return &TestStub{c: c, m: m}
 }
 
+func (t *TestStub) TemplateType() Type {
+	return TypeTestStub
+}
+
 func (t *TestStub) EmailRecipient() (string, error) {
 	return t.m.To, nil
 }
@@ -0,0 +1,11 @@
+package template
+
+// Type identifies the kind of message a template renders. It decides which sender is used for the message.
+type Type string
+
+const (
+	TypeRecovery        Type = "recovery"
+	TypeVerification    Type = "verification"
+	TypeAccountDeletion Type = "account_deletion"
+	TypeTestStub        Type = "test_stub"
+)
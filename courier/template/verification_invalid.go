@@ -12,7 +12,8 @@ type (
 		m *VerificationInvalidModel
 	}
 	VerificationInvalidModel struct {
-		To string
+		To      string
+		Context Context
 	}
 )
 
@@ -20,6 +21,10 @@ func NewVerificationInvalid(c *config.Provider, m *VerificationInvalidModel) *Ve
 	return &VerificationInvalid{c: c, m: m}
 }
 
+func (t *VerificationInvalid) TemplateType() Type {
+	return TypeVerification
+}
+
 func (t *VerificationInvalid) EmailRecipient() (string, error) {
 	return t.m.To, nil
 }
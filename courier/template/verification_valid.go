@@ -14,6 +14,7 @@ type (
 	VerificationValidModel struct {
 		To              string
 		VerificationURL string
+		Context         Context
 	}
 )
 
@@ -21,6 +22,10 @@ func NewVerificationValid(c *config.Provider, m *VerificationValidModel) *Verifi
 	return &VerificationValid{c: c, m: m}
 }
 
+func (t *VerificationValid) TemplateType() Type {
+	return TypeVerification
+}
+
 func (t *VerificationValid) EmailRecipient() (string, error) {
 	return t.m.To, nil
 }
@@ -1,6 +1,9 @@
 package courier
 
+import "github.com/ory/kratos/courier/template"
+
 type EmailTemplate interface {
+	TemplateType() template.Type
 	EmailSubject() (string, error)
 	EmailBody() (string, error)
 	EmailRecipient() (string, error)
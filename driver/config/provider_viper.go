@@ -6,15 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/markbates/pkger"
 	"github.com/rs/cors"
 	"github.com/tidwall/gjson"
+	"golang.org/x/net/publicsuffix"
 
 	"github.com/ory/x/configx"
 	"github.com/ory/x/jsonx"
@@ -34,49 +38,202 @@ const (
 	DefaultSQLiteMemoryDSN                                          = "sqlite://:memory:?_fk=true"
 	UnknownVersion                                                  = "unknown version"
 	ViperKeyDSN                                                     = "dsn"
+	ViperKeyDumpConfig                                              = "dump-config"
+	RedactedConfigValue                                             = "[redacted]"
 	ViperKeyCourierSMTPURL                                          = "courier.smtp.connection_uri"
 	ViperKeyCourierTemplatesPath                                    = "courier.template_override_path"
+	ViperKeyCourierTemplateContextIdentityTraits                    = "courier.template_context.identity_traits"
+	ViperKeyCourierTemplateContextRequestMetadata                   = "courier.template_context.request_metadata"
 	ViperKeyCourierSMTPFrom                                         = "courier.smtp.from_address"
+	ViperKeyCourierSMTPFromName                                     = "courier.smtp.from_name"
+	ViperKeyCourierSMTPSenders                                      = "courier.smtp.senders"
+	ViperKeyCourierSMTPTLSMinVersion                                = "courier.smtp.tls.min_version"
+	ViperKeyCourierSMTPTLSCipherSuites                              = "courier.smtp.tls.cipher_suites"
+	ViperKeyCourierSMTPTLSRequireStartTLS                           = "courier.smtp.tls.require_starttls"
+	ViperKeyCourierSMTPTLSCAPath                                    = "courier.smtp.tls.ca_path"
+	ViperKeyCourierSMTPTLSCertPath                                  = "courier.smtp.tls.cert_path"
+	ViperKeyCourierSMTPTLSKeyPath                                   = "courier.smtp.tls.key_path"
+	ViperKeyCourierMetricsSamplingInterval                          = "courier.metrics.sampling_interval"
+	ViperKeyCourierDispatchMaxConcurrency                           = "courier.dispatch.max_concurrency"
+	ViperKeyCourierDispatchRateLimitPerSecond                       = "courier.dispatch.rate_limit.messages_per_second"
+	ViperKeyCourierDispatchRateLimitBurst                           = "courier.dispatch.rate_limit.burst"
+	ViperKeyCourierDispatchBatchSize                                = "courier.dispatch.batch_size"
 	ViperKeySecretsDefault                                          = "secrets.default"
 	ViperKeySecretsCookie                                           = "secrets.cookie"
 	ViperKeyPublicBaseURL                                           = "serve.public.base_url"
 	ViperKeyPublicPort                                              = "serve.public.port"
 	ViperKeyPublicHost                                              = "serve.public.host"
+	ViperKeyPublicTrustedClients                                    = "serve.public.trusted_clients"
+	ViperKeyPublicTrustedProxies                                    = "serve.public.trusted_proxies"
+	ViperKeyPublicClientIPHeader                                    = "serve.public.client_ip_header"
+	ViperKeyPublicCompressionEnabled                                = "serve.public.compression.enabled"
+	ViperKeyPublicCompressionMinSize                                = "serve.public.compression.min_size"
+	ViperKeyEventsHTTPURL                                           = "events.http.url"
+	ViperKeyEventsHTTPSigningSecrets                                = "events.http.signing_secrets"
+	ViperKeyEventsBufferSize                                        = "events.buffer_size"
+	ViperKeyEventsBatchSize                                         = "events.batch_size"
+	ViperKeyEventsFlushInterval                                     = "events.flush_interval"
+	ViperKeyMaintenanceEnabled                                      = "maintenance.enabled"
+	ViperKeyMaintenanceRetryAfter                                   = "maintenance.retry_after"
+	ViperKeyDatabaseCircuitBreakerEnabled                           = "database.circuit_breaker.enabled"
+	ViperKeyDatabaseCircuitBreakerFailureThreshold                  = "database.circuit_breaker.failure_threshold"
+	ViperKeyDatabaseCircuitBreakerCheckInterval                     = "database.circuit_breaker.check_interval"
+	ViperKeyDatabaseCircuitBreakerCheckTimeout                      = "database.circuit_breaker.check_timeout"
+	ViperKeyDatabaseCircuitBreakerOpenDuration                      = "database.circuit_breaker.open_duration"
+	ViperKeyHotReloadEnabled                                        = "hot_reload.enabled"
+	ViperKeyHotReloadInterval                                       = "hot_reload.interval"
 	ViperKeyAdminBaseURL                                            = "serve.admin.base_url"
 	ViperKeyAdminPort                                               = "serve.admin.port"
 	ViperKeyAdminHost                                               = "serve.admin.host"
+	ViperKeyAdminAPIKeysEnabled                                     = "serve.admin.api_keys.enabled"
+	ViperKeyAdminAPIKeysRootKey                                     = "serve.admin.api_keys.root_key"
+	ViperKeyAdminAPIKeysDefaultLifespan                             = "serve.admin.api_keys.default_lifespan"
+	ViperKeyAdminAPIKeysPolicyURL                                   = "serve.admin.api_keys.policy_url"
+	ViperKeyAdminIPFilterAllow                                      = "serve.admin.ip_filter.allow"
+	ViperKeyAdminIPFilterDeny                                       = "serve.admin.ip_filter.deny"
+	ViperKeyAdminIPFilterTrustedProxies                             = "serve.admin.ip_filter.trusted_proxies"
+	ViperKeyAdminIPFilterClientIPHeader                             = "serve.admin.ip_filter.client_ip_header"
+	ViperKeyRedactInternalErrors                                    = "serve.redact_internal_errors"
+	ViperKeyHealthDetailedOutput                                    = "serve.health.detailed_output"
+	ViperKeySecurityHeadersHSTSEnabled                              = "serve.security_headers.hsts.enabled"
+	ViperKeySecurityHeadersHSTSMaxAge                               = "serve.security_headers.hsts.max_age"
+	ViperKeySecurityHeadersHSTSIncludeSubdomains                    = "serve.security_headers.hsts.include_subdomains"
+	ViperKeySecurityHeadersHSTSPreload                              = "serve.security_headers.hsts.preload"
+	ViperKeySecurityHeadersContentTypeOptions                       = "serve.security_headers.content_type_options"
+	ViperKeySecurityHeadersReferrerPolicy                           = "serve.security_headers.referrer_policy"
+	ViperKeySecurityHeadersContentSecurityPolicy                    = "serve.security_headers.content_security_policy"
 	ViperKeySessionLifespan                                         = "session.lifespan"
 	ViperKeySessionSameSite                                         = "session.cookie.same_site"
 	ViperKeySessionDomain                                           = "session.cookie.domain"
 	ViperKeySessionPath                                             = "session.cookie.path"
 	ViperKeySessionPersistentCookie                                 = "session.cookie.persistent"
+	ViperKeySessionCookieMaxChunks                                  = "session.cookie.max_chunks"
+	ViperKeySessionCookiePartitioned                                = "session.cookie.partitioned"
+	ViperKeySessionRefreshEnabled                                   = "session.refresh.enabled"
+	ViperKeySessionRefreshWindow                                    = "session.refresh.window"
+	ViperKeySessionRefreshMaxLifespan                               = "session.refresh.max_lifespan"
+	ViperKeySessionRotationEnabled                                  = "session.rotation.enabled"
+	ViperKeySessionImpersonationEnabled                             = "session.impersonation.enabled"
+	ViperKeySessionImpersonationLifespan                            = "session.impersonation.lifespan"
+	ViperKeySessionGeolocationEnabled                               = "session.geolocation.enabled"
+	ViperKeySessionGeolocationProvider                              = "session.geolocation.provider"
+	ViperKeySessionGeolocationDatabasePath                          = "session.geolocation.database_path"
+	ViperKeySessionFirstLoginFlag                                   = "session.first_login_flag"
+	ViperKeySessionIncludeEntitlements                              = "session.include_entitlements"
+	ViperKeySessionClaimsMapperURL                                  = "session.claims.mapper_url"
+	ViperKeySessionClaimsMaxSize                                    = "session.claims.max_size"
+	ViperKeySessionJWTEnabled                                       = "session.jwt.enabled"
+	ViperKeySessionJWTLifespan                                      = "session.jwt.lifespan"
+	ViperKeySessionJWTSigningKeyURL                                 = "session.jwt.signing_key_url"
+	ViperKeySessionJWTIncludeClaims                                 = "session.jwt.include_claims"
+	ViperKeySessionJWTIncludeEntitlements                           = "session.jwt.include_entitlements"
+	ViperKeyHTTPClientTimeout                                       = "http_client.timeout"
+	ViperKeyHTTPClientRetryMaxAttempts                              = "http_client.retry.max_attempts"
+	ViperKeyHTTPClientRetryBaseDelay                                = "http_client.retry.base_delay"
+	ViperKeyHTTPClientRetryMaxDelay                                 = "http_client.retry.max_delay"
+	ViperKeyHTTPClientProxyURL                                      = "http_client.proxy.url"
+	ViperKeyHTTPClientProxyUsername                                 = "http_client.proxy.username"
+	ViperKeyHTTPClientProxyPassword                                 = "http_client.proxy.password"
+	ViperKeyHTTPClientProxyNoProxy                                  = "http_client.proxy.no_proxy"
 	ViperKeySelfServiceStrategyConfig                               = "selfservice.methods"
 	ViperKeySelfServiceBrowserDefaultReturnTo                       = "selfservice." + DefaultBrowserReturnURL
 	ViperKeyURLsWhitelistedReturnToDomains                          = "selfservice.whitelisted_return_urls"
+	ViperKeySelfServiceClockSkewTolerance                           = "selfservice.clock_skew_tolerance"
+	ViperKeySelfServiceMinimumClientVersionsClientIDHeader          = "selfservice.minimum_client_versions.client_id_header"
+	ViperKeySelfServiceMinimumClientVersionsVersionHeader           = "selfservice.minimum_client_versions.client_version_header"
+	ViperKeySelfServiceMinimumClientVersionsClients                 = "selfservice.minimum_client_versions.clients"
+	ViperKeySelfServiceMinimumClientVersionsDefault                 = "selfservice.minimum_client_versions.default"
+	ViperKeySelfServiceCSRFPerFlow                                  = "selfservice.csrf.per_flow"
+	ViperKeySelfServiceCSRFTrustedOrigins                           = "selfservice.csrf.trusted_origins"
+	ViperKeySelfServiceCSRFTrustedOriginsIncludeCORS                = "selfservice.csrf.trusted_origins_include_cors"
 	ViperKeySelfServiceRegistrationUI                               = "selfservice.flows.registration.ui_url"
 	ViperKeySelfServiceRegistrationRequestLifespan                  = "selfservice.flows.registration.lifespan"
 	ViperKeySelfServiceRegistrationAfter                            = "selfservice.flows.registration.after"
 	ViperKeySelfServiceRegistrationBeforeHooks                      = "selfservice.flows.registration.before.hooks"
+	ViperKeySelfServiceRegistrationAfterRedirectRules               = "selfservice.flows.registration.after.redirect_rules_url"
+	ViperKeySelfServiceRegistrationDefaults                         = "selfservice.flows.registration.defaults_url"
+	ViperKeySelfServiceRegistrationAvailabilityEnabled              = "selfservice.flows.registration.availability_check.enabled"
+	ViperKeySelfServiceRegistrationAvailabilityMaxRequests          = "selfservice.flows.registration.availability_check.max_requests"
+	ViperKeySelfServiceRegistrationAvailabilityWindow               = "selfservice.flows.registration.availability_check.window"
+	ViperKeySelfServiceRegistrationCustomFields                     = "selfservice.flows.registration.custom_fields"
+	ViperKeySelfServiceRegistrationFormTransformURL                 = "selfservice.flows.registration.form_transform_url"
 	ViperKeySelfServiceLoginUI                                      = "selfservice.flows.login.ui_url"
 	ViperKeySelfServiceLoginRequestLifespan                         = "selfservice.flows.login.lifespan"
 	ViperKeySelfServiceLoginAfter                                   = "selfservice.flows.login.after"
 	ViperKeySelfServiceLoginBeforeHooks                             = "selfservice.flows.login.before.hooks"
+	ViperKeySelfServiceRecoveryBeforeHooks                          = "selfservice.flows.recovery.before.hooks"
+	ViperKeySelfServiceVerificationBeforeHooks                      = "selfservice.flows.verification.before.hooks"
+	ViperKeySelfServiceLoginAfterRedirectRules                      = "selfservice.flows.login.after.redirect_rules_url"
+	ViperKeySelfServiceLoginThrottlingEnabled                       = "selfservice.flows.login.throttling.enabled"
+	ViperKeySelfServiceLoginThrottlingMaxAttempts                   = "selfservice.flows.login.throttling.max_attempts"
+	ViperKeySelfServiceLoginThrottlingWindow                        = "selfservice.flows.login.throttling.window"
+	ViperKeySelfServiceLoginThrottlingStore                         = "selfservice.flows.login.throttling.store"
+	ViperKeySelfServiceLoginAttemptLogEnabled                       = "selfservice.flows.login.attempt_log.enabled"
+	ViperKeySelfServiceLoginAttemptLogRetention                     = "selfservice.flows.login.attempt_log.retention"
+	ViperKeySelfServiceLoginAttemptLogCleanupInterval               = "selfservice.flows.login.attempt_log.cleanup_interval"
+	ViperKeySelfServiceLoginAttemptLogBufferSize                    = "selfservice.flows.login.attempt_log.buffer_size"
+	ViperKeySelfServiceLoginAttemptLogBatchSize                     = "selfservice.flows.login.attempt_log.batch_size"
+	ViperKeySelfServiceLoginAttemptLogFlushInterval                 = "selfservice.flows.login.attempt_log.flush_interval"
+	ViperKeySelfServiceLoginRecentActivityEnabled                   = "selfservice.flows.login.recent_activity.enabled"
+	ViperKeySelfServiceLoginRecentActivityIncludeFailed             = "selfservice.flows.login.recent_activity.include_failed"
+	ViperKeySelfServiceLoginRecentActivityLimit                     = "selfservice.flows.login.recent_activity.limit"
+	ViperKeySelfServiceLoginFormTransformURL                        = "selfservice.flows.login.form_transform_url"
 	ViperKeySelfServiceErrorUI                                      = "selfservice.flows.error.ui_url"
+	ViperKeySelfServicePersistSubmittedData                         = "selfservice.flows.persist_submitted_data"
+	ViperKeySelfServiceStructuredValidationErrors                   = "selfservice.flows.structured_validation_errors"
 	ViperKeySelfServiceLogoutBrowserDefaultReturnTo                 = "selfservice.flows.logout.after." + DefaultBrowserReturnURL
+	ViperKeySelfServiceLogoutBackChannelURLs                        = "selfservice.flows.logout.after.back_channel.urls"
+	ViperKeySelfServiceLogoutBackChannelSigningSecrets              = "selfservice.flows.logout.after.back_channel.signing_secrets"
+	ViperKeySelfServiceLogoutBackChannelMaxAttempts                 = "selfservice.flows.logout.after.back_channel.max_attempts"
+	ViperKeySelfServiceLogoutBackChannelRetryDelay                  = "selfservice.flows.logout.after.back_channel.retry_delay"
+	ViperKeySelfServiceLogoutFrontChannelURLs                       = "selfservice.flows.logout.after.front_channel.urls"
+	ViperKeySelfServiceLogoutFrontChannelRedirectDelay              = "selfservice.flows.logout.after.front_channel.redirect_delay"
 	ViperKeySelfServiceSettingsURL                                  = "selfservice.flows.settings.ui_url"
 	ViperKeySelfServiceSettingsAfter                                = "selfservice.flows.settings.after"
 	ViperKeySelfServiceSettingsRequestLifespan                      = "selfservice.flows.settings.lifespan"
 	ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter        = "selfservice.flows.settings.privileged_session_max_age"
+	ViperKeySelfServiceSettingsPrivilegedChangesCredentials         = "selfservice.flows.settings.privileged_changes.credentials"
+	ViperKeySelfServiceSettingsPrivilegedChangesTraits              = "selfservice.flows.settings.privileged_changes.traits"
+	ViperKeySelfServiceSettingsFormTransformURL                     = "selfservice.flows.settings.form_transform_url"
 	ViperKeySelfServiceRecoveryEnabled                              = "selfservice.flows.recovery.enabled"
 	ViperKeySelfServiceRecoveryUI                                   = "selfservice.flows.recovery.ui_url"
 	ViperKeySelfServiceRecoveryRequestLifespan                      = "selfservice.flows.recovery.lifespan"
+	ViperKeySelfServiceRecoveryResponseJitterMin                    = "selfservice.flows.recovery.response_jitter.min"
+	ViperKeySelfServiceRecoveryResponseJitterMax                    = "selfservice.flows.recovery.response_jitter.max"
+	ViperKeySelfServiceRecoveryAdminLinkLifespan                    = "selfservice.flows.recovery.admin_link.lifespan"
+	ViperKeySelfServiceRecoveryAdminLinkMaxLifespan                 = "selfservice.flows.recovery.admin_link.max_lifespan"
+	ViperKeySelfServiceRecoveryAdminLinkMaxRequests                 = "selfservice.flows.recovery.admin_link.max_requests"
+	ViperKeySelfServiceRecoveryAdminLinkWindow                      = "selfservice.flows.recovery.admin_link.window"
+	ViperKeySelfServiceRecoveryMaxActiveTokens                      = "selfservice.flows.recovery.max_active_tokens"
+	ViperKeySelfServiceRecoveryDeduplicationWindow                  = "selfservice.flows.recovery.deduplication_window"
 	ViperKeySelfServiceRecoveryBrowserDefaultReturnTo               = "selfservice.flows.recovery.after." + DefaultBrowserReturnURL
 	ViperKeySelfServiceVerificationEnabled                          = "selfservice.flows.verification.enabled"
 	ViperKeySelfServiceVerificationUI                               = "selfservice.flows.verification.ui_url"
 	ViperKeySelfServiceVerificationRequestLifespan                  = "selfservice.flows.verification.lifespan"
+	ViperKeySelfServiceVerificationMaxActiveTokens                  = "selfservice.flows.verification.max_active_tokens"
 	ViperKeySelfServiceVerificationBrowserDefaultReturnTo           = "selfservice.flows.verification.after." + DefaultBrowserReturnURL
 	ViperKeyDefaultIdentitySchemaURL                                = "identity.default_schema_url"
+	ViperKeyDefaultIdentitySchemaVersion                            = "identity.default_schema_version"
+	ViperKeyDefaultIdentitySchemaChecksum                           = "identity.default_schema_checksum"
+	ViperKeyDefaultIdentitySchemaHistory                            = "identity.default_schema_history"
+	ViperKeyDefaultIdentitySchemaMaxTraitsSize                      = "identity.default_schema_max_traits_size"
+	ViperKeyDefaultIdentitySchemaVerificationEnforcement            = "identity.default_schema_verification_enforcement"
+	ViperKeyDefaultIdentitySchemaEmailChangeCooldown                = "identity.default_schema_email_change_cooldown"
+	ViperKeyDefaultIdentitySchemaMaxLinkedOIDCProviders             = "identity.default_schema_max_linked_oidc_providers"
+	ViperKeyIdentitySchemaHistoryMaxVersions                        = "identity.schema_history_max_versions"
+	ViperKeyIdentitySchemaRefreshInterval                           = "identity.schema_refresh_interval"
+	ViperKeyIdentitySchemaRegistryBaseURL                           = "identity.schema_registry.base_url"
+	ViperKeyIdentityTraitsTransform                                 = "identity.traits_transform"
 	ViperKeyIdentitySchemas                                         = "identity.schemas"
+	ViperKeyIdentityDeletionGracePeriod                             = "identity.deletion.grace_period"
+	ViperKeyIdentityDeletionPurgeInterval                           = "identity.deletion.purge_interval"
+	ViperKeyIdentityAuditRedactTraits                               = "identity.audit.redact_traits"
+	ViperKeyIdentityCredentialIdentifierHistoryEnabled              = "identity.credential_identifier_history.enabled"
+	ViperKeyIdentityCredentialIdentifierHistoryRetention            = "identity.credential_identifier_history.retention"
+	ViperKeyIdentityPartitionsEnabled                               = "identity.partitions.enabled"
+	ViperKeyIdentityPartitionsAdminHeader                           = "identity.partitions.admin_header"
+	ViperKeyIdentityPartitionsPublicHosts                           = "identity.partitions.public_hosts"
+	ViperKeyIdentityMergeConflictPolicy                             = "identity.merge.conflict_policy"
 	ViperKeyHasherArgon2ConfigMemory                                = "hashers.argon2.memory"
 	ViperKeyHasherArgon2ConfigIterations                            = "hashers.argon2.iterations"
 	ViperKeyHasherArgon2ConfigParallelism                           = "hashers.argon2.parallelism"
@@ -84,6 +241,10 @@ const (
 	ViperKeyHasherArgon2ConfigKeyLength                             = "hashers.argon2.key_length"
 	ViperKeyPasswordMaxBreaches                                     = "password.max_breaches"
 	ViperKeyIgnoreNetworkErrors                                     = "password.ignore_network_errors"
+	ViperKeyPasswordBlocklistPath                                   = "password.blocklist.path"
+	ViperKeyPasswordBlocklistPasswords                              = "password.blocklist.passwords"
+	ViperKeyPasswordStrengthEnabled                                 = "password.strength.enabled"
+	ViperKeyPasswordStrengthMinScore                                = "password.strength.min_score"
 	ViperKeyVersion                                                 = "version"
 	Argon2DefaultMemory                                      uint32 = 4 * 1024 * 1024
 	Argon2DefaultIterations                                  uint32 = 4
@@ -91,6 +252,42 @@ const (
 	Argon2DefaultKeyLength                                   uint32 = 32
 )
 
+const (
+	PersistSubmittedDataAlways         = "always"
+	PersistSubmittedDataUntilCompleted = "until_completed"
+)
+
+const (
+	HookModeBlocking = "blocking"
+	HookModeAsync    = "async"
+)
+
+const (
+	LoginThrottlingStoreMemory   = "memory"
+	LoginThrottlingStoreDatabase = "database"
+)
+
+const (
+	MinimumClientVersionsDefaultAllow = "allow"
+	MinimumClientVersionsDefaultWarn  = "warn"
+)
+
+const GeolocationProviderMaxMind = "maxmind"
+
+const (
+	SettingsGatePrivilegedSession = "privileged_session"
+	SettingsGateSession           = "session"
+)
+
+const DefaultVerificationEnforcementGracePeriod = 72 * time.Hour
+
+const (
+	VerificationEnforcementNone       = "none"
+	VerificationEnforcementWarn       = "warn"
+	VerificationEnforcementRestrict   = "restrict"
+	VerificationEnforcementDeactivate = "deactivate"
+)
+
 type (
 	HasherArgon2Config struct {
 		Memory      uint32 `json:"memory"`
@@ -102,18 +299,184 @@ type (
 	SelfServiceHook struct {
 		Name   string          `json:"hook"`
 		Config json.RawMessage `json:"config"`
+		// Mode is either HookModeBlocking or HookModeAsync. Hooks are blocking if no mode is set.
+		Mode string `json:"mode,omitempty"`
+	}
+	SelfServiceCustomField struct {
+		Name      string `json:"name"`
+		Type      string `json:"type"`
+		Required  bool   `json:"required"`
+		Pattern   string `json:"pattern"`
+		MaxLength int    `json:"max_length"`
 	}
 	SelfServiceStrategy struct {
 		Enabled bool            `json:"enabled"`
 		Config  json.RawMessage `json:"config"`
 	}
 	SchemaConfig struct {
-		ID  string `json:"id"`
-		URL string `json:"url"`
+		ID            string                `json:"id"`
+		URL           string                `json:"url"`
+		Version       string                `json:"version"`
+		Checksum      string                `json:"checksum"`
+		History       []SchemaVersionConfig `json:"history"`
+		MaxTraitsSize int                   `json:"max_traits_size"`
+
+		VerificationEnforcement VerificationEnforcementConfig `json:"verification_enforcement"`
+
+		// EmailChangeCooldown is a duration such as "24h" during which an identity can not change its email
+		// addresses again using the settings flow. The cooldown is disabled if it is empty.
+		EmailChangeCooldown string `json:"email_change_cooldown"`
+
+		// MaxLinkedOIDCProviders is the maximum number of OpenID Connect providers an identity can link. Zero
+		// falls back to the limit of the OpenID Connect method.
+		MaxLinkedOIDCProviders int `json:"max_linked_oidc_providers"`
+	}
+	VerificationEnforcementConfig struct {
+		// Action is one of VerificationEnforcementNone, VerificationEnforcementWarn,
+		// VerificationEnforcementRestrict, or VerificationEnforcementDeactivate.
+		Action string `json:"action"`
+		// GracePeriod is a duration such as "72h". DefaultVerificationEnforcementGracePeriod is used if it is empty.
+		GracePeriod string `json:"grace_period"`
+	}
+	SchemaVersionConfig struct {
+		Version  string `json:"version"`
+		URL      string `json:"url"`
+		Checksum string `json:"checksum"`
+	}
+	TraitsTransformConfig struct {
+		Rules                     []TraitsTransformRule `json:"rules"`
+		DefaultCountryCallingCode string                `json:"default_country_calling_code"`
+		MapperURL                 string                `json:"mapper_url"`
+	}
+	TraitsTransformRule struct {
+		Path       string   `json:"path"`
+		Transforms []string `json:"transforms"`
 	}
 	PasswordPolicyConfig struct {
-		MaxBreaches         uint `json:"max_breaches"`
-		IgnoreNetworkErrors bool `json:"ignore_network_errors"`
+		MaxBreaches         uint     `json:"max_breaches"`
+		IgnoreNetworkErrors bool     `json:"ignore_network_errors"`
+		BlocklistPath       string   `json:"blocklist_path"`
+		BlocklistPasswords  []string `json:"blocklist_passwords"`
+		StrengthEnabled     bool     `json:"strength_enabled"`
+		StrengthMinScore    int      `json:"strength_min_score"`
+	}
+	IdentityPartitionsConfig struct {
+		Enabled bool `json:"enabled"`
+		// AdminHeader is the request header the admin API reads the partition from.
+		AdminHeader string `json:"admin_header"`
+		// PublicHosts maps the host names of the public API to partitions.
+		PublicHosts []IdentityPartitionHost `json:"public_hosts"`
+	}
+	IdentityPartitionHost struct {
+		Host        string `json:"host"`
+		PartitionID string `json:"partition_id"`
+	}
+	IPFilterConfig struct {
+		ClientIPConfig
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+	}
+	ClientIPConfig struct {
+		TrustedProxies []string `json:"trusted_proxies"`
+		ClientIPHeader string   `json:"client_ip_header"`
+	}
+	SecurityHeadersConfig struct {
+		// HSTSEnabled is false if ORY Kratos runs in development mode.
+		HSTSEnabled           bool          `json:"hsts_enabled"`
+		HSTSMaxAge            time.Duration `json:"hsts_max_age"`
+		HSTSIncludeSubdomains bool          `json:"hsts_include_subdomains"`
+		HSTSPreload           bool          `json:"hsts_preload"`
+		ContentTypeOptions    bool          `json:"content_type_options"`
+		ReferrerPolicy        string        `json:"referrer_policy"`
+		ContentSecurityPolicy string        `json:"content_security_policy"`
+	}
+	DatabaseCircuitBreakerConfig struct {
+		Enabled bool `json:"enabled"`
+		// FailureThreshold is the number of consecutive failed checks which open the breaker.
+		FailureThreshold int `json:"failure_threshold"`
+		// CheckInterval is the minimum time between two checks while the breaker is closed.
+		CheckInterval time.Duration `json:"check_interval"`
+		CheckTimeout  time.Duration `json:"check_timeout"`
+		// OpenDuration is the time after which an open breaker checks whether the database recovered.
+		OpenDuration time.Duration `json:"open_duration"`
+	}
+	LoginThrottlingConfig struct {
+		Enabled     bool          `json:"enabled"`
+		MaxAttempts int           `json:"max_attempts"`
+		Window      time.Duration `json:"window"`
+		// Store is either LoginThrottlingStoreMemory or LoginThrottlingStoreDatabase.
+		Store string `json:"store"`
+	}
+	LoginAttemptLogConfig struct {
+		Enabled bool `json:"enabled"`
+		// Retention is the time after which login attempts are deleted. Zero keeps them forever.
+		Retention       time.Duration `json:"retention"`
+		CleanupInterval time.Duration `json:"cleanup_interval"`
+		// BufferSize is the number of login attempts which are queued before further attempts are dropped.
+		BufferSize    int           `json:"buffer_size"`
+		BatchSize     int           `json:"batch_size"`
+		FlushInterval time.Duration `json:"flush_interval"`
+	}
+	LoginRecentActivityConfig struct {
+		Enabled bool `json:"enabled"`
+		// IncludeFailed adds failed login attempts of the identity to the recent activity.
+		IncludeFailed bool `json:"include_failed"`
+		// Limit is the maximum number of login attempts returned.
+		Limit int `json:"limit"`
+	}
+	CourierTemplateContextConfig struct {
+		// IdentityTraits adds the traits of the recipient's identity to the template context.
+		IdentityTraits bool `json:"identity_traits"`
+		// RequestMetadata adds the client IP address and its location to the template context.
+		RequestMetadata bool `json:"request_metadata"`
+	}
+	SessionGeolocationConfig struct {
+		Enabled bool `json:"enabled"`
+		// Provider is currently always GeolocationProviderMaxMind.
+		Provider     string `json:"provider"`
+		DatabasePath string `json:"database_path"`
+	}
+	SettingsPrivilegedChangesConfig struct {
+		// Credentials maps credential types to SettingsGatePrivilegedSession or SettingsGateSession. Credential
+		// types which are not listed require a privileged session.
+		Credentials map[string]string `json:"credentials"`
+
+		// Traits contains the traits, in dot notation, which may only be changed using a privileged session.
+		Traits []string `json:"traits"`
+	}
+	MinimumClientVersion struct {
+		ID             string `json:"id"`
+		MinimumVersion string `json:"minimum_version"`
+	}
+	MinimumClientVersionsConfig struct {
+		ClientIDHeader      string                 `json:"client_id_header"`
+		ClientVersionHeader string                 `json:"client_version_header"`
+		Clients             []MinimumClientVersion `json:"clients"`
+		// Default is either MinimumClientVersionsDefaultAllow or MinimumClientVersionsDefaultWarn.
+		Default string `json:"default"`
+	}
+	TrustedClientsConfig struct {
+		// Origins contains the origins of the public base URL and of all trusted clients.
+		Origins []string `json:"origins"`
+
+		// Domain is the parent domain shared by the public base URL and all trusted clients. It is empty
+		// if they all use the same host or if they do not share a parent domain.
+		Domain string `json:"domain"`
+
+		// CrossSite is true if a trusted client does not share a parent domain with the public base URL.
+		CrossSite bool `json:"cross_site"`
+	}
+	CourierSMTPTLS struct {
+		MinVersion      string   `json:"min_version"`
+		CipherSuites    []string `json:"cipher_suites"`
+		RequireStartTLS bool     `json:"require_starttls"`
+		CAPath          string   `json:"ca_path"`
+		CertPath        string   `json:"cert_path"`
+		KeyPath         string   `json:"key_path"`
+	}
+	CourierSMTPSender struct {
+		FromAddress string `json:"from_address"`
+		FromName    string `json:"from_name"`
 	}
 	SchemaConfigs []SchemaConfig
 	Provider      struct {
@@ -142,6 +505,22 @@ func (s SchemaConfigs) FindSchemaByID(id string) (*SchemaConfig, error) {
 	return nil, errors.Errorf("could not find schema with id \"%s\"", id)
 }
 
+// redactedConfigKeys lists the keys which are redacted in the configuration dump. A "*" matches all
+// elements of an array.
+var redactedConfigKeys = []string{
+	ViperKeyDSN,
+	"secrets",
+	ViperKeyCourierSMTPURL,
+	ViperKeyAdminAPIKeysRootKey,
+	ViperKeySessionJWTSigningKeyURL,
+	ViperKeyHTTPClientProxyURL,
+	ViperKeyHTTPClientProxyPassword,
+	ViperKeyEventsHTTPSigningSecrets,
+	ViperKeySelfServiceLogoutBackChannelSigningSecrets,
+	"selfservice.methods.oidc.config.providers.*.client_secret",
+	"selfservice.methods.oidc.config.providers.*.headers",
+}
+
 func MustNew(l *logrusx.Logger, opts ...configx.OptionModifier) *Provider {
 	p, err := New(l, opts...)
 	if err != nil {
@@ -181,6 +560,71 @@ func (p *Provider) Source() *configx.Provider {
 	return p.p
 }
 
+// RedactedConfig returns the effective configuration as merged from the schema defaults, the config files,
+// the flags, and the environment variables. Secrets, such as the DSN or the cookie secrets, are redacted.
+func (p *Provider) RedactedConfig() (json.RawMessage, error) {
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var c interface{}
+	if err := json.Unmarshal(out, &c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, key := range redactedConfigKeys {
+		c = redactConfigValue(c, strings.Split(key, "."))
+	}
+
+	out, err = json.Marshal(c)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+func redactConfigValue(value interface{}, path []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if len(path) == 0 || path[0] == key {
+				v[key] = redactConfigValue(child, tail(path))
+			}
+		}
+		return v
+	case []interface{}:
+		for k, child := range v {
+			if len(path) == 0 || path[0] == "*" {
+				v[k] = redactConfigValue(child, tail(path))
+			}
+		}
+		return v
+	case string:
+		if v == "" {
+			return v
+		}
+	case nil:
+		return v
+	}
+
+	if len(path) > 0 {
+		return value
+	}
+	return RedactedConfigValue
+}
+
+func tail(path []string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return path[1:]
+}
+
+func (p *Provider) IsConfigDumpEnabled() bool {
+	return p.Source().Bool(ViperKeyDumpConfig)
+}
+
 func (p *Provider) CORS(iface string) (cors.Options, bool) {
 	switch iface {
 	case "admin":
@@ -212,7 +656,71 @@ func (p *Provider) MustSet(key string, value interface{}) {
 }
 
 func (p *Provider) SessionDomain() string {
-	return p.p.String(ViperKeySessionDomain)
+	if domain := p.p.String(ViperKeySessionDomain); domain != "" {
+		// Browsers ignore a leading dot in the cookie domain.
+		return strings.TrimPrefix(strings.ToLower(domain), ".")
+	}
+
+	if tc, err := p.TrustedClients(); err == nil {
+		return tc.Domain
+	}
+	return ""
+}
+
+// ValidateSessionDomain returns an error if the session cookie domain is an IP address, a public suffix such as
+// `com` or `co.uk`, or does not contain the host of the public base URL. Browsers either reject such cookies or
+// would send them to other sites. It also warns about UI URLs which do not receive the session cookie.
+func (p *Provider) ValidateSessionDomain() error {
+	public := strings.ToLower(p.SelfPublicURL().Hostname())
+	domain := p.SessionDomain()
+	if domain != "" {
+		if net.ParseIP(domain) != nil {
+			return errors.Errorf("%s must be a domain name but got the IP address: %s", ViperKeySessionDomain, domain)
+		}
+
+		if isPublicSuffix(domain) {
+			return errors.Errorf("%s must not be a public suffix such as \"com\" or \"co.uk\" because the session cookie would be sent to other sites but got: %s", ViperKeySessionDomain, domain)
+		}
+
+		if !domainMatches(public, domain) {
+			return errors.Errorf("%s must be the host of the public base URL \"%s\" or one of its parent domains but got: %s", ViperKeySessionDomain, public, domain)
+		}
+	}
+
+	for _, ui := range p.selfServiceUIs() {
+		host := strings.ToLower(ui.Hostname())
+		if host == "" || host == public {
+			continue
+		}
+
+		if domain != "" && !domainMatches(host, domain) {
+			p.l.Warnf("The UI at %s is not part of the session cookie domain %s and will not receive the session cookie.", ui, domain)
+		} else if domain == "" && sameRegistrableDomain(host, public) {
+			p.l.Warnf("The UI at %s is on another subdomain than the public base URL %s. Set %s to their shared parent domain to use the session cookie on both.", ui, public, ViperKeySessionDomain)
+		}
+	}
+
+	return nil
+}
+
+// isPublicSuffix returns true if cookies for the domain would be shared by unrelated sites. Single labels which
+// are not listed in the public suffix list, such as `localhost`, are allowed.
+func isPublicSuffix(domain string) bool {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	return suffix == domain && (icann || strings.Contains(domain, "."))
+}
+
+func domainMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func sameRegistrableDomain(a, b string) bool {
+	ra, err := publicsuffix.EffectiveTLDPlusOne(a)
+	if err != nil {
+		return false
+	}
+	rb, err := publicsuffix.EffectiveTLDPlusOne(b)
+	return err == nil && ra == rb
 }
 
 func (p *Provider) SessionPath() string {
@@ -251,9 +759,20 @@ func (p *Provider) DefaultIdentityTraitsSchemaURL() *url.URL {
 
 func (p *Provider) IdentityTraitsSchemas() SchemaConfigs {
 	ds := SchemaConfig{
-		ID:  DefaultIdentityTraitsSchemaID,
-		URL: p.DefaultIdentityTraitsSchemaURL().String(),
+		ID:            DefaultIdentityTraitsSchemaID,
+		URL:           p.DefaultIdentityTraitsSchemaURL().String(),
+		Version:       p.p.String(ViperKeyDefaultIdentitySchemaVersion),
+		Checksum:      p.p.String(ViperKeyDefaultIdentitySchemaChecksum),
+		History:       p.identitySchemaHistory(ViperKeyDefaultIdentitySchemaHistory),
+		MaxTraitsSize: p.p.Int(ViperKeyDefaultIdentitySchemaMaxTraitsSize),
+		VerificationEnforcement: VerificationEnforcementConfig{
+			Action:      p.p.StringF(ViperKeyDefaultIdentitySchemaVerificationEnforcement+".action", VerificationEnforcementNone),
+			GracePeriod: p.p.String(ViperKeyDefaultIdentitySchemaVerificationEnforcement + ".grace_period"),
+		},
+		EmailChangeCooldown:    p.p.String(ViperKeyDefaultIdentitySchemaEmailChangeCooldown),
+		MaxLinkedOIDCProviders: p.p.Int(ViperKeyDefaultIdentitySchemaMaxLinkedOIDCProviders),
 	}
+	ds.History = p.limitSchemaHistory(ds.History)
 
 	if !p.p.Exists(ViperKeyIdentitySchemas) {
 		return SchemaConfigs{ds}
@@ -276,13 +795,165 @@ func (p *Provider) IdentityTraitsSchemas() SchemaConfigs {
 		return SchemaConfigs{ds}
 	}
 
+	for k := range ss {
+		ss[k].History = p.limitSchemaHistory(ss[k].History)
+	}
+
 	return append(ss, ds)
 }
 
+func (p *Provider) identitySchemaHistory(key string) []SchemaVersionConfig {
+	if !p.p.Exists(key) {
+		return nil
+	}
+
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from %s.", key)
+		return nil
+	}
+
+	config := gjson.GetBytes(out, key).Raw
+	if len(config) == 0 {
+		return nil
+	}
+
+	var history []SchemaVersionConfig
+	if err := json.NewDecoder(bytes.NewBufferString(config)).Decode(&history); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode values from %s.", key)
+		return nil
+	}
+
+	return history
+}
+
+// limitSchemaHistory keeps only the most recent historical schema versions. The history is
+// expected to be ordered from the most recent to the oldest version.
+func (p *Provider) limitSchemaHistory(history []SchemaVersionConfig) []SchemaVersionConfig {
+	if max := p.IdentitySchemaHistoryMaxVersions(); len(history) > max {
+		return history[:max]
+	}
+	return history
+}
+
+func (p *Provider) IdentitySchemaHistoryMaxVersions() int {
+	if max := p.p.IntF(ViperKeyIdentitySchemaHistoryMaxVersions, 3); max > 0 {
+		return max
+	}
+	return 0
+}
+
+// IdentitySchemaRefreshInterval returns the interval in which remote identity schemas are fetched again.
+// Refreshing is disabled if it is zero.
+func (p *Provider) IdentitySchemaRefreshInterval() time.Duration {
+	return p.p.DurationF(ViperKeyIdentitySchemaRefreshInterval, 0)
+}
+
+// IdentitySchemaRegistryURL returns the base URL against which `registry://` references in identity schemas are
+// resolved, or nil if no schema registry is configured.
+func (p *Provider) IdentitySchemaRegistryURL() *url.URL {
+	if p.p.String(ViperKeyIdentitySchemaRegistryBaseURL) == "" {
+		return nil
+	}
+	return p.parseURIOrFail(ViperKeyIdentitySchemaRegistryBaseURL)
+}
+
+// IdentityTraitsTransform returns the transformations which are applied to traits submitted in the
+// registration and settings flows.
+func (p *Provider) IdentityTraitsTransform() *TraitsTransformConfig {
+	c := &TraitsTransformConfig{Rules: []TraitsTransformRule{}}
+	if !p.p.Exists(ViperKeyIdentityTraitsTransform) {
+		return c
+	}
+
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeyIdentityTraitsTransform)
+	}
+
+	config := gjson.GetBytes(out, ViperKeyIdentityTraitsTransform).Raw
+	if len(config) == 0 {
+		return c
+	} else if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(c); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeyIdentityTraitsTransform)
+	}
+
+	return c
+}
+
+// IdentityDeletionGracePeriod returns the time after which identities scheduled for deletion are deleted
+// permanently. If zero, identities are deleted immediately.
+func (p *Provider) IdentityDeletionGracePeriod() time.Duration {
+	return p.p.DurationF(ViperKeyIdentityDeletionGracePeriod, 0)
+}
+
+func (p *Provider) IdentityDeletionPurgeInterval() time.Duration {
+	return p.p.DurationF(ViperKeyIdentityDeletionPurgeInterval, time.Hour)
+}
+
+// IdentityAuditRedactTraits returns true if the values of changed traits and addresses must not be written
+// to the audit log.
+func (p *Provider) IdentityAuditRedactTraits() bool {
+	return p.p.BoolF(ViperKeyIdentityAuditRedactTraits, true)
+}
+
+// IdentityCredentialIdentifierHistoryEnabled returns true if identifiers removed from an identity's credentials
+// should be kept for searches.
+func (p *Provider) IdentityCredentialIdentifierHistoryEnabled() bool {
+	return p.p.Bool(ViperKeyIdentityCredentialIdentifierHistoryEnabled)
+}
+
+// IdentityCredentialIdentifierHistoryRetention returns how long historical identifiers are kept. Zero means they
+// are kept until the identity is deleted.
+func (p *Provider) IdentityCredentialIdentifierHistoryRetention() time.Duration {
+	return p.p.DurationF(ViperKeyIdentityCredentialIdentifierHistoryRetention, time.Hour*24*365)
+}
+
+// IdentityMergeConflictPolicy returns how credentials of the same type are resolved when merging identities
+// unless the merge request sets a policy.
+func (p *Provider) IdentityMergeConflictPolicy() string {
+	return p.p.StringF(ViperKeyIdentityMergeConflictPolicy, "fail")
+}
+
+// IdentityPartitions returns the configuration used to resolve the identity partition of a request.
+func (p *Provider) IdentityPartitions() *IdentityPartitionsConfig {
+	c := &IdentityPartitionsConfig{
+		Enabled:     p.p.Bool(ViperKeyIdentityPartitionsEnabled),
+		AdminHeader: p.p.StringF(ViperKeyIdentityPartitionsAdminHeader, "X-Kratos-Partition"),
+		PublicHosts: []IdentityPartitionHost{},
+	}
+
+	if !p.p.Exists(ViperKeyIdentityPartitionsPublicHosts) {
+		return c
+	}
+
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeyIdentityPartitionsPublicHosts)
+	}
+
+	config := gjson.GetBytes(out, ViperKeyIdentityPartitionsPublicHosts).Raw
+	if len(config) == 0 {
+		return c
+	} else if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&c.PublicHosts); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeyIdentityPartitionsPublicHosts)
+	}
+
+	return c
+}
+
 func (p *Provider) AdminListenOn() string {
 	return p.listenOn("admin")
 }
 
+func (p *Provider) PublicCompressionEnabled() bool {
+	return p.p.Bool(ViperKeyPublicCompressionEnabled)
+}
+
+func (p *Provider) PublicCompressionMinSize() int {
+	return p.p.IntF(ViperKeyPublicCompressionMinSize, 1024)
+}
+
 func (p *Provider) PublicListenOn() string {
 	return p.listenOn("public")
 }
@@ -326,6 +997,14 @@ func (p *Provider) SelfServiceFlowRegistrationBeforeHooks() []SelfServiceHook {
 	return p.selfServiceHooks(ViperKeySelfServiceRegistrationBeforeHooks)
 }
 
+func (p *Provider) SelfServiceFlowRecoveryBeforeHooks() []SelfServiceHook {
+	return p.selfServiceHooks(ViperKeySelfServiceRecoveryBeforeHooks)
+}
+
+func (p *Provider) SelfServiceFlowVerificationBeforeHooks() []SelfServiceHook {
+	return p.selfServiceHooks(ViperKeySelfServiceVerificationBeforeHooks)
+}
+
 func (p *Provider) selfServiceHooks(key string) []SelfServiceHook {
 	var hooks []SelfServiceHook
 	if !p.p.Exists(key) {
@@ -355,6 +1034,23 @@ func (p *Provider) selfServiceHooks(key string) []SelfServiceHook {
 	return hooks
 }
 
+// SelfServiceAsyncHooksMaxAttempts returns how often a hook with `mode: async` is executed before it is
+// marked as failed.
+func (p *Provider) SelfServiceAsyncHooksMaxAttempts() int {
+	return p.p.IntF(ViperKeySelfServiceAsyncHooksMaxAttempts, 5)
+}
+
+// SelfServiceAsyncHooksRetryBackoff returns the delay before the first retry of a failed asynchronous hook.
+// The delay doubles with every further attempt.
+func (p *Provider) SelfServiceAsyncHooksRetryBackoff() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceAsyncHooksRetryBackoff, 30*time.Second)
+}
+
+// SelfServiceAsyncHooksPollInterval returns how often the background worker checks for queued asynchronous hooks.
+func (p *Provider) SelfServiceAsyncHooksPollInterval() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceAsyncHooksPollInterval, 5*time.Second)
+}
+
 func (p *Provider) SelfServiceFlowLoginAfterHooks(strategy string) []SelfServiceHook {
 	return p.selfServiceHooks(HookStrategyKey(ViperKeySelfServiceLoginAfter, strategy))
 }
@@ -437,6 +1133,41 @@ func (p *Provider) SelfServiceBrowserDefaultReturnTo() *url.URL {
 	return p.parseURIOrFail(ViperKeySelfServiceBrowserDefaultReturnTo)
 }
 
+// SelfServiceClockSkewTolerance returns how long flows, self-service tokens, and OpenID Connect ID tokens are
+// accepted after they expired to compensate for clock drift.
+func (p *Provider) SelfServiceClockSkewTolerance() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceClockSkewTolerance, 5*time.Second)
+}
+
+// SelfServiceMinimumClientVersions returns the minimum versions of native apps which may use the self-service
+// flows and the policy for requests whose client or version is unknown.
+func (p *Provider) SelfServiceMinimumClientVersions() *MinimumClientVersionsConfig {
+	c := &MinimumClientVersionsConfig{
+		ClientIDHeader:      p.p.StringF(ViperKeySelfServiceMinimumClientVersionsClientIDHeader, "X-Kratos-Client-Id"),
+		ClientVersionHeader: p.p.StringF(ViperKeySelfServiceMinimumClientVersionsVersionHeader, "X-Kratos-Client-Version"),
+		Clients:             []MinimumClientVersion{},
+		Default:             p.p.StringF(ViperKeySelfServiceMinimumClientVersionsDefault, MinimumClientVersionsDefaultAllow),
+	}
+
+	if !p.p.Exists(ViperKeySelfServiceMinimumClientVersionsClients) {
+		return c
+	}
+
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceMinimumClientVersionsClients)
+	}
+
+	config := gjson.GetBytes(out, ViperKeySelfServiceMinimumClientVersionsClients).Raw
+	if len(config) == 0 {
+		return c
+	} else if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&c.Clients); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceMinimumClientVersionsClients)
+	}
+
+	return c
+}
+
 func (p *Provider) guessBaseURL(keyHost, keyPort string, defaultPort int) *url.URL {
 	port := p.p.IntF(keyPort, defaultPort)
 
@@ -485,6 +1216,202 @@ func (p *Provider) SelfAdminURL() *url.URL {
 	return p.baseURL(ViperKeyAdminBaseURL, ViperKeyAdminHost, ViperKeyAdminPort, 4434)
 }
 
+func (p *Provider) AdminAPIKeysEnabled() bool {
+	return p.p.Bool(ViperKeyAdminAPIKeysEnabled)
+}
+
+func (p *Provider) AdminAPIKeysRootKey() string {
+	return p.p.String(ViperKeyAdminAPIKeysRootKey)
+}
+
+func (p *Provider) AdminAPIKeysDefaultLifespan() time.Duration {
+	return p.p.DurationF(ViperKeyAdminAPIKeysDefaultLifespan, time.Hour*24*90)
+}
+
+// AdminAPIKeysPolicyURL returns an empty string when no admin API key policy is configured.
+func (p *Provider) AdminAPIKeysPolicyURL() string {
+	return p.p.String(ViperKeyAdminAPIKeysPolicyURL)
+}
+
+func (p *Provider) AdminIPFilter() *IPFilterConfig {
+	return &IPFilterConfig{
+		ClientIPConfig: ClientIPConfig{
+			TrustedProxies: p.p.Strings(ViperKeyAdminIPFilterTrustedProxies),
+			ClientIPHeader: p.p.StringF(ViperKeyAdminIPFilterClientIPHeader, "X-Forwarded-For"),
+		},
+		Allow: p.p.Strings(ViperKeyAdminIPFilterAllow),
+		Deny:  p.p.Strings(ViperKeyAdminIPFilterDeny),
+	}
+}
+
+// PublicClientIP returns the proxies whose client IP header is honored for requests to the public API.
+func (p *Provider) PublicClientIP() *ClientIPConfig {
+	return &ClientIPConfig{
+		TrustedProxies: p.p.Strings(ViperKeyPublicTrustedProxies),
+		ClientIPHeader: p.p.StringF(ViperKeyPublicClientIPHeader, "X-Forwarded-For"),
+	}
+}
+
+func (p *Provider) SecurityHeaders() *SecurityHeadersConfig {
+	return &SecurityHeadersConfig{
+		HSTSEnabled:           p.p.BoolF(ViperKeySecurityHeadersHSTSEnabled, true) && !p.IsInsecureDevMode(),
+		HSTSMaxAge:            p.p.DurationF(ViperKeySecurityHeadersHSTSMaxAge, time.Hour*24*365),
+		HSTSIncludeSubdomains: p.p.Bool(ViperKeySecurityHeadersHSTSIncludeSubdomains),
+		HSTSPreload:           p.p.Bool(ViperKeySecurityHeadersHSTSPreload),
+		ContentTypeOptions:    p.p.BoolF(ViperKeySecurityHeadersContentTypeOptions, true),
+		ReferrerPolicy:        p.p.StringF(ViperKeySecurityHeadersReferrerPolicy, "no-referrer"),
+		ContentSecurityPolicy: p.p.StringF(ViperKeySecurityHeadersContentSecurityPolicy, "default-src 'none'; frame-ancestors 'none'"),
+	}
+}
+
+// HealthDetailedOutput returns true if the readiness and version endpoints return detailed information.
+func (p *Provider) HealthDetailedOutput() bool {
+	return p.p.Bool(ViperKeyHealthDetailedOutput)
+}
+
+// RedactInternalErrors returns true if the details of unexpected errors must not be returned to clients. Errors
+// are never redacted in dev mode.
+func (p *Provider) RedactInternalErrors() bool {
+	return p.p.Bool(ViperKeyRedactInternalErrors) && !p.IsInsecureDevMode()
+}
+
+// TrustedClients returns the first-party clients configured in `serve.public.trusted_clients` and the cookie
+// scope derived from them. Returns an error if an entry is not a valid origin or if cross-site clients are
+// configured without HTTPS.
+func (p *Provider) TrustedClients() (*TrustedClientsConfig, error) {
+	public := p.SelfPublicURL()
+	tc := &TrustedClientsConfig{Origins: []string{origin(public)}}
+
+	values := p.p.Strings(ViperKeyPublicTrustedClients)
+	if len(values) == 0 {
+		return tc, nil
+	}
+
+	hosts := []string{public.Hostname()}
+	secure := public.Scheme == "https"
+	for k, v := range values {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse trusted client \"%s.%d\"", ViperKeyPublicTrustedClients, k)
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return nil, errors.Errorf("trusted client \"%s.%d\" must be an origin such as https://app.example.com but got: %s", ViperKeyPublicTrustedClients, k, v)
+		}
+
+		tc.Origins = append(tc.Origins, origin(u))
+		hosts = append(hosts, u.Hostname())
+		secure = secure && u.Scheme == "https"
+	}
+
+	tc.Domain, tc.CrossSite = sharedDomain(hosts)
+	if tc.CrossSite && !secure {
+		return nil, errors.Errorf("the trusted clients in \"%s\" do not share a parent domain with the public base URL which requires SameSite=None cookies and therefore HTTPS for all origins", ViperKeyPublicTrustedClients)
+	}
+
+	return tc, nil
+}
+
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// sharedDomain returns the parent domain shared by all hosts. The domain is empty if all hosts are equal.
+// If the hosts do not share a parent domain, crossSite is true.
+func sharedDomain(hosts []string) (domain string, crossSite bool) {
+	var equal = true
+	for _, h := range hosts[1:] {
+		if !strings.EqualFold(h, hosts[0]) {
+			equal = false
+		}
+	}
+
+	if equal {
+		return "", false
+	}
+
+	shared := strings.Split(strings.ToLower(hosts[0]), ".")
+	for _, h := range hosts {
+		if net.ParseIP(h) != nil {
+			return "", true
+		}
+
+		labels := strings.Split(strings.ToLower(h), ".")
+		var k int
+		for k < len(shared) && k < len(labels) && shared[len(shared)-1-k] == labels[len(labels)-1-k] {
+			k++
+		}
+		shared = shared[len(shared)-k:]
+	}
+
+	// A public suffix such as "com" or "co.uk" can not be used as a cookie domain.
+	if len(shared) < 2 || isPublicSuffix(strings.Join(shared, ".")) {
+		return "", true
+	}
+
+	return strings.Join(shared, "."), false
+}
+
+// EventsHTTPURL returns the URL self-service events are sent to or nil if events are disabled.
+func (p *Provider) EventsHTTPURL() *url.URL {
+	if p.p.String(ViperKeyEventsHTTPURL) == "" {
+		return nil
+	}
+	return p.parseURIOrFail(ViperKeyEventsHTTPURL)
+}
+
+// EventsHTTPSigningSecrets returns the secrets requests to the event sink are signed with. Requests are not signed
+// if there are none.
+func (p *Provider) EventsHTTPSigningSecrets() [][]byte {
+	secrets := p.p.Strings(ViperKeyEventsHTTPSigningSecrets)
+	result := make([][]byte, len(secrets))
+	for k, v := range secrets {
+		result[k] = []byte(v)
+	}
+	return result
+}
+
+func (p *Provider) EventsBufferSize() int {
+	return p.p.IntF(ViperKeyEventsBufferSize, 1000)
+}
+
+func (p *Provider) EventsBatchSize() int {
+	return p.p.IntF(ViperKeyEventsBatchSize, 100)
+}
+
+func (p *Provider) EventsFlushInterval() time.Duration {
+	return p.p.DurationF(ViperKeyEventsFlushInterval, 5*time.Second)
+}
+
+func (p *Provider) MaintenanceEnabled() bool {
+	return p.p.Bool(ViperKeyMaintenanceEnabled)
+}
+
+func (p *Provider) MaintenanceRetryAfter() time.Duration {
+	return p.p.DurationF(ViperKeyMaintenanceRetryAfter, 5*time.Minute)
+}
+
+// DatabaseCircuitBreaker returns the configuration of the circuit breaker which rejects requests while the
+// database is unavailable.
+func (p *Provider) DatabaseCircuitBreaker() *DatabaseCircuitBreakerConfig {
+	return &DatabaseCircuitBreakerConfig{
+		Enabled:          p.p.Bool(ViperKeyDatabaseCircuitBreakerEnabled),
+		FailureThreshold: p.p.IntF(ViperKeyDatabaseCircuitBreakerFailureThreshold, 3),
+		CheckInterval:    p.p.DurationF(ViperKeyDatabaseCircuitBreakerCheckInterval, time.Second),
+		CheckTimeout:     p.p.DurationF(ViperKeyDatabaseCircuitBreakerCheckTimeout, 2*time.Second),
+		OpenDuration:     p.p.DurationF(ViperKeyDatabaseCircuitBreakerOpenDuration, 10*time.Second),
+	}
+}
+
+func (p *Provider) HotReloadEnabled() bool {
+	return p.p.Bool(ViperKeyHotReloadEnabled)
+}
+
+func (p *Provider) HotReloadInterval() time.Duration {
+	return p.p.DurationF(ViperKeyHotReloadInterval, time.Second)
+}
+
 func (p *Provider) CourierSMTPURL() *url.URL {
 	return p.parseURIOrFail(ViperKeyCourierSMTPURL)
 }
@@ -501,6 +1428,23 @@ func (p *Provider) SelfServiceFlowErrorURL() *url.URL {
 	return p.parseURIOrFail(ViperKeySelfServiceErrorUI)
 }
 
+// SelfServiceStrategyErrorURL returns the error UI URL of the strategy or the global error UI URL if the
+// strategy does not override it.
+func (p *Provider) SelfServiceStrategyErrorURL(strategy string) *url.URL {
+	key := fmt.Sprintf("%s.%s.error_ui_url", ViperKeySelfServiceStrategyConfig, strategy)
+	if len(p.p.String(key)) == 0 {
+		return p.SelfServiceFlowErrorURL()
+	}
+	return p.parseURIOrFail(key)
+}
+
+// SelfServiceStrategyRateLimit returns how many flows of the strategy a client may submit per window. The
+// number of requests is zero if the strategy is not rate limited.
+func (p *Provider) SelfServiceStrategyRateLimit(strategy string) (maxRequests int, window time.Duration) {
+	key := fmt.Sprintf("%s.%s.rate_limit", ViperKeySelfServiceStrategyConfig, strategy)
+	return p.p.Int(key + ".max_requests"), p.p.DurationF(key+".window", time.Minute)
+}
+
 func (p *Provider) SelfServiceFlowRegistrationUI() *url.URL {
 	return p.parseURIOrFail(ViperKeySelfServiceRegistrationUI)
 }
@@ -518,8 +1462,222 @@ func (p *Provider) SessionPersistentCookie() bool {
 	return p.p.Bool(ViperKeySessionPersistentCookie)
 }
 
+func (p *Provider) SessionCookieMaxChunks() int {
+	return p.p.IntF(ViperKeySessionCookieMaxChunks, 4)
+}
+
+// SessionCookiePartitioned returns true if the session and continuity cookies are set with the Partitioned
+// attribute. Browsers only accept partitioned cookies which are Secure and SameSite=None, so an error is
+// returned if partitioned cookies are enabled in dev mode or with a different SameSite mode.
+func (p *Provider) SessionCookiePartitioned() (bool, error) {
+	if !p.p.Bool(ViperKeySessionCookiePartitioned) {
+		return false, nil
+	}
+
+	if p.IsInsecureDevMode() {
+		return false, errors.Errorf("%s requires secure cookies which are disabled in dev mode", ViperKeySessionCookiePartitioned)
+	}
+
+	if p.SessionSameSiteMode() != http.SameSiteNoneMode {
+		return false, errors.Errorf("%s requires %s to be None", ViperKeySessionCookiePartitioned, ViperKeySessionSameSite)
+	}
+
+	return true, nil
+}
+
+func (p *Provider) SessionRefreshEnabled() bool {
+	return p.p.Bool(ViperKeySessionRefreshEnabled)
+}
+
+// SessionRefreshWindow returns the time before a session expires in which it will be extended.
+func (p *Provider) SessionRefreshWindow() time.Duration {
+	return p.p.DurationF(ViperKeySessionRefreshWindow, time.Hour)
+}
+
+// SessionRefreshMaxLifespan returns the time after authentication beyond which a session will not be extended.
+func (p *Provider) SessionRefreshMaxLifespan() time.Duration {
+	return p.p.DurationF(ViperKeySessionRefreshMaxLifespan, time.Hour*24*30)
+}
+
+// SessionRotationEnabled returns true if previous sessions are revoked when a new session is issued
+// for the same browser or client.
+func (p *Provider) SessionRotationEnabled() bool {
+	return p.p.BoolF(ViperKeySessionRotationEnabled, true)
+}
+
+// SessionImpersonationEnabled returns true if impersonation sessions can be issued using the admin API.
+func (p *Provider) SessionImpersonationEnabled() bool {
+	return p.p.Bool(ViperKeySessionImpersonationEnabled)
+}
+
+// SessionImpersonationLifespan returns how long an impersonation session is active.
+func (p *Provider) SessionImpersonationLifespan() time.Duration {
+	return p.p.DurationF(ViperKeySessionImpersonationLifespan, time.Minute*15)
+}
+
+// SessionGeolocation returns the configuration of the geolocation database which is used to annotate sessions
+// with the location of the client.
+func (p *Provider) SessionGeolocation() *SessionGeolocationConfig {
+	return &SessionGeolocationConfig{
+		Enabled:      p.p.Bool(ViperKeySessionGeolocationEnabled),
+		Provider:     p.p.StringF(ViperKeySessionGeolocationProvider, GeolocationProviderMaxMind),
+		DatabasePath: p.p.String(ViperKeySessionGeolocationDatabasePath),
+	}
+}
+
+const (
+	// SessionFirstLoginFlagRequest only sets `first_login` for the first request which checks the session.
+	SessionFirstLoginFlagRequest = "request"
+	// SessionFirstLoginFlagSession sets `first_login` for every request until the session ends.
+	SessionFirstLoginFlagSession = "session"
+)
+
+// SessionFirstLoginFlag returns how long the session of an identity's first login is marked as the first login.
+func (p *Provider) SessionFirstLoginFlag() string {
+	return p.p.StringF(ViperKeySessionFirstLoginFlag, SessionFirstLoginFlagRequest)
+}
+
+// SessionIncludeEntitlements returns true if the identity's entitlements are part of the session returned by whoami.
+func (p *Provider) SessionIncludeEntitlements() bool {
+	return p.p.BoolF(ViperKeySessionIncludeEntitlements, true)
+}
+
+// HTTPClientTimeout returns the timeout of a single outbound HTTP request.
+func (p *Provider) HTTPClientTimeout() time.Duration {
+	return p.p.DurationF(ViperKeyHTTPClientTimeout, time.Second*10)
+}
+
+// HTTPClientRetryMaxAttempts returns how often an idempotent outbound HTTP request is attempted in total.
+func (p *Provider) HTTPClientRetryMaxAttempts() int {
+	return p.p.IntF(ViperKeyHTTPClientRetryMaxAttempts, 3)
+}
+
+func (p *Provider) HTTPClientRetryBaseDelay() time.Duration {
+	return p.p.DurationF(ViperKeyHTTPClientRetryBaseDelay, time.Millisecond*100)
+}
+
+func (p *Provider) HTTPClientRetryMaxDelay() time.Duration {
+	return p.p.DurationF(ViperKeyHTTPClientRetryMaxDelay, time.Second)
+}
+
+// HTTPClientProxyURL returns the proxy outbound HTTP requests are sent through, including the credentials used to
+// authenticate with the proxy. Returns nil if no proxy is configured, in which case the HTTP_PROXY, HTTPS_PROXY,
+// and NO_PROXY environment variables apply.
+func (p *Provider) HTTPClientProxyURL() *url.URL {
+	if p.p.String(ViperKeyHTTPClientProxyURL) == "" {
+		return nil
+	}
+
+	u := p.parseURIOrFail(ViperKeyHTTPClientProxyURL)
+	if username := p.p.String(ViperKeyHTTPClientProxyUsername); username != "" {
+		u.User = url.UserPassword(username, p.p.String(ViperKeyHTTPClientProxyPassword))
+	}
+	return u
+}
+
+// HTTPClientNoProxy returns the destinations which are reached directly instead of through the proxy.
+func (p *Provider) HTTPClientNoProxy() []string {
+	return p.p.Strings(ViperKeyHTTPClientProxyNoProxy)
+}
+
+// SessionClaimsMapperURL returns an empty string when no claims mapper is configured.
+func (p *Provider) SessionClaimsMapperURL() string {
+	return p.p.String(ViperKeySessionClaimsMapperURL)
+}
+
+// SessionClaimsMaxSize returns the maximum size in bytes of the claims returned by the claims mapper.
+func (p *Provider) SessionClaimsMaxSize() int {
+	return p.p.IntF(ViperKeySessionClaimsMaxSize, 4096)
+}
+
+func (p *Provider) SessionJWTEnabled() bool {
+	return p.p.Bool(ViperKeySessionJWTEnabled)
+}
+
+func (p *Provider) SessionJWTLifespan() time.Duration {
+	return p.p.DurationF(ViperKeySessionJWTLifespan, time.Minute*5)
+}
+
+// SessionJWTSigningKeyURL returns the URL of the PEM encoded private key used to sign session JSON Web Tokens.
+func (p *Provider) SessionJWTSigningKeyURL() string {
+	return p.p.String(ViperKeySessionJWTSigningKeyURL)
+}
+
+// SessionJWTIncludeClaims returns true if the custom session claims are added to session JSON Web Tokens.
+func (p *Provider) SessionJWTIncludeClaims() bool {
+	return p.p.Bool(ViperKeySessionJWTIncludeClaims)
+}
+
+// SessionJWTIncludeEntitlements returns true if the identity's entitlements are added to session JSON Web Tokens.
+func (p *Provider) SessionJWTIncludeEntitlements() bool {
+	return p.p.Bool(ViperKeySessionJWTIncludeEntitlements)
+}
+
 func (p *Provider) SelfServiceBrowserWhitelistedReturnToDomains() (us []url.URL) {
-	src := p.p.Strings(ViperKeyURLsWhitelistedReturnToDomains)
+	return p.parseURLs(ViperKeyURLsWhitelistedReturnToDomains)
+}
+
+// SelfServiceFlowWhitelistedReturnToDomains returns the return URLs whitelisted for the given flow, e.g. `login`
+// or `logout`. These restrict `selfservice.whitelisted_return_urls` further. Returns nil if none are configured.
+func (p *Provider) SelfServiceFlowWhitelistedReturnToDomains(flow string) []url.URL {
+	return p.parseURLs("selfservice.flows." + flow + ".whitelisted_return_urls")
+}
+
+// SelfServiceCSRFPerFlow returns true if the anti-CSRF token should be rotated whenever a browser flow is initialized
+// and only be accepted for the flow it was issued for.
+func (p *Provider) SelfServiceCSRFPerFlow() bool {
+	return p.p.Bool(ViperKeySelfServiceCSRFPerFlow)
+}
+
+// SelfServiceCSRFTrustedOrigins returns the origins state-changing browser requests are accepted from. These are
+// the origins in `selfservice.csrf.trusted_origins` and, if enabled, the allowed origins of the public CORS
+// configuration, as well as the public base URL and the self-service UIs. Origins may contain one wildcard.
+//
+// Returns nil if no trusted origins are configured, which disables the check.
+func (p *Provider) SelfServiceCSRFTrustedOrigins() []string {
+	values := p.p.Strings(ViperKeySelfServiceCSRFTrustedOrigins)
+	if p.p.Bool(ViperKeySelfServiceCSRFTrustedOriginsIncludeCORS) {
+		if options, enabled := p.CORS("public"); enabled {
+			values = append(values, options.AllowedOrigins...)
+		}
+	}
+
+	var origins []string
+	for _, v := range values {
+		// Trusting every origin would disable the check.
+		if v == "*" {
+			continue
+		}
+		origins = append(origins, strings.ToLower(strings.TrimSuffix(v, "/")))
+	}
+
+	if len(origins) == 0 {
+		return nil
+	}
+
+	origins = append(origins, origin(p.SelfPublicURL()))
+	for _, ui := range p.selfServiceUIs() {
+		if ui.Host != "" {
+			origins = append(origins, origin(ui))
+		}
+	}
+
+	return origins
+}
+
+func (p *Provider) selfServiceUIs() []*url.URL {
+	return []*url.URL{
+		p.SelfServiceFlowLoginUI(),
+		p.SelfServiceFlowRegistrationUI(),
+		p.SelfServiceFlowSettingsUI(),
+		p.SelfServiceFlowRecoveryUI(),
+		p.SelfServiceFlowVerificationUI(),
+		p.SelfServiceFlowErrorURL(),
+	}
+}
+
+func (p *Provider) parseURLs(key string) (us []url.URL) {
+	src := p.p.Strings(key)
 	for k, u := range src {
 		if len(u) == 0 {
 			continue
@@ -527,7 +1685,7 @@ func (p *Provider) SelfServiceBrowserWhitelistedReturnToDomains() (us []url.URL)
 
 		parsed, err := url.ParseRequestURI(u)
 		if err != nil {
-			p.l.WithError(err).Warnf("Ignoring URL \"%s\" from configuration key \"%s.%d\".", u, ViperKeyURLsWhitelistedReturnToDomains, k)
+			p.l.WithError(err).Warnf("Ignoring URL \"%s\" from configuration key \"%s.%d\".", u, key, k)
 			continue
 		}
 
@@ -541,6 +1699,53 @@ func (p *Provider) SelfServiceFlowLoginRequestLifespan() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceLoginRequestLifespan, time.Hour)
 }
 
+// SelfServiceFlowPersistSubmittedData returns either PersistSubmittedDataAlways or PersistSubmittedDataUntilCompleted.
+func (p *Provider) SelfServiceFlowPersistSubmittedData() string {
+	if p.p.String(ViperKeySelfServicePersistSubmittedData) == PersistSubmittedDataUntilCompleted {
+		return PersistSubmittedDataUntilCompleted
+	}
+	return PersistSubmittedDataAlways
+}
+
+// SelfServiceFlowStructuredValidationErrors returns true if identity trait validation errors should be reported
+// per failed JSON Schema keyword instead of as a flattened message.
+func (p *Provider) SelfServiceFlowStructuredValidationErrors() bool {
+	return p.p.Bool(ViperKeySelfServiceStructuredValidationErrors)
+}
+
+func (p *Provider) SelfServiceFlowLoginThrottling() *LoginThrottlingConfig {
+	store := LoginThrottlingStoreMemory
+	if p.p.String(ViperKeySelfServiceLoginThrottlingStore) == LoginThrottlingStoreDatabase {
+		store = LoginThrottlingStoreDatabase
+	}
+
+	return &LoginThrottlingConfig{
+		Enabled:     p.p.Bool(ViperKeySelfServiceLoginThrottlingEnabled),
+		MaxAttempts: p.p.IntF(ViperKeySelfServiceLoginThrottlingMaxAttempts, 10),
+		Window:      p.p.DurationF(ViperKeySelfServiceLoginThrottlingWindow, 15*time.Minute),
+		Store:       store,
+	}
+}
+
+func (p *Provider) SelfServiceFlowLoginAttemptLog() *LoginAttemptLogConfig {
+	return &LoginAttemptLogConfig{
+		Enabled:         p.p.Bool(ViperKeySelfServiceLoginAttemptLogEnabled),
+		Retention:       p.p.DurationF(ViperKeySelfServiceLoginAttemptLogRetention, 720*time.Hour),
+		CleanupInterval: p.p.DurationF(ViperKeySelfServiceLoginAttemptLogCleanupInterval, time.Hour),
+		BufferSize:      p.p.IntF(ViperKeySelfServiceLoginAttemptLogBufferSize, 1000),
+		BatchSize:       p.p.IntF(ViperKeySelfServiceLoginAttemptLogBatchSize, 100),
+		FlushInterval:   p.p.DurationF(ViperKeySelfServiceLoginAttemptLogFlushInterval, time.Second),
+	}
+}
+
+func (p *Provider) SelfServiceFlowLoginRecentActivity() *LoginRecentActivityConfig {
+	return &LoginRecentActivityConfig{
+		Enabled:       p.p.Bool(ViperKeySelfServiceLoginRecentActivityEnabled),
+		IncludeFailed: p.p.Bool(ViperKeySelfServiceLoginRecentActivityIncludeFailed),
+		Limit:         p.p.IntF(ViperKeySelfServiceLoginRecentActivityLimit, 10),
+	}
+}
+
 func (p *Provider) SelfServiceFlowSettingsFlowLifespan() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceSettingsRequestLifespan, time.Hour)
 }
@@ -549,18 +1754,177 @@ func (p *Provider) SelfServiceFlowRegistrationRequestLifespan() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceRegistrationRequestLifespan, time.Hour)
 }
 
+func (p *Provider) SelfServiceFlowRegistrationAvailabilityEnabled() bool {
+	return p.p.Bool(ViperKeySelfServiceRegistrationAvailabilityEnabled)
+}
+
+func (p *Provider) SelfServiceFlowRegistrationAvailabilityMaxRequests() int {
+	return p.p.IntF(ViperKeySelfServiceRegistrationAvailabilityMaxRequests, 10)
+}
+
+func (p *Provider) SelfServiceFlowRegistrationAvailabilityWindow() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceRegistrationAvailabilityWindow, time.Minute)
+}
+
+// SelfServiceFlowRegistrationCustomFields returns the additional fields of the registration form which
+// are not part of the identity traits.
+func (p *Provider) SelfServiceFlowRegistrationCustomFields() []SelfServiceCustomField {
+	if !p.p.Exists(ViperKeySelfServiceRegistrationCustomFields) {
+		return []SelfServiceCustomField{}
+	}
+
+	out, err := p.p.Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceRegistrationCustomFields)
+	}
+
+	var fields []SelfServiceCustomField
+	config := gjson.GetBytes(out, ViperKeySelfServiceRegistrationCustomFields).Raw
+	if len(config) == 0 {
+		return []SelfServiceCustomField{}
+	} else if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&fields); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceRegistrationCustomFields)
+	}
+
+	for k := range fields {
+		if len(fields[k].Type) == 0 {
+			fields[k].Type = "text"
+		}
+	}
+
+	return fields
+}
+
 func (p *Provider) SelfServiceFlowLogoutRedirectURL() *url.URL {
 	return p.p.RequestURIF(ViperKeySelfServiceLogoutBrowserDefaultReturnTo, p.SelfServiceBrowserDefaultReturnTo())
 }
 
+// SelfServiceFlowLogoutBackChannelURLs returns the URLs which are notified about ended sessions after logout.
+func (p *Provider) SelfServiceFlowLogoutBackChannelURLs() []url.URL {
+	return p.parseURLs(ViperKeySelfServiceLogoutBackChannelURLs)
+}
+
+// SelfServiceFlowLogoutBackChannelSigningSecrets returns the secrets back-channel logout notifications are signed
+// with. Notifications are not signed if there are none.
+func (p *Provider) SelfServiceFlowLogoutBackChannelSigningSecrets() [][]byte {
+	secrets := p.p.Strings(ViperKeySelfServiceLogoutBackChannelSigningSecrets)
+	result := make([][]byte, len(secrets))
+	for k, v := range secrets {
+		result[k] = []byte(v)
+	}
+	return result
+}
+
+// SelfServiceFlowLogoutBackChannelRetry returns how often a back-channel logout notification is sent at most and
+// how long to wait before the first retry. The delay doubles with every retry.
+func (p *Provider) SelfServiceFlowLogoutBackChannelRetry() (maxAttempts int, delay time.Duration) {
+	return p.p.IntF(ViperKeySelfServiceLogoutBackChannelMaxAttempts, 3),
+		p.p.DurationF(ViperKeySelfServiceLogoutBackChannelRetryDelay, time.Second)
+}
+
+// SelfServiceFlowLogoutFrontChannelURLs returns the URLs which are loaded by the browser after logout.
+func (p *Provider) SelfServiceFlowLogoutFrontChannelURLs() []url.URL {
+	return p.parseURLs(ViperKeySelfServiceLogoutFrontChannelURLs)
+}
+
+// SelfServiceFlowLogoutFrontChannelRedirectDelay returns how long the browser is given to load the front-channel
+// logout URLs before it is redirected to the return URL.
+func (p *Provider) SelfServiceFlowLogoutFrontChannelRedirectDelay() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceLogoutFrontChannelRedirectDelay, time.Second)
+}
+
 func (p *Provider) CourierSMTPFrom() string {
 	return p.p.StringF(ViperKeyCourierSMTPFrom, "noreply@kratos.ory.sh")
 }
 
+// CourierSMTPSender returns the sender of messages of the given type. Unset fields of the override in
+// `courier.smtp.senders` fall back to the global sender.
+func (p *Provider) CourierSMTPSender(messageType string) *CourierSMTPSender {
+	s := &CourierSMTPSender{
+		FromAddress: p.CourierSMTPFrom(),
+		FromName:    p.p.String(ViperKeyCourierSMTPFromName),
+	}
+	if messageType == "" {
+		return s
+	}
+
+	key := ViperKeyCourierSMTPSenders + "." + messageType
+	if address := p.p.String(key + ".from_address"); address != "" {
+		s.FromAddress = address
+	}
+	if name := p.p.String(key + ".from_name"); name != "" {
+		s.FromName = name
+	}
+	return s
+}
+
+func (p *Provider) CourierSMTPTLS() *CourierSMTPTLS {
+	return &CourierSMTPTLS{
+		MinVersion:      p.p.StringF(ViperKeyCourierSMTPTLSMinVersion, "1.2"),
+		CipherSuites:    p.p.Strings(ViperKeyCourierSMTPTLSCipherSuites),
+		RequireStartTLS: p.p.Bool(ViperKeyCourierSMTPTLSRequireStartTLS),
+		CAPath:          p.p.String(ViperKeyCourierSMTPTLSCAPath),
+		CertPath:        p.p.String(ViperKeyCourierSMTPTLSCertPath),
+		KeyPath:         p.p.String(ViperKeyCourierSMTPTLSKeyPath),
+	}
+}
+
+func (p *Provider) CourierMetricsSamplingInterval() time.Duration {
+	return p.p.DurationF(ViperKeyCourierMetricsSamplingInterval, time.Second*15)
+}
+
+// CourierDispatchMaxConcurrency returns the maximum number of messages the courier sends at the same time.
+func (p *Provider) CourierDispatchMaxConcurrency() int {
+	if c := p.p.IntF(ViperKeyCourierDispatchMaxConcurrency, 1); c > 0 {
+		return c
+	}
+	return 1
+}
+
+// CourierDispatchRateLimit returns the sustained number of messages the courier sends per second and the burst
+// size. Rate limiting is disabled if the rate is zero.
+func (p *Provider) CourierDispatchRateLimit() (perSecond float64, burst int) {
+	perSecond = p.p.Float64F(ViperKeyCourierDispatchRateLimitPerSecond, 5)
+	if perSecond < 0 {
+		perSecond = 0
+	}
+
+	burst = p.p.IntF(ViperKeyCourierDispatchRateLimitBurst, 5)
+	if burst < 1 {
+		burst = 1
+	}
+	return perSecond, burst
+}
+
+// CourierDispatchBatchSize returns how many queued messages the courier loads at once. The batch is at least as
+// large as the maximum concurrency so that all workers can be used.
+func (p *Provider) CourierDispatchBatchSize() uint8 {
+	size := p.p.IntF(ViperKeyCourierDispatchBatchSize, 10)
+	if c := p.CourierDispatchMaxConcurrency(); size < c {
+		size = c
+	}
+
+	if size < 1 {
+		return 1
+	} else if size > math.MaxUint8 {
+		return math.MaxUint8
+	}
+	return uint8(size)
+}
+
 func (p *Provider) CourierTemplatesRoot() string {
 	return p.p.StringF(ViperKeyCourierTemplatesPath, "/courier/template/templates")
 }
 
+// CourierTemplateContext returns which sensitive fields are added to the context of the recovery and
+// verification email templates.
+func (p *Provider) CourierTemplateContext() *CourierTemplateContextConfig {
+	return &CourierTemplateContextConfig{
+		IdentityTraits:  p.p.Bool(ViperKeyCourierTemplateContextIdentityTraits),
+		RequestMetadata: p.p.Bool(ViperKeyCourierTemplateContextRequestMetadata),
+	}
+}
+
 func (p *Provider) parseURIOrFail(key string) *url.URL {
 	u, err := url.ParseRequestURI(p.p.String(key))
 	if err != nil {
@@ -586,6 +1950,12 @@ func (p *Provider) SelfServiceFlowVerificationRequestLifespan() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceVerificationRequestLifespan, time.Hour)
 }
 
+// SelfServiceFlowVerificationMaxActiveTokens returns how many verification links may be valid at the same time
+// for one identity. Zero means unlimited.
+func (p *Provider) SelfServiceFlowVerificationMaxActiveTokens() int {
+	return p.p.IntF(ViperKeySelfServiceVerificationMaxActiveTokens, 0)
+}
+
 func (p *Provider) SelfServiceFlowVerificationReturnTo(defaultReturnTo *url.URL) *url.URL {
 	return p.p.RequestURIF(ViperKeySelfServiceVerificationBrowserDefaultReturnTo, defaultReturnTo)
 }
@@ -598,11 +1968,82 @@ func (p *Provider) SelfServiceFlowRecoveryRequestLifespan() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceRecoveryRequestLifespan, time.Hour)
 }
 
+// SelfServiceFlowRecoveryResponseJitter returns the range of the minimum response time of submitted recovery forms.
+// If max is smaller than min, min is used for both.
+func (p *Provider) SelfServiceFlowRecoveryResponseJitter() (min, max time.Duration) {
+	min = p.p.DurationF(ViperKeySelfServiceRecoveryResponseJitterMin, 0)
+	max = p.p.DurationF(ViperKeySelfServiceRecoveryResponseJitterMax, 0)
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+// SelfServiceFlowRecoveryMaxActiveTokens returns how many recovery links may be valid at the same time for one
+// identity. Zero means unlimited.
+func (p *Provider) SelfServiceFlowRecoveryMaxActiveTokens() int {
+	return p.p.IntF(ViperKeySelfServiceRecoveryMaxActiveTokens, 0)
+}
+
+// SelfServiceFlowRecoveryDeduplicationWindow returns how long after sending a recovery link to an address repeated
+// recovery requests for the address do not send another link. Zero disables the deduplication.
+func (p *Provider) SelfServiceFlowRecoveryDeduplicationWindow() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceRecoveryDeduplicationWindow, 0)
+}
+
+// SelfServiceFlowFormTransformURL returns the location of the Jsonnet transform which is applied to the
+// values submitted to the flow of the given type (`login`, `registration`, or `settings`), or an empty
+// string if none is configured.
+func (p *Provider) SelfServiceFlowFormTransformURL(flowType string) string {
+	switch flowType {
+	case "login":
+		return p.p.String(ViperKeySelfServiceLoginFormTransformURL)
+	case "registration":
+		return p.p.String(ViperKeySelfServiceRegistrationFormTransformURL)
+	case "settings":
+		return p.p.String(ViperKeySelfServiceSettingsFormTransformURL)
+	}
+	return ""
+}
+
+// SelfServiceFlowRecoveryAdminLinkLifespan returns the default and the maximum lifespan of recovery links
+// created using the admin API. If max is smaller than the default, the default is used for both.
+func (p *Provider) SelfServiceFlowRecoveryAdminLinkLifespan() (lifespan, max time.Duration) {
+	lifespan = p.p.DurationF(ViperKeySelfServiceRecoveryAdminLinkLifespan, 15*time.Minute)
+	max = p.p.DurationF(ViperKeySelfServiceRecoveryAdminLinkMaxLifespan, time.Hour)
+	if max < lifespan {
+		max = lifespan
+	}
+	return lifespan, max
+}
+
+func (p *Provider) SelfServiceFlowRecoveryAdminLinkMaxRequests() int {
+	return p.p.IntF(ViperKeySelfServiceRecoveryAdminLinkMaxRequests, 10)
+}
+
+func (p *Provider) SelfServiceFlowRecoveryAdminLinkWindow() time.Duration {
+	return p.p.DurationF(ViperKeySelfServiceRecoveryAdminLinkWindow, time.Minute)
+}
+
 func (p *Provider) SelfServiceFlowSettingsPrivilegedSessionMaxAge() time.Duration {
 	return p.p.DurationF(ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, time.Hour)
 }
 
+// SelfServiceFlowSettingsPrivilegedChanges returns which changes in the settings flow require a privileged
+// session in addition to credential identifiers and verifiable addresses, which always require one.
+func (p *Provider) SelfServiceFlowSettingsPrivilegedChanges() *SettingsPrivilegedChangesConfig {
+	return &SettingsPrivilegedChangesConfig{
+		Credentials: p.p.StringMap(ViperKeySelfServiceSettingsPrivilegedChangesCredentials),
+		Traits:      p.p.Strings(ViperKeySelfServiceSettingsPrivilegedChangesTraits),
+	}
+}
+
 func (p *Provider) SessionSameSiteMode() http.SameSite {
+	// Cookies are not sent to cross-site trusted clients unless SameSite is None.
+	if tc, err := p.TrustedClients(); err == nil && tc.CrossSite {
+		return http.SameSiteNoneMode
+	}
+
 	switch p.p.StringF(ViperKeySessionSameSite, "Lax") {
 	case "Lax":
 		return http.SameSiteLaxMode
@@ -622,6 +2063,18 @@ func (p *Provider) SelfServiceFlowRegistrationReturnTo(strategy string) *url.URL
 	return p.selfServiceReturnTo(ViperKeySelfServiceRegistrationAfter, strategy)
 }
 
+func (p *Provider) SelfServiceFlowLoginRedirectRulesURL() string {
+	return p.p.String(ViperKeySelfServiceLoginAfterRedirectRules)
+}
+
+func (p *Provider) SelfServiceFlowRegistrationRedirectRulesURL() string {
+	return p.p.String(ViperKeySelfServiceRegistrationAfterRedirectRules)
+}
+
+func (p *Provider) SelfServiceFlowRegistrationDefaultsURL() string {
+	return p.p.String(ViperKeySelfServiceRegistrationDefaults)
+}
+
 func (p *Provider) SelfServiceFlowSettingsReturnTo(strategy string, defaultReturnTo *url.URL) *url.URL {
 	return p.p.RequestURIF(
 		ViperKeySelfServiceSettingsAfter+"."+strategy+"."+DefaultBrowserReturnURL,
@@ -648,5 +2101,9 @@ func (p *Provider) PasswordPolicyConfig() *PasswordPolicyConfig {
 	return &PasswordPolicyConfig{
 		MaxBreaches:         uint(p.p.Int(ViperKeyPasswordMaxBreaches)),
 		IgnoreNetworkErrors: p.p.BoolF(ViperKeyIgnoreNetworkErrors, true),
+		BlocklistPath:       p.p.String(ViperKeyPasswordBlocklistPath),
+		BlocklistPasswords:  p.p.Strings(ViperKeyPasswordBlocklistPasswords),
+		StrengthEnabled:     p.p.Bool(ViperKeyPasswordStrengthEnabled),
+		StrengthMinScore:    p.p.Int(ViperKeyPasswordStrengthMinScore),
 	}
 }
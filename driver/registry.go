@@ -11,17 +11,22 @@ import (
 
 	"github.com/ory/x/logrusx"
 
+	"github.com/ory/kratos/apikey"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/hash"
+	"github.com/ory/kratos/health"
+	"github.com/ory/kratos/maintenance"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/selfservice/strategy/link"
 
-	"github.com/ory/x/healthx"
-
 	"github.com/ory/kratos/persistence"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/logout"
@@ -48,7 +53,7 @@ type Registry interface {
 	WithCSRFHandler(c x.CSRFHandler)
 	WithCSRFTokenGenerator(cg x.CSRFToken)
 
-	HealthHandler() *healthx.Handler
+	HealthHandler() *health.Handler
 	CookieManager() sessions.Store
 	ContinuityCookieManager(ctx context.Context) sessions.Store
 
@@ -64,6 +69,20 @@ type Registry interface {
 	x.CSRFProvider
 	x.WriterProvider
 	x.LoggingProvider
+	x.HTTPClientProvider
+
+	apikey.HandlerProvider
+	apikey.MiddlewareProvider
+	apikey.PolicyProvider
+	x.IPFilterProvider
+	x.CompressorProvider
+	x.SecurityHeadersProvider
+	x.ClientVersionGateProvider
+	flow.FormTransformerProvider
+	x.StrategyRateLimiterProvider
+	geoip.LocatorProvider
+	x.PartitionerProvider
+	apikey.PersistenceProvider
 
 	continuity.ManagementProvider
 	continuity.PersistenceProvider
@@ -76,20 +95,34 @@ type Registry interface {
 	errorx.HandlerProvider
 	errorx.PersistenceProvider
 
+	event.EmitterProvider
+
+	maintenance.ModeProvider
+	maintenance.HandlerProvider
+
+	persistence.BreakerProvider
+
 	hash.HashProvider
+	hash.RehasherProvider
 
 	identity.HandlerProvider
 	identity.ValidationProvider
+	identity.TraitsTransformerProvider
 	identity.PoolProvider
 	identity.PrivilegedPoolProvider
 	identity.ManagementProvider
+	identity.JanitorProvider
 	identity.ActiveCredentialsCounterStrategyProvider
+	identity.PasswordHashValidatorProvider
 
 	schema.HandlerProvider
+	schema.LoaderProvider
 
 	password2.ValidationProvider
 
 	session.HandlerProvider
+	session.ClaimsMapperProvider
+	session.TokenizerProvider
 	session.ManagementProvider
 	session.PersistenceProvider
 
@@ -99,12 +132,19 @@ type Registry interface {
 	settings.StrategyProvider
 
 	login.FlowPersistenceProvider
+	login.AttemptPersistenceProvider
+	login.ThrottlerProvider
+	login.AttemptLogPersistenceProvider
+	login.AttemptLoggerProvider
 	login.ErrorHandlerProvider
 	login.HooksProvider
 	login.HookExecutorProvider
 	login.HandlerProvider
 	login.StrategyProvider
 
+	hook.AsyncJobPersistenceProvider
+	hook.AsyncWorkerProvider
+
 	logout.HandlerProvider
 
 	registration.FlowPersistenceProvider
@@ -116,6 +156,8 @@ type Registry interface {
 
 	verification.FlowPersistenceProvider
 	verification.ErrorHandlerProvider
+	verification.HooksProvider
+	verification.HookExecutorProvider
 	verification.HandlerProvider
 	verification.StrategyProvider
 
@@ -125,6 +167,8 @@ type Registry interface {
 
 	recovery.FlowPersistenceProvider
 	recovery.ErrorHandlerProvider
+	recovery.HooksProvider
+	recovery.HookExecutorProvider
 	recovery.HandlerProvider
 	recovery.StrategyProvider
 
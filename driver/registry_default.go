@@ -13,13 +13,20 @@ import (
 
 	"github.com/gobuffalo/pop/v5"
 
+	"github.com/ory/kratos/apikey"
 	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/hash"
+	"github.com/ory/kratos/health"
+	"github.com/ory/kratos/maintenance"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/selfservice/strategy/deletion"
 	"github.com/ory/kratos/selfservice/strategy/link"
 	"github.com/ory/kratos/selfservice/strategy/profile"
 	"github.com/ory/kratos/x"
@@ -65,7 +72,7 @@ type RegistryDefault struct {
 	trc            *tracing.Tracer
 	pmm            *prometheus.MetricsManager
 	writer         herodot.Writer
-	healthxHandler *healthx.Handler
+	healthxHandler *health.Handler
 	metricsHandler *prometheus.Handler
 
 	courier   *courier.Courier
@@ -74,20 +81,49 @@ type RegistryDefault struct {
 	hookVerifier         *hook.Verifier
 	hookSessionIssuer    *hook.SessionIssuer
 	hookSessionDestroyer *hook.SessionDestroyer
-
-	identityHandler   *identity.Handler
-	identityValidator *identity.Validator
-	identityManager   *identity.Manager
+	hookAsyncWorker      *hook.AsyncWorker
+
+	apiKeyHandler     *apikey.Handler
+	apiKeyMiddleware  *apikey.Middleware
+	apiKeyPolicy      *apikey.Policy
+	adminIPFilter     *x.IPFilter
+	publicCompressor  *x.Compressor
+	securityHeaders   *x.SecurityHeaders
+	clientVersionGate *x.ClientVersionGate
+	formTransformer   *flow.FormTransformer
+	geoLocator        *geoip.Locator
+	strategyLimiter   *x.StrategyRateLimiter
+	publicPartitioner *x.Partitioner
+	adminPartitioner  *x.Partitioner
+
+	maintenanceMode    *maintenance.Mode
+	maintenanceHandler *maintenance.Handler
+	databaseBreaker    *persistence.Breaker
+
+	identityHandler           *identity.Handler
+	identityValidator         *identity.Validator
+	identityTraitsTransformer *identity.TraitsTransformer
+	identityManager           *identity.Manager
+	identityJanitor           *identity.Janitor
 
 	continuityManager continuity.Manager
 
 	schemaHandler *schema.Handler
+	schemaLoader  *schema.Loader
+
+	httpClient *http.Client
 
-	sessionHandler *session.Handler
-	sessionsStore  *sessions.CookieStore
-	sessionManager session.Manager
+	eventSink    event.Sink
+	eventEmitter *event.Emitter
+
+	sessionHandler      *session.Handler
+	sessionClaimsMapper *session.ClaimsMapper
+	sessionTokenizer    *session.Tokenizer
+	sessionsStore       *x.ChunkedCookieStore
+	sessionManager      session.Manager
 
 	passwordHasher    hash.Hasher
+	passwordRehasher  *hash.Rehasher
 	passwordValidator password2.Validator
 
 	errorHandler *errorx.Handler
@@ -101,6 +137,8 @@ type RegistryDefault struct {
 	selfserviceLoginExecutor            *login.HookExecutor
 	selfserviceLoginHandler             *login.Handler
 	selfserviceLoginRequestErrorHandler *login.ErrorHandler
+	selfserviceLoginThrottler           *login.Throttler
+	selfserviceLoginAttemptLogger       *login.AttemptLogger
 
 	selfserviceSettingsHandler      *settings.Handler
 	selfserviceSettingsErrorHandler *settings.ErrorHandler
@@ -109,11 +147,13 @@ type RegistryDefault struct {
 	selfserviceVerifyErrorHandler *verification.ErrorHandler
 	selfserviceVerifyManager      *identity.Manager
 	selfserviceVerifyHandler      *verification.Handler
+	selfserviceVerifyExecutor     *verification.HookExecutor
 
 	selfserviceLinkSender *link.Sender
 
 	selfserviceRecoveryErrorHandler *recovery.ErrorHandler
 	selfserviceRecoveryHandler      *recovery.Handler
+	selfserviceRecoveryExecutor     *recovery.HookExecutor
 
 	selfserviceLogoutHandler *logout.Handler
 
@@ -170,6 +210,11 @@ func (m *RegistryDefault) RegisterAdminRoutes(router *x.RouterAdmin) {
 	m.IdentityHandler().RegisterAdminRoutes(router)
 	m.SessionHandler().RegisterAdminRoutes(router)
 	m.SelfServiceErrorHandler().RegisterAdminRoutes(router)
+	m.MaintenanceHandler().RegisterAdminRoutes(router)
+
+	if m.c.AdminAPIKeysEnabled() {
+		m.APIKeyHandler().RegisterAdminRoutes(router)
+	}
 
 	if m.c.SelfServiceFlowRecoveryEnabled() {
 		m.RecoveryHandler().RegisterAdminRoutes(router)
@@ -204,10 +249,11 @@ func (m *RegistryDefault) LogoutHandler() *logout.Handler {
 	return m.selfserviceLogoutHandler
 }
 
-func (m *RegistryDefault) HealthHandler() *healthx.Handler {
+func (m *RegistryDefault) HealthHandler() *health.Handler {
 	if m.healthxHandler == nil {
-		m.healthxHandler = healthx.NewHandler(m.Writer(), config.Version,
-			healthx.ReadyCheckers{"database": m.Ping})
+		m.healthxHandler = health.NewHandler(m,
+			healthx.ReadyCheckers{"database": m.Ping, "database_circuit_breaker": m.DatabaseBreaker().Ready},
+			m.PendingMigrations)
 	}
 
 	return m.healthxHandler
@@ -246,6 +292,7 @@ func (m *RegistryDefault) selfServiceStrategies() []interface{} {
 			oidc.NewStrategy(m),
 			profile.NewStrategy(m),
 			link.NewStrategy(m),
+			deletion.NewStrategy(m),
 		}
 	}
 
@@ -307,6 +354,13 @@ func (m *RegistryDefault) IdentityValidator() *identity.Validator {
 	return m.identityValidator
 }
 
+func (m *RegistryDefault) IdentityTraitsTransformer() *identity.TraitsTransformer {
+	if m.identityTraitsTransformer == nil {
+		m.identityTraitsTransformer = identity.NewTraitsTransformer(m)
+	}
+	return m.identityTraitsTransformer
+}
+
 func (m *RegistryDefault) WithConfig(c *config.Provider) Registry {
 	m.c = c
 	return m
@@ -315,6 +369,14 @@ func (m *RegistryDefault) WithConfig(c *config.Provider) Registry {
 func (m *RegistryDefault) Writer() herodot.Writer {
 	if m.writer == nil {
 		h := herodot.NewJSONWriter(m.Logger())
+		enhance := h.ErrorEnhancer
+		h.ErrorEnhancer = func(r *http.Request, err error) interface{} {
+			m.DatabaseBreaker().RecordError(r.Context(), err)
+			if m.Configuration(r.Context()).RedactInternalErrors() {
+				err = x.RedactInternalError(m.Logger(), r, err)
+			}
+			return enhance(r, err)
+		}
 		m.writer = h
 	}
 	return m.writer
@@ -341,6 +403,157 @@ func (m *RegistryDefault) SchemaHandler() *schema.Handler {
 	return m.schemaHandler
 }
 
+func (m *RegistryDefault) IdentitySchemaLoader() *schema.Loader {
+	if m.schemaLoader == nil {
+		m.schemaLoader = schema.NewLoader(m)
+	}
+	return m.schemaLoader
+}
+
+func (m *RegistryDefault) APIKeyHandler() *apikey.Handler {
+	if m.apiKeyHandler == nil {
+		m.apiKeyHandler = apikey.NewHandler(m)
+	}
+	return m.apiKeyHandler
+}
+
+func (m *RegistryDefault) APIKeyMiddleware() *apikey.Middleware {
+	if m.apiKeyMiddleware == nil {
+		m.apiKeyMiddleware = apikey.NewMiddleware(m)
+	}
+	return m.apiKeyMiddleware
+}
+
+func (m *RegistryDefault) APIKeyPolicy() *apikey.Policy {
+	if m.apiKeyPolicy == nil {
+		m.apiKeyPolicy = apikey.NewPolicy(m)
+	}
+	return m.apiKeyPolicy
+}
+
+func (m *RegistryDefault) AdminIPFilter() *x.IPFilter {
+	if m.adminIPFilter == nil {
+		m.adminIPFilter = x.NewIPFilter(m)
+	}
+	return m.adminIPFilter
+}
+
+func (m *RegistryDefault) PublicCompressor() *x.Compressor {
+	if m.publicCompressor == nil {
+		m.publicCompressor = x.NewCompressor(m)
+	}
+	return m.publicCompressor
+}
+
+func (m *RegistryDefault) SecurityHeaders() *x.SecurityHeaders {
+	if m.securityHeaders == nil {
+		m.securityHeaders = x.NewSecurityHeaders(m)
+	}
+	return m.securityHeaders
+}
+
+func (m *RegistryDefault) ClientVersionGate() *x.ClientVersionGate {
+	if m.clientVersionGate == nil {
+		m.clientVersionGate = x.NewClientVersionGate(m)
+	}
+	return m.clientVersionGate
+}
+
+func (m *RegistryDefault) FormTransformer() *flow.FormTransformer {
+	if m.formTransformer == nil {
+		m.formTransformer = flow.NewFormTransformer(m)
+	}
+	return m.formTransformer
+}
+
+func (m *RegistryDefault) GeoLocator() *geoip.Locator {
+	if m.geoLocator == nil {
+		m.geoLocator = geoip.NewLocator(m)
+	}
+	return m.geoLocator
+}
+
+func (m *RegistryDefault) StrategyRateLimiter() *x.StrategyRateLimiter {
+	if m.strategyLimiter == nil {
+		m.strategyLimiter = x.NewStrategyRateLimiter(m)
+	}
+	return m.strategyLimiter
+}
+
+func (m *RegistryDefault) PublicPartitioner() *x.Partitioner {
+	if m.publicPartitioner == nil {
+		m.publicPartitioner = x.NewPublicPartitioner(m)
+	}
+	return m.publicPartitioner
+}
+
+func (m *RegistryDefault) AdminPartitioner() *x.Partitioner {
+	if m.adminPartitioner == nil {
+		m.adminPartitioner = x.NewAdminPartitioner(m)
+	}
+	return m.adminPartitioner
+}
+
+func (m *RegistryDefault) MaintenanceMode() *maintenance.Mode {
+	if m.maintenanceMode == nil {
+		m.maintenanceMode = maintenance.NewMode(m)
+	}
+	return m.maintenanceMode
+}
+
+func (m *RegistryDefault) DatabaseBreaker() *persistence.Breaker {
+	if m.databaseBreaker == nil {
+		m.databaseBreaker = persistence.NewBreaker(m, m.Ping)
+	}
+	return m.databaseBreaker
+}
+
+func (m *RegistryDefault) MaintenanceHandler() *maintenance.Handler {
+	if m.maintenanceHandler == nil {
+		m.maintenanceHandler = maintenance.NewHandler(m)
+	}
+	return m.maintenanceHandler
+}
+
+func (m *RegistryDefault) HTTPClient() *http.Client {
+	if m.httpClient == nil {
+		m.httpClient = x.NewHTTPClient(m.c)
+	}
+	return m.httpClient
+}
+
+// WithEventSink replaces the sink configured in `events.http.url`, for example to deliver events to a message broker.
+func (m *RegistryDefault) WithEventSink(s event.Sink) {
+	m.eventSink = s
+	m.eventEmitter = nil
+}
+
+func (m *RegistryDefault) EventEmitter() *event.Emitter {
+	if m.eventEmitter == nil {
+		if m.eventSink == nil {
+			if u := m.c.EventsHTTPURL(); u != nil {
+				m.eventSink = event.NewHTTPSink(m.HTTPClient(), u, m.c.EventsHTTPSigningSecrets())
+			}
+		}
+		m.eventEmitter = event.NewEmitter(m, m.eventSink)
+	}
+	return m.eventEmitter
+}
+
+func (m *RegistryDefault) SessionClaimsMapper() *session.ClaimsMapper {
+	if m.sessionClaimsMapper == nil {
+		m.sessionClaimsMapper = session.NewClaimsMapper(m)
+	}
+	return m.sessionClaimsMapper
+}
+
+func (m *RegistryDefault) SessionTokenizer() *session.Tokenizer {
+	if m.sessionTokenizer == nil {
+		m.sessionTokenizer = session.NewTokenizer(m)
+	}
+	return m.sessionTokenizer
+}
+
 func (m *RegistryDefault) SessionHandler() *session.Handler {
 	if m.sessionHandler == nil {
 		m.sessionHandler = session.NewHandler(m)
@@ -355,6 +568,17 @@ func (m *RegistryDefault) Hasher() hash.Hasher {
 	return m.passwordHasher
 }
 
+func (m *RegistryDefault) ValidatePasswordHash(h []byte) error {
+	return hash.Validate(h)
+}
+
+func (m *RegistryDefault) Rehasher() *hash.Rehasher {
+	if m.passwordRehasher == nil {
+		m.passwordRehasher = hash.NewRehasher(m)
+	}
+	return m.passwordRehasher
+}
+
 func (m *RegistryDefault) PasswordValidator() password2.Validator {
 	if m.passwordValidator == nil {
 		m.passwordValidator = password2.NewDefaultPasswordValidatorStrategy(m)
@@ -371,7 +595,7 @@ func (m *RegistryDefault) SelfServiceErrorHandler() *errorx.Handler {
 
 func (m *RegistryDefault) CookieManager() sessions.Store {
 	if m.sessionsStore == nil {
-		cs := sessions.NewCookieStore(m.c.SecretsSession()...)
+		cs := x.NewChunkedCookieStore(m.c.SessionCookieMaxChunks(), m.c.SecretsSession()...)
 		cs.Options.Secure = !m.c.IsInsecureDevMode()
 		cs.Options.HttpOnly = true
 		if m.c.SessionDomain() != "" {
@@ -386,6 +610,8 @@ func (m *RegistryDefault) CookieManager() sessions.Store {
 			cs.Options.SameSite = m.c.SessionSameSiteMode()
 		}
 
+		cs.Partitioned, _ = m.c.SessionCookiePartitioned()
+
 		cs.Options.MaxAge = 0
 		if m.c.SessionPersistentCookie() {
 			cs.Options.MaxAge = int(m.c.SessionLifespan().Seconds())
@@ -397,10 +623,15 @@ func (m *RegistryDefault) CookieManager() sessions.Store {
 
 func (m *RegistryDefault) ContinuityCookieManager(ctx context.Context) sessions.Store {
 	// To support hot reloading, this can not be instantiated only once.
-	cs := sessions.NewCookieStore(m.Configuration(ctx).SecretsSession()...)
+	cs := x.NewChunkedCookieStore(m.Configuration(ctx).SessionCookieMaxChunks(), m.Configuration(ctx).SecretsSession()...)
 	cs.Options.Secure = !m.Configuration(ctx).IsInsecureDevMode()
 	cs.Options.HttpOnly = true
 	cs.Options.SameSite = http.SameSiteLaxMode
+	if partitioned, _ := m.Configuration(ctx).SessionCookiePartitioned(); partitioned {
+		// Partitioned cookies must be SameSite=None to be sent in cross-site iframes.
+		cs.Options.SameSite = http.SameSiteNoneMode
+		cs.Partitioned = true
+	}
 	return cs
 }
 
@@ -511,6 +742,10 @@ func (m *RegistryDefault) ContinuityPersister() continuity.Persister {
 	return m.persister
 }
 
+func (m *RegistryDefault) APIKeyPersister() apikey.Persister {
+	return m.persister
+}
+
 func (m *RegistryDefault) IdentityPool() identity.Pool {
 	return m.persister
 }
@@ -531,6 +766,14 @@ func (m *RegistryDefault) LoginFlowPersister() login.FlowPersister {
 	return m.persister
 }
 
+func (m *RegistryDefault) LoginAttemptPersister() login.AttemptPersister {
+	return m.persister
+}
+
+func (m *RegistryDefault) LoginAttemptLogPersister() login.AttemptLogPersister {
+	return m.persister
+}
+
 func (m *RegistryDefault) SettingsFlowPersister() settings.FlowPersister {
 	return m.persister
 }
@@ -543,6 +786,10 @@ func (m *RegistryDefault) SessionPersister() session.Persister {
 	return m.persister
 }
 
+func (m *RegistryDefault) AsyncJobPersister() hook.AsyncJobPersister {
+	return m.persister
+}
+
 func (m *RegistryDefault) CourierPersister() courier.Persister {
 	return m.persister
 }
@@ -563,6 +810,10 @@ func (m *RegistryDefault) Ping() error {
 	return m.persister.Ping()
 }
 
+func (m *RegistryDefault) PendingMigrations(ctx context.Context) (int, error) {
+	return m.persister.PendingMigrations(ctx)
+}
+
 func (m *RegistryDefault) WithCSRFTokenGenerator(cg x.CSRFToken) {
 	m.csrfTokenGenerator = cg
 }
@@ -581,6 +832,13 @@ func (m *RegistryDefault) IdentityManager() *identity.Manager {
 	return m.identityManager
 }
 
+func (m *RegistryDefault) IdentityJanitor() *identity.Janitor {
+	if m.identityJanitor == nil {
+		m.identityJanitor = identity.NewJanitor(m)
+	}
+	return m.identityJanitor
+}
+
 func (m *RegistryDefault) PrometheusManager() *prometheus.MetricsManager {
 	m.rwl.Lock()
 	defer m.rwl.Unlock()
@@ -26,34 +26,70 @@ func (m *RegistryDefault) HookSessionDestroyer() *hook.SessionDestroyer {
 	return m.hookSessionDestroyer
 }
 
+func (m *RegistryDefault) AsyncHookWorker() *hook.AsyncWorker {
+	if m.hookAsyncWorker == nil {
+		m.hookAsyncWorker = hook.NewAsyncWorker(m)
+	}
+	return m.hookAsyncWorker
+}
+
 func (m *RegistryDefault) WithHooks(hooks map[string]func(config.SelfServiceHook) interface{}) {
 	m.injectedSelfserviceHooks = hooks
 }
 
+// ResolveHook returns the hook for the configuration, ignoring its mode, or nil if the hook is unknown
+// or misconfigured.
+func (m *RegistryDefault) ResolveHook(credentialsType string, h config.SelfServiceHook) interface{} {
+	switch h.Name {
+	case hook.KeySessionIssuer:
+		return m.HookSessionIssuer()
+	case hook.KeySessionDestroyer:
+		return m.HookSessionDestroyer()
+	case hook.KeyConsentRecorder:
+		recorder, err := hook.NewConsentRecorder(m, h.Config)
+		if err != nil {
+			m.l.
+				WithError(err).
+				WithField("for", credentialsType).
+				WithField("hook", hook.KeyConsentRecorder).
+				Errorf("The hook is misconfigured and can therefore not be used")
+			return nil
+		}
+		return recorder
+	}
+
+	for name, m := range m.injectedSelfserviceHooks {
+		if name == h.Name {
+			return m(h)
+		}
+	}
+
+	m.l.
+		WithField("for", credentialsType).
+		WithField("hook", h.Name).
+		Errorf("A unknown hook was requested and can therefore not be used")
+	return nil
+}
+
 func (m *RegistryDefault) getHooks(credentialsType string, configs []config.SelfServiceHook) (i []interface{}) {
 	for _, h := range configs {
-		switch h.Name {
-		case hook.KeySessionIssuer:
-			i = append(i, m.HookSessionIssuer())
-		case hook.KeySessionDestroyer:
-			i = append(i, m.HookSessionDestroyer())
-		default:
-			var found bool
-			for name, m := range m.injectedSelfserviceHooks {
-				if name == h.Name {
-					i = append(i, m(h))
-					found = true
-					break
-				}
-			}
-			if found {
-				continue
+		v := m.ResolveHook(credentialsType, h)
+		if v == nil {
+			continue
+		}
+
+		if h.Mode == config.HookModeAsync {
+			if h.Name == hook.KeySessionIssuer || !hook.SupportsAsync(v) {
+				m.l.
+					WithField("for", credentialsType).
+					WithField("hook", h.Name).
+					Errorf("The hook can not be executed asynchronously and is executed blocking instead")
+			} else {
+				v = hook.NewAsyncHook(m, credentialsType, h, v)
 			}
-			m.l.
-				WithField("for", credentialsType).
-				WithField("hook", h.Name).
-				Errorf("A unknown hook was requested and can therefore not be used")
 		}
+
+		i = append(i, v)
 	}
 
 	return i
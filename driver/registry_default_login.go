@@ -18,6 +18,13 @@ func (m *RegistryDefault) PreLoginHooks() (b []login.PreHookExecutor) {
 			b = append(b, hook)
 		}
 	}
+
+	// Strategies may need to complete actions, such as linking accounts, once the user signed in.
+	for _, s := range m.LoginStrategies() {
+		if hook, ok := s.(login.PostHookExecutor); ok {
+			b = append(b, hook)
+		}
+	}
 	return
 }
 
@@ -45,3 +52,19 @@ func (m *RegistryDefault) LoginFlowErrorHandler() *login.ErrorHandler {
 
 	return m.selfserviceLoginRequestErrorHandler
 }
+
+func (m *RegistryDefault) LoginThrottler() *login.Throttler {
+	if m.selfserviceLoginThrottler == nil {
+		m.selfserviceLoginThrottler = login.NewThrottler(m)
+	}
+
+	return m.selfserviceLoginThrottler
+}
+
+func (m *RegistryDefault) LoginAttemptLogger() *login.AttemptLogger {
+	if m.selfserviceLoginAttemptLogger == nil {
+		m.selfserviceLoginAttemptLogger = login.NewAttemptLogger(m)
+	}
+
+	return m.selfserviceLoginAttemptLogger
+}
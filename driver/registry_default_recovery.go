@@ -32,3 +32,19 @@ func (m *RegistryDefault) RecoveryStrategies() recovery.Strategies {
 	}
 	return m.recoveryStrategies
 }
+
+func (m *RegistryDefault) RecoveryExecutor() *recovery.HookExecutor {
+	if m.selfserviceRecoveryExecutor == nil {
+		m.selfserviceRecoveryExecutor = recovery.NewHookExecutor(m)
+	}
+	return m.selfserviceRecoveryExecutor
+}
+
+func (m *RegistryDefault) PreRecoveryHooks() (b []recovery.PreHookExecutor) {
+	for _, v := range m.getHooks("", m.c.SelfServiceFlowRecoveryBeforeHooks()) {
+		if hook, ok := v.(recovery.PreHookExecutor); ok {
+			b = append(b, hook)
+		}
+	}
+	return
+}
@@ -3,7 +3,9 @@ package driver
 import (
 	"context"
 	"net/url"
+	"time"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/schema"
 )
 
@@ -17,10 +19,58 @@ func (m *RegistryDefault) IdentityTraitsSchemas(ctx context.Context) schema.Sche
 			m.l.Fatalf("Could not parse url %s for schema %s", s.URL, s.ID)
 		}
 
+		enforcement := schema.VerificationEnforcement{
+			Action:      s.VerificationEnforcement.Action,
+			GracePeriod: config.DefaultVerificationEnforcementGracePeriod,
+		}
+		if s.VerificationEnforcement.GracePeriod != "" {
+			enforcement.GracePeriod, err = time.ParseDuration(s.VerificationEnforcement.GracePeriod)
+			if err != nil {
+				m.l.Fatalf("Could not parse verification enforcement grace period %s for schema %s", s.VerificationEnforcement.GracePeriod, s.ID)
+			}
+		}
+
+		var cooldown time.Duration
+		if s.EmailChangeCooldown != "" {
+			cooldown, err = time.ParseDuration(s.EmailChangeCooldown)
+			if err != nil {
+				m.l.Fatalf("Could not parse email change cooldown %s for schema %s", s.EmailChangeCooldown, s.ID)
+			}
+		}
+
+		history := make(schema.Schemas, len(s.History))
+		for k, h := range s.History {
+			hurl, err := url.Parse(h.URL)
+			if err != nil {
+				m.l.Fatalf("Could not parse url %s for schema %s in version %s", h.URL, s.ID, h.Version)
+			}
+
+			history[k] = schema.Schema{
+				ID:            s.ID,
+				URL:           hurl,
+				RawURL:        h.URL,
+				Version:       h.Version,
+				Checksum:      h.Checksum,
+				MaxTraitsSize: s.MaxTraitsSize,
+
+				VerificationEnforcement: enforcement,
+				EmailChangeCooldown:     cooldown,
+				MaxLinkedOIDCProviders:  s.MaxLinkedOIDCProviders,
+			}
+		}
+
 		ss = append(ss, schema.Schema{
-			ID:     s.ID,
-			URL:    surl,
-			RawURL: s.URL,
+			ID:            s.ID,
+			URL:           surl,
+			RawURL:        s.URL,
+			Version:       s.Version,
+			Checksum:      s.Checksum,
+			History:       history,
+			MaxTraitsSize: s.MaxTraitsSize,
+
+			VerificationEnforcement: enforcement,
+			EmailChangeCooldown:     cooldown,
+			MaxLinkedOIDCProviders:  s.MaxLinkedOIDCProviders,
 		})
 	}
 
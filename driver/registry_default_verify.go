@@ -41,3 +41,19 @@ func (m *RegistryDefault) LinkSender() *link.Sender {
 
 	return m.selfserviceLinkSender
 }
+
+func (m *RegistryDefault) VerificationExecutor() *verification.HookExecutor {
+	if m.selfserviceVerifyExecutor == nil {
+		m.selfserviceVerifyExecutor = verification.NewHookExecutor(m)
+	}
+	return m.selfserviceVerifyExecutor
+}
+
+func (m *RegistryDefault) PreVerificationHooks() (b []verification.PreHookExecutor) {
+	for _, v := range m.getHooks("", m.c.SelfServiceFlowVerificationBeforeHooks()) {
+		if hook, ok := v.(verification.PreHookExecutor); ok {
+			b = append(b, hook)
+		}
+	}
+	return
+}
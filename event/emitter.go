@@ -0,0 +1,167 @@
+package event
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	emitterDependencies interface {
+		config.Providers
+		x.LoggingProvider
+	}
+	EmitterProvider interface {
+		EventEmitter() *Emitter
+	}
+
+	// Sink delivers batches of events to an external system.
+	Sink interface {
+		Send(ctx context.Context, events []Event) error
+	}
+
+	// Emitter buffers events and delivers them to the sink in the background. Emitting never blocks:
+	// if the buffer is full or the emitter was closed, the event is dropped.
+	Emitter struct {
+		d      emitterDependencies
+		sink   Sink
+		events chan *Event
+		once   sync.Once
+		done   chan struct{}
+
+		// l guards closed and makes sure that no event is sent to the closed events channel.
+		l      sync.RWMutex
+		closed bool
+	}
+)
+
+func NewEmitter(d emitterDependencies, sink Sink) *Emitter {
+	return &Emitter{
+		d:      d,
+		sink:   sink,
+		events: make(chan *Event, d.Configuration(context.Background()).EventsBufferSize()),
+		done:   make(chan struct{}),
+	}
+}
+
+func (e *Emitter) start() {
+	e.once.Do(func() {
+		go e.work(context.Background())
+	})
+}
+
+// Emit queues the event for delivery. It is a no-op if no sink is configured.
+func (e *Emitter) Emit(ctx context.Context, ev *Event) {
+	if e.sink == nil {
+		return
+	}
+
+	e.start()
+
+	if ev.identityID != uuid.Nil {
+		ev.IdentityHash = e.anonymize(ctx, ev.identityID.String())
+	}
+
+	e.l.RLock()
+	defer e.l.RUnlock()
+
+	if e.closed {
+		e.d.Logger().
+			WithField("event_type", ev.Type).
+			WithField("flow_type", ev.FlowType).
+			Warn("Dropped self-service event because the event emitter was closed.")
+		return
+	}
+
+	select {
+	case e.events <- ev:
+	default:
+		e.d.Logger().
+			WithField("event_type", ev.Type).
+			WithField("flow_type", ev.FlowType).
+			Warn("Dropped self-service event because the event buffer is full.")
+	}
+}
+
+// Close stops accepting events and waits until the buffered events were delivered to the sink or the
+// context is done.
+func (e *Emitter) Close(ctx context.Context) error {
+	if e.sink == nil {
+		return nil
+	}
+
+	e.l.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.events)
+	}
+	e.l.Unlock()
+
+	e.start()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func (e *Emitter) anonymize(ctx context.Context, value string) string {
+	var secret []byte
+	if secrets := e.d.Configuration(ctx).SecretsDefault(); len(secrets) > 0 {
+		secret = secrets[0]
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (e *Emitter) work(ctx context.Context) {
+	defer close(e.done)
+
+	conf := e.d.Configuration(ctx)
+	batch := make([]Event, 0, conf.EventsBatchSize())
+	ticker := time.NewTicker(conf.EventsFlushInterval())
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := e.sink.Send(ctx, batch); err != nil {
+			e.d.Logger().
+				WithError(err).
+				WithField("events", len(batch)).
+				Warn("Unable to deliver self-service events.")
+		}
+		batch = make([]Event, 0, conf.EventsBatchSize())
+	}
+
+	for {
+		select {
+		case ev, ok := <-e.events:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, *ev)
+			if len(batch) >= conf.EventsBatchSize() {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+type memorySink struct {
+	sync.Mutex
+	batches [][]event.Event
+}
+
+func (s *memorySink) Send(_ context.Context, events []event.Event) error {
+	s.Lock()
+	defer s.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *memorySink) get() [][]event.Event {
+	s.Lock()
+	defer s.Unlock()
+	return s.batches
+}
+
+func TestEmitter(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyEventsBatchSize, 2)
+	conf.MustSet(config.ViperKeyEventsFlushInterval, "100ms")
+
+	sink := new(memorySink)
+	reg.WithEventSink(sink)
+
+	identityID := x.NewUUID()
+	flowID := x.NewUUID()
+	reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowCreated, "login", flowID, flow.TypeBrowser))
+	reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowSucceeded, "login", flowID, flow.TypeBrowser).
+		WithStrategy("password").WithIdentity(identityID))
+	reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowCreated, "registration", x.NewUUID(), flow.TypeAPI))
+
+	t.Run("case=delivers events in batches", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return len(sink.get()) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		batches := sink.get()
+		require.Len(t, batches[0], 2)
+		require.Len(t, batches[1], 1)
+
+		assert.Equal(t, event.FlowCreated, batches[0][0].Type)
+		assert.Equal(t, flowID, batches[0][0].FlowID)
+		assert.Equal(t, "registration", batches[1][0].FlowType)
+		assert.Equal(t, flow.TypeAPI, batches[1][0].RequestType)
+	})
+
+	t.Run("case=anonymizes the identity", func(t *testing.T) {
+		ev := sink.get()[0][1]
+		assert.Equal(t, "password", ev.Strategy)
+		assert.Len(t, ev.IdentityHash, 64)
+		assert.NotContains(t, ev.IdentityHash, identityID.String())
+		assert.Empty(t, sink.get()[0][0].IdentityHash)
+
+		out, err := json.Marshal(ev)
+		require.NoError(t, err)
+		assert.NotContains(t, string(out), identityID.String())
+	})
+}
+
+func TestEmitterClose(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyEventsBatchSize, 100)
+	conf.MustSet(config.ViperKeyEventsFlushInterval, "1h")
+
+	sink := new(memorySink)
+	reg.WithEventSink(sink)
+
+	reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowCreated, "login", x.NewUUID(), flow.TypeBrowser))
+	require.NoError(t, reg.EventEmitter().Close(context.Background()))
+	require.Len(t, sink.get(), 1)
+	assert.Len(t, sink.get()[0], 1)
+
+	reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowCreated, "login", x.NewUUID(), flow.TypeBrowser))
+	require.NoError(t, reg.EventEmitter().Close(context.Background()))
+	assert.Len(t, sink.get(), 1)
+}
+
+func TestEmitterWithoutSink(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	// Must not block or panic without a sink.
+	for k := 0; k < 2000; k++ {
+		reg.EventEmitter().Emit(context.Background(), event.NewFlowEvent(event.FlowCreated, "login", x.NewUUID(), flow.TypeBrowser))
+	}
+}
+
+func TestEventWithError(t *testing.T) {
+	for k, tc := range []struct {
+		err    error
+		expect string
+	}{
+		{err: errors.WithStack(&jsonschema.ValidationError{Message: "invalid"}), expect: "form_validation_failed"},
+		{err: errors.WithStack(herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityCSRFViolation)), expect: string(text.ErrorCodeSecurityCSRFViolation)},
+		{err: errors.WithStack(herodot.ErrBadRequest), expect: "unknown"},
+		{err: errors.New("foo"), expect: "unknown"},
+	} {
+		ev := event.NewFlowEvent(event.FlowFailed, "login", x.NewUUID(), flow.TypeBrowser).WithError(tc.err)
+		assert.Equal(t, tc.expect, ev.ErrorCode, "%d", k)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received []event.Event
+	var header http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		header = r.Header
+		body = x.MustReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	ev := event.NewFlowEvent(event.FlowSubmitted, "settings", x.NewUUID(), flow.TypeBrowser).WithStrategy("profile")
+	require.NoError(t, event.NewHTTPSink(ts.Client(), u, nil).Send(context.Background(), []event.Event{*ev}))
+	require.Len(t, received, 1)
+	assert.Equal(t, ev.FlowID, received[0].FlowID)
+	assert.Equal(t, "profile", received[0].Strategy)
+	assert.Empty(t, header.Get(x.WebhookHeaderSignature))
+
+	secrets := [][]byte{[]byte("new-secret-0123456789"), []byte("old-secret-0123456789")}
+	require.NoError(t, event.NewHTTPSink(ts.Client(), u, secrets).Send(context.Background(), []event.Event{*ev}))
+	require.NoError(t, x.VerifyWebhook(header, body, secrets[1:], time.Minute, time.Now()))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+	u, err = url.Parse(failing.URL)
+	require.NoError(t, err)
+	assert.Error(t, event.NewHTTPSink(failing.Client(), u, nil).Send(context.Background(), []event.Event{*ev}))
+}
@@ -0,0 +1,109 @@
+package event
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+// Type describes the state transition of a self-service flow.
+type Type string
+
+const (
+	// FlowCreated is emitted when a flow was initialized.
+	FlowCreated Type = "flow_created"
+
+	// FlowSubmitted is emitted when a flow was submitted using one of its methods.
+	FlowSubmitted Type = "flow_submitted"
+
+	// FlowSucceeded is emitted when a flow was completed successfully.
+	FlowSucceeded Type = "flow_succeeded"
+
+	// FlowFailed is emitted when a flow returned an error, including form validation errors.
+	FlowFailed Type = "flow_failed"
+)
+
+// Event is a self-service flow state transition. Events do not contain personally identifiable
+// information such as traits or IP addresses. Identity IDs are replaced by a keyed hash.
+type Event struct {
+	// ID is the event's unique identifier.
+	ID uuid.UUID `json:"id"`
+
+	// Type is the state transition, for example `flow_succeeded`.
+	Type Type `json:"type"`
+
+	// FlowType is the kind of flow, for example `login`.
+	FlowType string `json:"flow_type"`
+
+	// FlowID is the ID of the flow.
+	FlowID uuid.UUID `json:"flow_id"`
+
+	// RequestType is either `api` or `browser`.
+	RequestType flow.Type `json:"request_type"`
+
+	// Strategy is the method used to submit the flow, for example `password`.
+	Strategy string `json:"strategy,omitempty"`
+
+	// IdentityHash is an anonymized identifier of the identity. It is stable for the same identity
+	// as long as the secrets in `secrets.default` do not change.
+	IdentityHash string `json:"identity_hash,omitempty"`
+
+	// ErrorCode is set for failed flows and contains the error code or `unknown`.
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// Time is when the event was emitted.
+	Time time.Time `json:"time"`
+
+	identityID uuid.UUID
+}
+
+func NewFlowEvent(t Type, flowType string, flowID uuid.UUID, requestType flow.Type) *Event {
+	return &Event{
+		ID:          x.NewUUID(),
+		Type:        t,
+		FlowType:    flowType,
+		FlowID:      flowID,
+		RequestType: requestType,
+		Time:        time.Now().UTC(),
+	}
+}
+
+// WithStrategy sets the method the flow was submitted with.
+func (e *Event) WithStrategy(strategy string) *Event {
+	e.Strategy = strategy
+	return e
+}
+
+// WithIdentity sets the identity. The ID is anonymized before the event is delivered.
+func (e *Event) WithIdentity(id uuid.UUID) *Event {
+	e.identityID = id
+	return e
+}
+
+// WithError sets the error code of a failed flow. Form validation errors use the code `form_validation_failed`.
+func (e *Event) WithError(err error) *Event {
+	e.ErrorCode = "unknown"
+
+	if se := new(schema.ValidationError); errors.As(err, &se) {
+		e.ErrorCode = "form_validation_failed"
+	} else if ve := new(jsonschema.ValidationError); errors.As(err, &ve) {
+		e.ErrorCode = "form_validation_failed"
+	} else if de := new(herodot.DefaultError); errors.As(err, &de) {
+		switch code := de.DetailsField[text.ErrorCodeDetailKey].(type) {
+		case text.ErrorCode:
+			e.ErrorCode = string(code)
+		case string:
+			e.ErrorCode = code
+		}
+	}
+	return e
+}
@@ -0,0 +1,55 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/x"
+)
+
+var _ Sink = new(HTTPSink)
+
+// HTTPSink sends batches of events as a JSON array to an HTTP endpoint using POST. Requests are signed
+// using x.SignWebhook if signing secrets are set.
+type HTTPSink struct {
+	client  *http.Client
+	url     *url.URL
+	secrets [][]byte
+}
+
+func NewHTTPSink(client *http.Client, u *url.URL, secrets [][]byte) *HTTPSink {
+	return &HTTPSink{client: client, url: u, secrets: secrets}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, events []Event) error {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(events); err != nil {
+		return errors.WithStack(err)
+	}
+
+	body := b.Bytes()
+	req, err := http.NewRequest("POST", s.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	x.SignWebhook(req.Header, body, s.secrets, time.Now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("expected event sink %s to respond with a 2xx status code but got: %d", s.url, res.StatusCode)
+	}
+	return nil
+}
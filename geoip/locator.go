@@ -0,0 +1,108 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	// Location is the coarse location of an IP address.
+	Location struct {
+		// Country is the ISO 3166-1 country code, for example `DE`.
+		Country string `json:"country"`
+
+		// Region is the ISO 3166-2 subdivision code without the country prefix, for example `BE`.
+		Region string `json:"region"`
+	}
+
+	// Database looks up the location of IP addresses. It returns nil if the address is unknown.
+	Database interface {
+		Lookup(ip net.IP) (*Location, error)
+	}
+
+	locatorDependencies interface {
+		config.Providers
+		x.LoggingProvider
+	}
+	LocatorProvider interface {
+		GeoLocator() *Locator
+	}
+
+	// Locator resolves client IP addresses using the database configured in `session.geolocation`.
+	Locator struct {
+		sync.Mutex
+		d locatorDependencies
+
+		provider string
+		path     string
+		db       Database
+		err      error
+	}
+)
+
+func NewLocator(d locatorDependencies) *Locator {
+	return &Locator{d: d}
+}
+
+func openDatabase(provider, path string) (Database, error) {
+	switch provider {
+	case config.GeolocationProviderMaxMind:
+		return OpenMaxMindDatabase(path)
+	}
+	return nil, errors.Errorf("geolocation provider %s is not supported", provider)
+}
+
+// database opens the configured database once per provider and path. Errors are logged once and the
+// database is not retried until the configuration changes.
+func (l *Locator) database(conf *config.SessionGeolocationConfig) (Database, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.provider == conf.Provider && l.path == conf.DatabasePath && (l.db != nil || l.err != nil) {
+		return l.db, l.err
+	}
+
+	l.provider, l.path = conf.Provider, conf.DatabasePath
+	l.db, l.err = openDatabase(conf.Provider, conf.DatabasePath)
+	if l.err != nil {
+		l.d.Logger().
+			WithError(l.err).
+			WithField("database_path", conf.DatabasePath).
+			Warn("Unable to open the geolocation database, sessions are not annotated with a location.")
+	}
+
+	return l.db, l.err
+}
+
+// Locate returns the location of the IP address or nil if geolocation is disabled, the database is
+// unavailable, or the address is unknown.
+func (l *Locator) Locate(ctx context.Context, ip string) *Location {
+	conf := l.d.Configuration(ctx).SessionGeolocation()
+	if !conf.Enabled {
+		return nil
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+
+	db, err := l.database(conf)
+	if err != nil {
+		return nil
+	}
+
+	loc, err := db.Lookup(parsed)
+	if err != nil {
+		l.d.Logger().WithError(err).WithField("ip", ip).Debug("Unable to look up the location of an IP address.")
+		return nil
+	}
+
+	return loc
+}
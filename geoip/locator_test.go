@@ -0,0 +1,36 @@
+package geoip_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/geoip"
+	"github.com/ory/kratos/internal"
+)
+
+func TestLocator(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	ctx := context.Background()
+
+	t.Run("case=is disabled by default", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionGeolocationDatabasePath, "stub/test.mmdb")
+		assert.Nil(t, reg.GeoLocator().Locate(ctx, "81.1.2.3"))
+	})
+
+	conf.MustSet(config.ViperKeySessionGeolocationEnabled, true)
+
+	t.Run("case=locates known addresses", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionGeolocationDatabasePath, "stub/test.mmdb")
+		assert.Equal(t, &geoip.Location{Country: "DE", Region: "BE"}, reg.GeoLocator().Locate(ctx, "81.1.2.3"))
+		assert.Nil(t, reg.GeoLocator().Locate(ctx, "127.0.0.1"))
+		assert.Nil(t, reg.GeoLocator().Locate(ctx, "not-an-ip"))
+	})
+
+	t.Run("case=fails gracefully if the database is missing", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionGeolocationDatabasePath, "stub/does-not-exist.mmdb")
+		assert.Nil(t, reg.GeoLocator().Locate(ctx, "81.1.2.3"))
+	})
+}
@@ -0,0 +1,348 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// maxMindMetadataStart marks the beginning of the metadata section at the end of a MaxMind DB file.
+var maxMindMetadataStart = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// The data types of the MaxMind DB format, see https://maxmind.github.io/MaxMind-DB/.
+const (
+	maxMindTypeExtended = iota
+	maxMindTypePointer
+	maxMindTypeString
+	maxMindTypeDouble
+	maxMindTypeBytes
+	maxMindTypeUint16
+	maxMindTypeUint32
+	maxMindTypeMap
+	maxMindTypeInt32
+	maxMindTypeUint64
+	maxMindTypeUint128
+	maxMindTypeArray
+	maxMindTypeContainer
+	maxMindTypeEndMarker
+	maxMindTypeBool
+	maxMindTypeFloat
+)
+
+// maxMindMaxDepth limits how deeply maps, arrays and pointers may nest so that a corrupt or malicious database
+// can not exhaust the stack.
+const maxMindMaxDepth = 32
+
+var errMaxMindUnexpectedEnd = errors.New("unexpected end of MaxMind DB data")
+
+// MaxMindDatabase reads GeoIP2 and GeoLite2 Country and City databases in the MaxMind DB format.
+type MaxMindDatabase struct {
+	tree       []byte
+	data       maxMindDecoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+var _ Database = new(MaxMindDatabase)
+
+// OpenMaxMindDatabase reads the MaxMind DB file at path into memory.
+func OpenMaxMindDatabase(path string) (*MaxMindDatabase, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewMaxMindDatabase(buf)
+}
+
+// NewMaxMindDatabase parses the contents of a MaxMind DB file.
+func NewMaxMindDatabase(buf []byte) (*MaxMindDatabase, error) {
+	start := bytes.LastIndex(buf, maxMindMetadataStart)
+	if start < 0 {
+		return nil, errors.New("the file is not a MaxMind DB file because it has no metadata section")
+	}
+
+	meta := maxMindDecoder{buf: buf[start+len(maxMindMetadataStart):]}
+	raw, _, err := meta.decode(0)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode MaxMind DB metadata")
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the MaxMind DB metadata is not a map")
+	}
+
+	db := &MaxMindDatabase{
+		nodeCount:  maxMindUint(m["node_count"]),
+		recordSize: maxMindUint(m["record_size"]),
+		ipVersion:  maxMindUint(m["ip_version"]),
+	}
+
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, errors.Errorf("MaxMind DB record size %d is not supported", db.recordSize)
+	}
+
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, errors.Errorf("MaxMind DB IP version %d is not supported", db.ipVersion)
+	}
+
+	treeSize := db.recordSize / 4 * db.nodeCount
+	if treeSize+16 > uint(start) {
+		return nil, errors.New("the MaxMind DB search tree exceeds the file")
+	}
+
+	db.tree = buf[:treeSize]
+	db.data = maxMindDecoder{buf: buf[treeSize+16 : start]}
+
+	if db.ipVersion == 6 {
+		for i := 0; i < 96 && db.ipv4Start < db.nodeCount; i++ {
+			db.ipv4Start = db.readRecord(db.ipv4Start, 0)
+		}
+	}
+
+	return db, nil
+}
+
+func (db *MaxMindDatabase) readRecord(node, bit uint) uint {
+	b := db.tree
+	switch db.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		off := node * 7
+		if bit == 0 {
+			return (uint(b[off+3])&0xF0)<<20 | uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+		}
+		return (uint(b[off+3])&0x0F)<<24 | uint(b[off+4])<<16 | uint(b[off+5])<<8 | uint(b[off+6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(b[off : off+4]))
+	}
+}
+
+// Lookup returns the country and the first subdivision of the IP address or nil if the address is unknown.
+func (db *MaxMindDatabase) Lookup(ip net.IP) (*Location, error) {
+	bits, node := 128, uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits, node = ip4, 32, db.ipv4Start
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i%8))) & 1
+		node = db.readRecord(node, bit)
+	}
+
+	if node == db.nodeCount {
+		return nil, nil
+	} else if node < db.nodeCount {
+		return nil, errors.New("the MaxMind DB search tree is invalid")
+	}
+
+	raw, _, err := db.data.decode(node - db.nodeCount - 16)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode MaxMind DB record")
+	}
+
+	record, _ := raw.(map[string]interface{})
+	var loc Location
+	if country, ok := record["country"].(map[string]interface{}); ok {
+		loc.Country, _ = country["iso_code"].(string)
+	}
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		if region, ok := subdivisions[0].(map[string]interface{}); ok {
+			loc.Region, _ = region["iso_code"].(string)
+		}
+	}
+
+	if loc.Country == "" {
+		return nil, nil
+	}
+	return &loc, nil
+}
+
+func maxMindUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case int32:
+		return uint(n)
+	}
+	return 0
+}
+
+// maxMindDecoder decodes values of a MaxMind DB data or metadata section. Pointers are relative to
+// the start of buf.
+type maxMindDecoder struct {
+	buf []byte
+}
+
+func (d *maxMindDecoder) next(offset, n uint) ([]byte, error) {
+	if offset+n > uint(len(d.buf)) {
+		return nil, errors.WithStack(errMaxMindUnexpectedEnd)
+	}
+	return d.buf[offset : offset+n], nil
+}
+
+func (d *maxMindDecoder) decode(offset uint) (interface{}, uint, error) {
+	return d.decodeAt(offset, 0)
+}
+
+func (d *maxMindDecoder) decodeAt(offset, depth uint) (interface{}, uint, error) {
+	if depth > maxMindMaxDepth {
+		return nil, 0, errors.Errorf("MaxMind DB data exceeds the maximum nesting depth of %d", maxMindMaxDepth)
+	}
+
+	b, err := d.next(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctrl := b[0]
+	offset++
+
+	typ := uint(ctrl >> 5)
+	if typ == maxMindTypePointer {
+		pointer, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decodeAt(pointer, depth+1)
+		return value, next, err
+	}
+
+	if typ == maxMindTypeExtended {
+		b, err := d.next(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + uint(b[0])
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case maxMindTypeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decodeAt(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("MaxMind DB map key is not a string")
+			}
+			if value, offset, err = d.decodeAt(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case maxMindTypeArray:
+		a := make([]interface{}, size)
+		for i := range a {
+			if a[i], offset, err = d.decodeAt(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+		}
+		return a, offset, nil
+	case maxMindTypeBool:
+		return size != 0, offset, nil
+	}
+
+	b, err = d.next(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+
+	switch typ {
+	case maxMindTypeString:
+		return string(b), offset, nil
+	case maxMindTypeBytes, maxMindTypeUint128:
+		return append([]byte{}, b...), offset, nil
+	case maxMindTypeUint16, maxMindTypeUint32, maxMindTypeUint64:
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case maxMindTypeInt32:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int32(n), offset, nil
+	case maxMindTypeDouble:
+		if size != 8 {
+			return nil, 0, errors.Errorf("MaxMind DB double has invalid size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case maxMindTypeFloat:
+		if size != 4 {
+			return nil, 0, errors.Errorf("MaxMind DB float has invalid size %d", size)
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	}
+
+	return nil, 0, errors.Errorf("MaxMind DB data type %d is not supported", typ)
+}
+
+func (d *maxMindDecoder) decodeSize(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	n := size - 28
+	b, err := d.next(offset, n)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch size {
+	case 29:
+		size = 29 + uint(b[0])
+	case 30:
+		size = 285 + (uint(b[0])<<8 | uint(b[1]))
+	default:
+		size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+	}
+	return size, offset + n, nil
+}
+
+func (d *maxMindDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	ss := uint(ctrl>>3) & 0x3
+	b, err := d.next(offset, ss+1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	vvv := uint(ctrl & 0x7)
+	var pointer uint
+	switch ss {
+	case 0:
+		pointer = vvv<<8 | uint(b[0])
+	case 1:
+		pointer = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 2:
+		pointer = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		pointer = uint(binary.BigEndian.Uint32(b))
+	}
+	return pointer, offset + ss + 1, nil
+}
@@ -0,0 +1,117 @@
+package geoip
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type maxMindWriter struct {
+	bytes.Buffer
+}
+
+func (w *maxMindWriter) ctrl(typ, size int) {
+	if typ > maxMindTypeMap {
+		w.WriteByte(byte(size))
+		w.WriteByte(byte(typ - 7))
+		return
+	}
+	w.WriteByte(byte(typ<<5 | size))
+}
+
+func (w *maxMindWriter) str(s string) {
+	w.ctrl(maxMindTypeString, len(s))
+	w.WriteString(s)
+}
+
+func (w *maxMindWriter) uint16(v int) {
+	w.ctrl(maxMindTypeUint16, 2)
+	w.Write([]byte{byte(v >> 8), byte(v)})
+}
+
+// newTestMaxMindDatabase returns an IPv4 database with a 24 bit record size which locates 81.0.0.0/8 in
+// Berlin, Germany. It is stored in stub/test.mmdb for tests of other packages.
+func newTestMaxMindDatabase(t *testing.T) []byte {
+	const nodeCount = 8
+	prefix := byte(81)
+
+	var tree bytes.Buffer
+	record := func(v int) { tree.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)}) }
+	for i := 0; i < nodeCount; i++ {
+		next := i + 1
+		if i == nodeCount-1 {
+			next = nodeCount + 16 // points to the start of the data section
+		}
+
+		if prefix>>(7-uint(i))&1 == 0 {
+			record(next)
+			record(nodeCount)
+		} else {
+			record(nodeCount)
+			record(next)
+		}
+	}
+
+	var data maxMindWriter
+	data.ctrl(maxMindTypeMap, 2)
+	data.str("country")
+	data.ctrl(maxMindTypeMap, 1)
+	data.str("iso_code")
+	data.str("DE")
+	data.str("subdivisions")
+	data.ctrl(maxMindTypeArray, 1)
+	data.ctrl(maxMindTypeMap, 1)
+	data.str("iso_code")
+	data.str("BE")
+
+	var meta maxMindWriter
+	meta.Write(maxMindMetadataStart)
+	meta.ctrl(maxMindTypeMap, 3)
+	meta.str("node_count")
+	meta.uint16(nodeCount)
+	meta.str("record_size")
+	meta.uint16(24)
+	meta.str("ip_version")
+	meta.uint16(4)
+
+	var out bytes.Buffer
+	out.Write(tree.Bytes())
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.Write(meta.Bytes())
+	return out.Bytes()
+}
+
+func TestMaxMindDatabase(t *testing.T) {
+	buf := newTestMaxMindDatabase(t)
+	stub, err := ioutil.ReadFile("stub/test.mmdb")
+	require.NoError(t, err)
+	assert.Equal(t, buf, stub)
+
+	db, err := NewMaxMindDatabase(buf)
+	require.NoError(t, err)
+
+	loc, err := db.Lookup(net.ParseIP("81.1.2.3"))
+	require.NoError(t, err)
+	assert.Equal(t, &Location{Country: "DE", Region: "BE"}, loc)
+
+	for _, ip := range []string{"80.1.2.3", "127.0.0.1", "2001:db8::1"} {
+		loc, err := db.Lookup(net.ParseIP(ip))
+		require.NoError(t, err)
+		assert.Nil(t, loc, ip)
+	}
+
+	_, err = NewMaxMindDatabase([]byte("not a database"))
+	assert.Error(t, err)
+
+	t.Run("case=rejects pointers which reference themselves", func(t *testing.T) {
+		d := maxMindDecoder{buf: []byte{maxMindTypePointer << 5, 0}}
+		_, _, err := d.decode(0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "maximum nesting depth")
+	})
+}
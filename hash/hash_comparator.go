@@ -0,0 +1,231 @@
+package hash
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" // #nosec G505 - PBKDF2-SHA1 hashes are only verified to migrate them to Argon2id.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var ErrUnknownHashAlgorithm = errors.New("the hash algorithm is not supported")
+
+// pbkdf2Functions are the hash functions supported for PBKDF2 hashes by their name in the hash prefix.
+var pbkdf2Functions = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+type (
+	scryptParameters struct {
+		LogN uint
+		R    int
+		P    int
+	}
+	pbkdf2Parameters struct {
+		Function   func() hash.Hash
+		Iterations int
+	}
+)
+
+// Compare compares a password to a hash in one of the supported formats and returns nil if they match. Hashes
+// which were not generated by ORY Kratos, for example imported bcrypt hashes, are detected by their prefix:
+//
+// - Argon2id: $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+// - bcrypt: $2a$<cost>$<salt and hash>, $2b$ and $2y$ are accepted as well
+// - scrypt: $scrypt$ln=<log2 of N>,r=<block size>,p=<parallelism>$<salt>$<hash>
+// - PBKDF2: $pbkdf2-<sha1|sha256|sha512>$i=<iterations>$<salt>$<hash>
+//
+// Salts and hashes other than bcrypt's are encoded using standard base64 without padding.
+func Compare(ctx context.Context, password []byte, hash []byte) error {
+	switch {
+	case isArgon2idHash(hash):
+		return compareArgon2id(ctx, password, hash)
+	case isBcryptHash(hash):
+		return compareBcrypt(ctx, password, hash)
+	case isScryptHash(hash):
+		return compareScrypt(ctx, password, hash)
+	case isPBKDF2Hash(hash):
+		return comparePBKDF2(ctx, password, hash)
+	}
+	return errors.WithStack(ErrUnknownHashAlgorithm)
+}
+
+// Validate returns an error if the hash is not in one of the formats supported by Compare or can not be parsed.
+func Validate(hash []byte) error {
+	var err error
+	switch {
+	case isArgon2idHash(hash):
+		_, _, _, err = decodeHash(string(hash))
+	case isBcryptHash(hash):
+		_, err = bcrypt.Cost(hash)
+	case isScryptHash(hash):
+		_, _, _, err = decodeScryptHash(string(hash))
+	case isPBKDF2Hash(hash):
+		_, _, _, err = decodePBKDF2Hash(string(hash))
+	default:
+		err = ErrUnknownHashAlgorithm
+	}
+	return errors.WithStack(err)
+}
+
+// IsSupported returns true if the hash has the prefix of one of the formats supported by Compare. Unlike
+// Validate, it does not parse the hash.
+func IsSupported(hash []byte) bool {
+	return isArgon2idHash(hash) || isBcryptHash(hash) || isScryptHash(hash) || isPBKDF2Hash(hash)
+}
+
+func isArgon2idHash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
+
+func isBcryptHash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) || bytes.HasPrefix(hash, []byte("$2b$")) || bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+func isScryptHash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$scrypt$"))
+}
+
+func isPBKDF2Hash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$pbkdf2-"))
+}
+
+func compareArgon2id(_ context.Context, password []byte, hash []byte) error {
+	// Extract the parameters, salt and derived key from the encoded password
+	// hash.
+	p, salt, hash, err := decodeHash(string(hash))
+	if err != nil {
+		return err
+	}
+
+	// Derive the key from the other password using the same parameters.
+	otherHash := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	// Check that the contents of the hashed passwords are identical. Note
+	// that we are using the subtle.ConstantTimeCompare() function for this
+	// to help prevent timing attacks.
+	if subtle.ConstantTimeCompare(hash, otherHash) == 1 {
+		return nil
+	}
+	return ErrMismatchedHashAndPassword
+}
+
+func compareBcrypt(_ context.Context, password []byte, hash []byte) error {
+	if err := bcrypt.CompareHashAndPassword(hash, password); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedHashAndPassword
+		}
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func compareScrypt(_ context.Context, password []byte, hash []byte) error {
+	p, salt, hash, err := decodeScryptHash(string(hash))
+	if err != nil {
+		return err
+	}
+
+	otherHash, err := scrypt.Key(password, salt, 1<<p.LogN, p.R, p.P, len(hash))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if subtle.ConstantTimeCompare(hash, otherHash) == 1 {
+		return nil
+	}
+	return ErrMismatchedHashAndPassword
+}
+
+func comparePBKDF2(_ context.Context, password []byte, hash []byte) error {
+	p, salt, hash, err := decodePBKDF2Hash(string(hash))
+	if err != nil {
+		return err
+	}
+
+	otherHash := pbkdf2.Key(password, salt, p.Iterations, len(hash), p.Function)
+	if subtle.ConstantTimeCompare(hash, otherHash) == 1 {
+		return nil
+	}
+	return ErrMismatchedHashAndPassword
+}
+
+func decodeScryptHash(encodedHash string) (p *scryptParameters, salt, hash []byte, err error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 5 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	p = new(scryptParameters)
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &p.LogN, &p.R, &p.P); err != nil {
+		return nil, nil, nil, err
+	}
+	if p.LogN < 1 || p.LogN > 30 || p.R < 1 || p.P < 1 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, hash, err = decodeSaltAndHash(parts[3], parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return p, salt, hash, nil
+}
+
+func decodePBKDF2Hash(encodedHash string) (p *pbkdf2Parameters, salt, hash []byte, err error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 5 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	p = new(pbkdf2Parameters)
+	f, ok := pbkdf2Functions[strings.TrimPrefix(parts[1], "pbkdf2-")]
+	if !ok {
+		return nil, nil, nil, ErrUnknownHashAlgorithm
+	}
+	p.Function = f
+
+	if _, err := fmt.Sscanf(parts[2], "i=%d", &p.Iterations); err != nil {
+		return nil, nil, nil, err
+	}
+	if p.Iterations < 1 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, hash, err = decodeSaltAndHash(parts[3], parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return p, salt, hash, nil
+}
+
+func decodeSaltAndHash(encodedSalt, encodedHash string) (salt, hash []byte, err error) {
+	salt, err = base64.RawStdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hash, err = base64.RawStdEncoding.DecodeString(encodedHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hash) == 0 {
+		return nil, nil, ErrInvalidHash
+	}
+
+	return salt, hash, nil
+}
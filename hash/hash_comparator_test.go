@@ -0,0 +1,55 @@
+package hash_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ory/kratos/hash"
+	"github.com/ory/kratos/internal"
+)
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	argon2id, err := hash.NewHasherArgon2(reg).Generate(ctx, []byte("test"))
+	require.NoError(t, err)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("test"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, h := range []string{
+		string(argon2id),
+		string(bcryptHash),
+		"$scrypt$ln=14,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$RnX2PlT5dpOPBbisl6hW5mtYB0eIlH47bzPwG7V4+Oo",
+		"$pbkdf2-sha1$i=1000$c2FsdHNhbHRzYWx0c2FsdA$9RFFGO2qiTl+r3U617Ka74PcsImet0IIBfqaeKeqOT0",
+		"$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+		"$pbkdf2-sha512$i=1000$c2FsdHNhbHRzYWx0c2FsdA$dyidlA4OGvsctKcO0KZFOlGtA27staAA8mDrz6VzN3Q",
+	} {
+		t.Run("hash="+h, func(t *testing.T) {
+			assert.True(t, hash.IsSupported([]byte(h)))
+			require.NoError(t, hash.Validate([]byte(h)))
+			require.NoError(t, hash.Compare(ctx, []byte("test"), []byte(h)))
+			assert.Error(t, hash.Compare(ctx, []byte("tset"), []byte(h)))
+		})
+	}
+
+	for _, h := range []string{
+		"secret",
+		"$md5$c2FsdA$c2FsdA",
+		"$argon2id$v=19$m=32,t=2,p=4$not-base64$MNzk5BtR2vUhrp6qQEjRNw",
+		"$2a$04$tooshort",
+		"$scrypt$ln=14,r=8$c2FsdHNhbHRzYWx0c2FsdA$RnX2PlT5dpOPBbisl6hW5mtYB0eIlH47bzPwG7V4+Oo",
+		"$pbkdf2-md5$i=1000$c2FsdHNhbHRzYWx0c2FsdA$9RFFGO2qiTl+r3U617Ka74PcsImet0IIBfqaeKeqOT0",
+		"$pbkdf2-sha256$i=0$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE",
+	} {
+		t.Run("invalid="+h, func(t *testing.T) {
+			require.Error(t, hash.Validate([]byte(h)))
+			require.Error(t, hash.Compare(ctx, []byte("test"), []byte(h)))
+		})
+	}
+}
@@ -9,6 +9,9 @@ type Hasher interface {
 
 	// Generate returns a hash derived from the password or an error if the hash method failed.
 	Generate(ctx context.Context, password []byte) ([]byte, error)
+
+	// NeedsRehash returns true if the hash was not generated using the currently configured parameters.
+	NeedsRehash(ctx context.Context, hash []byte) bool
 }
 
 type HashProvider interface {
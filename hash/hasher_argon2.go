@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
-	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"strings"
@@ -60,24 +59,24 @@ func (h *Argon2) Generate(ctx context.Context, password []byte) ([]byte, error)
 	return b.Bytes(), nil
 }
 
+// Compare also accepts imported hashes in the other formats supported by Compare. NeedsRehash returns true for
+// them, so that they are replaced by an Argon2id hash when the user signs in.
 func (h *Argon2) Compare(ctx context.Context, password []byte, hash []byte) error {
-	// Extract the parameters, salt and derived key from the encoded password
-	// hash.
-	p, salt, hash, err := decodeHash(string(hash))
+	return Compare(ctx, password, hash)
+}
+
+func (h *Argon2) NeedsRehash(ctx context.Context, hash []byte) bool {
+	p, _, _, err := decodeHash(string(hash))
 	if err != nil {
-		return err
+		return true
 	}
 
-	// Derive the key from the other password using the same parameters.
-	otherHash := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
-
-	// Check that the contents of the hashed passwords are identical. Note
-	// that we are using the subtle.ConstantTimeCompare() function for this
-	// to help prevent timing attacks.
-	if subtle.ConstantTimeCompare(hash, otherHash) == 1 {
-		return nil
-	}
-	return ErrMismatchedHashAndPassword
+	c := h.c.Configuration(ctx).HasherArgon2()
+	return p.Memory != c.Memory ||
+		p.Iterations != c.Iterations ||
+		p.Parallelism != c.Parallelism ||
+		p.SaltLength != c.SaltLength ||
+		p.KeyLength != c.KeyLength
 }
 
 func decodeHash(encodedHash string) (p *config.HasherArgon2Config, salt, hash []byte, err error) {
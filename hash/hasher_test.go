@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/internal"
 )
@@ -50,3 +51,17 @@ func TestHasher(t *testing.T) {
 		})
 	}
 }
+
+func TestNeedsRehash(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	h := hash.NewHasherArgon2(reg)
+
+	hs, err := h.Generate(context.Background(), []byte("secret"))
+	require.NoError(t, err)
+
+	assert.False(t, h.NeedsRehash(context.Background(), hs))
+	assert.True(t, h.NeedsRehash(context.Background(), []byte("not-a-hash")))
+
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigIterations, 2)
+	assert.True(t, h.NeedsRehash(context.Background(), hs))
+}
@@ -0,0 +1,104 @@
+package hash
+
+import (
+	"context"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+)
+
+type (
+	rehasherDependencies interface {
+		config.Providers
+		identity.PrivilegedPoolProvider
+		HashProvider
+	}
+	RehasherProvider interface {
+		Rehasher() *Rehasher
+	}
+
+	// Rehasher inspects all stored password hashes in batches. Because the plaintext passwords are unknown,
+	// outdated hashes can not be upgraded directly. They are instead upgraded when the user signs in the next time.
+	Rehasher struct {
+		d rehasherDependencies
+	}
+
+	// RehashOptions controls how the stored credentials are iterated.
+	RehashOptions struct {
+		// BatchSize is the number of identities loaded per batch.
+		BatchSize int
+
+		// BatchDelay is the pause between two batches. Use it to avoid load spikes on the database.
+		BatchDelay time.Duration
+
+		// Progress, if set, is called after each batch with the intermediate report.
+		Progress func(report *RehashReport)
+	}
+
+	// RehashReport summarizes the state of the stored password hashes.
+	RehashReport struct {
+		// Identities is the number of inspected identities.
+		Identities int `json:"identities"`
+
+		// Credentials is the number of inspected password credentials.
+		Credentials int `json:"credentials"`
+
+		// Outdated is the number of password credentials which do not use the configured hashing parameters
+		// and will be rehashed on the next sign in.
+		Outdated int `json:"outdated"`
+	}
+)
+
+func NewRehasher(d rehasherDependencies) *Rehasher {
+	return &Rehasher{d: d}
+}
+
+// Report iterates over all identities and counts the password credentials using outdated hashing parameters.
+func (r *Rehasher) Report(ctx context.Context, opts RehashOptions) (*RehashReport, error) {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 100
+	}
+
+	var report RehashReport
+	for page := 1; ; page++ {
+		is, err := r.d.PrivilegedIdentityPool().ListIdentities(ctx, page, opts.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range is {
+			i, err := r.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, is[k].ID)
+			if err != nil {
+				return nil, err
+			}
+
+			report.Identities++
+			c, ok := i.GetCredentials(identity.CredentialsTypePassword)
+			if !ok {
+				continue
+			}
+
+			report.Credentials++
+			if r.d.Hasher().NeedsRehash(ctx, []byte(gjson.GetBytes(c.Config, "hashed_password").String())) {
+				report.Outdated++
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(&report)
+		}
+
+		if len(is) < opts.BatchSize {
+			return &report, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.BatchDelay):
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package hash_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hash"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+)
+
+func TestRehasher(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(t, conf, "file://./stub/identity.schema.json")
+
+	create := func(t *testing.T, identifier string) {
+		hs, err := reg.Hasher().Generate(context.Background(), []byte("secret"))
+		require.NoError(t, err)
+
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{identifier},
+			Config:      []byte(fmt.Sprintf(`{"hashed_password":"%s"}`, hs)),
+		})
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	}
+
+	for k := 0; k < 3; k++ {
+		create(t, fmt.Sprintf("outdated-%d@ory.sh", k))
+	}
+
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigIterations, 2)
+	for k := 0; k < 2; k++ {
+		create(t, fmt.Sprintf("current-%d@ory.sh", k))
+	}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)))
+
+	var batches int
+	report, err := reg.Rehasher().Report(context.Background(), hash.RehashOptions{
+		BatchSize: 2,
+		Progress: func(*hash.RehashReport) {
+			batches++
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, report.Identities)
+	assert.Equal(t, 5, report.Credentials)
+	assert.Equal(t, 3, report.Outdated)
+	assert.Equal(t, 4, batches)
+}
@@ -0,0 +1,187 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusPending = "pending"
+)
+
+// obfuscatedError replaces the errors of readiness checks on the public API.
+const obfuscatedError = "error may contain sensitive information and was obfuscated"
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		config.Providers
+	}
+
+	// PendingMigrationsCounter returns the number of database migrations which were not applied yet.
+	PendingMigrationsCounter func(ctx context.Context) (int, error)
+
+	// Handler serves the health and version endpoints. Unless `serve.health.detailed_output` is enabled,
+	// the responses are the same as the ones of healthx.Handler.
+	Handler struct {
+		*healthx.Handler
+
+		r          handlerDependencies
+		migrations PendingMigrationsCounter
+		startedAt  time.Time
+	}
+
+	// The detailed readiness status
+	//
+	// swagger:model healthDetailedStatus
+	DetailedStatus struct {
+		// Status is `ok` if all checks passed and `error` otherwise.
+		Status string `json:"status"`
+
+		// Checks contains the result of every readiness check by name.
+		Checks map[string]CheckStatus `json:"checks"`
+
+		Build BuildInfo `json:"build"`
+
+		Migrations MigrationStatus `json:"migrations"`
+
+		// StartedAt is the time the instance was started.
+		StartedAt time.Time `json:"started_at"`
+
+		// UptimeSeconds is the number of seconds since the instance was started.
+		UptimeSeconds int64 `json:"uptime_seconds"`
+	}
+	CheckStatus struct {
+		// Status is `ok` or `error`.
+		Status string `json:"status"`
+
+		// DurationMilliseconds is how long the check took.
+		DurationMilliseconds float64 `json:"duration_ms"`
+
+		// Error is the reason the check failed. It is obfuscated on the public API.
+		Error string `json:"error,omitempty"`
+	}
+	MigrationStatus struct {
+		// Status is `ok` if all migrations were applied, `pending` if some were not, and `error` if the
+		// status could not be determined.
+		Status string `json:"status"`
+
+		// Pending is the number of migrations which were not applied yet.
+		Pending int `json:"pending"`
+
+		Error string `json:"error,omitempty"`
+	}
+
+	// The build information
+	//
+	// swagger:model buildInfo
+	BuildInfo struct {
+		Version string `json:"version"`
+		Commit  string `json:"commit"`
+		Date    string `json:"build_date"`
+	}
+)
+
+func NewHandler(r handlerDependencies, checks healthx.ReadyCheckers, migrations PendingMigrationsCounter) *Handler {
+	return &Handler{
+		Handler:    healthx.NewHandler(r.Writer(), config.Version, checks),
+		r:          r,
+		migrations: migrations,
+		startedAt:  time.Now().UTC(),
+	}
+}
+
+// SetRoutes registers this handler's routes.
+func (h *Handler) SetRoutes(r *httprouter.Router, shareErrors bool) {
+	r.GET(healthx.AliveCheckPath, h.Alive)
+	r.GET(healthx.ReadyCheckPath, h.Ready(shareErrors))
+	r.GET(healthx.VersionPath, h.Version)
+}
+
+func buildInfo() BuildInfo {
+	return BuildInfo{Version: config.Version, Commit: config.Commit, Date: config.Date}
+}
+
+// Ready returns the detailed readiness status if it is enabled and the status of healthx.Handler otherwise.
+func (h *Handler) Ready(shareErrors bool) httprouter.Handle {
+	simple := h.Handler.Ready(shareErrors)
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !h.r.Configuration(r.Context()).HealthDetailedOutput() {
+			simple(w, r, ps)
+			return
+		}
+
+		status := h.Status(r.Context(), shareErrors)
+		if status.Status != StatusOK {
+			h.r.Writer().WriteCode(w, r, http.StatusServiceUnavailable, status)
+			return
+		}
+		h.r.Writer().Write(w, r, status)
+	}
+}
+
+// Status runs all readiness checks and collects the detailed readiness status.
+func (h *Handler) Status(ctx context.Context, shareErrors bool) *DetailedStatus {
+	status := &DetailedStatus{
+		Status:        StatusOK,
+		Checks:        make(map[string]CheckStatus, len(h.ReadyChecks)),
+		Build:         buildInfo(),
+		StartedAt:     h.startedAt,
+		UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+	}
+
+	names := make([]string, 0, len(h.ReadyChecks))
+	for name := range h.ReadyChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		start := time.Now()
+		err := h.ReadyChecks[name]()
+		check := CheckStatus{Status: StatusOK, DurationMilliseconds: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			status.Status = StatusError
+			check.Status = StatusError
+			check.Error = obfuscatedError
+			if shareErrors {
+				check.Error = err.Error()
+			}
+		}
+		status.Checks[name] = check
+	}
+
+	status.Migrations.Status = StatusOK
+	if pending, err := h.migrations(ctx); err != nil {
+		status.Migrations.Status = StatusError
+		status.Migrations.Error = obfuscatedError
+		if shareErrors {
+			status.Migrations.Error = err.Error()
+		}
+	} else if pending > 0 {
+		status.Migrations.Status = StatusPending
+		status.Migrations.Pending = pending
+	}
+
+	return status
+}
+
+// Version returns the build information if the detailed output is enabled and only the version otherwise.
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if !h.r.Configuration(r.Context()).HealthDetailedOutput() {
+		h.Handler.Version(w, r, ps)
+		return
+	}
+	h.r.Writer().Write(w, r, buildInfo())
+}
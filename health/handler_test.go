@@ -0,0 +1,106 @@
+package health_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/health"
+	"github.com/ory/kratos/internal"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	newServer := func(t *testing.T, h *health.Handler, shareErrors bool) *httptest.Server {
+		router := httprouter.New()
+		h.SetRoutes(router, shareErrors)
+		ts := httptest.NewServer(router)
+		t.Cleanup(ts.Close)
+		return ts
+	}
+
+	get := func(t *testing.T, ts *httptest.Server, path string, expectedCode int) gjson.Result {
+		res, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectedCode, res.StatusCode, "%s", body)
+		return gjson.ParseBytes(body)
+	}
+
+	ready := newServer(t, reg.HealthHandler(), true)
+
+	failing := health.NewHandler(reg, healthx.ReadyCheckers{
+		"ok":   func() error { return nil },
+		"fail": func() error { return errors.New("connection refused") },
+	}, func(context.Context) (int, error) {
+		return 2, nil
+	})
+	notReadyAdmin := newServer(t, failing, true)
+	notReadyPublic := newServer(t, failing, false)
+
+	t.Run("case=returns the simple output by default", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"ok"}`, get(t, ready, healthx.ReadyCheckPath, http.StatusOK).Raw)
+		assert.JSONEq(t, `{"version":"`+config.Version+`"}`, get(t, ready, healthx.VersionPath, http.StatusOK).Raw)
+		assert.False(t, get(t, notReadyAdmin, healthx.ReadyCheckPath, http.StatusServiceUnavailable).Get("checks").Exists())
+	})
+
+	conf.MustSet(config.ViperKeyHealthDetailedOutput, true)
+	t.Cleanup(func() {
+		conf.MustSet(config.ViperKeyHealthDetailedOutput, false)
+	})
+
+	t.Run("case=returns the detailed output", func(t *testing.T) {
+		res := get(t, ready, healthx.ReadyCheckPath, http.StatusOK)
+		assert.Equal(t, health.StatusOK, res.Get("status").String(), "%s", res.Raw)
+		for _, name := range []string{"database", "database_circuit_breaker"} {
+			assert.Equal(t, health.StatusOK, res.Get("checks."+name+".status").String(), "%s", res.Raw)
+			assert.True(t, res.Get("checks."+name+".duration_ms").Exists(), "%s", res.Raw)
+		}
+		assert.Equal(t, health.StatusOK, res.Get("migrations.status").String(), "%s", res.Raw)
+		assert.Equal(t, config.Version, res.Get("build.version").String(), "%s", res.Raw)
+		assert.Equal(t, config.Commit, res.Get("build.commit").String(), "%s", res.Raw)
+		assert.True(t, res.Get("started_at").Exists(), "%s", res.Raw)
+		assert.True(t, res.Get("uptime_seconds").Exists(), "%s", res.Raw)
+	})
+
+	t.Run("case=reports failing checks", func(t *testing.T) {
+		res := get(t, notReadyAdmin, healthx.ReadyCheckPath, http.StatusServiceUnavailable)
+		assert.Equal(t, health.StatusError, res.Get("status").String(), "%s", res.Raw)
+		assert.Equal(t, health.StatusOK, res.Get("checks.ok.status").String(), "%s", res.Raw)
+		assert.Equal(t, health.StatusError, res.Get("checks.fail.status").String(), "%s", res.Raw)
+		assert.Equal(t, "connection refused", res.Get("checks.fail.error").String(), "%s", res.Raw)
+		assert.Equal(t, health.StatusPending, res.Get("migrations.status").String(), "%s", res.Raw)
+		assert.EqualValues(t, 2, res.Get("migrations.pending").Int(), "%s", res.Raw)
+	})
+
+	t.Run("case=obfuscates errors on the public API", func(t *testing.T) {
+		res := get(t, notReadyPublic, healthx.ReadyCheckPath, http.StatusServiceUnavailable)
+		assert.NotContains(t, res.Raw, "connection refused")
+		assert.Equal(t, health.StatusError, res.Get("checks.fail.status").String(), "%s", res.Raw)
+	})
+
+	t.Run("case=returns the build info", func(t *testing.T) {
+		res := get(t, ready, healthx.VersionPath, http.StatusOK)
+		assert.Equal(t, config.Version, res.Get("version").String(), "%s", res.Raw)
+		assert.Equal(t, config.Commit, res.Get("commit").String(), "%s", res.Raw)
+		assert.Equal(t, config.Date, res.Get("build_date").String(), "%s", res.Raw)
+	})
+
+	t.Run("case=keeps the alive endpoint minimal", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"ok"}`, get(t, ready, healthx.AliveCheckPath, http.StatusOK).Raw)
+	})
+}
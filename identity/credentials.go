@@ -53,12 +53,52 @@ type (
 		UpdatedAt time.Time `json:"-" db:"updated_at"`
 	}
 
+	// CredentialsMetadata contains the non-sensitive information about an identity's credentials.
+	//
+	// swagger:model identityCredentialsMetadata
+	CredentialsMetadata struct {
+		// Type discriminates between different types of credentials.
+		Type CredentialsType `json:"type"`
+
+		// Identifiers represents a list of unique identifiers this credential type matches.
+		Identifiers []string `json:"identifiers"`
+
+		// CreatedAt is the time (UTC) when the credentials were created.
+		CreatedAt time.Time `json:"created_at"`
+
+		// UpdatedAt is the time (UTC) when the credentials were last changed.
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// CredentialsCount is the number of identities per credentials type.
+	//
+	// swagger:model credentialsCount
+	CredentialsCount struct {
+		// Identities is the total number of identities.
+		//
+		// required: true
+		Identities int64 `json:"identities"`
+
+		// Credentials maps each credentials type to the number of identities which have credentials of that type
+		// configured. An identity with several credentials of the same type is only counted once.
+		//
+		// required: true
+		Credentials map[CredentialsType]int64 `json:"credentials"`
+
+		// WithoutCredentials is the number of identities which have no credentials configured at all.
+		//
+		// required: true
+		WithoutCredentials int64 `json:"without_credentials"`
+	}
+
 	// swagger:ignore
 	CredentialIdentifier struct {
 		ID         uuid.UUID `db:"id"`
 		Identifier string    `db:"identifier"`
 		// IdentityCredentialsID is a helper struct field for gobuffalo.pop.
 		IdentityCredentialsID uuid.UUID `json:"-" db:"identity_credential_id"`
+		// PartitionID is a helper struct field for gobuffalo.pop.
+		PartitionID string `json:"-" db:"partition_id"`
 		// CreatedAt is a helper struct field for gobuffalo.pop.
 		CreatedAt time.Time `json:"-" db:"created_at"`
 		// UpdatedAt is a helper struct field for gobuffalo.pop.
@@ -87,6 +127,14 @@ type (
 	ActiveCredentialsCounterStrategyProvider interface {
 		ActiveCredentialsCounterStrategies(context.Context) []ActiveCredentialsCounter
 	}
+
+	// PasswordHashValidatorProvider validates password hashes before they are imported.
+	//
+	// swagger:ignore
+	PasswordHashValidatorProvider interface {
+		// ValidatePasswordHash returns an error if the hash is not in a format which can be used to sign in.
+		ValidatePasswordHash(hash []byte) error
+	}
 )
 
 func (c CredentialsTypeTable) TableName(ctx context.Context) string {
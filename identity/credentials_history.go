@@ -0,0 +1,44 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/corp"
+)
+
+// CredentialIdentifierHistory is an identifier which was removed from an identity's credentials, for example
+// because the user changed their email address. It can be used to find the identity but not to sign in.
+type CredentialIdentifierHistory struct {
+	ID uuid.UUID `json:"-" db:"id"`
+
+	// Identifier is the removed identifier.
+	Identifier string `json:"-" db:"identifier"`
+
+	// IdentityID is a helper struct field for gobuffalo.pop.
+	IdentityID uuid.UUID `json:"-" db:"identity_id"`
+	// CredentialTypeID is a helper struct field for gobuffalo.pop.
+	CredentialTypeID uuid.UUID `json:"-" db:"identity_credential_type_id"`
+	// CreatedAt is the time the identifier was removed.
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+// CredentialsIdentifierFilter restricts a list of identities to those having a credentials identifier matching
+// the identifier.
+type CredentialsIdentifierFilter struct {
+	// Identifier is compared against the identifiers of all credentials types. Password identifiers are
+	// compared case-insensitively.
+	Identifier string
+
+	// IncludeHistory also matches identifiers which were removed from the identity's credentials and are
+	// still retained.
+	IncludeHistory bool
+}
+
+func (h CredentialIdentifierHistory) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "identity_credential_identifier_history")
+}
@@ -0,0 +1,164 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Redacted replaces values which must not be written to the audit log.
+const Redacted = "[redacted]"
+
+// Change describes a modified field of an identity.
+type Change struct {
+	// Path is the path of the field, for example `traits.email` or `credentials.password`.
+	Path string `json:"path"`
+
+	// Before is the previous value or nil if the field was added.
+	Before interface{} `json:"before,omitempty"`
+
+	// After is the new value or nil if the field was removed.
+	After interface{} `json:"after,omitempty"`
+}
+
+// Diff returns the changes between the original and the updated identity, sorted by path. Credentials are always
+// redacted. If redactTraits is true, the values of traits and addresses are redacted as well. Entitlements and
+// session policies are never redacted.
+func Diff(original, updated *Identity, redactTraits bool) []Change {
+	var changes []Change
+	add := func(path string, before, after interface{}, redact bool) {
+		if redact {
+			if before != nil {
+				before = Redacted
+			}
+			if after != nil {
+				after = Redacted
+			}
+		}
+		changes = append(changes, Change{Path: path, Before: before, After: after})
+	}
+
+	if original.SchemaID != updated.SchemaID {
+		add("schema_id", original.SchemaID, updated.SchemaID, false)
+	}
+
+	if original.SchemaVersion != updated.SchemaVersion {
+		add("schema_version", original.SchemaVersion, updated.SchemaVersion, false)
+	}
+
+	before, after := flattenTraits(original.Traits), flattenTraits(updated.Traits)
+	for path, value := range before {
+		if v, ok := after[path]; !ok {
+			add(path, value, nil, redactTraits)
+		} else if !reflect.DeepEqual(value, v) {
+			add(path, value, v, redactTraits)
+		}
+	}
+	for path, value := range after {
+		if _, ok := before[path]; !ok {
+			add(path, nil, value, redactTraits)
+		}
+	}
+
+	if b, a := verifiableAddressValues(original), verifiableAddressValues(updated); !reflect.DeepEqual(b, a) {
+		add("verifiable_addresses", b, a, redactTraits)
+	}
+
+	if b, a := recoveryAddressValues(original), recoveryAddressValues(updated); !reflect.DeepEqual(b, a) {
+		add("recovery_addresses", b, a, redactTraits)
+	}
+
+	if b, a := decodeEntitlements(original.Entitlements), decodeEntitlements(updated.Entitlements); !reflect.DeepEqual(b, a) {
+		add("entitlements", b, a, false)
+	}
+
+	if b, a := sessionPolicyValue(original.SessionPolicy), sessionPolicyValue(updated.SessionPolicy); !reflect.DeepEqual(b, a) {
+		add("session_policy", b, a, false)
+	}
+
+	for t, c := range original.Credentials {
+		if u, ok := updated.Credentials[t]; !ok {
+			add("credentials."+string(t), c.Type, nil, true)
+		} else if !CredentialsEqual(map[CredentialsType]Credentials{t: c}, map[CredentialsType]Credentials{t: u}) {
+			add("credentials."+string(t), c.Type, u.Type, true)
+		}
+	}
+	for t, c := range updated.Credentials {
+		if _, ok := original.Credentials[t]; !ok {
+			add("credentials."+string(t), nil, c.Type, true)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func flattenTraits(traits Traits) map[string]interface{} {
+	flat := map[string]interface{}{}
+	if len(traits) == 0 {
+		return flat
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(traits, &decoded); err != nil {
+		return flat
+	}
+
+	flatten("traits", decoded, flat)
+	return flat
+}
+
+func flatten(prefix string, value interface{}, flat map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			flatten(prefix+"."+k, vv, flat)
+		}
+	case []interface{}:
+		for k, vv := range v {
+			flatten(fmt.Sprintf("%s.%d", prefix, k), vv, flat)
+		}
+	default:
+		flat[prefix] = v
+	}
+}
+
+func decodeEntitlements(e Entitlements) interface{} {
+	if len(e) == 0 {
+		return nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(e, &decoded); err != nil {
+		return string(e)
+	}
+	return decoded
+}
+
+func sessionPolicyValue(p *SessionPolicy) interface{} {
+	if p.IsEmpty() {
+		return nil
+	}
+	return *p
+}
+
+func verifiableAddressValues(i *Identity) []string {
+	values := make([]string, len(i.VerifiableAddresses))
+	for k, a := range i.VerifiableAddresses {
+		values[k] = a.Value
+	}
+	sort.Strings(values)
+	return values
+}
+
+func recoveryAddressValues(i *Identity) []string {
+	values := make([]string, len(i.RecoveryAddresses))
+	for k, a := range i.RecoveryAddresses {
+		values[k] = a.Value
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,84 @@
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/identity"
+)
+
+func TestDiff(t *testing.T) {
+	original := &identity.Identity{
+		SchemaID:     "default",
+		Entitlements: identity.Entitlements(`{"plan":"free"}`),
+		Traits:       identity.Traits(`{"email":"foo@ory.sh","name":{"first":"Foo","last":"Bar"},"tags":["a","b"]}`),
+		VerifiableAddresses: []identity.VerifiableAddress{
+			{Value: "foo@ory.sh"},
+		},
+		Credentials: map[identity.CredentialsType]identity.Credentials{
+			identity.CredentialsTypePassword: {
+				Type:        identity.CredentialsTypePassword,
+				Identifiers: []string{"foo@ory.sh"},
+				Config:      sqlxx.JSONRawMessage(`{"hashed_password":"foo"}`),
+			},
+		},
+	}
+
+	updated := &identity.Identity{
+		SchemaID:      "customer",
+		Entitlements:  identity.Entitlements(`{"plan":"pro"}`),
+		SessionPolicy: &identity.SessionPolicy{Lifespan: "1h"},
+		Traits:        identity.Traits(`{"email":"bar@ory.sh","name":{"first":"Foo"},"tags":["a","b","c"]}`),
+		VerifiableAddresses: []identity.VerifiableAddress{
+			{Value: "bar@ory.sh"},
+		},
+		Credentials: map[identity.CredentialsType]identity.Credentials{
+			identity.CredentialsTypePassword: {
+				Type:        identity.CredentialsTypePassword,
+				Identifiers: []string{"bar@ory.sh"},
+				Config:      sqlxx.JSONRawMessage(`{"hashed_password":"bar"}`),
+			},
+			identity.CredentialsTypeOIDC: {
+				Type:        identity.CredentialsTypeOIDC,
+				Identifiers: []string{"google:1234"},
+				Config:      sqlxx.JSONRawMessage(`{}`),
+			},
+		},
+	}
+
+	t.Run("case=returns all changes", func(t *testing.T) {
+		assert.Equal(t, []identity.Change{
+			{Path: "credentials.oidc", After: identity.Redacted},
+			{Path: "credentials.password", Before: identity.Redacted, After: identity.Redacted},
+			{Path: "entitlements", Before: map[string]interface{}{"plan": "free"}, After: map[string]interface{}{"plan": "pro"}},
+			{Path: "schema_id", Before: "default", After: "customer"},
+			{Path: "session_policy", After: identity.SessionPolicy{Lifespan: "1h"}},
+			{Path: "traits.email", Before: "foo@ory.sh", After: "bar@ory.sh"},
+			{Path: "traits.name.last", Before: "Bar"},
+			{Path: "traits.tags.2", After: "c"},
+			{Path: "verifiable_addresses", Before: []string{"foo@ory.sh"}, After: []string{"bar@ory.sh"}},
+		}, identity.Diff(original, updated, false))
+	})
+
+	t.Run("case=redacts traits", func(t *testing.T) {
+		for _, c := range identity.Diff(original, updated, true) {
+			switch c.Path {
+			case "schema_id", "entitlements", "session_policy":
+				continue
+			}
+			if c.Before != nil {
+				assert.Equal(t, identity.Redacted, c.Before, c.Path)
+			}
+			if c.After != nil {
+				assert.Equal(t, identity.Redacted, c.After, c.Path)
+			}
+		}
+	})
+
+	t.Run("case=returns nothing if unchanged", func(t *testing.T) {
+		assert.Empty(t, identity.Diff(original, original, false))
+	})
+}
@@ -0,0 +1,46 @@
+package identity
+
+import (
+	"strings"
+	"time"
+)
+
+// EmailAddresses returns the email addresses of the identity which are used for verification or recovery.
+func (i *Identity) EmailAddresses() map[string]struct{} {
+	addresses := map[string]struct{}{}
+	for _, a := range i.VerifiableAddresses {
+		if a.Via == VerifiableAddressTypeEmail {
+			addresses[strings.ToLower(a.Value)] = struct{}{}
+		}
+	}
+	for _, a := range i.RecoveryAddresses {
+		if a.Via == RecoveryAddressTypeEmail {
+			addresses[strings.ToLower(a.Value)] = struct{}{}
+		}
+	}
+	return addresses
+}
+
+// EmailAddressesChanged returns true if an email address was added to or removed from the identity.
+func EmailAddressesChanged(original, updated *Identity) bool {
+	before, after := original.EmailAddresses(), updated.EmailAddresses()
+	if len(before) != len(after) {
+		return true
+	}
+
+	for a := range before {
+		if _, ok := after[a]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailChangeCooldownUntil returns the time until which the identity can not change its email addresses
+// again. It returns the zero time if the identity is not in a cooldown.
+func (i *Identity) EmailChangeCooldownUntil(cooldown time.Duration) time.Time {
+	if cooldown <= 0 || i.EmailChangedAt == nil {
+		return time.Time{}
+	}
+	return i.EmailChangedAt.Add(cooldown)
+}
@@ -0,0 +1,67 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+)
+
+// maxIndexedTraitLength is the maximum length of an indexed trait value as limited by the SQL schema.
+const maxIndexedTraitLength = 255
+
+type SchemaExtensionIndexed struct {
+	l sync.Mutex
+	v []IndexedTrait
+	i *Identity
+}
+
+func NewSchemaExtensionIndexed(i *Identity) *SchemaExtensionIndexed {
+	return &SchemaExtensionIndexed{i: i}
+}
+
+func (r *SchemaExtensionIndexed) Run(ctx jsonschema.ValidationContext, s schema.ExtensionConfig, value interface{}) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if s.Indexed.Key == "" {
+		return nil
+	}
+
+	var normalized string
+	switch v := value.(type) {
+	case string:
+		// Searches are case-insensitive, just like unique traits.
+		normalized = strings.ToLower(v)
+	case json.Number:
+		normalized = v.String()
+	case float64, int, int64:
+		normalized = fmt.Sprintf("%v", v)
+	default:
+		return ctx.Error("indexed", "only strings and numbers can be indexed but got %T", value)
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	} else if len(normalized) > maxIndexedTraitLength {
+		return ctx.Error("indexed", "indexed values must not be longer than %d characters", maxIndexedTraitLength)
+	}
+
+	for _, has := range r.v {
+		if has.Key == s.Indexed.Key && has.Value == normalized {
+			return nil
+		}
+	}
+
+	r.v = append(r.v, *NewIndexedTrait(s.Indexed.Key, normalized, r.i.ID))
+	return nil
+}
+
+func (r *SchemaExtensionIndexed) Finish() error {
+	r.i.IndexedTraits = r.v
+	return nil
+}
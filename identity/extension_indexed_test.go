@@ -0,0 +1,80 @@
+package identity
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ory/jsonschema/v3"
+	_ "github.com/ory/jsonschema/v3/fileloader"
+
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaExtensionIndexed(t *testing.T) {
+	iid := x.NewUUID()
+	for k, tc := range []struct {
+		expectErr string
+		doc       string
+		expect    []IndexedTrait
+	}{
+		{
+			doc:    `{}`,
+			expect: []IndexedTrait{},
+		},
+		{
+			doc: `{"username":"FooBar"}`,
+			expect: []IndexedTrait{
+				{Key: "username", Value: "foobar", IdentityID: iid},
+			},
+		},
+		{
+			doc: `{"username":"foo@ory.sh","emails":["foo@ory.sh","FOO@ory.sh","bar@ory.sh"]}`,
+			expect: []IndexedTrait{
+				{Key: "username", Value: "foo@ory.sh", IdentityID: iid},
+				{Key: "email", Value: "foo@ory.sh", IdentityID: iid},
+				{Key: "email", Value: "bar@ory.sh", IdentityID: iid},
+			},
+		},
+		{
+			doc: `{"employee_id":1234}`,
+			expect: []IndexedTrait{
+				{Key: "employee_id", Value: "1234", IdentityID: iid},
+			},
+		},
+		{
+			doc:       `{"tags":{"foo":"bar"}}`,
+			expectErr: "only strings and numbers can be indexed",
+		},
+		{
+			doc:       fmt.Sprintf(`{"username":"%s"}`, strings.Repeat("a", 256)),
+			expectErr: "indexed values must not be longer than 255 characters",
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			id := &Identity{ID: iid}
+			c := jsonschema.NewCompiler()
+			runner, err := schema.NewExtensionRunner(schema.ExtensionRunnerIdentityMetaSchema)
+			require.NoError(t, err)
+
+			e := NewSchemaExtensionIndexed(id)
+			runner.AddRunner(e).Register(c)
+
+			err = c.MustCompile("file://./stub/extension/indexed/schema.json").Validate(bytes.NewBufferString(tc.doc))
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, e.Finish())
+			assert.ElementsMatch(t, tc.expect, id.IndexedTraits)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+)
+
+// maxUniqueTraitLength is the maximum length of a unique trait value as limited by the SQL schema.
+const maxUniqueTraitLength = 255
+
+type SchemaExtensionUnique struct {
+	l sync.Mutex
+	v []UniqueTrait
+	i *Identity
+}
+
+func NewSchemaExtensionUnique(i *Identity) *SchemaExtensionUnique {
+	return &SchemaExtensionUnique{i: i}
+}
+
+func (r *SchemaExtensionUnique) Run(ctx jsonschema.ValidationContext, s schema.ExtensionConfig, value interface{}) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	if s.Unique.Key == "" {
+		return nil
+	}
+
+	var normalized string
+	switch v := value.(type) {
+	case string:
+		// Force case-insensitivity like we do for credential identifiers.
+		normalized = strings.ToLower(v)
+	case json.Number:
+		normalized = v.String()
+	case float64, int, int64:
+		normalized = fmt.Sprintf("%v", v)
+	default:
+		return ctx.Error("unique", "only strings and numbers can be marked as unique but got %T", value)
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	} else if len(normalized) > maxUniqueTraitLength {
+		return ctx.Error("unique", "unique values must not be longer than %d characters", maxUniqueTraitLength)
+	}
+
+	for _, has := range r.v {
+		if has.Key == s.Unique.Key && has.Value == normalized {
+			return nil
+		}
+	}
+
+	r.v = append(r.v, *NewUniqueTrait(s.Unique.Key, normalized, r.i.ID))
+	return nil
+}
+
+func (r *SchemaExtensionUnique) Finish() error {
+	r.i.UniqueTraits = r.v
+	return nil
+}
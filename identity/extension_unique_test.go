@@ -0,0 +1,80 @@
+package identity
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ory/jsonschema/v3"
+	_ "github.com/ory/jsonschema/v3/fileloader"
+
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaExtensionUnique(t *testing.T) {
+	iid := x.NewUUID()
+	for k, tc := range []struct {
+		expectErr string
+		doc       string
+		expect    []UniqueTrait
+	}{
+		{
+			doc:    `{}`,
+			expect: []UniqueTrait{},
+		},
+		{
+			doc: `{"username":"FooBar"}`,
+			expect: []UniqueTrait{
+				{Key: "username", Value: "foobar", IdentityID: iid},
+			},
+		},
+		{
+			doc: `{"username":"foo@ory.sh","emails":["foo@ory.sh","FOO@ory.sh","bar@ory.sh"]}`,
+			expect: []UniqueTrait{
+				{Key: "username", Value: "foo@ory.sh", IdentityID: iid},
+				{Key: "email", Value: "foo@ory.sh", IdentityID: iid},
+				{Key: "email", Value: "bar@ory.sh", IdentityID: iid},
+			},
+		},
+		{
+			doc: `{"employee_id":1234}`,
+			expect: []UniqueTrait{
+				{Key: "employee_id", Value: "1234", IdentityID: iid},
+			},
+		},
+		{
+			doc:       `{"tags":{"foo":"bar"}}`,
+			expectErr: "only strings and numbers can be marked as unique",
+		},
+		{
+			doc:       fmt.Sprintf(`{"username":"%s"}`, strings.Repeat("a", 256)),
+			expectErr: "unique values must not be longer than 255 characters",
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			id := &Identity{ID: iid}
+			c := jsonschema.NewCompiler()
+			runner, err := schema.NewExtensionRunner(schema.ExtensionRunnerIdentityMetaSchema)
+			require.NoError(t, err)
+
+			e := NewSchemaExtensionUnique(id)
+			runner.AddRunner(e).Register(c)
+
+			err = c.MustCompile("file://./stub/extension/unique/schema.json").Validate(bytes.NewBufferString(tc.doc))
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, e.Finish())
+			assert.ElementsMatch(t, tc.expect, id.UniqueTraits)
+		})
+	}
+}
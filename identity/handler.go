@@ -1,28 +1,40 @@
 package identity
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/ory/kratos/driver/config"
 
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
+	"github.com/ory/herodot"
 	"github.com/ory/x/jsonx"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/x"
 )
 
-const RouteBase = "/identities"
+const (
+	RouteBase             = "/identities"
+	RouteCredentialsCount = "/credentials/count"
+)
 
 type (
 	handlerDependencies interface {
 		PoolProvider
 		PrivilegedPoolProvider
 		ManagementProvider
+		JanitorProvider
+		ActiveCredentialsCounterStrategyProvider
+		PasswordHashValidatorProvider
 		x.WriterProvider
+		x.LoggingProvider
 		config.Providers
 	}
 	HandlerProvider interface {
@@ -41,9 +53,14 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteBase, h.list)
 	admin.GET(RouteBase+"/:id", h.get)
 	admin.DELETE(RouteBase+"/:id", h.delete)
+	admin.DELETE(RouteBase+"/:id/deletion", h.cancelDeletion)
 
 	admin.POST(RouteBase, h.create)
 	admin.PUT(RouteBase+"/:id", h.update)
+	admin.POST(RouteMerge, h.merge)
+
+	admin.GET(RouteCredentialsCount, h.countCredentials)
+	admin.GET(RouteExport, h.export)
 }
 
 // A single identity.
@@ -87,13 +104,60 @@ type listIdentityParameters struct {
 	// default: 0
 	// min: 0
 	Page int `json:"page"`
+
+	// Indexed Trait
+	//
+	// The key of an indexed trait as defined in the identity traits schema. If set, only identities
+	// with a matching trait value are returned and `value` is required.
+	//
+	// required: false
+	// in: query
+	Trait string `json:"trait"`
+
+	// Indexed Trait Value
+	//
+	// The value the indexed trait must match. The comparison is case-insensitive.
+	//
+	// required: false
+	// in: query
+	Value string `json:"value"`
+
+	// Indexed Trait Match
+	//
+	// Set to `prefix` to return all identities whose trait value starts with `value`. Defaults to
+	// an exact match.
+	//
+	// required: false
+	// in: query
+	Match string `json:"match"`
+
+	// Credentials Identifier
+	//
+	// If set, only identities with a credentials identifier (for example the email address or username used
+	// to sign in with a password) matching the value are returned. Password identifiers are compared
+	// case-insensitively.
+	//
+	// required: false
+	// in: query
+	CredentialsIdentifier string `json:"credentials_identifier"`
+
+	// Include Historical Credentials Identifiers
+	//
+	// If true, identifiers which were removed from the identities' credentials are matched as well. Requires
+	// `identity.credential_identifier_history.enabled` to be set when the identifiers were changed.
+	//
+	// required: false
+	// in: query
+	IncludeHistory bool `json:"include_history"`
 }
 
 // swagger:route GET /identities admin listIdentities
 //
 // List Identities
 //
-// Lists all identities. Does not support search at the moment.
+// Lists all identities. Identities can be searched by traits which are marked as indexed in the identity
+// traits schema using the `trait`, `value`, and `match` query parameters, or by their credentials identifiers
+// using the `credentials_identifier` and `include_history` query parameters.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
@@ -104,25 +168,189 @@ type listIdentityParameters struct {
 //
 //     Responses:
 //       200: identityList
+//       400: genericError
 //       500: genericError
 func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	page, itemsPerPage := x.ParsePagination(r)
+	u := urlx.AppendPaths(h.r.Configuration(r.Context()).SelfAdminURL(), RouteBase)
+
+	if key := r.URL.Query().Get("trait"); key != "" {
+		h.listByTrait(w, r, u, key, page, itemsPerPage)
+		return
+	}
+
+	if identifier := r.URL.Query().Get("credentials_identifier"); identifier != "" {
+		h.listByCredentialsIdentifier(w, r, u, identifier, page, itemsPerPage)
+		return
+	}
+
 	is, err := h.r.IdentityPool().ListIdentities(r.Context(), page, itemsPerPage)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	if err := h.withCredentialsMetadata(r.Context(), is); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	total, err := h.r.IdentityPool().CountIdentities(r.Context())
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	x.PaginationHeader(w, urlx.AppendPaths(h.r.Configuration(r.Context()).SelfAdminURL(), RouteBase), total, page, itemsPerPage)
+	x.PaginationHeader(w, u, total, page, itemsPerPage)
 	h.r.Writer().Write(w, r, is)
 }
 
+func (h *Handler) listByTrait(w http.ResponseWriter, r *http.Request, u *url.URL, key string, page, itemsPerPage int) {
+	f := TraitFilter{Key: key, Value: r.URL.Query().Get("value")}
+	if f.Value == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Query parameter value must be set when searching by trait.")))
+		return
+	}
+
+	switch match := r.URL.Query().Get("match"); match {
+	case "", "exact":
+	case "prefix":
+		f.Prefix = true
+	default:
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter match must be "exact" or "prefix" but got "%s".`, match)))
+		return
+	}
+
+	is, err := h.r.IdentityPool().ListIdentitiesByTrait(r.Context(), f, page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.withCredentialsMetadata(r.Context(), is); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.r.IdentityPool().CountIdentitiesByTrait(r.Context(), f)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	q := u.Query()
+	for _, k := range []string{"trait", "value", "match"} {
+		if v := r.URL.Query().Get(k); v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	x.PaginationHeader(w, u, total, page, itemsPerPage)
+	h.r.Writer().Write(w, r, is)
+}
+
+func (h *Handler) listByCredentialsIdentifier(w http.ResponseWriter, r *http.Request, u *url.URL, identifier string, page, itemsPerPage int) {
+	f := CredentialsIdentifierFilter{Identifier: identifier}
+	if v := r.URL.Query().Get("include_history"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter include_history must be a boolean but got "%s".`, v)))
+			return
+		}
+		f.IncludeHistory = include
+	}
+
+	is, err := h.r.IdentityPool().ListIdentitiesByCredentialsIdentifier(r.Context(), f, page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.withCredentialsMetadata(r.Context(), is); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.r.IdentityPool().CountIdentitiesByCredentialsIdentifier(r.Context(), f)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	q := u.Query()
+	for _, k := range []string{"credentials_identifier", "include_history"} {
+		if v := r.URL.Query().Get(k); v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	x.PaginationHeader(w, u, total, page, itemsPerPage)
+	h.r.Writer().Write(w, r, is)
+}
+
+// withCredentialsMetadata adds the credentials' metadata to the listed identities.
+func (h *Handler) withCredentialsMetadata(ctx context.Context, is []Identity) error {
+	ids := make([]uuid.UUID, len(is))
+	for k := range is {
+		ids[k] = is[k].ID
+	}
+
+	metadata, err := h.r.PrivilegedIdentityPool().ListCredentialsMetadata(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for k := range is {
+		is[k].CredentialsMetadata = metadata[is[k].ID]
+	}
+	return nil
+}
+
+// The number of identities per credentials type.
+// swagger:response credentialsCount
+// nolint:deadcode,unused
+type credentialsCountResponse struct {
+	// required: true
+	// in: body
+	Body *CredentialsCount
+}
+
+// swagger:route GET /credentials/count admin countCredentials
+//
+// Count Identities per Credentials Type
+//
+// Returns how many identities have credentials of each credentials type configured, as well as how many identities
+// have no credentials at all. Every enabled credentials type is included, even if no identity uses it yet.
+//
+// The counts are computed using aggregate queries and can be used for reporting, for example to track
+// the adoption of a credentials type.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: credentialsCount
+//       500: genericError
+func (h *Handler) countCredentials(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	count, err := h.r.IdentityPool().CountCredentials(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	for _, strategy := range h.r.ActiveCredentialsCounterStrategies(r.Context()) {
+		if _, ok := count.Credentials[strategy.ID()]; !ok {
+			count.Credentials[strategy.ID()] = 0
+		}
+	}
+
+	h.r.Writer().Write(w, r, count)
+}
+
 // swagger:parameters getIdentity
 // nolint:deadcode,unused
 type getIdentityParameters struct {
@@ -152,13 +380,13 @@ type getIdentityParameters struct {
 //       400: genericError
 //       500: genericError
 func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	i, err := h.r.IdentityPool().GetIdentity(r.Context(), x.ParseUUID(ps.ByName("id")))
+	i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	h.r.Writer().Write(w, r, i)
+	h.r.Writer().Write(w, r, i.CopyWithCredentialsMetadata())
 }
 
 // swagger:parameters createIdentity
@@ -182,14 +410,44 @@ type CreateIdentity struct {
 	// required: true
 	// in: body
 	Traits json.RawMessage `json:"traits"`
+
+	// Credentials represents the credentials which should be set for the identity. OpenID Connect
+	// provider and subject pairs and Argon2id password hashes can be imported.
+	//
+	// in: body
+	Credentials *AdminIdentityImportCredentials `json:"credentials,omitempty"`
+
+	// RecoveryDisabled disables the self-service recovery flow for the identity.
+	//
+	// in: body
+	RecoveryDisabled bool `json:"recovery_disabled"`
+
+	// Entitlements sets the identity's feature flags and entitlements. It must be a JSON object.
+	//
+	// in: body
+	Entitlements json.RawMessage `json:"entitlements,omitempty"`
+
+	// SessionPolicy overrides the session configuration for the identity.
+	//
+	// in: body
+	SessionPolicy *SessionPolicy `json:"session_policy,omitempty"`
+
+	// VerifiableAddresses sets the verification status of the identity's addresses, for example to import an
+	// identity exported using `GET /export/identities`. Addresses are derived from the traits, so addresses
+	// which are not part of the traits are ignored.
+	//
+	// in: body
+	VerifiableAddresses []AdminIdentityImportVerifiableAddress `json:"verifiable_addresses,omitempty"`
 }
 
 // swagger:route POST /identities admin createIdentity
 //
 // Create an Identity
 //
-// This endpoint creates an identity. It is NOT possible to set an identity's credentials (password, ...)
-// using this method! A way to achieve that will be introduced in the future.
+// This endpoint creates an identity. It is NOT possible to set an identity's plaintext password using this method!
+// It is however possible to link the identity to OpenID Connect providers using the `credentials.oidc` field
+// and to import a password hash exported from ORY Kratos using the `credentials.password` field.
+// The providers must be configured in `selfservice.methods.oidc.config.providers`.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
@@ -213,19 +471,40 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
-	i := &Identity{SchemaID: cr.SchemaID, Traits: []byte(cr.Traits)}
+	i := &Identity{SchemaID: cr.SchemaID, Traits: []byte(cr.Traits), RecoveryDisabled: cr.RecoveryDisabled, Entitlements: Entitlements(cr.Entitlements), SessionPolicy: cr.SessionPolicy}
+	if err := h.importCredentials(r.Context(), i, cr.Credentials); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := importVerifiableAddresses(i, cr.VerifiableAddresses); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	if err := h.r.IdentityManager().Create(r.Context(), i); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	audit := x.AuditContext(r.Context(), h.r.Audit()).
+		WithField("identity_id", i.ID).
+		WithField("schema_id", i.SchemaID)
+	if len(i.Entitlements) > 0 {
+		audit = audit.WithField("entitlements", json.RawMessage(i.Entitlements))
+	}
+	if i.SessionPolicy != nil {
+		audit = audit.WithField("session_policy", i.SessionPolicy)
+	}
+	audit.Info("Identity was created using the admin API.")
+
 	h.r.Writer().WriteCreated(w, r,
 		urlx.AppendPaths(
 			h.r.Configuration(r.Context()).SelfAdminURL(),
 			"identities",
 			i.ID.String(),
 		).String(),
-		i,
+		i.CopyWithCredentialsMetadata(),
 	)
 }
 
@@ -252,14 +531,35 @@ type UpdateIdentity struct {
 	//
 	// required: true
 	Traits json.RawMessage `json:"traits"`
+
+	// Credentials represents the credentials which should be set for the identity. If set, the
+	// OpenID Connect credentials of the identity are replaced with the given provider and subject pairs
+	// and the password is replaced with the given password hash.
+	Credentials *AdminIdentityImportCredentials `json:"credentials,omitempty"`
+
+	// RecoveryDisabled disables the self-service recovery flow for the identity if set to true and enables
+	// it if set to false. If omitted, the setting is not changed.
+	RecoveryDisabled *bool `json:"recovery_disabled,omitempty"`
+
+	// Entitlements replaces the identity's feature flags and entitlements. It must be a JSON object.
+	// If omitted, the entitlements are not changed.
+	Entitlements json.RawMessage `json:"entitlements,omitempty"`
+
+	// SessionPolicy replaces the identity's session policy. An empty object removes the policy.
+	// If omitted, the session policy is not changed.
+	SessionPolicy *SessionPolicy `json:"session_policy,omitempty"`
+
+	// ResetEmailChangeCooldown lifts the email change cooldown of the identity if set to true, so that it can
+	// change its email addresses using the settings flow again.
+	ResetEmailChangeCooldown bool `json:"reset_email_change_cooldown,omitempty"`
 }
 
 // swagger:route PUT /identities/{id} admin updateIdentity
 //
 // Update an Identity
 //
-// This endpoint updates an identity. It is NOT possible to set an identity's credentials (password, ...)
-// using this method! A way to achieve that will be introduced in the future.
+// This endpoint updates an identity. It is NOT possible to set an identity's password using this method!
+// It is however possible to replace the identity's OpenID Connect providers using the `credentials.oidc` field.
 //
 // The full identity payload (except credentials) is expected. This endpoint does not support patching.
 //
@@ -296,7 +596,28 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		identity.SchemaID = ur.SchemaID
 	}
 
+	if ur.RecoveryDisabled != nil {
+		identity.RecoveryDisabled = *ur.RecoveryDisabled
+	}
+
+	if len(ur.Entitlements) > 0 {
+		identity.Entitlements = Entitlements(ur.Entitlements)
+	}
+
+	if ur.SessionPolicy != nil {
+		identity.SessionPolicy = ur.SessionPolicy
+	}
+
+	if ur.ResetEmailChangeCooldown {
+		identity.EmailChangedAt = nil
+	}
+
 	identity.Traits = []byte(ur.Traits)
+	if err := h.importCredentials(r.Context(), identity, ur.Credentials); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	if err := h.r.IdentityManager().Update(
 		r.Context(),
 		identity,
@@ -306,7 +627,7 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
-	h.r.Writer().Write(w, r, identity)
+	h.r.Writer().Write(w, r, identity.CopyWithCredentialsMetadata())
 }
 
 // swagger:parameters deleteIdentity
@@ -327,6 +648,10 @@ type deleteIdentityParameters struct {
 // This endpoint returns 204 when the identity was deleted or when the identity was not found, in which case it is
 // assumed that is has been deleted already.
 //
+// If `identity.deletion.grace_period` is set, the identity is only scheduled for deletion. Its sessions are revoked,
+// it can no longer sign in, and it is deleted permanently once the grace period has passed. The scheduled deletion
+// time is exposed in the identity's `delete_after` field.
+//
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
 //     Produces:
@@ -339,10 +664,55 @@ type deleteIdentityParameters struct {
 //		 404: genericError
 //       500: genericError
 func (h *Handler) delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	if err := h.r.IdentityPool().(PrivilegedPool).DeleteIdentity(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+	if _, err := h.r.IdentityJanitor().Delete(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// swagger:parameters cancelIdentityDeletion
+// nolint:deadcode,unused
+type cancelIdentityDeletionParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /identities/{id}/deletion admin cancelIdentityDeletion
+//
+// Cancel the Scheduled Deletion of an Identity
+//
+// Calling this endpoint cancels the deletion of an identity which was scheduled for deletion but whose grace
+// period has not yet passed. The identity can sign in again afterwards. Revoked sessions are not restored.
+//
+// Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityResponse
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) cancelDeletion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.IdentityJanitor().Cancel(r.Context(), id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, i.CopyWithCredentialsMetadata())
+}
@@ -0,0 +1,167 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/x"
+)
+
+// RouteExport is not nested below RouteBase because it would conflict with the identity ID route.
+const RouteExport = "/export/identities"
+
+// exportBatchSize is the number of identities loaded at once while exporting.
+const exportBatchSize = 100
+
+// swagger:parameters exportIdentities
+// nolint:deadcode,unused
+type exportIdentitiesParameters struct {
+	// Only export identities using this traits schema.
+	//
+	// required: false
+	// in: query
+	SchemaID string `json:"schema_id"`
+}
+
+// Identities in the format expected by `POST /identities`, one JSON object per line.
+// swagger:response exportedIdentities
+// nolint:deadcode,unused
+type exportedIdentitiesResponse struct {
+	// in: body
+	Body CreateIdentity
+}
+
+// swagger:route GET /export/identities admin exportIdentities
+//
+// Export Identities
+//
+// Streams all identities including their credentials as newline delimited JSON. Every line is a valid request body
+// for `POST /identities`, so the export can be imported into another ORY Kratos instance. Identity IDs and
+// addresses are not exported, addresses are recreated from the traits on import.
+//
+// The export contains password hashes in `credentials.password.hashed_password`! Treat it like a database dump.
+// Every export is written to the audit log.
+//
+// If an error occurs while streaming, the connection is closed without completing the response.
+//
+//     Produces:
+//     - application/x-ndjson
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: exportedIdentities
+//       500: genericError
+func (h *Handler) export(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	schemaID := r.URL.Query().Get("schema_id")
+
+	audit := x.AuditContext(ctx, h.r.Audit()).WithField("schema_id", schemaID)
+
+	// The first batch is loaded before writing the response so that errors can still be reported properly.
+	ids, err := h.r.PrivilegedIdentityPool().ListIdentityIDs(ctx, schemaID, uuid.Nil, exportBatchSize)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	audit.Info("Identity export with credentials was started.")
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	var exported int
+	enc := json.NewEncoder(w)
+	for {
+		for _, id := range ids {
+			i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id)
+			if errors.Is(err, sqlcon.ErrNoRows) {
+				// The identity was deleted while exporting.
+				continue
+			} else if err != nil {
+				h.abortExport(audit, exported, err)
+			}
+
+			if err := enc.Encode(NewExportedIdentity(i)); err != nil {
+				h.abortExport(audit, exported, err)
+			}
+			exported++
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if len(ids) < exportBatchSize {
+			break
+		}
+
+		if ids, err = h.r.PrivilegedIdentityPool().ListIdentityIDs(ctx, schemaID, ids[len(ids)-1], exportBatchSize); err != nil {
+			h.abortExport(audit, exported, err)
+		}
+	}
+
+	audit.WithField("identities", exported).Info("Identity export with credentials was completed.")
+}
+
+// abortExport closes the connection without completing the response so that clients do not mistake a
+// partial export for a complete one.
+func (h *Handler) abortExport(audit *logrusx.Logger, exported int, err error) {
+	audit.WithError(err).WithField("identities", exported).Error("Identity export with credentials was aborted.")
+	panic(http.ErrAbortHandler)
+}
+
+// NewExportedIdentity returns the identity in the format expected by `POST /identities`, including the verification
+// status of its addresses. The identity must have been loaded including its credentials and addresses.
+func NewExportedIdentity(i *Identity) *CreateIdentity {
+	e := &CreateIdentity{
+		SchemaID:         i.SchemaID,
+		Traits:           json.RawMessage(i.Traits),
+		RecoveryDisabled: i.RecoveryDisabled,
+		Entitlements:     json.RawMessage(i.Entitlements),
+		SessionPolicy:    i.SessionPolicy,
+	}
+
+	var creds AdminIdentityImportCredentials
+	if c, ok := i.GetCredentials(CredentialsTypePassword); ok {
+		var password AdminIdentityImportCredentialsPassword
+		if err := json.Unmarshal(c.Config, &password); err == nil && password.HashedPassword != "" {
+			creds.Password = &password
+		}
+	}
+
+	if c, ok := i.GetCredentials(CredentialsTypeOIDC); ok {
+		var oidc AdminIdentityImportCredentialsOIDC
+		if err := json.Unmarshal(c.Config, &oidc); err == nil && len(oidc.Providers) > 0 {
+			creds.OIDC = &oidc
+		}
+	}
+
+	if creds.Password != nil || creds.OIDC != nil {
+		e.Credentials = &creds
+	}
+
+	for _, a := range i.VerifiableAddresses {
+		address := AdminIdentityImportVerifiableAddress{
+			Value:    a.Value,
+			Via:      a.Via,
+			Verified: a.Verified,
+			Status:   a.Status,
+		}
+		if verifiedAt := time.Time(a.VerifiedAt); !verifiedAt.IsZero() {
+			address.VerifiedAt = &verifiedAt
+		}
+		e.VerifiableAddresses = append(e.VerifiableAddresses, address)
+	}
+
+	return e
+}
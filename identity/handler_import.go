@@ -0,0 +1,202 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlxx"
+)
+
+type (
+	// AdminIdentityImportCredentials contains the credentials which are set when creating or updating an
+	// identity using the Admin API.
+	AdminIdentityImportCredentials struct {
+		// OIDC links the identity to one or more OpenID Connect providers.
+		OIDC *AdminIdentityImportCredentialsOIDC `json:"oidc,omitempty"`
+
+		// Password sets the identity's password using a hash exported from ORY Kratos or another system.
+		Password *AdminIdentityImportCredentialsPassword `json:"password,omitempty"`
+	}
+
+	// AdminIdentityImportCredentialsPassword contains the hashed password of the identity.
+	AdminIdentityImportCredentialsPassword struct {
+		// HashedPassword is the hash of the password. Argon2id hashes exported from ORY Kratos as well as
+		// bcrypt, scrypt, and PBKDF2 hashes are supported. This field is sensitive and must be handled
+		// like a password.
+		//
+		// required: true
+		HashedPassword string `json:"hashed_password"`
+	}
+
+	// AdminIdentityImportVerifiableAddress contains the verification status of an address of the identity.
+	AdminIdentityImportVerifiableAddress struct {
+		// Value is the address, for example an email address.
+		//
+		// required: true
+		Value string `json:"value"`
+
+		// Via is the type of the address, for example `email`.
+		//
+		// required: true
+		Via VerifiableAddressType `json:"via"`
+
+		// Verified is true if the address was verified.
+		Verified bool `json:"verified"`
+
+		// VerifiedAt is the time the address was verified.
+		VerifiedAt *time.Time `json:"verified_at,omitempty"`
+
+		// Status is the state of the verification, for example `pending` or `completed`.
+		Status VerifiableAddressStatus `json:"status,omitempty"`
+	}
+
+	// AdminIdentityImportCredentialsOIDC contains the OpenID Connect providers the identity is linked to.
+	AdminIdentityImportCredentialsOIDC struct {
+		// Providers is a list of OpenID Connect provider and subject pairs.
+		//
+		// required: true
+		Providers []AdminIdentityImportCredentialsOIDCProvider `json:"providers"`
+	}
+
+	// AdminIdentityImportCredentialsOIDCProvider is a single OpenID Connect provider the identity is linked to.
+	AdminIdentityImportCredentialsOIDCProvider struct {
+		// Subject is the subject (`sub` claim) of the identity at the OpenID Connect provider.
+		//
+		// required: true
+		Subject string `json:"subject"`
+
+		// Provider is the ID of the OpenID Connect provider as set in the configuration.
+		//
+		// required: true
+		Provider string `json:"provider"`
+	}
+)
+
+// importVerifiableAddresses sets the verification status of the identity's addresses. The addresses are derived
+// from the traits when the identity is validated, which keeps the status of the addresses which are part of the
+// traits and ignores all others.
+func importVerifiableAddresses(i *Identity, addresses []AdminIdentityImportVerifiableAddress) error {
+	for _, a := range addresses {
+		address := VerifiableAddress{
+			Value:    a.Value,
+			Via:      a.Via,
+			Verified: a.Verified,
+			Status:   a.Status,
+		}
+
+		switch address.Status {
+		case "":
+			address.Status = VerifiableAddressStatusPending
+			if a.Verified {
+				address.Status = VerifiableAddressStatusCompleted
+			}
+		case VerifiableAddressStatusPending, VerifiableAddressStatusCompleted:
+		default:
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The status of address "%s" must be one of "%s" or "%s".`,
+				a.Value, VerifiableAddressStatusPending, VerifiableAddressStatusCompleted))
+		}
+
+		if a.VerifiedAt != nil {
+			address.VerifiedAt = sqlxx.NullTime(a.VerifiedAt.UTC())
+		}
+		i.VerifiableAddresses = append(i.VerifiableAddresses, address)
+	}
+	return nil
+}
+
+func (h *Handler) importCredentials(ctx context.Context, i *Identity, creds *AdminIdentityImportCredentials) error {
+	if creds == nil {
+		return nil
+	}
+
+	if creds.OIDC != nil {
+		if err := h.importOIDCCredentials(ctx, i, creds.OIDC); err != nil {
+			return err
+		}
+	}
+
+	if creds.Password != nil {
+		if err := h.importPasswordCredentials(i, creds.Password); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) importPasswordCredentials(i *Identity, creds *AdminIdentityImportCredentialsPassword) error {
+	if err := h.r.ValidatePasswordHash([]byte(creds.HashedPassword)); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.
+			WithReasonf("The hashed password is not a valid Argon2id, bcrypt, scrypt, or PBKDF2 hash: %s", err).
+			WithDetail("credentials_type", CredentialsTypePassword))
+	}
+
+	config, err := json.Marshal(creds)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// The identifiers are set from the traits when the identity is validated.
+	i.SetCredentials(CredentialsTypePassword, Credentials{
+		Type:        CredentialsTypePassword,
+		Identifiers: []string{},
+		Config:      config,
+	})
+	return nil
+}
+
+func (h *Handler) importOIDCCredentials(ctx context.Context, i *Identity, creds *AdminIdentityImportCredentialsOIDC) error {
+	configured := map[string]bool{}
+	for _, id := range gjson.GetBytes(h.r.Configuration(ctx).SelfServiceStrategy(string(CredentialsTypeOIDC)).Config, "providers.#.id").Array() {
+		configured[id.String()] = true
+	}
+
+	var target AdminIdentityImportCredentialsOIDC
+	identifiers := make([]string, 0, len(creds.Providers))
+	for _, p := range creds.Providers {
+		if !configured[p.Provider] {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`OpenID Connect Provider "%s" is unknown or has not been configured.`, p.Provider))
+		} else if p.Subject == "" {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The subject for OpenID Connect Provider "%s" must not be empty.`, p.Provider))
+		}
+
+		identifier := fmt.Sprintf("%s:%s", p.Provider, p.Subject)
+		var found bool
+		for _, existing := range identifiers {
+			if existing == identifier {
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+
+		identifiers = append(identifiers, identifier)
+		target.Providers = append(target.Providers, p)
+	}
+
+	if len(target.Providers) == 0 {
+		i.lock().Lock()
+		delete(i.Credentials, CredentialsTypeOIDC)
+		i.lock().Unlock()
+		return nil
+	}
+
+	config, err := json.Marshal(&target)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	i.SetCredentials(CredentialsTypeOIDC, Credentials{
+		Type:        CredentialsTypeOIDC,
+		Identifiers: identifiers,
+		Config:      config,
+	})
+	return nil
+}
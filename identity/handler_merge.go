@@ -0,0 +1,94 @@
+package identity
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/jsonx"
+)
+
+const RouteMerge = RouteBase + "/merge"
+
+// swagger:parameters mergeIdentities
+// nolint:deadcode,unused
+type mergeIdentitiesParameters struct {
+	// in: body
+	Body MergeIdentities
+}
+
+// The result of merging two identities.
+//
+// swagger:response identityMergeResult
+// nolint:deadcode,unused
+type identityMergeResultResponse struct {
+	// required: true
+	// in: body
+	Body *MergeResult
+}
+
+type MergeIdentities struct {
+	// PrimaryID is the ID of the identity which is kept.
+	//
+	// required: true
+	PrimaryID uuid.UUID `json:"primary_id"`
+
+	// SecondaryID is the ID of the identity which is merged into the primary identity and deleted.
+	//
+	// required: true
+	SecondaryID uuid.UUID `json:"secondary_id"`
+
+	// ConflictPolicy decides which credentials are kept if both identities have credentials of the same type.
+	// One of `fail`, `keep_primary`, and `keep_secondary`. Defaults to `identity.merge.conflict_policy`.
+	ConflictPolicy MergeConflictPolicy `json:"conflict_policy,omitempty"`
+}
+
+// swagger:route POST /identities/merge admin mergeIdentities
+//
+// Merge two Identities
+//
+// This endpoint merges duplicate identities. The credentials and sessions of the secondary identity are moved to
+// the primary identity and the secondary identity is deleted. The traits of the primary identity are kept. Addresses
+// of the primary identity which the secondary identity verified are marked as verified, the other addresses of the
+// secondary identity are moved to the primary identity.
+//
+// The OpenID Connect providers linked to either identity are combined. If both identities have other credentials of
+// the same type, the conflict policy decides which ones are kept. With the default policy `fail`, the merge is
+// rejected and the error details list the `conflicting_credentials`.
+//
+// The response reports what was moved, combined, and discarded.
+//
+// Every merge is written to the audit log.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityMergeResult
+//       400: genericError
+//       404: genericError
+//       409: genericError
+//       500: genericError
+func (h *Handler) merge(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var mr MergeIdentities
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&mr); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	result, err := h.r.IdentityManager().Merge(r.Context(), mr.PrimaryID, mr.SecondaryID, mr.ConflictPolicy, ManagerAllowWriteProtectedTraits)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	result.Identity = result.Identity.CopyWithCredentialsMetadata()
+	h.r.Writer().Write(w, r, result)
+}
@@ -2,12 +2,15 @@ package identity_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/internal/testhelpers"
@@ -20,6 +23,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
@@ -232,6 +236,234 @@ func TestHandler(t *testing.T) {
 		assert.EqualValues(t, "ory street", res.Get("traits.address").String(), "%s", res.Raw)
 	})
 
+	t.Run("case=should create and update an identity with self-service recovery disabled", func(t *testing.T) {
+		res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits": {"bar":"baz"}, "recovery_disabled": true}`))
+		assert.True(t, res.Get("recovery_disabled").Bool(), "%s", res.Raw)
+		id := res.Get("id").String()
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}}`))
+		assert.True(t, res.Get("recovery_disabled").Bool(), "the flag must not change if omitted: %s", res.Raw)
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}, "recovery_disabled": false}`))
+		assert.False(t, res.Get("recovery_disabled").Bool(), "%s", res.Raw)
+
+		res = get(t, "/identities/"+id, http.StatusOK)
+		assert.False(t, res.Get("recovery_disabled").Bool(), "%s", res.Raw)
+	})
+
+	t.Run("case=should create and update an identity with entitlements", func(t *testing.T) {
+		res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits": {"bar":"baz"}, "entitlements": ["pro"]}`))
+		assert.Contains(t, res.Get("error.reason").String(), "JSON object", "%s", res.Raw)
+
+		res = send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits": {"bar":"baz"}, "entitlements": {"plan":"pro"}}`))
+		assert.Equal(t, "pro", res.Get("entitlements.plan").String(), "%s", res.Raw)
+		id := res.Get("id").String()
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}}`))
+		assert.Equal(t, "pro", res.Get("entitlements.plan").String(), "the entitlements must not change if omitted: %s", res.Raw)
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}, "entitlements": {"plan":"free"}}`))
+		assert.Equal(t, "free", res.Get("entitlements.plan").String(), "%s", res.Raw)
+
+		res = get(t, "/identities/"+id, http.StatusOK)
+		assert.Equal(t, "free", res.Get("entitlements.plan").String(), "%s", res.Raw)
+	})
+
+	t.Run("case=should create and update an identity with a session policy", func(t *testing.T) {
+		res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits": {"bar":"baz"}, "session_policy": {"lifespan": "forever"}}`))
+		assert.Contains(t, res.Get("error.reason").String(), "lifespan", "%s", res.Raw)
+
+		send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits": {"bar":"baz"}, "session_policy": {"max_sessions": 1}}`))
+
+		res = send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits": {"bar":"baz"}, "session_policy": {"lifespan": "1h", "disable_refresh": true}}`))
+		assert.Equal(t, "1h", res.Get("session_policy.lifespan").String(), "%s", res.Raw)
+		assert.True(t, res.Get("session_policy.disable_refresh").Bool(), "%s", res.Raw)
+		id := res.Get("id").String()
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}}`))
+		assert.Equal(t, "1h", res.Get("session_policy.lifespan").String(), "the session policy must not change if omitted: %s", res.Raw)
+
+		res = send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}, "session_policy": {}}`))
+		assert.False(t, res.Get("session_policy").Exists(), "an empty session policy must remove the policy: %s", res.Raw)
+
+		res = get(t, "/identities/"+id, http.StatusOK)
+		assert.False(t, res.Get("session_policy").Exists(), "%s", res.Raw)
+	})
+
+	t.Run("case=should reset the email change cooldown", func(t *testing.T) {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"bar":"baz"}`)
+		changedAt := time.Now().UTC()
+		i.EmailChangedAt = &changedAt
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+		res := get(t, "/identities/"+i.ID.String(), http.StatusOK)
+		assert.True(t, res.Get("email_changed_at").Exists(), "%s", res.Raw)
+
+		res = send(t, "PUT", "/identities/"+i.ID.String(), http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}}`))
+		assert.True(t, res.Get("email_changed_at").Exists(), "the cooldown must not be reset if omitted: %s", res.Raw)
+
+		res = send(t, "PUT", "/identities/"+i.ID.String(), http.StatusOK, json.RawMessage(`{"traits": {"bar":"baz"}, "reset_email_change_cooldown": true}`))
+		assert.False(t, res.Get("email_changed_at").Exists(), "%s", res.Raw)
+
+		res = get(t, "/identities/"+i.ID.String(), http.StatusOK)
+		assert.False(t, res.Get("email_changed_at").Exists(), "%s", res.Raw)
+	})
+
+	t.Run("suite=import oidc credentials", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".oidc", map[string]interface{}{
+			"enabled": true,
+			"config": map[string]interface{}{
+				"providers": []map[string]interface{}{
+					{"id": "google", "provider": "google", "client_id": "foo", "client_secret": "bar", "mapper_url": "file://./stub/oidc.jsonnet"},
+					{"id": "github", "provider": "github", "client_id": "foo", "client_secret": "bar", "mapper_url": "file://./stub/oidc.jsonnet"},
+				},
+			},
+		})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".oidc", nil)
+		})
+
+		var id string
+		t.Run("case=should fail to create an identity with an unknown provider", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits":{"bar":"oidc"},"credentials":{"oidc":{"providers":[{"provider":"unknown","subject":"1234"}]}}}`))
+			assert.Contains(t, res.Get("error.reason").String(), "unknown", "%s", res.Raw)
+		})
+
+		t.Run("case=should fail to create an identity with an empty subject", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits":{"bar":"oidc"},"credentials":{"oidc":{"providers":[{"provider":"google","subject":""}]}}}`))
+			assert.Contains(t, res.Get("error.reason").String(), "subject", "%s", res.Raw)
+		})
+
+		t.Run("case=should create an identity with oidc credentials", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"oidc"},"credentials":{"oidc":{"providers":[{"provider":"google","subject":"1234"}]}}}`))
+			id = res.Get("id").String()
+
+			i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			c, ok := i.GetCredentials(identity.CredentialsTypeOIDC)
+			require.True(t, ok)
+			assert.EqualValues(t, []string{"google:1234"}, c.Identifiers)
+			assert.EqualValues(t, "1234", gjson.GetBytes(c.Config, "providers.0.subject").String(), "%s", c.Config)
+			assert.EqualValues(t, "google", gjson.GetBytes(c.Config, "providers.0.provider").String(), "%s", c.Config)
+		})
+
+		t.Run("case=should replace the oidc credentials on update", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits":{"bar":"oidc"},"credentials":{"oidc":{"providers":[{"provider":"github","subject":"5678"}]}}}`))
+
+			i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			c, ok := i.GetCredentials(identity.CredentialsTypeOIDC)
+			require.True(t, ok)
+			assert.EqualValues(t, []string{"github:5678"}, c.Identifiers)
+		})
+	})
+
+	t.Run("suite=export identities", func(t *testing.T) {
+		email := x.NewUUID().String() + "@ory.sh"
+		hash := "$argon2id$v=19$m=32,t=2,p=4$cm94YnRVOW5jZzFzcVE4bQ$MNzk5BtR2vUhrp6qQEjRNw"
+
+		t.Run("case=should fail to import a password which is not hashed", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"schema_id":"customer","traits":{"email":"`+email+`"},"credentials":{"password":{"hashed_password":"secret"}}}`))
+			assert.Contains(t, res.Get("error.reason").String(), "Argon2id", "%s", res.Raw)
+			assert.Equal(t, "password", res.Get("error.details.credentials_type").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should import a pbkdf2 hash and reject a malformed one", func(t *testing.T) {
+			email := x.NewUUID().String() + "@ory.sh"
+			res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"schema_id":"customer","traits":{"email":"`+email+`"},"credentials":{"password":{"hashed_password":"$pbkdf2-sha256$i=1000$c2FsdA"}}}`))
+			assert.Contains(t, res.Get("error.reason").String(), "PBKDF2", "%s", res.Raw)
+
+			send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"schema_id":"customer","traits":{"email":"`+email+`"},"credentials":{"password":{"hashed_password":"$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0c2FsdA$4+KBqsOVLqj6E9Td14CGM3lT9IqBoXdgdB7aKUjWvVE"}}}`))
+		})
+
+		res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"schema_id":"customer","traits":{"email":"`+email+`"},"credentials":{"password":{"hashed_password":"`+hash+`"}}}`))
+		id := x.ParseUUID(res.Get("id").String())
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), id)
+		require.NoError(t, err)
+		c, ok := i.GetCredentials(identity.CredentialsTypePassword)
+		require.True(t, ok)
+		assert.EqualValues(t, []string{email}, c.Identifiers)
+		assert.EqualValues(t, hash, gjson.GetBytes(c.Config, "hashed_password").String())
+
+		var export = func(t *testing.T, query string) []gjson.Result {
+			res, err := ts.Client().Get(ts.URL + "/export/identities" + query)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+			assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+			var lines []gjson.Result
+			for _, line := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+				if len(line) > 0 {
+					lines = append(lines, gjson.ParseBytes(line))
+				}
+			}
+			return lines
+		}
+
+		require.Len(t, i.VerifiableAddresses, 1)
+		verifiedAt := time.Now().UTC().Truncate(time.Second)
+		address := i.VerifiableAddresses[0]
+		address.Verified = true
+		address.Status = identity.VerifiableAddressStatusCompleted
+		address.VerifiedAt = sqlxx.NullTime(verifiedAt)
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateVerifiableAddress(context.Background(), &address))
+
+		var exported string
+		t.Run("case=should export identities with credentials", func(t *testing.T) {
+			lines := export(t, "?schema_id=customer")
+			for _, line := range lines {
+				assert.Equal(t, "customer", line.Get("schema_id").String(), "%s", line.Raw)
+				if line.Get("traits.email").String() == email {
+					exported = line.Raw
+				}
+			}
+
+			require.NotEmpty(t, exported)
+			assert.Equal(t, hash, gjson.Get(exported, "credentials.password.hashed_password").String(), "%s", exported)
+			assert.False(t, gjson.Get(exported, "id").Exists(), "%s", exported)
+			assert.Equal(t, email, gjson.Get(exported, "verifiable_addresses.0.value").String(), "%s", exported)
+			assert.True(t, gjson.Get(exported, "verifiable_addresses.0.verified").Bool(), "%s", exported)
+			assert.Equal(t, "completed", gjson.Get(exported, "verifiable_addresses.0.status").String(), "%s", exported)
+			assert.True(t, gjson.Get(exported, "verifiable_addresses.0.verified_at").Exists(), "%s", exported)
+		})
+
+		t.Run("case=should filter by schema", func(t *testing.T) {
+			for _, line := range export(t, "?schema_id=employee") {
+				assert.Equal(t, "employee", line.Get("schema_id").String(), "%s", line.Raw)
+			}
+			assert.Empty(t, export(t, "?schema_id=does-not-exist"))
+		})
+
+		t.Run("case=should import the exported identity", func(t *testing.T) {
+			remove(t, "/identities/"+id.String(), http.StatusNoContent)
+
+			res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(exported))
+			i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(res.Get("id").String()))
+			require.NoError(t, err)
+			c, ok := i.GetCredentials(identity.CredentialsTypePassword)
+			require.True(t, ok)
+			assert.EqualValues(t, []string{email}, c.Identifiers)
+			assert.EqualValues(t, hash, gjson.GetBytes(c.Config, "hashed_password").String())
+
+			require.Len(t, i.VerifiableAddresses, 1)
+			assert.Equal(t, email, i.VerifiableAddresses[0].Value)
+			assert.True(t, i.VerifiableAddresses[0].Verified)
+			assert.Equal(t, identity.VerifiableAddressStatusCompleted, i.VerifiableAddresses[0].Status)
+			assert.True(t, verifiedAt.Equal(time.Time(i.VerifiableAddresses[0].VerifiedAt).UTC()))
+		})
+
+		t.Run("case=should reject an unknown address status", func(t *testing.T) {
+			email := x.NewUUID().String() + "@ory.sh"
+			send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"schema_id":"customer","traits":{"email":"`+email+`"},"verifiable_addresses":[{"value":"`+email+`","via":"email","status":"unknown"}]}`))
+		})
+	})
+
 	t.Run("case=should be able to update multiple identities", func(t *testing.T) {
 		for i := 0; i <= 5; i++ {
 			var cr identity.CreateIdentity
@@ -254,10 +486,65 @@ func TestHandler(t *testing.T) {
 
 	t.Run("case=should list all identities", func(t *testing.T) {
 		res := get(t, "/identities", http.StatusOK)
-		assert.Empty(t, res.Get("0.credentials").String(), "%s", res.Raw)
+		assert.NotContains(t, res.Get("#.credentials").Raw, "config", "%s", res.Raw)
 		assert.EqualValues(t, "baz", res.Get(`#(traits.bar=="baz").traits.bar`).String(), "%s", res.Raw)
 	})
 
+	t.Run("case=should list identities by indexed trait", func(t *testing.T) {
+		for _, department := range []string{"Engineering", "engineering-ops", "Sales"} {
+			send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"department":"`+department+`"}}`))
+		}
+
+		res := get(t, "/identities?trait=department&value=engineering", http.StatusOK)
+		require.Len(t, res.Array(), 1, "%s", res.Raw)
+		assert.EqualValues(t, "Engineering", res.Get("0.traits.department").String(), "%s", res.Raw)
+
+		res = get(t, "/identities?trait=department&value=ENGINEERING&match=prefix", http.StatusOK)
+		assert.Len(t, res.Array(), 2, "%s", res.Raw)
+
+		res = get(t, "/identities?trait=department&value=marketing", http.StatusOK)
+		assert.Len(t, res.Array(), 0, "%s", res.Raw)
+
+		res = get(t, "/identities?trait=department", http.StatusBadRequest)
+		assert.Contains(t, res.Get("error.reason").String(), "value must be set", "%s", res.Raw)
+
+		res = get(t, "/identities?trait=department&value=sales&match=suffix", http.StatusBadRequest)
+		assert.Contains(t, res.Get("error.reason").String(), `must be "exact" or "prefix"`, "%s", res.Raw)
+	})
+
+	t.Run("case=should list identities by credentials identifier", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryEnabled, true)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryEnabled, false)
+		})
+
+		previous, current := x.NewUUID().String()+"@ory.sh", x.NewUUID().String()+"@ory.sh"
+		i := identity.NewIdentity("")
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type: identity.CredentialsTypePassword, Identifiers: []string{previous}, Config: sqlxx.JSONRawMessage(`{}`)})
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type: identity.CredentialsTypePassword, Identifiers: []string{current}, Config: sqlxx.JSONRawMessage(`{}`)})
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(context.Background(), i))
+
+		res := get(t, "/identities?credentials_identifier="+current, http.StatusOK)
+		require.Len(t, res.Array(), 1, "%s", res.Raw)
+		assert.EqualValues(t, i.ID.String(), res.Get("0.id").String(), "%s", res.Raw)
+		assert.Equal(t, current, res.Get("0.credentials.password.identifiers.0").String(), "%s", res.Raw)
+		assert.False(t, res.Get("0.credentials.password.config").Exists(), "%s", res.Raw)
+
+		res = get(t, "/identities?credentials_identifier="+previous, http.StatusOK)
+		assert.Len(t, res.Array(), 0, "%s", res.Raw)
+
+		res = get(t, "/identities?credentials_identifier="+previous+"&include_history=true", http.StatusOK)
+		require.Len(t, res.Array(), 1, "%s", res.Raw)
+		assert.EqualValues(t, i.ID.String(), res.Get("0.id").String(), "%s", res.Raw)
+
+		res = get(t, "/identities?credentials_identifier="+previous+"&include_history=maybe", http.StatusBadRequest)
+		assert.Contains(t, res.Get("error.reason").String(), "must be a boolean", "%s", res.Raw)
+	})
+
 	t.Run("case=should not be able to update an identity that does not exist yet", func(t *testing.T) {
 		res := send(t, "PUT", "/identities/not-found", http.StatusNotFound, json.RawMessage(`{"traits": {"bar":"baz"}}`))
 		assert.Contains(t, res.Get("error.message").String(), "Unable to locate the resource", "%s", res.Raw)
@@ -266,4 +553,210 @@ func TestHandler(t *testing.T) {
 	t.Run("case=should return 404 for non-existing identities", func(t *testing.T) {
 		remove(t, "/identities/"+x.NewUUID().String(), http.StatusNotFound)
 	})
+
+	t.Run("suite=deletion grace period", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyIdentityDeletionGracePeriod, "1h")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyIdentityDeletionGracePeriod, "0s")
+		})
+
+		create := func(t *testing.T) string {
+			res := send(t, "POST", "/identities", http.StatusCreated, identity.CreateIdentity{Traits: []byte(`{"bar":"baz"}`)})
+			return res.Get("id").String()
+		}
+
+		t.Run("case=should schedule the deletion and allow cancelling it", func(t *testing.T) {
+			id := create(t)
+			assert.False(t, get(t, "/identities/"+id, http.StatusOK).Get("delete_after").Exists())
+
+			remove(t, "/identities/"+id, http.StatusNoContent)
+			deleteAfter := get(t, "/identities/"+id, http.StatusOK).Get("delete_after")
+			require.True(t, deleteAfter.Exists())
+			assert.WithinDuration(t, time.Now().Add(time.Hour), deleteAfter.Time(), time.Minute)
+
+			remove(t, "/identities/"+id, http.StatusNoContent)
+			assert.Equal(t, deleteAfter.String(), get(t, "/identities/"+id, http.StatusOK).Get("delete_after").String(), "deleting again must not extend the grace period")
+
+			res := send(t, "DELETE", "/identities/"+id+"/deletion", http.StatusOK, nil)
+			assert.False(t, res.Get("delete_after").Exists(), "%s", res.Raw)
+			assert.False(t, get(t, "/identities/"+id, http.StatusOK).Get("delete_after").Exists())
+
+			send(t, "DELETE", "/identities/"+id+"/deletion", http.StatusBadRequest, nil)
+		})
+
+		t.Run("case=should return 404 for non-existing identities", func(t *testing.T) {
+			remove(t, "/identities/"+x.NewUUID().String(), http.StatusNotFound)
+			send(t, "DELETE", "/identities/"+x.NewUUID().String()+"/deletion", http.StatusNotFound, nil)
+		})
+
+		t.Run("case=should purge identities once the grace period passed", func(t *testing.T) {
+			due, pending := create(t), create(t)
+			require.NoError(t, reg.PrivilegedIdentityPool().ScheduleIdentityDeletion(context.Background(), x.ParseUUID(due), time.Now().Add(-time.Minute)))
+			remove(t, "/identities/"+pending, http.StatusNoContent)
+
+			purged, err := reg.IdentityJanitor().Purge(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 1, purged)
+
+			get(t, "/identities/"+due, http.StatusNotFound)
+			get(t, "/identities/"+pending, http.StatusOK)
+		})
+	})
+
+	t.Run("suite=merge identities", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".oidc", map[string]interface{}{
+			"enabled": true,
+			"config": map[string]interface{}{
+				"providers": []map[string]interface{}{
+					{"id": "google", "provider": "google", "client_id": "foo", "client_secret": "bar", "mapper_url": "file://./stub/oidc.jsonnet"},
+					{"id": "github", "provider": "github", "client_id": "foo", "client_secret": "bar", "mapper_url": "file://./stub/oidc.jsonnet"},
+				},
+			},
+		})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".oidc", nil)
+		})
+
+		create := func(t *testing.T, credentials string) string {
+			return send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"merge"}`+credentials+`}`)).Get("id").String()
+		}
+
+		oidcIdentifiers := func(t *testing.T, id string) []string {
+			i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			c, ok := i.GetCredentials(identity.CredentialsTypeOIDC)
+			require.True(t, ok)
+			return c.Identifiers
+		}
+
+		merge := func(t *testing.T, primary, secondary, policy string, expectCode int) gjson.Result {
+			return send(t, "POST", identity.RouteMerge, expectCode, json.RawMessage(`{"primary_id":"`+primary+`","secondary_id":"`+secondary+`","conflict_policy":"`+policy+`"}`))
+		}
+
+		t.Run("case=should move credentials and sessions", func(t *testing.T) {
+			primary := create(t, "")
+			secondary := create(t, `,"credentials":{"oidc":{"providers":[{"provider":"google","subject":"merge-1"}]}}`)
+
+			sess := session.Session{ID: x.NewUUID(), Identity: &identity.Identity{ID: x.ParseUUID(secondary)}, IdentityID: x.ParseUUID(secondary)}
+			require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), &sess))
+
+			res := merge(t, primary, secondary, "", http.StatusOK)
+			assert.Equal(t, primary, res.Get("identity.id").String(), "%s", res.Raw)
+			assert.True(t, res.Get("identity.credentials.oidc").Exists(), "%s", res.Raw)
+			assert.EqualValues(t, "oidc", res.Get("moved_credentials.0").String(), "%s", res.Raw)
+			assert.EqualValues(t, 1, res.Get("moved_sessions").Int(), "%s", res.Raw)
+
+			get(t, "/identities/"+secondary, http.StatusNotFound)
+			assert.EqualValues(t, []string{"google:merge-1"}, oidcIdentifiers(t, primary))
+
+			actual, err := reg.SessionPersister().GetSession(context.Background(), sess.ID)
+			require.NoError(t, err)
+			assert.Equal(t, primary, actual.IdentityID.String())
+		})
+
+		t.Run("case=should move addresses", func(t *testing.T) {
+			primary := create(t, "")
+
+			email := x.NewUUID().String() + "@ory.sh"
+			secondary := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			secondary.Traits = identity.Traits(`{"bar":"merge"}`)
+			verified := identity.NewVerifiableEmailAddress(email, secondary.ID)
+			verified.Verified = true
+			verified.Status = identity.VerifiableAddressStatusCompleted
+			secondary.VerifiableAddresses = []identity.VerifiableAddress{*verified}
+			secondary.RecoveryAddresses = []identity.RecoveryAddress{*identity.NewRecoveryEmailAddress(email, secondary.ID)}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), secondary))
+
+			res := merge(t, primary, secondary.ID.String(), "", http.StatusOK)
+			assert.EqualValues(t, email, res.Get("moved_addresses.0").String(), "%s", res.Raw)
+
+			actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(primary))
+			require.NoError(t, err)
+			require.Len(t, actual.VerifiableAddresses, 1)
+			assert.Equal(t, email, actual.VerifiableAddresses[0].Value)
+			assert.True(t, actual.VerifiableAddresses[0].Verified)
+			require.Len(t, actual.RecoveryAddresses, 1)
+			assert.Equal(t, email, actual.RecoveryAddresses[0].Value)
+		})
+
+		t.Run("case=should combine the OpenID Connect providers", func(t *testing.T) {
+			primary := create(t, `,"credentials":{"oidc":{"providers":[{"provider":"google","subject":"merge-2"}]}}`)
+			secondary := create(t, `,"credentials":{"oidc":{"providers":[{"provider":"github","subject":"merge-3"}]}}`)
+
+			res := merge(t, primary, secondary, "", http.StatusOK)
+			assert.EqualValues(t, "oidc", res.Get("merged_credentials.0").String(), "%s", res.Raw)
+			assert.Empty(t, res.Get("moved_credentials").Array(), "%s", res.Raw)
+			assert.ElementsMatch(t, []string{"google:merge-2", "github:merge-3"}, oidcIdentifiers(t, primary))
+			get(t, "/identities/"+secondary, http.StatusNotFound)
+		})
+
+		t.Run("case=should resolve conflicts according to the policy", func(t *testing.T) {
+			createWithPassword := func(t *testing.T, hash string) string {
+				i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+				i.Traits = identity.Traits(`{"bar":"merge"}`)
+				i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+					Identifiers: []string{x.NewUUID().String()},
+					Config:      sqlxx.JSONRawMessage(`{"hashed_password":"` + hash + `"}`),
+				})
+				require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+				return i.ID.String()
+			}
+
+			hashedPassword := func(t *testing.T, id string) string {
+				i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), x.ParseUUID(id))
+				require.NoError(t, err)
+				c, ok := i.GetCredentials(identity.CredentialsTypePassword)
+				require.True(t, ok)
+				return gjson.GetBytes(c.Config, "hashed_password").String()
+			}
+
+			primary := createWithPassword(t, "primary")
+			secondary := createWithPassword(t, "secondary")
+
+			res := merge(t, primary, secondary, "", http.StatusConflict)
+			assert.Contains(t, res.Get("error.reason").String(), "password", "%s", res.Raw)
+			assert.EqualValues(t, "password", res.Get("error.details.conflicting_credentials.0").String(), "%s", res.Raw)
+			get(t, "/identities/"+secondary, http.StatusOK)
+
+			res = merge(t, primary, secondary, "keep_primary", http.StatusOK)
+			assert.EqualValues(t, "password", res.Get("discarded_credentials.0").String(), "%s", res.Raw)
+			assert.Equal(t, "primary", hashedPassword(t, primary))
+			get(t, "/identities/"+secondary, http.StatusNotFound)
+
+			secondary = createWithPassword(t, "secondary")
+			res = merge(t, primary, secondary, "keep_secondary", http.StatusOK)
+			assert.EqualValues(t, "password", res.Get("moved_credentials.0").String(), "%s", res.Raw)
+			assert.Equal(t, "secondary", hashedPassword(t, primary))
+		})
+
+		t.Run("case=should reject invalid requests", func(t *testing.T) {
+			primary := create(t, "")
+			merge(t, primary, primary, "", http.StatusBadRequest)
+			merge(t, primary, create(t, ""), "unknown", http.StatusBadRequest)
+			merge(t, primary, x.NewUUID().String(), "", http.StatusNotFound)
+			merge(t, x.NewUUID().String(), primary, "", http.StatusNotFound)
+		})
+	})
+
+	t.Run("case=should count identities per credentials type", func(t *testing.T) {
+		before := get(t, identity.RouteCredentialsCount, http.StatusOK)
+		assert.True(t, before.Get("credentials.oidc").Exists(), "%s", before.Raw)
+
+		send(t, "POST", "/identities", http.StatusCreated, identity.CreateIdentity{Traits: []byte(`{"bar":"baz"}`)})
+
+		email := x.NewUUID().String() + "@ory.sh"
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"` + email + `"}`)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Identifiers: []string{email},
+			Config:      sqlxx.JSONRawMessage(`{"hashed_password":"foo"}`),
+		})
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+
+		after := get(t, identity.RouteCredentialsCount, http.StatusOK)
+		assert.Equal(t, before.Get("identities").Int()+2, after.Get("identities").Int(), "%s", after.Raw)
+		assert.Equal(t, before.Get("without_credentials").Int()+1, after.Get("without_credentials").Int(), "%s", after.Raw)
+		assert.Equal(t, before.Get("credentials.password").Int()+1, after.Get("credentials.password").Int(), "%s", after.Raw)
+		assert.Equal(t, before.Get("credentials.oidc").Int(), after.Get("credentials.oidc").Int(), "%s", after.Raw)
+	})
 }
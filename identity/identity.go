@@ -46,6 +46,9 @@ type (
 		// required: true
 		SchemaID string `json:"schema_id" faker:"-" db:"schema_id"`
 
+		// SchemaVersion is the version of the JSON Schema the identity's traits were last validated against.
+		SchemaVersion string `json:"schema_version,omitempty" faker:"-" db:"schema_version"`
+
 		// SchemaURL is the URL of the endpoint where the identity's traits schema can be fetched from.
 		//
 		// format: url
@@ -75,6 +78,61 @@ type (
 		// ---
 		RecoveryAddresses []RecoveryAddress `json:"recovery_addresses,omitempty" faker:"-" has_many:"identity_recovery_addresses" fk_id:"identity_id"`
 
+		// Credentials contains metadata about the identity's credentials such as when they were last changed.
+		// It is only returned by the admin API.
+		//
+		// Extensions:
+		// ---
+		// x-omitempty: true
+		// ---
+		CredentialsMetadata map[CredentialsType]CredentialsMetadata `json:"credentials,omitempty" faker:"-" db:"-"`
+
+		// DeleteAfter is set when the identity was scheduled for deletion. The identity can no longer sign in and
+		// will be deleted permanently after this point in time unless the deletion is cancelled.
+		DeleteAfter *time.Time `json:"delete_after,omitempty" faker:"-" db:"delete_after"`
+
+		// RecoveryDisabled is set if the identity can not be recovered using the self-service recovery flow.
+		// Recovery links created using the admin API continue to work.
+		RecoveryDisabled bool `json:"recovery_disabled" faker:"-" db:"recovery_disabled"`
+
+		// LoginCount is the number of times the identity signed in. Sessions issued after registration count as
+		// a login as well.
+		LoginCount int `json:"login_count" faker:"-" db:"login_count"`
+
+		// FirstLoginAt is the time the identity signed in for the first time.
+		FirstLoginAt *time.Time `json:"first_login_at,omitempty" faker:"-" db:"first_login_at"`
+
+		// Entitlements contains feature flags and entitlements of the identity such as its plan or enabled
+		// features. Unlike traits, entitlements can only be changed using the admin API.
+		//
+		// Extensions:
+		// ---
+		// x-omitempty: true
+		// ---
+		Entitlements Entitlements `json:"entitlements,omitempty" faker:"-" db:"entitlements"`
+
+		// SessionPolicy overrides the session configuration for the identity, for example to shorten the
+		// lifespan of its sessions. Like entitlements, it can only be changed using the admin API.
+		//
+		// Extensions:
+		// ---
+		// x-omitempty: true
+		// ---
+		SessionPolicy *SessionPolicy `json:"session_policy,omitempty" faker:"-" db:"session_policy"`
+
+		// EmailChangedAt is the time the identity last changed one of its email addresses using the settings
+		// flow. Further changes are rejected during the email change cooldown of the identity's schema.
+		EmailChangedAt *time.Time `json:"email_changed_at,omitempty" faker:"-" db:"email_changed_at"`
+
+		// PartitionID is the identity partition the identity was created in. It is empty for the default partition.
+		PartitionID string `json:"-" faker:"-" db:"partition_id"`
+
+		// UniqueTraits contains the trait values which must be unique across all identities of the partition.
+		UniqueTraits []UniqueTrait `json:"-" faker:"-" db:"-"`
+
+		// IndexedTraits contains the trait values by which the identity can be searched.
+		IndexedTraits []IndexedTrait `json:"-" faker:"-" db:"-"`
+
 		// CredentialsCollection is a helper struct field for gobuffalo.pop.
 		CredentialsCollection CredentialsCollection `json:"-" faker:"-" has_many:"identity_credentials" fk_id:"identity_id"`
 
@@ -85,6 +143,9 @@ type (
 		UpdatedAt time.Time `json:"-" db:"updated_at"`
 	}
 	Traits json.RawMessage
+
+	// Entitlements is a JSON object of feature flags and entitlements managed using the admin API.
+	Entitlements json.RawMessage
 )
 
 func (t *Traits) Scan(value interface{}) error {
@@ -116,6 +177,51 @@ func (t *Traits) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (e *Entitlements) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
+	return sqlxx.JSONScan(e, value)
+}
+
+func (e Entitlements) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return sqlxx.JSONValue(e)
+}
+
+// MarshalJSON returns e as the JSON encoding of e.
+func (e Entitlements) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("null"), nil
+	}
+	return e, nil
+}
+
+// UnmarshalJSON sets *e to a copy of data.
+func (e *Entitlements) UnmarshalJSON(data []byte) error {
+	if e == nil {
+		return errors.New("json.RawMessage: UnmarshalJSON on nil pointer")
+	}
+	*e = append((*e)[0:0], data...)
+	return nil
+}
+
+// Validate returns an error if the entitlements are set but are not a JSON object.
+func (e Entitlements) Validate() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(e, &v); err != nil || v == nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("Entitlements must be a JSON object."))
+	}
+	return nil
+}
+
 func (i Identity) TableName(ctx context.Context) string {
 	return corp.ContextualizeTableName(ctx, "identities")
 }
@@ -169,12 +275,37 @@ func (i *Identity) ParseCredentials(t CredentialsType, config interface{}) (*Cre
 	return nil, herodot.ErrNotFound.WithReasonf("identity does not have credential type %s", t)
 }
 
+// IsScheduledForDeletion returns true if the identity was scheduled for deletion and can no longer be used.
+func (i *Identity) IsScheduledForDeletion() bool {
+	return i.DeleteAfter != nil
+}
+
 func (i *Identity) CopyWithoutCredentials() *Identity {
 	var ii = *i
 	ii.Credentials = nil
+	ii.CredentialsMetadata = nil
 	return &ii
 }
 
+// CopyWithCredentialsMetadata returns a copy of the identity without credentials but with the
+// credentials' metadata. It must only be used for responses of the admin API.
+func (i *Identity) CopyWithCredentialsMetadata() *Identity {
+	i.lock().RLock()
+	defer i.lock().RUnlock()
+
+	ii := i.CopyWithoutCredentials()
+	ii.CredentialsMetadata = make(map[CredentialsType]CredentialsMetadata, len(i.Credentials))
+	for t, c := range i.Credentials {
+		ii.CredentialsMetadata[t] = CredentialsMetadata{
+			Type:        t,
+			Identifiers: c.Identifiers,
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+		}
+	}
+	return ii
+}
+
 func NewIdentity(traitsSchemaID string) *Identity {
 	if traitsSchemaID == "" {
 		traitsSchemaID = config.DefaultIdentityTraitsSchemaID
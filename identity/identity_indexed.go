@@ -0,0 +1,52 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/corp"
+)
+
+// IndexedTrait is a trait value which is kept in a side table so that identities can be searched by it.
+type IndexedTrait struct {
+	ID uuid.UUID `json:"-" db:"id"`
+
+	// Key is the index key defined in the identity traits schema.
+	Key string `json:"-" db:"trait_key"`
+
+	// Value is the normalized trait value.
+	Value string `json:"-" db:"value"`
+
+	// IdentityID is a helper struct field for gobuffalo.pop.
+	IdentityID uuid.UUID `json:"-" db:"identity_id"`
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+// TraitFilter restricts a list of identities to those having an indexed trait matching the value.
+type TraitFilter struct {
+	// Key is the index key defined in the identity traits schema.
+	Key string
+
+	// Value is compared against the normalized trait value.
+	Value string
+
+	// Prefix matches all trait values starting with Value instead of requiring an exact match.
+	Prefix bool
+}
+
+func (t IndexedTrait) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "identity_indexed_traits")
+}
+
+func NewIndexedTrait(key, value string, identity uuid.UUID) *IndexedTrait {
+	return &IndexedTrait{
+		Key:        key,
+		Value:      value,
+		IdentityID: identity,
+	}
+}
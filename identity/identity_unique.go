@@ -0,0 +1,43 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/corp"
+)
+
+// UniqueTrait is a trait value which must not be shared by two identities.
+type UniqueTrait struct {
+	ID uuid.UUID `json:"-" db:"id"`
+
+	// Key is the uniqueness key defined in the identity traits schema. Traits sharing the same key
+	// must have distinct values.
+	Key string `json:"-" db:"trait_key"`
+
+	// Value is the normalized trait value.
+	Value string `json:"-" db:"value"`
+
+	// IdentityID is a helper struct field for gobuffalo.pop.
+	IdentityID uuid.UUID `json:"-" db:"identity_id"`
+	// PartitionID is a helper struct field for gobuffalo.pop.
+	PartitionID string `json:"-" db:"partition_id"`
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (t UniqueTrait) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "identity_unique_traits")
+}
+
+func NewUniqueTrait(key, value string, identity uuid.UUID) *UniqueTrait {
+	return &UniqueTrait{
+		Key:        key,
+		Value:      value,
+		IdentityID: identity,
+	}
+}
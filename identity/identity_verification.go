@@ -46,6 +46,8 @@ type (
 
 		// IdentityID is a helper struct field for gobuffalo.pop.
 		IdentityID uuid.UUID `json:"-" faker:"-" db:"identity_id"`
+		// PartitionID is a helper struct field for gobuffalo.pop.
+		PartitionID string `json:"-" faker:"-" db:"partition_id"`
 		// CreatedAt is a helper struct field for gobuffalo.pop.
 		CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
 		// UpdatedAt is a helper struct field for gobuffalo.pop.
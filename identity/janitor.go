@@ -0,0 +1,180 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+// ErrScheduledForDeletion is returned when an identity which was scheduled for deletion tries to sign in.
+var ErrScheduledForDeletion = herodot.ErrForbidden.
+	WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeIdentityScheduledForDeletion).
+	WithError("identity is scheduled for deletion").
+	WithReason("This account is scheduled for deletion and can no longer be used.")
+
+type (
+	janitorDependencies interface {
+		PrivilegedPoolProvider
+		config.Providers
+		x.LoggingProvider
+	}
+	JanitorProvider interface {
+		IdentityJanitor() *Janitor
+	}
+
+	// Janitor deletes identities in two phases. Identities are first scheduled for deletion and are deleted
+	// permanently once the grace period configured in `identity.deletion.grace_period` has passed. It also
+	// removes historical credential identifiers once `identity.credential_identifier_history.retention` has passed.
+	Janitor struct {
+		d janitorDependencies
+	}
+)
+
+const janitorBatchSize = 100
+
+func NewJanitor(d janitorDependencies) *Janitor {
+	return &Janitor{d: d}
+}
+
+// Delete schedules the deletion of the identity. If no grace period is configured, the identity is deleted immediately.
+// Returns the deletion time or nil if the identity was deleted.
+func (j *Janitor) Delete(ctx context.Context, id uuid.UUID) (*time.Time, error) {
+	grace := j.d.Configuration(ctx).IdentityDeletionGracePeriod()
+	if grace <= 0 {
+		if err := j.d.PrivilegedIdentityPool().DeleteIdentity(ctx, id); err != nil {
+			return nil, err
+		}
+
+		x.AuditContext(ctx, j.d.Audit()).
+			WithField("identity_id", id).
+			Info("Identity was deleted.")
+		return nil, nil
+	}
+
+	i, err := j.d.PrivilegedIdentityPool().GetIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleting an identity again must not extend its grace period.
+	if i.IsScheduledForDeletion() {
+		return i.DeleteAfter, nil
+	}
+
+	deleteAfter := time.Now().UTC().Add(grace)
+	if err := j.d.PrivilegedIdentityPool().ScheduleIdentityDeletion(ctx, id, deleteAfter); err != nil {
+		return nil, err
+	}
+
+	x.AuditContext(ctx, j.d.Audit()).
+		WithField("identity_id", id).
+		WithField("delete_after", deleteAfter).
+		Info("Identity was scheduled for deletion and its sessions were revoked.")
+	return &deleteAfter, nil
+}
+
+// Cancel cancels the scheduled deletion of the identity.
+func (j *Janitor) Cancel(ctx context.Context, id uuid.UUID) error {
+	i, err := j.d.PrivilegedIdentityPool().GetIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !i.IsScheduledForDeletion() {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The identity is not scheduled for deletion."))
+	}
+
+	if err := j.d.PrivilegedIdentityPool().CancelIdentityDeletion(ctx, id); err != nil {
+		return err
+	}
+
+	x.AuditContext(ctx, j.d.Audit()).
+		WithField("identity_id", id).
+		Info("Scheduled deletion of identity was cancelled.")
+	return nil
+}
+
+// Purge permanently deletes all identities whose grace period has passed. Their sessions, credentials,
+// addresses, and tokens are removed as well. Returns the number of deleted identities.
+func (j *Janitor) Purge(ctx context.Context) (int, error) {
+	var purged int
+	for {
+		is, err := j.d.PrivilegedIdentityPool().ListIdentitiesDueForDeletion(ctx, time.Now().UTC(), janitorBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for k := range is {
+			// Identities are listed across all partitions but can only be deleted from within their partition.
+			if err := j.d.PrivilegedIdentityPool().DeleteIdentity(x.WithPartitionID(ctx, is[k].PartitionID), is[k].ID); err != nil {
+				// The identity might have been purged by another instance in the meantime.
+				if errors.Is(err, sqlcon.ErrNoRows) {
+					continue
+				}
+				return purged, err
+			}
+
+			purged++
+			x.AuditContext(ctx, j.d.Audit()).
+				WithField("identity_id", is[k].ID).
+				WithField("delete_after", is[k].DeleteAfter).
+				Info("Identity was purged after its deletion grace period passed.")
+		}
+
+		if len(is) < janitorBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeCredentialIdentifierHistory permanently deletes the historical credential identifiers of all identities which
+// are no longer retained. Returns the number of deleted identifiers.
+func (j *Janitor) PurgeCredentialIdentifierHistory(ctx context.Context) (int, error) {
+	retention := j.d.Configuration(ctx).IdentityCredentialIdentifierHistoryRetention()
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	purged, err := j.d.PrivilegedIdentityPool().PurgeCredentialIdentifierHistory(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if purged > 0 {
+		j.d.Logger().
+			WithField("purged", purged).
+			Debug("Purged historical credential identifiers after their retention passed.")
+	}
+	return purged, nil
+}
+
+// Work purges identities and historical credential identifiers periodically until the context is cancelled.
+func (j *Janitor) Work(ctx context.Context) error {
+	for {
+		if _, err := j.Purge(ctx); err != nil {
+			j.d.Logger().WithError(err).Error("Unable to purge identities scheduled for deletion.")
+		}
+
+		if _, err := j.PurgeCredentialIdentifierHistory(ctx); err != nil {
+			j.d.Logger().WithError(err).Error("Unable to purge historical credential identifiers.")
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(j.d.Configuration(ctx).IdentityDeletionPurgeInterval()):
+		}
+	}
+}
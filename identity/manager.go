@@ -3,6 +3,7 @@ package identity
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -14,6 +15,9 @@ import (
 	"github.com/ory/x/errorsx"
 
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
 )
 
 var ErrProtectedFieldModified = herodot.ErrForbidden.
@@ -24,6 +28,9 @@ type (
 		PoolProvider
 		courier.Provider
 		ValidationProvider
+		IdentityTraitsSchemas(ctx context.Context) schema.Schemas
+		config.Providers
+		x.LoggingProvider
 	}
 	ManagementProvider interface {
 		IdentityManager() *Manager
@@ -33,8 +40,9 @@ type (
 	}
 
 	managerOptions struct {
-		ExposeValidationErrors    bool
-		AllowWriteProtectedTraits bool
+		ExposeValidationErrors     bool
+		AllowWriteProtectedTraits  bool
+		EnforceEmailChangeCooldown bool
 	}
 
 	ManagerOption func(*managerOptions)
@@ -52,6 +60,12 @@ func ManagerAllowWriteProtectedTraits(options *managerOptions) {
 	options.AllowWriteProtectedTraits = true
 }
 
+// ManagerEnforceEmailChangeCooldown rejects changes of the identity's email addresses during the email change
+// cooldown of its schema and starts a new cooldown when they change.
+func ManagerEnforceEmailChangeCooldown(options *managerOptions) {
+	options.EnforceEmailChangeCooldown = true
+}
+
 func newManagerOptions(opts []ManagerOption) *managerOptions {
 	var o managerOptions
 	for _, f := range opts {
@@ -62,6 +76,13 @@ func newManagerOptions(opts []ManagerOption) *managerOptions {
 
 func (m *Manager) Create(ctx context.Context, i *Identity, opts ...ManagerOption) error {
 	o := newManagerOptions(opts)
+
+	version, err := m.r.IdentityValidator().LatestSchemaVersion(ctx, i)
+	if err != nil {
+		return err
+	}
+	i.SchemaVersion = version
+
 	if err := m.validate(ctx, i, o); err != nil {
 		return err
 	}
@@ -84,13 +105,17 @@ func (m *Manager) requiresPrivilegedAccess(_ context.Context, original, updated
 			*updated = *original
 			return errors.WithStack(ErrProtectedFieldModified)
 		}
+
+		// Entitlements and session policies are managed using the admin API only.
+		updated.Entitlements = original.Entitlements
+		updated.SessionPolicy = original.SessionPolicy
 	}
 	return nil
 }
 
 func (m *Manager) Update(ctx context.Context, updated *Identity, opts ...ManagerOption) error {
 	o := newManagerOptions(opts)
-	if err := m.validate(ctx, updated, o); err != nil {
+	if err := m.migrateAndValidate(ctx, updated, o); err != nil {
 		return err
 	}
 
@@ -103,7 +128,18 @@ func (m *Manager) Update(ctx context.Context, updated *Identity, opts ...Manager
 		return err
 	}
 
-	return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated)
+	if o.EnforceEmailChangeCooldown {
+		if err := m.enforceEmailChangeCooldown(ctx, original, updated); err != nil {
+			return err
+		}
+	}
+
+	if err := m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated); err != nil {
+		return err
+	}
+
+	m.auditUpdate(ctx, original, updated)
+	return nil
 }
 
 func (m *Manager) UpdateSchemaID(ctx context.Context, id uuid.UUID, schemaID string, opts ...ManagerOption) error {
@@ -117,12 +153,24 @@ func (m *Manager) UpdateSchemaID(ctx context.Context, id uuid.UUID, schemaID str
 		return errors.WithStack(ErrProtectedFieldModified)
 	}
 
-	original.SchemaID = schemaID
-	if err := m.validate(ctx, original, o); err != nil {
+	updated := deepcopy.Copy(original).(*Identity)
+	updated.SchemaID = schemaID
+	version, err := m.r.IdentityValidator().LatestSchemaVersion(ctx, updated)
+	if err != nil {
+		return err
+	}
+	updated.SchemaVersion = version
+
+	if err := m.validate(ctx, updated, o); err != nil {
 		return err
 	}
 
-	return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, original)
+	if err := m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated); err != nil {
+		return err
+	}
+
+	m.auditUpdate(ctx, original, updated)
+	return nil
 }
 
 func (m *Manager) UpdateTraits(ctx context.Context, id uuid.UUID, traits Traits, opts ...ManagerOption) error {
@@ -135,7 +183,7 @@ func (m *Manager) UpdateTraits(ctx context.Context, id uuid.UUID, traits Traits,
 	// original is used to check whether protected traits were modified
 	updated := deepcopy.Copy(original).(*Identity)
 	updated.Traits = traits
-	if err := m.validate(ctx, updated, o); err != nil {
+	if err := m.migrateAndValidate(ctx, updated, o); err != nil {
 		return err
 	}
 
@@ -143,10 +191,83 @@ func (m *Manager) UpdateTraits(ctx context.Context, id uuid.UUID, traits Traits,
 		return err
 	}
 
-	return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated)
+	if err := m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated); err != nil {
+		return err
+	}
+
+	m.auditUpdate(ctx, original, updated)
+	return nil
+}
+
+// enforceEmailChangeCooldown rejects the update if it changes the identity's email addresses during the
+// cooldown of its schema and records the time of the change otherwise.
+func (m *Manager) enforceEmailChangeCooldown(ctx context.Context, original, updated *Identity) error {
+	updated.EmailChangedAt = original.EmailChangedAt
+	if !EmailAddressesChanged(original, updated) {
+		return nil
+	}
+
+	s, err := m.r.IdentityTraitsSchemas(ctx).GetByID(updated.SchemaID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if until := original.EmailChangeCooldownUntil(s.EmailChangeCooldown); now.Before(until) {
+		return schema.NewEmailChangeCooldownError(until)
+	}
+
+	updated.EmailChangedAt = &now
+	return nil
+}
+
+// auditUpdate writes the changes of an identity to the audit log. Credentials are always redacted.
+func (m *Manager) auditUpdate(ctx context.Context, original, updated *Identity) {
+	changes := Diff(original, updated, m.r.Configuration(ctx).IdentityAuditRedactTraits())
+	if len(changes) == 0 {
+		return
+	}
+
+	x.AuditContext(ctx, m.r.Audit()).
+		WithField("identity_id", updated.ID).
+		WithField("changes", changes).
+		Info("Identity was updated.")
+}
+
+// migrateAndValidate migrates the identity forward to the latest version of its traits schema. If the
+// traits are not valid against the latest version, the identity is validated against the version it
+// was recorded with instead, as long as that version is still part of the schema's history.
+func (m *Manager) migrateAndValidate(ctx context.Context, i *Identity, o *managerOptions) error {
+	recorded := i.SchemaVersion
+	latest, err := m.r.IdentityValidator().LatestSchemaVersion(ctx, i)
+	if err != nil {
+		return err
+	}
+
+	i.SchemaVersion = latest
+	if err := m.r.IdentityValidator().Validate(ctx, i); err == nil {
+		return nil
+	} else if _, ok := errorsx.Cause(err).(*jsonschema.ValidationError); ok && recorded != "" && recorded != latest {
+		i.SchemaVersion = recorded
+	}
+
+	return m.validate(ctx, i, o)
 }
 
 func (m *Manager) validate(ctx context.Context, i *Identity, o *managerOptions) error {
+	if err := i.Entitlements.Validate(); err != nil {
+		return err
+	}
+
+	if err := i.SessionPolicy.Validate(); err != nil {
+		return err
+	}
+
+	// An empty policy does not override any setting and is not stored.
+	if i.SessionPolicy.IsEmpty() {
+		i.SessionPolicy = nil
+	}
+
 	if err := m.r.IdentityValidator().Validate(ctx, i); err != nil {
 		if _, ok := errorsx.Cause(err).(*jsonschema.ValidationError); ok && !o.ExposeValidationErrors {
 			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
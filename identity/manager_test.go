@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/x/sqlxx"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -108,6 +113,21 @@ func TestManager(t *testing.T) {
 			checkExtensionFields(fromStore, "email-update-1@ory.sh")(t)
 		})
 
+		t.Run("case=should not update entitlements without option", func(t *testing.T) {
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits("entitlements-update@ory.sh", "")
+			original.Entitlements = identity.Entitlements(`{"plan":"free"}`)
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			original.Entitlements = identity.Entitlements(`{"plan":"pro"}`)
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), original))
+			assert.JSONEq(t, `{"plan":"free"}`, string(original.Entitlements))
+
+			fromStore, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"plan":"free"}`, string(fromStore.Entitlements))
+		})
+
 		t.Run("case=changing recovery address removes it from the store", func(t *testing.T) {
 			originalEmail := x.NewUUID().String() + "@ory.sh"
 			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
@@ -149,6 +169,99 @@ func TestManager(t *testing.T) {
 			}
 			require.True(t, foundVerifiableAddress)
 		})
+
+		t.Run("case=should enforce the email change cooldown with option", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaEmailChangeCooldown, "1h")
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyDefaultIdentitySchemaEmailChangeCooldown, "")
+			})
+
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits(x.NewUUID().String()+"@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			original.Traits = newTraits(x.NewUUID().String()+"@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), original, identity.ManagerAllowWriteProtectedTraits, identity.ManagerEnforceEmailChangeCooldown))
+			require.NotNil(t, original.EmailChangedAt)
+
+			fromStore, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			require.NotNil(t, fromStore.EmailChangedAt)
+			changedAt := *fromStore.EmailChangedAt
+
+			t.Run("other traits can still be changed", func(t *testing.T) {
+				fromStore.Traits = newTraits(fromStore.VerifiableAddresses[0].Value, "baz")
+				require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits, identity.ManagerEnforceEmailChangeCooldown))
+				assert.Equal(t, changedAt.Unix(), fromStore.EmailChangedAt.Unix())
+			})
+
+			t.Run("email addresses can not be changed again", func(t *testing.T) {
+				fromStore.Traits = newTraits(x.NewUUID().String()+"@ory.sh", "")
+				err := reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits, identity.ManagerEnforceEmailChangeCooldown)
+				require.Error(t, err)
+				var ve *schema.ValidationError
+				require.True(t, errors.As(err, &ve), "%+v", err)
+				assert.Equal(t, text.ErrorValidationSettingsEmailChangeCooldown, ve.Messages[0].ID)
+			})
+
+			t.Run("email addresses can be changed without option", func(t *testing.T) {
+				fromStore.Traits = newTraits(x.NewUUID().String()+"@ory.sh", "")
+				require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits))
+			})
+
+			t.Run("email addresses can be changed once the cooldown is lifted", func(t *testing.T) {
+				fromStore.EmailChangedAt = nil
+				require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits))
+
+				fromStore.Traits = newTraits(x.NewUUID().String()+"@ory.sh", "")
+				require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits, identity.ManagerEnforceEmailChangeCooldown))
+				require.NotNil(t, fromStore.EmailChangedAt)
+			})
+		})
+
+		t.Run("case=should only touch credentials timestamps when credentials change", func(t *testing.T) {
+			email := x.NewUUID().String() + "@ory.sh"
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits(email, "")
+			original.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+				Identifiers: []string{email},
+				Config:      sqlxx.JSONRawMessage(`{"hashed_password":"foo"}`),
+			})
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			fromStore, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			created := fromStore.Credentials[identity.CredentialsTypePassword]
+			require.False(t, created.CreatedAt.IsZero())
+			require.False(t, created.UpdatedAt.IsZero())
+
+			// Make sure that a changed timestamp can be detected.
+			time.Sleep(time.Second)
+
+			fromStore.Traits = newTraits(email, "unprotected")
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits))
+
+			fromStore, err = reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			actual := fromStore.Credentials[identity.CredentialsTypePassword]
+			assert.Equal(t, created.CreatedAt.Unix(), actual.CreatedAt.Unix())
+			assert.Equal(t, created.UpdatedAt.Unix(), actual.UpdatedAt.Unix())
+
+			actual.Config = sqlxx.JSONRawMessage(`{"hashed_password":"bar"}`)
+			fromStore.SetCredentials(identity.CredentialsTypePassword, actual)
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), fromStore, identity.ManagerAllowWriteProtectedTraits))
+
+			fromStore, err = reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			actual = fromStore.Credentials[identity.CredentialsTypePassword]
+			assert.Equal(t, created.CreatedAt.Unix(), actual.CreatedAt.Unix())
+			assert.True(t, actual.UpdatedAt.After(created.UpdatedAt), "%s should be after %s", actual.UpdatedAt, created.UpdatedAt)
+
+			metadata := fromStore.CopyWithCredentialsMetadata()
+			assert.Nil(t, metadata.Credentials)
+			assert.Equal(t, []string{email}, metadata.CredentialsMetadata[identity.CredentialsTypePassword].Identifiers)
+			assert.Nil(t, metadata.CopyWithoutCredentials().CredentialsMetadata)
+		})
 	})
 
 	t.Run("method=UpdateTraits", func(t *testing.T) {
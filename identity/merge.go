@@ -0,0 +1,309 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/mohae/deepcopy"
+	"github.com/pkg/errors"
+
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/x"
+)
+
+const (
+	// MergeConflictPolicyFail rejects the merge if both identities have credentials of the same type.
+	MergeConflictPolicyFail MergeConflictPolicy = "fail"
+	// MergeConflictPolicyKeepPrimary keeps the primary identity's credentials if both identities have
+	// credentials of the same type.
+	MergeConflictPolicyKeepPrimary MergeConflictPolicy = "keep_primary"
+	// MergeConflictPolicyKeepSecondary replaces the primary identity's credentials with the secondary
+	// identity's credentials if both identities have credentials of the same type.
+	MergeConflictPolicyKeepSecondary MergeConflictPolicy = "keep_secondary"
+)
+
+type (
+	// MergeConflictPolicy decides which credentials are kept if both merged identities have credentials of the
+	// same type.
+	MergeConflictPolicy string
+
+	// MergeResult describes what was moved from the secondary to the primary identity.
+	//
+	// swagger:model identityMergeResult
+	MergeResult struct {
+		// Identity is the merged primary identity.
+		//
+		// required: true
+		Identity *Identity `json:"identity"`
+
+		// MovedCredentials are the credential types which were moved to the primary identity.
+		//
+		// required: true
+		MovedCredentials []CredentialsType `json:"moved_credentials"`
+
+		// MergedCredentials are the credential types of which both identities had credentials and which were
+		// combined, such as the OpenID Connect providers linked to either identity.
+		//
+		// required: true
+		MergedCredentials []CredentialsType `json:"merged_credentials"`
+
+		// DiscardedCredentials are the credential types of the secondary identity which were discarded because
+		// the primary identity has credentials of the same type.
+		//
+		// required: true
+		DiscardedCredentials []CredentialsType `json:"discarded_credentials"`
+
+		// VerifiedAddresses are the addresses of the primary identity which were marked verified because
+		// the secondary identity verified them.
+		//
+		// required: true
+		VerifiedAddresses []string `json:"verified_addresses"`
+
+		// MovedAddresses are the verifiable and recovery addresses of the secondary identity which were moved
+		// to the primary identity because its traits do not contain them.
+		//
+		// required: true
+		MovedAddresses []string `json:"moved_addresses"`
+
+		// MovedSessions is the number of sessions which were moved to the primary identity.
+		//
+		// required: true
+		MovedSessions int `json:"moved_sessions"`
+	}
+)
+
+func (p MergeConflictPolicy) valid() bool {
+	switch p {
+	case MergeConflictPolicyFail, MergeConflictPolicyKeepPrimary, MergeConflictPolicyKeepSecondary:
+		return true
+	}
+	return false
+}
+
+// Merge moves the credentials, addresses, and sessions of the secondary identity to the primary identity and
+// deletes the secondary identity. The traits of the primary identity are kept. Addresses of the secondary identity
+// which the primary identity also has stay verified if the secondary identity verified them, all other addresses
+// are moved to the primary identity. The OpenID Connect providers of both identities are combined. If policy is
+// empty, the configured conflict policy is used.
+func (m *Manager) Merge(ctx context.Context, primaryID, secondaryID uuid.UUID, policy MergeConflictPolicy, opts ...ManagerOption) (*MergeResult, error) {
+	if policy == "" {
+		policy = MergeConflictPolicy(m.r.Configuration(ctx).IdentityMergeConflictPolicy())
+	}
+	if !policy.valid() {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Conflict policy %q is not supported, use one of %q, %q, or %q.",
+			policy, MergeConflictPolicyFail, MergeConflictPolicyKeepPrimary, MergeConflictPolicyKeepSecondary))
+	}
+
+	if primaryID == secondaryID {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("An identity can not be merged into itself."))
+	}
+
+	pool := m.r.IdentityPool().(PrivilegedPool)
+	primary, err := pool.GetIdentityConfidential(ctx, primaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	secondary, err := pool.GetIdentityConfidential(ctx, secondaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MergeResult{
+		Identity:             deepcopy.Copy(primary).(*Identity),
+		MovedCredentials:     []CredentialsType{},
+		MergedCredentials:    []CredentialsType{},
+		DiscardedCredentials: []CredentialsType{},
+		VerifiedAddresses:    []string{},
+		MovedAddresses:       []string{},
+	}
+	merged := result.Identity
+	if merged.Credentials == nil {
+		merged.Credentials = map[CredentialsType]Credentials{}
+	}
+
+	if policy == MergeConflictPolicyFail {
+		var conflicts []string
+		for t := range secondary.Credentials {
+			if _, ok := merged.Credentials[t]; ok && t != CredentialsTypeOIDC {
+				conflicts = append(conflicts, string(t))
+			}
+		}
+
+		if len(conflicts) > 0 {
+			sort.Strings(conflicts)
+			return nil, errors.WithStack(herodot.ErrConflict.
+				WithReasonf("Both identities have %s credentials. Choose a conflict policy to decide which ones are kept.", strings.Join(conflicts, ", ")).
+				WithDetail("conflicting_credentials", conflicts))
+		}
+	}
+
+	for t, c := range secondary.Credentials {
+		if existing, ok := merged.Credentials[t]; ok {
+			if t == CredentialsTypeOIDC {
+				combined, err := mergeOIDCCredentials(existing, c)
+				if err != nil {
+					return nil, err
+				}
+				merged.Credentials[t] = combined
+				result.MergedCredentials = append(result.MergedCredentials, t)
+				continue
+			}
+
+			if policy == MergeConflictPolicyKeepPrimary {
+				result.DiscardedCredentials = append(result.DiscardedCredentials, t)
+				continue
+			}
+		}
+
+		// The secondary identity is deleted before the primary identity is updated, so its identifiers
+		// can be moved without violating their uniqueness.
+		c.ID = uuid.Nil
+		c.IdentityID = merged.ID
+		merged.Credentials[t] = c
+		result.MovedCredentials = append(result.MovedCredentials, t)
+	}
+
+	for k, address := range merged.VerifiableAddresses {
+		if address.Verified {
+			continue
+		}
+
+		for _, verified := range secondary.VerifiableAddresses {
+			if verified.Verified && verified.Via == address.Via && verified.Value == address.Value {
+				merged.VerifiableAddresses[k].Verified = true
+				merged.VerifiableAddresses[k].Status = verified.Status
+				merged.VerifiableAddresses[k].VerifiedAt = verified.VerifiedAt
+				result.VerifiedAddresses = append(result.VerifiedAddresses, address.Value)
+				break
+			}
+		}
+	}
+
+	if err := m.validate(ctx, merged, newManagerOptions(opts)); err != nil {
+		return nil, err
+	}
+
+	// The addresses are moved after the validation, which derives the addresses from the primary identity's traits.
+	moveAddresses(merged, secondary, result)
+
+	result.MovedSessions, err = pool.MergeIdentities(ctx, merged, secondary.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sortCredentialsTypes(result.MovedCredentials)
+	sortCredentialsTypes(result.MergedCredentials)
+	sortCredentialsTypes(result.DiscardedCredentials)
+	m.auditMerge(ctx, secondary.ID, policy, result)
+	return result, nil
+}
+
+// moveAddresses adds the verifiable and recovery addresses of the secondary identity which the merged identity does
+// not have yet, keeping their verification status.
+func moveAddresses(merged, secondary *Identity, result *MergeResult) {
+	moved := map[string]bool{}
+	for _, address := range secondary.VerifiableAddresses {
+		var found bool
+		for _, existing := range merged.VerifiableAddresses {
+			if existing.Via == address.Via && existing.Value == address.Value {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		address.ID = uuid.Nil
+		address.IdentityID = merged.ID
+		merged.VerifiableAddresses = append(merged.VerifiableAddresses, address)
+		moved[address.Value] = true
+	}
+
+	for _, address := range secondary.RecoveryAddresses {
+		var found bool
+		for _, existing := range merged.RecoveryAddresses {
+			if existing.Via == address.Via && existing.Value == address.Value {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		address.ID = uuid.Nil
+		address.IdentityID = merged.ID
+		merged.RecoveryAddresses = append(merged.RecoveryAddresses, address)
+		moved[address.Value] = true
+	}
+
+	for value := range moved {
+		result.MovedAddresses = append(result.MovedAddresses, value)
+	}
+	sort.Strings(result.MovedAddresses)
+}
+
+// mergeOIDCCredentials combines the OpenID Connect providers of both credentials so that the merged identity can
+// sign in using any of them.
+func mergeOIDCCredentials(primary, secondary Credentials) (Credentials, error) {
+	var p, s AdminIdentityImportCredentialsOIDC
+	if err := json.Unmarshal(primary.Config, &p); err != nil {
+		return primary, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(secondary.Config, &s); err != nil {
+		return primary, errors.WithStack(err)
+	}
+
+	for _, provider := range s.Providers {
+		var found bool
+		for _, existing := range p.Providers {
+			if existing.Provider == provider.Provider && existing.Subject == provider.Subject {
+				found = true
+				break
+			}
+		}
+		if !found {
+			p.Providers = append(p.Providers, provider)
+		}
+	}
+
+	config, err := json.Marshal(&p)
+	if err != nil {
+		return primary, errors.WithStack(err)
+	}
+
+	primary.Config = config
+	primary.Identifiers = stringslice.Unique(append(primary.Identifiers, secondary.Identifiers...))
+	return primary, nil
+}
+
+func sortCredentialsTypes(types []CredentialsType) {
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+}
+
+// auditMerge writes the outcome of a merge to the audit log. Addresses are redacted if traits are redacted.
+func (m *Manager) auditMerge(ctx context.Context, secondaryID uuid.UUID, policy MergeConflictPolicy, result *MergeResult) {
+	l := m.r.Audit().
+		WithField("identity_id", result.Identity.ID).
+		WithField("merged_identity_id", secondaryID).
+		WithField("conflict_policy", policy).
+		WithField("moved_credentials", result.MovedCredentials).
+		WithField("merged_credentials", result.MergedCredentials).
+		WithField("discarded_credentials", result.DiscardedCredentials).
+		WithField("moved_sessions", result.MovedSessions)
+	if m.r.Configuration(ctx).IdentityAuditRedactTraits() {
+		l = l.WithField("verified_addresses", len(result.VerifiedAddresses)).
+			WithField("moved_addresses", len(result.MovedAddresses))
+	} else {
+		l = l.WithField("verified_addresses", result.VerifiedAddresses).
+			WithField("moved_addresses", result.MovedAddresses)
+	}
+	x.AuditContext(ctx, l).Info("Identity was merged.")
+}
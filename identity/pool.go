@@ -35,6 +35,22 @@ type (
 		// CountIdentities counts the number of identities in the store.
 		CountIdentities(ctx context.Context) (int64, error)
 
+		// ListIdentitiesByTrait lists all identities having an indexed trait matching the filter.
+		ListIdentitiesByTrait(ctx context.Context, f TraitFilter, page, itemsPerPage int) ([]Identity, error)
+
+		// CountIdentitiesByTrait counts the number of identities having an indexed trait matching the filter.
+		CountIdentitiesByTrait(ctx context.Context, f TraitFilter) (int64, error)
+
+		// ListIdentitiesByCredentialsIdentifier lists all identities having a credentials identifier matching the filter.
+		ListIdentitiesByCredentialsIdentifier(ctx context.Context, f CredentialsIdentifierFilter, page, itemsPerPage int) ([]Identity, error)
+
+		// CountIdentitiesByCredentialsIdentifier counts the number of identities having a credentials identifier
+		// matching the filter.
+		CountIdentitiesByCredentialsIdentifier(ctx context.Context, f CredentialsIdentifierFilter) (int64, error)
+
+		// CountCredentials counts the number of identities per credentials type using an aggregate query.
+		CountCredentials(ctx context.Context) (*CredentialsCount, error)
+
 		// GetIdentity returns an identity by its id. Will return an error if the identity does not exist or backend
 		// connectivity is broken.
 		GetIdentity(context.Context, uuid.UUID) (*Identity, error)
@@ -74,15 +90,48 @@ type (
 		// UpdateIdentity updates an identity including its confidential / privileged / protected data.
 		UpdateIdentity(context.Context, *Identity) error
 
+		// ListIdentityIDs lists the IDs of up to limit identities in ascending order, starting after the given ID. If
+		// schemaID is not empty, only identities using that traits schema are listed.
+		ListIdentityIDs(ctx context.Context, schemaID string, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
 		// GetIdentityConfidential returns the identity including it's raw credentials. This should only be used internally.
 		GetIdentityConfidential(context.Context, uuid.UUID) (*Identity, error)
 
+		// ListCredentialsMetadata returns the metadata of the credentials of the given identities by their IDs.
+		// Identities without credentials are omitted.
+		ListCredentialsMetadata(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]map[CredentialsType]CredentialsMetadata, error)
+
 		// ListVerifiableAddresses lists all tracked verifiable addresses, regardless of whether they are already verified
 		// or not.
 		ListVerifiableAddresses(ctx context.Context, page, itemsPerPage int) ([]VerifiableAddress, error)
 
 		// ListRecoveryAddresses lists all tracked recovery addresses.
 		ListRecoveryAddresses(ctx context.Context, page, itemsPerPage int) ([]RecoveryAddress, error)
+
+		// ScheduleIdentityDeletion marks the identity for deletion after the given time and revokes all of its sessions.
+		// Returns sql.ErrNoRows if the identity does not exist.
+		ScheduleIdentityDeletion(ctx context.Context, id uuid.UUID, deleteAfter time.Time) error
+
+		// CancelIdentityDeletion removes the deletion mark of an identity. Returns sql.ErrNoRows if the identity does not exist.
+		CancelIdentityDeletion(ctx context.Context, id uuid.UUID) error
+
+		// RecordLogin increments the login count of the identity and sets the time of its first login if it
+		// is not set yet. It returns the updated login count. Returns sql.ErrNoRows if the identity does not exist.
+		RecordLogin(ctx context.Context, id uuid.UUID, at time.Time) (int, error)
+
+		// MergeIdentities updates the primary identity, moves the sessions of the secondary identity to it, and deletes
+		// the secondary identity in one transaction. It returns the number of moved sessions. Returns sql.ErrNoRows
+		// if either identity does not exist.
+		MergeIdentities(ctx context.Context, primary *Identity, secondaryID uuid.UUID) (int, error)
+
+		// ListIdentitiesDueForDeletion lists at most limit identities which were scheduled for deletion before the given time.
+		// Unlike the other methods, it is not restricted to the identity partition of the context.
+		ListIdentitiesDueForDeletion(ctx context.Context, before time.Time, limit int) ([]Identity, error)
+
+		// PurgeCredentialIdentifierHistory deletes the historical credential identifiers which were recorded before
+		// the given time and returns how many were deleted. Unlike the other methods, it is not restricted to the
+		// identity partition of the context.
+		PurgeCredentialIdentifierHistory(ctx context.Context, before time.Time) (int, error)
 	}
 )
 
@@ -240,6 +289,284 @@ func TestPool(conf *config.Provider, p interface {
 			createdIDs = append(createdIDs, second.ID)
 		})
 
+		t.Run("case=fail on duplicate unique traits", func(t *testing.T) {
+			username := x.NewUUID().String()
+			initial := oidcIdentity("", x.NewUUID().String())
+			initial.Traits = Traits(fmt.Sprintf(`{"username":"%s"}`, username))
+			require.NoError(t, p.CreateIdentity(ctx, initial))
+			createdIDs = append(createdIDs, initial.ID)
+
+			expected := oidcIdentity("", x.NewUUID().String())
+			expected.Traits = Traits(fmt.Sprintf(`{"username":"%s"}`, strings.ToUpper(username)))
+			err := p.CreateIdentity(ctx, expected)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `unique trait "username" exists already`)
+
+			_, err = p.GetIdentity(ctx, expected.ID)
+			require.Error(t, err)
+
+			t.Run("case=update fails if trait is taken", func(t *testing.T) {
+				other := oidcIdentity("", x.NewUUID().String())
+				require.NoError(t, p.CreateIdentity(ctx, other))
+				createdIDs = append(createdIDs, other.ID)
+
+				other.Traits = Traits(fmt.Sprintf(`{"username":"%s"}`, username))
+				err := p.UpdateIdentity(ctx, other)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), `unique trait "username" exists already`)
+			})
+
+			t.Run("case=trait is released once changed", func(t *testing.T) {
+				initial.Traits = Traits(`{}`)
+				require.NoError(t, p.UpdateIdentity(ctx, initial))
+				require.NoError(t, p.CreateIdentity(ctx, expected))
+				createdIDs = append(createdIDs, expected.ID)
+			})
+		})
+
+		t.Run("case=list and count by indexed trait", func(t *testing.T) {
+			department := x.NewUUID().String()
+
+			first := oidcIdentity("", x.NewUUID().String())
+			first.Traits = Traits(fmt.Sprintf(`{"department":"%s-Sales"}`, department))
+			require.NoError(t, p.CreateIdentity(ctx, first))
+			createdIDs = append(createdIDs, first.ID)
+
+			second := oidcIdentity("", x.NewUUID().String())
+			second.Traits = Traits(fmt.Sprintf(`{"department":"%s-support"}`, department))
+			require.NoError(t, p.CreateIdentity(ctx, second))
+			createdIDs = append(createdIDs, second.ID)
+
+			for k, tc := range []struct {
+				f      TraitFilter
+				expect []uuid.UUID
+			}{
+				{f: TraitFilter{Key: "department", Value: department + "-sales"}, expect: []uuid.UUID{first.ID}},
+				{f: TraitFilter{Key: "department", Value: strings.ToUpper(department) + "-SUPPORT"}, expect: []uuid.UUID{second.ID}},
+				{f: TraitFilter{Key: "department", Value: department, Prefix: true}, expect: []uuid.UUID{first.ID, second.ID}},
+				{f: TraitFilter{Key: "department", Value: department + "-s_", Prefix: true}, expect: []uuid.UUID{}},
+				{f: TraitFilter{Key: "department", Value: department}, expect: []uuid.UUID{}},
+				{f: TraitFilter{Key: "username", Value: department, Prefix: true}, expect: []uuid.UUID{}},
+			} {
+				t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+					is, err := p.ListIdentitiesByTrait(ctx, tc.f, 0, 10)
+					require.NoError(t, err)
+
+					actual := make([]uuid.UUID, len(is))
+					for i := range is {
+						actual[i] = is[i].ID
+					}
+					assert.ElementsMatch(t, tc.expect, actual)
+
+					count, err := p.CountIdentitiesByTrait(ctx, tc.f)
+					require.NoError(t, err)
+					assert.EqualValues(t, len(tc.expect), count)
+				})
+			}
+
+			t.Run("case=index is updated with the traits", func(t *testing.T) {
+				first.Traits = Traits(`{}`)
+				require.NoError(t, p.UpdateIdentity(ctx, first))
+
+				count, err := p.CountIdentitiesByTrait(ctx, TraitFilter{Key: "department", Value: department, Prefix: true})
+				require.NoError(t, err)
+				assert.EqualValues(t, 1, count)
+			})
+		})
+
+		t.Run("case=list and count by credentials identifier", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryEnabled, true)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryEnabled, false)
+				conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryRetention, "8760h")
+			})
+
+			previous, current := x.NewUUID().String()+"@ory.sh", x.NewUUID().String()+"@ory.sh"
+			expected := passwordIdentity("", previous)
+			expected.Traits = Traits(`{}`)
+			require.NoError(t, p.CreateIdentity(ctx, expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			expected.SetCredentials(CredentialsTypePassword, Credentials{
+				Type: CredentialsTypePassword, Identifiers: []string{current},
+				Config: sqlxx.JSONRawMessage(`{"foo":"bar"}`),
+			})
+			require.NoError(t, p.UpdateIdentity(ctx, expected))
+
+			assertFound := func(t *testing.T, f CredentialsIdentifierFilter, expect []uuid.UUID) {
+				is, err := p.ListIdentitiesByCredentialsIdentifier(ctx, f, 0, 10)
+				require.NoError(t, err)
+
+				actual := make([]uuid.UUID, len(is))
+				for i := range is {
+					actual[i] = is[i].ID
+				}
+				assert.ElementsMatch(t, expect, actual)
+
+				count, err := p.CountIdentitiesByCredentialsIdentifier(ctx, f)
+				require.NoError(t, err)
+				assert.EqualValues(t, len(expect), count)
+			}
+
+			for k, tc := range []struct {
+				f      CredentialsIdentifierFilter
+				expect []uuid.UUID
+			}{
+				{f: CredentialsIdentifierFilter{Identifier: current}, expect: []uuid.UUID{expected.ID}},
+				{f: CredentialsIdentifierFilter{Identifier: strings.ToUpper(current)}, expect: []uuid.UUID{expected.ID}},
+				{f: CredentialsIdentifierFilter{Identifier: current, IncludeHistory: true}, expect: []uuid.UUID{expected.ID}},
+				{f: CredentialsIdentifierFilter{Identifier: previous}, expect: []uuid.UUID{}},
+				{f: CredentialsIdentifierFilter{Identifier: previous, IncludeHistory: true}, expect: []uuid.UUID{expected.ID}},
+				{f: CredentialsIdentifierFilter{Identifier: x.NewUUID().String(), IncludeHistory: true}, expect: []uuid.UUID{}},
+			} {
+				t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+					assertFound(t, tc.f, tc.expect)
+				})
+			}
+
+			t.Run("case=historical identifiers can not be used to sign in", func(t *testing.T) {
+				_, _, err := p.FindByCredentialsIdentifier(ctx, CredentialsTypePassword, previous)
+				require.Error(t, err)
+			})
+
+			t.Run("case=historical identifiers expire", func(t *testing.T) {
+				conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryRetention, "1ns")
+				assertFound(t, CredentialsIdentifierFilter{Identifier: previous, IncludeHistory: true}, []uuid.UUID{})
+			})
+
+			t.Run("case=expired historical identifiers are purged", func(t *testing.T) {
+				conf.MustSet(config.ViperKeyIdentityCredentialIdentifierHistoryRetention, "8760h")
+				assertFound(t, CredentialsIdentifierFilter{Identifier: previous, IncludeHistory: true}, []uuid.UUID{expected.ID})
+
+				purged, err := p.PurgeCredentialIdentifierHistory(ctx, time.Now().UTC().Add(-time.Hour))
+				require.NoError(t, err)
+				assert.Equal(t, 0, purged)
+				assertFound(t, CredentialsIdentifierFilter{Identifier: previous, IncludeHistory: true}, []uuid.UUID{expected.ID})
+
+				purged, err = p.PurgeCredentialIdentifierHistory(ctx, time.Now().UTC())
+				require.NoError(t, err)
+				assert.GreaterOrEqual(t, purged, 1)
+				assertFound(t, CredentialsIdentifierFilter{Identifier: previous, IncludeHistory: true}, []uuid.UUID{})
+			})
+		})
+
+		t.Run("case=identities are isolated by partition", func(t *testing.T) {
+			identifier := x.NewUUID().String() + "@ory.sh"
+			username := x.NewUUID().String()
+			create := func(t *testing.T, ctx context.Context) *Identity {
+				i := passwordIdentity("", identifier)
+				i.Traits = Traits(fmt.Sprintf(`{"username":"%s"}`, username))
+				require.NoError(t, p.CreateIdentity(ctx, i))
+				return i
+			}
+
+			inDefault := create(t, ctx)
+			createdIDs = append(createdIDs, inDefault.ID)
+
+			partitionCtx := x.WithPartitionID(ctx, "partition-"+x.NewUUID().String())
+			inPartition := create(t, partitionCtx)
+			assert.NotEqual(t, inDefault.ID, inPartition.ID)
+
+			for _, tc := range []struct {
+				ctx      context.Context
+				expected *Identity
+				other    *Identity
+			}{
+				{ctx: ctx, expected: inDefault, other: inPartition},
+				{ctx: partitionCtx, expected: inPartition, other: inDefault},
+			} {
+				actual, creds, err := p.FindByCredentialsIdentifier(tc.ctx, CredentialsTypePassword, identifier)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected.ID, actual.ID)
+				assert.Equal(t, []string{identifier}, creds.Identifiers)
+
+				_, err = p.GetIdentity(tc.ctx, tc.other.ID)
+				require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+				_, err = p.GetIdentityConfidential(tc.ctx, tc.other.ID)
+				require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+				require.True(t, errors.Is(p.UpdateIdentity(tc.ctx, tc.other), sqlcon.ErrNoRows))
+				require.True(t, errors.Is(p.DeleteIdentity(tc.ctx, tc.other.ID), sqlcon.ErrNoRows))
+
+				is, err := p.ListIdentitiesByCredentialsIdentifier(tc.ctx, CredentialsIdentifierFilter{Identifier: identifier}, 0, 10)
+				require.NoError(t, err)
+				require.Len(t, is, 1)
+				assert.Equal(t, tc.expected.ID, is[0].ID)
+			}
+
+			count, err := p.CountIdentities(partitionCtx)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, count)
+
+			t.Run("case=identifiers are unique within the partition", func(t *testing.T) {
+				err := p.CreateIdentity(partitionCtx, passwordIdentity("", identifier))
+				require.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+			})
+
+			require.NoError(t, p.DeleteIdentity(partitionCtx, inPartition.ID))
+		})
+
+		t.Run("case=records logins", func(t *testing.T) {
+			i := NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			require.NoError(t, p.CreateIdentity(ctx, i))
+			createdIDs = append(createdIDs, i.ID)
+
+			first := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+			for k, at := range []time.Time{first, time.Now().UTC()} {
+				count, err := p.RecordLogin(ctx, i.ID, at)
+				require.NoError(t, err)
+				assert.Equal(t, k+1, count)
+			}
+
+			// Updating the identity must not reset the login count.
+			require.NoError(t, p.UpdateIdentity(ctx, i))
+
+			actual, err := p.GetIdentity(ctx, i.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 2, actual.LoginCount)
+			require.NotNil(t, actual.FirstLoginAt)
+			assert.Equal(t, first.Unix(), actual.FirstLoginAt.Unix())
+
+			_, err = p.RecordLogin(ctx, x.NewUUID(), time.Now())
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=merging takes over the secondary identity's identifiers", func(t *testing.T) {
+			identifier := x.NewUUID().String() + "@ory.sh"
+			primary := oidcIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, primary))
+			createdIDs = append(createdIDs, primary.ID)
+
+			secondary := passwordIdentity("", identifier)
+			require.NoError(t, p.CreateIdentity(ctx, secondary))
+
+			merged, err := p.GetIdentityConfidential(ctx, primary.ID)
+			require.NoError(t, err)
+			password, ok := secondary.GetCredentials(CredentialsTypePassword)
+			require.True(t, ok)
+			merged.SetCredentials(CredentialsTypePassword, *password)
+
+			_, err = p.MergeIdentities(ctx, merged, x.NewUUID())
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			actual, err := p.GetIdentityConfidential(ctx, primary.ID)
+			require.NoError(t, err)
+			assert.Len(t, actual.Credentials, 1, "a failed merge must not change the primary identity")
+
+			moved, err := p.MergeIdentities(ctx, merged, secondary.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 0, moved)
+
+			_, err = p.GetIdentity(ctx, secondary.ID)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			actual, creds, err := p.FindByCredentialsIdentifier(ctx, CredentialsTypePassword, identifier)
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, actual.ID)
+			assert.Equal(t, []string{identifier}, creds.Identifiers)
+		})
+
 		t.Run("case=create with invalid traits data", func(t *testing.T) {
 			expected := oidcIdentity("", x.NewUUID().String())
 			expected.Traits = Traits(`{"bar":123}`) // bar should be a string
@@ -291,6 +618,45 @@ func TestPool(conf *config.Provider, p interface {
 			assert.Equal(t, expected.Credentials[CredentialsTypeOIDC], actual.Credentials[CredentialsTypeOIDC])
 		})
 
+		t.Run("case=credentials keep their timestamps unless their configuration changes", func(t *testing.T) {
+			initial := passwordIdentity("", "timestamps-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, initial))
+			createdIDs = append(createdIDs, initial.ID)
+
+			stored, err := p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+			created := stored.Credentials[CredentialsTypePassword]
+
+			// Make sure that a changed timestamp can be detected.
+			time.Sleep(time.Second)
+
+			stored.Traits = Traits(`{"bar":"baz"}`)
+			require.NoError(t, p.UpdateIdentity(ctx, stored))
+
+			actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created.CreatedAt.Unix(), actual.Credentials[CredentialsTypePassword].CreatedAt.Unix())
+			assert.Equal(t, created.UpdatedAt.Unix(), actual.Credentials[CredentialsTypePassword].UpdatedAt.Unix())
+
+			// The credentials still carry the previous timestamps, for example if they were changed without using
+			// the identity manager.
+			changed := actual.Credentials[CredentialsTypePassword]
+			changed.Config = sqlxx.JSONRawMessage(`{"foo":"baz"}`)
+			actual.SetCredentials(CredentialsTypePassword, changed)
+			require.NoError(t, p.UpdateIdentity(ctx, actual))
+
+			actual, err = p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+			assert.Equal(t, created.CreatedAt.Unix(), actual.Credentials[CredentialsTypePassword].CreatedAt.Unix())
+			assert.True(t, actual.Credentials[CredentialsTypePassword].UpdatedAt.After(created.UpdatedAt))
+
+			metadata, err := p.ListCredentialsMetadata(ctx, []uuid.UUID{initial.ID, x.NewUUID()})
+			require.NoError(t, err)
+			require.Len(t, metadata, 1)
+			assert.Equal(t, actual.Credentials[CredentialsTypePassword].Identifiers, metadata[initial.ID][CredentialsTypePassword].Identifiers)
+			assert.Equal(t, actual.Credentials[CredentialsTypePassword].UpdatedAt.Unix(), metadata[initial.ID][CredentialsTypePassword].UpdatedAt.Unix())
+		})
+
 		t.Run("case=fail to update because validation fails", func(t *testing.T) {
 			initial := oidcIdentity("", x.NewUUID().String())
 
@@ -348,6 +714,42 @@ func TestPool(conf *config.Provider, p interface {
 			require.Error(t, err)
 		})
 
+		t.Run("case=schedule and cancel the deletion of an identity", func(t *testing.T) {
+			due := passwordIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, due))
+			pending := passwordIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, pending))
+
+			now := time.Now().UTC()
+			require.NoError(t, p.ScheduleIdentityDeletion(ctx, due.ID, now.Add(-time.Minute)))
+			require.NoError(t, p.ScheduleIdentityDeletion(ctx, pending.ID, now.Add(time.Hour)))
+			assert.True(t, errors.Is(p.ScheduleIdentityDeletion(ctx, x.NewUUID(), now), sqlcon.ErrNoRows))
+
+			actual, err := p.GetIdentity(ctx, pending.ID)
+			require.NoError(t, err)
+			require.True(t, actual.IsScheduledForDeletion())
+			assert.WithinDuration(t, now.Add(time.Hour), *actual.DeleteAfter, time.Second)
+
+			is, err := p.ListIdentitiesDueForDeletion(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, is, 1)
+			assert.Equal(t, due.ID, is[0].ID)
+
+			require.NoError(t, p.CancelIdentityDeletion(ctx, due.ID))
+			assert.True(t, errors.Is(p.CancelIdentityDeletion(ctx, x.NewUUID()), sqlcon.ErrNoRows))
+
+			actual, err = p.GetIdentity(ctx, due.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.IsScheduledForDeletion())
+
+			is, err = p.ListIdentitiesDueForDeletion(ctx, now, 10)
+			require.NoError(t, err)
+			assert.Len(t, is, 0)
+
+			require.NoError(t, p.DeleteIdentity(ctx, due.ID))
+			require.NoError(t, p.DeleteIdentity(ctx, pending.ID))
+		})
+
 		t.Run("case=create with empty credentials config", func(t *testing.T) {
 			// This test covers a case where the config value of a credentials setting is empty. This causes
 			// issues with postgres' json field.
@@ -376,6 +778,29 @@ func TestPool(conf *config.Provider, p interface {
 			}
 		})
 
+		t.Run("case=list identity ids in batches", func(t *testing.T) {
+			var ids []uuid.UUID
+			after := uuid.Nil
+			for {
+				batch, err := p.ListIdentityIDs(ctx, "", after, 2)
+				require.NoError(t, err)
+				ids = append(ids, batch...)
+				if len(batch) < 2 {
+					break
+				}
+				after = batch[len(batch)-1]
+			}
+
+			assert.ElementsMatch(t, createdIDs, ids)
+			for k := 1; k < len(ids); k++ {
+				assert.True(t, ids[k-1].String() < ids[k].String(), "%s must be listed before %s", ids[k-1], ids[k])
+			}
+
+			ids, err := p.ListIdentityIDs(ctx, "does-not-exist", uuid.Nil, 100)
+			require.NoError(t, err)
+			assert.Empty(t, ids)
+		})
+
 		t.Run("case=find identity by its credentials identifier", func(t *testing.T) {
 			expected := passwordIdentity("", "find-credentials-identifier@ory.sh")
 			expected.Traits = Traits(`{}`)
@@ -415,6 +840,43 @@ func TestPool(conf *config.Provider, p interface {
 			assertEqual(t, expected, actual)
 		})
 
+		t.Run("case=find identity by any of its credentials identifiers", func(t *testing.T) {
+			email, username := x.NewUUID().String()+"@ory.sh", x.NewUUID().String()
+			expected := passwordIdentity("", email)
+			expected.Traits = Traits(`{}`)
+			expected.Credentials[CredentialsTypePassword] = Credentials{
+				Type: CredentialsTypePassword, Identifiers: []string{email, username},
+				Config: sqlxx.JSONRawMessage(`{"foo":"bar"}`),
+			}
+
+			require.NoError(t, p.CreateIdentity(ctx, expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			for _, identifier := range []string{email, username} {
+				actual, creds, err := p.FindByCredentialsIdentifier(ctx, CredentialsTypePassword, identifier)
+				require.NoError(t, err)
+
+				assert.EqualValues(t, expected.Credentials[CredentialsTypePassword].ID, creds.ID)
+				assert.ElementsMatch(t, []string{email, username}, creds.Identifiers)
+				assert.Equal(t, expected.ID, actual.ID)
+			}
+
+			t.Run("case=identifiers are unique across traits", func(t *testing.T) {
+				// Another identity must not use the username as its email address or vice versa.
+				for _, identifier := range []string{email, username} {
+					other := passwordIdentity("", x.NewUUID().String())
+					other.Credentials[CredentialsTypePassword] = Credentials{
+						Type: CredentialsTypePassword, Identifiers: []string{x.NewUUID().String(), identifier},
+						Config: sqlxx.JSONRawMessage(`{"foo":"bar"}`),
+					}
+
+					err := p.CreateIdentity(ctx, other)
+					require.Error(t, err)
+					require.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+				}
+			})
+		})
+
 		t.Run("suite=verifiable-address", func(t *testing.T) {
 			createIdentityWithAddresses := func(t *testing.T, email string) VerifiableAddress {
 				var i Identity
@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlxx"
+)
+
+// SessionPolicy overrides the session configuration for a single identity. It can only make the identity's
+// sessions stricter than the configuration in `session`.
+//
+// swagger:model identitySessionPolicy
+type SessionPolicy struct {
+	// Lifespan limits how long the identity's sessions are valid after authentication, for example `1h`,
+	// including sliding refreshes. It can only shorten `session.lifespan`.
+	Lifespan string `json:"lifespan,omitempty"`
+
+	// DisableRefresh disables the sliding refresh of the identity's sessions configured in `session.refresh`.
+	DisableRefresh bool `json:"disable_refresh,omitempty"`
+
+	// RequiredAAL is the minimum authenticator assurance level of the identity's sessions.
+	RequiredAAL string `json:"required_aal,omitempty"`
+}
+
+func (p *SessionPolicy) Scan(value interface{}) error {
+	return sqlxx.JSONScan(p, value)
+}
+
+func (p SessionPolicy) Value() (driver.Value, error) {
+	return sqlxx.JSONValue(p)
+}
+
+// IsEmpty returns true if the policy does not override any setting.
+func (p *SessionPolicy) IsEmpty() bool {
+	return p == nil || *p == SessionPolicy{}
+}
+
+// Validate returns an error if the policy is malformed or requires an assurance level which can not be reached.
+func (p *SessionPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.Lifespan != "" {
+		lifespan, err := time.ParseDuration(p.Lifespan)
+		if err != nil || lifespan <= 0 {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The session policy's lifespan must be a positive duration such as 1h but got: %s", p.Lifespan))
+		}
+	}
+
+	switch p.RequiredAAL {
+	case "", "aal1":
+	case "aal2":
+		// Requiring aal2 would lock the identity out as long as no second authentication factor is available.
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The session policy can not require aal2 because no second authentication factor is available."))
+	default:
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The session policy's required_aal must be aal1 or aal2 but got: %s", p.RequiredAAL))
+	}
+
+	return nil
+}
+
+// EffectiveLifespan returns the lifespan of the identity's sessions given the configured lifespan.
+func (p *SessionPolicy) EffectiveLifespan(configured time.Duration) time.Duration {
+	if p == nil || p.Lifespan == "" {
+		return configured
+	}
+
+	lifespan, err := time.ParseDuration(p.Lifespan)
+	if err != nil || lifespan <= 0 || lifespan > configured {
+		return configured
+	}
+	return lifespan
+}
+
+// RefreshDisabled returns true if the identity's sessions must not be refreshed.
+func (p *SessionPolicy) RefreshDisabled() bool {
+	return p != nil && p.DisableRefresh
+}
+
+// MinimumAAL returns the minimum authenticator assurance level of the identity's sessions or an empty string.
+func (p *SessionPolicy) MinimumAAL() string {
+	if p == nil {
+		return ""
+	}
+	return p.RequiredAAL
+}
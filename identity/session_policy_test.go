@@ -0,0 +1,46 @@
+package identity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionPolicy(t *testing.T) {
+	t.Run("method=Validate", func(t *testing.T) {
+		for k, tc := range []struct {
+			p   *SessionPolicy
+			err bool
+		}{
+			{p: nil},
+			{p: &SessionPolicy{}},
+			{p: &SessionPolicy{Lifespan: "1h", DisableRefresh: true, RequiredAAL: "aal1"}},
+			{p: &SessionPolicy{Lifespan: "an hour"}, err: true},
+			{p: &SessionPolicy{Lifespan: "-1h"}, err: true},
+			{p: &SessionPolicy{RequiredAAL: "aal2"}, err: true},
+			{p: &SessionPolicy{RequiredAAL: "aal3"}, err: true},
+		} {
+			if tc.err {
+				assert.Error(t, tc.p.Validate(), "%d", k)
+			} else {
+				assert.NoError(t, tc.p.Validate(), "%d", k)
+			}
+		}
+	})
+
+	t.Run("method=EffectiveLifespan", func(t *testing.T) {
+		var p *SessionPolicy
+		assert.Equal(t, time.Hour, p.EffectiveLifespan(time.Hour))
+		assert.Equal(t, time.Hour, (&SessionPolicy{}).EffectiveLifespan(time.Hour))
+		assert.Equal(t, time.Minute, (&SessionPolicy{Lifespan: "1m"}).EffectiveLifespan(time.Hour))
+		assert.Equal(t, time.Hour, (&SessionPolicy{Lifespan: "2h"}).EffectiveLifespan(time.Hour))
+	})
+
+	t.Run("method=IsEmpty", func(t *testing.T) {
+		var p *SessionPolicy
+		assert.True(t, p.IsEmpty())
+		assert.True(t, (&SessionPolicy{}).IsEmpty())
+		assert.False(t, (&SessionPolicy{DisableRefresh: true}).IsEmpty())
+	})
+}
@@ -0,0 +1,220 @@
+package identity
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	traitsTransformerDependencies interface {
+		config.Providers
+		x.HTTPClientProvider
+	}
+	TraitsTransformerProvider interface {
+		IdentityTraitsTransformer() *TraitsTransformer
+	}
+
+	// TraitsTransformer transforms the traits submitted in the registration and settings flows before
+	// they are validated, as configured in `identity.traits_transform`.
+	TraitsTransformer struct {
+		r traitsTransformerDependencies
+		f *fetcher.Fetcher
+
+		l       sync.Mutex
+		url     string
+		snippet string
+	}
+)
+
+var (
+	traitsTransforms = map[string]func(value, countryCode string) string{
+		"trim": func(value, _ string) string {
+			return strings.TrimSpace(value)
+		},
+		"lowercase": func(value, _ string) string {
+			return strings.ToLower(value)
+		},
+		"uppercase": func(value, _ string) string {
+			return strings.ToUpper(value)
+		},
+		"e164": normalizeE164,
+	}
+
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+func NewTraitsTransformer(r traitsTransformerDependencies) *TraitsTransformer {
+	return &TraitsTransformer{r: r, f: fetcher.NewFetcher(fetcher.WithClient(r.HTTPClient()))}
+}
+
+// Validate makes sure that the transformation rules are valid and that the Jsonnet mapper, if configured,
+// can be parsed.
+func (t *TraitsTransformer) Validate(ctx context.Context) error {
+	c := t.r.Configuration(ctx).IdentityTraitsTransform()
+	for _, rule := range c.Rules {
+		if strings.ContainsAny(rule.Path, "*?#|@") {
+			return errors.Errorf("traits transformation path %s must not contain wildcards, queries, or modifiers", rule.Path)
+		}
+
+		for _, name := range rule.Transforms {
+			if _, ok := traitsTransforms[name]; !ok {
+				return errors.Errorf("traits transformation path %s uses unknown transform %s", rule.Path, name)
+			}
+		}
+	}
+
+	if c.MapperURL == "" {
+		return nil
+	}
+
+	snippet, err := t.load(c.MapperURL)
+	if err != nil {
+		return err
+	}
+
+	if _, err := jsonnet.SnippetToAST(c.MapperURL, snippet); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the traits transformation mapper: %s", err))
+	}
+
+	return nil
+}
+
+func (t *TraitsTransformer) load(location string) (string, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if t.url == location {
+		return t.snippet, nil
+	}
+
+	jn, err := t.f.Fetch(location)
+	if err != nil {
+		return "", err
+	}
+
+	t.url = location
+	t.snippet = jn.String()
+	return t.snippet, nil
+}
+
+// Transform applies the transformation rules and the Jsonnet mapper to the identity's traits. Traits which
+// are not strings are left unchanged, so that they fail validation as usual.
+func (t *TraitsTransformer) Transform(ctx context.Context, i *Identity) error {
+	c := t.r.Configuration(ctx).IdentityTraitsTransform()
+
+	traits := []byte(i.Traits)
+	if len(traits) == 0 {
+		traits = []byte("{}")
+	}
+
+	for _, rule := range c.Rules {
+		value := gjson.GetBytes(traits, rule.Path)
+		if !value.Exists() {
+			continue
+		}
+
+		var err error
+		if value.IsArray() {
+			for k, item := range value.Array() {
+				if item.Type != gjson.String {
+					continue
+				}
+				if traits, err = sjson.SetBytes(traits, rule.Path+"."+strconv.Itoa(k), applyTransforms(item.String(), rule.Transforms, c.DefaultCountryCallingCode)); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		} else if value.Type == gjson.String {
+			if traits, err = sjson.SetBytes(traits, rule.Path, applyTransforms(value.String(), rule.Transforms, c.DefaultCountryCallingCode)); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	if c.MapperURL != "" {
+		snippet, err := t.load(c.MapperURL)
+		if err != nil {
+			return err
+		}
+
+		vm := jsonnet.MakeVM()
+		vm.ExtCode("traits", string(traits))
+		evaluated, err := vm.EvaluateSnippet(c.MapperURL, snippet)
+		if err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the traits transformation mapper: %s", err))
+		}
+
+		mapped := gjson.Get(evaluated, "traits")
+		if !mapped.IsObject() {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReason("The traits transformation mapper did not return an object for key traits."))
+		}
+		traits = []byte(mapped.Raw)
+	}
+
+	i.Traits = Traits(traits)
+	return nil
+}
+
+func applyTransforms(value string, transforms []string, countryCode string) string {
+	for _, name := range transforms {
+		if transform, ok := traitsTransforms[name]; ok {
+			value = transform(value, countryCode)
+		}
+	}
+	return value
+}
+
+// normalizeE164 normalizes phone numbers such as "+49 (0)170 123-4567" or "0170 1234567" to the E.164 format.
+// Values which do not look like phone numbers, or which have no country calling code if countryCode is empty,
+// are returned unchanged.
+func normalizeE164(value, countryCode string) string {
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "+") {
+		// Remove the trunk prefix which is often written after the country calling code, e.g. "+49 (0)170".
+		trimmed = strings.Replace(trimmed, "(0)", "", 1)
+	}
+
+	var b strings.Builder
+	for k, r := range trimmed {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && k == 0:
+			b.WriteRune(r)
+		case strings.ContainsRune(" -./()", r):
+		default:
+			return value
+		}
+	}
+
+	number := b.String()
+	switch {
+	case len(strings.TrimPrefix(number, "+")) == 0:
+		return value
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + strings.TrimPrefix(number, "00")
+	case countryCode != "":
+		number = "+" + countryCode + strings.TrimPrefix(number, "0")
+	default:
+		return value
+	}
+
+	if !e164Pattern.MatchString(number) {
+		return value
+	}
+	return number
+}
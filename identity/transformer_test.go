@@ -0,0 +1,118 @@
+package identity_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+)
+
+func TestTraitsTransformer(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	var configure = func(t *testing.T, c map[string]interface{}) *identity.TraitsTransformer {
+		conf.MustSet(config.ViperKeyIdentityTraitsTransform, c)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyIdentityTraitsTransform, nil)
+		})
+		return identity.NewTraitsTransformer(reg)
+	}
+
+	var transform = func(t *testing.T, tt *identity.TraitsTransformer, traits string) string {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(traits)
+		require.NoError(t, tt.Transform(ctx, i))
+		return string(i.Traits)
+	}
+
+	t.Run("case=no transformation configured", func(t *testing.T) {
+		tt := identity.NewTraitsTransformer(reg)
+		require.NoError(t, tt.Validate(ctx))
+		assert.JSONEq(t, `{"email":" Foo@ory.sh "}`, transform(t, tt, `{"email":" Foo@ory.sh "}`))
+	})
+
+	t.Run("case=applies the rules", func(t *testing.T) {
+		tt := configure(t, map[string]interface{}{
+			"rules": []map[string]interface{}{
+				{"path": "email", "transforms": []string{"trim", "lowercase"}},
+				{"path": "name.last", "transforms": []string{"trim", "uppercase"}},
+				{"path": "emails", "transforms": []string{"trim", "lowercase"}},
+				{"path": "age", "transforms": []string{"trim"}},
+				{"path": "missing", "transforms": []string{"trim"}},
+			},
+		})
+		require.NoError(t, tt.Validate(ctx))
+
+		assert.JSONEq(t,
+			`{"email":"foo@ory.sh","name":{"first":" Foo ","last":"BAR"},"emails":["foo@ory.sh","bar@ory.sh",1],"age":1}`,
+			transform(t, tt, `{"email":" Foo@ORY.sh ","name":{"first":" Foo ","last":" bar "},"emails":[" Foo@ory.sh","BAR@ory.sh ",1],"age":1}`))
+	})
+
+	t.Run("case=normalizes phone numbers", func(t *testing.T) {
+		for _, tc := range []struct {
+			countryCode string
+			in          string
+			expected    string
+		}{
+			{in: "+49 170 1234567", expected: "+491701234567"},
+			{in: "+49 (0)170 123-4567", expected: "+491701234567"},
+			{in: "0049 170/1234567", expected: "+491701234567"},
+			{in: "(555) 123-4567", expected: "(555) 123-4567"},
+			{in: "(555) 123-4567", countryCode: "1", expected: "+15551234567"},
+			{in: "0170 1234567", countryCode: "49", expected: "+491701234567"},
+			{in: "not a phone number", countryCode: "49", expected: "not a phone number"},
+			{in: "", countryCode: "49", expected: ""},
+			{in: "+0123", expected: "+0123"},
+		} {
+			t.Run("in="+tc.in+"/country="+tc.countryCode, func(t *testing.T) {
+				c := map[string]interface{}{
+					"rules": []map[string]interface{}{{"path": "phone", "transforms": []string{"e164"}}},
+				}
+				if tc.countryCode != "" {
+					c["default_country_calling_code"] = tc.countryCode
+				}
+
+				tt := configure(t, c)
+				assert.JSONEq(t, `{"phone":"`+tc.expected+`"}`, transform(t, tt, `{"phone":"`+tc.in+`"}`))
+			})
+		}
+	})
+
+	t.Run("case=applies the mapper after the rules", func(t *testing.T) {
+		tt := configure(t, map[string]interface{}{
+			"rules": []map[string]interface{}{{"path": "email", "transforms": []string{"trim"}}},
+			"mapper_url": "base64://" + base64.StdEncoding.EncodeToString([]byte(`local traits = std.extVar('traits');
+{ traits: traits + { username: std.split(traits.email, '@')[0] } }`)),
+		})
+		require.NoError(t, tt.Validate(ctx))
+
+		assert.JSONEq(t, `{"email":"foo@ory.sh","username":"foo"}`, transform(t, tt, `{"email":" foo@ory.sh"}`))
+	})
+
+	t.Run("case=fails if the mapper does not return traits", func(t *testing.T) {
+		tt := configure(t, map[string]interface{}{
+			"mapper_url": "base64://" + base64.StdEncoding.EncodeToString([]byte(`{}`)),
+		})
+		require.NoError(t, tt.Validate(ctx))
+
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		require.Error(t, tt.Transform(ctx, i))
+	})
+
+	t.Run("case=validation fails", func(t *testing.T) {
+		for _, c := range []map[string]interface{}{
+			{"rules": []map[string]interface{}{{"path": "emails.#", "transforms": []string{"trim"}}}},
+			{"mapper_url": "base64://" + base64.StdEncoding.EncodeToString([]byte(`{ traits: `))},
+		} {
+			tt := configure(t, c)
+			assert.Error(t, tt.Validate(ctx))
+		}
+	})
+}
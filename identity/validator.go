@@ -1,10 +1,15 @@
 package identity
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 
+	"github.com/pkg/errors"
 	"github.com/tidwall/sjson"
 
+	"github.com/ory/herodot"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/schema"
 )
@@ -33,19 +38,53 @@ func (v *Validator) ValidateWithRunner(ctx context.Context, i *Identity, runners
 		return err
 	}
 
-	s, err := v.d.IdentityTraitsSchemas(ctx).GetByID(i.SchemaID)
+	s, err := v.d.IdentityTraitsSchemas(ctx).GetByIDAndVersion(i.SchemaID, i.SchemaVersion)
 	if err != nil {
 		return err
 	}
 
+	if err := validateTraitsSize(s, i.Traits); err != nil {
+		return err
+	}
+
 	traits, err := sjson.SetRawBytes([]byte(`{}`), "traits", i.Traits)
 	if err != nil {
 		return err
 	}
 
+	if v.d.Configuration(ctx).SelfServiceFlowStructuredValidationErrors() {
+		return v.v.Validate(s.URL.String(), traits, schema.WithExtensionRunner(runner), schema.WithStructuredErrors())
+	}
+
 	return v.v.Validate(s.URL.String(), traits, schema.WithExtensionRunner(runner))
 }
 
+// validateTraitsSize rejects traits exceeding the schema's maximum size. Insignificant whitespace is not counted.
+func validateTraitsSize(s *schema.Schema, traits Traits) error {
+	if s.MaxTraitsSize <= 0 || len(traits) <= s.MaxTraitsSize {
+		return nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, traits); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse the identity traits: %s", err))
+	}
+
+	if compact.Len() > s.MaxTraitsSize {
+		return schema.NewTraitsTooLargeError(s.MaxTraitsSize, compact.Len())
+	}
+	return nil
+}
+
+// LatestSchemaVersion returns the latest version of the identity's traits schema.
+func (v *Validator) LatestSchemaVersion(ctx context.Context, i *Identity) (string, error) {
+	s, err := v.d.IdentityTraitsSchemas(ctx).GetByID(i.SchemaID)
+	if err != nil {
+		return "", err
+	}
+	return s.Version, nil
+}
+
 func (v *Validator) Validate(ctx context.Context, i *Identity) error {
 	return v.ValidateWithRunner(ctx, i,
 		NewSchemaExtensionCredentials(i),
@@ -2,6 +2,7 @@ package identity_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -9,11 +10,14 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/kratos/driver/config"
 	. "github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/text"
 )
 
 func TestSchemaValidator(t *testing.T) {
@@ -60,6 +64,7 @@ func TestSchemaValidator(t *testing.T) {
 	conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{
 		{ID: "whatever", URL: ts.URL + "/schema/whatever"},
 		{ID: "unreachable-url", URL: ts.URL + "/404-not-found"},
+		{ID: "limited", URL: ts.URL + "/schema/firstName", MaxTraitsSize: 64},
 	})
 	v := NewValidator(reg)
 
@@ -114,4 +119,21 @@ func TestSchemaValidator(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("case=rejects traits exceeding the maximum size", func(t *testing.T) {
+		require.NoError(t, v.Validate(context.Background(), &Identity{
+			SchemaID: "limited",
+			// Whitespace does not count towards the limit.
+			Traits: Traits(`{ "firstName": "first-name", "lastName": "last-name",      "age": 1 }`),
+		}))
+
+		err := v.Validate(context.Background(), &Identity{
+			SchemaID: "limited",
+			Traits:   Traits(`{"firstName":"first-name","lastName":"a-much-longer-last-name","age":1}`),
+		})
+		var ve *schema.ValidationError
+		require.True(t, errors.As(err, &ve), "%+v", err)
+		require.Len(t, ve.Messages, 1)
+		assert.Equal(t, text.ErrorValidationTraitsTooLarge, ve.Messages[0].ID)
+	})
 }
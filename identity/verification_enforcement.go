@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"time"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/text"
+)
+
+// ErrVerificationRequired is returned when an identity signs in which did not verify any of its addresses
+// within the grace period of its schema.
+var ErrVerificationRequired = herodot.ErrForbidden.
+	WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeIdentityVerificationRequired).
+	WithError("identity did not verify any of its addresses in time").
+	WithReason("This account must verify one of its addresses before it can be used again.")
+
+// IsVerificationOverdue returns true if the identity has verifiable addresses but did not verify any of them
+// within the grace period after its creation.
+func (i *Identity) IsVerificationOverdue(grace time.Duration, now time.Time) bool {
+	if len(i.VerifiableAddresses) == 0 {
+		return false
+	}
+
+	for _, a := range i.VerifiableAddresses {
+		if a.Verified {
+			return false
+		}
+	}
+
+	return now.After(i.CreatedAt.Add(grace))
+}
+
+// EnforcedVerificationAction returns the verification enforcement action of the identity's schema if the
+// identity's verification is overdue. It returns config.VerificationEnforcementNone otherwise.
+//
+// Because the action is derived from the identity's addresses, verifying an address lifts the restriction.
+func EnforcedVerificationAction(ss schema.Schemas, i *Identity, now time.Time) string {
+	s, err := ss.GetByID(i.SchemaID)
+	if err != nil {
+		return config.VerificationEnforcementNone
+	}
+
+	e := s.VerificationEnforcement
+	if e.Action == "" || e.Action == config.VerificationEnforcementNone {
+		return config.VerificationEnforcementNone
+	}
+
+	if !i.IsVerificationOverdue(e.GracePeriod, now) {
+		return config.VerificationEnforcementNone
+	}
+
+	return e.Action
+}
@@ -0,0 +1,73 @@
+package identity
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+)
+
+func TestEnforcedVerificationAction(t *testing.T) {
+	now := time.Now().UTC()
+	ss := schema.Schemas{
+		{ID: "default"},
+		{ID: "enforced", VerificationEnforcement: schema.VerificationEnforcement{
+			Action:      config.VerificationEnforcementDeactivate,
+			GracePeriod: time.Hour,
+		}},
+	}
+
+	newIdentity := func(schemaID string, age time.Duration, verified ...bool) *Identity {
+		i := NewIdentity(schemaID)
+		i.CreatedAt = now.Add(-age)
+		for _, v := range verified {
+			i.VerifiableAddresses = append(i.VerifiableAddresses, VerifiableAddress{Verified: v})
+		}
+		return i
+	}
+
+	for k, tc := range []struct {
+		d        string
+		i        *Identity
+		expected string
+	}{
+		{
+			d:        "schema without enforcement",
+			i:        newIdentity("default", 2*time.Hour, false),
+			expected: config.VerificationEnforcementNone,
+		},
+		{
+			d:        "unknown schema",
+			i:        newIdentity("unknown", 2*time.Hour, false),
+			expected: config.VerificationEnforcementNone,
+		},
+		{
+			d:        "within grace period",
+			i:        newIdentity("enforced", time.Minute, false),
+			expected: config.VerificationEnforcementNone,
+		},
+		{
+			d:        "no verifiable addresses",
+			i:        newIdentity("enforced", 2*time.Hour),
+			expected: config.VerificationEnforcementNone,
+		},
+		{
+			d:        "one address verified",
+			i:        newIdentity("enforced", 2*time.Hour, false, true),
+			expected: config.VerificationEnforcementNone,
+		},
+		{
+			d:        "grace period passed",
+			i:        newIdentity("enforced", 2*time.Hour, false, false),
+			expected: config.VerificationEnforcementDeactivate,
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+			assert.Equal(t, tc.expected, EnforcedVerificationAction(ss, tc.i, now))
+		})
+	}
+}
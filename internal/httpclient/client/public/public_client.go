@@ -689,7 +689,10 @@ func (a *Client) GetSelfServiceVerificationFlow(params *GetSelfServiceVerificati
 with browsers (Chrome, Firefox, ...).
 
 On successful logout, the browser will be redirected (HTTP 302 Found) to the `return_to` parameter of the initial request
-or fall back to `urls.default_return_to`.
+or fall back to `urls.default_return_to`. If front-channel logout URLs are configured, the endpoint instead responds
+with an HTML page which loads these URLs and then redirects the browser.
+
+If back-channel logout URLs are configured, they are notified about the ended session in the background.
 
 More information can be found at [ORY Kratos User Logout Documentation](https://www.ory.sh/docs/next/kratos/self-service/flows/user-logout).
 */
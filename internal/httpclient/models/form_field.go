@@ -20,6 +20,9 @@ type FormField struct {
 	// Disabled is the equivalent of `<input {{if .Disabled}}disabled{{end}}">`
 	Disabled bool `json:"disabled,omitempty"`
 
+	// group
+	Group FieldGroup `json:"group,omitempty"`
+
 	// messages
 	Messages Messages `json:"messages,omitempty"`
 
@@ -27,6 +30,9 @@ type FormField struct {
 	// Required: true
 	Name *string `json:"name"`
 
+	// Order is the position of the field within its group, starting at 1.
+	Order int64 `json:"order,omitempty"`
+
 	// Pattern is the equivalent of `<input pattern="{{.Pattern}}">`
 	Pattern string `json:"pattern,omitempty"`
 
@@ -45,6 +51,10 @@ type FormField struct {
 func (m *FormField) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateGroup(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateMessages(formats); err != nil {
 		res = append(res, err)
 	}
@@ -63,6 +73,22 @@ func (m *FormField) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *FormField) validateGroup(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Group) { // not required
+		return nil
+	}
+
+	if err := m.Group.Validate(formats); err != nil {
+		if ve, ok := err.(*errors.Validation); ok {
+			return ve.ValidateName("group")
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (m *FormField) validateMessages(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.Messages) { // not required
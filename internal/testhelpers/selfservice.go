@@ -17,8 +17,10 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
 )
 
 func TestSelfServicePreHook(
@@ -45,7 +47,7 @@ func TestSelfServicePreHook(
 
 		t.Run("case=err if hooks err", func(t *testing.T) {
 			t.Cleanup(SelfServiceHookConfigReset(t, conf))
-			conf.MustSet(configKey, []config.SelfServiceHook{{Name: "err", Config: []byte(`{"ExecuteLoginPreHook": "err","ExecuteRegistrationPreHook": "err"}`)}})
+			conf.MustSet(configKey, []config.SelfServiceHook{{Name: "err", Config: []byte(`{"ExecuteLoginPreHook": "err","ExecuteRegistrationPreHook": "err","ExecuteRecoveryPreHook": "err","ExecuteVerificationPreHook": "err"}`)}})
 
 			res, body := makeRequestPre(t, newServer(t))
 			assert.EqualValues(t, http.StatusInternalServerError, res.StatusCode, "%s", body)
@@ -54,7 +56,7 @@ func TestSelfServicePreHook(
 
 		t.Run("case=abort if hooks aborts", func(t *testing.T) {
 			t.Cleanup(SelfServiceHookConfigReset(t, conf))
-			conf.MustSet(configKey, []config.SelfServiceHook{{Name: "err", Config: []byte(`{"ExecuteLoginPreHook": "abort","ExecuteRegistrationPreHook": "abort"}`)}})
+			conf.MustSet(configKey, []config.SelfServiceHook{{Name: "err", Config: []byte(`{"ExecuteLoginPreHook": "abort","ExecuteRegistrationPreHook": "abort","ExecuteRecoveryPreHook": "abort","ExecuteVerificationPreHook": "abort"}`)}})
 
 			res, body := makeRequestPre(t, newServer(t))
 			assert.EqualValues(t, http.StatusOK, res.StatusCode)
@@ -93,6 +95,8 @@ func SelfServiceHookConfigReset(t *testing.T, conf *config.Provider) func() {
 		conf.MustSet(config.ViperKeySelfServiceLoginBeforeHooks, nil)
 		conf.MustSet(config.ViperKeySelfServiceRegistrationAfter, nil)
 		conf.MustSet(config.ViperKeySelfServiceRegistrationBeforeHooks, nil)
+		conf.MustSet(config.ViperKeySelfServiceRecoveryBeforeHooks, nil)
+		conf.MustSet(config.ViperKeySelfServiceVerificationBeforeHooks, nil)
 		conf.MustSet(config.ViperKeySelfServiceSettingsAfter, nil)
 	}
 }
@@ -137,6 +141,14 @@ func SelfServiceHookRegistrationErrorHandler(t *testing.T, w http.ResponseWriter
 	return selfServiceHookErrorHandler(t, w, r, registration.ErrHookAbortFlow, err)
 }
 
+func SelfServiceHookRecoveryErrorHandler(t *testing.T, w http.ResponseWriter, r *http.Request, err error) bool {
+	return selfServiceHookErrorHandler(t, w, r, recovery.ErrHookAbortFlow, err)
+}
+
+func SelfServiceHookVerificationErrorHandler(t *testing.T, w http.ResponseWriter, r *http.Request, err error) bool {
+	return selfServiceHookErrorHandler(t, w, r, verification.ErrHookAbortFlow, err)
+}
+
 func SelfServiceHookSettingsErrorHandler(t *testing.T, w http.ResponseWriter, r *http.Request, err error) bool {
 	return selfServiceHookErrorHandler(t, w, r, settings.ErrHookAbortRequest, err)
 }
@@ -170,6 +182,14 @@ func SelfServiceMakeRegistrationPostHookRequest(t *testing.T, ts *httptest.Serve
 	return selfServiceMakeHookRequest(t, ts, "/registration/post", asAPI, query)
 }
 
+func SelfServiceMakeRecoveryPreHookRequest(t *testing.T, ts *httptest.Server) (*http.Response, string) {
+	return selfServiceMakeHookRequest(t, ts, "/recovery/pre", false, url.Values{})
+}
+
+func SelfServiceMakeVerificationPreHookRequest(t *testing.T, ts *httptest.Server) (*http.Response, string) {
+	return selfServiceMakeHookRequest(t, ts, "/verification/pre", false, url.Values{})
+}
+
 func SelfServiceMakeSettingsPostHookRequest(t *testing.T, ts *httptest.Server, asAPI bool, query url.Values) (*http.Response, string) {
 	return selfServiceMakeHookRequest(t, ts, "/settings/post", asAPI, query)
 }
@@ -0,0 +1,100 @@
+package maintenance
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/jsonx"
+
+	"github.com/ory/kratos/x"
+)
+
+const RouteBase = "/maintenance"
+
+type (
+	handlerDependencies interface {
+		ModeProvider
+		x.WriterProvider
+	}
+	HandlerProvider interface {
+		MaintenanceHandler() *Handler
+	}
+	Handler struct {
+		r handlerDependencies
+	}
+
+	// swagger:model maintenanceStatus
+	Status struct {
+		// Enabled is true if requests which modify data are rejected.
+		//
+		// required: true
+		Enabled bool `json:"enabled"`
+	}
+)
+
+func NewHandler(r handlerDependencies) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteBase, h.get)
+	admin.PUT(RouteBase, h.set)
+}
+
+// swagger:route GET /maintenance admin getMaintenanceStatus
+//
+// Get Maintenance Status
+//
+// Returns whether maintenance mode is enabled.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: maintenanceStatus
+//       500: genericError
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.r.Writer().Write(w, r, &Status{Enabled: h.r.MaintenanceMode().Enabled(r.Context())})
+}
+
+// swagger:parameters setMaintenanceStatus
+// nolint:deadcode,unused
+type setMaintenanceStatusParameters struct {
+	// in: body
+	Body Status
+}
+
+// swagger:route PUT /maintenance admin setMaintenanceStatus
+//
+// Enable or Disable Maintenance Mode
+//
+// While maintenance mode is enabled, requests which modify data are rejected with 503 Service Unavailable.
+// The value takes precedence over `maintenance.enabled` until ORY Kratos is restarted and only affects the
+// instance which received the request.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: maintenanceStatus
+//       400: genericError
+//       500: genericError
+func (h *Handler) set(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var s Status
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&s); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	h.r.MaintenanceMode().Set(r.Context(), s.Enabled)
+	h.r.Writer().Write(w, r, &Status{Enabled: h.r.MaintenanceMode().Enabled(r.Context())})
+}
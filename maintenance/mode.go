@@ -0,0 +1,152 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/logout"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+var ErrMaintenance = herodot.DefaultError{
+	CodeField:    http.StatusServiceUnavailable,
+	StatusField:  http.StatusText(http.StatusServiceUnavailable),
+	ErrorField:   "The service is undergoing maintenance, please try again later.",
+	DetailsField: map[string]interface{}{text.ErrorCodeDetailKey: text.ErrorCodeMaintenanceMode},
+}
+
+type (
+	modeDependencies interface {
+		config.Providers
+		x.LoggingProvider
+		x.WriterProvider
+	}
+	ModeProvider interface {
+		MaintenanceMode() *Mode
+	}
+
+	// Mode rejects requests which modify data while maintenance mode is enabled. It is enabled using
+	// `maintenance.enabled` or at runtime using the admin API, which takes precedence over the configuration.
+	Mode struct {
+		sync.Mutex
+		d        modeDependencies
+		override *bool
+		enabled  bool
+	}
+)
+
+// exemptPaths are never rejected. The session check accepts any HTTP method but does not modify data,
+// maintenance mode must be possible to disable while it is enabled, and health checks must keep working.
+var exemptPaths = []string{
+	session.RouteWhoami,
+	RouteBase,
+	healthx.AliveCheckPath,
+	healthx.ReadyCheckPath,
+	healthx.VersionPath,
+}
+
+// mutatingReadPaths are requested using GET but persist flows, identities, or sessions.
+var mutatingReadPaths = []string{
+	login.RouteInitBrowserFlow,
+	login.RouteInitAPIFlow,
+	registration.RouteInitBrowserFlow,
+	registration.RouteInitAPIFlow,
+	settings.RouteInitBrowserFlow,
+	settings.RouteInitAPIFlow,
+	recovery.RouteInitBrowserFlow,
+	recovery.RouteInitAPIFlow,
+	verification.RouteInitBrowserFlow,
+	verification.RouteInitAPIFlow,
+	logout.RouteBrowser,
+	link.RouteRecovery,
+	link.RouteVerification,
+}
+
+// oidcCallbackPrefix matches the OpenID Connect callback of every provider.
+var oidcCallbackPrefix = strings.TrimSuffix(oidc.RouteCallback, ":provider")
+
+func NewMode(d modeDependencies) *Mode {
+	return &Mode{d: d}
+}
+
+// Enabled returns true if maintenance mode is enabled.
+func (m *Mode) Enabled(ctx context.Context) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	enabled := m.d.Configuration(ctx).MaintenanceEnabled()
+	if m.override != nil {
+		enabled = *m.override
+	}
+
+	if enabled != m.enabled {
+		m.enabled = enabled
+		if enabled {
+			m.d.Logger().Warn("Maintenance mode was enabled, requests which modify data will be rejected.")
+		} else {
+			m.d.Logger().Info("Maintenance mode was disabled.")
+		}
+	}
+
+	return enabled
+}
+
+// Set enables or disables maintenance mode regardless of the configuration until the process restarts.
+func (m *Mode) Set(ctx context.Context, enabled bool) {
+	m.Lock()
+	m.override = &enabled
+	m.Unlock()
+
+	m.d.Audit().
+		WithField("maintenance", enabled).
+		Info("Maintenance mode was changed using the admin API.")
+	m.Enabled(ctx)
+}
+
+func isMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		for _, p := range mutatingReadPaths {
+			if r.URL.Path == p {
+				return true
+			}
+		}
+		return strings.HasPrefix(r.URL.Path, oidcCallbackPrefix)
+	}
+	return true
+}
+
+func (m *Mode) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	for _, p := range exemptPaths {
+		if r.URL.Path == p {
+			next(w, r)
+			return
+		}
+	}
+
+	if !isMutating(r) || !m.Enabled(r.Context()) {
+		next(w, r)
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(m.d.Configuration(r.Context()).MaintenanceRetryAfter().Seconds())))
+	m.d.Writer().WriteError(w, r, errors.WithStack(ErrMaintenance))
+}
@@ -0,0 +1,138 @@
+package maintenance_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/maintenance"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/logout"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+func TestMode(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	do := func(t *testing.T, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		reg.MaintenanceMode().ServeHTTP(w, httptest.NewRequest(method, path, nil), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w
+	}
+
+	t.Run("case=passes through if disabled", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, "POST", "/self-service/registration/methods/password").Code)
+		assert.Equal(t, http.StatusNoContent, do(t, "GET", login.RouteInitBrowserFlow).Code)
+	})
+
+	conf.MustSet(config.ViperKeyMaintenanceEnabled, true)
+	conf.MustSet(config.ViperKeyMaintenanceRetryAfter, "2m")
+
+	t.Run("case=rejects mutations", func(t *testing.T) {
+		for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+			res := do(t, method, "/identities/some-id")
+			assert.Equal(t, http.StatusServiceUnavailable, res.Code, method)
+			assert.Equal(t, "120", res.Header().Get("Retry-After"), method)
+			assert.Equal(t, string(text.ErrorCodeMaintenanceMode), gjson.GetBytes(res.Body.Bytes(), "error.details.id").String(), method)
+		}
+	})
+
+	t.Run("case=rejects reads which modify data", func(t *testing.T) {
+		for _, path := range []string{
+			login.RouteInitBrowserFlow,
+			registration.RouteInitAPIFlow,
+			settings.RouteInitBrowserFlow,
+			recovery.RouteInitAPIFlow,
+			verification.RouteInitBrowserFlow,
+			logout.RouteBrowser,
+			link.RouteRecovery,
+			link.RouteVerification,
+			"/self-service/methods/oidc/callback/github",
+		} {
+			res := do(t, "GET", path)
+			assert.Equal(t, http.StatusServiceUnavailable, res.Code, path)
+			assert.Equal(t, string(text.ErrorCodeMaintenanceMode), gjson.GetBytes(res.Body.Bytes(), "error.details.id").String(), path)
+		}
+	})
+
+	for _, tc := range []struct {
+		d      string
+		method string
+		path   string
+	}{
+		{d: "reads are allowed", method: "GET", path: "/identities"},
+		{d: "fetching flows is allowed", method: "GET", path: login.RouteGetFlow},
+		{d: "health checks are not intercepted", method: "GET", path: healthx.AliveCheckPath},
+		{d: "readiness checks are not intercepted", method: "GET", path: healthx.ReadyCheckPath},
+		{d: "session checks are allowed", method: "POST", path: session.RouteWhoami},
+		{d: "maintenance mode can be changed", method: "PUT", path: maintenance.RouteBase},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			assert.Equal(t, http.StatusNoContent, do(t, tc.method, tc.path).Code)
+		})
+	}
+
+	t.Run("case=runtime value takes precedence", func(t *testing.T) {
+		reg.MaintenanceMode().Set(context.Background(), false)
+		assert.False(t, reg.MaintenanceMode().Enabled(context.Background()))
+		assert.Equal(t, http.StatusNoContent, do(t, "POST", "/identities").Code)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+	router := x.NewRouterAdmin()
+	reg.MaintenanceHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T) bool {
+		res, err := ts.Client().Get(ts.URL + maintenance.RouteBase)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var s maintenance.Status
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&s))
+		return s.Enabled
+	}
+
+	set := func(t *testing.T, body string) *http.Response {
+		req, err := http.NewRequest("PUT", ts.URL+maintenance.RouteBase, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res
+	}
+
+	assert.False(t, get(t))
+
+	assert.Equal(t, http.StatusOK, set(t, `{"enabled":true}`).StatusCode)
+	assert.True(t, get(t))
+
+	assert.Equal(t, http.StatusOK, set(t, `{"enabled":false}`).StatusCode)
+	assert.False(t, get(t))
+
+	assert.Equal(t, http.StatusBadRequest, set(t, `{"foo":true}`).StatusCode)
+}
@@ -1,32 +1,144 @@
 package prometheus
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 // Metrics prototypes
 type Metrics struct {
-	ResponseTime *prometheus.HistogramVec
+	ResponseTime          *prometheus.HistogramVec
+	CourierQueueDepth     prometheus.Gauge
+	CourierMessagesSent   prometheus.Counter
+	CourierMessagesFailed prometheus.Counter
+
+	CourierDispatchMaxConcurrency prometheus.Gauge
+	CourierDispatchRateLimit      prometheus.Gauge
+	CourierDispatchInFlight       prometheus.Gauge
+
+	DatabaseCircuitBreakerOpen        prometheus.Gauge
+	DatabaseCircuitBreakerTransitions *prometheus.CounterVec
+
+	StrategyRateLimitRequests *prometheus.CounterVec
+
+	AsyncHookExecutions *prometheus.CounterVec
 }
 
 // Method for creation new custom Prometheus  metrics
 func NewMetrics(version, hash, date string) *Metrics {
+	labels := map[string]string{
+		"version":   version,
+		"hash":      hash,
+		"buildTime": date,
+	}
+
 	pm := &Metrics{
 		ResponseTime: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
-				Name: "kratos_response_time_seconds",
-				Help: "Description",
-				ConstLabels: map[string]string{
-					"version":   version,
-					"hash":      hash,
-					"buildTime": date,
-				},
+				Name:        "kratos_response_time_seconds",
+				Help:        "Description",
+				ConstLabels: labels,
 			},
 			[]string{"endpoint"},
 		),
+		CourierQueueDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "kratos_courier_queue_depth",
+				Help:        "Number of courier messages which are queued and have not been sent yet.",
+				ConstLabels: labels,
+			},
+		),
+		CourierMessagesSent: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name:        "kratos_courier_messages_sent_total",
+				Help:        "Number of courier messages which were sent successfully.",
+				ConstLabels: labels,
+			},
+		),
+		CourierMessagesFailed: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name:        "kratos_courier_messages_failed_total",
+				Help:        "Number of failed attempts to send a courier message.",
+				ConstLabels: labels,
+			},
+		),
+		CourierDispatchMaxConcurrency: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "kratos_courier_dispatch_max_concurrency",
+				Help:        "Maximum number of courier messages which are sent at the same time.",
+				ConstLabels: labels,
+			},
+		),
+		CourierDispatchRateLimit: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "kratos_courier_dispatch_rate_limit",
+				Help:        "Maximum sustained number of courier messages sent per second. Zero if the send rate is not limited.",
+				ConstLabels: labels,
+			},
+		),
+		CourierDispatchInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "kratos_courier_dispatch_in_flight",
+				Help:        "Number of courier messages which are being sent right now.",
+				ConstLabels: labels,
+			},
+		),
+		DatabaseCircuitBreakerOpen: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name:        "kratos_database_circuit_breaker_open",
+				Help:        "One if requests are rejected because the database is unavailable, zero otherwise.",
+				ConstLabels: labels,
+			},
+		),
+		DatabaseCircuitBreakerTransitions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "kratos_database_circuit_breaker_transitions_total",
+				Help:        "Number of times the database circuit breaker changed to the given state.",
+				ConstLabels: labels,
+			},
+			[]string{"state"},
+		),
+		StrategyRateLimitRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "kratos_strategy_rate_limit_requests_total",
+				Help:        "Number of rate limited flow submissions per strategy and whether they were allowed or rejected.",
+				ConstLabels: labels,
+			},
+			[]string{"strategy", "result"},
+		),
+		AsyncHookExecutions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "kratos_async_hook_executions_total",
+				Help:        "Number of executions of asynchronous hooks per hook and whether they succeeded, will be retried, or failed permanently.",
+				ConstLabels: labels,
+			},
+			[]string{"hook", "result"},
+		),
 	}
-	err := prometheus.Register(pm.ResponseTime)
 
-	if err != nil {
+	pm.ResponseTime = register(pm.ResponseTime).(*prometheus.HistogramVec)
+	pm.CourierQueueDepth = register(pm.CourierQueueDepth).(prometheus.Gauge)
+	pm.CourierMessagesSent = register(pm.CourierMessagesSent).(prometheus.Counter)
+	pm.CourierMessagesFailed = register(pm.CourierMessagesFailed).(prometheus.Counter)
+	pm.CourierDispatchMaxConcurrency = register(pm.CourierDispatchMaxConcurrency).(prometheus.Gauge)
+	pm.CourierDispatchRateLimit = register(pm.CourierDispatchRateLimit).(prometheus.Gauge)
+	pm.CourierDispatchInFlight = register(pm.CourierDispatchInFlight).(prometheus.Gauge)
+	pm.DatabaseCircuitBreakerOpen = register(pm.DatabaseCircuitBreakerOpen).(prometheus.Gauge)
+	pm.DatabaseCircuitBreakerTransitions = register(pm.DatabaseCircuitBreakerTransitions).(*prometheus.CounterVec)
+	pm.StrategyRateLimitRequests = register(pm.StrategyRateLimitRequests).(*prometheus.CounterVec)
+	pm.AsyncHookExecutions = register(pm.AsyncHookExecutions).(*prometheus.CounterVec)
+	return pm
+}
+
+// register registers the collector and returns it. If an identical collector has already been registered,
+// for example by another registry in the same process, the existing collector is returned instead.
+func register(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
 		panic(err)
 	}
-	return pm
+	return c
 }
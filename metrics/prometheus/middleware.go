@@ -22,3 +22,59 @@ func (pmm *MetricsManager) ServeHTTP(rw http.ResponseWriter, r *http.Request, ne
 
 	pmm.prometheusMetrics.ResponseTime.WithLabelValues(r.URL.Path).Observe(time.Since(start).Seconds())
 }
+
+// SetCourierQueueDepth records the number of courier messages which have not been sent yet.
+func (pmm *MetricsManager) SetCourierQueueDepth(depth int) {
+	pmm.prometheusMetrics.CourierQueueDepth.Set(float64(depth))
+}
+
+// CourierMessageSent records a successfully sent courier message.
+func (pmm *MetricsManager) CourierMessageSent() {
+	pmm.prometheusMetrics.CourierMessagesSent.Inc()
+}
+
+// CourierMessageFailed records a failed attempt to send a courier message.
+func (pmm *MetricsManager) CourierMessageFailed() {
+	pmm.prometheusMetrics.CourierMessagesFailed.Inc()
+}
+
+// SetCourierDispatchLimits records the effective concurrency and send rate limits of the courier.
+func (pmm *MetricsManager) SetCourierDispatchLimits(maxConcurrency int, perSecond float64) {
+	pmm.prometheusMetrics.CourierDispatchMaxConcurrency.Set(float64(maxConcurrency))
+	pmm.prometheusMetrics.CourierDispatchRateLimit.Set(perSecond)
+}
+
+// CourierDispatchStarted records a courier message which is being sent.
+func (pmm *MetricsManager) CourierDispatchStarted() {
+	pmm.prometheusMetrics.CourierDispatchInFlight.Inc()
+}
+
+// CourierDispatchFinished records a courier message which is no longer being sent.
+func (pmm *MetricsManager) CourierDispatchFinished() {
+	pmm.prometheusMetrics.CourierDispatchInFlight.Dec()
+}
+
+// DatabaseCircuitBreakerTransitioned records a state change of the database circuit breaker.
+func (pmm *MetricsManager) DatabaseCircuitBreakerTransitioned(state string, open bool) {
+	pmm.prometheusMetrics.DatabaseCircuitBreakerTransitions.WithLabelValues(state).Inc()
+	if open {
+		pmm.prometheusMetrics.DatabaseCircuitBreakerOpen.Set(1)
+	} else {
+		pmm.prometheusMetrics.DatabaseCircuitBreakerOpen.Set(0)
+	}
+}
+
+// StrategyRateLimitChecked records a flow submission which was checked against the strategy's rate limit.
+func (pmm *MetricsManager) StrategyRateLimitChecked(strategy string, allowed bool) {
+	result := "allowed"
+	if !allowed {
+		result = "rejected"
+	}
+	pmm.prometheusMetrics.StrategyRateLimitRequests.WithLabelValues(strategy, result).Inc()
+}
+
+// AsyncHookExecuted records the result of executing an asynchronous hook. The result is one of
+// `succeeded`, `retried`, or `failed`.
+func (pmm *MetricsManager) AsyncHookExecuted(hook, result string) {
+	pmm.prometheusMetrics.AsyncHookExecutions.WithLabelValues(hook, result).Inc()
+}
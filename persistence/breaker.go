@@ -0,0 +1,249 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/metrics/prometheus"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+var ErrDatabaseUnavailable = herodot.DefaultError{
+	CodeField:    http.StatusServiceUnavailable,
+	StatusField:  http.StatusText(http.StatusServiceUnavailable),
+	ErrorField:   "The database is currently unavailable, please try again later.",
+	DetailsField: map[string]interface{}{text.ErrorCodeDetailKey: text.ErrorCodeDatabaseUnavailable},
+}
+
+const (
+	// BreakerClosed means that the database is available and requests are accepted.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen means that the database is unavailable and requests are rejected.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen means that requests are rejected while checking whether the database recovered.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type (
+	breakerDependencies interface {
+		config.Providers
+		x.LoggingProvider
+		x.WriterProvider
+		PrometheusManager() *prometheus.MetricsManager
+	}
+	BreakerProvider interface {
+		DatabaseBreaker() *Breaker
+	}
+
+	BreakerState string
+
+	// Breaker rejects requests while the database is unavailable, as configured in `database.circuit_breaker`.
+	// The database is checked in the background whenever a request is received and the last check is older
+	// than the check interval, so requests never wait for a check. Failed queries reported with RecordError
+	// count as failed checks.
+	Breaker struct {
+		sync.Mutex
+		d    breakerDependencies
+		ping func() error
+
+		state     BreakerState
+		failures  int
+		lastCheck time.Time
+		checking  bool
+
+		// queryErr is the last query error recorded since the last check.
+		queryErr error
+	}
+)
+
+// breakerExemptPaths are never rejected so that the health checks and metrics keep working.
+var breakerExemptPaths = []string{
+	healthx.AliveCheckPath,
+	healthx.ReadyCheckPath,
+	healthx.VersionPath,
+	prometheus.MetricsPrometheusPath,
+}
+
+func NewBreaker(d breakerDependencies, ping func() error) *Breaker {
+	return &Breaker{d: d, ping: ping, state: BreakerClosed}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.Lock()
+	defer b.Unlock()
+	return b.state
+}
+
+// Ready returns an error if the breaker rejects requests. It is used as a readiness check.
+func (b *Breaker) Ready() error {
+	if state := b.State(); state != BreakerClosed {
+		return errors.Errorf("the database circuit breaker is %s", state)
+	}
+	return nil
+}
+
+// Check pings the database and updates the state of the breaker.
+func (b *Breaker) Check(ctx context.Context) {
+	conf := b.d.Configuration(ctx).DatabaseCircuitBreaker()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.ping()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(conf.CheckTimeout):
+		err = errors.Errorf("the database did not respond within %s", conf.CheckTimeout)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.checking = false
+	b.lastCheck = time.Now()
+
+	queryErr := b.queryErr
+	b.queryErr = nil
+	if err == nil && queryErr != nil {
+		// The database responds to pings but queries failed since the last check, so it did not recover. The
+		// failure was already counted by RecordError.
+		if b.state == BreakerHalfOpen {
+			b.transition(BreakerOpen, queryErr)
+		}
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		b.transition(BreakerClosed, nil)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= conf.FailureThreshold {
+		b.transition(BreakerOpen, err)
+	}
+}
+
+// RecordError counts a failed query as a failed check if the error indicates that the database is unavailable.
+// Other errors, such as missing rows or constraint violations, are ignored.
+func (b *Breaker) RecordError(ctx context.Context, err error) {
+	if err == nil || !isUnavailableError(err) {
+		return
+	}
+
+	conf := b.d.Configuration(ctx).DatabaseCircuitBreaker()
+	if !conf.Enabled {
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	b.queryErr = err
+	b.failures++
+	if b.state == BreakerClosed && b.failures >= conf.FailureThreshold {
+		b.lastCheck = time.Now()
+		b.transition(BreakerOpen, err)
+	}
+}
+
+// isUnavailableError returns true if the error was caused by a broken or refused database connection. Network
+// errors of HTTP requests, for example to webhooks, are not database errors.
+func isUnavailableError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	if e := new(url.Error); errors.As(err, &e) {
+		return false
+	}
+
+	e := new(net.OpError)
+	return errors.As(err, &e)
+}
+
+// transition must be called while holding the lock.
+func (b *Breaker) transition(state BreakerState, err error) {
+	if b.state == state {
+		return
+	}
+
+	b.state = state
+	b.d.PrometheusManager().DatabaseCircuitBreakerTransitioned(string(state), state != BreakerClosed)
+
+	l := b.d.Logger().WithField("circuit_breaker_state", state)
+	switch state {
+	case BreakerOpen:
+		l.WithError(err).Error("The database is unavailable, requests will be rejected until it recovers.")
+	case BreakerClosed:
+		l.Info("The database recovered, requests are accepted again.")
+	default:
+		l.Debug("Checking whether the database recovered.")
+	}
+}
+
+// checkIfDue starts a check in the background if one is due.
+func (b *Breaker) checkIfDue(ctx context.Context) {
+	conf := b.d.Configuration(ctx).DatabaseCircuitBreaker()
+
+	b.Lock()
+	defer b.Unlock()
+	if b.checking {
+		return
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		if time.Since(b.lastCheck) < conf.CheckInterval {
+			return
+		}
+	case BreakerOpen:
+		if time.Since(b.lastCheck) < conf.OpenDuration {
+			return
+		}
+		b.transition(BreakerHalfOpen, nil)
+	}
+
+	b.checking = true
+	go b.Check(context.Background())
+}
+
+func (b *Breaker) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	conf := b.d.Configuration(r.Context()).DatabaseCircuitBreaker()
+	if !conf.Enabled {
+		next(w, r)
+		return
+	}
+
+	for _, p := range breakerExemptPaths {
+		if r.URL.Path == p {
+			next(w, r)
+			return
+		}
+	}
+
+	b.checkIfDue(r.Context())
+	if b.State() == BreakerClosed {
+		next(w, r)
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(conf.OpenDuration.Seconds())))
+	b.d.Writer().WriteError(w, r, errors.WithStack(ErrDatabaseUnavailable))
+}
@@ -0,0 +1,166 @@
+package persistence_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/text"
+)
+
+type fakeDatabase struct {
+	sync.Mutex
+	err   error
+	delay time.Duration
+}
+
+func (d *fakeDatabase) set(err error, delay time.Duration) {
+	d.Lock()
+	defer d.Unlock()
+	d.err, d.delay = err, delay
+}
+
+func (d *fakeDatabase) ping() error {
+	d.Lock()
+	err, delay := d.err, d.delay
+	d.Unlock()
+	time.Sleep(delay)
+	return err
+}
+
+func TestBreaker(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDatabaseCircuitBreakerFailureThreshold, 2)
+	conf.MustSet(config.ViperKeyDatabaseCircuitBreakerCheckInterval, "1ms")
+	conf.MustSet(config.ViperKeyDatabaseCircuitBreakerCheckTimeout, "50ms")
+	conf.MustSet(config.ViperKeyDatabaseCircuitBreakerOpenDuration, "30s")
+
+	db := new(fakeDatabase)
+	b := persistence.NewBreaker(reg, db.ping)
+	ctx := context.Background()
+
+	do := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, httptest.NewRequest("GET", path, nil), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w
+	}
+
+	t.Run("case=passes through if disabled", func(t *testing.T) {
+		db.set(errors.New("connection refused"), 0)
+		for i := 0; i < 3; i++ {
+			b.Check(ctx)
+		}
+		assert.Equal(t, http.StatusNoContent, do(t, "/identities").Code)
+		db.set(nil, 0)
+		b.Check(ctx)
+	})
+
+	conf.MustSet(config.ViperKeyDatabaseCircuitBreakerEnabled, true)
+
+	t.Run("case=opens after consecutive failures", func(t *testing.T) {
+		db.set(errors.New("connection refused"), 0)
+
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerClosed, b.State(), "a single failure must not open the breaker")
+		assert.NoError(t, b.Ready())
+		assert.Equal(t, http.StatusNoContent, do(t, "/identities").Code)
+
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerOpen, b.State())
+		assert.Error(t, b.Ready())
+
+		res := do(t, "/identities")
+		assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+		assert.Equal(t, "30", res.Header().Get("Retry-After"))
+		assert.Equal(t, string(text.ErrorCodeDatabaseUnavailable), gjson.GetBytes(res.Body.Bytes(), "error.details.id").String())
+
+		assert.Equal(t, http.StatusNoContent, do(t, healthx.ReadyCheckPath).Code, "health checks must not be rejected")
+	})
+
+	t.Run("case=closes once the database recovers", func(t *testing.T) {
+		db.set(nil, 0)
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerClosed, b.State())
+		assert.NoError(t, b.Ready())
+		assert.Equal(t, http.StatusNoContent, do(t, "/identities").Code)
+	})
+
+	t.Run("case=slow responses count as failures", func(t *testing.T) {
+		db.set(nil, 200*time.Millisecond)
+		b.Check(ctx)
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerOpen, b.State())
+
+		db.set(nil, 0)
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerClosed, b.State())
+	})
+
+	t.Run("case=opens if queries fail although pings succeed", func(t *testing.T) {
+		db.set(nil, 0)
+		queryErr := errors.WithStack(driver.ErrBadConn)
+
+		b.RecordError(ctx, queryErr)
+		assert.Equal(t, persistence.BreakerClosed, b.State())
+		b.RecordError(ctx, queryErr)
+		assert.Equal(t, persistence.BreakerOpen, b.State())
+		assert.Equal(t, http.StatusServiceUnavailable, do(t, "/identities").Code)
+
+		b.RecordError(ctx, queryErr)
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerOpen, b.State(), "a successful ping must not close the breaker while queries fail")
+
+		b.Check(ctx)
+		assert.Equal(t, persistence.BreakerClosed, b.State())
+	})
+
+	t.Run("case=ignores errors which do not indicate an unavailable database", func(t *testing.T) {
+		for _, err := range []error{
+			errors.WithStack(sqlcon.ErrNoRows),
+			errors.WithStack(sqlcon.ErrUniqueViolation),
+			errors.WithStack(&url.Error{Op: "Post", URL: "https://www.ory.sh/", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}),
+		} {
+			for i := 0; i < 3; i++ {
+				b.RecordError(ctx, err)
+			}
+			assert.Equal(t, persistence.BreakerClosed, b.State(), "%+v", err)
+		}
+	})
+
+	t.Run("case=records database errors of responses", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			reg.Writer().WriteError(httptest.NewRecorder(), httptest.NewRequest("GET", "/identities", nil),
+				errors.WithStack(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}))
+		}
+		assert.Equal(t, persistence.BreakerOpen, reg.DatabaseBreaker().State())
+	})
+
+	t.Run("case=checks in the background while closed", func(t *testing.T) {
+		db.set(errors.New("connection refused"), 0)
+		assert.Eventually(t, func() bool {
+			do(t, "/identities")
+			return b.State() == persistence.BreakerOpen
+		}, time.Second, 5*time.Millisecond)
+
+		db.set(nil, 0)
+		b.Check(ctx)
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gobuffalo/pop/v5"
 
+	"github.com/ory/kratos/apikey"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
 	"github.com/ory/kratos/identity"
@@ -15,6 +16,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/selfservice/strategy/link"
 	"github.com/ory/kratos/session"
 )
@@ -25,9 +27,12 @@ type Provider interface {
 
 type Persister interface {
 	continuity.Persister
+	apikey.Persister
 	identity.PrivilegedPool
 	registration.FlowPersister
 	login.FlowPersister
+	login.AttemptPersister
+	login.AttemptLogPersister
 	settings.FlowPersister
 	courier.Persister
 	session.Persister
@@ -36,10 +41,12 @@ type Persister interface {
 	recovery.FlowPersister
 	link.RecoveryTokenPersister
 	link.VerificationTokenPersister
+	hook.AsyncJobPersister
 
 	Close(context.Context) error
 	Ping() error
 	MigrationStatus(c context.Context, b io.Writer) error
+	PendingMigrations(c context.Context) (int, error)
 	MigrateDown(c context.Context, steps int) error
 	MigrateUp(c context.Context) error
 	GetConnection(ctx context.Context) *pop.Connection
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/recovery"
@@ -205,6 +206,61 @@ func TestMigrations(t *testing.T) {
 						compareWithFixture(t, id, "recovery_token", id.ID.String())
 					}
 				})
+
+				t.Run("case=plaintext tokens are expired", func(t *testing.T) {
+					raw, err := ioutil.ReadFile(filepath.Join("fixtures", "expired_token", "20210126100000.json"))
+					require.NoError(t, err)
+
+					var expired struct {
+						RecoveryToken     []string `json:"recovery_token"`
+						VerificationToken []string `json:"verification_token"`
+					}
+					require.NoError(t, json.Unmarshal(raw, &expired))
+
+					for _, id := range expired.RecoveryToken {
+						exists, err := c.Where("id = ?", id).Exists(&link.RecoveryToken{})
+						require.NoError(t, err)
+						assert.False(t, exists, "recovery token %s should have been deleted", id)
+					}
+					for _, id := range expired.VerificationToken {
+						exists, err := c.Where("id = ?", id).Exists(&link.VerificationToken{})
+						require.NoError(t, err)
+						assert.False(t, exists, "verification token %s should have been deleted", id)
+					}
+
+					for _, id := range []string{"5f0e22b8-6f24-4ae6-9e8a-0d3f2a3a6c51"} {
+						exists, err := c.Where("id = ?", id).Exists(&link.RecoveryToken{})
+						require.NoError(t, err)
+						assert.True(t, exists, "recovery token %s should have been kept", id)
+					}
+					for _, id := range []string{"8c2f6f1e-3b1d-4c0a-a6e2-5d8b9f7e1a23"} {
+						exists, err := c.Where("id = ?", id).Exists(&link.VerificationToken{})
+						require.NoError(t, err)
+						assert.True(t, exists, "verification token %s should have been kept", id)
+					}
+				})
+			})
+
+			t.Run("suite=partitions", func(t *testing.T) {
+				newIdentifier := func(partition string) *identity.CredentialIdentifier {
+					return &identity.CredentialIdentifier{
+						ID:                    x.NewUUID(),
+						Identifier:            "partitioned@ory.sh",
+						IdentityCredentialsID: x.ParseUUID("35b60ecf-30f9-42d6-bf5d-47ad41148691"),
+						PartitionID:           partition,
+					}
+				}
+
+				t.Run("case=duplicate identifiers in different partitions are allowed", func(t *testing.T) {
+					require.NoError(t, c.Create(newIdentifier("partition-a")))
+					require.NoError(t, c.Create(newIdentifier("partition-b")))
+				})
+
+				t.Run("case=duplicate identifiers in the same partition are rejected", func(t *testing.T) {
+					err := sqlcon.HandleError(c.Create(newIdentifier("partition-a")))
+					require.Error(t, err)
+					assert.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+				})
 			})
 
 			t.Run("suite=constraints", func(t *testing.T) {
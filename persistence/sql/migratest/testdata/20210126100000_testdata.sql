@@ -0,0 +1,5 @@
+INSERT INTO identity_recovery_tokens (id, token, used, used_at, identity_recovery_address_id, selfservice_recovery_flow_id, created_at, updated_at, expires_at, issued_at)
+VALUES ('5f0e22b8-6f24-4ae6-9e8a-0d3f2a3a6c51', '6b3c4d1b6f1f4f0e92e1c7f3b7d1a1c0e4a9d2b7c5e8f3a1d6b9c2e5f8a1b4c7', false, null, 'b8293f1c-010f-45d9-b809-f3fc5365ba80', '13178936-095a-466b-abe0-36d977d3dc18', '2013-10-07 08:23:19', '2013-10-07 08:23:19', '2013-10-07 08:23:19', '2013-10-07 08:23:19');
+
+INSERT INTO identity_verification_tokens (id, token, used, used_at, identity_verifiable_address_id, selfservice_verification_flow_id, created_at, updated_at, expires_at, issued_at)
+VALUES ('8c2f6f1e-3b1d-4c0a-a6e2-5d8b9f7e1a23', '0e4a9d2b7c5e8f3a1d6b9c2e5f8a1b4c76b3c4d1b6f1f4f0e92e1c7f3b7d1a1c', false, null, '45e867e9-2745-4f16-8dd4-84334a252b61', '5385c962-0295-4575-9b1b-d7eef13c0eda', '2013-10-07 08:23:19', '2013-10-07 08:23:19', '2013-10-07 08:23:19', '2013-10-07 08:23:19');
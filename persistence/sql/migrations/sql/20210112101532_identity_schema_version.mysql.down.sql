@@ -0,0 +1 @@
+ALTER TABLE `identities` DROP COLUMN `schema_version`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "schema_version" VARCHAR (255) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "schema_version" TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "admin_api_keys";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE `admin_api_keys` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`name` VARCHAR (255) NOT NULL,
+`key_hash` VARCHAR (64) NOT NULL,
+`scopes` VARCHAR (1024) NOT NULL,
+`expires_at` DATETIME NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `admin_api_keys_key_hash_uq_idx` ON `admin_api_keys` (`key_hash`);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "admin_api_keys";
\ No newline at end of file
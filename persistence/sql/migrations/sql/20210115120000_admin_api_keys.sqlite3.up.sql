@@ -0,0 +1,10 @@
+CREATE TABLE "admin_api_keys" (
+"id" TEXT PRIMARY KEY,
+"name" TEXT NOT NULL,
+"key_hash" TEXT NOT NULL,
+"scopes" TEXT NOT NULL,
+"expires_at" DATETIME NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX "admin_api_keys_key_hash_uq_idx" ON "admin_api_keys" (key_hash);
\ No newline at end of file
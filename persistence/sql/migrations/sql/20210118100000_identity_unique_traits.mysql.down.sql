@@ -0,0 +1 @@
+DROP TABLE `identity_unique_traits`;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE `identity_unique_traits` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`trait_key` VARCHAR (64) NOT NULL,
+`value` VARCHAR (255) NOT NULL,
+`identity_id` char(36) NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL,
+FOREIGN KEY (`identity_id`) REFERENCES `identities` (`id`) ON DELETE cascade
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `identity_unique_traits_key_value_uq_idx` ON `identity_unique_traits` (`trait_key`, `value`);
+CREATE INDEX `identity_unique_traits_identity_id_idx` ON `identity_unique_traits` (`identity_id`);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE "identity_unique_traits" (
+"id" TEXT PRIMARY KEY,
+"trait_key" TEXT NOT NULL,
+"value" TEXT NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+CREATE UNIQUE INDEX "identity_unique_traits_key_value_uq_idx" ON "identity_unique_traits" (trait_key, value);
+CREATE INDEX "identity_unique_traits_identity_id_idx" ON "identity_unique_traits" (identity_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "delete_after";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "delete_after";
\ No newline at end of file
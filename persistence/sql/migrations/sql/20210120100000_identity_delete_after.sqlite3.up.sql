@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "delete_after" DATETIME;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "recovery_disabled";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
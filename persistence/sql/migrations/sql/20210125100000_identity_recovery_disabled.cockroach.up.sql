@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "recovery_disabled" bool NOT NULL DEFAULT false;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "recovery_disabled";
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"schema_version" TEXT NOT NULL DEFAULT '',
+"delete_after" DATETIME
+);
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, schema_version, delete_after) SELECT id, schema_id, traits, created_at, updated_at, schema_version, delete_after FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
\ No newline at end of file
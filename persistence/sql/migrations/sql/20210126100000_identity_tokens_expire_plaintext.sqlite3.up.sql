@@ -0,0 +1,2 @@
+DELETE FROM identity_recovery_tokens WHERE LENGTH(token) <> 64;
+DELETE FROM identity_verification_tokens WHERE LENGTH(token) <> 64;
\ No newline at end of file
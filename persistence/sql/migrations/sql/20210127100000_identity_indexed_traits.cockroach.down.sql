@@ -0,0 +1 @@
+DROP TABLE "identity_indexed_traits";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "identity_indexed_traits" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"trait_key" VARCHAR (64) NOT NULL,
+"value" VARCHAR (255) NOT NULL,
+"identity_id" UUID NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "identity_indexed_traits_identities_id_fk" FOREIGN KEY ("identity_id") REFERENCES "identities" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_indexed_traits_key_value_idx" ON "identity_indexed_traits" (trait_key, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_indexed_traits_identity_id_idx" ON "identity_indexed_traits" (identity_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE `identity_indexed_traits` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`trait_key` VARCHAR (64) NOT NULL,
+`value` VARCHAR (255) NOT NULL,
+`identity_id` char(36) NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL,
+FOREIGN KEY (`identity_id`) REFERENCES `identities` (`id`) ON DELETE cascade
+) ENGINE=InnoDB;
+CREATE INDEX `identity_indexed_traits_key_value_idx` ON `identity_indexed_traits` (`trait_key`, `value`);
+CREATE INDEX `identity_indexed_traits_identity_id_idx` ON `identity_indexed_traits` (`identity_id`);
\ No newline at end of file
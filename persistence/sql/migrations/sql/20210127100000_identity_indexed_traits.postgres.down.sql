@@ -0,0 +1 @@
+DROP TABLE "identity_indexed_traits";
\ No newline at end of file
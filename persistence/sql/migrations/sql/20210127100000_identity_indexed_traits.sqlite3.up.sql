@@ -0,0 +1,11 @@
+CREATE TABLE "identity_indexed_traits" (
+"id" TEXT PRIMARY KEY,
+"trait_key" TEXT NOT NULL,
+"value" TEXT NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+CREATE INDEX "identity_indexed_traits_key_value_idx" ON "identity_indexed_traits" (trait_key, value);
+CREATE INDEX "identity_indexed_traits_identity_id_idx" ON "identity_indexed_traits" (identity_id);
\ No newline at end of file
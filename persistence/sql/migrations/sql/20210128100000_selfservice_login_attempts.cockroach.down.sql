@@ -0,0 +1 @@
+DROP TABLE "selfservice_login_attempts";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
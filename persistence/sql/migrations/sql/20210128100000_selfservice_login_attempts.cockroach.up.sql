@@ -0,0 +1,9 @@
+CREATE TABLE "selfservice_login_attempts" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identifier_hash" VARCHAR (64) NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "selfservice_login_attempts_identifier_hash_idx" ON "selfservice_login_attempts" (identifier_hash, created_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "selfservice_login_attempts_created_at_idx" ON "selfservice_login_attempts" (created_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
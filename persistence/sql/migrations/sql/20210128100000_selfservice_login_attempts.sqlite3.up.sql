@@ -0,0 +1,8 @@
+CREATE TABLE "selfservice_login_attempts" (
+"id" TEXT PRIMARY KEY,
+"identifier_hash" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE INDEX "selfservice_login_attempts_identifier_hash_idx" ON "selfservice_login_attempts" (identifier_hash, created_at);
+CREATE INDEX "selfservice_login_attempts_created_at_idx" ON "selfservice_login_attempts" (created_at);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE "identity_credential_identifier_history" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identifier" VARCHAR (255) NOT NULL,
+"identity_id" UUID NOT NULL,
+"identity_credential_type_id" UUID NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "identity_credential_identifier_history_identities_id_fk" FOREIGN KEY ("identity_id") REFERENCES "identities" ("id") ON DELETE cascade,
+CONSTRAINT "identity_credential_identifier_history_identity_credential_types_id_fk" FOREIGN KEY ("identity_credential_type_id") REFERENCES "identity_credential_types" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_credential_identifier_history_identifier_idx" ON "identity_credential_identifier_history" (identifier);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_credential_identifier_history_identity_id_idx" ON "identity_credential_identifier_history" (identity_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE `identity_credential_identifier_history`;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "identity_credential_identifier_history" (
+"id" TEXT PRIMARY KEY,
+"identifier" TEXT NOT NULL,
+"identity_id" char(36) NOT NULL,
+"identity_credential_type_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade,
+FOREIGN KEY (identity_credential_type_id) REFERENCES identity_credential_types (id) ON DELETE cascade
+);
+CREATE INDEX "identity_credential_identifier_history_identifier_idx" ON "identity_credential_identifier_history" (identifier);
+CREATE INDEX "identity_credential_identifier_history_identity_id_idx" ON "identity_credential_identifier_history" (identity_id);
\ No newline at end of file
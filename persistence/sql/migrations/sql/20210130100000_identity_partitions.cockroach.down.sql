@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS "identity_credential_identifiers"@"identity_credential_identifiers_identifier_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_credential_identifiers_identifier_idx" ON "identity_credential_identifiers" (identifier);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_verifiable_addresses"@"identity_verifiable_addresses_status_via_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_verifiable_addresses_status_via_uq_idx" ON "identity_verifiable_addresses" (via, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_recovery_addresses"@"identity_recovery_addresses_status_via_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_recovery_addresses_status_via_uq_idx" ON "identity_recovery_addresses" (via, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_unique_traits"@"identity_unique_traits_key_value_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_unique_traits_key_value_uq_idx" ON "identity_unique_traits" (trait_key, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identities"@"identities_partition_id_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "partition_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_credential_identifiers" DROP COLUMN "partition_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_verifiable_addresses" DROP COLUMN "partition_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_recovery_addresses" DROP COLUMN "partition_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_unique_traits" DROP COLUMN "partition_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
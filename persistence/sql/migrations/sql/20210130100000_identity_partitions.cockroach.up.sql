@@ -0,0 +1,14 @@
+ALTER TABLE "identities" ADD COLUMN "partition_id" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_credential_identifiers" ADD COLUMN "partition_id" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_verifiable_addresses" ADD COLUMN "partition_id" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_recovery_addresses" ADD COLUMN "partition_id" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identity_unique_traits" ADD COLUMN "partition_id" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identities_partition_id_idx" ON "identities" (partition_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_credential_identifiers"@"identity_credential_identifiers_identifier_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_credential_identifiers_identifier_idx" ON "identity_credential_identifiers" (partition_id, identifier);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_verifiable_addresses"@"identity_verifiable_addresses_status_via_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_verifiable_addresses_status_via_uq_idx" ON "identity_verifiable_addresses" (partition_id, via, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_recovery_addresses"@"identity_recovery_addresses_status_via_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_recovery_addresses_status_via_uq_idx" ON "identity_recovery_addresses" (partition_id, via, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
+DROP INDEX IF EXISTS "identity_unique_traits"@"identity_unique_traits_key_value_uq_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_unique_traits_key_value_uq_idx" ON "identity_unique_traits" (partition_id, trait_key, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
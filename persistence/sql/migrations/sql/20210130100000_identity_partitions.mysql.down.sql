@@ -0,0 +1,14 @@
+DROP INDEX `identity_credential_identifiers_identifier_idx` ON `identity_credential_identifiers`;
+CREATE UNIQUE INDEX `identity_credential_identifiers_identifier_idx` ON `identity_credential_identifiers` (`identifier`);
+DROP INDEX `identity_verifiable_addresses_status_via_uq_idx` ON `identity_verifiable_addresses`;
+CREATE UNIQUE INDEX `identity_verifiable_addresses_status_via_uq_idx` ON `identity_verifiable_addresses` (`via`, `value`);
+DROP INDEX `identity_recovery_addresses_status_via_uq_idx` ON `identity_recovery_addresses`;
+CREATE UNIQUE INDEX `identity_recovery_addresses_status_via_uq_idx` ON `identity_recovery_addresses` (`via`, `value`);
+DROP INDEX `identity_unique_traits_key_value_uq_idx` ON `identity_unique_traits`;
+CREATE UNIQUE INDEX `identity_unique_traits_key_value_uq_idx` ON `identity_unique_traits` (`trait_key`, `value`);
+DROP INDEX `identities_partition_id_idx` ON `identities`;
+ALTER TABLE `identities` DROP COLUMN `partition_id`;
+ALTER TABLE `identity_credential_identifiers` DROP COLUMN `partition_id`;
+ALTER TABLE `identity_verifiable_addresses` DROP COLUMN `partition_id`;
+ALTER TABLE `identity_recovery_addresses` DROP COLUMN `partition_id`;
+ALTER TABLE `identity_unique_traits` DROP COLUMN `partition_id`;
\ No newline at end of file
@@ -0,0 +1,72 @@
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+, "schema_version" TEXT NOT NULL DEFAULT '', "delete_after" DATETIME, "recovery_disabled" bool NOT NULL DEFAULT false);
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled) SELECT id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
+CREATE TABLE "_identity_credential_identifiers_tmp" (
+"id" TEXT PRIMARY KEY,
+"identifier" TEXT NOT NULL,
+"identity_credential_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_credential_id) REFERENCES identity_credentials (id) ON DELETE cascade
+);
+INSERT INTO "_identity_credential_identifiers_tmp" (id, identifier, identity_credential_id, created_at, updated_at) SELECT id, identifier, identity_credential_id, created_at, updated_at FROM "identity_credential_identifiers";
+
+DROP TABLE "identity_credential_identifiers";
+ALTER TABLE "_identity_credential_identifiers_tmp" RENAME TO "identity_credential_identifiers";
+CREATE UNIQUE INDEX "identity_credential_identifiers_identifier_idx" ON "identity_credential_identifiers" (identifier);
+CREATE TABLE "_identity_verifiable_addresses_tmp" (
+"id" TEXT PRIMARY KEY,
+"status" TEXT NOT NULL,
+"via" TEXT NOT NULL,
+"verified" bool NOT NULL,
+"value" TEXT NOT NULL,
+"verified_at" DATETIME,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+INSERT INTO "_identity_verifiable_addresses_tmp" (id, status, via, verified, value, verified_at, identity_id, created_at, updated_at) SELECT id, status, via, verified, value, verified_at, identity_id, created_at, updated_at FROM "identity_verifiable_addresses";
+
+DROP TABLE "identity_verifiable_addresses";
+ALTER TABLE "_identity_verifiable_addresses_tmp" RENAME TO "identity_verifiable_addresses";
+CREATE INDEX "identity_verifiable_addresses_status_via_idx" ON "identity_verifiable_addresses" (via, value);
+CREATE UNIQUE INDEX "identity_verifiable_addresses_status_via_uq_idx" ON "identity_verifiable_addresses" (via, value);
+CREATE TABLE "_identity_recovery_addresses_tmp" (
+"id" TEXT PRIMARY KEY,
+"via" TEXT NOT NULL,
+"value" TEXT NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+INSERT INTO "_identity_recovery_addresses_tmp" (id, via, value, identity_id, created_at, updated_at) SELECT id, via, value, identity_id, created_at, updated_at FROM "identity_recovery_addresses";
+
+DROP TABLE "identity_recovery_addresses";
+ALTER TABLE "_identity_recovery_addresses_tmp" RENAME TO "identity_recovery_addresses";
+CREATE UNIQUE INDEX "identity_recovery_addresses_status_via_uq_idx" ON "identity_recovery_addresses" (via, value);
+CREATE INDEX "identity_recovery_addresses_status_via_idx" ON "identity_recovery_addresses" (via, value);
+CREATE TABLE "_identity_unique_traits_tmp" (
+"id" TEXT PRIMARY KEY,
+"trait_key" TEXT NOT NULL,
+"value" TEXT NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+INSERT INTO "_identity_unique_traits_tmp" (id, trait_key, value, identity_id, created_at, updated_at) SELECT id, trait_key, value, identity_id, created_at, updated_at FROM "identity_unique_traits";
+
+DROP TABLE "identity_unique_traits";
+ALTER TABLE "_identity_unique_traits_tmp" RENAME TO "identity_unique_traits";
+CREATE UNIQUE INDEX "identity_unique_traits_key_value_uq_idx" ON "identity_unique_traits" (trait_key, value);
+CREATE INDEX "identity_unique_traits_identity_id_idx" ON "identity_unique_traits" (identity_id);
\ No newline at end of file
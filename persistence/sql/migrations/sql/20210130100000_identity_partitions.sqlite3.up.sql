@@ -0,0 +1,14 @@
+ALTER TABLE "identities" ADD COLUMN "partition_id" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "identity_credential_identifiers" ADD COLUMN "partition_id" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "identity_verifiable_addresses" ADD COLUMN "partition_id" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "identity_recovery_addresses" ADD COLUMN "partition_id" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "identity_unique_traits" ADD COLUMN "partition_id" TEXT NOT NULL DEFAULT '';
+CREATE INDEX "identities_partition_id_idx" ON "identities" (partition_id);
+DROP INDEX IF EXISTS "identity_credential_identifiers_identifier_idx";
+CREATE UNIQUE INDEX "identity_credential_identifiers_identifier_idx" ON "identity_credential_identifiers" (partition_id, identifier);
+DROP INDEX IF EXISTS "identity_verifiable_addresses_status_via_uq_idx";
+CREATE UNIQUE INDEX "identity_verifiable_addresses_status_via_uq_idx" ON "identity_verifiable_addresses" (partition_id, via, value);
+DROP INDEX IF EXISTS "identity_recovery_addresses_status_via_uq_idx";
+CREATE UNIQUE INDEX "identity_recovery_addresses_status_via_uq_idx" ON "identity_recovery_addresses" (partition_id, via, value);
+DROP INDEX IF EXISTS "identity_unique_traits_key_value_uq_idx";
+CREATE UNIQUE INDEX "identity_unique_traits_key_value_uq_idx" ON "identity_unique_traits" (partition_id, trait_key, value);
\ No newline at end of file
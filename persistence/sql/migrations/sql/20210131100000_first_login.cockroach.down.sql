@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" DROP COLUMN "first_login";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "first_login_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "login_count";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" DROP COLUMN "first_login";
+ALTER TABLE "identities" DROP COLUMN "first_login_at";
+ALTER TABLE "identities" DROP COLUMN "login_count";
\ No newline at end of file
@@ -0,0 +1,29 @@
+CREATE TABLE "_sessions_tmp" (
+"id" TEXT PRIMARY KEY,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"authenticated_at" DATETIME NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"token" TEXT, "active" NUMERIC DEFAULT 'false',
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+INSERT INTO "_sessions_tmp" (id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active) SELECT id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active FROM "sessions";
+
+DROP TABLE "sessions";
+ALTER TABLE "_sessions_tmp" RENAME TO "sessions";
+CREATE UNIQUE INDEX "sessions_token_uq_idx" ON "sessions" (token);
+CREATE INDEX "sessions_token_idx" ON "sessions" (token);
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+, "schema_version" TEXT NOT NULL DEFAULT '', "delete_after" DATETIME, "recovery_disabled" bool NOT NULL DEFAULT false, "partition_id" TEXT NOT NULL DEFAULT '');
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled, partition_id) SELECT id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled, partition_id FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
+CREATE INDEX "identities_partition_id_idx" ON "identities" (partition_id);
\ No newline at end of file
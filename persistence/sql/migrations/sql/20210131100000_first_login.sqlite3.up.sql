@@ -0,0 +1,3 @@
+ALTER TABLE "identities" ADD COLUMN "login_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "identities" ADD COLUMN "first_login_at" DATETIME;
+ALTER TABLE "sessions" ADD COLUMN "first_login" bool NOT NULL DEFAULT false;
\ No newline at end of file
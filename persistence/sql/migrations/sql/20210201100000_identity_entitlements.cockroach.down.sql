@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "entitlements";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "entitlements" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `identities` DROP COLUMN `entitlements`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `identities` ADD COLUMN `entitlements` JSON;
\ No newline at end of file
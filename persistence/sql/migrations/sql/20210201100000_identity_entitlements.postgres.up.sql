@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "entitlements" jsonb;
\ No newline at end of file
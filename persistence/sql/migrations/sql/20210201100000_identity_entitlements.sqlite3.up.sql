@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "entitlements" TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "courier_messages" DROP COLUMN "template_type";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
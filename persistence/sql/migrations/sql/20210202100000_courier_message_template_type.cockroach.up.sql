@@ -0,0 +1 @@
+ALTER TABLE "courier_messages" ADD COLUMN "template_type" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `courier_messages` DROP COLUMN `template_type`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "sessions" ADD COLUMN "impersonator" VARCHAR (255) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `sessions` DROP COLUMN `impersonator`;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "sessions" ADD COLUMN "impersonator" TEXT NOT NULL DEFAULT '';
\ No newline at end of file
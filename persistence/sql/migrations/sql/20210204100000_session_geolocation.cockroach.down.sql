@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" DROP COLUMN "region";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "country";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
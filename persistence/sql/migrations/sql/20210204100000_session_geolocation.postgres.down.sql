@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" DROP COLUMN "region";
+ALTER TABLE "sessions" DROP COLUMN "country";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" ADD COLUMN "country" VARCHAR (2) NOT NULL DEFAULT '';
+ALTER TABLE "sessions" ADD COLUMN "region" VARCHAR (3) NOT NULL DEFAULT '';
\ No newline at end of file
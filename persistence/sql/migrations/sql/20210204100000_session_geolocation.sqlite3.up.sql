@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" ADD COLUMN "country" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "sessions" ADD COLUMN "region" TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE "selfservice_async_hook_jobs" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"partition_id" VARCHAR (64) NOT NULL DEFAULT '',
+"hook" VARCHAR (255) NOT NULL,
+"config" json,
+"flow_type" VARCHAR (32) NOT NULL,
+"flow_id" UUID NOT NULL,
+"credentials_type" VARCHAR (32) NOT NULL,
+"payload" json NOT NULL,
+"status" VARCHAR (16) NOT NULL,
+"attempts" int NOT NULL,
+"next_attempt_at" timestamp NOT NULL,
+"last_error" text NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "selfservice_async_hook_jobs_status_next_attempt_at_idx" ON "selfservice_async_hook_jobs" (status, next_attempt_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE `selfservice_async_hook_jobs`;
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE `selfservice_async_hook_jobs` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`partition_id` VARCHAR (64) NOT NULL DEFAULT '',
+`hook` VARCHAR (255) NOT NULL,
+`config` JSON,
+`flow_type` VARCHAR (32) NOT NULL,
+`flow_id` char(36) NOT NULL,
+`credentials_type` VARCHAR (32) NOT NULL,
+`payload` JSON NOT NULL,
+`status` VARCHAR (16) NOT NULL,
+`attempts` INTEGER NOT NULL,
+`next_attempt_at` DATETIME NOT NULL,
+`last_error` text NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE INDEX `selfservice_async_hook_jobs_status_next_attempt_at_idx` ON `selfservice_async_hook_jobs` (`status`, `next_attempt_at`);
\ No newline at end of file
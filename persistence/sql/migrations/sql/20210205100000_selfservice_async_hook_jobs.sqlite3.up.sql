@@ -0,0 +1,17 @@
+CREATE TABLE "selfservice_async_hook_jobs" (
+"id" TEXT PRIMARY KEY,
+"partition_id" TEXT NOT NULL DEFAULT '',
+"hook" TEXT NOT NULL,
+"config" TEXT,
+"flow_type" TEXT NOT NULL,
+"flow_id" char(36) NOT NULL,
+"credentials_type" TEXT NOT NULL,
+"payload" TEXT NOT NULL,
+"status" TEXT NOT NULL,
+"attempts" INTEGER NOT NULL,
+"next_attempt_at" DATETIME NOT NULL,
+"last_error" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE INDEX "selfservice_async_hook_jobs_status_next_attempt_at_idx" ON "selfservice_async_hook_jobs" (status, next_attempt_at);
\ No newline at end of file
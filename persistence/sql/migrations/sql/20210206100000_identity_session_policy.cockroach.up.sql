@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "session_policy" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "session_policy";
\ No newline at end of file
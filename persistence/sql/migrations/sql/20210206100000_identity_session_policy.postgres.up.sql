@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "session_policy" jsonb;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+, "schema_version" TEXT NOT NULL DEFAULT '', "delete_after" DATETIME, "recovery_disabled" bool NOT NULL DEFAULT false, "partition_id" TEXT NOT NULL DEFAULT '', "login_count" INTEGER NOT NULL DEFAULT 0, "first_login_at" DATETIME, "entitlements" TEXT);
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled, partition_id, login_count, first_login_at, entitlements) SELECT id, schema_id, traits, created_at, updated_at, schema_version, delete_after, recovery_disabled, partition_id, login_count, first_login_at, entitlements FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
+CREATE INDEX "identities_partition_id_idx" ON "identities" (partition_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "session_policy" TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "email_changed_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
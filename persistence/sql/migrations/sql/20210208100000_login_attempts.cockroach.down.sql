@@ -0,0 +1 @@
+DROP TABLE "login_attempts";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE "login_attempts" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"partition_id" VARCHAR (64) NOT NULL DEFAULT '',
+"identity_id" UUID,
+"identifier" VARCHAR (255) NOT NULL,
+"method" VARCHAR (32) NOT NULL,
+"outcome" VARCHAR (16) NOT NULL,
+"reason" VARCHAR (255) NOT NULL,
+"ip_address" VARCHAR (64) NOT NULL,
+"user_agent" text NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "login_attempts_partition_id_created_at_idx" ON "login_attempts" (partition_id, created_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "login_attempts_identity_id_created_at_idx" ON "login_attempts" (identity_id, created_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE "login_attempts" (
+"id" TEXT PRIMARY KEY,
+"partition_id" TEXT NOT NULL DEFAULT '',
+"identity_id" char(36),
+"identifier" TEXT NOT NULL,
+"method" TEXT NOT NULL,
+"outcome" TEXT NOT NULL,
+"reason" TEXT NOT NULL,
+"ip_address" TEXT NOT NULL,
+"user_agent" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE INDEX "login_attempts_partition_id_created_at_idx" ON "login_attempts" (partition_id, created_at);
+CREATE INDEX "login_attempts_identity_id_created_at_idx" ON "login_attempts" (identity_id, created_at);
\ No newline at end of file
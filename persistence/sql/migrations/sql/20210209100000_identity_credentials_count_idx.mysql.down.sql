@@ -0,0 +1,2 @@
+DROP INDEX `identity_credentials_type_id_identity_id_idx` ON `identity_credentials`;
+DROP INDEX `identity_credentials_identity_id_type_id_idx` ON `identity_credentials`;
\ No newline at end of file
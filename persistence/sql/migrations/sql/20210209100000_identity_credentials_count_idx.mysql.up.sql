@@ -0,0 +1,2 @@
+CREATE INDEX `identity_credentials_identity_id_type_id_idx` ON `identity_credentials` (`identity_id`, `identity_credential_type_id`);
+CREATE INDEX `identity_credentials_type_id_identity_id_idx` ON `identity_credentials` (`identity_credential_type_id`, `identity_id`);
\ No newline at end of file
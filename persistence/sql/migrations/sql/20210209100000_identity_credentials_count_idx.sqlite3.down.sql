@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "identity_credentials_type_id_identity_id_idx";
+DROP INDEX IF EXISTS "identity_credentials_identity_id_type_id_idx";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "selfservice_settings_flows" DROP COLUMN "csrf_token";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
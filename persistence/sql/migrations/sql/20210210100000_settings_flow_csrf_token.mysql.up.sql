@@ -0,0 +1 @@
+ALTER TABLE `selfservice_settings_flows` ADD COLUMN `csrf_token` VARCHAR (255) NOT NULL DEFAULT '';
\ No newline at end of file
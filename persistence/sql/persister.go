@@ -2,9 +2,11 @@ package sql
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/sqlcon"
 
 	"github.com/gobuffalo/pop/v5"
 	"github.com/markbates/pkger"
@@ -53,6 +55,38 @@ func (p *Persister) MigrationStatus(ctx context.Context, w io.Writer) error {
 	return errors.WithStack(p.mb.Status(w))
 }
 
+// PendingMigrations returns the number of migrations for the database dialect which were not applied yet.
+func (p *Persister) PendingMigrations(ctx context.Context) (int, error) {
+	if err := p.mb.CreateSchemaMigrations(); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	c := p.Connection(ctx)
+	var rows []struct {
+		Version string `db:"version"`
+	}
+	if err := c.RawQuery(fmt.Sprintf("SELECT version FROM %s", c.MigrationTableName())).All(&rows); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	applied := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = true
+	}
+
+	var pending int
+	for _, m := range p.mb.Migrations["up"] {
+		if m.DBType != "all" && m.DBType != c.Dialect.Name() {
+			continue
+		}
+		if !applied[m.Version] {
+			applied[m.Version] = true
+			pending++
+		}
+	}
+	return pending, nil
+}
+
 func (p *Persister) MigrateDown(ctx context.Context, steps int) error {
 	return errors.WithStack(p.mb.Down(steps))
 }
@@ -0,0 +1,86 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/apikey"
+)
+
+var _ apikey.Persister = new(Persister)
+
+func (p *Persister) CreateAPIKey(ctx context.Context, key *apikey.APIKey) error {
+	k := key.Key
+	key.Key = p.hmacValue(ctx, k)
+
+	if err := p.GetConnection(ctx).Create(key); err != nil {
+		key.Key = k
+		return sqlcon.HandleError(err)
+	}
+
+	key.Key = k
+	return nil
+}
+
+func (p *Persister) GetAPIKey(ctx context.Context, id uuid.UUID) (*apikey.APIKey, error) {
+	var k apikey.APIKey
+	if err := p.GetConnection(ctx).Find(&k, id); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	k.Key = ""
+	return &k, nil
+}
+
+func (p *Persister) GetAPIKeyByKey(ctx context.Context, key string) (*apikey.APIKey, error) {
+	var k apikey.APIKey
+	if err := sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) (err error) {
+		for _, secret := range p.r.Configuration(ctx).SecretsSession() {
+			if err = tx.Where("key_hash = ?", p.hmacValueWithSecret(key, secret)).First(&k); err != nil {
+				if !errors.Is(sqlcon.HandleError(err), sqlcon.ErrNoRows) {
+					return err
+				}
+			} else {
+				break
+			}
+		}
+		return err
+	})); err != nil {
+		return nil, err
+	}
+
+	k.Key = ""
+	return &k, nil
+}
+
+func (p *Persister) ListAPIKeys(ctx context.Context) ([]apikey.APIKey, error) {
+	keys := make([]apikey.APIKey, 0)
+	if err := p.GetConnection(ctx).Order("created_at ASC").All(&keys); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	for k := range keys {
+		keys[k].Key = ""
+	}
+	return keys, nil
+}
+
+func (p *Persister) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ?", new(apikey.APIKey).TableName(ctx)), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/x"
+)
+
+var _ hook.AsyncJobPersister = new(Persister)
+
+func (p *Persister) CreateAsyncJob(ctx context.Context, j *hook.AsyncJob) error {
+	j.PartitionID = x.PartitionID(ctx)
+	j.Status = hook.AsyncJobStatusQueued
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(j))
+}
+
+func (p *Persister) ListDueAsyncJobs(ctx context.Context, now time.Time, limit int) ([]hook.AsyncJob, error) {
+	var jobs []hook.AsyncJob
+	if err := p.GetConnection(ctx).
+		Where("status = ? AND next_attempt_at <= ?", hook.AsyncJobStatusQueued, now.UTC()).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		All(&jobs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return jobs, nil
+}
+
+func (p *Persister) ClaimAsyncJob(ctx context.Context, j *hook.AsyncJob, leaseUntil time.Time) (bool, error) {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ? WHERE id = ? AND attempts = ? AND status = ?",
+		j.TableName(ctx)),
+		leaseUntil.UTC(), time.Now().UTC(), j.ID, j.Attempts, hook.AsyncJobStatusQueued).ExecWithCount()
+	if err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+
+	j.Attempts++
+	j.NextAttemptAt = leaseUntil.UTC()
+	return true, nil
+}
+
+func (p *Persister) UpdateAsyncJob(ctx context.Context, j *hook.AsyncJob) error {
+	// The payload contains the session or identity and is no longer needed once the job will not be executed again.
+	if j.Status != hook.AsyncJobStatusQueued {
+		j.Payload = sqlxx.JSONRawMessage("{}")
+	}
+
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET status = ?, next_attempt_at = ?, last_error = ?, payload = ?, updated_at = ? WHERE id = ?",
+		j.TableName(ctx)),
+		j.Status, j.NextAttemptAt.UTC(), j.LastError, j.Payload, time.Now().UTC(), j.ID).Exec())
+}
@@ -53,6 +53,17 @@ func (p *Persister) LatestQueuedMessage(ctx context.Context) (*courier.Message,
 	return &m, nil
 }
 
+func (p *Persister) CountQueuedMessages(ctx context.Context) (int, error) {
+	count, err := p.GetConnection(ctx).
+		Where("status != ?", courier.MessageStatusSent).
+		Count(new(courier.Message))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	return count, nil
+}
+
 func (p *Persister) SetMessageStatus(ctx context.Context, id uuid.UUID, ms courier.MessageStatus) error {
 	count, err := p.GetConnection(ctx).RawQuery("UPDATE courier_messages SET status = ? WHERE id = ?", ms, id).ExecWithCount()
 	if err != nil {
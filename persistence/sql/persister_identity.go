@@ -23,13 +23,20 @@ import (
 	"github.com/ory/x/sqlcon"
 
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/session"
 )
 
 var _ identity.Pool = new(Persister)
 var _ identity.PrivilegedPool = new(Persister)
 
+// partitioned returns a query which is restricted to the identity partition of the context.
+func (p *Persister) partitioned(ctx context.Context) *pop.Query {
+	return p.GetConnection(ctx).Where("partition_id = ?", x.PartitionID(ctx))
+}
+
 func (p *Persister) ListVerifiableAddresses(ctx context.Context, page, itemsPerPage int) (a []identity.VerifiableAddress, err error) {
-	if err := p.GetConnection(ctx).Order("id desc").Paginate(page, x.MaxItemsPerPage(itemsPerPage)).All(&a); err != nil {
+	if err := p.partitioned(ctx).Order("id desc").Paginate(page, x.MaxItemsPerPage(itemsPerPage)).All(&a); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -37,7 +44,7 @@ func (p *Persister) ListVerifiableAddresses(ctx context.Context, page, itemsPerP
 }
 
 func (p *Persister) ListRecoveryAddresses(ctx context.Context, page, itemsPerPage int) (a []identity.RecoveryAddress, err error) {
-	if err := p.GetConnection(ctx).Order("id desc").Paginate(page, x.MaxItemsPerPage(itemsPerPage)).All(&a); err != nil {
+	if err := p.partitioned(ctx).Order("id desc").Paginate(page, x.MaxItemsPerPage(itemsPerPage)).All(&a); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -65,7 +72,8 @@ FROM identity_credentials ic
          INNER JOIN identity_credential_types ict on ic.identity_credential_type_id = ict.id
          INNER JOIN identity_credential_identifiers ici on ic.id = ici.identity_credential_id
 WHERE ici.identifier = ?
-  AND ict.name = ?`, match, ct).First(&find); err != nil {
+  AND ici.partition_id = ?
+  AND ict.name = ?`, match, x.PartitionID(ctx), ct).First(&find); err != nil {
 		if errors.Cause(err) == sql.ErrNoRows {
 			return nil, nil, herodot.ErrNotFound.WithTrace(err).WithReasonf(`No identity matching credentials identifier "%s" could be found.`, match)
 		}
@@ -94,7 +102,9 @@ func (p *Persister) findIdentityCredentialsType(ctx context.Context, ct identity
 	return &m, nil
 }
 
-func (p *Persister) createIdentityCredentials(ctx context.Context, i *identity.Identity) error {
+// createIdentityCredentials stores the identity's credentials. Credentials which existed before keep their creation
+// time and, if their configuration is unchanged, their update time.
+func (p *Persister) createIdentityCredentials(ctx context.Context, i *identity.Identity, previous map[identity.CredentialsType]identity.Credentials) error {
 	c := p.GetConnection(ctx)
 
 	for k := range i.Credentials {
@@ -110,10 +120,26 @@ func (p *Persister) createIdentityCredentials(ctx context.Context, i *identity.I
 		}
 
 		cred.CredentialTypeID = ct.ID
+		cred.CreatedAt, cred.UpdatedAt = time.Time{}, time.Time{}
 		if err := c.Create(&cred); err != nil {
 			return sqlcon.HandleError(err)
 		}
 
+		// Pop always sets the timestamps, so we restore them for credentials which existed before. The update
+		// timestamp is only restored if the credentials' configuration did not change.
+		if o, ok := previous[cred.Type]; ok {
+			updatedAt := cred.UpdatedAt
+			if string(cred.Config) == string(o.Config) {
+				updatedAt = o.UpdatedAt
+			}
+
+			/* #nosec G201 TableName is static */
+			if err := c.RawQuery(fmt.Sprintf("UPDATE %s SET created_at = ?, updated_at = ? WHERE id = ?", cred.TableName(ctx)), o.CreatedAt, updatedAt, cred.ID).Exec(); err != nil {
+				return sqlcon.HandleError(err)
+			}
+			cred.CreatedAt, cred.UpdatedAt = o.CreatedAt, updatedAt
+		}
+
 		for _, ids := range cred.Identifiers {
 			// Force case-insensitivity for identifiers
 			if cred.Type == identity.CredentialsTypePassword {
@@ -127,6 +153,7 @@ func (p *Persister) createIdentityCredentials(ctx context.Context, i *identity.I
 			ci := &identity.CredentialIdentifier{
 				Identifier:            ids,
 				IdentityCredentialsID: cred.ID,
+				PartitionID:           i.PartitionID,
 			}
 			if err := c.Create(ci); err != nil {
 				return sqlcon.HandleError(err)
@@ -139,9 +166,63 @@ func (p *Persister) createIdentityCredentials(ctx context.Context, i *identity.I
 	return nil
 }
 
+// credentialsMetadataRow is a credentials identifier joined with its credentials as stored in the database.
+type credentialsMetadataRow struct {
+	IdentityID uuid.UUID                `db:"identity_id"`
+	Type       identity.CredentialsType `db:"name"`
+	CreatedAt  time.Time                `db:"created_at"`
+	UpdatedAt  time.Time                `db:"updated_at"`
+	Identifier sql.NullString           `db:"identifier"`
+}
+
+func (p *Persister) ListCredentialsMetadata(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]map[identity.CredentialsType]identity.CredentialsMetadata, error) {
+	result := make(map[uuid.UUID]map[identity.CredentialsType]identity.CredentialsMetadata, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for k := range ids {
+		args[k] = ids[k]
+	}
+
+	var rows []credentialsMetadataRow
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(`SELECT ic.identity_id, ict.name, ic.created_at, ic.updated_at, ici.identifier
+FROM %s ic
+         INNER JOIN %s ict ON ic.identity_credential_type_id = ict.id
+         LEFT JOIN %s ici ON ici.identity_credential_id = ic.id
+WHERE ic.identity_id IN (%s)
+ORDER BY ici.identifier`,
+		new(identity.Credentials).TableName(ctx),
+		new(identity.CredentialsTypeTable).TableName(ctx),
+		new(identity.CredentialIdentifier).TableName(ctx),
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")), args...).All(&rows); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	for _, row := range rows {
+		if _, ok := result[row.IdentityID]; !ok {
+			result[row.IdentityID] = map[identity.CredentialsType]identity.CredentialsMetadata{}
+		}
+
+		m, ok := result[row.IdentityID][row.Type]
+		if !ok {
+			m = identity.CredentialsMetadata{Type: row.Type, Identifiers: []string{}, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+		}
+		if row.Identifier.Valid {
+			m.Identifiers = append(m.Identifiers, row.Identifier.String)
+		}
+		result[row.IdentityID][row.Type] = m
+	}
+
+	return result, nil
+}
+
 func (p *Persister) createVerifiableAddresses(ctx context.Context, i *identity.Identity) error {
 	for k := range i.VerifiableAddresses {
 		i.VerifiableAddresses[k].IdentityID = i.ID
+		i.VerifiableAddresses[k].PartitionID = i.PartitionID
 		if err := p.GetConnection(ctx).Create(&i.VerifiableAddresses[k]); err != nil {
 			return err
 		}
@@ -152,6 +233,7 @@ func (p *Persister) createVerifiableAddresses(ctx context.Context, i *identity.I
 func (p *Persister) createRecoveryAddresses(ctx context.Context, i *identity.Identity) error {
 	for k := range i.RecoveryAddresses {
 		i.RecoveryAddresses[k].IdentityID = i.ID
+		i.RecoveryAddresses[k].PartitionID = i.PartitionID
 		if err := p.GetConnection(ctx).Create(&i.RecoveryAddresses[k]); err != nil {
 			return err
 		}
@@ -159,14 +241,234 @@ func (p *Persister) createRecoveryAddresses(ctx context.Context, i *identity.Ide
 	return nil
 }
 
+func (p *Persister) createUniqueTraits(ctx context.Context, i *identity.Identity) error {
+	for k := range i.UniqueTraits {
+		i.UniqueTraits[k].IdentityID = i.ID
+		i.UniqueTraits[k].PartitionID = i.PartitionID
+		if err := p.GetConnection(ctx).Create(&i.UniqueTraits[k]); err != nil {
+			if errors.Is(sqlcon.HandleError(err), sqlcon.ErrUniqueViolation) {
+				return schema.NewDuplicateTraitError(i.UniqueTraits[k].Key)
+			}
+			return sqlcon.HandleError(err)
+		}
+	}
+	return nil
+}
+
+func (p *Persister) createIndexedTraits(ctx context.Context, i *identity.Identity) error {
+	for k := range i.IndexedTraits {
+		i.IndexedTraits[k].IdentityID = i.ID
+		if err := p.GetConnection(ctx).Create(&i.IndexedTraits[k]); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+	return nil
+}
+
+// traitFilterQuery returns a query selecting the identities matching the trait filter using the indexed traits table.
+func (p *Persister) traitFilterQuery(ctx context.Context, f identity.TraitFilter) *pop.Query {
+	value := strings.ToLower(f.Value)
+	if f.Prefix {
+		/* #nosec G201 TableName is static */
+		return p.partitioned(ctx).Where(fmt.Sprintf(
+			"id IN (SELECT identity_id FROM %s WHERE trait_key = ? AND value LIKE ? ESCAPE '!')",
+			new(identity.IndexedTrait).TableName(ctx)), f.Key, escapeLike(value)+"%")
+	}
+
+	/* #nosec G201 TableName is static */
+	return p.partitioned(ctx).Where(fmt.Sprintf(
+		"id IN (SELECT identity_id FROM %s WHERE trait_key = ? AND value = ?)",
+		new(identity.IndexedTrait).TableName(ctx)), f.Key, value)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern using "!" as the escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+func (p *Persister) CountIdentitiesByTrait(ctx context.Context, f identity.TraitFilter) (int64, error) {
+	count, err := p.traitFilterQuery(ctx, f).Count(new(identity.Identity))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return int64(count), nil
+}
+
+func (p *Persister) ListIdentitiesByTrait(ctx context.Context, f identity.TraitFilter, page, perPage int) ([]identity.Identity, error) {
+	is := make([]identity.Identity, 0)
+
+	if err := sqlcon.HandleError(p.traitFilterQuery(ctx, f).Paginate(page, perPage).Order("id DESC").
+		Eager("VerifiableAddresses", "RecoveryAddresses").All(&is)); err != nil {
+		return nil, err
+	}
+
+	for i := range is {
+		if err := p.injectTraitsSchemaURL(ctx, &(is[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	return is, nil
+}
+
+func (p *Persister) credentialsIdentifierFilterQuery(ctx context.Context, f identity.CredentialsIdentifierFilter) *pop.Query {
+	/* #nosec G201 TableName is static */
+	current := fmt.Sprintf(
+		"id IN (SELECT ic.identity_id FROM %s ic INNER JOIN %s ici ON ici.identity_credential_id = ic.id WHERE ici.identifier IN (?, ?))",
+		new(identity.Credentials).TableName(ctx), new(identity.CredentialIdentifier).TableName(ctx))
+	if !f.IncludeHistory {
+		return p.partitioned(ctx).Where(current, f.Identifier, strings.ToLower(f.Identifier))
+	}
+
+	/* #nosec G201 TableName is static */
+	return p.partitioned(ctx).Where(fmt.Sprintf(
+		"(%s OR id IN (SELECT identity_id FROM %s WHERE identifier IN (?, ?) AND created_at > ?))",
+		current, new(identity.CredentialIdentifierHistory).TableName(ctx)),
+		f.Identifier, strings.ToLower(f.Identifier),
+		f.Identifier, strings.ToLower(f.Identifier), p.credentialIdentifierHistoryCutoff(ctx))
+}
+
+// credentialIdentifierHistoryCutoff returns the time before which historical identifiers are no longer retained.
+func (p *Persister) credentialIdentifierHistoryCutoff(ctx context.Context) time.Time {
+	retention := p.r.Configuration(ctx).IdentityCredentialIdentifierHistoryRetention()
+	if retention <= 0 {
+		return time.Time{}
+	}
+	return time.Now().UTC().Add(-retention)
+}
+
+func (p *Persister) CountIdentitiesByCredentialsIdentifier(ctx context.Context, f identity.CredentialsIdentifierFilter) (int64, error) {
+	count, err := p.credentialsIdentifierFilterQuery(ctx, f).Count(new(identity.Identity))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return int64(count), nil
+}
+
+func (p *Persister) ListIdentitiesByCredentialsIdentifier(ctx context.Context, f identity.CredentialsIdentifierFilter, page, perPage int) ([]identity.Identity, error) {
+	is := make([]identity.Identity, 0)
+
+	if err := sqlcon.HandleError(p.credentialsIdentifierFilterQuery(ctx, f).Paginate(page, perPage).Order("id DESC").
+		Eager("VerifiableAddresses", "RecoveryAddresses").All(&is)); err != nil {
+		return nil, err
+	}
+
+	for i := range is {
+		if err := p.injectTraitsSchemaURL(ctx, &(is[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	return is, nil
+}
+
+// credentialIdentifierRow is an identifier of an identity's credentials as stored in the database.
+type credentialIdentifierRow struct {
+	Identifier       string    `db:"identifier"`
+	CredentialTypeID uuid.UUID `db:"identity_credential_type_id"`
+}
+
+func (p *Persister) findCredentialIdentifiers(ctx context.Context, tx *pop.Connection, id uuid.UUID) ([]credentialIdentifierRow, error) {
+	var rows []credentialIdentifierRow
+	/* #nosec G201 TableName is static */
+	if err := tx.RawQuery(fmt.Sprintf(
+		"SELECT ici.identifier, ic.identity_credential_type_id FROM %s ici INNER JOIN %s ic ON ici.identity_credential_id = ic.id WHERE ic.identity_id = ?",
+		new(identity.CredentialIdentifier).TableName(ctx), new(identity.Credentials).TableName(ctx)), id).All(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// recordCredentialIdentifierHistory keeps the identifiers which were removed from the identity's credentials and
+// removes historical identifiers which are no longer retained.
+func (p *Persister) recordCredentialIdentifierHistory(ctx context.Context, tx *pop.Connection, id uuid.UUID, previous []credentialIdentifierRow) error {
+	current, err := p.findCredentialIdentifiers(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[credentialIdentifierRow]struct{}, len(current))
+	for _, c := range current {
+		kept[c] = struct{}{}
+	}
+
+	for _, c := range previous {
+		if _, ok := kept[c]; ok {
+			continue
+		}
+
+		if err := tx.Create(&identity.CredentialIdentifierHistory{
+			Identifier:       c.Identifier,
+			IdentityID:       id,
+			CredentialTypeID: c.CredentialTypeID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if cutoff := p.credentialIdentifierHistoryCutoff(ctx); !cutoff.IsZero() {
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf(
+			"DELETE FROM %s WHERE identity_id = ? AND created_at <= ?",
+			new(identity.CredentialIdentifierHistory).TableName(ctx)), id, cutoff).Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (p *Persister) CountIdentities(ctx context.Context) (int64, error) {
-	count, err := p.c.WithContext(ctx).Count(new(identity.Identity))
+	count, err := p.partitioned(ctx).Count(new(identity.Identity))
 	if err != nil {
 		return 0, sqlcon.HandleError(err)
 	}
 	return int64(count), nil
 }
 
+func (p *Persister) CountCredentials(ctx context.Context) (*identity.CredentialsCount, error) {
+	total, err := p.CountIdentities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		Name       identity.CredentialsType `db:"name"`
+		Identities int64                    `db:"identities"`
+	}
+	if err := p.GetConnection(ctx).RawQuery(`SELECT
+    ict.name, COUNT(DISTINCT ic.identity_id) AS identities
+FROM identity_credentials ic
+         INNER JOIN identity_credential_types ict on ic.identity_credential_type_id = ict.id
+         INNER JOIN identities i on ic.identity_id = i.id
+WHERE i.partition_id = ?
+GROUP BY ict.name`, x.PartitionID(ctx)).All(&counts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	var without struct {
+		Identities int64 `db:"identities"`
+	}
+	if err := p.GetConnection(ctx).RawQuery(`SELECT
+    COUNT(*) AS identities
+FROM identities i
+WHERE i.partition_id = ?
+  AND NOT EXISTS(SELECT 1 FROM identity_credentials ic WHERE ic.identity_id = i.id)`, x.PartitionID(ctx)).First(&without); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	result := &identity.CredentialsCount{
+		Identities:         total,
+		Credentials:        make(map[identity.CredentialsType]int64, len(counts)),
+		WithoutCredentials: without.Identities,
+	}
+	for _, c := range counts {
+		result.Credentials[c.Name] = c.Identities
+	}
+
+	return result, nil
+}
+
 func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) error {
 	if i.SchemaID == "" {
 		i.SchemaID = config.DefaultIdentityTraitsSchemaID
@@ -184,6 +486,7 @@ func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) er
 		return err
 	}
 
+	i.PartitionID = x.PartitionID(ctx)
 	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
 		if err := tx.Create(i); err != nil {
 			return sqlcon.HandleError(err)
@@ -197,7 +500,15 @@ func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) er
 			return sqlcon.HandleError(err)
 		}
 
-		return p.createIdentityCredentials(ctx, i)
+		if err := p.createUniqueTraits(ctx, i); err != nil {
+			return err
+		}
+
+		if err := p.createIndexedTraits(ctx, i); err != nil {
+			return err
+		}
+
+		return p.createIdentityCredentials(ctx, i, nil)
 	})
 }
 
@@ -205,7 +516,7 @@ func (p *Persister) ListIdentities(ctx context.Context, page, perPage int) ([]id
 	is := make([]identity.Identity, 0)
 
 	/* #nosec G201 TableName is static */
-	if err := sqlcon.HandleError(p.GetConnection(ctx).Paginate(page, perPage).Order("id DESC").
+	if err := sqlcon.HandleError(p.partitioned(ctx).Paginate(page, perPage).Order("id DESC").
 		Eager("VerifiableAddresses", "RecoveryAddresses").All(&is)); err != nil {
 		return nil, err
 	}
@@ -226,16 +537,33 @@ func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) er
 
 	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
 
-		if count, err := tx.Where("id = ?", i.ID).Count(i); err != nil {
+		if count, err := tx.Where("id = ? AND partition_id = ?", i.ID, x.PartitionID(ctx)).Count(i); err != nil {
 			return err
 		} else if count == 0 {
 			return sql.ErrNoRows
 		}
+		i.PartitionID = x.PartitionID(ctx)
+
+		stored, err := p.GetIdentityConfidential(ctx, i.ID)
+		if err != nil {
+			return err
+		}
+
+		var previous []credentialIdentifierRow
+		if p.r.Configuration(ctx).IdentityCredentialIdentifierHistoryEnabled() {
+			rows, err := p.findCredentialIdentifiers(ctx, tx, i.ID)
+			if err != nil {
+				return err
+			}
+			previous = rows
+		}
 
 		for _, tn := range []string{
 			new(identity.Credentials).TableName(ctx),
 			new(identity.VerifiableAddress).TableName(ctx),
 			new(identity.RecoveryAddress).TableName(ctx),
+			new(identity.UniqueTrait).TableName(ctx),
+			new(identity.IndexedTrait).TableName(ctx),
 		} {
 			/* #nosec G201 TableName is static */
 			if err := tx.RawQuery(fmt.Sprintf(
@@ -244,7 +572,8 @@ func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) er
 			}
 		}
 
-		if err := tx.Update(i); err != nil {
+		// The login count is only changed by RecordLogin so that concurrent logins are not lost.
+		if err := tx.Update(i, "login_count", "first_login_at"); err != nil {
 			return err
 		}
 
@@ -256,13 +585,97 @@ func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) er
 			return err
 		}
 
-		return p.createIdentityCredentials(ctx, i)
+		if err := p.createUniqueTraits(ctx, i); err != nil {
+			return err
+		}
+
+		if err := p.createIndexedTraits(ctx, i); err != nil {
+			return err
+		}
+
+		if err := p.createIdentityCredentials(ctx, i, stored.Credentials); err != nil {
+			return err
+		}
+
+		if p.r.Configuration(ctx).IdentityCredentialIdentifierHistoryEnabled() {
+			return p.recordCredentialIdentifierHistory(ctx, tx, i.ID, previous)
+		}
+
+		return nil
 	}))
 }
 
 func (p *Persister) DeleteIdentity(ctx context.Context, id uuid.UUID) error {
 	/* #nosec G201 TableName is static */
-	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ?", new(identity.Identity).TableName(ctx)), id).ExecWithCount()
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND partition_id = ?", new(identity.Identity).TableName(ctx)), id, x.PartitionID(ctx)).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return sqlcon.ErrNoRows
+	}
+	return nil
+}
+
+func (p *Persister) RecordLogin(ctx context.Context, id uuid.UUID, at time.Time) (count int, err error) {
+	err = sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		/* #nosec G201 TableName is static */
+		updated, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET login_count = login_count + 1, first_login_at = COALESCE(first_login_at, ?) WHERE id = ? AND partition_id = ?", new(identity.Identity).TableName(ctx)), at.UTC(), id, x.PartitionID(ctx)).ExecWithCount()
+		if err != nil {
+			return err
+		} else if updated == 0 {
+			return sql.ErrNoRows
+		}
+
+		var i identity.Identity
+		if err := tx.Select("login_count").Where("id = ?", id).First(&i); err != nil {
+			return err
+		}
+		count = i.LoginCount
+		return nil
+	}))
+	return count, err
+}
+
+func (p *Persister) MergeIdentities(ctx context.Context, primary *identity.Identity, secondaryID uuid.UUID) (moved int, err error) {
+	err = sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		/* #nosec G201 TableName is static */
+		count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET identity_id = ? WHERE identity_id = ?", new(session.Session).TableName(ctx)), primary.ID, secondaryID).ExecWithCount()
+		if err != nil {
+			return err
+		}
+		moved = count
+
+		// The secondary identity is deleted first so that its credential identifiers, addresses, and unique
+		// traits can be taken over by the primary identity.
+		if err := p.DeleteIdentity(ctx, secondaryID); err != nil {
+			return err
+		}
+
+		return p.UpdateIdentity(ctx, primary)
+	}))
+	return moved, err
+}
+
+func (p *Persister) ScheduleIdentityDeletion(ctx context.Context, id uuid.UUID, deleteAfter time.Time) error {
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		/* #nosec G201 TableName is static */
+		count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET delete_after = ?, updated_at = ? WHERE id = ? AND partition_id = ?", new(identity.Identity).TableName(ctx)), deleteAfter.UTC(), time.Now().UTC(), id, x.PartitionID(ctx)).ExecWithCount()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return sql.ErrNoRows
+		}
+
+		/* #nosec G201 TableName is static */
+		return tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE identity_id = ?", new(session.Session).TableName(ctx)), id).Exec()
+	}))
+}
+
+func (p *Persister) CancelIdentityDeletion(ctx context.Context, id uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("UPDATE %s SET delete_after = NULL, updated_at = ? WHERE id = ? AND partition_id = ?", new(identity.Identity).TableName(ctx)), time.Now().UTC(), id, x.PartitionID(ctx)).ExecWithCount()
 	if err != nil {
 		return sqlcon.HandleError(err)
 	}
@@ -272,9 +685,28 @@ func (p *Persister) DeleteIdentity(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (p *Persister) PurgeCredentialIdentifierHistory(ctx context.Context, before time.Time) (int, error) {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE created_at <= ?",
+		new(identity.CredentialIdentifierHistory).TableName(ctx)), before.UTC()).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) ListIdentitiesDueForDeletion(ctx context.Context, before time.Time, limit int) ([]identity.Identity, error) {
+	is := make([]identity.Identity, 0)
+	if err := p.GetConnection(ctx).Where("delete_after IS NOT NULL AND delete_after <= ?", before.UTC()).
+		Order("delete_after ASC").Limit(limit).All(&is); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return is, nil
+}
+
 func (p *Persister) GetIdentity(ctx context.Context, id uuid.UUID) (*identity.Identity, error) {
 	var i identity.Identity
-	if err := p.GetConnection(ctx).Eager("VerifiableAddresses", "RecoveryAddresses").Find(&i, id); err != nil {
+	if err := p.partitioned(ctx).Eager("VerifiableAddresses", "RecoveryAddresses").Find(&i, id); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -286,9 +718,27 @@ func (p *Persister) GetIdentity(ctx context.Context, id uuid.UUID) (*identity.Id
 	return &i, nil
 }
 
+func (p *Persister) ListIdentityIDs(ctx context.Context, schemaID string, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	q := p.partitioned(ctx).Where("id > ?", after)
+	if schemaID != "" {
+		q = q.Where("schema_id = ?", schemaID)
+	}
+
+	var is []identity.Identity
+	if err := q.Select("id").Order("id ASC").Limit(limit).All(&is); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	ids := make([]uuid.UUID, len(is))
+	for k := range is {
+		ids[k] = is[k].ID
+	}
+	return ids, nil
+}
+
 func (p *Persister) GetIdentityConfidential(ctx context.Context, id uuid.UUID) (*identity.Identity, error) {
 	var i identity.Identity
-	if err := p.GetConnection(ctx).Eager().Find(&i, id); err != nil {
+	if err := p.partitioned(ctx).Eager().Find(&i, id); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -326,7 +776,7 @@ func (p *Persister) GetIdentityConfidential(ctx context.Context, id uuid.UUID) (
 
 func (p *Persister) FindVerifiableAddressByValue(ctx context.Context, via identity.VerifiableAddressType, value string) (*identity.VerifiableAddress, error) {
 	var address identity.VerifiableAddress
-	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
+	if err := p.partitioned(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -335,7 +785,7 @@ func (p *Persister) FindVerifiableAddressByValue(ctx context.Context, via identi
 
 func (p *Persister) FindRecoveryAddressByValue(ctx context.Context, via identity.RecoveryAddressType, value string) (*identity.RecoveryAddress, error) {
 	var address identity.RecoveryAddress
-	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
+	if err := p.partitioned(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -376,7 +826,11 @@ func (p *Persister) UpdateVerifiableAddress(ctx context.Context, address *identi
 }
 
 func (p *Persister) validateIdentity(ctx context.Context, i *identity.Identity) error {
-	if err := p.r.IdentityValidator().ValidateWithRunner(ctx, i); err != nil {
+	// The unique and indexed traits are always collected here so that they can not get out of sync with the traits.
+	if err := p.r.IdentityValidator().ValidateWithRunner(ctx, i,
+		identity.NewSchemaExtensionUnique(i),
+		identity.NewSchemaExtensionIndexed(i),
+	); err != nil {
 		if _, ok := errorsx.Cause(err).(*jsonschema.ValidationError); ok {
 			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
 		}
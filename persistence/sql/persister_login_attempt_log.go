@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/x"
+)
+
+var _ login.AttemptLogPersister = new(Persister)
+
+func (p *Persister) CreateLoginAttemptLogs(ctx context.Context, logs []login.AttemptLog) error {
+	// The attempts keep the partition of the request they were recorded in.
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		for k := range logs {
+			if err := tx.Create(&logs[k]); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) attemptLogFilterQuery(ctx context.Context, f login.AttemptLogFilter) *pop.Query {
+	q := p.partitioned(ctx)
+	if f.IdentityID != uuid.Nil {
+		q = q.Where("identity_id = ?", f.IdentityID)
+	}
+	if f.Outcome != "" {
+		q = q.Where("outcome = ?", f.Outcome)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From.UTC())
+	}
+	if !f.Until.IsZero() {
+		q = q.Where("created_at < ?", f.Until.UTC())
+	}
+	return q
+}
+
+func (p *Persister) ListLoginAttemptLogs(ctx context.Context, f login.AttemptLogFilter, page, perPage int) ([]login.AttemptLog, error) {
+	logs := make([]login.AttemptLog, 0)
+	if err := p.attemptLogFilterQuery(ctx, f).
+		Order("created_at DESC").
+		Paginate(page, x.MaxItemsPerPage(perPage)).
+		All(&logs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return logs, nil
+}
+
+func (p *Persister) CountLoginAttemptLogs(ctx context.Context, f login.AttemptLogFilter) (int64, error) {
+	count, err := p.attemptLogFilterQuery(ctx, f).Count(new(login.AttemptLog))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return int64(count), nil
+}
+
+func (p *Persister) DeleteLoginAttemptLogsBefore(ctx context.Context, before time.Time) error {
+	// The retention applies to all partitions.
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE created_at < ?", new(login.AttemptLog).TableName(ctx)),
+		before.UTC()).Exec())
+}
@@ -0,0 +1,44 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/flow/login"
+)
+
+var _ login.AttemptPersister = new(Persister)
+
+func (p *Persister) CreateLoginAttempt(ctx context.Context, identifier string) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(&login.Attempt{
+		IdentifierHash: p.hmacValue(ctx, identifier),
+		CreatedAt:      time.Now().UTC(),
+	}))
+}
+
+func (p *Persister) CountLoginAttempts(ctx context.Context, identifier string, since time.Time) (int, error) {
+	count, err := p.GetConnection(ctx).
+		Where("identifier_hash = ? AND created_at > ?", p.hmacValue(ctx, identifier), since.UTC()).
+		Count(new(login.Attempt))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) DeleteLoginAttempts(ctx context.Context, identifier string) error {
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE identifier_hash = ?", new(login.Attempt).TableName(ctx)),
+		p.hmacValue(ctx, identifier)).Exec())
+}
+
+func (p *Persister) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE created_at < ?", new(login.Attempt).TableName(ctx)),
+		before.UTC()).Exec())
+}
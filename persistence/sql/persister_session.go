@@ -2,6 +2,7 @@ package sql
 
 import (
 	"context"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -66,6 +67,21 @@ func (p *Persister) DeleteSessionByToken(ctx context.Context, token string) erro
 	return nil
 }
 
+func (p *Persister) ExtendSession(ctx context.Context, sid uuid.UUID, expiresAt time.Time) error {
+	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET expires_at = ?, updated_at = ? WHERE id = ?", expiresAt, time.Now().UTC(), sid).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
+func (p *Persister) ConsumeFirstLogin(ctx context.Context, sid uuid.UUID) (bool, error) {
+	count, err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET first_login = false WHERE id = ? AND first_login = true", sid).ExecWithCount()
+	if err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+	return count > 0, nil
+}
+
 func (p *Persister) RevokeSessionByToken(ctx context.Context, token string) error {
 	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE token = ?", token).Exec(); err != nil {
 		return sqlcon.HandleError(err)
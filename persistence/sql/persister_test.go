@@ -7,14 +7,17 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
 
 	"github.com/go-errors/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ory/x/sqlcon"
 
+	"github.com/ory/kratos/apikey"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/persistence/sql"
@@ -163,6 +166,14 @@ func TestPersister(t *testing.T) {
 				pop.SetLogger(pl(t))
 				login.TestFlowPersister(p)(t)
 			})
+			t.Run("contract=login.TestAttemptPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				login.TestAttemptPersister(p)(t)
+			})
+			t.Run("contract=login.TestAttemptLogPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				login.TestAttemptLogPersister(p)(t)
+			})
 			t.Run("contract=settings.TestFlowPersister", func(t *testing.T) {
 				pop.SetLogger(pl(t))
 				settings.TestRequestPersister(conf, p)(t)
@@ -191,6 +202,28 @@ func TestPersister(t *testing.T) {
 				pop.SetLogger(pl(t))
 				continuity.TestPersister(p)(t)
 			})
+			t.Run("contract=apikey.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				apikey.TestPersister(p)(t)
+			})
+			t.Run("case=counts pending migrations", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				ctx := context.Background()
+
+				pending, err := p.PendingMigrations(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, 0, pending)
+
+				require.NoError(t, p.MigrateDown(ctx, 1))
+				pending, err = p.PendingMigrations(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, 1, pending)
+
+				require.NoError(t, p.MigrateUp(ctx))
+				pending, err = p.PendingMigrations(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, 0, pending)
+			})
 		})
 	}
 }
@@ -247,3 +280,54 @@ func TestPersister_Transaction(t *testing.T) {
 		assert.Equal(t, sqlcon.ErrNoRows.Error(), err.Error())
 	})
 }
+
+func TestPersister_TokensAreHashed(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	p := reg.Persister()
+	ctx := context.Background()
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.RecoveryAddresses = []identity.RecoveryAddress{{Value: "hashed-tokens@ory.sh", Via: identity.RecoveryAddressTypeEmail}}
+	i.VerifiableAddresses = []identity.VerifiableAddress{{Value: "hashed-tokens@ory.sh", Via: identity.VerifiableAddressTypeEmail}}
+	require.NoError(t, p.CreateIdentity(ctx, i))
+
+	storedToken := func(t *testing.T, table string, id interface{}) string {
+		var stored struct {
+			Token string `db:"token"`
+		}
+		/* #nosec G201 table is static */
+		require.NoError(t, p.GetConnection(ctx).RawQuery(fmt.Sprintf("SELECT token FROM %s WHERE id = ?", table), id).First(&stored))
+		return stored.Token
+	}
+
+	t.Run("token=recovery", func(t *testing.T) {
+		token := link.NewRecoveryToken(&i.RecoveryAddresses[0], time.Hour)
+		raw := token.Token
+		require.NoError(t, p.CreateRecoveryToken(ctx, token))
+		assert.Equal(t, raw, token.Token)
+
+		stored := storedToken(t, "identity_recovery_tokens", token.ID)
+		assert.NotEqual(t, raw, stored)
+		assert.Len(t, stored, 64)
+
+		require.NoError(t, p.DeleteRecoveryToken(ctx, raw))
+		_, err := p.UseRecoveryToken(ctx, raw)
+		require.Error(t, err)
+	})
+
+	t.Run("token=verification", func(t *testing.T) {
+		token := link.NewVerificationToken(&i.VerifiableAddresses[0], time.Hour)
+		raw := token.Token
+		require.NoError(t, p.CreateVerificationToken(ctx, token))
+		assert.Equal(t, raw, token.Token)
+
+		stored := storedToken(t, "identity_verification_tokens", token.ID)
+		assert.NotEqual(t, raw, stored)
+		assert.Len(t, stored, 64)
+
+		require.NoError(t, p.DeleteVerificationToken(ctx, raw))
+		_, err := p.UseVerificationToken(ctx, raw)
+		require.Error(t, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gobuffalo/pop/v5"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/ory/x/sqlcon"
 
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/strategy/link"
 )
@@ -101,6 +103,39 @@ func (p *Persister) UseVerificationToken(ctx context.Context, token string) (*li
 }
 
 func (p *Persister) DeleteVerificationToken(ctx context.Context, token string) error {
-	/* #nosec G201 TableName is static */
-	return p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE token=?", new(link.VerificationToken).TableName(ctx)), token).Exec()
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		// Tokens are stored as HMACs, so we need to remove the token for every secret it could have been hashed with.
+		for _, secret := range p.r.Configuration(ctx).SecretsSession() {
+			/* #nosec G201 TableName is static */
+			if err := tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE token=?", new(link.VerificationToken).TableName(ctx)), p.hmacValueWithSecret(token, secret)).Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func (p *Persister) InvalidateSurplusVerificationTokens(ctx context.Context, identityID uuid.UUID, keep int) error {
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		var active []link.VerificationToken
+		/* #nosec G201 TableName is static */
+		if err := tx.Where(fmt.Sprintf("identity_verifiable_address_id IN (SELECT id FROM %s WHERE identity_id = ?) AND NOT used AND expires_at > ?",
+			new(identity.VerifiableAddress).TableName(ctx)), identityID, time.Now().UTC()).
+			Order("issued_at DESC").All(&active); err != nil {
+			return err
+		}
+
+		if len(active) <= keep {
+			return nil
+		}
+
+		args := []interface{}{time.Now().UTC()}
+		for _, t := range active[keep:] {
+			args = append(args, t.ID)
+		}
+
+		/* #nosec G201 TableName is static */
+		return tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id IN (?%s)", new(link.VerificationToken).TableName(ctx),
+			strings.Repeat(", ?", len(args)-2)), args...).Exec()
+	}))
 }
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -69,6 +70,23 @@ func NewPasswordPolicyViolationError(instancePtr string, reason string) error {
 	})
 }
 
+type ValidationErrorContextPasswordNotAllowed struct{}
+
+func (r *ValidationErrorContextPasswordNotAllowed) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextPasswordNotAllowed) FinishInstanceContext() {}
+
+func NewPasswordNotAllowedError(instancePtr string) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     "the password is not allowed",
+			InstancePtr: instancePtr,
+			Context:     &ValidationErrorContextPasswordNotAllowed{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationPasswordNotAllowed()),
+	})
+}
+
 type ValidationErrorContextInvalidCredentialsError struct{}
 
 func (r *ValidationErrorContextInvalidCredentialsError) AddContext(_, _ string) {}
@@ -102,3 +120,96 @@ func NewDuplicateCredentialsError() error {
 		Messages: new(text.Messages).Add(text.NewErrorValidationDuplicateCredentials()),
 	})
 }
+
+type ValidationErrorContextDuplicateTraitError struct{}
+
+func (r *ValidationErrorContextDuplicateTraitError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextDuplicateTraitError) FinishInstanceContext() {}
+
+func NewDuplicateTraitError(key string) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf(`an account with the same value for unique trait "%s" exists already`, key),
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextDuplicateTraitError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationDuplicateTrait(key)),
+	})
+}
+
+type ValidationErrorContextTraitsTooLargeError struct{}
+
+func (r *ValidationErrorContextTraitsTooLargeError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextTraitsTooLargeError) FinishInstanceContext() {}
+
+func NewTraitsTooLargeError(max, actual int) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the traits must be at most %d bytes but are %d bytes", max, actual),
+			InstancePtr: "#/traits",
+			Context:     &ValidationErrorContextTraitsTooLargeError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationTraitsTooLarge(max, actual)),
+	})
+}
+
+type ValidationErrorContextLoginThrottledError struct{}
+
+func (r *ValidationErrorContextLoginThrottledError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextLoginThrottledError) FinishInstanceContext() {}
+
+func NewLoginThrottledError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     "too many failed login attempts for this identifier",
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextLoginThrottledError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginThrottled()),
+	})
+}
+
+type ValidationErrorContextEmailChangeCooldownError struct {
+	AvailableAt time.Time
+}
+
+func (r *ValidationErrorContextEmailChangeCooldownError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextEmailChangeCooldownError) FinishInstanceContext() {}
+
+func NewEmailChangeCooldownError(until time.Time) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the email address can not be changed again before %s", until.UTC().Format(time.RFC3339)),
+			InstancePtr: "#/traits",
+			Context: &ValidationErrorContextEmailChangeCooldownError{
+				AvailableAt: until,
+			},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationSettingsEmailChangeCooldown(until)),
+	})
+}
+
+type ValidationErrorContextLinkedProvidersLimitError struct {
+	Limit int
+}
+
+func (r *ValidationErrorContextLinkedProvidersLimitError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextLinkedProvidersLimitError) FinishInstanceContext() {}
+
+func NewLinkedProvidersLimitError(limit int) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("can not link more than %d OpenID Connect connections", limit),
+			InstancePtr: "#/",
+			Context: &ValidationErrorContextLinkedProvidersLimitError{
+				Limit: limit,
+			},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationSettingsLinkedProvidersLimit(limit)),
+	})
+}
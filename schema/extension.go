@@ -34,6 +34,12 @@ type (
 		Recovery struct {
 			Via string `json:"via"`
 		} `json:"recovery"`
+		Unique struct {
+			Key string `json:"key"`
+		} `json:"unique"`
+		Indexed struct {
+			Key string `json:"key"`
+		} `json:"indexed"`
 		Mappings struct {
 			Identity struct {
 				Traits []struct {
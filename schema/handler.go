@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
 
 	"github.com/ory/kratos/x"
 )
@@ -36,10 +37,12 @@ const SchemasPath string = "schemas"
 
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	public.GET(fmt.Sprintf("/%s/:id", SchemasPath), h.get)
+	public.GET(fmt.Sprintf("/%s/:id/template", SchemasPath), h.template)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(fmt.Sprintf("/%s/:id", SchemasPath), h.get)
+	admin.GET(fmt.Sprintf("/%s/:id/template", SchemasPath), h.template)
 }
 
 // The raw identity traits schema
@@ -59,6 +62,11 @@ type getSchemaParameters struct {
 	// required: true
 	// in: path
 	ID string `json:"id"`
+
+	// Version of the schema. Defaults to the latest version.
+	//
+	// in: query
+	Version string `json:"version"`
 }
 
 // swagger:route GET /schemas/{id} public admin getSchema
@@ -75,7 +83,7 @@ type getSchemaParameters struct {
 //       404: genericError
 //       500: genericError
 func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	s, err := h.r.IdentityTraitsSchemas(r.Context()).GetByID(ps.ByName("id"))
+	s, err := h.r.IdentityTraitsSchemas(r.Context()).GetByIDAndVersion(ps.ByName("id"), r.URL.Query().Get("version"))
 	if err != nil {
 		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithDebugf("%+v", err)))
 		return
@@ -90,13 +98,12 @@ func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		}
 		defer src.Close()
 	} else {
-		resp, err := http.Get(s.URL.String())
+		src, err = jsonschema.LoadURL(s.URL.String())
 		if err != nil {
 			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The file for this JSON Schema ID could not be found or opened. This is a configuration issue.").WithDebugf("%+v", err)))
 			return
 		}
-		defer resp.Body.Close()
-		src = resp.Body
+		defer src.Close()
 	}
 
 	w.Header().Add("Content-Type", "application/json")
@@ -105,3 +112,50 @@ func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		return
 	}
 }
+
+// nolint:deadcode,unused
+// swagger:parameters getSchemaTemplate
+type getSchemaTemplateParameters struct {
+	// ID must be set to the ID of schema you want to get the template for
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Version of the schema. Defaults to the latest version.
+	//
+	// in: query
+	Version string `json:"version"`
+}
+
+// swagger:route GET /schemas/{id}/template public admin getSchemaTemplate
+//
+// Get a Traits Template for a Schema
+//
+// Returns an identity whose traits contain the default values of the traits schema. Required traits without
+// a default value are set to an empty value of their type. Optional traits without a default value are omitted.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityTraitsTemplate
+//       404: genericError
+//       500: genericError
+func (h *Handler) template(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	s, err := h.r.IdentityTraitsSchemas(r.Context()).GetByIDAndVersion(ps.ByName("id"), r.URL.Query().Get("version"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithDebugf("%+v", err)))
+		return
+	}
+
+	traits, err := NewTraitsTemplate(s.URL.String())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The template for this JSON Schema ID could not be created. This is a configuration issue.").WithDebugf("%+v", err)))
+		return
+	}
+
+	h.r.Writer().Write(w, r, &TraitsTemplate{SchemaID: s.ID, Traits: traits})
+}
@@ -125,4 +125,10 @@ func TestHandler(t *testing.T) {
 	t.Run("case=get not-existing schema", func(t *testing.T) {
 		_ = getFromTS("not-existing", http.StatusNotFound)
 	})
+
+	t.Run("case=get schema template", func(t *testing.T) {
+		require.JSONEq(t, `{"schema_id":"identity2","traits":{}}`, getFromTS("identity2/template", http.StatusOK))
+		_ = getFromTS("no-file/template", http.StatusInternalServerError)
+		_ = getFromTS("not-existing/template", http.StatusNotFound)
+	})
 }
@@ -0,0 +1,394 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	loaderDependencies interface {
+		config.Providers
+		x.LoggingProvider
+		x.HTTPClientProvider
+		IdentityTraitsProvider
+	}
+	LoaderProvider interface {
+		IdentitySchemaLoader() *Loader
+	}
+
+	// Loader loads the identity JSON Schemas when the server starts and verifies their checksums. JSON Schemas
+	// served over HTTP(S) are kept in memory afterwards instead of being fetched for every validation.
+	//
+	// JSON Schemas with other schemes, for example `s3://`, are loaded using the loader registered for the scheme
+	// in `jsonschema.Loaders`.
+	//
+	// If `hot_reload.enabled` is set, JSON Schemas loaded from the file system are kept in memory as well and are
+	// replaced when the file changes.
+	//
+	// References using the `registry://` scheme, for example `registry://shared/address.schema.json#/definitions/address`,
+	// are resolved against `identity.schema_registry.base_url` and are kept in memory like JSON Schemas served over
+	// HTTP(S).
+	Loader struct {
+		sync.RWMutex
+		d    loaderDependencies
+		docs map[string][]byte
+	}
+)
+
+// registryScheme is the URL scheme of references which are resolved against the schema registry.
+const registryScheme = "registry"
+
+// fileLoader is the jsonschema loader for `file://` which is replaced by the Loader if hot reload is enabled.
+var fileLoader = jsonschema.Loaders["file"]
+
+// loaders routes the loaders registered in `jsonschema.Loaders` to the Loader which was loaded last. The loaders
+// are registered only once because `jsonschema.Loaders` is read without synchronization whenever a JSON Schema
+// is compiled.
+var loaders struct {
+	sync.RWMutex
+	register  sync.Once
+	active    *Loader
+	hotReload bool
+}
+
+func NewLoader(d loaderDependencies) *Loader {
+	return &Loader{d: d, docs: map[string][]byte{}}
+}
+
+// Load loads all identity JSON Schemas including their historical versions and registers the loader for HTTP(S)
+// and the schema registry, and for files if hot reload is enabled. Returns an error if a JSON Schema can not be
+// loaded, if its checksum does not match, or if one of its `$ref`s can not be resolved.
+func (l *Loader) Load(ctx context.Context) error {
+	hotReload := l.d.Configuration(ctx).HotReloadEnabled()
+	l.register(hotReload)
+
+	docs := map[string][]byte{}
+	loaded := map[string][]byte{}
+	for _, s := range l.sources(ctx) {
+		doc, err := l.load(ctx, s.RawURL)
+		if err != nil {
+			return errors.WithMessagef(err, "unable to load JSON Schema %s", s.ID)
+		}
+
+		loaded[s.URL.String()] = doc
+		if isRemote(s.URL) || (hotReload && isFile(s.URL)) {
+			docs[s.URL.String()] = doc
+		}
+	}
+
+	l.Lock()
+	l.docs = docs
+	l.Unlock()
+
+	// Compiling resolves all `$ref`s, which are kept in memory by open if they are remote, so that broken
+	// references are reported on start up and not when the first identity is validated.
+	for _, s := range l.sources(ctx) {
+		if err := compile(s.URL.String(), loaded[s.URL.String()]); err != nil {
+			return errors.WithMessagef(err, "unable to resolve the $refs of JSON Schema %s", s.ID)
+		}
+	}
+
+	return nil
+}
+
+// Refresh fetches all JSON Schemas served over HTTP(S) or from the schema registry again. If a JSON Schema can
+// not be fetched, is invalid, or its checksum does not match, the previously loaded JSON Schema continues to be
+// used.
+func (l *Loader) Refresh(ctx context.Context) {
+	l.refresh(ctx, l.urls(ctx, func(u *url.URL) bool {
+		return isRemote(u) || isRegistry(u)
+	}))
+}
+
+// Reload reads all JSON Schemas loaded from the file system again. If a JSON Schema can not be read, is
+// invalid, or its checksum does not match, the previously loaded JSON Schema continues to be used.
+func (l *Loader) Reload(ctx context.Context) {
+	l.refresh(ctx, l.urls(ctx, isFile))
+}
+
+func (l *Loader) urls(ctx context.Context, include func(u *url.URL) bool) map[string]struct{} {
+	urls := map[string]struct{}{}
+	for _, s := range l.sources(ctx) {
+		if include(s.URL) {
+			urls[s.URL.String()] = struct{}{}
+		}
+	}
+
+	l.RLock()
+	for u := range l.docs {
+		if parsed, err := url.Parse(u); err == nil && include(parsed) {
+			urls[u] = struct{}{}
+		}
+	}
+	l.RUnlock()
+
+	return urls
+}
+
+func (l *Loader) refresh(ctx context.Context, urls map[string]struct{}) {
+	for u := range urls {
+		doc, err := l.load(ctx, u)
+		if err == nil {
+			err = compile(u, doc)
+		}
+		if err != nil {
+			l.d.Logger().WithError(err).WithField("url", u).Error("Unable to reload JSON Schema, the previously loaded JSON Schema will be used.")
+			continue
+		}
+
+		l.Lock()
+		previous, ok := l.docs[u]
+		l.docs[u] = doc
+		l.Unlock()
+
+		if ok && !bytes.Equal(previous, doc) {
+			forgetKeysInOrder(u)
+			l.d.Logger().WithField("url", u).Info("JSON Schema was changed and has been reloaded.")
+		}
+	}
+}
+
+// Work refreshes the JSON Schemas periodically until the context is cancelled. It returns immediately if
+// `identity.schema_refresh_interval` is not set.
+func (l *Loader) Work(ctx context.Context) error {
+	for {
+		interval := l.d.Configuration(ctx).IdentitySchemaRefreshInterval()
+		if interval <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		l.Refresh(ctx)
+	}
+}
+
+// Watch reloads the JSON Schemas loaded from the file system periodically until the context is cancelled. It
+// returns immediately if `hot_reload.enabled` is not set.
+func (l *Loader) Watch(ctx context.Context) error {
+	for {
+		if !l.d.Configuration(ctx).HotReloadEnabled() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(l.d.Configuration(ctx).HotReloadInterval()):
+		}
+
+		l.Reload(ctx)
+	}
+}
+
+// register makes the Loader the one used by the jsonschema loaders for HTTP(S), the schema registry, and, if hot
+// reload is enabled, for files.
+func (l *Loader) register(hotReload bool) {
+	loaders.Lock()
+	loaders.active, loaders.hotReload = l, hotReload
+	loaders.Unlock()
+
+	loaders.register.Do(func() {
+		jsonschema.Loaders["http"] = openRemote
+		jsonschema.Loaders["https"] = openRemote
+		jsonschema.Loaders[registryScheme] = openRemote
+		jsonschema.Loaders["file"] = openFile
+	})
+}
+
+func activeLoader() (*Loader, bool) {
+	loaders.RLock()
+	defer loaders.RUnlock()
+	return loaders.active, loaders.hotReload
+}
+
+func openRemote(rawURL string) (io.ReadCloser, error) {
+	l, _ := activeLoader()
+	return l.open(rawURL)
+}
+
+func openFile(rawURL string) (io.ReadCloser, error) {
+	if l, hotReload := activeLoader(); hotReload {
+		return l.open(rawURL)
+	}
+	return fileLoader(rawURL)
+}
+
+// open implements the jsonschema loader for HTTP(S) and the schema registry. JSON Schemas which are not in memory yet, for example
+// because they are referenced using `$ref`, are fetched and kept in memory.
+func (l *Loader) open(rawURL string) (io.ReadCloser, error) {
+	l.RLock()
+	doc, ok := l.docs[rawURL]
+	l.RUnlock()
+	if ok {
+		return ioutil.NopCloser(bytes.NewReader(doc)), nil
+	}
+
+	doc, err := l.load(context.Background(), rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Lock()
+	l.docs[rawURL] = doc
+	l.Unlock()
+	return ioutil.NopCloser(bytes.NewReader(doc)), nil
+}
+
+// load fetches the JSON Schema and verifies its checksum if the URL belongs to a configured JSON Schema.
+func (l *Loader) load(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	doc, err := l.fetch(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range l.sources(ctx) {
+		if s.URL.String() == u.String() {
+			if err := verifyChecksum(doc, s.Checksum); err != nil {
+				return nil, errors.WithMessagef(err, "JSON Schema %s at %s", s.ID, rawURL)
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+func (l *Loader) fetch(ctx context.Context, u *url.URL) ([]byte, error) {
+	if isRegistry(u) {
+		resolved, err := l.resolveRegistry(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		return l.fetch(ctx, resolved)
+	}
+
+	if !isRemote(u) {
+		load := jsonschema.LoadURL
+		if isFile(u) {
+			// The file loader may have been replaced by Loader.open, which would call fetch again.
+			load = fileLoader
+		}
+
+		src, err := load(u.String())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer src.Close()
+		return ioutil.ReadAll(src)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := l.d.HTTPClient().Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("expected status code %d when fetching %s but got %d", http.StatusOK, u, res.StatusCode)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
+
+func (l *Loader) sources(ctx context.Context) Schemas {
+	var sources Schemas
+	for _, s := range l.d.IdentityTraitsSchemas(ctx) {
+		sources = append(sources, s)
+		sources = append(sources, s.History...)
+	}
+	return sources
+}
+
+// resolveRegistry resolves a `registry://` URL against `identity.schema_registry.base_url`.
+func (l *Loader) resolveRegistry(ctx context.Context, u *url.URL) (*url.URL, error) {
+	base := l.d.Configuration(ctx).IdentitySchemaRegistryURL()
+	if base == nil {
+		return nil, errors.Errorf("unable to resolve %s because identity.schema_registry.base_url is not set", u)
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base = urlx.Copy(base)
+		base.Path += "/"
+	}
+
+	return base.ResolveReference(&url.URL{Path: strings.TrimPrefix(u.Host+u.Path, "/"), RawQuery: u.RawQuery}), nil
+}
+
+func isRegistry(u *url.URL) bool {
+	return u.Scheme == registryScheme
+}
+
+func isRemote(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+func isFile(u *url.URL) bool {
+	return u.Scheme == "file"
+}
+
+// compile makes sure that the document is a valid JSON Schema before it replaces the loaded JSON Schema.
+func compile(href string, doc []byte) error {
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(href, bytes.NewReader(doc)); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := c.Compile(href); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func verifyChecksum(doc []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(doc)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); !strings.EqualFold(actual, checksum) {
+		return errors.Errorf("checksum mismatch: expected %s but got %s", checksum, actual)
+	}
+	return nil
+}
+
+func forgetKeysInOrder(schemaRef string) {
+	orderedKeyCacheMutex.Lock()
+	delete(orderedKeyCache, schemaRef)
+	orderedKeyCacheMutex.Unlock()
+}
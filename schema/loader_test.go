@@ -0,0 +1,231 @@
+package schema_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+)
+
+type remoteSchema struct {
+	sync.Mutex
+	doc    string
+	status int
+	hits   int
+}
+
+func (s *remoteSchema) set(doc string, status int) {
+	s.Lock()
+	defer s.Unlock()
+	s.doc, s.status = doc, status
+}
+
+func (s *remoteSchema) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.hits
+}
+
+func (s *remoteSchema) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.hits++
+	w.WriteHeader(s.status)
+	_, _ = w.Write([]byte(s.doc))
+}
+
+func checksum(doc string) string {
+	sum := sha256.Sum256([]byte(doc))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestLoader(t *testing.T) {
+	const (
+		v1 = `{"type":"object","properties":{"traits":{"type":"object","properties":{"email":{"type":"string"}}}}}`
+		v2 = `{"type":"object","properties":{"traits":{"type":"object","properties":{"email":{"type":"string"},"name":{"type":"string"}}}}}`
+	)
+
+	newLoader := func(t *testing.T, sum string) (*schema.Loader, *remoteSchema, string) {
+		remote := &remoteSchema{doc: v1, status: http.StatusOK}
+		ts := httptest.NewServer(remote)
+		t.Cleanup(ts.Close)
+
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, ts.URL+"/identity.schema.json")
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaChecksum, sum)
+		return reg.IdentitySchemaLoader(), remote, ts.URL + "/identity.schema.json"
+	}
+
+	read := func(t *testing.T, u string) string {
+		src, err := jsonschema.LoadURL(u)
+		require.NoError(t, err)
+		defer src.Close()
+		doc, err := ioutil.ReadAll(src)
+		require.NoError(t, err)
+		return string(doc)
+	}
+
+	t.Run("case=loads the schema once and serves it from memory", func(t *testing.T) {
+		l, remote, u := newLoader(t, checksum(v1))
+		require.NoError(t, l.Load(context.Background()))
+		assert.Equal(t, 1, remote.count())
+
+		assert.Equal(t, v1, read(t, u))
+		assert.Equal(t, v1, read(t, u))
+		assert.Equal(t, 1, remote.count())
+	})
+
+	t.Run("case=loading does not race with compiling JSON Schemas", func(t *testing.T) {
+		l, _, u := newLoader(t, checksum(v1))
+		require.NoError(t, l.Load(context.Background()))
+
+		var wg sync.WaitGroup
+		for k := 0; k < 10; k++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, l.Load(context.Background()))
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := jsonschema.Compile(u)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("case=fails if the checksum does not match", func(t *testing.T) {
+		l, _, _ := newLoader(t, checksum(v2))
+		err := l.Load(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	})
+
+	t.Run("case=fails if the schema is unreachable", func(t *testing.T) {
+		l, remote, _ := newLoader(t, "")
+		remote.set("", http.StatusNotFound)
+		require.Error(t, l.Load(context.Background()))
+	})
+
+	t.Run("case=refresh picks up changes", func(t *testing.T) {
+		l, remote, u := newLoader(t, "")
+		require.NoError(t, l.Load(context.Background()))
+
+		keys, err := schema.GetKeysInOrder(u)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"traits.email"}, keys)
+
+		remote.set(v2, http.StatusOK)
+		l.Refresh(context.Background())
+		assert.Equal(t, v2, read(t, u))
+
+		keys, err = schema.GetKeysInOrder(u)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"traits.email", "traits.name"}, keys)
+	})
+
+	t.Run("case=refresh keeps the previous schema on errors", func(t *testing.T) {
+		l, remote, u := newLoader(t, checksum(v1))
+		require.NoError(t, l.Load(context.Background()))
+
+		remote.set(v2, http.StatusOK)
+		l.Refresh(context.Background())
+		assert.Equal(t, v1, read(t, u), "checksum mismatch must be ignored")
+
+		remote.set("", http.StatusInternalServerError)
+		l.Refresh(context.Background())
+		assert.Equal(t, v1, read(t, u), "unreachable schema must be ignored")
+	})
+
+	t.Run("case=reload picks up file changes if hot reload is enabled", func(t *testing.T) {
+		fp := filepath.Join(t.TempDir(), "identity.schema.json")
+		require.NoError(t, ioutil.WriteFile(fp, []byte(v1), 0600))
+		u := "file://" + fp
+
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, u)
+		conf.MustSet(config.ViperKeyHotReloadEnabled, true)
+		l := reg.IdentitySchemaLoader()
+		require.NoError(t, l.Load(context.Background()))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyHotReloadEnabled, false)
+			require.NoError(t, l.Load(context.Background()))
+		})
+
+		require.NoError(t, ioutil.WriteFile(fp, []byte(v2), 0600))
+		assert.Equal(t, v1, read(t, u), "changes must only be picked up on reload")
+
+		l.Reload(context.Background())
+		assert.Equal(t, v2, read(t, u))
+
+		keys, err := schema.GetKeysInOrder(u)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"traits.email", "traits.name"}, keys)
+
+		for _, doc := range []string{`{"type":`, `{"type":"not-a-type"}`} {
+			require.NoError(t, ioutil.WriteFile(fp, []byte(doc), 0600))
+			l.Reload(context.Background())
+			assert.Equal(t, v2, read(t, u), "invalid schema %s must be ignored", doc)
+		}
+	})
+
+	t.Run("case=resolves references against the schema registry", func(t *testing.T) {
+		const (
+			address  = `{"definitions":{"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}}}`
+			identity = `{"type":"object","properties":{"traits":{"type":"object","properties":{"address":{"$ref":"registry://shared/address.schema.json#/definitions/address"}}}}}`
+		)
+
+		registry := &remoteSchema{doc: address, status: http.StatusOK}
+		ts := httptest.NewServer(registry)
+		t.Cleanup(ts.Close)
+
+		fp := filepath.Join(t.TempDir(), "identity.schema.json")
+		require.NoError(t, ioutil.WriteFile(fp, []byte(identity), 0600))
+
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://"+fp)
+		conf.MustSet(config.ViperKeyIdentitySchemaRegistryBaseURL, ts.URL+"/schemas")
+
+		l := reg.IdentitySchemaLoader()
+		require.NoError(t, l.Load(context.Background()))
+		assert.Equal(t, 1, registry.count())
+		assert.Equal(t, address, read(t, "registry://shared/address.schema.json"))
+		assert.Equal(t, 1, registry.count(), "the referenced schema must be served from memory")
+
+		v := schema.NewValidator()
+		require.NoError(t, v.Validate("file://"+fp, []byte(`{"traits":{"address":{"city":"Munich"}}}`)))
+		require.Error(t, v.Validate("file://"+fp, []byte(`{"traits":{"address":{}}}`)))
+
+		t.Run("case=fails if a reference can not be resolved", func(t *testing.T) {
+			registry.set("", http.StatusNotFound)
+			conf.MustSet(config.ViperKeyIdentitySchemaRegistryBaseURL, ts.URL+"/other")
+
+			err := l.Load(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unable to resolve the $refs of JSON Schema default")
+		})
+
+		t.Run("case=fails if no schema registry is configured", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyIdentitySchemaRegistryBaseURL, "")
+
+			err := l.Load(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "identity.schema_registry.base_url is not set")
+		})
+	})
+}
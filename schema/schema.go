@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
@@ -37,6 +38,27 @@ func (s Schemas) GetByID(id string) (*Schema, error) {
 	return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to find JSON Schema ID: %s", id))
 }
 
+// GetByIDAndVersion returns the schema with the given ID in the given version. If the version is empty, is the
+// latest version, or is no longer part of the schema's history, the latest version of the schema is returned.
+func (s Schemas) GetByIDAndVersion(id, version string) (*Schema, error) {
+	latest, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" || version == latest.Version {
+		return latest, nil
+	}
+
+	for _, h := range latest.History {
+		if h.Version == version {
+			return &h, nil
+		}
+	}
+
+	return latest, nil
+}
+
 var orderedKeyCacheMutex sync.RWMutex
 var orderedKeyCache map[string][]string
 
@@ -80,9 +102,40 @@ func GetKeysInOrder(schemaRef string) ([]string, error) {
 }
 
 type Schema struct {
-	ID     string   `json:"id"`
-	URL    *url.URL `json:"-"`
-	RawURL string   `json:"url"`
+	ID      string   `json:"id"`
+	URL     *url.URL `json:"-"`
+	RawURL  string   `json:"url"`
+	Version string   `json:"version,omitempty"`
+
+	// Checksum is the expected SHA-256 checksum of the JSON Schema in the format `sha256:<hex>`. It is not
+	// verified if empty.
+	Checksum string `json:"-"`
+
+	// History contains previous versions of this schema, ordered from the most recent to the oldest.
+	History Schemas `json:"-"`
+
+	// MaxTraitsSize is the maximum size of the compacted traits in bytes. Zero means no limit.
+	MaxTraitsSize int `json:"-"`
+
+	// VerificationEnforcement is applied to identities which did not verify any of their addresses in time.
+	VerificationEnforcement VerificationEnforcement `json:"-"`
+
+	// EmailChangeCooldown is the time during which an identity can not change its email addresses again
+	// using the settings flow. Zero disables the cooldown.
+	EmailChangeCooldown time.Duration `json:"-"`
+
+	// MaxLinkedOIDCProviders is the maximum number of OpenID Connect providers an identity can link. Zero
+	// falls back to the limit of the OpenID Connect method.
+	MaxLinkedOIDCProviders int `json:"-"`
+}
+
+// VerificationEnforcement describes what happens to identities which did not verify any of their addresses
+// within the grace period after their creation.
+type VerificationEnforcement struct {
+	// Action is one of the config.VerificationEnforcement* constants. An empty action is equal to
+	// config.VerificationEnforcementNone.
+	Action      string
+	GracePeriod time.Duration
 }
 
 func (s *Schema) SchemaURL(host *url.URL) *url.URL {
@@ -70,6 +70,47 @@ func TestSchemas_GetByID(t *testing.T) {
 	})
 }
 
+func TestSchemas_GetByIDAndVersion(t *testing.T) {
+	ss := Schemas{
+		Schema{
+			ID:      "foo",
+			Version: "v3",
+			History: Schemas{
+				{ID: "foo", Version: "v2"},
+				{ID: "foo", Version: "v1"},
+			},
+		},
+		Schema{
+			ID: config.DefaultIdentityTraitsSchemaID,
+		},
+	}
+
+	for _, tc := range []struct {
+		d        string
+		id       string
+		version  string
+		expected string
+	}{
+		{d: "latest version when version is empty", id: "foo", expected: "v3"},
+		{d: "latest version", id: "foo", version: "v3", expected: "v3"},
+		{d: "historical version", id: "foo", version: "v2", expected: "v2"},
+		{d: "oldest historical version", id: "foo", version: "v1", expected: "v1"},
+		{d: "latest version when version is no longer kept", id: "foo", version: "v0", expected: "v3"},
+		{d: "unversioned schema", id: "", version: "v1", expected: ""},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			s, err := ss.GetByIDAndVersion(tc.id, tc.version)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Version)
+		})
+	}
+
+	t.Run("case=get not existing schema", func(t *testing.T) {
+		_, err := ss.GetByIDAndVersion("not existing id", "v1")
+		require.Error(t, err)
+	})
+}
+
 func TestGetKeysInOrder(t *testing.T) {
 	for i, tc := range []struct {
 		schemaRef string
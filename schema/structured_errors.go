@@ -0,0 +1,156 @@
+package schema
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/text"
+)
+
+// StructuredValidationError contains one ValidationError per failed JSON Schema keyword. Each of them is
+// attached to the value it concerns and carries the keyword as well as the expected and actual values.
+//
+// Messages contains a human-readable summary of all failures.
+type StructuredValidationError struct {
+	Validations []*ValidationError
+	Messages    text.Messages
+
+	cause *jsonschema.ValidationError
+}
+
+func (e *StructuredValidationError) Error() string {
+	return e.cause.Error()
+}
+
+// Cause returns the original JSON Schema validation error.
+func (e *StructuredValidationError) Cause() error {
+	return e.cause
+}
+
+// Unwrap returns the original JSON Schema validation error.
+func (e *StructuredValidationError) Unwrap() error {
+	return e.cause
+}
+
+// newStructuredValidationError flattens the JSON Schema validation error into its leaf causes and resolves
+// the expected value from the schema and the actual value from the document.
+func newStructuredValidationError(err *jsonschema.ValidationError, href string, schema, document json.RawMessage) *StructuredValidationError {
+	e := &StructuredValidationError{cause: err}
+
+	// Errors reference the schema by its `$id` if it has one, so the validated schema is known by both URLs.
+	schemas := map[string]string{href: string(schema)}
+	if id := gjson.GetBytes(schema, "$id").String(); id != "" {
+		schemas[strings.SplitN(id, "#", 2)[0]] = string(schema)
+	}
+
+	for _, leaf := range validationErrorLeaves(err) {
+		keyword := schemaKeyword(leaf.SchemaPtr)
+
+		if ctx, ok := leaf.Context.(*jsonschema.ValidationErrorContextRequired); ok {
+			for _, missing := range ctx.Missing {
+				segments := strings.Split(missing, "/")
+				property := segments[len(segments)-1]
+				e.Validations = append(e.Validations, &ValidationError{
+					ValidationError: &jsonschema.ValidationError{
+						Message:     leaf.Message,
+						InstancePtr: missing,
+						SchemaURL:   leaf.SchemaURL,
+						SchemaPtr:   leaf.SchemaPtr,
+						Context:     &jsonschema.ValidationErrorContextRequired{Missing: []string{missing}},
+					},
+					Messages: new(text.Messages).Add(text.NewErrorValidationSchema(
+						text.NewValidationErrorRequired(property).Text, missing, keyword, property, nil)),
+				})
+			}
+			continue
+		}
+
+		expected := lookupPointer(loadSchema(schemas, leaf.SchemaURL), leaf.SchemaPtr)
+		actual := lookupPointer(string(document), leaf.InstancePtr)
+		e.Validations = append(e.Validations, &ValidationError{
+			ValidationError: leaf,
+			Messages: new(text.Messages).Add(text.NewErrorValidationSchema(
+				leaf.Message, leaf.InstancePtr, keyword, expected, actual)),
+		})
+	}
+
+	pointers := make([]string, len(e.Validations))
+	for k, v := range e.Validations {
+		pointers[k] = v.InstancePtr
+	}
+	e.Messages = new(text.Messages).Add(text.NewErrorValidationSchemaSummary(pointers))
+
+	return e
+}
+
+func validationErrorLeaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, validationErrorLeaves(cause)...)
+	}
+	return leaves
+}
+
+// schemaKeyword returns the keyword a schema pointer such as `#/properties/age/minimum` points to.
+func schemaKeyword(ptr string) string {
+	segments := strings.Split(strings.TrimPrefix(ptr, "#"), "/")
+	return unescapePointerSegment(segments[len(segments)-1])
+}
+
+func loadSchema(schemas map[string]string, u string) string {
+	u = strings.SplitN(u, "#", 2)[0]
+	if s, ok := schemas[u]; ok {
+		return s
+	}
+
+	// The schema is only needed to enrich the error, so it is not a problem if it can not be loaded.
+	var s string
+	if r, err := jsonschema.LoadURL(u); err == nil {
+		defer r.Close()
+		if raw, err := ioutil.ReadAll(r); err == nil {
+			s = string(raw)
+		}
+	}
+
+	schemas[u] = s
+	return s
+}
+
+// lookupPointer returns the decoded value of the JSON document at the given JSON pointer or nil if the
+// value does not exist.
+func lookupPointer(document, ptr string) interface{} {
+	ptr = strings.TrimPrefix(strings.TrimPrefix(ptr, "#"), "/")
+	if document == "" {
+		return nil
+	} else if ptr == "" {
+		return gjson.Parse(document).Value()
+	}
+
+	segments := strings.Split(ptr, "/")
+	for k, segment := range segments {
+		segments[k] = escapeGJSONPath(unescapePointerSegment(segment))
+	}
+
+	result := gjson.Get(document, strings.Join(segments, "."))
+	if !result.Exists() {
+		return nil
+	}
+	return result.Value()
+}
+
+func unescapePointerSegment(segment string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+}
+
+func escapeGJSONPath(segment string) string {
+	return strings.NewReplacer(`\`, `\\`, ".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`).Replace(segment)
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/text"
+)
+
+func TestStructuredValidationError(t *testing.T) {
+	href := "file://./stub/validator/structured.schema.json"
+
+	validate := func(t *testing.T, document string, opts ...func(*validatorOptions)) error {
+		err := NewValidator().Validate(href, json.RawMessage(document), opts...)
+		require.Error(t, err)
+		return err
+	}
+
+	t.Run("case=returns the JSON Schema error by default", func(t *testing.T) {
+		err := validate(t, `{"traits":{"email":"not-an-email","age":1}}`)
+		assert.False(t, errors.As(err, new(*StructuredValidationError)))
+	})
+
+	t.Run("case=reports every failed keyword", func(t *testing.T) {
+		err := validate(t, `{"traits":{"email":"not-an-email","name":{"first":"ab"},"age":1}}`, WithStructuredErrors())
+
+		var e *StructuredValidationError
+		require.True(t, errors.As(err, &e), "%+v", err)
+		assert.True(t, errors.As(err, new(*jsonschema.ValidationError)), "the JSON Schema error must remain accessible")
+
+		byPointer := map[string]text.Message{}
+		for _, v := range e.Validations {
+			require.Len(t, v.Messages, 1)
+			byPointer[v.InstancePtr] = v.Messages[0]
+		}
+		require.Len(t, byPointer, 3, "%+v", e.Validations)
+
+		for ptr, expected := range map[string]struct {
+			keyword  string
+			expected interface{}
+			actual   interface{}
+		}{
+			"#/traits/email":      {keyword: "format", expected: "email", actual: "not-an-email"},
+			"#/traits/name/first": {keyword: "minLength", expected: float64(3), actual: "ab"},
+			"#/traits/age":        {keyword: "minimum", expected: float64(18), actual: float64(1)},
+		} {
+			m, ok := byPointer[ptr]
+			require.True(t, ok, "%s", ptr)
+			assert.Equal(t, text.ErrorValidationSchema, m.ID)
+			assert.NotEmpty(t, m.Text)
+
+			ctx := gjson.ParseBytes(m.Context)
+			assert.Equal(t, ptr, ctx.Get("pointer").String())
+			assert.Equal(t, expected.keyword, ctx.Get("keyword").String())
+			assert.Equal(t, expected.expected, ctx.Get("expected").Value(), "%s", ptr)
+			assert.Equal(t, expected.actual, ctx.Get("actual").Value(), "%s", ptr)
+		}
+
+		require.Len(t, e.Messages, 1)
+		assert.Equal(t, text.ErrorValidationSchemaSummary, e.Messages[0].ID)
+		assert.Len(t, gjson.GetBytes(e.Messages[0].Context, "pointers").Array(), 3)
+	})
+
+	t.Run("case=reports missing properties individually", func(t *testing.T) {
+		err := validate(t, `{"traits":{}}`, WithStructuredErrors())
+
+		var e *StructuredValidationError
+		require.True(t, errors.As(err, &e), "%+v", err)
+		require.Len(t, e.Validations, 2)
+
+		var properties []string
+		for _, v := range e.Validations {
+			ctx := gjson.ParseBytes(v.Messages[0].Context)
+			assert.Equal(t, "required", ctx.Get("keyword").String())
+			properties = append(properties, ctx.Get("expected").String())
+		}
+		assert.ElementsMatch(t, []string{"email", "age"}, properties)
+	})
+}
+
+func TestLookupPointer(t *testing.T) {
+	document := `{"a":{"b.c":[1,{"d/e":"f"}],"g~h":true}}`
+	for ptr, expected := range map[string]interface{}{
+		"#/a/b.c/0":      float64(1),
+		"#/a/b.c/1/d~1e": "f",
+		"#/a/g~0h":       true,
+		"#/a/missing":    nil,
+		"/a/b.c/1/d~1e":  "f",
+	} {
+		assert.Equal(t, expected, lookupPointer(document, ptr), "%s", ptr)
+	}
+}
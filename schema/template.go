@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// templateMaxDepth stops recursive schemas from being expanded indefinitely.
+const templateMaxDepth = 32
+
+// TraitsTemplate is an identity whose traits contain the default values of the identity traits schema and an
+// empty value for all required traits without a default.
+//
+// swagger:model identityTraitsTemplate
+type TraitsTemplate struct {
+	// SchemaID is the ID of the identity traits schema.
+	//
+	// required: true
+	SchemaID string `json:"schema_id"`
+
+	// Traits is the template of the identity's traits.
+	//
+	// required: true
+	Traits interface{} `json:"traits"`
+}
+
+type templateBuilder struct {
+	schemas map[string]string
+}
+
+// NewTraitsTemplate derives a traits template from the identity schema at href. Values are taken from `default`
+// and `const`. Required properties without such a value are set to an empty value of their type: an empty string,
+// array, or object, false, or null. Optional properties are only included if they contain a default value.
+func NewTraitsTemplate(href string) (interface{}, error) {
+	b := &templateBuilder{schemas: map[string]string{}}
+
+	base, root, err := b.resolve(href, loadSchema(b.schemas, href), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	traits := gjson.Get(root, "properties.traits")
+	if !traits.Exists() {
+		return map[string]interface{}{}, nil
+	}
+
+	value, _, err := b.build(base, traits.Raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// resolve follows the `$ref` of a schema and returns the URL of the document the resolved schema is part of.
+func (b *templateBuilder) resolve(base, raw string, depth int) (string, string, error) {
+	if raw == "" {
+		return "", "", errors.Errorf("unable to load JSON Schema: %s", base)
+	}
+
+	ref := gjson.Get(raw, "$ref")
+	if !ref.Exists() {
+		return base, raw, nil
+	} else if depth > templateMaxDepth {
+		return "", "", errors.Errorf("unable to resolve JSON Schema reference %s: too many nested references", ref.String())
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	refURL, err := url.Parse(ref.String())
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	target := baseURL.ResolveReference(refURL)
+	fragment := target.Fragment
+	target.Fragment = ""
+	if refURL.Scheme == "" && refURL.Host == "" && refURL.Path == "" {
+		target = baseURL
+	}
+
+	document := loadSchema(b.schemas, target.String())
+	resolved := document
+	if ptr := strings.TrimPrefix(fragment, "/"); ptr != "" {
+		segments := strings.Split(ptr, "/")
+		for k, segment := range segments {
+			segments[k] = escapeGJSONPath(unescapePointerSegment(segment))
+		}
+		resolved = gjson.Get(document, strings.Join(segments, ".")).Raw
+	}
+
+	return b.resolve(target.String(), resolved, depth+1)
+}
+
+// build returns the template value of the schema and whether it contains a value from the schema.
+func (b *templateBuilder) build(base, raw string, depth int) (interface{}, bool, error) {
+	base, raw, err := b.resolve(base, raw, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s := gjson.Parse(raw)
+	if v := s.Get("default"); v.Exists() {
+		return v.Value(), true, nil
+	} else if v := s.Get("const"); v.Exists() {
+		return v.Value(), true, nil
+	}
+
+	var typ string
+	if t := s.Get("type"); t.IsArray() {
+		for _, candidate := range t.Array() {
+			if candidate.String() != "null" {
+				typ = candidate.String()
+				break
+			}
+		}
+	} else {
+		typ = t.String()
+	}
+
+	if typ == "" && s.Get("properties").Exists() {
+		typ = "object"
+	}
+
+	switch typ {
+	case "object":
+		result := map[string]interface{}{}
+		if depth > templateMaxDepth {
+			return result, false, nil
+		}
+
+		required := map[string]bool{}
+		for _, r := range s.Get("required").Array() {
+			required[r.String()] = true
+		}
+
+		var hasValue bool
+		var buildErr error
+		s.Get("properties").ForEach(func(key, property gjson.Result) bool {
+			value, ok, err := b.build(base, property.Raw, depth+1)
+			if err != nil {
+				buildErr = err
+				return false
+			}
+
+			if ok || required[key.String()] {
+				result[key.String()] = value
+			}
+			hasValue = hasValue || ok
+			return true
+		})
+		if buildErr != nil {
+			return nil, false, buildErr
+		}
+
+		return result, hasValue, nil
+	case "array":
+		return []interface{}{}, false, nil
+	case "string":
+		return "", false, nil
+	case "boolean":
+		return false, false, nil
+	default:
+		return nil, false, nil
+	}
+}
@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTraitsTemplate(t *testing.T) {
+	t.Run("case=applies defaults and adds required traits", func(t *testing.T) {
+		template, err := NewTraitsTemplate("file://./stub/template.schema.json")
+		require.NoError(t, err)
+
+		actual, err := json.Marshal(template)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+  "email": "",
+  "name": {"first": ""},
+  "newsletter": true,
+  "age": null,
+  "tags": [],
+  "address": {"country": "DE"}
+}`, string(actual))
+	})
+
+	t.Run("case=returns an empty object if the schema has no traits", func(t *testing.T) {
+		template, err := NewTraitsTemplate("file://./stub/identity.schema.json")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{}, template)
+	})
+
+	t.Run("case=fails if the schema can not be loaded", func(t *testing.T) {
+		_, err := NewTraitsTemplate("file://./stub/does-not-exist.schema.json")
+		require.Error(t, err)
+	})
+}
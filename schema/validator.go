@@ -3,6 +3,7 @@ package schema
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -25,7 +26,8 @@ func NewValidator() *Validator {
 }
 
 type validatorOptions struct {
-	e *ExtensionRunner
+	e          *ExtensionRunner
+	structured bool
 }
 
 func WithExtensionRunner(e *ExtensionRunner) func(*validatorOptions) {
@@ -34,6 +36,13 @@ func WithExtensionRunner(e *ExtensionRunner) func(*validatorOptions) {
 	}
 }
 
+// WithStructuredErrors returns a StructuredValidationError instead of the JSON Schema validation error.
+func WithStructuredErrors() func(*validatorOptions) {
+	return func(o *validatorOptions) {
+		o.structured = true
+	}
+}
+
 func (v *Validator) Validate(
 	href string,
 	document json.RawMessage,
@@ -54,7 +63,13 @@ func (v *Validator) Validate(
 		o.e.Register(compiler)
 	}
 
-	if err := compiler.AddResource(href, resource); err != nil {
+	defer resource.Close()
+	raw, err := ioutil.ReadAll(resource)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse validate JSON object against JSON schema.").WithDebugf("%s", err))
+	}
+
+	if err := compiler.AddResource(href, bytes.NewReader(raw)); err != nil {
 		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse validate JSON object against JSON schema.").WithDebugf("%s", err))
 	}
 
@@ -64,6 +79,9 @@ func (v *Validator) Validate(
 	}
 
 	if err := schema.Validate(bytes.NewBuffer(document)); err != nil {
+		if e := new(jsonschema.ValidationError); o.structured && errors.As(err, &e) {
+			return errors.WithStack(newStructuredValidationError(e, href, raw, document))
+		}
 		return errors.WithStack(err)
 	}
 
@@ -36,20 +36,36 @@ func NewManager(d managerDependencies) *Manager {
 }
 
 // Create is a simple helper that saves all errors in the store and returns the
-// error url, appending the error ID.
+// error url, appending the error ID. If an error was annotated with a strategy using
+// WithStrategy, the strategy's error url is used if configured.
 func (m *Manager) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, errs ...error) (string, error) {
-	for _, err := range errs {
+	persisted := make([]error, len(errs))
+	for k, err := range errs {
 		m.d.Logger().WithError(err).WithRequest(r).Errorf("An error occurred and is being forwarded to the error user interface.")
+
+		// Stored errors can be fetched using the public API, so they are redacted just like error responses.
+		persisted[k] = err
+		if err != nil && m.d.Configuration(ctx).RedactInternalErrors() {
+			persisted[k] = x.RedactInternalError(m.d.Logger(), r, err)
+		}
 	}
 
-	id, emerr := m.d.SelfServiceErrorPersister().Add(ctx, m.d.GenerateCSRFToken(r), errs...)
+	id, emerr := m.d.SelfServiceErrorPersister().Add(ctx, m.d.GenerateCSRFToken(r), persisted...)
 	if emerr != nil {
 		return "", emerr
 	}
 	q := url.Values{}
 	q.Set("error", id.String())
 
-	return urlx.CopyWithQuery(m.d.Configuration(ctx).SelfServiceFlowErrorURL(), q).String(), nil
+	to := m.d.Configuration(ctx).SelfServiceFlowErrorURL()
+	for _, err := range errs {
+		if strategy, ok := StrategyFromError(err); ok {
+			to = m.d.Configuration(ctx).SelfServiceStrategyErrorURL(strategy)
+			break
+		}
+	}
+
+	return urlx.CopyWithQuery(to, q).String(), nil
 }
 
 // Forward is a simple helper that saves all errors in the store and forwards the HTTP Request
@@ -0,0 +1,63 @@
+package errorx_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/x"
+)
+
+func TestManagerStrategyErrorURL(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceErrorUI, "https://www.ory.sh/error")
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".oidc.error_ui_url", "https://www.ory.sh/oidc-error")
+
+	create := func(t *testing.T, err error) *url.URL {
+		to, err := reg.SelfServiceErrorManager().Create(context.Background(), httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), err)
+		require.NoError(t, err)
+
+		u, err := url.Parse(to)
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("case=uses the global error ui without strategy", func(t *testing.T) {
+		u := create(t, errors.WithStack(herodot.ErrBadRequest))
+		assert.Equal(t, "/error", u.Path)
+	})
+
+	t.Run("case=uses the global error ui if the strategy has no override", func(t *testing.T) {
+		u := create(t, errorx.WithStrategy(errors.WithStack(herodot.ErrBadRequest), "password"))
+		assert.Equal(t, "/error", u.Path)
+	})
+
+	t.Run("case=uses the strategy error ui", func(t *testing.T) {
+		u := create(t, errorx.WithStrategy(errors.WithStack(herodot.ErrBadRequest.WithReason("provider misconfigured")), "oidc"))
+		assert.Equal(t, "/oidc-error", u.Path)
+
+		ec, err := reg.SelfServiceErrorPersister().Read(context.Background(), x.ParseUUID(u.Query().Get("error")))
+		require.NoError(t, err)
+		assert.Equal(t, "provider misconfigured", gjson.GetBytes(ec.Errors, "0.reason").String(), "%s", ec.Errors)
+	})
+
+	t.Run("case=strategy can be read from wrapped errors", func(t *testing.T) {
+		strategy, ok := errorx.StrategyFromError(errors.Wrap(errorx.WithStrategy(herodot.ErrBadRequest, "oidc"), "wrapped"))
+		assert.True(t, ok)
+		assert.Equal(t, "oidc", strategy)
+
+		_, ok = errorx.StrategyFromError(herodot.ErrBadRequest)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,36 @@
+package errorx
+
+import (
+	"github.com/pkg/errors"
+)
+
+type strategyError struct {
+	error
+	strategy string
+}
+
+// WithStrategy annotates the error with the self-service strategy (e.g. "password" or "oidc") it occurred in.
+// If the strategy has its own error UI configured, the Manager forwards the error there.
+func WithStrategy(err error, strategy string) error {
+	if err == nil || len(strategy) == 0 {
+		return err
+	}
+	return &strategyError{error: err, strategy: strategy}
+}
+
+func (e *strategyError) Unwrap() error {
+	return e.error
+}
+
+func (e *strategyError) Cause() error {
+	return e.error
+}
+
+// StrategyFromError returns the strategy the error was annotated with using WithStrategy.
+func StrategyFromError(err error) (string, bool) {
+	var e *strategyError
+	if errors.As(err, &e) {
+		return e.strategy, true
+	}
+	return "", false
+}
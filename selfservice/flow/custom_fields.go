@@ -0,0 +1,166 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/form"
+)
+
+// CustomFieldsKey is the key of custom fields in self-service forms and payloads, e.g. `custom_fields.company_name`.
+const CustomFieldsKey = "custom_fields"
+
+const customFieldsSchemaURL = "https://schemas.ory.sh/kratos/selfservice/flow/custom_fields.schema.json"
+
+// customFieldTypes maps the custom field types to JSON Schema types.
+var customFieldTypes = map[string]string{
+	"text":     "string",
+	"email":    "string",
+	"url":      "string",
+	"date":     "string",
+	"number":   "number",
+	"checkbox": "boolean",
+}
+
+// customFieldFormats maps the custom field types to JSON Schema formats.
+var customFieldFormats = map[string]string{
+	"email": "email",
+	"url":   "uri",
+	"date":  "date",
+}
+
+// ValidateCustomFieldDeclarations returns an error if the custom fields are declared more than once or
+// use options which do not apply to their type.
+func ValidateCustomFieldDeclarations(fields []config.SelfServiceCustomField) error {
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f.Name] {
+			return errors.Errorf("custom field %s is declared more than once", f.Name)
+		}
+		seen[f.Name] = true
+
+		if _, ok := customFieldTypes[f.Type]; !ok {
+			return errors.Errorf("custom field %s has unknown type %s", f.Name, f.Type)
+		}
+
+		if customFieldTypes[f.Type] != "string" && (len(f.Pattern) > 0 || f.MaxLength > 0) {
+			return errors.Errorf("custom field %s of type %s must not set pattern or max_length", f.Name, f.Type)
+		}
+
+		if len(f.Pattern) > 0 {
+			if _, err := regexp.Compile(f.Pattern); err != nil {
+				return errors.Wrapf(err, "custom field %s has an invalid pattern", f.Name)
+			}
+		}
+	}
+
+	_, err := compileCustomFieldsSchema(fields)
+	return err
+}
+
+// CustomFieldsSchema returns the JSON Schema of the custom fields' values.
+func CustomFieldsSchema(fields []config.SelfServiceCustomField) (json.RawMessage, error) {
+	properties := make(map[string]interface{}, len(fields))
+	required := []string{}
+	for _, f := range fields {
+		p := map[string]interface{}{"type": customFieldTypes[f.Type]}
+		if format, ok := customFieldFormats[f.Type]; ok {
+			p["format"] = format
+		}
+		if len(f.Pattern) > 0 {
+			p["pattern"] = f.Pattern
+		}
+		if f.MaxLength > 0 {
+			p["maxLength"] = f.MaxLength
+		}
+		if f.Required {
+			required = append(required, f.Name)
+			if f.Type == "checkbox" {
+				p["const"] = true
+			} else if p["type"] == "string" {
+				p["minLength"] = 1
+			}
+		}
+		properties[f.Name] = p
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	})
+	return raw, errors.WithStack(err)
+}
+
+// CustomFormFields returns the form fields of the custom fields.
+func CustomFormFields(fields []config.SelfServiceCustomField) form.Fields {
+	ff := make(form.Fields, len(fields))
+	for k, f := range fields {
+		ff[k] = form.Field{
+			Name:     CustomFieldsKey + "." + f.Name,
+			Type:     f.Type,
+			Pattern:  f.Pattern,
+			Required: f.Required,
+		}
+		if f.Type == "checkbox" {
+			ff[k].Value = false
+		}
+	}
+	return ff
+}
+
+// ValidateCustomFields validates the submitted values of the custom fields. Validation errors point to
+// `#/custom_fields/<name>` to be shown next to the respective form field.
+func ValidateCustomFields(fields []config.SelfServiceCustomField, values json.RawMessage) error {
+	s, err := compileCustomFieldsSchema(fields)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		values = json.RawMessage("{}")
+	}
+
+	document, err := sjson.SetRawBytes([]byte("{}"), CustomFieldsKey, values)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := s.Validate(bytes.NewBuffer(document)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func compileCustomFieldsSchema(fields []config.SelfServiceCustomField) (*jsonschema.Schema, error) {
+	raw, err := CustomFieldsSchema(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err = sjson.SetRawBytes([]byte(`{"type":"object"}`), "properties."+CustomFieldsKey, raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(customFieldsSchemaURL, bytes.NewBuffer(raw)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s, err := compiler.Compile(customFieldsSchemaURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,118 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow"
+)
+
+func TestValidateCustomFieldDeclarations(t *testing.T) {
+	for k, tc := range []struct {
+		fields []config.SelfServiceCustomField
+		err    string
+	}{
+		{fields: []config.SelfServiceCustomField{}},
+		{fields: []config.SelfServiceCustomField{
+			{Name: "company_name", Type: "text", Pattern: "^[a-z]+$", MaxLength: 10},
+			{Name: "accept_terms", Type: "checkbox", Required: true},
+		}},
+		{
+			fields: []config.SelfServiceCustomField{{Name: "company_name", Type: "text"}, {Name: "company_name", Type: "email"}},
+			err:    "custom field company_name is declared more than once",
+		},
+		{
+			fields: []config.SelfServiceCustomField{{Name: "company_name", Type: "textarea"}},
+			err:    "custom field company_name has unknown type textarea",
+		},
+		{
+			fields: []config.SelfServiceCustomField{{Name: "accept_terms", Type: "checkbox", Pattern: "^true$"}},
+			err:    "custom field accept_terms of type checkbox must not set pattern or max_length",
+		},
+		{
+			fields: []config.SelfServiceCustomField{{Name: "company_name", Type: "text", Pattern: "^[a-z"}},
+			err:    "custom field company_name has an invalid pattern",
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			err := flow.ValidateCustomFieldDeclarations(tc.fields)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	fields := []config.SelfServiceCustomField{
+		{Name: "company_name", Type: "text", MaxLength: 10},
+		{Name: "website", Type: "url"},
+		{Name: "accept_terms", Type: "checkbox", Required: true},
+	}
+
+	for k, tc := range []struct {
+		values  string
+		pointer string
+	}{
+		{values: `{"accept_terms":true}`},
+		{values: `{"company_name":"ORY","website":"https://www.ory.sh/","accept_terms":true}`},
+		{values: ``, pointer: "#/custom_fields/accept_terms"},
+		{values: `{"accept_terms":false}`, pointer: "#/custom_fields/accept_terms"},
+		{values: `{"accept_terms":true,"company_name":"a very long company name"}`, pointer: "#/custom_fields/company_name"},
+		{values: `{"accept_terms":true,"website":"not a url"}`, pointer: "#/custom_fields/website"},
+		{values: `{"accept_terms":true,"unknown":"field"}`, pointer: "#/custom_fields"},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			err := flow.ValidateCustomFields(fields, json.RawMessage(tc.values))
+			if tc.pointer == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var e *jsonschema.ValidationError
+			require.True(t, errors.As(err, &e), "%+v", err)
+
+			var pointers []string
+			var collect func(e *jsonschema.ValidationError)
+			collect = func(e *jsonschema.ValidationError) {
+				pointers = append(pointers, e.InstancePtr)
+				if required, ok := e.Context.(*jsonschema.ValidationErrorContextRequired); ok {
+					pointers = append(pointers, required.Missing...)
+				}
+				for _, c := range e.Causes {
+					collect(c)
+				}
+			}
+			collect(e)
+			assert.Contains(t, pointers, tc.pointer)
+		})
+	}
+}
+
+func TestCustomFormFields(t *testing.T) {
+	fields := flow.CustomFormFields([]config.SelfServiceCustomField{
+		{Name: "company_name", Type: "text", Pattern: "^[a-z]+$"},
+		{Name: "accept_terms", Type: "checkbox", Required: true},
+	})
+
+	require.Len(t, fields, 2)
+	assert.Equal(t, "custom_fields.company_name", fields[0].Name)
+	assert.Equal(t, "text", fields[0].Type)
+	assert.Equal(t, "^[a-z]+$", fields[0].Pattern)
+	assert.Nil(t, fields[0].Value)
+	assert.Equal(t, "custom_fields.accept_terms", fields[1].Name)
+	assert.Equal(t, "checkbox", fields[1].Type)
+	assert.True(t, fields[1].Required)
+	assert.Equal(t, false, fields[1].Value)
+}
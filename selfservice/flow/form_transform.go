@@ -0,0 +1,261 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// FormTransformFlows are the flow types which support a form transform.
+var FormTransformFlows = []string{"login", "registration", "settings"}
+
+// formTransformMaxBodySize is the maximum size in bytes of request bodies which are transformed.
+const formTransformMaxBodySize = 1 << 20
+
+type (
+	formTransformerDependencies interface {
+		config.Providers
+		x.WriterProvider
+		x.HTTPClientProvider
+	}
+	FormTransformerProvider interface {
+		FormTransformer() *FormTransformer
+	}
+
+	// FormTransformer remaps the values submitted to the login, registration, and settings flows using the
+	// Jsonnet transform located at `selfservice.flows.<flow>.form_transform_url` before the strategy
+	// decodes and validates them.
+	FormTransformer struct {
+		d formTransformerDependencies
+		f *fetcher.Fetcher
+
+		l        sync.Mutex
+		snippets map[string]string
+	}
+
+	// FormTransformInput is passed to the Jsonnet transform as the external variable `ctx`.
+	FormTransformInput struct {
+		// Flow is the flow type, for example `login`.
+		Flow string `json:"flow"`
+
+		// Method is the submitted method, for example `password`.
+		Method string `json:"method"`
+
+		// Values are the submitted values keyed by their form field name, for example `traits.email`.
+		Values map[string]interface{} `json:"values"`
+	}
+)
+
+func NewFormTransformer(d formTransformerDependencies) *FormTransformer {
+	return &FormTransformer{d: d, f: fetcher.NewFetcher(fetcher.WithClient(d.HTTPClient())), snippets: map[string]string{}}
+}
+
+// Validate fetches the Jsonnet transform of every flow type and makes sure that it can be parsed.
+func (t *FormTransformer) Validate(ctx context.Context) error {
+	for _, flowType := range FormTransformFlows {
+		location := t.d.Configuration(ctx).SelfServiceFlowFormTransformURL(flowType)
+		if location == "" {
+			continue
+		}
+
+		snippet, err := t.load(location)
+		if err != nil {
+			return err
+		}
+
+		if _, err := jsonnet.SnippetToAST(location, snippet); err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the %s form transform: %s", flowType, err))
+		}
+	}
+
+	return nil
+}
+
+func (t *FormTransformer) load(location string) (string, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if snippet, ok := t.snippets[location]; ok {
+		return snippet, nil
+	}
+
+	jn, err := t.f.Fetch(location)
+	if err != nil {
+		return "", err
+	}
+
+	t.snippets[location] = jn.String()
+	return t.snippets[location], nil
+}
+
+// formTransformTarget returns the flow type and method of submissions to `/self-service/<flow>/methods/<method>`.
+func formTransformTarget(r *http.Request) (flowType, method string, ok bool) {
+	if r.Method != http.MethodPost {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) != 4 || segments[0] != "self-service" || segments[2] != "methods" {
+		return "", "", false
+	}
+
+	for _, f := range FormTransformFlows {
+		if f == segments[1] {
+			return f, segments[3], true
+		}
+	}
+	return "", "", false
+}
+
+func (t *FormTransformer) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	flowType, method, ok := formTransformTarget(r)
+	if !ok {
+		next(w, r)
+		return
+	}
+
+	location := t.d.Configuration(r.Context()).SelfServiceFlowFormTransformURL(flowType)
+	if location == "" {
+		next(w, r)
+		return
+	}
+
+	if err := t.Transform(r, location, &FormTransformInput{Flow: flowType, Method: method}); err != nil {
+		t.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	next(w, r)
+}
+
+// Transform evaluates the Jsonnet transform and replaces the request body with the values it returned. Only
+// URL-encoded forms and JSON payloads are transformed. The CSRF token of URL-encoded forms is always kept.
+func (t *FormTransformer) Transform(r *http.Request, location string, in *FormTransformInput) error {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != "application/x-www-form-urlencoded" && contentType != "application/json" {
+		return nil
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(r.Body, formTransformMaxBodySize+1))
+	if err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to read the request body: %s", err))
+	}
+	_ = r.Body.Close()
+
+	if len(raw) > formTransformMaxBodySize {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The request body must not be larger than %d bytes.", formTransformMaxBodySize))
+	}
+
+	var form url.Values
+	if contentType == "application/json" {
+		in.Values = map[string]interface{}{}
+		if len(bytes.TrimSpace(raw)) > 0 {
+			if err := json.Unmarshal(raw, &in.Values); err != nil {
+				return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err))
+			}
+		}
+	} else {
+		form, err = url.ParseQuery(string(raw))
+		if err != nil {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err))
+		}
+
+		in.Values = make(map[string]interface{}, len(form))
+		for k, v := range form {
+			if len(v) == 1 {
+				in.Values[k] = v[0]
+			} else {
+				in.Values[k] = v
+			}
+		}
+	}
+
+	snippet, err := t.load(location)
+	if err != nil {
+		return err
+	}
+
+	input, err := json.Marshal(in)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("ctx", string(input))
+	evaluated, err := vm.EvaluateSnippet(location, snippet)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the %s form transform: %s", in.Flow, err))
+	}
+
+	values := gjson.Get(evaluated, "values")
+	if !values.IsObject() {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The %s form transform did not return an object for key values.", in.Flow))
+	}
+
+	var body []byte
+	if contentType == "application/json" {
+		body = []byte(values.Raw)
+	} else {
+		transformed, err := formTransformValues(values)
+		if err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The %s form transform returned invalid values: %s", in.Flow, err))
+		}
+		if token, ok := form["csrf_token"]; ok {
+			transformed["csrf_token"] = token
+		}
+		body = []byte(transformed.Encode())
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Form = nil
+	r.PostForm = nil
+	return nil
+}
+
+// formTransformValues converts the values returned by the transform to form values. Arrays become repeated
+// values and null values are omitted.
+func formTransformValues(values gjson.Result) (url.Values, error) {
+	out := url.Values{}
+	var err error
+	values.ForEach(func(key, value gjson.Result) bool {
+		items := []gjson.Result{value}
+		if value.IsArray() {
+			items = value.Array()
+		}
+
+		for _, item := range items {
+			switch item.Type {
+			case gjson.Null:
+			case gjson.String, gjson.Number, gjson.True, gjson.False:
+				out.Add(key.String(), item.String())
+			default:
+				err = fmt.Errorf("value of key %s must be a string, number, boolean, or an array of those", key.String())
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package flow_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+)
+
+func TestFormTransformer(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	jsonnetURL := func(snippet string) string {
+		return "base64://" + base64.StdEncoding.EncodeToString([]byte(snippet))
+	}
+
+	conf.MustSet(config.ViperKeySelfServiceLoginFormTransformURL, jsonnetURL(`
+local ctx = std.extVar('ctx');
+{
+  values: {
+    identifier: ctx.values.username,
+    password: ctx.values.password,
+    method: ctx.method,
+  },
+}`))
+	conf.MustSet(config.ViperKeySelfServiceRegistrationFormTransformURL, jsonnetURL(`
+local ctx = std.extVar('ctx');
+{
+  values: ctx.values + { 'traits.email': std.asciiLower(ctx.values['traits.email']) },
+}`))
+	t.Cleanup(func() {
+		conf.MustSet(config.ViperKeySelfServiceLoginFormTransformURL, "")
+		conf.MustSet(config.ViperKeySelfServiceRegistrationFormTransformURL, "")
+		conf.MustSet(config.ViperKeySelfServiceSettingsFormTransformURL, "")
+	})
+
+	do := func(t *testing.T, method, path, contentType, body string) (int, string, *http.Request) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+
+		var received *http.Request
+		var receivedBody string
+		reg.FormTransformer().ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			raw, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			received, receivedBody = r, string(raw)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		if w.Code != http.StatusNoContent {
+			return w.Code, w.Body.String(), nil
+		}
+		return w.Code, receivedBody, received
+	}
+
+	t.Run("case=validates the transforms", func(t *testing.T) {
+		require.NoError(t, reg.FormTransformer().Validate(context.Background()))
+	})
+
+	t.Run("case=transforms URL-encoded forms and keeps the CSRF token", func(t *testing.T) {
+		code, body, r := do(t, "POST", "/self-service/login/methods/password?flow=123", "application/x-www-form-urlencoded",
+			url.Values{"username": {"foo@bar.com"}, "password": {"secret"}, "csrf_token": {"token"}}.Encode())
+		require.Equal(t, http.StatusNoContent, code, body)
+
+		values, err := url.ParseQuery(body)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"identifier": {"foo@bar.com"},
+			"password":   {"secret"},
+			"method":     {"password"},
+			"csrf_token": {"token"},
+		}, values)
+		assert.EqualValues(t, len(body), r.ContentLength)
+		assert.Equal(t, "123", r.URL.Query().Get("flow"))
+	})
+
+	t.Run("case=transforms JSON payloads", func(t *testing.T) {
+		code, body, _ := do(t, "POST", "/self-service/registration/methods/password", "application/json",
+			`{"traits.email":"FOO@BAR.COM","password":"secret"}`)
+		require.Equal(t, http.StatusNoContent, code, body)
+		assert.JSONEq(t, `{"traits.email":"foo@bar.com","password":"secret"}`, body)
+	})
+
+	t.Run("case=rejects bodies above the size limit", func(t *testing.T) {
+		code, body, _ := do(t, "POST", "/self-service/login/methods/password", "application/x-www-form-urlencoded",
+			url.Values{"password": {strings.Repeat("a", 1<<20)}}.Encode())
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "must not be larger than")
+	})
+
+	t.Run("case=is scoped per flow type", func(t *testing.T) {
+		payload := url.Values{"username": {"foo@bar.com"}}.Encode()
+		for _, path := range []string{
+			"/self-service/settings/methods/password",
+			"/self-service/recovery/methods/link",
+			"/self-service/login/flows",
+		} {
+			code, body, _ := do(t, "POST", path, "application/x-www-form-urlencoded", payload)
+			require.Equal(t, http.StatusNoContent, code, body)
+			assert.Equal(t, payload, body, path)
+		}
+
+		code, body, _ := do(t, "GET", "/self-service/login/methods/password", "application/x-www-form-urlencoded", payload)
+		require.Equal(t, http.StatusNoContent, code, body)
+		assert.Equal(t, payload, body)
+	})
+
+	t.Run("case=fails if the transform does not return values", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceSettingsFormTransformURL, jsonnetURL(`{ traits: {} }`))
+		code, body, _ := do(t, "POST", "/self-service/settings/methods/profile", "application/json", `{}`)
+		assert.Equal(t, http.StatusInternalServerError, code)
+		assert.Contains(t, body, "did not return an object for key values")
+	})
+
+	t.Run("case=fails validation on invalid transforms", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceSettingsFormTransformURL, jsonnetURL(`{ values: `))
+		require.Error(t, reg.FormTransformer().Validate(context.Background()))
+	})
+}
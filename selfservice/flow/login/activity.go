@@ -0,0 +1,173 @@
+package login
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/geoip"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/text"
+)
+
+const RouteRecentActivity = "/self-service/login/activity"
+
+// A login of the current identity
+//
+// The IP address and the submitted identifier are not included.
+//
+// swagger:model loginActivity
+type Activity struct {
+	// Time is the time of the login attempt.
+	//
+	// required: true
+	Time time.Time `json:"time"`
+
+	// Method is the login method, for example `password` or `oidc`.
+	//
+	// required: true
+	Method identity.CredentialsType `json:"method"`
+
+	// Outcome is either `success` or `failure`.
+	//
+	// required: true
+	Outcome string `json:"outcome"`
+
+	// Location is the coarse location of the client's IP address. It is null if `session.geolocation` is
+	// disabled or the location is unknown.
+	Location *geoip.Location `json:"location"`
+
+	// Device is the browser and operating system of the client, for example `Firefox on Linux`. It is empty
+	// if they are unknown.
+	//
+	// required: true
+	Device string `json:"device"`
+}
+
+// A list of logins.
+// swagger:response loginActivityList
+// nolint:deadcode,unused
+type loginActivityListResponse struct {
+	// in: body
+	// required: true
+	// type: array
+	Body []Activity
+}
+
+// nolint:deadcode,unused
+// swagger:parameters listSelfServiceLoginActivity
+type listSelfServiceLoginActivityParameters struct {
+	// in: header
+	Cookie string `json:"Cookie"`
+
+	// in: authorization
+	Authorization string `json:"Authorization"`
+}
+
+var (
+	activityOperatingSystems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"CrOS", "ChromeOS"},
+		{"Windows", "Windows"},
+		{"Macintosh", "macOS"},
+		{"Linux", "Linux"},
+	}
+	activityBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+)
+
+// activityDevice reduces the user agent to its browser and operating system so that version numbers and
+// other details which help fingerprinting the client are not returned.
+func activityDevice(userAgent string) string {
+	var browser, os string
+	for _, b := range activityBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, o := range activityOperatingSystems {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	}
+	return os
+}
+
+// swagger:route GET /self-service/login/activity public listSelfServiceLoginActivity
+//
+// List the Recent Logins of the Current Identity
+//
+// This endpoint lists the most recent logins of the identity the session belongs to, newest first, so that
+// users can spot logins they do not recognize. It is disabled by default and can be enabled with
+// `selfservice.flows.login.recent_activity.enabled`, which requires `selfservice.flows.login.attempt_log.enabled`.
+//
+// Failed attempts are only included if `selfservice.flows.login.recent_activity.include_failed` is set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: loginActivityList
+//       401: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) listActivity(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	conf := h.d.Configuration(r.Context()).SelfServiceFlowLoginRecentActivity()
+	if !conf.Enabled {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The recent login activity is disabled.")))
+		return
+	}
+
+	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	f := AttemptLogFilter{IdentityID: s.IdentityID}
+	if !conf.IncludeFailed {
+		f.Outcome = AttemptOutcomeSuccess
+	}
+
+	logs, err := h.d.LoginAttemptLogPersister().ListLoginAttemptLogs(r.Context(), f, 0, conf.Limit)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	activity := make([]Activity, len(logs))
+	for k := range logs {
+		activity[k] = Activity{
+			Time:     logs[k].CreatedAt,
+			Method:   logs[k].Method,
+			Outcome:  logs[k].Outcome,
+			Location: h.d.GeoLocator().Locate(r.Context(), logs[k].IPAddress),
+			Device:   activityDevice(logs[k].UserAgent),
+		}
+	}
+
+	h.d.Writer().Write(w, r, activity)
+}
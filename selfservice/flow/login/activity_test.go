@@ -0,0 +1,101 @@
+package login_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/x"
+)
+
+func TestRecentActivity(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/login.schema.json")
+	public, _ := testhelpers.NewKratosServerWithCSRF(t, reg)
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	client := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, i)
+
+	now := time.Now().UTC().Round(time.Second)
+	newLog := func(identityID uuid.UUID, outcome, userAgent string, createdAt time.Time) login.AttemptLog {
+		return login.AttemptLog{
+			IdentityID: uuid.NullUUID{UUID: identityID, Valid: true},
+			Identifier: "foo@ory.sh",
+			Method:     identity.CredentialsTypePassword,
+			Outcome:    outcome,
+			IPAddress:  "192.0.2.1",
+			UserAgent:  userAgent,
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		}
+	}
+	require.NoError(t, reg.LoginAttemptLogPersister().CreateLoginAttemptLogs(context.Background(), []login.AttemptLog{
+		newLog(i.ID, login.AttemptOutcomeSuccess, "Mozilla/5.0 (X11; Linux x86_64; rv:85.0) Gecko/20100101 Firefox/85.0", now.Add(-3*time.Hour)),
+		newLog(i.ID, login.AttemptOutcomeFailure, "curl/7.68.0", now.Add(-2*time.Hour)),
+		newLog(i.ID, login.AttemptOutcomeSuccess, "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1", now.Add(-time.Hour)),
+		newLog(x.NewUUID(), login.AttemptOutcomeSuccess, "curl/7.68.0", now),
+	}))
+
+	get := func(t *testing.T, c *http.Client, expectedCode int) gjson.Result {
+		res, body := x.EasyGet(t, c, public.URL+login.RouteRecentActivity)
+		require.Equal(t, expectedCode, res.StatusCode, "%s", body)
+		return gjson.ParseBytes(body)
+	}
+
+	t.Run("case=is disabled by default", func(t *testing.T) {
+		get(t, client, http.StatusNotFound)
+	})
+
+	conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityEnabled, true)
+	t.Cleanup(func() {
+		conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityEnabled, false)
+		conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityIncludeFailed, false)
+		conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityLimit, 10)
+	})
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		get(t, http.DefaultClient, http.StatusUnauthorized)
+	})
+
+	t.Run("case=lists the successful logins of the identity", func(t *testing.T) {
+		body := get(t, client, http.StatusOK)
+		require.Len(t, body.Array(), 2, "%s", body.Raw)
+		assert.Equal(t, "Safari on iOS", body.Get("0.device").String(), "%s", body.Raw)
+		assert.Equal(t, "Firefox on Linux", body.Get("1.device").String(), "%s", body.Raw)
+		assert.Equal(t, "password", body.Get("0.method").String(), "%s", body.Raw)
+		assert.Equal(t, login.AttemptOutcomeSuccess, body.Get("0.outcome").String(), "%s", body.Raw)
+		assert.True(t, body.Get("0.location").Type == gjson.Null, "%s", body.Raw)
+	})
+
+	t.Run("case=redacts the IP address and identifier", func(t *testing.T) {
+		body := get(t, client, http.StatusOK)
+		assert.NotContains(t, body.Raw, "192.0.2.1")
+		assert.NotContains(t, body.Raw, "foo@ory.sh")
+	})
+
+	t.Run("case=includes failed attempts if enabled", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityIncludeFailed, true)
+		body := get(t, client, http.StatusOK)
+		require.Len(t, body.Array(), 3, "%s", body.Raw)
+		assert.Equal(t, login.AttemptOutcomeFailure, body.Get("1.outcome").String(), "%s", body.Raw)
+		assert.Empty(t, body.Get("1.device").String(), "%s", body.Raw)
+	})
+
+	t.Run("case=limits the number of logins", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceLoginRecentActivityLimit, 1)
+		body := get(t, client, http.StatusOK)
+		require.Len(t, body.Array(), 1, "%s", body.Raw)
+		assert.Equal(t, "Safari on iOS", body.Get("0.device").String(), "%s", body.Raw)
+	})
+}
@@ -0,0 +1,276 @@
+package login
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/corp"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	AttemptOutcomeSuccess = "success"
+	AttemptOutcomeFailure = "failure"
+
+	// AttemptReasonInvalidCredentials is the reason of attempts with an unknown identifier or a wrong password.
+	AttemptReasonInvalidCredentials = "invalid_credentials"
+	// AttemptReasonThrottled is the reason of attempts which were blocked by the login throttling.
+	AttemptReasonThrottled = "throttled"
+
+	attemptLogReasonMaxLength     = 255
+	attemptLogIdentifierMaxLength = 255
+	attemptLogIPAddressMaxLength  = 64
+
+	// attemptLogDrainTimeout limits how long writing the queued login attempts may take on shutdown.
+	attemptLogDrainTimeout = 10 * time.Second
+)
+
+type (
+	// AttemptLogFilter limits the login attempts which are listed. Zero values do not filter.
+	AttemptLogFilter struct {
+		IdentityID uuid.UUID
+		Outcome    string
+		From       time.Time
+		Until      time.Time
+	}
+
+	// AttemptLogPersister stores the login attempts recorded by the AttemptLogger.
+	AttemptLogPersister interface {
+		CreateLoginAttemptLogs(ctx context.Context, logs []AttemptLog) error
+		ListLoginAttemptLogs(ctx context.Context, f AttemptLogFilter, page, perPage int) ([]AttemptLog, error)
+		CountLoginAttemptLogs(ctx context.Context, f AttemptLogFilter) (int64, error)
+		DeleteLoginAttemptLogsBefore(ctx context.Context, before time.Time) error
+	}
+	AttemptLogPersistenceProvider interface {
+		LoginAttemptLogPersister() AttemptLogPersister
+	}
+
+	attemptLoggerDependencies interface {
+		config.Providers
+		x.LoggingProvider
+		AttemptLogPersistenceProvider
+	}
+	AttemptLoggerProvider interface {
+		LoginAttemptLogger() *AttemptLogger
+	}
+
+	// AttemptLogger records login attempts if `selfservice.flows.login.attempt_log.enabled` is set. Attempts
+	// are written in batches in the background by Work so that logins never wait for the database. If the buffer
+	// is full, the attempt is dropped.
+	AttemptLogger struct {
+		d    attemptLoggerDependencies
+		logs chan *AttemptLog
+	}
+
+	// A login attempt
+	//
+	// swagger:model loginAttempt
+	AttemptLog struct {
+		// required: true
+		ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+		// IdentityID is the identity which tried to sign in. It is null if the identifier is unknown.
+		IdentityID uuid.NullUUID `json:"identity_id" faker:"-" db:"identity_id"`
+
+		// Identifier is the identifier which was submitted, for example an email address.
+		//
+		// required: true
+		Identifier string `json:"identifier" db:"identifier"`
+
+		// Method is the login method, for example `password` or `oidc`.
+		//
+		// required: true
+		Method identity.CredentialsType `json:"method" db:"method"`
+
+		// Outcome is either `success` or `failure`.
+		//
+		// required: true
+		Outcome string `json:"outcome" db:"outcome"`
+
+		// Reason explains why the attempt failed. It is empty for successful attempts.
+		Reason string `json:"reason" db:"reason"`
+
+		IPAddress string `json:"ip_address" db:"ip_address"`
+		UserAgent string `json:"user_agent" db:"user_agent"`
+
+		// CreatedAt is the time of the attempt.
+		//
+		// required: true
+		CreatedAt time.Time `json:"created_at" db:"created_at"`
+		// UpdatedAt is a helper struct field for gobuffalo.pop.
+		UpdatedAt   time.Time `json:"-" db:"updated_at"`
+		PartitionID string    `json:"-" faker:"-" db:"partition_id"`
+	}
+)
+
+func (a AttemptLog) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "login_attempts")
+}
+
+// truncate shortens the value to at most length characters. Invalid UTF-8 sequences are removed so that the value
+// can be stored in the database.
+func truncate(value string, length int) string {
+	value = strings.ToValidUTF8(value, "")
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+	return string([]rune(value)[:length])
+}
+
+// NewAttemptLog creates a login attempt of the request. The identity ID may be uuid.Nil if the identifier is unknown.
+func NewAttemptLog(r *http.Request, method identity.CredentialsType, identifier string, identityID uuid.UUID, outcome, reason string) *AttemptLog {
+	now := time.Now().UTC()
+	return &AttemptLog{
+		IdentityID:  uuid.NullUUID{UUID: identityID, Valid: identityID != uuid.Nil},
+		Identifier:  truncate(identifier, attemptLogIdentifierMaxLength),
+		Method:      method,
+		Outcome:     outcome,
+		Reason:      truncate(reason, attemptLogReasonMaxLength),
+		UserAgent:   strings.ToValidUTF8(r.UserAgent(), ""),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		PartitionID: x.PartitionID(r.Context()),
+	}
+}
+
+// AttemptFailureReason returns the reason of the error without its stack trace or debug information.
+func AttemptFailureReason(err error) string {
+	var reasoner interface{ Reason() string }
+	if errors.As(err, &reasoner) && reasoner.Reason() != "" {
+		return reasoner.Reason()
+	}
+	return err.Error()
+}
+
+func NewAttemptLogger(d attemptLoggerDependencies) *AttemptLogger {
+	return &AttemptLogger{
+		d:    d,
+		logs: make(chan *AttemptLog, d.Configuration(context.Background()).SelfServiceFlowLoginAttemptLog().BufferSize),
+	}
+}
+
+// Record queues the login attempt with the client IP address of the request. It is a no-op unless the attempt log
+// is enabled.
+func (l *AttemptLogger) Record(r *http.Request, a *AttemptLog) {
+	conf := l.d.Configuration(r.Context())
+	if !conf.SelfServiceFlowLoginAttemptLog().Enabled {
+		return
+	}
+
+	a.IPAddress = truncate(x.TrustedClientIP(r, conf.PublicClientIP()), attemptLogIPAddressMaxLength)
+
+	select {
+	case l.logs <- a:
+	default:
+		l.d.Logger().
+			WithField("login_method", a.Method).
+			WithField("outcome", a.Outcome).
+			Warn("Dropped login attempt because the login attempt log buffer is full.")
+	}
+}
+
+// RecordIdentity records the login attempt of a known identity. The identifier is the first identifier of the
+// identity's credentials of the given type.
+func (l *AttemptLogger) RecordIdentity(r *http.Request, method identity.CredentialsType, i *identity.Identity, outcome, reason string) {
+	var identifier string
+	if c, ok := i.GetCredentials(method); ok && len(c.Identifiers) > 0 {
+		identifier = c.Identifiers[0]
+	}
+	l.Record(r, NewAttemptLog(r, method, identifier, i.ID, outcome, reason))
+}
+
+// write writes the queued login attempts in batches until the context is cancelled. The attempts which are still
+// queued at that point are written before write returns.
+func (l *AttemptLogger) write(ctx context.Context) {
+	conf := l.d.Configuration(ctx).SelfServiceFlowLoginAttemptLog()
+	batch := make([]AttemptLog, 0, conf.BatchSize)
+	ticker := time.NewTicker(conf.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := l.d.LoginAttemptLogPersister().CreateLoginAttemptLogs(ctx, batch); err != nil {
+			l.d.Logger().
+				WithError(err).
+				WithField("login_attempts", len(batch)).
+				Warn("Unable to write login attempts.")
+		}
+		batch = make([]AttemptLog, 0, conf.BatchSize)
+	}
+
+	for {
+		select {
+		case a := <-l.logs:
+			batch = append(batch, *a)
+			if len(batch) >= conf.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			// The context is cancelled already, so the remaining attempts are written using a new one.
+			drainCtx, cancel := context.WithTimeout(context.Background(), attemptLogDrainTimeout)
+			defer cancel()
+			for {
+				select {
+				case a := <-l.logs:
+					batch = append(batch, *a)
+					if len(batch) >= conf.BatchSize {
+						flush(drainCtx)
+					}
+				default:
+					flush(drainCtx)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Purge deletes the login attempts older than `selfservice.flows.login.attempt_log.retention`.
+func (l *AttemptLogger) Purge(ctx context.Context) error {
+	retention := l.d.Configuration(ctx).SelfServiceFlowLoginAttemptLog().Retention
+	if retention <= 0 {
+		return nil
+	}
+	return l.d.LoginAttemptLogPersister().DeleteLoginAttemptLogsBefore(ctx, time.Now().UTC().Add(-retention))
+}
+
+// Work writes the recorded login attempts and purges expired ones periodically until the context is cancelled.
+// It returns once the queued login attempts were written.
+func (l *AttemptLogger) Work(ctx context.Context) error {
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		l.write(ctx)
+	}()
+	defer func() { <-written }()
+
+	for {
+		if l.d.Configuration(ctx).SelfServiceFlowLoginAttemptLog().Enabled {
+			if err := l.Purge(ctx); err != nil {
+				l.d.Logger().WithError(err).Error("Unable to purge expired login attempts.")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(l.d.Configuration(ctx).SelfServiceFlowLoginAttemptLog().CleanupInterval):
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package login_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/x"
+)
+
+func TestAttemptLogger(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogFlushInterval, "10ms")
+
+	newRequest := func() *http.Request {
+		r := &http.Request{Header: http.Header{}, RemoteAddr: "192.0.2.1:4321", URL: &url.URL{}}
+		r.Header.Set("User-Agent", "attempt-log-test")
+		return r.WithContext(context.Background())
+	}
+
+	list := func(t *testing.T, f login.AttemptLogFilter) []login.AttemptLog {
+		logs, err := reg.LoginAttemptLogPersister().ListLoginAttemptLogs(context.Background(), f, 0, 100)
+		require.NoError(t, err)
+		return logs
+	}
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type:        identity.CredentialsTypePassword,
+		Identifiers: []string{"foo@ory.sh"},
+		Config:      []byte(`{}`),
+	})
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	t.Run("case=does not record attempts by default", func(t *testing.T) {
+		reg.LoginAttemptLogger().RecordIdentity(newRequest(), identity.CredentialsTypePassword, i, login.AttemptOutcomeSuccess, "")
+		time.Sleep(50 * time.Millisecond)
+		assert.Empty(t, list(t, login.AttemptLogFilter{IdentityID: i.ID}))
+	})
+
+	conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogEnabled, true)
+	t.Cleanup(func() {
+		conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogEnabled, false)
+	})
+
+	t.Run("case=records attempts in the background", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			require.NoError(t, reg.LoginAttemptLogger().Work(ctx))
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		reg.LoginAttemptLogger().RecordIdentity(newRequest(), identity.CredentialsTypePassword, i, login.AttemptOutcomeSuccess, "")
+		reg.LoginAttemptLogger().Record(newRequest(), login.NewAttemptLog(newRequest(), identity.CredentialsTypePassword,
+			"unknown@ory.sh", uuid.Nil, login.AttemptOutcomeFailure, login.AttemptReasonInvalidCredentials))
+
+		var logs []login.AttemptLog
+		require.Eventually(t, func() bool {
+			logs = list(t, login.AttemptLogFilter{})
+			return len(logs) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+
+		known := list(t, login.AttemptLogFilter{IdentityID: i.ID})
+		require.Len(t, known, 1)
+		assert.Equal(t, "foo@ory.sh", known[0].Identifier)
+		assert.Equal(t, identity.CredentialsTypePassword, known[0].Method)
+		assert.Equal(t, login.AttemptOutcomeSuccess, known[0].Outcome)
+		assert.Equal(t, "192.0.2.1", known[0].IPAddress)
+		assert.Equal(t, "attempt-log-test", known[0].UserAgent)
+
+		failed := list(t, login.AttemptLogFilter{Outcome: login.AttemptOutcomeFailure})
+		require.Len(t, failed, 1)
+		assert.Equal(t, "unknown@ory.sh", failed[0].Identifier)
+		assert.False(t, failed[0].IdentityID.Valid)
+		assert.Equal(t, login.AttemptReasonInvalidCredentials, failed[0].Reason)
+	})
+
+	t.Run("case=writes queued attempts on shutdown", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogFlushInterval, "1h")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogFlushInterval, "10ms")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			require.NoError(t, reg.LoginAttemptLogger().Work(ctx))
+		}()
+
+		reg.LoginAttemptLogger().Record(newRequest(), login.NewAttemptLog(newRequest(), identity.CredentialsTypePassword,
+			"shutdown@ory.sh", uuid.Nil, login.AttemptOutcomeFailure, login.AttemptReasonInvalidCredentials))
+		cancel()
+		<-done
+
+		var found bool
+		for _, l := range list(t, login.AttemptLogFilter{}) {
+			found = found || l.Identifier == "shutdown@ory.sh"
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("case=truncates without breaking characters", func(t *testing.T) {
+		a := login.NewAttemptLog(newRequest(), identity.CredentialsTypePassword,
+			strings.Repeat("ü", 300)+"\xff", uuid.Nil, login.AttemptOutcomeFailure, strings.Repeat("€", 300))
+		assert.Equal(t, strings.Repeat("ü", 255), a.Identifier)
+		assert.Equal(t, strings.Repeat("€", 255), a.Reason)
+		assert.True(t, utf8.ValidString(a.Identifier))
+	})
+
+	t.Run("case=purges expired attempts", func(t *testing.T) {
+		old := login.NewAttemptLog(newRequest(), identity.CredentialsTypePassword, "old@ory.sh", uuid.Nil, login.AttemptOutcomeFailure, "")
+		old.CreatedAt = time.Now().UTC().Add(-2 * time.Hour)
+		require.NoError(t, reg.LoginAttemptLogPersister().CreateLoginAttemptLogs(context.Background(), []login.AttemptLog{*old}))
+		require.Len(t, list(t, login.AttemptLogFilter{}), 4)
+
+		conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogRetention, "1h")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceLoginAttemptLogRetention, "720h")
+		})
+
+		require.NoError(t, reg.LoginAttemptLogger().Purge(context.Background()))
+		assert.Len(t, list(t, login.AttemptLogFilter{}), 3)
+	})
+}
+
+func TestListAttempts(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	_, admin := testhelpers.NewKratosServerWithCSRF(t, reg)
+	conf.MustSet(config.ViperKeyAdminBaseURL, admin.URL)
+
+	identityID := x.NewUUID()
+	now := time.Now().UTC().Round(time.Second)
+	newLog := func(identityID uuid.UUID, outcome string, createdAt time.Time) login.AttemptLog {
+		return login.AttemptLog{
+			IdentityID: uuid.NullUUID{UUID: identityID, Valid: identityID != uuid.Nil},
+			Identifier: "foo@ory.sh",
+			Method:     identity.CredentialsTypePassword,
+			Outcome:    outcome,
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		}
+	}
+	require.NoError(t, reg.LoginAttemptLogPersister().CreateLoginAttemptLogs(context.Background(), []login.AttemptLog{
+		newLog(identityID, login.AttemptOutcomeSuccess, now.Add(-3*time.Hour)),
+		newLog(identityID, login.AttemptOutcomeFailure, now.Add(-2*time.Hour)),
+		newLog(uuid.Nil, login.AttemptOutcomeFailure, now.Add(-time.Hour)),
+	}))
+
+	get := func(t *testing.T, query url.Values, expectedCode int) (*http.Response, gjson.Result) {
+		res, body := x.EasyGet(t, admin.Client(), admin.URL+login.RouteAdminListAttempts+"?"+query.Encode())
+		require.Equal(t, expectedCode, res.StatusCode, "%s", body)
+		return res, gjson.ParseBytes(body)
+	}
+
+	t.Run("case=lists all attempts newest first", func(t *testing.T) {
+		_, body := get(t, url.Values{}, http.StatusOK)
+		require.Len(t, body.Array(), 3, "%s", body.Raw)
+		assert.True(t, body.Get("0.identity_id").Type == gjson.Null, "%s", body.Raw)
+		assert.Equal(t, login.AttemptOutcomeSuccess, body.Get("2.outcome").String(), "%s", body.Raw)
+	})
+
+	t.Run("case=filters attempts", func(t *testing.T) {
+		_, body := get(t, url.Values{"identity_id": {identityID.String()}}, http.StatusOK)
+		assert.Len(t, body.Array(), 2, "%s", body.Raw)
+
+		_, body = get(t, url.Values{"identity_id": {identityID.String()}, "outcome": {login.AttemptOutcomeFailure}}, http.StatusOK)
+		require.Len(t, body.Array(), 1, "%s", body.Raw)
+		assert.Equal(t, identityID.String(), body.Get("0.identity_id").String())
+
+		_, body = get(t, url.Values{
+			"from":  {now.Add(-150 * time.Minute).Format(time.RFC3339)},
+			"until": {now.Add(-30 * time.Minute).Format(time.RFC3339)},
+		}, http.StatusOK)
+		assert.Len(t, body.Array(), 2, "%s", body.Raw)
+	})
+
+	t.Run("case=paginates attempts", func(t *testing.T) {
+		res, body := get(t, url.Values{"per_page": {"2"}, "page": {"1"}, "outcome": {login.AttemptOutcomeFailure}}, http.StatusOK)
+		assert.Len(t, body.Array(), 0, "%s", body.Raw)
+		assert.Contains(t, res.Header.Get("Link"), "outcome=failure")
+	})
+
+	t.Run("case=rejects invalid filters", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{"identity_id": {"not-a-uuid"}},
+			{"outcome": {"maybe"}},
+			{"from": {"yesterday"}},
+		} {
+			get(t, query, http.StatusBadRequest)
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/x"
@@ -22,11 +23,17 @@ import (
 
 var (
 	ErrHookAbortFlow   = errors.New("aborted login hook execution")
-	ErrAlreadyLoggedIn = herodot.ErrBadRequest.WithReason("A valid session was detected and thus login is not possible. Did you forget to set `?refresh=true`?")
+	ErrAlreadyLoggedIn = herodot.ErrBadRequest.
+				WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionAlreadyAvailable).
+				WithReason("A valid session was detected and thus login is not possible. Did you forget to set `?refresh=true`?")
+	ErrRefreshIdentityMismatch = herodot.ErrForbidden.
+					WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityIdentityMismatch).
+					WithReason("The login flow was initiated to re-authenticate another identity. Sign out first to sign in with a different account.")
 )
 
 type (
 	errorHandlerDependencies interface {
+		event.EmitterProvider
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
@@ -54,6 +61,7 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 		ago: ago,
 		DefaultError: herodot.ErrBadRequest.
 			WithError("login flow expired").
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowExpired).
 			WithReasonf(`The login flow has expired. Please restart the flow.`).
 			WithReasonf("The login flow expired %.2f minutes ago, please try again.", ago.Minutes()),
 	}
@@ -70,11 +78,15 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, ct
 		WithField("login_flow", f).
 		Info("Encountered self-service login error.")
 
+	err = errorx.WithStrategy(err, string(ct))
+
 	if f == nil {
 		s.forward(w, r, nil, err)
 		return
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowFailed, "login", f.ID, f.Type).WithStrategy(string(ct)).WithError(err))
+
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
 		a, err := s.d.LoginHandler().NewLoginFlow(w, r, f.Type)
@@ -102,6 +114,7 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, ct
 	method, ok := f.Methods[ct]
 	if !ok {
 		s.forward(w, r, f, errors.WithStack(herodot.ErrInternalServerError.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodMissing).
 			WithErrorf(`Expected login method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, ct)))
 		return
 	}
@@ -139,9 +152,23 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 		return
 	}
 
+	s.clearSubmittedValues(r, rr)
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 	}
 }
+
+// clearSubmittedValues removes the values submitted by the user from the failed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (s *ErrorHandler) clearSubmittedValues(r *http.Request, f *Flow) {
+	if s.c.SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	f.ResetSubmittedValues()
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), f); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the failed login flow.")
+	}
+}
@@ -135,8 +135,9 @@ func (f Flow) TableName(ctx context.Context) string {
 	return corp.ContextualizeTableName(ctx, "selfservice_login_flows")
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the flow expired more than clockSkew ago.
+func (f *Flow) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -153,3 +154,12 @@ func (f *Flow) IsForced() bool {
 func (f *Flow) AppendTo(src *url.URL) *url.URL {
 	return urlx.CopyWithQuery(src, url.Values{"flow": {f.ID.String()}})
 }
+
+// ResetSubmittedValues removes the values submitted by the user from all methods.
+func (f *Flow) ResetSubmittedValues() {
+	for _, m := range f.Methods {
+		if m.Config != nil && m.Config.FlowMethodConfigurator != nil {
+			m.Config.ResetSubmittedValues()
+		}
+	}
+}
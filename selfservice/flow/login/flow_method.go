@@ -59,9 +59,11 @@ type FlowMethodConfigurator interface {
 	form.ErrorParser
 	form.ValueSetter
 	form.Resetter
+	form.SubmittedValuesResetter
 	form.MessageResetter
 	form.CSRFSetter
 	form.MessageAdder
+	form.FieldGrouper
 }
 
 // swagger:model loginFlowMethodConfig
@@ -69,19 +69,30 @@ func TestFlow(t *testing.T) {
 
 	t.Run("case=expired", func(t *testing.T) {
 		for _, tc := range []struct {
-			r     *login.Flow
-			valid bool
+			r         *login.Flow
+			clockSkew time.Duration
+			valid     bool
 		}{
 			{
 				r:     &login.Flow{ExpiresAt: time.Now().Add(time.Hour), IssuedAt: time.Now().Add(-time.Minute)},
 				valid: true,
 			},
 			{r: &login.Flow{ExpiresAt: time.Now().Add(-time.Hour), IssuedAt: time.Now().Add(-time.Minute)}},
+			{r: &login.Flow{ExpiresAt: time.Now().Add(-time.Second), IssuedAt: time.Now().Add(-time.Minute)}},
+			{
+				r:         &login.Flow{ExpiresAt: time.Now().Add(-time.Second), IssuedAt: time.Now().Add(-time.Minute)},
+				clockSkew: 5 * time.Second,
+				valid:     true,
+			},
+			{
+				r:         &login.Flow{ExpiresAt: time.Now().Add(-time.Minute), IssuedAt: time.Now().Add(-time.Hour)},
+				clockSkew: 5 * time.Second,
+			},
 		} {
 			if tc.valid {
-				require.NoError(t, tc.r.Valid())
+				require.NoError(t, tc.r.Valid(tc.clockSkew))
 			} else {
-				require.Error(t, tc.r.Valid())
+				require.Error(t, tc.r.Valid(tc.clockSkew))
 			}
 		}
 	})
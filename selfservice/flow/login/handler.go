@@ -4,15 +4,21 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gofrs/uuid"
+
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
+	"github.com/ory/herodot"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -21,12 +27,17 @@ const (
 	RouteInitAPIFlow     = "/self-service/login/api"
 
 	RouteGetFlow = "/self-service/login/flows"
+
+	RouteAdminListAttempts = "/login-attempts"
 )
 
 type (
 	handlerDependencies interface {
+		event.EmitterProvider
 		HookExecutorProvider
 		FlowPersistenceProvider
+		AttemptLogPersistenceProvider
+		geoip.LocatorProvider
 		errorx.ManagementProvider
 		StrategyProvider
 		session.HandlerProvider
@@ -54,14 +65,26 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	public.GET(RouteInitBrowserFlow, h.initBrowserFlow)
 	public.GET(RouteInitAPIFlow, h.initAPIFlow)
 	public.GET(RouteGetFlow, h.fetchFlow)
+	public.GET(RouteRecentActivity, h.listActivity)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteGetFlow, h.fetchFlow)
+	admin.GET(RouteAdminListAttempts, h.listAttempts)
 }
 
-func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, flow flow.Type) (*Flow, error) {
-	a := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowLoginRequestLifespan(), h.d.GenerateCSRFToken(r), r, flow)
+func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, ft flow.Type) (*Flow, error) {
+	if err := flow.VerifyReturnTo(r, h.d.Configuration(r.Context()), "login"); err != nil {
+		return nil, err
+	}
+
+	a := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowLoginRequestLifespan(), flow.CSRFToken(w, r, ft, h.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), h.d.CSRFHandler(), h.d.GenerateCSRFToken), r, ft)
+	if a.Forced {
+		// Let the UI know that the user is asked to re-authenticate although being signed in.
+		if _, err := h.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil {
+			a.Messages.Add(text.NewInfoLoginReAuth())
+		}
+	}
 	for _, s := range h.d.LoginStrategies() {
 		if err := s.PopulateLoginMethod(r, a); err != nil {
 			return nil, err
@@ -75,6 +98,8 @@ func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, flow flow
 	if err := h.d.LoginFlowPersister().CreateLoginFlow(r.Context(), a); err != nil {
 		return nil, err
 	}
+
+	h.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowCreated, "login", a.ID, a.Type))
 	return a, nil
 }
 
@@ -237,7 +262,7 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, _ httprouter
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Add(h.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()).Before(time.Now()) {
 		if ar.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The login flow has expired. Redirect the user to the login flow init endpoint to initialize a new login flow.").
@@ -252,3 +277,150 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, _ httprouter
 
 	h.d.Writer().Write(w, r, ar)
 }
+
+// A list of login attempts.
+// swagger:response loginAttemptList
+// nolint:deadcode,unused
+type loginAttemptListResponse struct {
+	// in: body
+	// required: true
+	// type: array
+	Body []AttemptLog
+}
+
+// swagger:parameters listLoginAttempts
+// nolint:deadcode,unused
+type listLoginAttemptsParameters struct {
+	// Items per Page
+	//
+	// This is the number of items per page.
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 500
+	PerPage int `json:"per_page"`
+
+	// Pagination Page
+	//
+	// required: false
+	// in: query
+	// default: 0
+	// min: 0
+	Page int `json:"page"`
+
+	// Identity ID
+	//
+	// If set, only attempts of this identity are returned.
+	//
+	// required: false
+	// in: query
+	IdentityID string `json:"identity_id"`
+
+	// Outcome
+	//
+	// If set to `success` or `failure`, only attempts with this outcome are returned.
+	//
+	// required: false
+	// in: query
+	Outcome string `json:"outcome"`
+
+	// From
+	//
+	// If set, only attempts at or after this time (RFC 3339) are returned.
+	//
+	// required: false
+	// in: query
+	// format: date-time
+	From string `json:"from"`
+
+	// Until
+	//
+	// If set, only attempts before this time (RFC 3339) are returned.
+	//
+	// required: false
+	// in: query
+	// format: date-time
+	Until string `json:"until"`
+}
+
+// swagger:route GET /login-attempts admin listLoginAttempts
+//
+// List Login Attempts
+//
+// Lists the login attempts recorded if `selfservice.flows.login.attempt_log.enabled` is set, newest first.
+// Attempts can be filtered by identity, outcome, and time range.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: loginAttemptList
+//       400: genericError
+//       500: genericError
+func (h *Handler) listAttempts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	f, err := parseAttemptLogFilter(r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	page, itemsPerPage := x.ParsePagination(r)
+	logs, err := h.d.LoginAttemptLogPersister().ListLoginAttemptLogs(r.Context(), *f, page, itemsPerPage)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.d.LoginAttemptLogPersister().CountLoginAttemptLogs(r.Context(), *f)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	u := urlx.AppendPaths(h.d.Configuration(r.Context()).SelfAdminURL(), RouteAdminListAttempts)
+	q := u.Query()
+	for _, k := range []string{"identity_id", "outcome", "from", "until"} {
+		if v := r.URL.Query().Get(k); v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	x.PaginationHeader(w, u, total, page, itemsPerPage)
+	h.d.Writer().Write(w, r, logs)
+}
+
+func parseAttemptLogFilter(r *http.Request) (*AttemptLogFilter, error) {
+	query := r.URL.Query()
+	var f AttemptLogFilter
+
+	if v := query.Get("identity_id"); v != "" {
+		id, err := uuid.FromString(v)
+		if err != nil {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Query parameter identity_id must be a UUID: %s", err))
+		}
+		f.IdentityID = id
+	}
+
+	switch f.Outcome = query.Get("outcome"); f.Outcome {
+	case "", AttemptOutcomeSuccess, AttemptOutcomeFailure:
+	default:
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter outcome must be "%s" or "%s" but got "%s".`, AttemptOutcomeSuccess, AttemptOutcomeFailure, f.Outcome))
+	}
+
+	for key, target := range map[string]*time.Time{"from": &f.From, "until": &f.Until} {
+		if v := query.Get(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Query parameter %s must be a RFC 3339 timestamp: %s", key, err))
+			}
+			*target = t
+		}
+	}
+
+	return &f, nil
+}
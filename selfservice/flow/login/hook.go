@@ -8,7 +8,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
@@ -31,13 +34,20 @@ type (
 
 type (
 	executorDependencies interface {
+		event.EmitterProvider
 		config.Providers
+		geoip.LocatorProvider
+		identity.PrivilegedPoolProvider
 		session.ManagementProvider
 		session.PersistenceProvider
+		schema.IdentityTraitsProvider
 		x.WriterProvider
 		x.LoggingProvider
+		x.HTTPClientProvider
 
+		FlowPersistenceProvider
 		HooksProvider
+		AttemptLoggerProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -59,8 +69,42 @@ func NewHookExecutor(d executorDependencies) *HookExecutor {
 	return &HookExecutor{d: d}
 }
 
+// PostLoginHook signs the identity in and records the outcome in the login attempt log.
 func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct identity.CredentialsType, a *Flow, i *identity.Identity) error {
-	s := session.NewActiveSession(i, e.d.Configuration(r.Context()), time.Now().UTC()).Declassify()
+	if err := e.postLoginHook(w, r, ct, a, i); err != nil {
+		e.d.LoginAttemptLogger().RecordIdentity(r, ct, i, AttemptOutcomeFailure, AttemptFailureReason(err))
+		return err
+	}
+
+	e.d.LoginAttemptLogger().RecordIdentity(r, ct, i, AttemptOutcomeSuccess, "")
+	return nil
+}
+
+func (e *HookExecutor) postLoginHook(w http.ResponseWriter, r *http.Request, ct identity.CredentialsType, a *Flow, i *identity.Identity) error {
+	if i.IsScheduledForDeletion() {
+		return errors.WithStack(identity.ErrScheduledForDeletion)
+	}
+
+	switch identity.EnforcedVerificationAction(e.d.IdentityTraitsSchemas(r.Context()), i, time.Now().UTC()) {
+	case config.VerificationEnforcementWarn:
+		e.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", i.ID).
+			Warn("Identity signed in without having verified any of its addresses within the grace period.")
+	case config.VerificationEnforcementRestrict, config.VerificationEnforcementDeactivate:
+		return errors.WithStack(identity.ErrVerificationRequired)
+	}
+
+	if a.Forced {
+		// A refresh re-authenticates the identity of the existing session and must not switch to another identity.
+		if existing, err := e.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil && existing.IdentityID != i.ID {
+			return errors.WithStack(ErrRefreshIdentityMismatch)
+		}
+	}
+
+	c := e.d.Configuration(r.Context())
+	s := session.NewActiveSession(i, c, time.Now().UTC()).Declassify()
+	s.SetLocation(e.d.GeoLocator().Locate(r.Context(), x.TrustedClientIP(r, c.PublicClientIP())))
 
 	e.d.Logger().
 		WithRequest(r).
@@ -93,20 +137,50 @@ func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct
 			Debug("ExecuteLoginPostHook completed successfully.")
 	}
 
+	// A refresh re-authenticates the identity of an existing session and is not counted as another login.
+	if !a.Forced {
+		count, err := e.d.PrivilegedIdentityPool().RecordLogin(r.Context(), i.ID, s.AuthenticatedAt)
+		if err != nil {
+			return err
+		}
+		s.Identity.LoginCount = count
+		s.FirstLogin = count == 1
+	}
+
 	if a.Type == flow.TypeAPI {
 		if err := e.d.SessionPersister().CreateSession(r.Context(), s); err != nil {
 			return errors.WithStack(err)
 		}
+		if _, err := e.d.SessionManager().RotateFromRequest(r.Context(), r, s); err != nil {
+			return err
+		}
 		e.d.Audit().
 			WithRequest(r).
 			WithField("session_id", s.ID).
 			WithField("identity_id", i.ID).
+			WithField("country", s.Country).
+			WithField("region", s.Region).
 			Info("Identity authenticated successfully and was issued an ORY Kratos Session Token.")
+		e.clearSubmittedValues(r, a)
+		e.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "login", a.ID, a.Type).WithStrategy(string(ct)).WithIdentity(i.ID))
 
 		e.d.Writer().Write(w, r, &APIFlowResponse{Session: s, Token: s.Token})
 		return nil
 	}
 
+	// The redirect rules are evaluated before the session cookie is issued so that a failure does not leave the
+	// browser with a session of a failed flow.
+	returnTo, err := flow.EvaluateRedirectRules(c, e.d.HTTPClient(), c.SelfServiceFlowLoginRedirectRulesURL(), &flow.RedirectRulesContext{
+		Flow:            "login",
+		CredentialsType: ct,
+		Identity:        i,
+	})
+	if err != nil {
+		return err
+	} else if returnTo == nil {
+		returnTo = c.SelfServiceFlowLoginReturnTo(ct.String())
+	}
+
 	if err := e.d.SessionManager().CreateAndIssueCookie(r.Context(), w, r, s); err != nil {
 		return errors.WithStack(err)
 	}
@@ -115,9 +189,14 @@ func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct
 		WithRequest(r).
 		WithField("identity_id", i.ID).
 		WithField("session_id", s.ID).
+		WithField("country", s.Country).
+		WithField("region", s.Region).
 		Info("Identity authenticated successfully and was issued an ORY Kratos Session Cookie.")
+	e.clearSubmittedValues(r, a)
+	e.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "login", a.ID, a.Type).WithStrategy(string(ct)).WithIdentity(i.ID))
+
 	return x.SecureContentNegotiationRedirection(w, r, s.Declassify(), a.RequestURL,
-		e.d.Writer(), e.d.Configuration(r.Context()), x.SecureRedirectOverrideDefaultReturnTo(e.d.Configuration(r.Context()).SelfServiceFlowLoginReturnTo(ct.String())))
+		e.d.Writer(), c, x.SecureRedirectOverrideDefaultReturnTo(returnTo))
 }
 
 func (e *HookExecutor) PreLoginHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
@@ -129,3 +208,16 @@ func (e *HookExecutor) PreLoginHook(w http.ResponseWriter, r *http.Request, a *F
 
 	return nil
 }
+
+// clearSubmittedValues removes the values submitted by the user from the completed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (e *HookExecutor) clearSubmittedValues(r *http.Request, a *Flow) {
+	if e.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	a.ResetSubmittedValues()
+	if err := e.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), a); err != nil {
+		e.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the completed login flow.")
+	}
+}
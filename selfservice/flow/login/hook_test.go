@@ -1,6 +1,7 @@
 package login_test
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/gobuffalo/httptest"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
 	"github.com/ory/kratos/driver/config"
@@ -17,6 +19,7 @@ import (
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/x"
 )
 
@@ -159,3 +162,91 @@ func TestLoginExecutor(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginExecutorPersistSubmittedData(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/login.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/")
+
+	var f *login.Flow
+	router := httprouter.New()
+	router.GET("/login/post", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		testhelpers.SelfServiceHookLoginErrorHandler(t, w, r,
+			reg.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypePassword, f, testhelpers.SelfServiceHookCreateFakeIdentity(t, reg)))
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyPublicBaseURL, ts.URL)
+
+	run := func(t *testing.T, ft flow.Type) *form.HTMLForm {
+		r, err := http.NewRequest("GET", ts.URL+"/login/browser", nil)
+		require.NoError(t, err)
+
+		c := form.NewHTMLForm(ts.URL)
+		c.SetCSRF("csrf-token")
+		c.SetValue("identifier", "foo@ory.sh")
+		c.SetField(form.Field{Name: "password", Type: "password", Value: "secret"})
+
+		f = login.NewFlow(time.Minute, "csrf-token", r, ft)
+		f.Methods[identity.CredentialsTypePassword] = &login.FlowMethod{
+			Method: identity.CredentialsTypePassword,
+			Config: &login.FlowMethodConfig{FlowMethodConfigurator: c},
+		}
+		require.NoError(t, reg.LoginFlowPersister().CreateLoginFlow(context.Background(), f))
+
+		res, _ := testhelpers.SelfServiceMakeLoginPostHookRequest(t, ts, ft == flow.TypeAPI, url.Values{})
+		assert.EqualValues(t, http.StatusOK, res.StatusCode)
+
+		actual, err := reg.LoginFlowPersister().GetLoginFlow(context.Background(), f.ID)
+		require.NoError(t, err)
+		return actual.Methods[identity.CredentialsTypePassword].Config.FlowMethodConfigurator.(*form.HTMLForm)
+	}
+
+	for _, ft := range []flow.Type{flow.TypeBrowser, flow.TypeAPI} {
+		t.Run("type="+string(ft), func(t *testing.T) {
+			t.Run("case=keeps submitted values by default", func(t *testing.T) {
+				conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataAlways)
+
+				c := run(t, ft)
+				assert.Equal(t, "foo@ory.sh", c.Fields[1].Value)
+			})
+
+			t.Run("case=clears submitted values once completed", func(t *testing.T) {
+				conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataUntilCompleted)
+
+				c := run(t, ft)
+				require.Len(t, c.Fields, 3)
+				assert.Equal(t, form.CSRFTokenName, c.Fields[0].Name)
+				assert.Equal(t, "csrf-token", c.Fields[0].Value)
+				assert.Empty(t, c.Fields[1].Value, "identifier")
+				assert.Empty(t, c.Fields[2].Value, "password")
+			})
+		})
+	}
+}
+
+func TestLoginExecutorFirstLogin(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/login.schema.json")
+
+	i := testhelpers.SelfServiceHookCreateFakeIdentity(t, reg)
+	router := httprouter.New()
+	router.GET("/login/post", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		testhelpers.SelfServiceHookLoginErrorHandler(t, w, r,
+			reg.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypePassword, login.NewFlow(time.Minute, "", r, flow.TypeAPI), i))
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyPublicBaseURL, ts.URL)
+
+	for k, expected := range []bool{true, false} {
+		_, body := testhelpers.SelfServiceMakeLoginPostHookRequest(t, ts, true, url.Values{})
+		assert.Equal(t, expected, gjson.Get(body, "session.first_login").Bool(), "%s", body)
+		assert.EqualValues(t, k+1, gjson.Get(body, "session.identity.login_count").Int(), "%s", body)
+	}
+
+	actual, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, actual.LoginCount)
+	require.NotNil(t, actual.FirstLoginAt)
+}
@@ -3,6 +3,7 @@ package login
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/gofrs/uuid"
@@ -26,6 +27,17 @@ type (
 	FlowPersistenceProvider interface {
 		LoginFlowPersister() FlowPersister
 	}
+
+	// AttemptPersister stores failed login attempts per identifier.
+	AttemptPersister interface {
+		CreateLoginAttempt(ctx context.Context, identifier string) error
+		CountLoginAttempts(ctx context.Context, identifier string, since time.Time) (int, error)
+		DeleteLoginAttempts(ctx context.Context, identifier string) error
+		DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+	}
+	AttemptPersistenceProvider interface {
+		LoginAttemptPersister() AttemptPersister
+	}
 )
 
 func TestFlowPersister(p FlowPersister) func(t *testing.T) {
@@ -197,3 +209,108 @@ func TestFlowPersister(p FlowPersister) func(t *testing.T) {
 		})
 	}
 }
+
+func TestAttemptPersister(p AttemptPersister) func(t *testing.T) {
+	ctx := context.Background()
+	return func(t *testing.T) {
+		identifier := x.NewUUID().String() + "@ory.sh"
+		other := x.NewUUID().String() + "@ory.sh"
+		before := time.Now().Add(-time.Minute)
+
+		count := func(t *testing.T, identifier string, since time.Time) int {
+			actual, err := p.CountLoginAttempts(ctx, identifier, since)
+			require.NoError(t, err)
+			return actual
+		}
+
+		t.Run("case=counts attempts per identifier", func(t *testing.T) {
+			assert.Equal(t, 0, count(t, identifier, before))
+
+			require.NoError(t, p.CreateLoginAttempt(ctx, identifier))
+			require.NoError(t, p.CreateLoginAttempt(ctx, identifier))
+			require.NoError(t, p.CreateLoginAttempt(ctx, other))
+
+			assert.Equal(t, 2, count(t, identifier, before))
+			assert.Equal(t, 1, count(t, other, before))
+			assert.Equal(t, 0, count(t, identifier, time.Now().Add(time.Minute)))
+		})
+
+		t.Run("case=deletes expired attempts", func(t *testing.T) {
+			require.NoError(t, p.DeleteExpiredLoginAttempts(ctx, before))
+			assert.Equal(t, 2, count(t, identifier, before))
+
+			require.NoError(t, p.DeleteExpiredLoginAttempts(ctx, time.Now().Add(time.Minute)))
+			assert.Equal(t, 0, count(t, identifier, before))
+			assert.Equal(t, 0, count(t, other, before))
+		})
+
+		t.Run("case=deletes attempts of an identifier", func(t *testing.T) {
+			require.NoError(t, p.CreateLoginAttempt(ctx, identifier))
+			require.NoError(t, p.CreateLoginAttempt(ctx, other))
+
+			require.NoError(t, p.DeleteLoginAttempts(ctx, identifier))
+			assert.Equal(t, 0, count(t, identifier, before))
+			assert.Equal(t, 1, count(t, other, before))
+		})
+	}
+}
+
+func TestAttemptLogPersister(p AttemptLogPersister) func(t *testing.T) {
+	ctx := context.Background()
+	return func(t *testing.T) {
+		identityID := x.NewUUID()
+		// Use timestamps far in the past so that other tests are not affected by the purge.
+		start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		newLog := func(identityID uuid.UUID, outcome string, offset time.Duration) AttemptLog {
+			return AttemptLog{
+				IdentityID: uuid.NullUUID{UUID: identityID, Valid: identityID != uuid.Nil},
+				Identifier: "foo@ory.sh",
+				Method:     identity.CredentialsTypePassword,
+				Outcome:    outcome,
+				IPAddress:  "192.0.2.1",
+				UserAgent:  "test",
+				CreatedAt:  start.Add(offset),
+				UpdatedAt:  start.Add(offset),
+			}
+		}
+
+		list := func(t *testing.T, f AttemptLogFilter) []AttemptLog {
+			actual, err := p.ListLoginAttemptLogs(ctx, f, 0, 100)
+			require.NoError(t, err)
+			count, err := p.CountLoginAttemptLogs(ctx, f)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(actual), count)
+			return actual
+		}
+
+		t.Run("case=creates and filters attempts", func(t *testing.T) {
+			require.NoError(t, p.CreateLoginAttemptLogs(ctx, []AttemptLog{
+				newLog(identityID, AttemptOutcomeSuccess, time.Minute),
+				newLog(identityID, AttemptOutcomeFailure, 2*time.Minute),
+				newLog(uuid.Nil, AttemptOutcomeFailure, 3*time.Minute),
+			}))
+
+			actual := list(t, AttemptLogFilter{IdentityID: identityID})
+			require.Len(t, actual, 2)
+			assert.Equal(t, AttemptOutcomeFailure, actual[0].Outcome, "attempts are sorted by time descending")
+			assert.Equal(t, identityID, actual[0].IdentityID.UUID)
+			assert.Equal(t, "192.0.2.1", actual[0].IPAddress)
+
+			assert.Len(t, list(t, AttemptLogFilter{IdentityID: identityID, Outcome: AttemptOutcomeSuccess}), 1)
+			assert.Len(t, list(t, AttemptLogFilter{From: start, Until: start.Add(time.Hour), Outcome: AttemptOutcomeFailure}), 2)
+			assert.Len(t, list(t, AttemptLogFilter{From: start.Add(2 * time.Minute), Until: start.Add(3 * time.Minute)}), 1)
+
+			actual = list(t, AttemptLogFilter{From: start.Add(3 * time.Minute), Until: start.Add(time.Hour)})
+			require.Len(t, actual, 1)
+			assert.False(t, actual[0].IdentityID.Valid)
+		})
+
+		t.Run("case=deletes expired attempts", func(t *testing.T) {
+			require.NoError(t, p.DeleteLoginAttemptLogsBefore(ctx, start.Add(150*time.Second)))
+			assert.Len(t, list(t, AttemptLogFilter{From: start, Until: start.Add(time.Hour)}), 1)
+
+			require.NoError(t, p.DeleteLoginAttemptLogsBefore(ctx, start.Add(time.Hour)))
+			assert.Len(t, list(t, AttemptLogFilter{From: start, Until: start.Add(time.Hour)}), 0)
+		})
+	}
+}
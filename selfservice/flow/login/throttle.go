@@ -0,0 +1,184 @@
+package login
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/corp"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+)
+
+// throttlerSweepInterval is the minimum interval between removing expired attempts from the store.
+const throttlerSweepInterval = time.Minute
+
+type (
+	throttlerDependencies interface {
+		config.Providers
+		AttemptPersistenceProvider
+	}
+	ThrottlerProvider interface {
+		LoginThrottler() *Throttler
+	}
+
+	// Throttler blocks logins for an identifier after too many failed attempts, independent of the client's
+	// IP address. It is configured in `selfservice.flows.login.throttling`.
+	Throttler struct {
+		sync.Mutex
+		d         throttlerDependencies
+		memory    *MemoryAttemptPersister
+		lastSweep time.Time
+	}
+
+	// Attempt is a failed login attempt stored in the database.
+	Attempt struct {
+		ID uuid.UUID `json:"-" db:"id"`
+
+		// IdentifierHash is the HMAC of the normalized identifier.
+		IdentifierHash string `json:"-" db:"identifier_hash"`
+
+		// CreatedAt is a helper struct field for gobuffalo.pop.
+		CreatedAt time.Time `json:"-" db:"created_at"`
+		// UpdatedAt is a helper struct field for gobuffalo.pop.
+		UpdatedAt time.Time `json:"-" db:"updated_at"`
+	}
+
+	// MemoryAttemptPersister stores failed login attempts in memory. Attempts are not shared between multiple
+	// instances.
+	MemoryAttemptPersister struct {
+		sync.Mutex
+		attempts map[string][]time.Time
+	}
+)
+
+var _ AttemptPersister = new(MemoryAttemptPersister)
+
+func (a Attempt) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "selfservice_login_attempts")
+}
+
+func NewThrottler(d throttlerDependencies) *Throttler {
+	return &Throttler{d: d, memory: NewMemoryAttemptPersister()}
+}
+
+func (t *Throttler) store(ctx context.Context) AttemptPersister {
+	if t.d.Configuration(ctx).SelfServiceFlowLoginThrottling().Store == config.LoginThrottlingStoreDatabase {
+		return t.d.LoginAttemptPersister()
+	}
+	return t.memory
+}
+
+// normalizeIdentifier ensures that variations of the same identifier share their attempts.
+func normalizeIdentifier(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// Check returns a validation error if the identifier exceeded the maximum number of failed attempts. It must
+// be called before the identifier is looked up so that it behaves the same for unknown identifiers.
+func (t *Throttler) Check(ctx context.Context, identifier string) error {
+	conf := t.d.Configuration(ctx).SelfServiceFlowLoginThrottling()
+	if !conf.Enabled {
+		return nil
+	}
+
+	count, err := t.store(ctx).CountLoginAttempts(ctx, normalizeIdentifier(identifier), time.Now().Add(-conf.Window))
+	if err != nil {
+		return err
+	}
+
+	if count >= conf.MaxAttempts {
+		return schema.NewLoginThrottledError()
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login attempt for the identifier.
+func (t *Throttler) RecordFailure(ctx context.Context, identifier string) error {
+	conf := t.d.Configuration(ctx).SelfServiceFlowLoginThrottling()
+	if !conf.Enabled {
+		return nil
+	}
+
+	store := t.store(ctx)
+	if t.shouldSweep() {
+		if err := store.DeleteExpiredLoginAttempts(ctx, time.Now().Add(-conf.Window)); err != nil {
+			return err
+		}
+	}
+	return store.CreateLoginAttempt(ctx, normalizeIdentifier(identifier))
+}
+
+func (t *Throttler) shouldSweep() bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if time.Since(t.lastSweep) < throttlerSweepInterval {
+		return false
+	}
+	t.lastSweep = time.Now()
+	return true
+}
+
+// Reset removes the failed attempts of the identifier after a successful login.
+func (t *Throttler) Reset(ctx context.Context, identifier string) error {
+	if !t.d.Configuration(ctx).SelfServiceFlowLoginThrottling().Enabled {
+		return nil
+	}
+	return t.store(ctx).DeleteLoginAttempts(ctx, normalizeIdentifier(identifier))
+}
+
+func NewMemoryAttemptPersister() *MemoryAttemptPersister {
+	return &MemoryAttemptPersister{attempts: map[string][]time.Time{}}
+}
+
+func (p *MemoryAttemptPersister) CreateLoginAttempt(_ context.Context, identifier string) error {
+	p.Lock()
+	defer p.Unlock()
+	p.attempts[identifier] = append(p.attempts[identifier], time.Now())
+	return nil
+}
+
+func (p *MemoryAttemptPersister) CountLoginAttempts(_ context.Context, identifier string, since time.Time) (int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	var count int
+	for _, at := range p.attempts[identifier] {
+		if at.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p *MemoryAttemptPersister) DeleteLoginAttempts(_ context.Context, identifier string) error {
+	p.Lock()
+	defer p.Unlock()
+	delete(p.attempts, identifier)
+	return nil
+}
+
+func (p *MemoryAttemptPersister) DeleteExpiredLoginAttempts(_ context.Context, before time.Time) error {
+	p.Lock()
+	defer p.Unlock()
+
+	for identifier, attempts := range p.attempts {
+		kept := attempts[:0]
+		for _, at := range attempts {
+			if !at.Before(before) {
+				kept = append(kept, at)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(p.attempts, identifier)
+		} else {
+			p.attempts[identifier] = kept
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package login_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/text"
+)
+
+func TestMemoryAttemptPersister(t *testing.T) {
+	login.TestAttemptPersister(login.NewMemoryAttemptPersister())(t)
+}
+
+func TestThrottler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	ctx := context.Background()
+
+	for _, store := range []string{config.LoginThrottlingStoreMemory, config.LoginThrottlingStoreDatabase} {
+		t.Run("store="+store, func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceLoginThrottlingStore, store)
+			conf.MustSet(config.ViperKeySelfServiceLoginThrottlingMaxAttempts, 2)
+			conf.MustSet(config.ViperKeySelfServiceLoginThrottlingEnabled, true)
+			th := login.NewThrottler(reg)
+
+			assertThrottled := func(t *testing.T, identifier string) {
+				err := th.Check(ctx, identifier)
+				var ve *schema.ValidationError
+				require.True(t, errors.As(err, &ve), "%+v", err)
+				assert.Equal(t, text.ErrorValidationLoginThrottled, ve.Messages[0].ID)
+			}
+
+			t.Run("case=blocks after too many failed attempts", func(t *testing.T) {
+				require.NoError(t, th.Check(ctx, store+"@ory.sh"))
+				require.NoError(t, th.RecordFailure(ctx, store+"@ory.sh"))
+				require.NoError(t, th.Check(ctx, store+"@ory.sh"))
+				require.NoError(t, th.RecordFailure(ctx, " "+store+"@ORY.sh"))
+
+				assertThrottled(t, store+"@ory.sh")
+				assertThrottled(t, store+"@Ory.Sh")
+				require.NoError(t, th.Check(ctx, "other-"+store+"@ory.sh"))
+			})
+
+			t.Run("case=is not enforced when disabled", func(t *testing.T) {
+				conf.MustSet(config.ViperKeySelfServiceLoginThrottlingEnabled, false)
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeySelfServiceLoginThrottlingEnabled, true)
+				})
+
+				require.NoError(t, th.Check(ctx, store+"@ory.sh"))
+			})
+
+			t.Run("case=reset removes the failed attempts", func(t *testing.T) {
+				require.NoError(t, th.Reset(ctx, store+"@ory.sh"))
+				require.NoError(t, th.Check(ctx, store+"@ory.sh"))
+			})
+		})
+	}
+}
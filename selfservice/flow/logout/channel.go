@@ -0,0 +1,141 @@
+package logout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+// BackChannelNotification is the body of back-channel logout notifications.
+type BackChannelNotification struct {
+	// SessionID is the ID of the session which was ended.
+	SessionID uuid.UUID `json:"session_id"`
+
+	// IdentityID is the ID of the identity the session belonged to.
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// LoggedOutAt is the time (UTC) the session was ended.
+	LoggedOutAt time.Time `json:"logged_out_at"`
+}
+
+var frontChannelPage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="refresh" content="{{ .Delay }};url={{ .ReturnTo }}">
+<title>Signing out</title>
+</head>
+<body>
+{{ range .URLs }}<iframe src="{{ . }}" style="display:none" title="Signing out"></iframe>
+{{ end }}<a href="{{ .ReturnTo }}">Continue</a>
+</body>
+</html>
+`))
+
+// notifyBackChannel sends the back-channel logout notifications in the background.
+func (h *Handler) notifyBackChannel(s *session.Session) {
+	urls := h.c.SelfServiceFlowLogoutBackChannelURLs()
+	if len(urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(&BackChannelNotification{
+		SessionID:   s.ID,
+		IdentityID:  s.IdentityID,
+		LoggedOutAt: time.Now().UTC(),
+	})
+	if err != nil {
+		h.d.Logger().WithError(err).Error("Unable to encode back-channel logout notification.")
+		return
+	}
+
+	for k := range urls {
+		go h.deliver(context.Background(), urls[k], body)
+	}
+}
+
+// deliver sends a back-channel logout notification and retries with exponential backoff if it fails.
+func (h *Handler) deliver(ctx context.Context, u url.URL, body []byte) {
+	maxAttempts, delay := h.c.SelfServiceFlowLogoutBackChannelRetry()
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = h.send(ctx, u, body); err == nil {
+			return
+		}
+
+		h.d.Logger().
+			WithError(err).
+			WithField("url", u.String()).
+			WithField("attempt", attempt).
+			Warn("Unable to deliver back-channel logout notification.")
+
+		if attempt < maxAttempts {
+			time.Sleep(delay * time.Duration(math.Pow(2, float64(attempt-1))))
+		}
+	}
+
+	h.d.Logger().
+		WithError(err).
+		WithField("url", u.String()).
+		Error("Giving up on delivering back-channel logout notification.")
+}
+
+func (h *Handler) send(ctx context.Context, u url.URL, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	x.SignWebhook(req.Header, body, h.c.SelfServiceFlowLogoutBackChannelSigningSecrets(), time.Now())
+
+	res, err := h.d.HTTPClient().Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("expected back-channel logout URL %s to respond with a 2xx status code but got: %d", u.String(), res.StatusCode)
+	}
+	return nil
+}
+
+// renderFrontChannel responds with a page which loads the front-channel logout URLs and then redirects the
+// browser to returnTo. It returns false if no front-channel logout URLs are configured.
+func (h *Handler) renderFrontChannel(w http.ResponseWriter, s *session.Session, returnTo *url.URL) (bool, error) {
+	configured := h.c.SelfServiceFlowLogoutFrontChannelURLs()
+	if len(configured) == 0 {
+		return false, nil
+	}
+
+	urls := make([]string, len(configured))
+	for k := range configured {
+		u := configured[k]
+		if s != nil {
+			urls[k] = urlx.CopyWithQuery(&u, url.Values{"sid": {s.ID.String()}}).String()
+		} else {
+			urls[k] = u.String()
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	return true, errors.WithStack(frontChannelPage.Execute(w, map[string]interface{}{
+		"Delay":    int(math.Ceil(h.c.SelfServiceFlowLogoutFrontChannelRedirectDelay().Seconds())),
+		"ReturnTo": returnTo.String(),
+		"URLs":     urls,
+	}))
+}
@@ -1,13 +1,14 @@
 package logout
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
 
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -19,6 +20,8 @@ const (
 type (
 	handlerDependencies interface {
 		x.CSRFProvider
+		x.LoggingProvider
+		x.HTTPClientProvider
 		session.ManagementProvider
 		errorx.ManagementProvider
 	}
@@ -49,7 +52,10 @@ func (h *Handler) RegisterPublicRoutes(router *x.RouterPublic) {
 // with browsers (Chrome, Firefox, ...).
 //
 // On successful logout, the browser will be redirected (HTTP 302 Found) to the `return_to` parameter of the initial request
-// or fall back to `urls.default_return_to`.
+// or fall back to `urls.default_return_to`. If front-channel logout URLs are configured, the endpoint instead responds
+// with an HTML page which loads these URLs and then redirects the browser.
+//
+// If back-channel logout URLs are configured, they are notified about the ended session in the background.
 //
 // More information can be found at [ORY Kratos User Logout Documentation](https://www.ory.sh/docs/next/kratos/self-service/flows/user-logout).
 //
@@ -59,9 +65,7 @@ func (h *Handler) RegisterPublicRoutes(router *x.RouterPublic) {
 //       302: emptyResponse
 //       500: genericError
 func (h *Handler) logout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	_ = h.d.CSRFHandler().RegenerateToken(w, r)
-
-	if err := h.d.SessionManager().PurgeFromRequest(r.Context(), w, r); err != nil {
+	if err := flow.VerifyReturnTo(r, h.c, "logout"); err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
@@ -72,10 +76,34 @@ func (h *Handler) logout(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		x.SecureRedirectAllowSelfServiceURLs(h.c.SelfPublicURL()),
 	)
 	if err != nil {
-		fmt.Printf("\n%s\n\n", err.Error())
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
 
+	_ = h.d.CSRFHandler().RegenerateToken(w, r)
+
+	// The session is only needed to notify other applications, so logging out works without an active session.
+	sess, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil && !errors.Is(err, session.ErrNoActiveSessionFound) {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if err := h.d.SessionManager().PurgeFromRequest(r.Context(), w, r); err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if sess != nil {
+		h.notifyBackChannel(sess)
+	}
+
+	if rendered, err := h.renderFrontChannel(w, sess, ret); err != nil {
+		h.d.Logger().WithError(err).Error("Unable to render front-channel logout page.")
+		return
+	} else if rendered {
+		return
+	}
+
 	http.Redirect(w, r, ret.String(), http.StatusFound)
 }
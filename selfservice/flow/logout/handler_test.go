@@ -2,12 +2,16 @@ package logout_test
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gobuffalo/httptest"
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -109,4 +113,62 @@ func TestLogoutHandler(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, returnToURL, res.Request.URL.String())
 	})
+
+	t.Run("case=notifies back-channel and front-channel logout URLs", func(t *testing.T) {
+		notifications := make(chan logout.BackChannelNotification, 10)
+		var attempts int32
+		backChannel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, x.VerifyWebhook(r.Header, body, [][]byte{[]byte("back-channel-secret")}, time.Minute, time.Now()))
+
+			// The first attempt fails to make sure that the notification is retried.
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			var n logout.BackChannelNotification
+			require.NoError(t, json.Unmarshal(body, &n))
+			notifications <- n
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer backChannel.Close()
+
+		conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelURLs, []string{backChannel.URL})
+		conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelSigningSecrets, []string{"back-channel-secret"})
+		conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelRetryDelay, "10ms")
+		conf.MustSet(config.ViperKeySelfServiceLogoutFrontChannelURLs, []string{"https://app.example.com/logout"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelURLs, []string{})
+			conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelSigningSecrets, []string{})
+			conf.MustSet(config.ViperKeySelfServiceLogoutFrontChannelURLs, []string{})
+		})
+
+		client := testhelpers.NewClientWithCookies(t)
+		testhelpers.MockHydrateCookieClient(t, client, ts.URL+"/set")
+
+		res, err := client.Get(ts.URL + logout.RouteBrowser)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(body), `<iframe src="https://app.example.com/logout?sid=`)
+		assert.Contains(t, string(body), `url=`+redirTS.URL)
+
+		select {
+		case n := <-notifications:
+			assert.NotEqual(t, uuid.Nil, n.SessionID)
+			assert.NotEqual(t, uuid.Nil, n.IdentityID)
+			assert.Contains(t, string(body), "sid="+n.SessionID.String())
+
+			_, err := reg.SessionPersister().GetSession(context.Background(), n.SessionID)
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("back-channel logout notification was not delivered")
+		}
+		assert.EqualValues(t, 2, atomic.LoadInt32(&attempts))
+	})
 }
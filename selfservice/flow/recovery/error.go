@@ -13,6 +13,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/text"
@@ -20,7 +21,9 @@ import (
 )
 
 var (
-	ErrAlreadyLoggedIn = herodot.ErrBadRequest.WithReason("A valid session was detected and thus recovery is not possible.")
+	ErrAlreadyLoggedIn = herodot.ErrBadRequest.
+		WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionAlreadyAvailable).
+		WithReason("A valid session was detected and thus recovery is not possible.")
 )
 
 type FlowExpiredError struct {
@@ -34,6 +37,7 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 		ago: ago,
 		DefaultError: herodot.ErrBadRequest.
 			WithError("recovery flow expired").
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowExpired).
 			WithReasonf(`The recovery flow has expired. Please restart the flow.`).
 			WithReasonf("The recovery flow expired %.2f minutes ago, please try again.", ago.Minutes()),
 	}
@@ -41,10 +45,12 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 
 type (
 	errorHandlerDependencies interface {
+		event.EmitterProvider
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFTokenGeneratorProvider
+		x.CSRFProvider
 		config.Providers
 		StrategyProvider
 
@@ -77,14 +83,18 @@ func (s *ErrorHandler) WriteFlowError(
 		WithField("recovery_flow", f).
 		Info("Encountered self-service recovery error.")
 
+	err = errorx.WithStrategy(err, methodName)
+
 	if f == nil {
 		s.forward(w, r, nil, err)
 		return
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowFailed, "recovery", f.ID, f.Type).WithStrategy(methodName).WithError(err))
+
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
-		a, err := NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), f.Type)
+		a, err := NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), flow.CSRFToken(w, r, f.Type, s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.CSRFHandler(), s.d.GenerateCSRFToken), r, s.d.RecoveryStrategies(), f.Type)
 		if err != nil {
 			// failed to create a new session and redirect to it, handle that error as a new one
 			s.WriteFlowError(w, r, methodName, f, err)
@@ -109,6 +119,7 @@ func (s *ErrorHandler) WriteFlowError(
 	method, ok := f.Methods[methodName]
 	if !ok {
 		s.forward(w, r, f, errors.WithStack(herodot.ErrInternalServerError.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodMissing).
 			WithErrorf(`Expected recovery method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, methodName)))
 		return
 	}
@@ -147,9 +158,23 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 		return
 	}
 
+	s.clearSubmittedValues(r, rr)
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 	}
 }
+
+// clearSubmittedValues removes the values submitted by the user from the failed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (s *ErrorHandler) clearSubmittedValues(r *http.Request, f *Flow) {
+	if s.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	f.ResetSubmittedValues()
+	if err := s.d.RecoveryFlowPersister().UpdateRecoveryFlow(r.Context(), f); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the failed recovery flow.")
+	}
+}
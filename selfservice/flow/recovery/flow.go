@@ -127,8 +127,9 @@ func (f *Flow) GetID() uuid.UUID {
 	return f.ID
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now().UTC()) {
+// Valid returns an error if the flow expired more than clockSkew ago.
+func (f *Flow) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now().UTC()) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -176,3 +177,12 @@ func (f *Flow) MethodToForm(id string) (form.Form, error) {
 func (f *Flow) AppendTo(src *url.URL) *url.URL {
 	return urlx.CopyWithQuery(src, url.Values{"flow": {f.ID.String()}})
 }
+
+// ResetSubmittedValues removes the values submitted by the user from all methods.
+func (f *Flow) ResetSubmittedValues() {
+	for _, m := range f.Methods {
+		if m.Config != nil && m.Config.FlowMethodConfigurator != nil {
+			m.Config.ResetSubmittedValues()
+		}
+	}
+}
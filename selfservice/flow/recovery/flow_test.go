@@ -30,7 +30,7 @@ func TestFlow(t *testing.T) {
 		{r: must(recovery.NewFlow(-time.Hour, "", u, nil, flow.TypeBrowser)), expectErr: true},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
-			err := tc.r.Valid()
+			err := tc.r.Valid(0)
 			if tc.expectErr {
 				require.Error(t, err)
 				return
@@ -10,6 +10,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
@@ -28,12 +29,14 @@ type (
 		RecoveryHandler() *Handler
 	}
 	handlerDependencies interface {
+		event.EmitterProvider
 		errorx.ManagementProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
 		session.HandlerProvider
 		StrategyProvider
 		FlowPersistenceProvider
+		HookExecutorProvider
 		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.CSRFProvider
@@ -91,17 +94,32 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 //       500: genericError
 //       400: genericError
 func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := flow.VerifyReturnTo(r, h.d.Configuration(r.Context()), "recovery"); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	req, err := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.RecoveryStrategies(), flow.TypeAPI)
 	if err != nil {
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
 
+	if err := h.d.RecoveryExecutor().PreRecoveryHook(w, r, req); err != nil {
+		if errors.Is(err, ErrHookAbortFlow) {
+			return
+		}
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	if err := h.d.RecoveryFlowPersister().CreateRecoveryFlow(r.Context(), req); err != nil {
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowCreated, "recovery", req.ID, req.Type))
+
 	h.d.Writer().Write(w, r, req)
 }
 
@@ -123,17 +141,32 @@ func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprout
 //       302: emptyResponse
 //       500: genericError
 func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	req, err := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.RecoveryStrategies(), flow.TypeBrowser)
+	if err := flow.VerifyReturnTo(r, h.d.Configuration(r.Context()), "recovery"); err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	req, err := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), flow.CSRFToken(w, r, flow.TypeBrowser, h.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), h.d.CSRFHandler(), h.d.GenerateCSRFToken), r, h.d.RecoveryStrategies(), flow.TypeBrowser)
 	if err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
 
+	if err := h.d.RecoveryExecutor().PreRecoveryHook(w, r, req); err != nil {
+		if errors.Is(err, ErrHookAbortFlow) {
+			return
+		}
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
 	if err := h.d.RecoveryFlowPersister().CreateRecoveryFlow(r.Context(), req); err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
 
+	h.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowCreated, "recovery", req.ID, req.Type))
+
 	http.Redirect(w, r, req.AppendTo(h.d.Configuration(r.Context()).SelfServiceFlowRecoveryUI()).String(), http.StatusFound)
 }
 
@@ -176,7 +209,7 @@ func (h *Handler) fetch(w http.ResponseWriter, r *http.Request, _ httprouter.Par
 		return
 	}
 
-	if req.ExpiresAt.Before(time.Now().UTC()) {
+	if req.ExpiresAt.Add(h.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()).Before(time.Now().UTC()) {
 		if req.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The recovery flow has expired. Redirect the user to the recovery flow init endpoint to initialize a new recovery flow.").
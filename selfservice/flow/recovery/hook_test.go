@@ -0,0 +1,135 @@
+package recovery_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/httptest"
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+func TestRecoveryExecutor(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	newServer := func(t *testing.T, ft flow.Type) *httptest.Server {
+		router := httprouter.New()
+		router.GET("/recovery/pre", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			f, err := recovery.NewFlow(time.Minute, x.FakeCSRFToken, r, reg.RecoveryStrategies(), ft)
+			require.NoError(t, err)
+			if testhelpers.SelfServiceHookRecoveryErrorHandler(t, w, r, reg.RecoveryExecutor().PreRecoveryHook(w, r, f)) {
+				_, _ = w.Write([]byte("ok"))
+			}
+		})
+
+		ts := httptest.NewServer(router)
+		t.Cleanup(ts.Close)
+		return ts
+	}
+
+	for _, ft := range []flow.Type{flow.TypeAPI, flow.TypeBrowser} {
+		t.Run("type="+string(ft), func(t *testing.T) {
+			t.Run("method=PreRecoveryHook", testhelpers.TestSelfServicePreHook(
+				config.ViperKeySelfServiceRecoveryBeforeHooks,
+				testhelpers.SelfServiceMakeRecoveryPreHookRequest,
+				func(t *testing.T) *httptest.Server {
+					return newServer(t, ft)
+				},
+				conf,
+			))
+		})
+	}
+}
+
+func TestRecoveryPersistSubmittedData(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceRecoveryEnabled, true)
+
+	var f *recovery.Flow
+	router := httprouter.New()
+	router.GET("/recovery/error", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		reg.RecoveryFlowErrorHandler().WriteFlowError(w, r, recovery.StrategyRecoveryLinkName, f,
+			errors.WithStack(herodot.ErrInternalServerError.WithReason("system error")))
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	newFlow := func(t *testing.T) *recovery.Flow {
+		r, err := http.NewRequest("GET", ts.URL+"/recovery", nil)
+		require.NoError(t, err)
+
+		f, err := recovery.NewFlow(time.Minute, x.FakeCSRFToken, r, reg.RecoveryStrategies(), flow.TypeAPI)
+		require.NoError(t, err)
+
+		c := form.NewHTMLForm(ts.URL)
+		c.SetCSRF(x.FakeCSRFToken)
+		c.SetField(form.Field{Name: "email", Type: "email", Value: "foo@ory.sh"})
+		f.Methods[recovery.StrategyRecoveryLinkName] = &recovery.FlowMethod{
+			Method: recovery.StrategyRecoveryLinkName,
+			Config: &recovery.FlowMethodConfig{FlowMethodConfigurator: c},
+		}
+		require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+		return f
+	}
+
+	values := func(t *testing.T, id uuid.UUID) map[string]interface{} {
+		actual, err := reg.RecoveryFlowPersister().GetRecoveryFlow(context.Background(), id)
+		require.NoError(t, err)
+
+		result := map[string]interface{}{}
+		for _, field := range actual.Methods[recovery.StrategyRecoveryLinkName].Config.FlowMethodConfigurator.(*form.HTMLForm).Fields {
+			result[field.Name] = field.Value
+		}
+		return result
+	}
+
+	complete := func(t *testing.T) map[string]interface{} {
+		f := newFlow(t)
+		r, err := http.NewRequest("GET", ts.URL+"/recovery", nil)
+		require.NoError(t, err)
+
+		reg.RecoveryExecutor().PostRecoveryHook(r, f)
+		require.NoError(t, reg.RecoveryFlowPersister().UpdateRecoveryFlow(context.Background(), f))
+		return values(t, f.ID)
+	}
+
+	fail := func(t *testing.T) map[string]interface{} {
+		f = newFlow(t)
+		res, err := ts.Client().Get(ts.URL + "/recovery/error")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		return values(t, f.ID)
+	}
+
+	t.Run("case=keeps submitted values by default", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataAlways)
+
+		assert.Equal(t, "foo@ory.sh", complete(t)["email"])
+		assert.Equal(t, "foo@ory.sh", fail(t)["email"])
+	})
+
+	t.Run("case=clears submitted values once completed", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataUntilCompleted)
+
+		for name, actual := range map[string]map[string]interface{}{"hook": complete(t), "error": fail(t)} {
+			assert.Empty(t, actual["email"], name)
+			assert.Equal(t, x.FakeCSRFToken, actual[form.CSRFTokenName], name)
+		}
+	})
+}
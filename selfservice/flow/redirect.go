@@ -0,0 +1,133 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/stringsx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+// RedirectRulesContext is passed to the Jsonnet redirect rules as the external variable `ctx`.
+type RedirectRulesContext struct {
+	// Flow is the name of the completed flow, e.g. `login` or `registration`.
+	Flow string `json:"flow"`
+
+	// CredentialsType is the strategy which was used to complete the flow, e.g. `password` or `oidc`.
+	CredentialsType identity.CredentialsType `json:"credentials_type"`
+
+	// Identity is the identity which completed the flow.
+	Identity *identity.Identity `json:"identity"`
+}
+
+// redirectRules caches the fetched redirect rules by their location so that they are not fetched again on every
+// completed flow.
+var redirectRules = struct {
+	sync.RWMutex
+	snippets map[string]string
+}{snippets: map[string]string{}}
+
+func loadRedirectRules(hc *http.Client, rulesURL string) (string, error) {
+	redirectRules.RLock()
+	snippet, ok := redirectRules.snippets[rulesURL]
+	redirectRules.RUnlock()
+	if ok {
+		return snippet, nil
+	}
+
+	jn, err := fetcher.NewFetcher(fetcher.WithClient(hc)).Fetch(rulesURL)
+	if err != nil {
+		return "", err
+	}
+
+	redirectRules.Lock()
+	defer redirectRules.Unlock()
+	redirectRules.snippets[rulesURL] = jn.String()
+	return jn.String(), nil
+}
+
+// EvaluateRedirectRules evaluates the Jsonnet redirect rules located at rulesURL. The rules are expected to return an
+// object with the key `redirect_to`. If no rules are configured or if the rules do not return a redirect target, nil
+// is returned and the default return URL should be used instead. The rules are fetched once per location using hc and
+// cached afterwards.
+//
+// The redirect target must be whitelisted in `selfservice.whitelisted_return_urls`.
+func EvaluateRedirectRules(c *config.Provider, hc *http.Client, rulesURL string, ctx *RedirectRulesContext) (*url.URL, error) {
+	if rulesURL == "" {
+		return nil, nil
+	}
+
+	snippet, err := loadRedirectRules(hc, rulesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var input bytes.Buffer
+	if err := json.NewEncoder(&input).Encode(ctx); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("ctx", input.String())
+	evaluated, err := vm.EvaluateSnippet(rulesURL, snippet)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the redirect rules: %s", err))
+	}
+
+	target := gjson.Get(evaluated, "redirect_to").String()
+	if target == "" {
+		return nil, nil
+	}
+
+	returnTo, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the redirect URL returned by the redirect rules: %s", err))
+	}
+
+	defaultReturnTo := c.SelfServiceBrowserDefaultReturnTo()
+	returnTo.Host = stringsx.Coalesce(returnTo.Host, defaultReturnTo.Host)
+	returnTo.Scheme = stringsx.Coalesce(returnTo.Scheme, defaultReturnTo.Scheme)
+
+	if !x.IsWhitelistedRedirectURL(returnTo, append(c.SelfServiceBrowserWhitelistedReturnToDomains(), *defaultReturnTo)) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeReturnToNotWhitelisted).
+			WithReasonf("Redirect URL \"%s\" returned by the redirect rules is not whitelisted.", returnTo).
+			WithDebugf("Whitelisted domains are: %v", c.SelfServiceBrowserWhitelistedReturnToDomains()))
+	}
+
+	return returnTo, nil
+}
+
+// VerifyReturnTo checks the `?return_to=...` query parameter of the request initializing the flow against the return
+// URLs whitelisted in `selfservice.flows.<flow>.whitelisted_return_urls`. These restrict the global
+// `selfservice.whitelisted_return_urls` further, so the return URL must be whitelisted by both. If the flow has no
+// whitelist, the return URL is only checked against `selfservice.whitelisted_return_urls` once the flow completes.
+func VerifyReturnTo(r *http.Request, c *config.Provider, flow string) error {
+	allowed := c.SelfServiceFlowWhitelistedReturnToDomains(flow)
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, whitelist := range [][]url.URL{allowed, c.SelfServiceBrowserWhitelistedReturnToDomains()} {
+		if _, err := x.SecureRedirectTo(r, c.SelfServiceBrowserDefaultReturnTo(),
+			x.SecureRedirectAllowSelfServiceURLs(c.SelfPublicURL()),
+			x.SecureRedirectAllowURLs(whitelist),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package flow_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+)
+
+func TestEvaluateRedirectRules(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/")
+	conf.MustSet(config.ViperKeyURLsWhitelistedReturnToDomains, []string{"https://www.ory.sh/onboarding", "https://www.ory.sh/sso"})
+
+	rules := "base64://" + base64.StdEncoding.EncodeToString([]byte(`local ctx = std.extVar('ctx');
+if ctx.flow == 'registration' then { redirect_to: '/onboarding' }
+else if ctx.credentials_type == 'oidc' then { redirect_to: 'https://www.ory.sh/sso' }
+else if ctx.credentials_type == 'not-whitelisted' then { redirect_to: 'https://evil.ory.sh/' }
+else {}`))
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+
+	for _, tc := range []struct {
+		d        string
+		rules    string
+		ctx      *flow.RedirectRulesContext
+		expected string
+		err      bool
+	}{
+		{
+			d:   "no rules configured",
+			ctx: &flow.RedirectRulesContext{Flow: "login", CredentialsType: identity.CredentialsTypePassword, Identity: i},
+		},
+		{
+			d:     "no rule matched",
+			rules: rules,
+			ctx:   &flow.RedirectRulesContext{Flow: "login", CredentialsType: identity.CredentialsTypePassword, Identity: i},
+		},
+		{
+			d:        "relative redirect",
+			rules:    rules,
+			ctx:      &flow.RedirectRulesContext{Flow: "registration", CredentialsType: identity.CredentialsTypePassword, Identity: i},
+			expected: "https://www.ory.sh/onboarding",
+		},
+		{
+			d:        "absolute redirect",
+			rules:    rules,
+			ctx:      &flow.RedirectRulesContext{Flow: "login", CredentialsType: identity.CredentialsTypeOIDC, Identity: i},
+			expected: "https://www.ory.sh/sso",
+		},
+		{
+			d:     "redirect not whitelisted",
+			rules: rules,
+			ctx:   &flow.RedirectRulesContext{Flow: "login", CredentialsType: "not-whitelisted", Identity: i},
+			err:   true,
+		},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			returnTo, err := flow.EvaluateRedirectRules(conf, reg.HTTPClient(), tc.rules, tc.ctx)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tc.expected == "" {
+				assert.Nil(t, returnTo)
+				return
+			}
+			require.NotNil(t, returnTo)
+			assert.Equal(t, tc.expected, returnTo.String())
+		})
+	}
+
+	t.Run("case=rules are fetched once", func(t *testing.T) {
+		var fetched int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&fetched, 1)
+			_, _ = w.Write([]byte(`{ redirect_to: '/onboarding' }`))
+		}))
+		t.Cleanup(ts.Close)
+
+		for k := 0; k < 3; k++ {
+			returnTo, err := flow.EvaluateRedirectRules(conf, reg.HTTPClient(), ts.URL, &flow.RedirectRulesContext{Flow: "login", CredentialsType: identity.CredentialsTypePassword, Identity: i})
+			require.NoError(t, err)
+			assert.Equal(t, "https://www.ory.sh/onboarding", returnTo.String())
+		}
+		assert.EqualValues(t, 1, atomic.LoadInt32(&fetched))
+	})
+}
+
+func TestVerifyReturnTo(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://auth.ory.sh/")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://app.ory.sh/")
+	conf.MustSet(config.ViperKeyURLsWhitelistedReturnToDomains, []string{"https://app.ory.sh/", "https://www.ory.sh/"})
+	conf.MustSet("selfservice.flows.login.whitelisted_return_urls", []string{"https://app.ory.sh/"})
+	conf.MustSet("selfservice.flows.registration.whitelisted_return_urls", []string{"https://app.ory.sh/", "https://shop.ory.sh/"})
+
+	for _, tc := range []struct {
+		d        string
+		flow     string
+		returnTo string
+		err      bool
+	}{
+		{d: "no return_to", flow: "login"},
+		{d: "whitelisted for the flow", flow: "login", returnTo: "https://app.ory.sh/dashboard"},
+		{d: "self-service URL", flow: "login", returnTo: "https://auth.ory.sh/self-service/login/browser"},
+		{d: "only whitelisted globally", flow: "login", returnTo: "https://www.ory.sh/", err: true},
+		{d: "flow without own whitelist", flow: "logout", returnTo: "https://www.ory.sh/"},
+		{d: "whitelisted globally and for the flow", flow: "registration", returnTo: "https://app.ory.sh/welcome"},
+		{d: "only whitelisted for the flow", flow: "registration", returnTo: "https://shop.ory.sh/", err: true},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://auth.ory.sh/self-service/"+tc.flow+"/browser?"+url.Values{"return_to": {tc.returnTo}}.Encode(), nil)
+			if tc.returnTo == "" {
+				r.URL.RawQuery = ""
+			}
+
+			err := flow.VerifyReturnTo(r, conf, tc.flow)
+			if !tc.err {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, errors.Cause(err).(*herodot.DefaultError).StatusCode())
+		})
+	}
+}
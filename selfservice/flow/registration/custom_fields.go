@@ -0,0 +1,51 @@
+package registration
+
+import (
+	"context"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+)
+
+type (
+	// CustomFieldsHook is implemented by registration hooks which depend on custom fields.
+	CustomFieldsHook interface {
+		// ValidateCustomFields returns an error if the hook depends on custom fields which are not declared.
+		ValidateCustomFields(fields []config.SelfServiceCustomField) error
+	}
+
+	customFieldsDependencies interface {
+		config.Providers
+		HooksProvider
+	}
+)
+
+// ValidateCustomFields validates the declared custom fields of the registration flow and the
+// registration hooks which depend on them.
+func ValidateCustomFields(ctx context.Context, d customFieldsDependencies) error {
+	fields := d.Configuration(ctx).SelfServiceFlowRegistrationCustomFields()
+	if err := flow.ValidateCustomFieldDeclarations(fields); err != nil {
+		return err
+	}
+
+	for _, ct := range []identity.CredentialsType{identity.CredentialsTypePassword, identity.CredentialsTypeOIDC} {
+		var hooks []interface{}
+		for _, h := range d.PostRegistrationPrePersistHooks(ct) {
+			hooks = append(hooks, h)
+		}
+		for _, h := range d.PostRegistrationPostPersistHooks(ct) {
+			hooks = append(hooks, h)
+		}
+
+		for _, h := range hooks {
+			if h, ok := h.(CustomFieldsHook); ok {
+				if err := h.ValidateCustomFields(fields); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
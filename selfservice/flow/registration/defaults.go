@@ -0,0 +1,155 @@
+package registration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// These headers are not passed to the Jsonnet defaults because they contain credentials.
+var defaultsOmittedHeaders = []string{"Authorization", "Cookie", "X-Session-Token"}
+
+type (
+	// DefaultsContext is passed to the Jsonnet defaults as the external variable `ctx`.
+	DefaultsContext struct {
+		// IdentityID is the ID of the identity which is being registered.
+		IdentityID uuid.UUID `json:"identity_id"`
+
+		// SchemaID is the ID of the identity's JSON Schema.
+		SchemaID string `json:"schema_id"`
+
+		// CredentialsType is the strategy which is used to register, e.g. `password` or `oidc`.
+		CredentialsType identity.CredentialsType `json:"credentials_type"`
+
+		// Traits are the traits submitted by the user.
+		Traits json.RawMessage `json:"traits"`
+
+		// Time is the time of the registration.
+		Time time.Time `json:"time"`
+
+		// Request contains information about the HTTP request which completes the registration.
+		Request DefaultsRequestContext `json:"request"`
+	}
+
+	// DefaultsRequestContext contains information about the HTTP request which completes the registration.
+	DefaultsRequestContext struct {
+		URL      string            `json:"url"`
+		ClientIP string            `json:"client_ip"`
+		Headers  map[string]string `json:"headers"`
+	}
+)
+
+func newDefaultsRequestContext(r *http.Request, conf *config.Provider) DefaultsRequestContext {
+	headers := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		headers[k] = r.Header.Get(k)
+	}
+	for _, k := range defaultsOmittedHeaders {
+		delete(headers, http.CanonicalHeaderKey(k))
+	}
+
+	return DefaultsRequestContext{
+		URL:      x.RequestURL(r).String(),
+		ClientIP: x.TrustedClientIP(r, conf.PublicClientIP()),
+		Headers:  headers,
+	}
+}
+
+// mergeDefaults sets all values of defaults which are missing in traits. Nested objects are merged recursively.
+func mergeDefaults(traits, defaults map[string]interface{}) {
+	for k, v := range defaults {
+		existing, ok := traits[k]
+		if !ok {
+			traits[k] = v
+			continue
+		}
+
+		em, eok := existing.(map[string]interface{})
+		dm, dok := v.(map[string]interface{})
+		if eok && dok {
+			mergeDefaults(em, dm)
+		}
+	}
+}
+
+// ApplyDefaults evaluates the Jsonnet defaults configured in `selfservice.flows.registration.defaults_url` and merges
+// the returned `identity.traits` into the identity's traits. Traits submitted by the user take precedence.
+//
+// Strategies must call this before validating the identity so that defaults can be set for required traits.
+func (e *HookExecutor) ApplyDefaults(r *http.Request, ct identity.CredentialsType, i *identity.Identity) error {
+	conf := e.d.Configuration(r.Context())
+	defaultsURL := conf.SelfServiceFlowRegistrationDefaultsURL()
+	if defaultsURL == "" {
+		return nil
+	}
+
+	jn, err := fetcher.NewFetcher(fetcher.WithClient(e.d.HTTPClient())).Fetch(defaultsURL)
+	if err != nil {
+		return err
+	}
+
+	traits := json.RawMessage(i.Traits)
+	if len(traits) == 0 {
+		traits = json.RawMessage("{}")
+	}
+
+	var input bytes.Buffer
+	if err := json.NewEncoder(&input).Encode(&DefaultsContext{
+		IdentityID:      i.ID,
+		SchemaID:        i.SchemaID,
+		CredentialsType: ct,
+		Traits:          traits,
+		Time:            time.Now().UTC(),
+		Request:         newDefaultsRequestContext(r, conf),
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("ctx", input.String())
+	evaluated, err := vm.EvaluateSnippet(defaultsURL, jn.String())
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the registration defaults: %s", err))
+	}
+
+	result := gjson.Get(evaluated, "identity.traits")
+	if !result.Exists() {
+		return nil
+	} else if !result.IsObject() {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The registration defaults must return an object for key identity.traits."))
+	}
+
+	var defaults, submitted map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Raw), &defaults); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := json.Unmarshal(traits, &submitted); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the submitted traits: %s", err))
+	}
+
+	if submitted == nil {
+		submitted = map[string]interface{}{}
+	}
+	mergeDefaults(submitted, defaults)
+
+	merged, err := json.Marshal(submitted)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	i.Traits = identity.Traits(merged)
+	return nil
+}
@@ -0,0 +1,99 @@
+package registration_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	setDefaults := func(t *testing.T, jsonnet string) {
+		conf.MustSet(config.ViperKeySelfServiceRegistrationDefaults, "base64://"+base64.StdEncoding.EncodeToString([]byte(jsonnet)))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceRegistrationDefaults, "")
+		})
+	}
+
+	newRequest := func(t *testing.T) *http.Request {
+		r, err := http.NewRequest("POST", "https://www.ory.sh/self-service/registration", nil)
+		require.NoError(t, err)
+		r.Header.Set("Accept-Language", "de-DE")
+		r.Header.Set("Cookie", "ory_kratos_session=secret")
+		return r
+	}
+
+	newIdentity := func(traits string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(traits)
+		return i
+	}
+
+	t.Run("case=does nothing without defaults", func(t *testing.T) {
+		i := newIdentity(`{"email":"foo@ory.sh"}`)
+		require.NoError(t, reg.RegistrationExecutor().ApplyDefaults(newRequest(t), identity.CredentialsTypePassword, i))
+		assert.JSONEq(t, `{"email":"foo@ory.sh"}`, string(i.Traits))
+	})
+
+	t.Run("case=merges defaults below submitted traits", func(t *testing.T) {
+		setDefaults(t, `local ctx = std.extVar('ctx');
+{
+  identity: {
+    traits: {
+      username: std.split(ctx.traits.email, '@')[0],
+      role: 'member',
+      name: { first: 'Anonymous', last: 'User' },
+      locale: ctx.request.headers['Accept-Language'],
+      method: ctx.credentials_type,
+      signed_up_at: ctx.time,
+      has_cookie: std.objectHas(ctx.request.headers, 'Cookie'),
+    },
+  },
+}`)
+
+		i := newIdentity(`{"email":"foo@ory.sh","role":"admin","name":{"first":"Foo"}}`)
+		require.NoError(t, reg.RegistrationExecutor().ApplyDefaults(newRequest(t), identity.CredentialsTypePassword, i))
+
+		traits := gjson.ParseBytes(i.Traits)
+		assert.Equal(t, "foo@ory.sh", traits.Get("email").String())
+		assert.Equal(t, "foo", traits.Get("username").String())
+		assert.Equal(t, "admin", traits.Get("role").String(), "submitted traits take precedence")
+		assert.Equal(t, "Foo", traits.Get("name.first").String(), "submitted traits take precedence")
+		assert.Equal(t, "User", traits.Get("name.last").String(), "nested objects are merged")
+		assert.Equal(t, "de-DE", traits.Get("locale").String())
+		assert.Equal(t, "password", traits.Get("method").String())
+		assert.NotEmpty(t, traits.Get("signed_up_at").String())
+		assert.False(t, traits.Get("has_cookie").Bool(), "credentials must not be passed to the defaults")
+	})
+
+	t.Run("case=ignores defaults without traits", func(t *testing.T) {
+		setDefaults(t, `{}`)
+
+		i := newIdentity(`{"email":"foo@ory.sh"}`)
+		require.NoError(t, reg.RegistrationExecutor().ApplyDefaults(newRequest(t), identity.CredentialsTypePassword, i))
+		assert.JSONEq(t, `{"email":"foo@ory.sh"}`, string(i.Traits))
+	})
+
+	t.Run("case=fails if traits are not an object", func(t *testing.T) {
+		setDefaults(t, `{identity: {traits: 'foo'}}`)
+
+		i := newIdentity(`{"email":"foo@ory.sh"}`)
+		require.Error(t, reg.RegistrationExecutor().ApplyDefaults(newRequest(t), identity.CredentialsTypePassword, i))
+	})
+
+	t.Run("case=fails if jsonnet is invalid", func(t *testing.T) {
+		setDefaults(t, `{identity: `)
+
+		i := newIdentity(`{"email":"foo@ory.sh"}`)
+		require.Error(t, reg.RegistrationExecutor().ApplyDefaults(newRequest(t), identity.CredentialsTypePassword, i))
+	})
+}
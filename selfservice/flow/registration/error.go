@@ -14,6 +14,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/x"
@@ -21,11 +22,14 @@ import (
 
 var (
 	ErrHookAbortFlow   = errors.New("aborted registration hook execution")
-	ErrAlreadyLoggedIn = herodot.ErrBadRequest.WithReason("A valid session was detected and thus registration is not possible.")
+	ErrAlreadyLoggedIn = herodot.ErrBadRequest.
+				WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionAlreadyAvailable).
+				WithReason("A valid session was detected and thus registration is not possible.")
 )
 
 type (
 	errorHandlerDependencies interface {
+		event.EmitterProvider
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
@@ -53,6 +57,7 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 		ago: ago,
 		DefaultError: herodot.ErrBadRequest.
 			WithError("registration flow expired").
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowExpired).
 			WithReasonf(`The registration flow has expired. Please restart the flow.`).
 			WithReasonf("The registration flow expired %.2f minutes ago, please try again.", ago.Minutes()),
 	}
@@ -75,11 +80,15 @@ func (s *ErrorHandler) WriteFlowError(
 		WithField("registration_flow", f).
 		Info("Encountered self-service flow error.")
 
+	err = errorx.WithStrategy(err, string(ct))
+
 	if f == nil {
 		s.forward(w, r, nil, err)
 		return
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowFailed, "registration", f.ID, f.Type).WithStrategy(string(ct)).WithError(err))
+
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
 		a, err := s.d.RegistrationHandler().NewRegistrationFlow(w, r, f.Type)
@@ -107,6 +116,7 @@ func (s *ErrorHandler) WriteFlowError(
 	method, ok := f.Methods[ct]
 	if !ok {
 		s.forward(w, r, f, errors.WithStack(herodot.ErrInternalServerError.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodMissing).
 			WithErrorf(`Expected registration method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, ct)))
 		return
 	}
@@ -144,9 +154,23 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 		return
 	}
 
+	s.clearSubmittedValues(r, rr)
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 	}
 }
+
+// clearSubmittedValues removes the values submitted by the user from the failed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (s *ErrorHandler) clearSubmittedValues(r *http.Request, f *Flow) {
+	if s.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	f.ResetSubmittedValues()
+	if err := s.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), f); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the failed registration flow.")
+	}
+}
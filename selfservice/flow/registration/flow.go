@@ -2,6 +2,7 @@ package registration
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"time"
@@ -75,6 +76,10 @@ type Flow struct {
 
 	// CSRFToken contains the anti-csrf token associated with this flow. Only set for browser flows.
 	CSRFToken string `json:"-" db:"csrf_token"`
+
+	// CustomFields contains the submitted values of the custom fields (`selfservice.flows.registration.custom_fields`).
+	// They are available to registration hooks, but are neither persisted nor stored as identity traits.
+	CustomFields json.RawMessage `json:"-" faker:"-" db:"-"`
 }
 
 func NewFlow(exp time.Duration, csrf string, r *http.Request, ft flow.Type) *Flow {
@@ -125,8 +130,9 @@ func (f *Flow) GetID() uuid.UUID {
 	return f.ID
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the flow expired more than clockSkew ago.
+func (f *Flow) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -135,3 +141,12 @@ func (f *Flow) Valid() error {
 func (f *Flow) AppendTo(src *url.URL) *url.URL {
 	return urlx.CopyWithQuery(src, url.Values{"flow": {f.ID.String()}})
 }
+
+// ResetSubmittedValues removes the values submitted by the user from all methods.
+func (f *Flow) ResetSubmittedValues() {
+	for _, m := range f.Methods {
+		if m.Config != nil && m.Config.FlowMethodConfigurator != nil {
+			m.Config.ResetSubmittedValues()
+		}
+	}
+}
@@ -2,14 +2,18 @@ package registration
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
+	"github.com/ory/herodot"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
@@ -21,10 +25,17 @@ const (
 	RouteInitAPIFlow     = "/self-service/registration/api"
 
 	RouteGetFlow = "/self-service/registration/flows"
+
+	RouteAvailability = "/self-service/registration/available"
+
+	// availabilityMinResponseTime is the minimum time it takes to respond to an availability check. It hides
+	// timing differences between taken and available identifiers.
+	availabilityMinResponseTime = 200 * time.Millisecond
 )
 
 type (
 	handlerDependencies interface {
+		event.EmitterProvider
 		config.Providers
 		errorx.ManagementProvider
 		session.HandlerProvider
@@ -35,17 +46,19 @@ type (
 		StrategyProvider
 		HookExecutorProvider
 		FlowPersistenceProvider
+		identity.PrivilegedPoolProvider
 	}
 	HandlerProvider interface {
 		RegistrationHandler() *Handler
 	}
 	Handler struct {
-		d handlerDependencies
+		d       handlerDependencies
+		limiter *x.RateLimiter
 	}
 )
 
 func NewHandler(d handlerDependencies) *Handler {
-	return &Handler{d: d}
+	return &Handler{d: d, limiter: x.NewRateLimiter()}
 }
 
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
@@ -56,6 +69,7 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 		session.RespondWithJSONErrorOnAuthenticated(h.d.Writer(), errors.WithStack(ErrAlreadyLoggedIn))))
 
 	public.GET(RouteGetFlow, h.fetchFlow)
+	public.GET(RouteAvailability, h.checkAvailability)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -63,7 +77,11 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 }
 
 func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft flow.Type) (*Flow, error) {
-	a := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowRegistrationRequestLifespan(), h.d.GenerateCSRFToken(r), r, ft)
+	if err := flow.VerifyReturnTo(r, h.d.Configuration(r.Context()), "registration"); err != nil {
+		return nil, err
+	}
+
+	a := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowRegistrationRequestLifespan(), flow.CSRFToken(w, r, ft, h.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), h.d.CSRFHandler(), h.d.GenerateCSRFToken), r, ft)
 	for _, s := range h.d.RegistrationStrategies() {
 		if err := s.PopulateRegistrationMethod(r, a); err != nil {
 			return nil, err
@@ -78,6 +96,8 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 		return nil, err
 	}
 
+	h.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowCreated, "registration", a.ID, a.Type))
+
 	return a, nil
 }
 
@@ -195,7 +215,7 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Add(h.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()).Before(time.Now()) {
 		if ar.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The registration flow has expired. Redirect the user to the registration flow init endpoint to initialize a new registration flow.").
@@ -210,3 +230,82 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, ps httproute
 
 	h.d.Writer().Write(w, r, ar)
 }
+
+// nolint:deadcode,unused
+// swagger:parameters checkSelfServiceRegistrationAvailability
+type checkSelfServiceRegistrationAvailabilityParameters struct {
+	// The identifier (e.g. email address or username) to check.
+	//
+	// required: true
+	// in: query
+	Identifier string `json:"identifier"`
+}
+
+// The result of an identifier availability check.
+//
+// swagger:model registrationAvailability
+type Availability struct {
+	// Available is true if no identity uses the identifier yet.
+	//
+	// required: true
+	Available bool `json:"available"`
+}
+
+// swagger:route GET /self-service/registration/available public checkSelfServiceRegistrationAvailability
+//
+// Check Identifier Availability
+//
+// This endpoint checks whether a login identifier (e.g. email address or username) is already used by an identity.
+// It is disabled by default and can be enabled with `selfservice.flows.registration.availability_check.enabled`.
+//
+// :::warning
+//
+// This endpoint allows account enumeration. Requests are rate limited per client IP address.
+//
+// :::
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: registrationAvailability
+//       400: genericError
+//       404: genericError
+//       429: genericError
+//       500: genericError
+func (h *Handler) checkAvailability(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	conf := h.d.Configuration(r.Context())
+	if !conf.SelfServiceFlowRegistrationAvailabilityEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The identifier availability check is disabled.")))
+		return
+	}
+
+	if !h.limiter.Allow(x.TrustedClientIP(r, conf.PublicClientIP()), conf.SelfServiceFlowRegistrationAvailabilityMaxRequests(), conf.SelfServiceFlowRegistrationAvailabilityWindow()) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrTooManyRequests))
+		return
+	}
+
+	identifier := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("identifier")))
+	if len(identifier) == 0 {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The query parameter identifier must be set.")))
+		return
+	}
+
+	deadline := time.Now().Add(availabilityMinResponseTime)
+	defer func() {
+		time.Sleep(time.Until(deadline))
+	}()
+
+	_, _, err := h.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), identity.CredentialsTypePassword, identifier)
+	if errors.Is(err, herodot.ErrNotFound) {
+		h.d.Writer().Write(w, r, &Availability{Available: true})
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &Availability{Available: false})
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -197,3 +198,55 @@ func TestGetFlow(t *testing.T) {
 		run(t, public)
 	})
 }
+
+func TestCheckAvailability(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	public, _ := testhelpers.NewKratosServerWithRouters(t, reg, x.NewRouterPublic(), x.NewRouterAdmin())
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"email":"taken@ory.sh"}`)
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type: identity.CredentialsTypePassword, Identifiers: []string{"taken@ory.sh"}, Config: []byte(`{}`)})
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	check := func(t *testing.T, identifier string) (*http.Response, []byte) {
+		return x.EasyGet(t, public.Client(), public.URL+registration.RouteAvailability+"?identifier="+identifier)
+	}
+
+	t.Run("case=is disabled by default", func(t *testing.T) {
+		res, _ := check(t, "taken@ory.sh")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	conf.MustSet(config.ViperKeySelfServiceRegistrationAvailabilityEnabled, true)
+	conf.MustSet(config.ViperKeySelfServiceRegistrationAvailabilityMaxRequests, 4)
+	conf.MustSet(config.ViperKeySelfServiceRegistrationAvailabilityWindow, "1h")
+
+	t.Run("case=reports a taken identifier", func(t *testing.T) {
+		res, body := check(t, "TAKEN@ory.sh")
+		assert.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.False(t, gjson.GetBytes(body, "available").Bool(), "%s", body)
+	})
+
+	t.Run("case=reports an available identifier", func(t *testing.T) {
+		res, body := check(t, "available@ory.sh")
+		assert.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.True(t, gjson.GetBytes(body, "available").Bool(), "%s", body)
+	})
+
+	t.Run("case=requires an identifier", func(t *testing.T) {
+		res, _ := check(t, "")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("case=is rate limited", func(t *testing.T) {
+		res, _ := check(t, "available@ory.sh")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, body := check(t, "available@ory.sh")
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, string(text.ErrorCodeRateLimitExceeded), gjson.GetBytes(body, "error.details.id").String(), "%s", body)
+	})
+}
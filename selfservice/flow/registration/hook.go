@@ -3,6 +3,7 @@ package registration
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,6 +11,8 @@ import (
 	"github.com/ory/x/sqlcon"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
@@ -60,13 +63,17 @@ func (f PostHookPrePersistExecutorFunc) ExecutePostRegistrationPrePersistHook(w
 
 type (
 	executorDependencies interface {
+		event.EmitterProvider
 		config.Providers
+		geoip.LocatorProvider
 		identity.ManagementProvider
 		identity.ValidationProvider
 		session.PersistenceProvider
 		HooksProvider
+		FlowPersistenceProvider
 		x.LoggingProvider
 		x.WriterProvider
+		x.HTTPClientProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -114,9 +121,29 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 	// We need to make sure that the identity has a valid schema before passing it down to the identity pool.
 	if err := e.d.IdentityValidator().Validate(r.Context(), i); err != nil {
 		return err
-		// We're now creating the identity because any of the hooks could trigger a "redirect" or a "session" which
-		// would imply that the identity has to exist already.
-	} else if err := e.d.IdentityManager().Create(r.Context(), i); err != nil {
+	}
+
+	// The redirect rules are evaluated before the identity is created and the session hook issues the session cookie
+	// so that a failure does not leave behind an identity or a session of a failed flow.
+	c := e.d.Configuration(r.Context())
+	var returnTo *url.URL
+	if a.Type != flow.TypeAPI {
+		var err error
+		returnTo, err = flow.EvaluateRedirectRules(c, e.d.HTTPClient(), c.SelfServiceFlowRegistrationRedirectRulesURL(), &flow.RedirectRulesContext{
+			Flow:            "registration",
+			CredentialsType: ct,
+			Identity:        i,
+		})
+		if err != nil {
+			return err
+		} else if returnTo == nil {
+			returnTo = c.SelfServiceFlowRegistrationReturnTo(ct.String())
+		}
+	}
+
+	// We're now creating the identity because any of the hooks could trigger a "redirect" or a "session" which
+	// would imply that the identity has to exist already.
+	if err := e.d.IdentityManager().Create(r.Context(), i); err != nil {
 		if errors.Is(err, sqlcon.ErrUniqueViolation) {
 			return schema.NewDuplicateCredentialsError()
 		}
@@ -126,8 +153,11 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 		WithRequest(r).
 		WithField("identity_id", i.ID).
 		Info("A new identity has registered using self-service registration.")
+	e.clearSubmittedValues(r, a)
+	e.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "registration", a.ID, a.Type).WithStrategy(string(ct)).WithIdentity(i.ID))
 
-	s := session.NewActiveSession(i, e.d.Configuration(r.Context()), time.Now().UTC())
+	s := session.NewActiveSession(i, c, time.Now().UTC())
+	s.SetLocation(e.d.GeoLocator().Locate(r.Context(), x.TrustedClientIP(r, c.PublicClientIP())))
 	e.d.Logger().
 		WithRequest(r).
 		WithField("identity_id", i.ID).
@@ -170,7 +200,7 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 	}
 
 	return x.SecureContentNegotiationRedirection(w, r, s.Declassify(), a.RequestURL,
-		e.d.Writer(), e.d.Configuration(r.Context()), x.SecureRedirectOverrideDefaultReturnTo(e.d.Configuration(r.Context()).SelfServiceFlowRegistrationReturnTo(ct.String())))
+		e.d.Writer(), c, x.SecureRedirectOverrideDefaultReturnTo(returnTo))
 }
 
 func (e *HookExecutor) PreRegistrationHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
@@ -182,3 +212,16 @@ func (e *HookExecutor) PreRegistrationHook(w http.ResponseWriter, r *http.Reques
 
 	return nil
 }
+
+// clearSubmittedValues removes the values submitted by the user from the completed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (e *HookExecutor) clearSubmittedValues(r *http.Request, a *Flow) {
+	if e.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	a.ResetSubmittedValues()
+	if err := e.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), a); err != nil {
+		e.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the completed registration flow.")
+	}
+}
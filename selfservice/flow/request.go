@@ -6,20 +6,28 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/nosurf"
 )
 
 var ErrOriginHeaderNeedsBrowserFlow = herodot.ErrBadRequest.
+	WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeBrowserFlowRequired).
 	WithReasonf(`The HTTP Request Header included the "Origin" key, indicating that this request was made as part of an AJAX request in a Browser. The flow however was initiated as an API request. To prevent potential misuse and mitigate several attack vectors including CSRF, the request has been blocked. Please consult the documentation.`)
 var ErrCookieHeaderNeedsBrowserFlow = herodot.ErrBadRequest.
+	WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeBrowserFlowRequired).
 	WithReasonf(`The HTTP Request Header included the "Cookie" key, indicating that this request was made by a Browser. The flow however was initiated as an API request. To prevent potential misuse and mitigate several attack vectors including CSRF, the request has been blocked. Please consult the documentation.`)
 
+// VerifyRequest ensures that API flows are not submitted by browsers and that browser flows are submitted with a
+// valid anti-CSRF token. If per-flow anti-CSRF tokens are enabled, browser flows must also be submitted with the
+// anti-CSRF token the flow was initialized with, see VerifyFlowCSRFToken.
 func VerifyRequest(
 	r *http.Request,
 	flowType Type,
 	disableAPIFlowEnforcement bool,
+	perFlow bool,
 	generator func(r *http.Request) string,
+	expected string,
 	actual string,
 ) error {
 	switch flowType {
@@ -47,5 +55,43 @@ func VerifyRequest(
 		}
 	}
 
+	return VerifyFlowCSRFToken(r, flowType, perFlow, generator, expected)
+}
+
+// CSRFToken returns the anti-CSRF token for a new flow. If per-flow anti-CSRF tokens are enabled and the flow is a
+// browser flow, the anti-CSRF token is regenerated so that tokens issued for previously initialized flows are no
+// longer accepted.
+func CSRFToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	flowType Type,
+	perFlow bool,
+	handler x.CSRFHandler,
+	generator func(r *http.Request) string,
+) string {
+	if perFlow && flowType == TypeBrowser {
+		return handler.RegenerateToken(w, r)
+	}
+	return generator(r)
+}
+
+// VerifyFlowCSRFToken ensures that the flow was initialized with the anti-CSRF token of the request's anti-CSRF
+// cookie. As the token is rotated whenever a flow is initialized, this rejects submissions of all but the most
+// recently initialized flow. This is only checked for browser flows if per-flow anti-CSRF tokens are enabled.
+func VerifyFlowCSRFToken(
+	r *http.Request,
+	flowType Type,
+	perFlow bool,
+	generator func(r *http.Request) string,
+	expected string,
+) error {
+	if !perFlow || flowType != TypeBrowser {
+		return nil
+	}
+
+	if !nosurf.VerifyToken(generator(r), expected) {
+		return errors.WithStack(x.ErrInvalidCSRFToken)
+	}
+
 	return nil
 }
@@ -1,22 +1,100 @@
 package flow
 
 import (
+	"io/ioutil"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/nosurf"
+
 	"github.com/ory/kratos/x"
 )
 
 func TestVerifyRequest(t *testing.T) {
-	require.EqualError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, x.FakeCSRFTokenGenerator, "not_csrf_token"), x.ErrInvalidCSRFToken.Error())
-	require.NoError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, x.FakeCSRFTokenGenerator, x.FakeCSRFToken), nil)
-	require.NoError(t, VerifyRequest(&http.Request{}, TypeAPI, false, x.FakeCSRFTokenGenerator, ""))
+	require.EqualError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, false, x.FakeCSRFTokenGenerator, "", "not_csrf_token"), x.ErrInvalidCSRFToken.Error())
+	require.NoError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, false, x.FakeCSRFTokenGenerator, "", x.FakeCSRFToken), nil)
+	require.NoError(t, VerifyRequest(&http.Request{}, TypeAPI, false, false, x.FakeCSRFTokenGenerator, "", ""))
 	require.EqualError(t, VerifyRequest(&http.Request{
 		Header: http.Header{"Origin": {"https://www.ory.sh"}},
-	}, TypeAPI, false, x.FakeCSRFTokenGenerator, ""), ErrOriginHeaderNeedsBrowserFlow.Error())
+	}, TypeAPI, false, false, x.FakeCSRFTokenGenerator, "", ""), ErrOriginHeaderNeedsBrowserFlow.Error())
 	require.EqualError(t, VerifyRequest(&http.Request{
 		Header: http.Header{"Cookie": {"cookie=ory"}},
-	}, TypeAPI, false, x.FakeCSRFTokenGenerator, ""), ErrCookieHeaderNeedsBrowserFlow.Error())
+	}, TypeAPI, false, false, x.FakeCSRFTokenGenerator, "", ""), ErrCookieHeaderNeedsBrowserFlow.Error())
+	require.NoError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, true, x.FakeCSRFTokenGenerator, x.FakeCSRFToken, x.FakeCSRFToken))
+	require.EqualError(t, VerifyRequest(&http.Request{}, TypeBrowser, false, true, x.FakeCSRFTokenGenerator, "not_csrf_token", x.FakeCSRFToken), x.ErrInvalidCSRFToken.Error())
+}
+
+func TestVerifyFlowCSRFToken(t *testing.T) {
+	require.NoError(t, VerifyFlowCSRFToken(&http.Request{}, TypeBrowser, false, x.FakeCSRFTokenGenerator, "not_csrf_token"))
+	require.NoError(t, VerifyFlowCSRFToken(&http.Request{}, TypeAPI, true, x.FakeCSRFTokenGenerator, ""))
+	require.NoError(t, VerifyFlowCSRFToken(&http.Request{}, TypeBrowser, true, x.FakeCSRFTokenGenerator, x.FakeCSRFToken))
+	require.EqualError(t, VerifyFlowCSRFToken(&http.Request{}, TypeBrowser, true, x.FakeCSRFTokenGenerator, "not_csrf_token"), x.ErrInvalidCSRFToken.Error())
+
+	newServer := func(t *testing.T, perFlow bool) (*httptest.Server, *http.Client) {
+		router := http.NewServeMux()
+		handler := nosurf.New(router)
+		router.HandleFunc("/init", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(CSRFToken(w, r, TypeBrowser, perFlow, handler, nosurf.Token)))
+		})
+		router.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+			if err := VerifyRequest(r, TypeBrowser, false, perFlow, nosurf.Token, r.URL.Query().Get("expected"), r.URL.Query().Get("actual")); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		ts := httptest.NewServer(handler)
+		t.Cleanup(ts.Close)
+
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		return ts, &http.Client{Jar: jar}
+	}
+
+	initFlow := func(t *testing.T, ts *httptest.Server, c *http.Client) string {
+		res, err := c.Get(ts.URL + "/init")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	submitFlow := func(t *testing.T, ts *httptest.Server, c *http.Client, expected, actual string) int {
+		res, err := c.Get(ts.URL + "/submit?" + url.Values{"expected": {expected}, "actual": {actual}}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("case=tokens are valid across flows if per-flow tokens are disabled", func(t *testing.T) {
+		ts, c := newServer(t, false)
+		first := initFlow(t, ts, c)
+		second := initFlow(t, ts, c)
+
+		assert.Equal(t, http.StatusNoContent, submitFlow(t, ts, c, first, first))
+		assert.Equal(t, http.StatusNoContent, submitFlow(t, ts, c, second, second))
+		assert.Equal(t, http.StatusNoContent, submitFlow(t, ts, c, first, second))
+	})
+
+	t.Run("case=tokens are rejected across flows if per-flow tokens are enabled", func(t *testing.T) {
+		ts, c := newServer(t, true)
+		first := initFlow(t, ts, c)
+		assert.Equal(t, http.StatusNoContent, submitFlow(t, ts, c, first, first))
+
+		second := initFlow(t, ts, c)
+		assert.Equal(t, http.StatusNoContent, submitFlow(t, ts, c, second, second))
+
+		// The token of the first flow was rotated when the second flow was initialized.
+		assert.Equal(t, http.StatusForbidden, submitFlow(t, ts, c, first, first))
+		// The token of the second flow can not be used to complete the first flow.
+		assert.Equal(t, http.StatusForbidden, submitFlow(t, ts, c, first, second))
+	})
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
@@ -25,6 +26,7 @@ var (
 
 type (
 	errorHandlerDependencies interface {
+		event.EmitterProvider
 		config.Providers
 		errorx.ManagementProvider
 		x.WriterProvider
@@ -52,6 +54,7 @@ type (
 
 func NewFlowNeedsReAuth() *FlowNeedsReAuth {
 	return &FlowNeedsReAuth{DefaultError: herodot.ErrForbidden.
+		WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionRefreshRequired).
 		WithReasonf("The login session is too old and thus not allowed to update these fields. Please re-authenticate.")}
 }
 
@@ -61,6 +64,7 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 		ago: ago,
 		DefaultError: herodot.ErrBadRequest.
 			WithError("settings flow expired").
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowExpired).
 			WithReasonf(`The settings flow has expired. Please restart the flow.`).
 			WithReasonf("The settings flow expired %.2f minutes ago, please try again.", ago.Minutes()),
 	}
@@ -101,11 +105,15 @@ func (s *ErrorHandler) WriteFlowError(
 		WithField("settings_flow", f).
 		Info("Encountered self-service settings error.")
 
+	err = errorx.WithStrategy(err, method)
+
 	if f == nil {
 		s.forward(w, r, f, err)
 		return
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowFailed, "settings", f.ID, f.Type).WithStrategy(method).WithIdentity(f.IdentityID).WithError(err))
+
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
 		a, err := s.d.SettingsHandler().NewFlow(w, r, id, f.Type)
@@ -137,6 +145,7 @@ func (s *ErrorHandler) WriteFlowError(
 
 	if _, ok := f.Methods[method]; !ok {
 		s.forward(w, r, f, errors.WithStack(herodot.ErrInternalServerError.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodMissing).
 			WithErrorf(`Expected settings method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, method)))
 		return
 	}
@@ -174,9 +183,23 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 		return
 	}
 
+	s.clearSubmittedValues(r, rr)
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 	}
 }
+
+// clearSubmittedValues removes the values submitted by the user from the failed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (s *ErrorHandler) clearSubmittedValues(r *http.Request, f *Flow) {
+	if s.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	f.ResetSubmittedValues()
+	if err := s.d.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), f); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the failed settings flow.")
+	}
+}
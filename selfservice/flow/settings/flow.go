@@ -96,6 +96,9 @@ type Flow struct {
 	// required: true
 	State State `json:"state" faker:"-" db:"state"`
 
+	// CSRFToken contains the anti-csrf token associated with this flow. Only set for browser flows.
+	CSRFToken string `json:"-" db:"csrf_token"`
+
 	// IdentityID is a helper struct field for gobuffalo.pop.
 	IdentityID uuid.UUID `json:"-" faker:"-" db:"identity_id"`
 	// CreatedAt is a helper struct field for gobuffalo.pop.
@@ -148,8 +151,10 @@ func (r *Flow) AppendTo(settingsURL *url.URL) *url.URL {
 	return urlx.CopyWithQuery(settingsURL, url.Values{"flow": {r.ID.String()}})
 }
 
-func (r *Flow) Valid(s *session.Session) error {
-	if r.ExpiresAt.Before(time.Now().UTC()) {
+// Valid returns an error if the flow expired more than clockSkew ago, belongs to another identity, or is
+// submitted using an impersonation session.
+func (r *Flow) Valid(s *session.Session, clockSkew time.Duration) error {
+	if r.ExpiresAt.Add(clockSkew).Before(time.Now().UTC()) {
 		return errors.WithStack(NewFlowExpiredError(r.ExpiresAt))
 	}
 
@@ -158,6 +163,10 @@ func (r *Flow) Valid(s *session.Session) error {
 			"You must restart the flow because the resumable session was initiated by another person."))
 	}
 
+	if s.IsImpersonated() {
+		return errors.WithStack(session.ErrImpersonatedSession)
+	}
+
 	return nil
 }
 
@@ -183,3 +192,12 @@ func (r *Flow) AfterFind(_ *pop.Connection) error {
 	r.MethodsRaw = nil
 	return nil
 }
+
+// ResetSubmittedValues removes the values submitted by the user from all methods.
+func (r *Flow) ResetSubmittedValues() {
+	for _, m := range r.Methods {
+		if m.Config != nil && m.Config.FlowMethodConfigurator != nil {
+			m.Config.ResetSubmittedValues()
+		}
+	}
+}
@@ -100,9 +100,19 @@ func TestFlow(t *testing.T) {
 			s:         &session.Session{Identity: &identity.Identity{ID: alice}},
 			expectErr: true,
 		},
+		{
+			r: settings.NewFlow(
+				time.Hour,
+				&http.Request{URL: urlx.ParseOrPanic("http://foo/bar/baz"), Host: "foo"},
+				&identity.Identity{ID: alice},
+				flow.TypeBrowser,
+			),
+			s:         &session.Session{Identity: &identity.Identity{ID: alice}, Impersonator: "support@example.org"},
+			expectErr: true,
+		},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
-			err := tc.r.Valid(tc.s)
+			err := tc.r.Valid(tc.s, 0)
 			if tc.expectErr {
 				require.Error(t, err)
 				return
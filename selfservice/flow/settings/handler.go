@@ -10,11 +10,13 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/nosurf"
 	"github.com/ory/x/urlx"
@@ -34,7 +36,9 @@ func ContinuityKey(id string) string {
 
 type (
 	handlerDependencies interface {
+		event.EmitterProvider
 		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.LoggingProvider
 
@@ -87,7 +91,12 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 }
 
 func (h *Handler) NewFlow(w http.ResponseWriter, r *http.Request, i *identity.Identity, ft flow.Type) (*Flow, error) {
+	if err := flow.VerifyReturnTo(r, h.d.Configuration(r.Context()), "settings"); err != nil {
+		return nil, err
+	}
+
 	f := NewFlow(h.d.Configuration(r.Context()).SelfServiceFlowSettingsFlowLifespan(), r, i, ft)
+	f.CSRFToken = flow.CSRFToken(w, r, ft, h.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), h.d.CSRFHandler(), h.d.GenerateCSRFToken)
 	for _, strategy := range h.d.SettingsStrategies() {
 		if err := h.d.ContinuityManager().Abort(r.Context(), w, r, ContinuityKey(strategy.SettingsStrategyID())); err != nil {
 			return nil, err
@@ -102,6 +111,8 @@ func (h *Handler) NewFlow(w http.ResponseWriter, r *http.Request, i *identity.Id
 		return nil, err
 	}
 
+	h.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowCreated, "settings", f.ID, f.Type).WithIdentity(i.ID))
+
 	return f, nil
 }
 
@@ -268,11 +279,11 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, checkSession
 		}
 
 		if pr.IdentityID != sess.Identity.ID {
-			return errors.WithStack(herodot.ErrForbidden.WithReasonf("The request was made for another identity and has been blocked for security reasons."))
+			return errors.WithStack(herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityIdentityMismatch).WithReasonf("The request was made for another identity and has been blocked for security reasons."))
 		}
 	}
 
-	if pr.ExpiresAt.Before(time.Now().UTC()) {
+	if pr.ExpiresAt.Add(h.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()).Before(time.Now().UTC()) {
 		if pr.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The settings flow has expired. Redirect the user to the settings flow init endpoint to initialize a new settings flow.").
@@ -3,12 +3,12 @@ package settings
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/x"
@@ -28,6 +28,7 @@ type (
 		PostSettingsPostPersistHooks(settingsType string) []PostHookPostPersistExecutor
 	}
 	executorDependencies interface {
+		event.EmitterProvider
 		identity.ManagementProvider
 		identity.ValidationProvider
 		config.Providers
@@ -119,9 +120,18 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		e.d.Logger().WithRequest(r).WithFields(logFields).Debug("ExecuteSettingsPrePersistHook completed successfully.")
 	}
 
-	options := []identity.ManagerOption{identity.ManagerExposeValidationErrorsForInternalTypeAssertion}
-	ttl := e.d.Configuration(r.Context()).SelfServiceFlowSettingsPrivilegedSessionMaxAge()
-	if ctxUpdate.Session.AuthenticatedAt.Add(ttl).After(time.Now()) {
+	conf := e.d.Configuration(r.Context())
+	privileged := IsPrivileged(conf, ctxUpdate.Session)
+	if !privileged && privilegedTraitsChanged(conf, ctxUpdate.Session.Identity.Traits, i.Traits) {
+		e.d.Logger().Debug("Modifying privileged traits requires re-authentication.")
+		return errors.WithStack(NewFlowNeedsReAuth())
+	}
+
+	options := []identity.ManagerOption{
+		identity.ManagerExposeValidationErrorsForInternalTypeAssertion,
+		identity.ManagerEnforceEmailChangeCooldown,
+	}
+	if privileged || !RequiresPrivilegedSession(conf, identity.CredentialsType(settingsType)) {
 		options = append(options, identity.ManagerAllowWriteProtectedTraits)
 	}
 
@@ -136,6 +146,7 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		WithRequest(r).
 		WithField("identity_id", i.ID).
 		Debug("An identity's settings have been updated.")
+	e.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "settings", ctxUpdate.Flow.ID, ctxUpdate.Flow.Type).WithStrategy(settingsType).WithIdentity(i.ID))
 
 	ctxUpdate.Session.Identity = i
 	ctxUpdate.Flow.State = StateSuccess
@@ -149,6 +160,8 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		method.Config.ResetMessages()
 	}
 
+	e.clearSubmittedValues(r, ctxUpdate.Flow)
+
 	if err := e.d.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), ctxUpdate.Flow); err != nil {
 		return err
 	}
@@ -199,3 +212,11 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 			e.d.Configuration(r.Context()).SelfServiceFlowSettingsReturnTo(settingsType,
 				ctxUpdate.Flow.AppendTo(e.d.Configuration(r.Context()).SelfServiceFlowSettingsUI()))))
 }
+
+// clearSubmittedValues removes the values submitted by the user from the completed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`. The flow is stored by the caller.
+func (e *HookExecutor) clearSubmittedValues(r *http.Request, a *Flow) {
+	if e.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() == config.PersistSubmittedDataUntilCompleted {
+		a.ResetSubmittedValues()
+	}
+}
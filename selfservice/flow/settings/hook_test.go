@@ -1,6 +1,7 @@
 package settings_test
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"testing"
@@ -10,15 +11,19 @@ import (
 
 	"github.com/gobuffalo/httptest"
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/herodot"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
@@ -153,3 +158,74 @@ func TestSettingsExecutor(t *testing.T) {
 		})
 	}
 }
+
+func TestSettingsPersistSubmittedData(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/")
+
+	var f *settings.Flow
+	var sess *session.Session
+	router := httprouter.New()
+	router.GET("/settings/post", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		_ = testhelpers.SelfServiceHookSettingsErrorHandler(t, w, r, reg.SettingsHookExecutor().
+			PostSettingsHook(w, r, settings.StrategyProfile, &settings.UpdateContext{Flow: f, Session: sess}, sess.Identity))
+	})
+	router.GET("/settings/error", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		reg.SettingsFlowErrorHandler().WriteFlowError(w, r, settings.StrategyProfile, f, sess.Identity,
+			errors.WithStack(herodot.ErrInternalServerError.WithReason("system error")))
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyPublicBaseURL, ts.URL)
+
+	run := func(t *testing.T, path string, expectedCode int) map[string]interface{} {
+		r, err := http.NewRequest("GET", ts.URL+"/settings", nil)
+		require.NoError(t, err)
+
+		sess = session.NewActiveSession(testhelpers.SelfServiceHookCreateFakeIdentity(t, reg), conf, time.Now().UTC())
+		c := form.NewHTMLForm(ts.URL)
+		c.SetCSRF(x.FakeCSRFToken)
+		c.SetField(form.Field{Name: "traits.email", Type: "email", Value: "foo@ory.sh"})
+
+		f = settings.NewFlow(time.Minute, r, sess.Identity, flow.TypeAPI)
+		f.Methods[settings.StrategyProfile] = &settings.FlowMethod{
+			Method: settings.StrategyProfile,
+			Config: &settings.FlowMethodConfig{FlowMethodConfigurator: c},
+		}
+		require.NoError(t, reg.SettingsFlowPersister().CreateSettingsFlow(context.Background(), f))
+
+		res, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, expectedCode, res.StatusCode)
+
+		actual, err := reg.SettingsFlowPersister().GetSettingsFlow(context.Background(), f.ID)
+		require.NoError(t, err)
+
+		values := map[string]interface{}{}
+		for _, field := range actual.Methods[settings.StrategyProfile].Config.FlowMethodConfigurator.(*form.HTMLForm).Fields {
+			values[field.Name] = field.Value
+		}
+		return values
+	}
+
+	t.Run("case=keeps submitted values by default", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataAlways)
+
+		assert.Equal(t, "foo@ory.sh", run(t, "/settings/post", http.StatusOK)["traits.email"])
+		assert.Equal(t, "foo@ory.sh", run(t, "/settings/error", http.StatusInternalServerError)["traits.email"])
+	})
+
+	t.Run("case=clears submitted values once completed", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataUntilCompleted)
+
+		for name, actual := range map[string]map[string]interface{}{
+			"hook":  run(t, "/settings/post", http.StatusOK),
+			"error": run(t, "/settings/error", http.StatusInternalServerError),
+		} {
+			assert.Empty(t, actual["traits.email"], name)
+			assert.Equal(t, x.FakeCSRFToken, actual[form.CSRFTokenName], name)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/jsonschemax"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/session"
+)
+
+type privilegedChangesDependencies interface {
+	config.Providers
+	schema.IdentityTraitsProvider
+}
+
+// IsPrivileged returns true if the session was authenticated less than
+// `selfservice.flows.settings.privileged_session_max_age` ago.
+func IsPrivileged(c *config.Provider, s *session.Session) bool {
+	return s.AuthenticatedAt.Add(c.SelfServiceFlowSettingsPrivilegedSessionMaxAge()).After(time.Now())
+}
+
+// RequiresPrivilegedSession returns true if changing credentials of the given type requires a privileged
+// session as configured in `selfservice.flows.settings.privileged_changes.credentials`.
+func RequiresPrivilegedSession(c *config.Provider, ct identity.CredentialsType) bool {
+	return c.SelfServiceFlowSettingsPrivilegedChanges().Credentials[string(ct)] != config.SettingsGateSession
+}
+
+// CredentialsChangeNeedsReAuth returns true if the session may not change credentials of the given type
+// without signing in again.
+func CredentialsChangeNeedsReAuth(c *config.Provider, s *session.Session, ct identity.CredentialsType) bool {
+	return RequiresPrivilegedSession(c, ct) && !IsPrivileged(c, s)
+}
+
+// privilegedTraitsChanged returns true if one of the traits configured in
+// `selfservice.flows.settings.privileged_changes.traits` differs between the original and the updated traits.
+func privilegedTraitsChanged(c *config.Provider, original, updated identity.Traits) bool {
+	for _, path := range c.SelfServiceFlowSettingsPrivilegedChanges().Traits {
+		if gjson.GetBytes(original, path).Raw != gjson.GetBytes(updated, path).Raw {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePrivilegedChanges returns an error if `selfservice.flows.settings.privileged_changes` references
+// unknown credential types or traits which are not defined by any identity schema.
+func ValidatePrivilegedChanges(ctx context.Context, d privilegedChangesDependencies) error {
+	conf := d.Configuration(ctx).SelfServiceFlowSettingsPrivilegedChanges()
+
+	for ct := range conf.Credentials {
+		switch identity.CredentialsType(ct) {
+		case identity.CredentialsTypePassword, identity.CredentialsTypeOIDC:
+		default:
+			return errors.Errorf("selfservice.flows.settings.privileged_changes.credentials contains unknown credentials type %s", ct)
+		}
+	}
+
+	if len(conf.Traits) == 0 {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, s := range d.IdentityTraitsSchemas(ctx) {
+		paths, err := jsonschemax.ListPaths(s.URL.String(), jsonschema.NewCompiler())
+		if err != nil {
+			return errors.Wrapf(err, "unable to list the traits of identity schema %s", s.ID)
+		}
+		for _, p := range paths {
+			known[strings.TrimPrefix(p.Name, "traits.")] = true
+		}
+	}
+
+	for _, trait := range conf.Traits {
+		if !known[trait] {
+			return errors.Errorf("selfservice.flows.settings.privileged_changes.traits contains trait %s which is not defined by any identity schema", trait)
+		}
+	}
+
+	return nil
+}
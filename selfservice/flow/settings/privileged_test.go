@@ -0,0 +1,92 @@
+package settings_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+)
+
+func TestPrivilegedChanges(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, "1h")
+
+	i := testhelpers.SelfServiceHookCreateFakeIdentity(t, reg)
+	stale := session.NewActiveSession(i, conf, time.Now().UTC().Add(-2*time.Hour))
+	fresh := session.NewActiveSession(i, conf, time.Now().UTC())
+
+	t.Run("case=credentials require a privileged session by default", func(t *testing.T) {
+		assert.True(t, settings.CredentialsChangeNeedsReAuth(conf, stale, identity.CredentialsTypePassword))
+		assert.True(t, settings.CredentialsChangeNeedsReAuth(conf, stale, identity.CredentialsTypeOIDC))
+		assert.False(t, settings.CredentialsChangeNeedsReAuth(conf, fresh, identity.CredentialsTypePassword))
+	})
+
+	t.Run("case=credentials can be changed with a plain session", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesCredentials, map[string]interface{}{
+			"password": config.SettingsGatePrivilegedSession,
+			"oidc":     config.SettingsGateSession,
+		})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesCredentials, nil)
+		})
+
+		assert.True(t, settings.CredentialsChangeNeedsReAuth(conf, stale, identity.CredentialsTypePassword))
+		assert.False(t, settings.CredentialsChangeNeedsReAuth(conf, stale, identity.CredentialsTypeOIDC))
+	})
+
+	t.Run("case=privileged traits require a privileged session", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, []string{"stringy"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, nil)
+		})
+
+		run := func(t *testing.T, sess *session.Session, traits string) error {
+			r := httptest.NewRequest("POST", "/settings", nil)
+			f := settings.NewFlow(time.Minute, r, sess.Identity, flow.TypeBrowser)
+			require.NoError(t, reg.SettingsFlowPersister().CreateSettingsFlow(context.Background(), f))
+
+			update := *sess.Identity
+			update.Traits = identity.Traits(traits)
+			return reg.SettingsHookExecutor().PostSettingsHook(httptest.NewRecorder(), r,
+				settings.StrategyProfile, &settings.UpdateContext{Flow: f, Session: sess}, &update)
+		}
+
+		err := run(t, stale, `{"stringy":"changed"}`)
+		require.Error(t, err)
+		assert.True(t, errors.As(err, new(*settings.FlowNeedsReAuth)))
+
+		assert.NoError(t, run(t, stale, `{"booly":true}`))
+		assert.NoError(t, run(t, fresh, `{"stringy":"changed"}`))
+	})
+
+	t.Run("method=ValidatePrivilegedChanges", func(t *testing.T) {
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesCredentials, nil)
+			conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, nil)
+		})
+
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesCredentials, map[string]interface{}{"password": "session"})
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, []string{"email", "stringy"})
+		require.NoError(t, settings.ValidatePrivilegedChanges(context.Background(), reg))
+
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, []string{"unknown"})
+		assert.Error(t, settings.ValidatePrivilegedChanges(context.Background(), reg))
+
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesTraits, nil)
+		conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedChangesCredentials, map[string]interface{}{"totp": "session"})
+		assert.Error(t, settings.ValidatePrivilegedChanges(context.Background(), reg))
+	})
+}
@@ -11,7 +11,8 @@ import (
 )
 
 const (
-	StrategyProfile = "profile"
+	StrategyProfile         = "profile"
+	StrategyAccountDeletion = "account_deletion"
 )
 
 var pkgName = reflect.TypeOf(Strategies{}).PkgPath()
@@ -19,6 +19,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -45,6 +46,7 @@ func (c UpdateContext) GetSessionIdentity() *identity.Identity {
 
 func PrepareUpdate(d interface {
 	x.LoggingProvider
+	config.Providers
 	continuity.ManagementProvider
 	session.ManagementProvider
 	FlowPersistenceProvider
@@ -62,12 +64,20 @@ func PrepareUpdate(d interface {
 	payload.SetFlowID(rid)
 	req, err := d.SettingsFlowPersister().GetSettingsFlow(r.Context(), rid)
 	if errors.Is(err, sqlcon.ErrNoRows) {
-		return new(UpdateContext), errors.WithStack(herodot.ErrNotFound.WithReasonf("The settings request could not be found. Please restart the flow."))
+		return new(UpdateContext), errors.WithStack(herodot.ErrNotFound.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowNotFound).WithReasonf("The settings request could not be found. Please restart the flow."))
 	} else if err != nil {
 		return new(UpdateContext), err
 	}
 
-	if err := req.Valid(ss); err != nil {
+	if err := req.Valid(ss, d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
+		if ss.IsImpersonated() {
+			d.Audit().
+				WithRequest(r).
+				WithField("session_id", ss.ID).
+				WithField("identity_id", ss.IdentityID).
+				WithField("impersonator", ss.Impersonator).
+				Info("Impersonation session was denied to update the identity's settings.")
+		}
 		return new(UpdateContext), err
 	}
 
@@ -105,7 +115,7 @@ func ContinuityOptions(p interface{}, i *identity.Identity) []continuity.Manager
 func GetFlowID(r *http.Request) (uuid.UUID, error) {
 	rid := x.ParseUUID(r.URL.Query().Get("flow"))
 	if rid == uuid.Nil {
-		return rid, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The request query parameter is missing or malformed."))
+		return rid, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowIDMissing).WithReasonf("The request query parameter is missing or malformed."))
 	}
 	return rid, nil
 }
@@ -117,7 +127,7 @@ func OnUnauthenticated(reg interface {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		handler := session.RedirectOnUnauthenticated(reg.Configuration(r.Context()).SelfServiceFlowLoginUI().String())
 		if x.IsJSONRequest(r) {
-			handler = session.RespondWithJSONErrorOnAuthenticated(reg.Writer(), herodot.ErrUnauthorized.WithReasonf("A valid ORY Session Cookie or ORY Session Token is missing."))
+			handler = session.RespondWithJSONErrorOnAuthenticated(reg.Writer(), herodot.ErrUnauthorized.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithReasonf("A valid ORY Session Cookie or ORY Session Token is missing."))
 		}
 
 		handler(w, r, ps)
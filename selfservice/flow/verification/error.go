@@ -13,6 +13,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/text"
@@ -21,10 +22,12 @@ import (
 
 type (
 	errorHandlerDependencies interface {
+		event.EmitterProvider
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFTokenGeneratorProvider
+		x.CSRFProvider
 		config.Providers
 		FlowPersistenceProvider
 		StrategyProvider
@@ -50,6 +53,7 @@ func NewFlowExpiredError(at time.Time) *FlowExpiredError {
 		ago: ago,
 		DefaultError: herodot.ErrBadRequest.
 			WithError("verification flow expired").
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowExpired).
 			WithReasonf(`The verification flow has expired. Please restart the flow.`).
 			WithReasonf("The verification flow expired %.2f minutes ago, please try again.", ago.Minutes()),
 	}
@@ -72,15 +76,19 @@ func (s *ErrorHandler) WriteFlowError(
 		WithField("verification_flow", f).
 		Info("Encountered self-service verification error.")
 
+	err = errorx.WithStrategy(err, methodName)
+
 	if f == nil {
 		s.forward(w, r, nil, err)
 		return
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowFailed, "verification", f.ID, f.Type).WithStrategy(methodName).WithError(err))
+
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
 		a, err := NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowVerificationRequestLifespan(),
-			s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), f.Type)
+			flow.CSRFToken(w, r, f.Type, s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.CSRFHandler(), s.d.GenerateCSRFToken), r, s.d.VerificationStrategies(), f.Type)
 		if err != nil {
 			// failed to create a new session and redirect to it, handle that error as a new one
 			s.WriteFlowError(w, r, methodName, f, err)
@@ -105,6 +113,7 @@ func (s *ErrorHandler) WriteFlowError(
 	method, ok := f.Methods[methodName]
 	if !ok {
 		s.forward(w, r, f, errors.WithStack(herodot.ErrInternalServerError.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodMissing).
 			WithErrorf(`Expected verification method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, methodName)))
 		return
 	}
@@ -143,9 +152,23 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 		return
 	}
 
+	s.clearSubmittedValues(r, rr)
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 	}
 }
+
+// clearSubmittedValues removes the values submitted by the user from the failed flow if
+// `selfservice.flows.persist_submitted_data` is set to `until_completed`.
+func (s *ErrorHandler) clearSubmittedValues(r *http.Request, f *Flow) {
+	if s.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() != config.PersistSubmittedDataUntilCompleted {
+		return
+	}
+
+	f.ResetSubmittedValues()
+	if err := s.d.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to remove the submitted values from the failed verification flow.")
+	}
+}
@@ -114,8 +114,9 @@ func NewFlow(exp time.Duration, csrf string, r *http.Request, strategies Strateg
 	return f, nil
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the flow expired more than clockSkew ago.
+func (f *Flow) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -163,3 +164,12 @@ func (f *Flow) AfterFind(_ *pop.Connection) error {
 	f.MethodsRaw = nil
 	return nil
 }
+
+// ResetSubmittedValues removes the values submitted by the user from all methods.
+func (f *Flow) ResetSubmittedValues() {
+	for _, m := range f.Methods {
+		if m.Config != nil && m.Config.FlowMethodConfigurator != nil {
+			m.Config.ResetSubmittedValues()
+		}
+	}
+}
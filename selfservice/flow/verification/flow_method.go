@@ -60,9 +60,11 @@ type FlowMethodConfigurator interface {
 	form.FieldUnsetter
 	form.ValueSetter
 	form.Resetter
+	form.SubmittedValuesResetter
 	form.MessageResetter
 	form.CSRFSetter
 	form.FieldSorter
+	form.FieldGrouper
 	form.MessageAdder
 }
 
@@ -0,0 +1,65 @@
+package verification
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// ErrHookAbortFlow is returned by a pre-flow hook which has already written the response and wants to abort the flow.
+var ErrHookAbortFlow = errors.New("aborted verification hook execution")
+
+type (
+	PreHookExecutor interface {
+		ExecuteVerificationPreHook(w http.ResponseWriter, r *http.Request, a *Flow) error
+	}
+	PreHookExecutorFunc func(w http.ResponseWriter, r *http.Request, a *Flow) error
+
+	HooksProvider interface {
+		PreVerificationHooks() []PreHookExecutor
+	}
+)
+
+func (f PreHookExecutorFunc) ExecuteVerificationPreHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
+	return f(w, r, a)
+}
+
+type (
+	executorDependencies interface {
+		config.Providers
+		HooksProvider
+	}
+	HookExecutor struct {
+		d executorDependencies
+	}
+	HookExecutorProvider interface {
+		VerificationExecutor() *HookExecutor
+	}
+)
+
+func NewHookExecutor(d executorDependencies) *HookExecutor {
+	return &HookExecutor{d: d}
+}
+
+// PreVerificationHook runs the configured pre-flow hooks. The hooks may modify the flow's methods before it is
+// stored or abort the flow by returning an error.
+func (e *HookExecutor) PreVerificationHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
+	for _, executor := range e.d.PreVerificationHooks() {
+		if err := executor.ExecuteVerificationPreHook(w, r, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PostVerificationHook is called once the verification flow passed the challenge. It removes the values submitted by the
+// user from the flow if `selfservice.flows.persist_submitted_data` is set to `until_completed`. The flow is
+// stored by the caller.
+func (e *HookExecutor) PostVerificationHook(r *http.Request, a *Flow) {
+	if e.d.Configuration(r.Context()).SelfServiceFlowPersistSubmittedData() == config.PersistSubmittedDataUntilCompleted {
+		a.ResetSubmittedValues()
+	}
+}
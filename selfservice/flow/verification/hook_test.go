@@ -0,0 +1,102 @@
+package verification_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/httptest"
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+func TestVerificationPersistSubmittedData(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceVerificationEnabled, true)
+
+	var f *verification.Flow
+	router := httprouter.New()
+	router.GET("/verification/error", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		reg.VerificationFlowErrorHandler().WriteFlowError(w, r, verification.StrategyVerificationLinkName, f,
+			errors.WithStack(herodot.ErrInternalServerError.WithReason("system error")))
+	})
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	newFlow := func(t *testing.T) *verification.Flow {
+		r, err := http.NewRequest("GET", ts.URL+"/verification", nil)
+		require.NoError(t, err)
+
+		f, err := verification.NewFlow(time.Minute, x.FakeCSRFToken, r, reg.VerificationStrategies(), flow.TypeAPI)
+		require.NoError(t, err)
+
+		c := form.NewHTMLForm(ts.URL)
+		c.SetCSRF(x.FakeCSRFToken)
+		c.SetField(form.Field{Name: "email", Type: "email", Value: "foo@ory.sh"})
+		f.Methods[verification.StrategyVerificationLinkName] = &verification.FlowMethod{
+			Method: verification.StrategyVerificationLinkName,
+			Config: &verification.FlowMethodConfig{FlowMethodConfigurator: c},
+		}
+		require.NoError(t, reg.VerificationFlowPersister().CreateVerificationFlow(context.Background(), f))
+		return f
+	}
+
+	values := func(t *testing.T, id uuid.UUID) map[string]interface{} {
+		actual, err := reg.VerificationFlowPersister().GetVerificationFlow(context.Background(), id)
+		require.NoError(t, err)
+
+		result := map[string]interface{}{}
+		for _, field := range actual.Methods[verification.StrategyVerificationLinkName].Config.FlowMethodConfigurator.(*form.HTMLForm).Fields {
+			result[field.Name] = field.Value
+		}
+		return result
+	}
+
+	complete := func(t *testing.T) map[string]interface{} {
+		f := newFlow(t)
+		r, err := http.NewRequest("GET", ts.URL+"/verification", nil)
+		require.NoError(t, err)
+
+		reg.VerificationExecutor().PostVerificationHook(r, f)
+		require.NoError(t, reg.VerificationFlowPersister().UpdateVerificationFlow(context.Background(), f))
+		return values(t, f.ID)
+	}
+
+	fail := func(t *testing.T) map[string]interface{} {
+		f = newFlow(t)
+		res, err := ts.Client().Get(ts.URL + "/verification/error")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		return values(t, f.ID)
+	}
+
+	t.Run("case=keeps submitted values by default", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataAlways)
+
+		assert.Equal(t, "foo@ory.sh", complete(t)["email"])
+		assert.Equal(t, "foo@ory.sh", fail(t)["email"])
+	})
+
+	t.Run("case=clears submitted values once completed", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServicePersistSubmittedData, config.PersistSubmittedDataUntilCompleted)
+
+		for name, actual := range map[string]map[string]interface{}{"hook": complete(t), "error": fail(t)} {
+			assert.Empty(t, actual["email"], name)
+			assert.Equal(t, x.FakeCSRFToken, actual[form.CSRFTokenName], name)
+		}
+	})
+}
@@ -52,6 +52,11 @@ type Resetter interface {
 	Reset(exclude ...string)
 }
 
+type SubmittedValuesResetter interface {
+	// ResetSubmittedValues removes the values submitted by the user but keeps hidden fields such as the CSRF token.
+	ResetSubmittedValues()
+}
+
 type MessageResetter interface {
 	// ResetMessages resets the form's or field's messages..
 	ResetMessages(exclude ...string)
@@ -60,3 +65,8 @@ type MessageResetter interface {
 type FieldSorter interface {
 	SortFields(schemaRef string) error
 }
+
+type FieldGrouper interface {
+	// GroupFields assigns the group to all fields which do not belong to a group yet and sorts the fields by group.
+	GroupFields(group FieldGroup)
+}
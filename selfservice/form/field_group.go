@@ -0,0 +1,95 @@
+package form
+
+import "sort"
+
+// FieldGroup groups form fields which belong together, for example the fields of a credentials method.
+type FieldGroup string
+
+const (
+	// FieldGroupDefault contains fields which are shared by all methods, such as the CSRF token.
+	FieldGroupDefault FieldGroup = "default"
+	// FieldGroupProfile contains the fields of the profile settings method.
+	FieldGroupProfile FieldGroup = "profile"
+	// FieldGroupPassword contains the fields of the password method.
+	FieldGroupPassword FieldGroup = "password"
+	// FieldGroupLink contains the fields of the recovery and verification link methods.
+	FieldGroupLink FieldGroup = "link"
+	// FieldGroupOIDC contains the fields of the OpenID Connect method, such as the provider buttons.
+	FieldGroupOIDC FieldGroup = "oidc"
+	// FieldGroupDeletion contains the fields of the account deletion settings method.
+	FieldGroupDeletion FieldGroup = "deletion"
+)
+
+// fieldGroupsInOrder defines the order in which groups are rendered. Unknown groups are rendered
+// after the known groups.
+var fieldGroupsInOrder = []FieldGroup{
+	FieldGroupDefault,
+	FieldGroupProfile,
+	FieldGroupPassword,
+	FieldGroupLink,
+	FieldGroupOIDC,
+	FieldGroupDeletion,
+}
+
+func (g FieldGroup) position() int {
+	for i, group := range fieldGroupsInOrder {
+		if g == group {
+			return i
+		}
+	}
+	return len(fieldGroupsInOrder)
+}
+
+// SortByGroup sorts the fields by group, renders submit buttons after the other fields of their
+// group, and then sorts them by order. Fields which are equal keep their relative position, so the
+// result is deterministic.
+func (ff Fields) SortByGroup() {
+	sort.SliceStable(ff, func(i, j int) bool {
+		return ff.less(i, j, true)
+	})
+}
+
+func (ff Fields) less(i, j int, byOrder bool) bool {
+	a, b := ff[i], ff[j]
+	if pa, pb := a.Group.position(), b.Group.position(); pa != pb {
+		return pa < pb
+	} else if a.Group != b.Group {
+		return a.Group < b.Group
+	}
+
+	if sa, sb := a.Type == "submit", b.Type == "submit"; sa != sb {
+		return sb
+	}
+
+	return byOrder && a.Order < b.Order
+}
+
+// GroupFields assigns the group to all fields which do not belong to a group yet and assigns the
+// CSRF token to the default group. The fields are then sorted like SortByGroup, keeping the current
+// order within each group (e.g. from SortFields), and numbered within their group, starting at 1.
+func (c *HTMLForm) GroupFields(group FieldGroup) {
+	c.defaults()
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range c.Fields {
+		if c.Fields[i].Name == CSRFTokenName {
+			c.Fields[i].Group = FieldGroupDefault
+		} else if c.Fields[i].Group == "" {
+			c.Fields[i].Group = group
+		}
+	}
+
+	sort.SliceStable(c.Fields, func(i, j int) bool {
+		return c.Fields.less(i, j, false)
+	})
+
+	order := 0
+	for i := range c.Fields {
+		if i == 0 || c.Fields[i].Group != c.Fields[i-1].Group {
+			order = 0
+		}
+		order++
+		c.Fields[i].Order = order
+	}
+}
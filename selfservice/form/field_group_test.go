@@ -0,0 +1,48 @@
+package form
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupFields(t *testing.T) {
+	f := &HTMLForm{Fields: Fields{
+		{Name: "provider", Type: "submit", Group: FieldGroupOIDC},
+		{Name: "identifier", Type: "text"},
+		{Name: "submit", Type: "submit"},
+		{Name: "password", Type: "password"},
+	}}
+	f.SetCSRF("csrf-token")
+	f.GroupFields(FieldGroupPassword)
+
+	assert.EqualValues(t, Fields{
+		{Name: CSRFTokenName, Type: "hidden", Required: true, Value: "csrf-token", Group: FieldGroupDefault, Order: 1},
+		{Name: "identifier", Type: "text", Group: FieldGroupPassword, Order: 1},
+		{Name: "password", Type: "password", Group: FieldGroupPassword, Order: 2},
+		{Name: "submit", Type: "submit", Group: FieldGroupPassword, Order: 3},
+		{Name: "provider", Type: "submit", Group: FieldGroupOIDC, Order: 1},
+	}, f.Fields)
+
+	t.Run("case=set field keeps group and order", func(t *testing.T) {
+		f.SetField(Field{Name: "identifier", Type: "text", Value: "foo"})
+		assert.Equal(t, Field{Name: "identifier", Type: "text", Value: "foo", Group: FieldGroupPassword, Order: 1}, f.Fields[1])
+	})
+}
+
+func TestSortByGroup(t *testing.T) {
+	ff := Fields{
+		{Name: "custom", Group: "zzz", Order: 1},
+		{Name: "provider", Type: "submit", Group: FieldGroupOIDC, Order: 1},
+		{Name: "b", Group: FieldGroupPassword, Order: 2},
+		{Name: "a", Group: FieldGroupPassword, Order: 1},
+		{Name: CSRFTokenName, Group: FieldGroupDefault, Order: 1},
+	}
+	ff.SortByGroup()
+
+	var names []string
+	for _, f := range ff {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{CSRFTokenName, "a", "b", "provider", "custom"}, names)
+}
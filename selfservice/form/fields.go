@@ -56,6 +56,13 @@ type Field struct {
 
 	// Messages contains a list of messages (e.g. validation errors) that affect this field.
 	Messages text.Messages `json:"messages,omitempty"`
+
+	// Group is the group the field belongs to, e.g. `default` for the CSRF token, `password`, or `oidc`.
+	// Fields of the same group should be rendered together.
+	Group FieldGroup `json:"group,omitempty"`
+
+	// Order is the position of the field within its group, starting at 1.
+	Order int `json:"order,omitempty"`
 }
 
 // Reset resets a field's value and errors.
@@ -28,6 +28,8 @@ var (
 	_       ValueSetter = new(HTMLForm)
 	_       Resetter    = new(HTMLForm)
 	_       CSRFSetter  = new(HTMLForm)
+
+	_ SubmittedValuesResetter = new(HTMLForm)
 )
 
 // HTMLForm represents a HTML Form. The container can work with both HTTP Form and JSON requests
@@ -147,6 +149,21 @@ func (c *HTMLForm) Reset(exclude ...string) {
 	}
 }
 
+// ResetSubmittedValues removes the values of all fields except hidden fields and submit buttons
+// (e.g. the CSRF token or the OpenID Connect provider) which are not submitted by the user.
+func (c *HTMLForm) ResetSubmittedValues() {
+	c.defaults()
+	c.Lock()
+	defer c.Unlock()
+
+	for k, f := range c.Fields {
+		if f.Type != "hidden" && f.Type != "submit" {
+			f.Value = nil
+		}
+		c.Fields[k] = f
+	}
+}
+
 // ParseError type asserts the given error and sets the container's errors or a
 // field's errors and if the error is not something to be handled by the
 // form container, the error is returned.
@@ -160,6 +177,17 @@ func (c *HTMLForm) ParseError(err error) error {
 			return nil
 		}
 		return err
+	} else if e := new(schema.StructuredValidationError); errors.As(err, &e) {
+		for _, v := range e.Validations {
+			pointer, _ := jsonschemax.JSONPointerToDotNotation(v.InstancePtr)
+			for i := range v.Messages {
+				c.AddMessage(&v.Messages[i], pointer)
+			}
+		}
+		for i := range e.Messages {
+			c.AddMessage(&e.Messages[i])
+		}
+		return nil
 	} else if e := new(schema.ValidationError); errors.As(err, &e) {
 		pointer, _ := jsonschemax.JSONPointerToDotNotation(e.InstancePtr)
 		for i := range e.Messages {
@@ -266,7 +294,8 @@ func (c *HTMLForm) SetCSRF(token string) {
 	})
 }
 
-// SetField sets a field.
+// SetField sets a field. If the field replaces a field which belongs to a group, the group and the order
+// are kept unless the new field sets a group itself.
 func (c *HTMLForm) SetField(field Field) {
 	c.defaults()
 	c.Lock()
@@ -274,6 +303,9 @@ func (c *HTMLForm) SetField(field Field) {
 
 	for i := range c.Fields {
 		if c.Fields[i].Name == field.Name {
+			if field.Group == "" {
+				field.Group, field.Order = c.Fields[i].Group, c.Fields[i].Order
+			}
 			c.Fields[i] = field
 			return
 		}
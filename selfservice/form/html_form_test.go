@@ -196,6 +196,16 @@ func TestContainer(t *testing.T) {
 	})
 
 	t.Run("method=ParseError", func(t *testing.T) {
+		fieldMessage := text.NewErrorValidationSchema("test", "#/foo/bar", "minLength", 3, "ab")
+		summaryMessage := text.NewErrorValidationSchemaSummary([]string{"#/foo/bar"})
+		structured := &schema.StructuredValidationError{
+			Validations: []*schema.ValidationError{{
+				ValidationError: &jsonschema.ValidationError{Message: "test", InstancePtr: "#/foo/bar"},
+				Messages:        text.Messages{*fieldMessage},
+			}},
+			Messages: text.Messages{*summaryMessage},
+		}
+
 		for k, tc := range []struct {
 			err       error
 			expectErr bool
@@ -207,6 +217,7 @@ func TestContainer(t *testing.T) {
 			{err: schema.NewInvalidCredentialsError(), expect: HTMLForm{Fields: Fields{}, Messages: text.Messages{*text.NewErrorValidationInvalidCredentials()}}},
 			{err: &jsonschema.ValidationError{Message: "test", InstancePtr: "#/foo/bar/baz"}, expect: HTMLForm{Fields: Fields{Field{Name: "foo.bar.baz", Type: "", Messages: text.Messages{*text.NewValidationErrorGeneric("test")}}}}},
 			{err: &jsonschema.ValidationError{Message: "test", InstancePtr: ""}, expect: HTMLForm{Fields: Fields{}, Messages: text.Messages{*text.NewValidationErrorGeneric("test")}}},
+			{err: structured, expect: HTMLForm{Fields: Fields{Field{Name: "foo.bar", Type: "", Messages: text.Messages{*fieldMessage}}}, Messages: text.Messages{*summaryMessage}}},
 		} {
 			t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
 				for _, in := range []error{tc.err, errors.WithStack(tc.err)} {
@@ -305,6 +316,24 @@ func TestContainer(t *testing.T) {
 		assert.Empty(t, c.getField("2").Value)
 	})
 
+	t.Run("method=ResetSubmittedValues", func(t *testing.T) {
+		c := HTMLForm{
+			Fields: Fields{
+				{Name: "csrf_token", Type: "hidden", Value: "csrf"},
+				{Name: "provider", Type: "submit", Value: "github"},
+				{Name: "traits.email", Type: "email", Value: "foo@ory.sh", Messages: text.Messages{{Text: "foo"}}},
+				{Name: "password", Type: "password", Value: "secret"},
+			},
+		}
+		c.ResetSubmittedValues()
+
+		assert.Equal(t, "csrf", c.getField("csrf_token").Value)
+		assert.Equal(t, "github", c.getField("provider").Value)
+		assert.Empty(t, c.getField("traits.email").Value)
+		assert.NotEmpty(t, c.getField("traits.email").Messages)
+		assert.Empty(t, c.getField("password").Value)
+	})
+
 	t.Run("method=SortFields", func(t *testing.T) {
 		// use a schema compiler that disables identifiers
 		schemaCompiler := jsonschema.NewCompiler()
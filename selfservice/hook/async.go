@@ -0,0 +1,189 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/corp"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	AsyncJobStatusQueued AsyncJobStatus = "queued"
+	AsyncJobStatusDone   AsyncJobStatus = "done"
+	AsyncJobStatusFailed AsyncJobStatus = "failed"
+)
+
+const (
+	AsyncFlowTypeRegistration = "registration"
+	AsyncFlowTypeLogin        = "login"
+	AsyncFlowTypeSettings     = "settings"
+)
+
+var (
+	_ registration.PostHookPostPersistExecutor = new(AsyncHook)
+	_ login.PostHookExecutor                   = new(AsyncHook)
+	_ settings.PostHookPostPersistExecutor     = new(AsyncHook)
+)
+
+type (
+	// AsyncJobStatus is the state of an AsyncJob.
+	AsyncJobStatus string
+
+	// AsyncJob is the execution of a hook with `mode: async` stored in the outbox. It is executed by the
+	// AsyncWorker after the flow completed and retried until it succeeds or runs out of attempts.
+	AsyncJob struct {
+		ID uuid.UUID `json:"id" db:"id"`
+
+		// PartitionID is the identity partition the flow was completed in.
+		PartitionID string `json:"-" db:"partition_id"`
+
+		// Hook is the name of the hook, for example `revoke_active_sessions`.
+		Hook string `json:"hook" db:"hook"`
+
+		// Config is the configuration of the hook.
+		Config sqlxx.NullJSONRawMessage `json:"config" db:"config"`
+
+		// FlowType is the kind of flow which completed, for example `registration`.
+		FlowType string `json:"flow_type" db:"flow_type"`
+
+		// FlowID is the ID of the flow which completed.
+		FlowID uuid.UUID `json:"flow_id" db:"flow_id"`
+
+		// CredentialsType is the strategy which completed the flow.
+		CredentialsType string `json:"credentials_type" db:"credentials_type"`
+
+		// Payload is the session or, for settings flows, the identity the hook is executed for.
+		Payload sqlxx.JSONRawMessage `json:"-" db:"payload"`
+
+		Status AsyncJobStatus `json:"status" db:"status"`
+
+		// Attempts is the number of times the hook was executed.
+		Attempts int `json:"attempts" db:"attempts"`
+
+		// NextAttemptAt is the earliest time the hook is executed again.
+		NextAttemptAt time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+
+		// LastError is the error of the most recent failed attempt.
+		LastError string `json:"last_error" db:"last_error"`
+
+		// CreatedAt is a helper struct field for gobuffalo.pop.
+		CreatedAt time.Time `json:"created_at" db:"created_at"`
+		// UpdatedAt is a helper struct field for gobuffalo.pop.
+		UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	}
+
+	AsyncJobPersister interface {
+		// CreateAsyncJob queues the job in the partition of the context.
+		CreateAsyncJob(ctx context.Context, j *AsyncJob) error
+
+		// ListDueAsyncJobs returns queued jobs of all partitions which are due at the given time.
+		ListDueAsyncJobs(ctx context.Context, now time.Time, limit int) ([]AsyncJob, error)
+
+		// ClaimAsyncJob increments the attempts of the job and postpones its next attempt until the lease
+		// ends, so that other workers do not execute it at the same time. It returns false if the job was
+		// claimed by another worker in the meantime.
+		ClaimAsyncJob(ctx context.Context, j *AsyncJob, leaseUntil time.Time) (bool, error)
+
+		// UpdateAsyncJob stores the status, next attempt, and last error of the job. The payload is cleared once
+		// the job is done or failed.
+		UpdateAsyncJob(ctx context.Context, j *AsyncJob) error
+	}
+	AsyncJobPersistenceProvider interface {
+		AsyncJobPersister() AsyncJobPersister
+	}
+
+	asyncHookDependencies interface {
+		AsyncJobPersistenceProvider
+		x.LoggingProvider
+	}
+
+	// AsyncHook queues the execution of a hook configured with `mode: async` instead of executing it
+	// while the flow completes. Only the parts of a hook which run after the flow's data was persisted
+	// are executed, because the hook can no longer change the flow.
+	AsyncHook struct {
+		d               asyncHookDependencies
+		hook            config.SelfServiceHook
+		credentialsType string
+		inner           interface{}
+	}
+)
+
+func (j AsyncJob) TableName(ctx context.Context) string {
+	return corp.ContextualizeTableName(ctx, "selfservice_async_hook_jobs")
+}
+
+// SupportsAsync returns true if the hook can be executed asynchronously.
+func SupportsAsync(h interface{}) bool {
+	switch h.(type) {
+	case registration.PostHookPostPersistExecutor, login.PostHookExecutor, settings.PostHookPostPersistExecutor:
+		return true
+	}
+	return false
+}
+
+func NewAsyncHook(d asyncHookDependencies, credentialsType string, h config.SelfServiceHook, inner interface{}) *AsyncHook {
+	return &AsyncHook{d: d, hook: h, credentialsType: credentialsType, inner: inner}
+}
+
+func (e *AsyncHook) enqueue(r *http.Request, flowType string, flowID uuid.UUID, payload interface{}) error {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	j := &AsyncJob{
+		Hook:            e.hook.Name,
+		Config:          sqlxx.NullJSONRawMessage(e.hook.Config),
+		FlowType:        flowType,
+		FlowID:          flowID,
+		CredentialsType: e.credentialsType,
+		Payload:         p,
+		NextAttemptAt:   time.Now().UTC(),
+	}
+	if err := e.d.AsyncJobPersister().CreateAsyncJob(r.Context(), j); err != nil {
+		return err
+	}
+
+	e.d.Logger().
+		WithRequest(r).
+		WithField("hook", e.hook.Name).
+		WithField("job_id", j.ID).
+		WithField("flow_type", flowType).
+		Debug("Queued asynchronous hook.")
+	return nil
+}
+
+func (e *AsyncHook) ExecutePostRegistrationPostPersistHook(_ http.ResponseWriter, r *http.Request, a *registration.Flow, s *session.Session) error {
+	if _, ok := e.inner.(registration.PostHookPostPersistExecutor); !ok {
+		return nil
+	}
+	return e.enqueue(r, AsyncFlowTypeRegistration, a.ID, s)
+}
+
+func (e *AsyncHook) ExecuteLoginPostHook(_ http.ResponseWriter, r *http.Request, a *login.Flow, s *session.Session) error {
+	if _, ok := e.inner.(login.PostHookExecutor); !ok {
+		return nil
+	}
+	return e.enqueue(r, AsyncFlowTypeLogin, a.ID, s)
+}
+
+func (e *AsyncHook) ExecuteSettingsPostPersistHook(_ http.ResponseWriter, r *http.Request, a *settings.Flow, i *identity.Identity) error {
+	if _, ok := e.inner.(settings.PostHookPostPersistExecutor); !ok {
+		return nil
+	}
+	return e.enqueue(r, AsyncFlowTypeSettings, a.ID, i)
+}
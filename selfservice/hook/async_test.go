@@ -0,0 +1,123 @@
+package hook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+)
+
+func TestAsyncHook(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	ctx := context.Background()
+
+	conf.MustSet(config.ViperKeyPublicBaseURL, "http://localhost/")
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/stub.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceAsyncHooksMaxAttempts, 2)
+	conf.MustSet(config.ViperKeySelfServiceAsyncHooksRetryBackoff, "0s")
+
+	var i identity.Identity
+	require.NoError(t, faker.FakeData(&i))
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, &i))
+
+	completeLogin := func(t *testing.T, hookConfig string) uuid.UUID {
+		testhelpers.SelfServiceHookLoginViperSetPost(t, conf, identity.CredentialsTypePassword.String(), []config.SelfServiceHook{
+			{Name: "err", Config: []byte(hookConfig), Mode: config.HookModeAsync},
+		})
+
+		r := httptest.NewRequest("POST", "http://localhost/self-service/login", nil)
+		f := login.NewFlow(time.Minute, "", r, flow.TypeBrowser)
+		require.NoError(t, reg.LoginFlowPersister().CreateLoginFlow(ctx, f))
+
+		hooks := reg.PostLoginHooks(identity.CredentialsTypePassword)
+		require.Len(t, hooks, 1)
+		require.IsType(t, new(hook.AsyncHook), hooks[0])
+
+		// The hook fails, but the failure is not surfaced to the user.
+		require.NoError(t, hooks[0].ExecuteLoginPostHook(httptest.NewRecorder(), r, f, &session.Session{ID: f.ID, Identity: &i}))
+		return f.ID
+	}
+
+	job := func(t *testing.T, flowID uuid.UUID) hook.AsyncJob {
+		var j hook.AsyncJob
+		require.NoError(t, reg.Persister().GetConnection(ctx).Where("flow_id = ?", flowID).First(&j))
+		return j
+	}
+
+	due := func(t *testing.T) []hook.AsyncJob {
+		jobs, err := reg.AsyncJobPersister().ListDueAsyncJobs(ctx, time.Now().UTC(), 100)
+		require.NoError(t, err)
+		return jobs
+	}
+
+	t.Run("case=retries failing hooks until they run out of attempts", func(t *testing.T) {
+		t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+		flowID := completeLogin(t, `{"ExecuteLoginPostHook": "err"}`)
+
+		jobs := due(t)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "err", jobs[0].Hook)
+		assert.Equal(t, hook.AsyncFlowTypeLogin, jobs[0].FlowType)
+		assert.Equal(t, 0, jobs[0].Attempts)
+
+		executed, err := reg.AsyncHookWorker().Execute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, executed)
+
+		jobs = due(t)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, 1, jobs[0].Attempts)
+		assert.Equal(t, "err", jobs[0].LastError)
+
+		executed, err = reg.AsyncHookWorker().Execute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, executed)
+		assert.Empty(t, due(t))
+
+		failed := job(t, flowID)
+		assert.Equal(t, hook.AsyncJobStatusFailed, failed.Status)
+		assert.JSONEq(t, "{}", string(failed.Payload))
+	})
+
+	t.Run("case=executes passing hooks once", func(t *testing.T) {
+		t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+		flowID := completeLogin(t, `{}`)
+		assert.NotEqual(t, "{}", string(job(t, flowID).Payload))
+
+		executed, err := reg.AsyncHookWorker().Execute(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, executed)
+		assert.Empty(t, due(t))
+
+		done := job(t, flowID)
+		assert.Equal(t, hook.AsyncJobStatusDone, done.Status)
+		assert.JSONEq(t, "{}", string(done.Payload))
+	})
+
+	t.Run("case=executes hooks without a mode blocking", func(t *testing.T) {
+		t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+		testhelpers.SelfServiceHookLoginViperSetPost(t, conf, identity.CredentialsTypePassword.String(), []config.SelfServiceHook{
+			{Name: "err", Config: []byte(`{"ExecuteLoginPostHook": "err"}`)},
+		})
+
+		hooks := reg.PostLoginHooks(identity.CredentialsTypePassword)
+		require.Len(t, hooks, 1)
+		assert.Error(t, hooks[0].ExecuteLoginPostHook(httptest.NewRecorder(), new(http.Request), nil, nil))
+		assert.Empty(t, due(t))
+	})
+}
@@ -0,0 +1,242 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/metrics/prometheus"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const asyncWorkerBatchSize = 50
+
+type (
+	asyncWorkerDependencies interface {
+		AsyncJobPersistenceProvider
+		AsyncHookResolver
+		registration.FlowPersistenceProvider
+		login.FlowPersistenceProvider
+		settings.FlowPersistenceProvider
+		config.Providers
+		x.LoggingProvider
+		PrometheusManager() *prometheus.MetricsManager
+	}
+	AsyncHookResolver interface {
+		// ResolveHook returns the hook for the configuration, ignoring its mode, or nil if the hook is unknown.
+		ResolveHook(credentialsType string, h config.SelfServiceHook) interface{}
+	}
+	AsyncWorkerProvider interface {
+		AsyncHookWorker() *AsyncWorker
+	}
+
+	// AsyncWorker executes the hooks queued by AsyncHook in the background.
+	AsyncWorker struct {
+		d asyncWorkerDependencies
+	}
+
+	// discardResponseWriter is passed to asynchronous hooks because the response was already sent.
+	discardResponseWriter struct {
+		header http.Header
+	}
+)
+
+func NewAsyncWorker(d asyncWorkerDependencies) *AsyncWorker {
+	return &AsyncWorker{d: d}
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+// Execute executes all due jobs and returns the number of executed jobs.
+func (w *AsyncWorker) Execute(ctx context.Context) (int, error) {
+	var executed int
+	for {
+		jobs, err := w.d.AsyncJobPersister().ListDueAsyncJobs(ctx, time.Now().UTC(), asyncWorkerBatchSize)
+		if err != nil {
+			return executed, err
+		}
+
+		for k := range jobs {
+			ok, err := w.execute(ctx, &jobs[k])
+			if err != nil {
+				return executed, err
+			} else if ok {
+				executed++
+			}
+		}
+
+		if len(jobs) < asyncWorkerBatchSize {
+			return executed, nil
+		}
+	}
+}
+
+func (w *AsyncWorker) execute(ctx context.Context, j *AsyncJob) (bool, error) {
+	conf := w.d.Configuration(ctx)
+	ctx = x.WithPartitionID(ctx, j.PartitionID)
+
+	backoff := conf.SelfServiceAsyncHooksRetryBackoff()
+	if claimed, err := w.d.AsyncJobPersister().ClaimAsyncJob(ctx, j, time.Now().UTC().Add(backoff)); err != nil {
+		return false, err
+	} else if !claimed {
+		return false, nil
+	}
+
+	l := w.d.Logger().
+		WithField("hook", j.Hook).
+		WithField("job_id", j.ID).
+		WithField("flow_type", j.FlowType).
+		WithField("attempt", j.Attempts)
+
+	if err := w.run(ctx, j); err != nil {
+		j.LastError = err.Error()
+		if j.Attempts >= conf.SelfServiceAsyncHooksMaxAttempts() {
+			j.Status = AsyncJobStatusFailed
+			w.d.PrometheusManager().AsyncHookExecuted(j.Hook, "failed")
+			l.WithError(err).Error("Asynchronous hook failed and will not be retried.")
+		} else {
+			j.NextAttemptAt = time.Now().UTC().Add(backoff << uint(j.Attempts-1))
+			w.d.PrometheusManager().AsyncHookExecuted(j.Hook, "retried")
+			l.WithError(err).WithField("next_attempt_at", j.NextAttemptAt).Warn("Asynchronous hook failed and will be retried.")
+		}
+	} else {
+		j.Status = AsyncJobStatusDone
+		j.LastError = ""
+		w.d.PrometheusManager().AsyncHookExecuted(j.Hook, "succeeded")
+		l.Debug("Asynchronous hook was executed.")
+	}
+
+	if err := w.d.AsyncJobPersister().UpdateAsyncJob(ctx, j); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (w *AsyncWorker) run(ctx context.Context, j *AsyncJob) error {
+	h := w.d.ResolveHook(j.CredentialsType, config.SelfServiceHook{Name: j.Hook, Config: json.RawMessage(j.Config)})
+	if h == nil {
+		return errors.Errorf("hook %s is unknown or misconfigured", j.Hook)
+	}
+
+	newRequest := func(requestURL string) (*http.Request, error) {
+		r, err := http.NewRequest("POST", requestURL, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return r.WithContext(ctx), nil
+	}
+
+	switch j.FlowType {
+	case AsyncFlowTypeRegistration:
+		e, ok := h.(registration.PostHookPostPersistExecutor)
+		if !ok {
+			return nil
+		}
+
+		var s session.Session
+		if err := json.Unmarshal(j.Payload, &s); err != nil {
+			return errors.WithStack(err)
+		}
+
+		f, err := w.d.RegistrationFlowPersister().GetRegistrationFlow(ctx, j.FlowID)
+		if err != nil {
+			return err
+		}
+
+		r, err := newRequest(f.RequestURL)
+		if err != nil {
+			return err
+		}
+		return e.ExecutePostRegistrationPostPersistHook(new(discardResponseWriter), r, f, restoreSession(&s))
+	case AsyncFlowTypeLogin:
+		e, ok := h.(login.PostHookExecutor)
+		if !ok {
+			return nil
+		}
+
+		var s session.Session
+		if err := json.Unmarshal(j.Payload, &s); err != nil {
+			return errors.WithStack(err)
+		}
+
+		f, err := w.d.LoginFlowPersister().GetLoginFlow(ctx, j.FlowID)
+		if err != nil {
+			return err
+		}
+
+		r, err := newRequest(f.RequestURL)
+		if err != nil {
+			return err
+		}
+		return e.ExecuteLoginPostHook(new(discardResponseWriter), r, f, restoreSession(&s))
+	case AsyncFlowTypeSettings:
+		e, ok := h.(settings.PostHookPostPersistExecutor)
+		if !ok {
+			return nil
+		}
+
+		var i identity.Identity
+		if err := json.Unmarshal(j.Payload, &i); err != nil {
+			return errors.WithStack(err)
+		}
+
+		f, err := w.d.SettingsFlowPersister().GetSettingsFlow(ctx, j.FlowID)
+		if err != nil {
+			return err
+		}
+
+		r, err := newRequest(f.RequestURL)
+		if err != nil {
+			return err
+		}
+		return e.ExecuteSettingsPostPersistHook(new(discardResponseWriter), r, f, &i)
+	}
+
+	return errors.Errorf("flow type %s is not supported by asynchronous hooks", j.FlowType)
+}
+
+// restoreSession sets the fields which are not part of the session's JSON representation.
+func restoreSession(s *session.Session) *session.Session {
+	if s.Identity != nil {
+		s.IdentityID = s.Identity.ID
+	}
+	return s
+}
+
+// Work executes due jobs periodically until the context is cancelled.
+func (w *AsyncWorker) Work(ctx context.Context) error {
+	for {
+		if _, err := w.Execute(ctx); err != nil {
+			w.d.Logger().WithError(err).Error("Unable to execute asynchronous hooks.")
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		case <-time.After(w.d.Configuration(ctx).SelfServiceAsyncHooksPollInterval()):
+		}
+	}
+}
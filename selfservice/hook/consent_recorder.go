@@ -0,0 +1,90 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/jsonx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var (
+	_ registration.PostHookPostPersistExecutor = new(ConsentRecorder)
+	_ registration.CustomFieldsHook            = new(ConsentRecorder)
+)
+
+type (
+	consentRecorderDependencies interface {
+		x.LoggingProvider
+	}
+	ConsentRecorderConfig struct {
+		// Fields are the names of the custom checkbox fields which record consent.
+		Fields []string `json:"fields"`
+
+		// Version is the version of the document the user consented to.
+		Version string `json:"version"`
+	}
+	// ConsentRecorder writes the consent given in custom checkbox fields, e.g. the acceptance of the
+	// terms of service, to the audit log.
+	ConsentRecorder struct {
+		r consentRecorderDependencies
+		c ConsentRecorderConfig
+	}
+)
+
+func NewConsentRecorder(r consentRecorderDependencies, config json.RawMessage) (*ConsentRecorder, error) {
+	var c ConsentRecorderConfig
+	if err := jsonx.NewStrictDecoder(bytes.NewBuffer(config)).Decode(&c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &ConsentRecorder{r: r, c: c}, nil
+}
+
+func (e *ConsentRecorder) ValidateCustomFields(fields []config.SelfServiceCustomField) error {
+	if len(e.c.Fields) == 0 {
+		return errors.Errorf("hook %s must record the consent of at least one custom field", KeyConsentRecorder)
+	}
+
+outer:
+	for _, name := range e.c.Fields {
+		for _, f := range fields {
+			if f.Name != name {
+				continue
+			} else if f.Type != "checkbox" {
+				return errors.Errorf("hook %s can only record the consent of checkbox fields but custom field %s is of type %s", KeyConsentRecorder, name, f.Type)
+			}
+			continue outer
+		}
+		return errors.Errorf("hook %s records the consent of custom field %s which is not declared", KeyConsentRecorder, name)
+	}
+
+	return nil
+}
+
+func (e *ConsentRecorder) ExecutePostRegistrationPostPersistHook(_ http.ResponseWriter, r *http.Request, a *registration.Flow, s *session.Session) error {
+	for _, name := range e.c.Fields {
+		if !gjson.GetBytes(a.CustomFields, name).Bool() {
+			continue
+		}
+
+		e.r.Audit().
+			WithRequest(r).
+			WithField("identity_id", s.Identity.ID).
+			WithField("flow_id", a.ID).
+			WithField("consent", name).
+			WithField("consent_version", e.c.Version).
+			WithField("consented_at", time.Now().UTC()).
+			Info("The identity gave consent during self-service registration.")
+	}
+
+	return nil
+}
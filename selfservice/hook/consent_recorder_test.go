@@ -0,0 +1,72 @@
+package hook_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+)
+
+func TestConsentRecorder(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	fields := []config.SelfServiceCustomField{
+		{Name: "company_name", Type: "text"},
+		{Name: "accept_terms", Type: "checkbox", Required: true},
+	}
+
+	t.Run("method=ValidateCustomFields", func(t *testing.T) {
+		for _, tc := range []struct {
+			config string
+			err    string
+		}{
+			{config: `{"fields":["accept_terms"],"version":"2021-01-01"}`},
+			{config: `{"fields":[]}`, err: "must record the consent of at least one custom field"},
+			{config: `{"fields":["accept_privacy_policy"]}`, err: "custom field accept_privacy_policy which is not declared"},
+			{config: `{"fields":["company_name"]}`, err: "custom field company_name is of type text"},
+		} {
+			t.Run("config="+tc.config, func(t *testing.T) {
+				h, err := hook.NewConsentRecorder(reg, json.RawMessage(tc.config))
+				require.NoError(t, err)
+
+				err = h.ValidateCustomFields(fields)
+				if tc.err == "" {
+					require.NoError(t, err)
+					return
+				}
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+
+	t.Run("method=NewConsentRecorder", func(t *testing.T) {
+		_, err := hook.NewConsentRecorder(reg, json.RawMessage(`{"fields":["accept_terms"],"unknown":true}`))
+		require.Error(t, err)
+	})
+
+	t.Run("method=ExecutePostRegistrationPostPersistHook", func(t *testing.T) {
+		h, err := hook.NewConsentRecorder(reg, json.RawMessage(`{"fields":["accept_terms"]}`))
+		require.NoError(t, err)
+
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		for _, values := range []string{`{"accept_terms":true}`, `{"accept_terms":false}`, ``} {
+			f := &registration.Flow{CustomFields: json.RawMessage(values)}
+			require.NoError(t, h.ExecutePostRegistrationPostPersistHook(
+				httptest.NewRecorder(),
+				httptest.NewRequest("POST", "/self-service/registration/methods/password", nil),
+				f,
+				&session.Session{Identity: i},
+			))
+		}
+	})
+}
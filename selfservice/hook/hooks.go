@@ -3,4 +3,5 @@ package hook
 const (
 	KeySessionIssuer    = "session"
 	KeySessionDestroyer = "revoke_active_sessions"
+	KeyConsentRecorder  = "record_consent"
 )
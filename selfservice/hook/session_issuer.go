@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/session"
@@ -18,6 +19,7 @@ var (
 
 type (
 	sessionIssuerDependencies interface {
+		identity.PrivilegedPoolProvider
 		session.ManagementProvider
 		session.PersistenceProvider
 		x.WriterProvider
@@ -36,6 +38,15 @@ func NewSessionIssuer(r sessionIssuerDependencies) *SessionIssuer {
 
 func (e *SessionIssuer) ExecutePostRegistrationPostPersistHook(w http.ResponseWriter, r *http.Request, a *registration.Flow, s *session.Session) error {
 	s.AuthenticatedAt = time.Now().UTC()
+
+	// Signing in right after the registration is the identity's first login.
+	count, err := e.r.PrivilegedIdentityPool().RecordLogin(r.Context(), s.Identity.ID, s.AuthenticatedAt)
+	if err != nil {
+		return err
+	}
+	s.Identity.LoginCount = count
+	s.FirstLogin = count == 1
+
 	if err := e.r.SessionPersister().CreateSession(r.Context(), s); err != nil {
 		return err
 	}
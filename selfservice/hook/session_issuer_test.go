@@ -45,6 +45,8 @@ func TestSessionIssuer(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, sid, got.ID)
 			assert.True(t, got.AuthenticatedAt.After(time.Now().Add(-time.Minute)))
+			assert.True(t, got.FirstLogin, "signing in after the registration is the first login")
+			assert.Equal(t, 1, got.Identity.LoginCount)
 
 			assert.Contains(t, w.Header().Get("Set-Cookie"), session.DefaultSessionCookieName)
 		})
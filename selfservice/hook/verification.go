@@ -52,7 +52,7 @@ func (e *Verifier) do(r *http.Request, i *identity.Identity) error {
 			return err
 		}
 
-		if err := e.r.LinkSender().SendVerificationTokenTo(r.Context(), address, token); err != nil {
+		if err := e.r.LinkSender().SendVerificationTokenTo(r, address, token); err != nil {
 			return err
 		}
 	}
@@ -2,17 +2,22 @@ package hook_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/kratos/courier"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
@@ -72,3 +77,44 @@ func TestVerifier(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifierQueuesSettingsMessages(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/verify.schema.json")
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://www.ory.sh/")
+	conf.MustSet(config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
+	conf.MustSet(config.ViperKeySelfServiceVerificationEnabled, true)
+
+	// Changing the email address of many identities at once must not send any message directly. All messages are
+	// queued and sent by the courier, which applies `courier.dispatch.rate_limit` to every message in the queue.
+	const identities = 20
+	for k := 0; k < identities; k++ {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(fmt.Sprintf(`{"emails":["old-%d@ory.sh"]}`, k))
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+		sess := session.NewActiveSession(i, conf, time.Now().UTC())
+
+		r := httptest.NewRequest("POST", "/self-service/settings", nil)
+		f := settings.NewFlow(time.Hour, r, i, flow.TypeAPI)
+		require.NoError(t, reg.SettingsFlowPersister().CreateSettingsFlow(context.Background(), f))
+
+		updated := *i
+		updated.Traits = identity.Traits(fmt.Sprintf(`{"emails":["new-%d@ory.sh"]}`, k))
+		w := httptest.NewRecorder()
+		require.NoError(t, reg.SettingsHookExecutor().PostSettingsHook(w, r, settings.StrategyProfile,
+			&settings.UpdateContext{Flow: f, Session: sess}, &updated))
+		require.Equal(t, http.StatusOK, w.Code, "%s", w.Body.String())
+	}
+
+	messages, err := reg.CourierPersister().NextMessages(context.Background(), 255)
+	require.NoError(t, err)
+
+	var sent int
+	for _, m := range messages {
+		if strings.HasPrefix(m.Recipient, "new-") {
+			sent++
+			assert.Equal(t, courier.MessageStatusQueued, m.Status, "%s", m.Recipient)
+		}
+	}
+	assert.Equal(t, identities, sent, "every verification message must be queued")
+}
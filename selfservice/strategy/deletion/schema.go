@@ -0,0 +1,7 @@
+package deletion
+
+import (
+	"github.com/markbates/pkger"
+)
+
+var _ = pkger.Dir("github.com/ory/kratos:/selfservice/strategy/deletion/.schema")
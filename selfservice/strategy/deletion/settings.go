@@ -0,0 +1,312 @@
+package deletion
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteSettings = "/self-service/settings/methods/account_deletion"
+)
+
+// ErrNotAllowed is returned when the identity's schema does not allow account deletion.
+var ErrNotAllowed = herodot.ErrForbidden.WithReason("Deleting this account is not allowed.")
+
+func (s *Strategy) RegisterSettingsRoutes(public *x.RouterPublic) {
+	s.d.CSRFHandler().IgnorePath(RouteSettings)
+
+	public.POST(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.d)))
+	public.GET(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.d)))
+}
+
+// swagger:model settingsAccountDeletionFormConfig
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	c, err := s.Config(r.Context())
+	if err != nil {
+		return err
+	}
+
+	if !c.isEnabledFor(id) {
+		return nil
+	}
+
+	f.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
+		Method: s.SettingsStrategyID(),
+		Config: &settings.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: s.newForm(r, f)}},
+	}
+	return nil
+}
+
+func (s *Strategy) newForm(r *http.Request, f *settings.Flow) *form.HTMLForm {
+	hf := &form.HTMLForm{Action: urlx.CopyWithQuery(urlx.AppendPaths(s.d.Configuration(r.Context()).SelfPublicURL(), RouteSettings),
+		url.Values{"flow": {f.ID.String()}}).String(), Fields: form.Fields{{Name: "confirm",
+		Type: "checkbox", Required: true, Value: false}}, Method: "POST"}
+	hf.SetCSRF(s.d.GenerateCSRFToken(r))
+	hf.GroupFields(form.FieldGroupDeletion)
+	return hf
+}
+
+// nolint:deadcode,unused
+// swagger:parameters completeSelfServiceSettingsFlowWithAccountDeletionMethod
+type completeSelfServiceSettingsFlowWithAccountDeletionMethod struct {
+	// in: body
+	Body CompleteSelfServiceSettingsFlowWithAccountDeletionMethod
+
+	// Flow is flow ID.
+	//
+	// in: query
+	Flow string `json:"flow"`
+}
+
+type CompleteSelfServiceSettingsFlowWithAccountDeletionMethod struct {
+	// Confirm must be true to delete the account.
+	//
+	// required: true
+	Confirm bool `json:"confirm"`
+
+	// CSRFToken is the anti-CSRF token
+	//
+	// type: string
+	CSRFToken string `json:"csrf_token"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithAccountDeletionMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithAccountDeletionMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+// swagger:route POST /self-service/settings/methods/account_deletion public completeSelfServiceSettingsFlowWithAccountDeletionMethod
+//
+// Complete Settings Flow by Deleting the Account
+//
+// Use this endpoint to delete the identity's account. The identity is deleted respecting `identity.deletion.grace_period`
+// and all of its sessions are revoked. This endpoint behaves differently for API and browser flows.
+//
+// API-initiated flows expect `application/json` to be sent in the body and respond with
+//   - HTTP 204 when the account was deleted;
+//   - HTTP 302 redirect to a fresh settings flow if the original flow expired with the appropriate error messages set;
+//   - HTTP 400 on form validation errors, for example if `confirm` was not set.
+//   - HTTP 401 when the endpoint is called without a valid session token.
+//   - HTTP 403 when `selfservice.flows.settings.privileged_session_max_age` was reached.
+//     Implies that the user needs to re-authenticate.
+//
+// Browser flows expect `application/x-www-form-urlencoded` to be sent in the body and responds with
+//   - a HTTP 302 redirect to `selfservice.default_browser_return_url` when the account was deleted;
+//   - a HTTP 302 redirect to the Settings UI URL with the flow ID containing the validation errors otherwise.
+//   - a HTTP 302 redirect to the login endpoint when `selfservice.flows.settings.privileged_session_max_age` was reached.
+//
+// More information can be found at [ORY Kratos User Settings & Profile Management Documentation](../self-service/flows/user-settings).
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Security:
+//       sessionToken:
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       302: emptyResponse
+//       400: settingsFlow
+//       401: genericError
+//       403: genericError
+//       500: genericError
+func (s *Strategy) submitSettingsFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p CompleteSelfServiceSettingsFlowWithAccountDeletionMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		s.continueSettingsFlow(w, r, ctxUpdate, &p)
+		return
+	} else if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	if err := s.d.StrategyRateLimiter().Allow(r, "settings", s.SettingsStrategyID()); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	s.continueSettingsFlow(w, r, ctxUpdate, &p)
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(pkgerx.MustRead(pkger.Open("github.com/ory/kratos:/selfservice/strategy/deletion/.schema/settings.schema.json")))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return s.dc.Decode(r, dest, compiler,
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	w http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithAccountDeletionMethod,
+) {
+	if err := flow.VerifyRequest(r, ctxUpdate.Flow.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, ctxUpdate.Flow.CSRFToken, p.CSRFToken); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "settings", ctxUpdate.Flow.ID, ctxUpdate.Flow.Type).WithStrategy(s.SettingsStrategyID()).WithIdentity(ctxUpdate.Session.IdentityID))
+
+	c, err := s.Config(r.Context())
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	if !c.isEnabledFor(ctxUpdate.Session.Identity) {
+		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(ErrNotAllowed))
+		return
+	}
+
+	if ctxUpdate.Session.AuthenticatedAt.Add(s.d.Configuration(r.Context()).SelfServiceFlowSettingsPrivilegedSessionMaxAge()).Before(time.Now()) {
+		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(settings.NewFlowNeedsReAuth()))
+		return
+	}
+
+	if !p.Confirm {
+		s.handleSettingsError(w, r, ctxUpdate, p, schema.NewRequiredError("#/confirm", "confirm"))
+		return
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentity(r.Context(), ctxUpdate.Session.IdentityID)
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	if c.Notify {
+		if err := s.notify(r.Context(), i); err != nil {
+			s.handleSettingsError(w, r, ctxUpdate, p, err)
+			return
+		}
+	}
+
+	deleteAfter, err := s.d.IdentityJanitor().Delete(r.Context(), i.ID)
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("delete_after", deleteAfter).
+		Info("Identity deleted its own account using the settings flow.")
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "settings", ctxUpdate.Flow.ID, ctxUpdate.Flow.Type).WithStrategy(s.SettingsStrategyID()).WithIdentity(i.ID))
+
+	// All sessions were revoked already, this removes the session cookie.
+	if err := s.d.SessionManager().PurgeFromRequest(r.Context(), w, r); err != nil {
+		s.d.Logger().WithError(err).WithRequest(r).Warn("Unable to remove the session cookie after the account was deleted.")
+	}
+
+	if ctxUpdate.Flow.Type == flow.TypeAPI {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(w, r, s.d.Configuration(r.Context()).SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
+}
+
+// notify sends an email to the first email address of the identity. It is a no-op if the identity has none.
+func (s *Strategy) notify(ctx context.Context, i *identity.Identity) error {
+	var to string
+	for _, a := range i.RecoveryAddresses {
+		if a.Via == identity.RecoveryAddressTypeEmail {
+			to = a.Value
+			break
+		}
+	}
+	if len(to) == 0 {
+		for _, a := range i.VerifiableAddresses {
+			if a.Via == identity.VerifiableAddressTypeEmail {
+				to = a.Value
+				break
+			}
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	var deleteAfter *time.Time
+	if grace := s.d.Configuration(ctx).IdentityDeletionGracePeriod(); grace > 0 {
+		t := time.Now().UTC().Add(grace)
+		deleteAfter = &t
+	}
+
+	_, err := s.d.Courier().QueueEmail(ctx, template.NewAccountDeletion(s.d.Configuration(ctx),
+		&template.AccountDeletionModel{To: to, DeleteAfter: deleteAfter}))
+	return err
+}
+
+func (s *Strategy) handleSettingsError(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithAccountDeletionMethod, err error) {
+	// Do not pause flow if the flow type is an API flow as we can't save cookies in those flows.
+	if e := new(settings.FlowNeedsReAuth); errors.As(err, &e) && ctxUpdate.Flow != nil && ctxUpdate.Flow.Type == flow.TypeBrowser {
+		if err := s.d.ContinuityManager().Pause(r.Context(), w, r,
+			settings.ContinuityKey(s.SettingsStrategyID()), settings.ContinuityOptions(p, ctxUpdate.Session.Identity)...); err != nil {
+			s.d.SettingsFlowErrorHandler().WriteFlowError(w, r, s.SettingsStrategyID(), ctxUpdate.Flow, ctxUpdate.Session.Identity, err)
+			return
+		}
+	}
+
+	var id *identity.Identity
+	if ctxUpdate.Flow != nil {
+		if m, ok := ctxUpdate.Flow.Methods[s.SettingsStrategyID()]; ok {
+			m.Config.Reset()
+			m.Config.SetCSRF(s.d.GenerateCSRFToken(r))
+		}
+		id = ctxUpdate.Session.Identity
+	}
+
+	s.d.SettingsFlowErrorHandler().WriteFlowError(w, r, s.SettingsStrategyID(), ctxUpdate.Flow, id, err)
+}
@@ -0,0 +1,157 @@
+package deletion_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+)
+
+func init() {
+	internal.RegisterFakes()
+}
+
+func TestSettings(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/default.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, "5m")
+	testhelpers.StrategyEnable(t, conf, settings.StrategyProfile, true)
+	testhelpers.StrategyEnable(t, conf, settings.StrategyAccountDeletion, true)
+
+	_ = testhelpers.NewSettingsUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+	_ = testhelpers.NewLoginUIWith401Response(t, conf)
+	redirTS := testhelpers.NewRedirTS(t, "deleted", conf)
+
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIdentity := func(t *testing.T, email string) *identity.Identity {
+		i := &identity.Identity{Traits: identity.Traits(`{"email":"` + email + `"}`), SchemaID: config.DefaultIdentityTraitsSchemaID}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+		return i
+	}
+
+	newClient := func(t *testing.T, isAPI bool, i *identity.Identity) *http.Client {
+		if isAPI {
+			return testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, i)
+		}
+		return testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, i)
+	}
+
+	submit := func(t *testing.T, isAPI bool, hc *http.Client, values func(url.Values), expectedStatusCode int, expectedURL string) string {
+		return testhelpers.SubmitSettingsForm(t, isAPI, hc, publicTS, values,
+			settings.StrategyAccountDeletion, expectedStatusCode, expectedURL)
+	}
+
+	confirm := func(v url.Values) {
+		v.Set("confirm", "true")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		isAPI bool
+	}{
+		{name: "browser", isAPI: false},
+		{name: "api", isAPI: true},
+	} {
+		t.Run("type="+tc.name, func(t *testing.T) {
+			t.Run("case=method is not shown if the identity schema is not allowed", func(t *testing.T) {
+				conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+settings.StrategyAccountDeletion+".config.identity_schemas", []string{"customer"})
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+settings.StrategyAccountDeletion+".config.identity_schemas", []string{})
+				})
+
+				hc := newClient(t, tc.isAPI, newIdentity(t, "not-allowed-"+tc.name+"@ory.sh"))
+				if tc.isAPI {
+					assert.NotContains(t, testhelpers.InitializeSettingsFlowViaAPI(t, hc, publicTS).Payload.Methods, settings.StrategyAccountDeletion)
+				} else {
+					assert.NotContains(t, testhelpers.InitializeSettingsFlowViaBrowser(t, hc, publicTS).Payload.Methods, settings.StrategyAccountDeletion)
+				}
+			})
+
+			t.Run("case=fails without confirmation", func(t *testing.T) {
+				i := newIdentity(t, "unconfirmed-"+tc.name+"@ory.sh")
+				actual := submit(t, tc.isAPI, newClient(t, tc.isAPI, i), func(url.Values) {},
+					testhelpers.ExpectStatusCode(tc.isAPI, http.StatusBadRequest, http.StatusOK),
+					testhelpers.ExpectURL(tc.isAPI, publicTS.URL, conf.SelfServiceFlowSettingsUI().String()))
+				assert.NotEmpty(t, gjson.Get(actual, "methods.account_deletion.config.fields.#(name==confirm).messages.0.text").String(), "%s", actual)
+
+				_, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				require.NoError(t, err)
+			})
+
+			t.Run("case=fails if the session is not privileged", func(t *testing.T) {
+				if !tc.isAPI {
+					t.Skip("Browser flows are redirected to the login UI, which is covered by the settings flow tests.")
+				}
+
+				conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, "1ns")
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, "5m")
+				})
+
+				i := newIdentity(t, "unprivileged-"+tc.name+"@ory.sh")
+				submit(t, tc.isAPI, newClient(t, tc.isAPI, i), confirm, http.StatusForbidden, publicTS.URL)
+
+				_, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				require.NoError(t, err)
+			})
+
+			t.Run("case=deletes the account immediately", func(t *testing.T) {
+				i := newIdentity(t, "delete-"+tc.name+"@ory.sh")
+				actual := submit(t, tc.isAPI, newClient(t, tc.isAPI, i), confirm,
+					testhelpers.ExpectStatusCode(tc.isAPI, http.StatusNoContent, http.StatusOK),
+					testhelpers.ExpectURL(tc.isAPI, publicTS.URL, redirTS.URL))
+				if !tc.isAPI {
+					assert.Equal(t, "deleted", actual)
+				}
+
+				_, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				assert.Equal(t, sqlcon.ErrNoRows.Error(), errorsx.Cause(err).Error())
+			})
+
+			t.Run("case=schedules the deletion and notifies the user", func(t *testing.T) {
+				conf.MustSet(config.ViperKeyIdentityDeletionGracePeriod, "720h")
+				conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+settings.StrategyAccountDeletion+".config.notify", true)
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeyIdentityDeletionGracePeriod, "0s")
+					conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+settings.StrategyAccountDeletion+".config.notify", false)
+				})
+
+				email := "schedule-" + tc.name + "@ory.sh"
+				i := newIdentity(t, email)
+				hc := newClient(t, tc.isAPI, i)
+				submit(t, tc.isAPI, hc, confirm,
+					testhelpers.ExpectStatusCode(tc.isAPI, http.StatusNoContent, http.StatusOK),
+					testhelpers.ExpectURL(tc.isAPI, publicTS.URL, redirTS.URL))
+
+				actual, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				require.NoError(t, err)
+				require.True(t, actual.IsScheduledForDeletion())
+				assert.WithinDuration(t, time.Now().Add(720*time.Hour), *actual.DeleteAfter, time.Minute)
+
+				res, err := hc.Get(publicTS.URL + session.RouteWhoami)
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+				assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "sessions must be revoked")
+
+				testhelpers.CourierExpectMessage(t, reg, email, "Your account will be deleted")
+			})
+		})
+	}
+}
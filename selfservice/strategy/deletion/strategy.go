@@ -0,0 +1,96 @@
+package deletion
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/stringslice"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var _ settings.Strategy = new(Strategy)
+
+type (
+	strategyDependencies interface {
+		event.EmitterProvider
+
+		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.StrategyRateLimiterProvider
+
+		config.Providers
+
+		continuity.ManagementProvider
+
+		session.HandlerProvider
+		session.ManagementProvider
+
+		identity.PrivilegedPoolProvider
+		identity.JanitorProvider
+
+		courier.Provider
+
+		errorx.ManagementProvider
+
+		settings.ErrorHandlerProvider
+		settings.FlowPersistenceProvider
+	}
+
+	// Strategy lets users delete their own account in the settings flow.
+	Strategy struct {
+		d  strategyDependencies
+		dc *decoderx.HTTP
+	}
+
+	// Configuration is the configuration of the account deletion method.
+	Configuration struct {
+		// IdentitySchemas restricts account deletion to identities using one of these schemas. All identities
+		// can delete their account if it is empty.
+		IdentitySchemas []string `json:"identity_schemas"`
+
+		// Notify sends an email to the identity before the account is deleted.
+		Notify bool `json:"notify"`
+	}
+)
+
+func NewStrategy(d strategyDependencies) *Strategy {
+	return &Strategy{d: d, dc: decoderx.NewHTTP()}
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return settings.StrategyAccountDeletion
+}
+
+func (s *Strategy) Config(ctx context.Context) (*Configuration, error) {
+	var c Configuration
+
+	conf := s.d.Configuration(ctx).SelfServiceStrategy(s.SettingsStrategyID()).Config
+	if err := jsonx.
+		NewStrictDecoder(bytes.NewBuffer(conf)).
+		Decode(&c); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode account deletion configuration: %s", err))
+	}
+
+	return &c, nil
+}
+
+// isEnabledFor returns true if the identity's schema allows account deletion.
+func (c *Configuration) isEnabledFor(i *identity.Identity) bool {
+	return len(c.IdentitySchemas) == 0 || stringslice.Has(c.IdentitySchemas, i.SchemaID)
+}
@@ -2,6 +2,9 @@ package link
 
 import (
 	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
 )
 
 type (
@@ -9,6 +12,14 @@ type (
 		CreateRecoveryToken(ctx context.Context, token *RecoveryToken) error
 		UseRecoveryToken(ctx context.Context, token string) (*RecoveryToken, error)
 		DeleteRecoveryToken(ctx context.Context, token string) error
+
+		// InvalidateSurplusRecoveryTokens marks all but the newest keep unused and unexpired recovery tokens
+		// of the identity as used.
+		InvalidateSurplusRecoveryTokens(ctx context.Context, identityID uuid.UUID, keep int) error
+
+		// RefreshRecentRecoveryToken extends the expiry of the newest unused and unexpired recovery token of the
+		// address which was issued after issuedAfter to expiresAt. It returns false if there is no such token.
+		RefreshRecentRecoveryToken(ctx context.Context, addressID uuid.UUID, issuedAfter, expiresAt time.Time) (bool, error)
 	}
 
 	RecoveryTokenPersistenceProvider interface {
@@ -19,6 +30,10 @@ type (
 		CreateVerificationToken(ctx context.Context, token *VerificationToken) error
 		UseVerificationToken(ctx context.Context, token string) (*VerificationToken, error)
 		DeleteVerificationToken(ctx context.Context, token string) error
+
+		// InvalidateSurplusVerificationTokens marks all but the newest keep unused and unexpired verification
+		// tokens of the identity as used.
+		InvalidateSurplusVerificationTokens(ctx context.Context, identityID uuid.UUID, keep int) error
 	}
 
 	VerificationTokenPersistenceProvider interface {
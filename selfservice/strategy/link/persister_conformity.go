@@ -83,6 +83,30 @@ func TestPersister(conf *config.Provider, p interface {
 				require.Error(t, err)
 			})
 
+			t.Run("case=should refresh a recent recovery token", func(t *testing.T) {
+				token := newRecoveryToken(t, "refresh-user@ory.sh")
+				token.IssuedAt = time.Now().UTC()
+				token.ExpiresAt = time.Now().UTC().Add(time.Minute)
+				require.NoError(t, p.CreateRecoveryToken(ctx, token))
+
+				refreshed, err := p.RefreshRecentRecoveryToken(ctx, token.RecoveryAddress.ID, time.Now().UTC().Add(time.Minute), time.Now().UTC().Add(time.Hour))
+				require.NoError(t, err)
+				assert.False(t, refreshed, "tokens issued before the window must not be refreshed")
+
+				expiresAt := time.Now().UTC().Add(time.Hour)
+				refreshed, err = p.RefreshRecentRecoveryToken(ctx, token.RecoveryAddress.ID, time.Now().UTC().Add(-time.Minute), expiresAt)
+				require.NoError(t, err)
+				assert.True(t, refreshed)
+
+				actual, err := p.UseRecoveryToken(ctx, token.Token)
+				require.NoError(t, err)
+				assert.WithinDuration(t, expiresAt, actual.ExpiresAt, time.Second)
+
+				refreshed, err = p.RefreshRecentRecoveryToken(ctx, token.RecoveryAddress.ID, time.Now().UTC().Add(-time.Minute), expiresAt)
+				require.NoError(t, err)
+				assert.False(t, refreshed, "used tokens must not be refreshed")
+			})
+
 		})
 		t.Run("token=verification", func(t *testing.T) {
 
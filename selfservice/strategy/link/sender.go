@@ -2,8 +2,12 @@ package link
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/ory/x/errorsx"
@@ -13,6 +17,7 @@ import (
 	"github.com/ory/kratos/courier"
 	templates "github.com/ory/kratos/courier/template"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/verification"
@@ -22,6 +27,7 @@ import (
 type (
 	senderDependencies interface {
 		courier.Provider
+		geoip.LocatorProvider
 		identity.PoolProvider
 		identity.ManagementProvider
 		x.LoggingProvider
@@ -40,7 +46,15 @@ type (
 	}
 )
 
-var ErrUnknownAddress = errors.New("verification requested for unknown address")
+const (
+	templateFlowRecovery     = "recovery"
+	templateFlowVerification = "verification"
+)
+
+var (
+	ErrUnknownAddress   = errors.New("verification requested for unknown address")
+	ErrRecoveryDisabled = errors.New("recovery requested for identity with self-service recovery disabled")
+)
 
 func NewSender(r senderDependencies) *Sender {
 	return &Sender{r: r}
@@ -48,8 +62,12 @@ func NewSender(r senderDependencies) *Sender {
 
 // SendRecoveryLink sends a recovery link to the specified address. If the address does not exist in the store, an email is
 // still being sent to prevent account enumeration attacks. In that case, this function returns the ErrUnknownAddress
-// error.
-func (s *Sender) SendRecoveryLink(ctx context.Context, f *recovery.Flow, via identity.VerifiableAddressType, to string) error {
+// error. If self-service recovery is disabled for the identity, no email is sent and the ErrRecoveryDisabled error is
+// returned. If a recovery link was sent to the address within the deduplication window, no new link is sent and the
+// existing link stays valid until the flow expires instead. If a maximum of active recovery links is configured, the
+// oldest links of the identity are invalidated.
+func (s *Sender) SendRecoveryLink(r *http.Request, f *recovery.Flow, via identity.VerifiableAddressType, to string) error {
+	ctx := r.Context()
 	s.r.Logger().
 		WithField("via", via).
 		WithSensitiveField("address", to).
@@ -57,18 +75,53 @@ func (s *Sender) SendRecoveryLink(ctx context.Context, f *recovery.Flow, via ide
 
 	address, err := s.r.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressTypeEmail, to)
 	if err != nil {
-		if err := s.send(ctx, string(via), templates.NewRecoveryInvalid(s.r.Configuration(ctx), &templates.RecoveryInvalidModel{To: to})); err != nil {
+		if err := s.send(ctx, string(via), templates.NewRecoveryInvalid(s.r.Configuration(ctx), &templates.RecoveryInvalidModel{
+			To: to, Context: s.templateContext(r, templateFlowRecovery, time.Time{}, uuid.Nil)})); err != nil {
 			return err
 		}
 		return errors.Cause(ErrUnknownAddress)
 	}
 
+	i, err := s.r.IdentityPool().GetIdentity(ctx, address.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	if i.RecoveryDisabled {
+		s.r.Audit().
+			WithField("via", address.Via).
+			WithField("identity_id", address.IdentityID).
+			WithSensitiveField("email_address", address.Value).
+			Info("Not sending out recovery email because self-service recovery is disabled for the identity.")
+		return errors.Cause(ErrRecoveryDisabled)
+	}
+
+	if window := s.r.Configuration(ctx).SelfServiceFlowRecoveryDeduplicationWindow(); window > 0 {
+		refreshed, err := s.r.RecoveryTokenPersister().RefreshRecentRecoveryToken(ctx, address.ID, time.Now().UTC().Add(-window), f.ExpiresAt)
+		if err != nil {
+			return err
+		} else if refreshed {
+			s.r.Audit().
+				WithField("via", address.Via).
+				WithField("identity_id", address.IdentityID).
+				WithSensitiveField("email_address", address.Value).
+				Info("Not sending out recovery email because a recovery link was sent to the address recently.")
+			return nil
+		}
+	}
+
 	token := NewSelfServiceRecoveryToken(address, f)
 	if err := s.r.RecoveryTokenPersister().CreateRecoveryToken(ctx, token); err != nil {
 		return err
 	}
 
-	if err := s.SendRecoveryTokenTo(ctx, address, token); err != nil {
+	if max := s.r.Configuration(ctx).SelfServiceFlowRecoveryMaxActiveTokens(); max > 0 {
+		if err := s.r.RecoveryTokenPersister().InvalidateSurplusRecoveryTokens(ctx, address.IdentityID, max); err != nil {
+			return err
+		}
+	}
+
+	if err := s.SendRecoveryTokenTo(r, address, token); err != nil {
 		return err
 	}
 
@@ -78,7 +131,8 @@ func (s *Sender) SendRecoveryLink(ctx context.Context, f *recovery.Flow, via ide
 // SendVerificationLink sends a verification link to the specified address. If the address does not exist in the store, an email is
 // still being sent to prevent account enumeration attacks. In that case, this function returns the ErrUnknownAddress
 // error.
-func (s *Sender) SendVerificationLink(ctx context.Context, f *verification.Flow, via identity.VerifiableAddressType, to string) error {
+func (s *Sender) SendVerificationLink(r *http.Request, f *verification.Flow, via identity.VerifiableAddressType, to string) error {
+	ctx := r.Context()
 	s.r.Logger().
 		WithField("via", via).
 		WithSensitiveField("address", to).
@@ -91,7 +145,8 @@ func (s *Sender) SendVerificationLink(ctx context.Context, f *verification.Flow,
 				WithField("via", via).
 				WithSensitiveField("email_address", address).
 				Info("Sending out invalid verification email because address is unknown.")
-			if err := s.send(ctx, string(via), templates.NewVerificationInvalid(s.r.Configuration(ctx), &templates.VerificationInvalidModel{To: to})); err != nil {
+			if err := s.send(ctx, string(via), templates.NewVerificationInvalid(s.r.Configuration(ctx), &templates.VerificationInvalidModel{
+				To: to, Context: s.templateContext(r, templateFlowVerification, time.Time{}, uuid.Nil)})); err != nil {
 				return err
 			}
 			return errors.Cause(ErrUnknownAddress)
@@ -104,13 +159,20 @@ func (s *Sender) SendVerificationLink(ctx context.Context, f *verification.Flow,
 		return err
 	}
 
-	if err := s.SendVerificationTokenTo(ctx, address, token); err != nil {
+	if max := s.r.Configuration(ctx).SelfServiceFlowVerificationMaxActiveTokens(); max > 0 {
+		if err := s.r.VerificationTokenPersister().InvalidateSurplusVerificationTokens(ctx, address.IdentityID, max); err != nil {
+			return err
+		}
+	}
+
+	if err := s.SendVerificationTokenTo(r, address, token); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *Sender) SendRecoveryTokenTo(ctx context.Context, address *identity.RecoveryAddress, token *RecoveryToken) error {
+func (s *Sender) SendRecoveryTokenTo(r *http.Request, address *identity.RecoveryAddress, token *RecoveryToken) error {
+	ctx := r.Context()
 	s.r.Audit().
 		WithField("via", address.Via).
 		WithField("identity_id", address.IdentityID).
@@ -121,10 +183,12 @@ func (s *Sender) SendRecoveryTokenTo(ctx context.Context, address *identity.Reco
 	return s.send(ctx, string(address.Via), templates.NewRecoveryValid(s.r.Configuration(ctx),
 		&templates.RecoveryValidModel{To: address.Value, RecoveryURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.r.Configuration(ctx).SelfPublicURL(), RouteRecovery),
-			url.Values{"token": {token.Token}}).String()}))
+			url.Values{"token": {token.Token}}).String(),
+			Context: s.templateContext(r, templateFlowRecovery, token.ExpiresAt, address.IdentityID)}))
 }
 
-func (s *Sender) SendVerificationTokenTo(ctx context.Context, address *identity.VerifiableAddress, token *VerificationToken) error {
+func (s *Sender) SendVerificationTokenTo(r *http.Request, address *identity.VerifiableAddress, token *VerificationToken) error {
+	ctx := r.Context()
 	s.r.Audit().
 		WithField("via", address.Via).
 		WithField("identity_id", address.IdentityID).
@@ -136,7 +200,37 @@ func (s *Sender) SendVerificationTokenTo(ctx context.Context, address *identity.
 	return s.send(ctx, string(address.Via), templates.NewVerificationValid(s.r.Configuration(ctx),
 		&templates.VerificationValidModel{To: address.Value, VerificationURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.r.Configuration(ctx).SelfPublicURL(), RouteVerification),
-			url.Values{"token": {token.Token}}).String()}))
+			url.Values{"token": {token.Token}}).String(),
+			Context: s.templateContext(r, templateFlowVerification, token.ExpiresAt, address.IdentityID)}))
+}
+
+// templateContext assembles the context of the email templates. The identity's traits and the request metadata
+// are only added if they are enabled in `courier.template_context`. Errors are logged and the respective fields
+// are omitted so that the email is sent regardless.
+func (s *Sender) templateContext(r *http.Request, flowType string, expiresAt time.Time, identityID uuid.UUID) templates.Context {
+	ctx := r.Context()
+	conf := s.r.Configuration(ctx).CourierTemplateContext()
+	tc := templates.Context{FlowType: flowType, ExpiresAt: expiresAt}
+
+	if identityID != uuid.Nil {
+		tc.Identity = &templates.ContextIdentity{ID: identityID.String()}
+		if conf.IdentityTraits {
+			if i, err := s.r.IdentityPool().GetIdentity(ctx, identityID); err != nil {
+				s.r.Logger().WithError(err).Warn("Unable to add the identity traits to the email template context.")
+			} else if err := json.Unmarshal(i.Traits, &tc.Identity.Traits); err != nil {
+				s.r.Logger().WithError(err).Warn("Unable to add the identity traits to the email template context.")
+			}
+		}
+	}
+
+	if conf.RequestMetadata {
+		tc.Request = &templates.ContextRequest{IP: x.TrustedClientIP(r, s.r.Configuration(ctx).PublicClientIP())}
+		if loc := s.r.GeoLocator().Locate(ctx, tc.Request.IP); loc != nil {
+			tc.Request.Country, tc.Request.Region = loc.Country, loc.Region
+		}
+	}
+
+	return tc
 }
 
 func (s *Sender) send(ctx context.Context, via string, t courier.EmailTemplate) error {
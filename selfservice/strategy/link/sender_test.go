@@ -2,7 +2,11 @@ package link_test
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
@@ -25,7 +29,7 @@ func TestManager(t *testing.T) {
 	conf.MustSet(config.ViperKeyPublicBaseURL, "https://www.ory.sh/")
 	conf.MustSet(config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
 
-	u := &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/")}
+	u := &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/"), RemoteAddr: "192.0.2.1:4321"}
 
 	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
 	i.Traits = identity.Traits(`{"email": "tracked@ory.sh"}`)
@@ -37,8 +41,8 @@ func TestManager(t *testing.T) {
 
 		require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
 
-		require.NoError(t, reg.LinkSender().SendRecoveryLink(context.Background(), f, "email", "tracked@ory.sh"))
-		require.EqualError(t, reg.LinkSender().SendRecoveryLink(context.Background(), f, "email", "not-tracked@ory.sh"), link.ErrUnknownAddress.Error())
+		require.NoError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "tracked@ory.sh"))
+		require.EqualError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "not-tracked@ory.sh"), link.ErrUnknownAddress.Error())
 
 		messages, err := reg.CourierPersister().NextMessages(context.Background(), 12)
 		require.NoError(t, err)
@@ -59,8 +63,8 @@ func TestManager(t *testing.T) {
 
 		require.NoError(t, reg.VerificationFlowPersister().CreateVerificationFlow(context.Background(), f))
 
-		require.NoError(t, reg.LinkSender().SendVerificationLink(context.Background(), f, "email", "tracked@ory.sh"))
-		require.EqualError(t, reg.LinkSender().SendVerificationLink(context.Background(), f, "email", "not-tracked@ory.sh"), link.ErrUnknownAddress.Error())
+		require.NoError(t, reg.LinkSender().SendVerificationLink(u, f, "email", "tracked@ory.sh"))
+		require.EqualError(t, reg.LinkSender().SendVerificationLink(u, f, "email", "not-tracked@ory.sh"), link.ErrUnknownAddress.Error())
 
 		messages, err := reg.CourierPersister().NextMessages(context.Background(), 12)
 		require.NoError(t, err)
@@ -74,4 +78,198 @@ func TestManager(t *testing.T) {
 		assert.Contains(t, messages[3].Subject, "tried to verify")
 		assert.NotContains(t, messages[3].Body, urlx.AppendPaths(conf.SelfPublicURL(), link.RouteVerification).String()+"?token=")
 	})
+
+	t.Run("method=SendRecoveryLink/case=recovery disabled", func(t *testing.T) {
+		disabled := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		disabled.Traits = identity.Traits(`{"email": "recovery-disabled@ory.sh"}`)
+		disabled.RecoveryDisabled = true
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), disabled))
+
+		f, err := recovery.NewFlow(time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+
+		require.EqualError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "recovery-disabled@ory.sh"), link.ErrRecoveryDisabled.Error())
+
+		messages, err := reg.CourierPersister().NextMessages(context.Background(), 12)
+		require.NoError(t, err)
+		for _, m := range messages {
+			assert.NotEqual(t, "recovery-disabled@ory.sh", m.Recipient)
+		}
+	})
+
+	t.Run("case=max active tokens", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceRecoveryMaxActiveTokens, 1)
+		conf.MustSet(config.ViperKeySelfServiceVerificationMaxActiveTokens, 1)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceRecoveryMaxActiveTokens, 0)
+			conf.MustSet(config.ViperKeySelfServiceVerificationMaxActiveTokens, 0)
+		})
+
+		limited := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		limited.Traits = identity.Traits(`{"email": "limited@ory.sh"}`)
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), limited))
+
+		tokensFor := func(t *testing.T, route string) (tokens []string) {
+			messages, err := reg.CourierPersister().NextMessages(context.Background(), 100)
+			require.NoError(t, err)
+			for _, m := range messages {
+				if m.Recipient != "limited@ory.sh" {
+					continue
+				}
+				if match := regexp.MustCompile(regexp.QuoteMeta(route) + `\?token=([a-zA-Z0-9]+)`).FindStringSubmatch(m.Body); len(match) == 2 {
+					tokens = append(tokens, match[1])
+				}
+			}
+			return tokens
+		}
+
+		t.Run("flow=recovery", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				f, err := recovery.NewFlow(time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+				require.NoError(t, err)
+				require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+				require.NoError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "limited@ory.sh"))
+			}
+
+			tokens := tokensFor(t, link.RouteRecovery)
+			require.Len(t, tokens, 2)
+
+			_, err := reg.RecoveryTokenPersister().UseRecoveryToken(context.Background(), tokens[0])
+			require.Error(t, err, "the older link must have been invalidated")
+			_, err = reg.RecoveryTokenPersister().UseRecoveryToken(context.Background(), tokens[1])
+			require.NoError(t, err)
+		})
+
+		t.Run("flow=verification", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				f, err := verification.NewFlow(time.Hour, "", u, reg.VerificationStrategies(), flow.TypeBrowser)
+				require.NoError(t, err)
+				require.NoError(t, reg.VerificationFlowPersister().CreateVerificationFlow(context.Background(), f))
+				require.NoError(t, reg.LinkSender().SendVerificationLink(u, f, "email", "limited@ory.sh"))
+			}
+
+			tokens := tokensFor(t, link.RouteVerification)
+			require.Len(t, tokens, 2)
+
+			_, err := reg.VerificationTokenPersister().UseVerificationToken(context.Background(), tokens[0])
+			require.Error(t, err, "the older link must have been invalidated")
+			_, err = reg.VerificationTokenPersister().UseVerificationToken(context.Background(), tokens[1])
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("case=template context", func(t *testing.T) {
+		root := t.TempDir()
+		for path, content := range map[string]string{
+			"recovery/valid/email.subject.gotmpl":       `{{ .Context.FlowType }} for {{ .Context.Identity.ID }}`,
+			"recovery/valid/email.body.gotmpl":          `name={{ with .Context.Identity.Traits }}{{ .email }}{{ end }} ip={{ with .Context.Request }}{{ .IP }}{{ end }} expires={{ .Context.ExpiresAt.IsZero }}`,
+			"recovery/invalid/email.subject.gotmpl":     `{{ .Context.FlowType }} unknown`,
+			"recovery/invalid/email.body.gotmpl":        `identity={{ if .Context.Identity }}known{{ else }}unknown{{ end }}`,
+			"verification/valid/email.subject.gotmpl":   `{{ .Context.FlowType }}`,
+			"verification/valid/email.body.gotmpl":      `{{ .VerificationURL }}`,
+			"verification/invalid/email.subject.gotmpl": `{{ .Context.FlowType }}`,
+			"verification/invalid/email.body.gotmpl":    `invalid`,
+		} {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0700))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(root, path), []byte(content), 0600))
+		}
+
+		previous := conf.CourierTemplatesRoot()
+		conf.MustSet(config.ViperKeyCourierTemplatesPath, root)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyCourierTemplatesPath, previous)
+			conf.MustSet(config.ViperKeyCourierTemplateContextIdentityTraits, false)
+			conf.MustSet(config.ViperKeyCourierTemplateContextRequestMetadata, false)
+		})
+
+		contextual := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		contextual.Traits = identity.Traits(`{"email": "context@ory.sh"}`)
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), contextual))
+
+		send := func(t *testing.T, to string) (subject, body string) {
+			f, err := recovery.NewFlow(time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+			require.NoError(t, err)
+			require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+			_ = reg.LinkSender().SendRecoveryLink(u, f, "email", to)
+
+			messages, err := reg.CourierPersister().NextMessages(context.Background(), 100)
+			require.NoError(t, err)
+			for _, m := range messages {
+				if m.Recipient == to {
+					subject, body = m.Subject, m.Body
+				}
+			}
+			return
+		}
+
+		t.Run("case=omits sensitive fields by default", func(t *testing.T) {
+			subject, body := send(t, "context@ory.sh")
+			assert.Equal(t, "recovery for "+contextual.ID.String(), subject)
+			assert.Equal(t, "name= ip= expires=false", body)
+		})
+
+		t.Run("case=adds sensitive fields if enabled", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyCourierTemplateContextIdentityTraits, true)
+			conf.MustSet(config.ViperKeyCourierTemplateContextRequestMetadata, true)
+
+			_, body := send(t, "context@ory.sh")
+			assert.Equal(t, "name=context@ory.sh ip=192.0.2.1 expires=false", body)
+		})
+
+		t.Run("case=has no identity for unknown addresses", func(t *testing.T) {
+			subject, body := send(t, "context-unknown@ory.sh")
+			assert.Equal(t, "recovery unknown", subject)
+			assert.Equal(t, "identity=unknown", body)
+		})
+	})
+
+	t.Run("case=deduplicates recovery links", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceRecoveryDeduplicationWindow, "1m")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceRecoveryDeduplicationWindow, "0s")
+		})
+
+		deduplicated := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		deduplicated.Traits = identity.Traits(`{"email": "deduplicated@ory.sh"}`)
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), deduplicated))
+
+		var flows []*recovery.Flow
+		for i := 0; i < 3; i++ {
+			f, err := recovery.NewFlow(time.Hour*time.Duration(i+1), "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+			require.NoError(t, err)
+			require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+			require.NoError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "deduplicated@ory.sh"))
+			flows = append(flows, f)
+		}
+
+		tokens := func(t *testing.T) (tokens []string) {
+			messages, err := reg.CourierPersister().NextMessages(context.Background(), 100)
+			require.NoError(t, err)
+			for _, m := range messages {
+				if m.Recipient != "deduplicated@ory.sh" {
+					continue
+				}
+				if match := regexp.MustCompile(regexp.QuoteMeta(link.RouteRecovery) + `\?token=([a-zA-Z0-9]+)`).FindStringSubmatch(m.Body); len(match) == 2 {
+					tokens = append(tokens, match[1])
+				}
+			}
+			return tokens
+		}
+
+		sent := tokens(t)
+		require.Len(t, sent, 1, "only the first request must send a recovery link")
+
+		token, err := reg.RecoveryTokenPersister().UseRecoveryToken(context.Background(), sent[0])
+		require.NoError(t, err)
+		assert.Equal(t, flows[0].ID, token.FlowID.UUID)
+		assert.WithinDuration(t, flows[2].ExpiresAt, token.ExpiresAt, time.Second, "the link must be valid until the latest flow expires")
+
+		f, err := recovery.NewFlow(time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+		require.NoError(t, reg.LinkSender().SendRecoveryLink(u, f, "email", "deduplicated@ory.sh"))
+
+		assert.Len(t, tokens(t), 2, "a new link must be sent once the previous link was used")
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/ory/x/decoderx"
 
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
@@ -31,10 +32,13 @@ type (
 	}
 
 	strategyDependencies interface {
+		event.EmitterProvider
+
 		x.CSRFProvider
 		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.LoggingProvider
+		x.StrategyRateLimiterProvider
 
 		config.Providers
 
@@ -54,10 +58,12 @@ type (
 
 		recovery.ErrorHandlerProvider
 		recovery.FlowPersistenceProvider
+		recovery.HookExecutorProvider
 		recovery.StrategyProvider
 
 		verification.ErrorHandlerProvider
 		verification.FlowPersistenceProvider
+		verification.HookExecutorProvider
 		verification.StrategyProvider
 
 		RecoveryTokenPersistenceProvider
@@ -68,11 +74,12 @@ type (
 	}
 
 	Strategy struct {
-		d  strategyDependencies
-		dx *decoderx.HTTP
+		d       strategyDependencies
+		dx      *decoderx.HTTP
+		limiter *x.RateLimiter
 	}
 )
 
 func NewStrategy(d strategyDependencies) *Strategy {
-	return &Strategy{d: d, dx: decoderx.NewHTTP()}
+	return &Strategy{d: d, dx: decoderx.NewHTTP(), limiter: x.NewRateLimiter()}
 }
@@ -1,6 +1,9 @@
 package link
 
 import (
+	"context"
+	"crypto/rand"
+	"math/big"
 	"net/http"
 	"net/url"
 	"time"
@@ -20,6 +23,7 @@ import (
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
@@ -54,6 +58,7 @@ func (s *Strategy) PopulateRecoveryMethod(r *http.Request, req *recovery.Flow) e
 
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
 	f.SetField(form.Field{Name: "email", Type: "email", Required: true})
+	f.GroupFields(form.FieldGroupLink)
 
 	req.Methods[s.RecoveryStrategyID()] = &recovery.FlowMethod{
 		Method: s.RecoveryStrategyID(),
@@ -81,7 +86,8 @@ type CreateRecoveryLink struct {
 	// Link Expires In
 	//
 	// The recovery link will expire at that point in time. Defaults to the configuration value of
-	// `selfservice.flows.recovery.request_lifespan`.
+	// `selfservice.flows.recovery.admin_link.lifespan` and must not exceed
+	// `selfservice.flows.recovery.admin_link.max_lifespan`.
 	//
 	//
 	// pattern: ^[0-9]+(ns|us|ms|s|m|h)$
@@ -115,7 +121,10 @@ type recoveryLink struct {
 // Create a Recovery Link
 //
 // This endpoint creates a recovery link which should be given to the user in order for them to recover
-// (or activate) their account.
+// (or activate) their account. The link is returned but not sent to the user, for example so that support
+// staff can hand it over out-of-band.
+//
+// Creating recovery links is rate limited per caller and every request is written to the audit log.
 //
 //     Consumes:
 //     - application/json
@@ -129,15 +138,33 @@ type recoveryLink struct {
 //       200: recoveryLink
 //       404: genericError
 //       400: genericError
+//       429: genericError
 //       500: genericError
 func (s *Strategy) createRecoveryLink(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	conf := s.d.Configuration(r.Context())
+	actor := x.AuditActor(r.Context())
+	caller := actor
+	if len(caller) == 0 {
+		caller = x.TrustedClientIP(r, &conf.AdminIPFilter().ClientIPConfig)
+	}
+
+	if !s.limiter.Allow(caller, conf.SelfServiceFlowRecoveryAdminLinkMaxRequests(), conf.SelfServiceFlowRecoveryAdminLinkWindow()) {
+		s.d.Audit().
+			WithRequest(r).
+			WithField("actor", actor).
+			WithField("request_id", x.AuditRequestID(r.Context())).
+			Warn("Creating a recovery link was denied because the rate limit was exceeded.")
+		s.d.Writer().WriteError(w, r, errors.WithStack(x.ErrTooManyRequests))
+		return
+	}
+
 	var p CreateRecoveryLink
 	if err := s.dx.Decode(r, &p, decoderx.HTTPJSONDecoder()); err != nil {
 		s.d.Writer().WriteError(w, r, err)
 		return
 	}
 
-	expiresIn := s.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan()
+	expiresIn, maxExpiresIn := conf.SelfServiceFlowRecoveryAdminLinkLifespan()
 	if len(p.ExpiresIn) > 0 {
 		var err error
 		expiresIn, err = time.ParseDuration(p.ExpiresIn)
@@ -152,6 +179,11 @@ func (s *Strategy) createRecoveryLink(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
+	if expiresIn > maxExpiresIn {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Value from "expires_in" must not exceed %s: %s`, maxExpiresIn, p.ExpiresIn)))
+		return
+	}
+
 	req, err := recovery.NewFlow(expiresIn, s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), flow.TypeBrowser)
 	if err != nil {
 		s.d.Writer().WriteError(w, r, err)
@@ -177,11 +209,15 @@ func (s *Strategy) createRecoveryLink(w http.ResponseWriter, r *http.Request, _
 	}
 
 	s.d.Audit().
+		WithRequest(r).
+		WithField("actor", actor).
+		WithField("request_id", x.AuditRequestID(r.Context())).
 		WithField("via", address.Via).
 		WithField("identity_id", address.IdentityID).
+		WithField("expires_at", token.ExpiresAt).
 		WithSensitiveField("email_address", address.Value).
 		WithSensitiveField("recovery_link_token", token).
-		Info("A recovery link has been created.")
+		Warn("A recovery link has been created using the admin API.")
 
 	s.d.Writer().Write(w, r, &recoveryLink{
 		ExpiresAt: req.ExpiresAt.UTC(),
@@ -273,6 +309,11 @@ func (s *Strategy) handleRecovery(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	if err := s.d.StrategyRateLimiter().Allow(r, "recovery", s.RecoveryStrategyID()); err != nil {
+		s.handleRecoveryError(w, r, nil, body, err)
+		return
+	}
+
 	if len(body.Token) > 0 {
 		s.recoveryUseToken(w, r, body)
 		return
@@ -284,7 +325,7 @@ func (s *Strategy) handleRecovery(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	if err := req.Valid(); err != nil {
+	if err := req.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
 		s.handleRecoveryError(w, r, req, body, err)
 		return
 	}
@@ -318,6 +359,7 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		UUID:  recoveredID,
 		Valid: true,
 	}
+	s.d.RecoveryExecutor().PostRecoveryHook(r, f)
 	if err := s.d.RecoveryFlowPersister().UpdateRecoveryFlow(r.Context(), f); err != nil {
 		s.handleRecoveryError(w, r, f, nil, err)
 		return
@@ -328,6 +370,7 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		s.handleRecoveryError(w, r, f, nil, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "recovery", f.ID, f.Type).WithStrategy(s.RecoveryStrategyID()).WithIdentity(recoveredID))
 
 	sf, err := s.d.SettingsHandler().NewFlow(w, r, sess.Identity, flow.TypeBrowser)
 	if err != nil {
@@ -376,18 +419,33 @@ func (s *Strategy) recoveryUseToken(w http.ResponseWriter, r *http.Request, body
 		}
 	}
 
-	if err := token.Valid(); err != nil {
+	if err := token.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
 		s.handleRecoveryError(w, r, f, body, err)
 		return
 	}
 
+	if token.FlowID.Valid {
+		// Links sent by the self-service flow before recovery was disabled for the identity must not be used
+		// either. Links created using the admin API have no flow and continue to work.
+		recovered, err := s.d.IdentityPool().GetIdentity(r.Context(), token.RecoveryAddress.IdentityID)
+		if err != nil {
+			s.handleRecoveryError(w, r, f, body, err)
+			return
+		}
+
+		if recovered.RecoveryDisabled {
+			s.retryRecoveryFlowWithMessage(w, r, flow.TypeBrowser, text.NewErrorValidationRecoveryTokenInvalidOrAlreadyUsed())
+			return
+		}
+	}
+
 	s.recoveryIssueSession(w, r, f, token.RecoveryAddress.IdentityID)
 }
 
 func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) {
 	s.d.Logger().WithRequest(r).WithField("message", message).Debug("A recovery flow is being retried because a validation error occurred.")
 
-	req, err := recovery.NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), ft)
+	req, err := recovery.NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowRecoveryRequestLifespan(), flow.CSRFToken(w, r, ft, s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.CSRFHandler(), s.d.GenerateCSRFToken), r, s.d.RecoveryStrategies(), ft)
 	if err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -409,6 +467,7 @@ func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.R
 }
 
 func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.Request, req *recovery.Flow) {
+	start := time.Now()
 	var body = new(completeSelfServiceRecoveryFlowWithLinkMethodParameters)
 	body, err := s.decodeRecovery(r, true)
 	if err != nil {
@@ -421,13 +480,15 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if err := flow.VerifyRequest(r, req.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, body.Body.CSRFToken); err != nil {
+	if err := flow.VerifyRequest(r, req.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, req.CSRFToken, body.Body.CSRFToken); err != nil {
 		s.handleRecoveryError(w, r, req, body, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "recovery", req.ID, req.Type).WithStrategy(s.RecoveryStrategyID()))
 
-	if err := s.d.LinkSender().SendRecoveryLink(r.Context(), req, identity.VerifiableAddressTypeEmail, body.Body.Email); err != nil {
-		if !errors.Is(err, ErrUnknownAddress) {
+	if err := s.d.LinkSender().SendRecoveryLink(r, req, identity.VerifiableAddressTypeEmail, body.Body.Email); err != nil {
+		// The response must not reveal whether the address is unknown or recovery is disabled for the identity.
+		if !errors.Is(err, ErrUnknownAddress) && !errors.Is(err, ErrRecoveryDisabled) {
 			s.handleRecoveryError(w, r, req, body, err)
 			return
 		}
@@ -452,6 +513,8 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	s.waitForRecoveryResponseJitter(r.Context(), start)
+
 	if req.Type == flow.TypeBrowser {
 		http.Redirect(w, r, req.AppendTo(s.d.Configuration(r.Context()).SelfServiceFlowRecoveryUI()).String(), http.StatusFound)
 		return
@@ -466,6 +529,29 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 	s.d.Writer().Write(w, r, updatedFlow)
 }
 
+// waitForRecoveryResponseJitter blocks until a random duration within `selfservice.flows.recovery.response_jitter`
+// has passed since start. The response time therefore does not reveal whether an account exists for the address.
+func (s *Strategy) waitForRecoveryResponseJitter(ctx context.Context, start time.Time) {
+	min, max := s.d.Configuration(ctx).SelfServiceFlowRecoveryResponseJitter()
+	if max <= 0 {
+		return
+	}
+
+	wait := min
+	if max > min {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min)))
+		if err != nil {
+			n = big.NewInt(int64(max - min))
+		}
+		wait += time.Duration(n.Int64())
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Until(start.Add(wait))):
+	}
+}
+
 func (s *Strategy) handleRecoveryError(w http.ResponseWriter, r *http.Request, req *recovery.Flow, body *completeSelfServiceRecoveryFlowWithLinkMethodParameters, err error) {
 	if req != nil {
 		config, err := req.MethodToForm(s.RecoveryStrategyID())
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -125,6 +126,67 @@ func TestAdminStrategy(t *testing.T) {
 		require.Len(t, sr.Payload.Messages, 1)
 		assert.Equal(t, "You successfully recovered your account. Please change your password or set up an alternative login method (e.g. social sign in) within the next 60.00 minutes.", sr.Payload.Messages[0].Text)
 	})
+
+	t.Run("description=should recover an account with self-service recovery disabled", func(t *testing.T) {
+		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.disabled@ory.sh"}`), RecoveryDisabled: true}
+
+		require.NoError(t, reg.IdentityManager().Create(context.Background(),
+			&id, identity.ManagerAllowWriteProtectedTraits))
+
+		rl, err := adminSDK.Admin.CreateRecoveryLink(admin.NewCreateRecoveryLinkParams().
+			WithBody(&models.CreateRecoveryLink{IdentityID: models.UUID(id.ID.String())}))
+		require.NoError(t, err)
+
+		res, err := publicTS.Client().Get(*rl.Payload.RecoveryLink)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Request.URL.String(), conf.SelfServiceFlowSettingsUI().String())
+	})
+
+	createLink := func(t *testing.T, body string) *http.Response {
+		res, err := adminTS.Client().Post(adminTS.URL+link.RouteAdminCreateRecoveryLink, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+
+	t.Run("description=should use the short admin link lifespan by default", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceRecoveryAdminLinkLifespan, "5m")
+		t.Cleanup(func() { conf.MustSet(config.ViperKeySelfServiceRecoveryAdminLinkLifespan, "15m") })
+
+		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.default-lifespan@ory.sh"}`)}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(),
+			&id, identity.ManagerAllowWriteProtectedTraits))
+
+		rl, err := adminSDK.Admin.CreateRecoveryLink(admin.NewCreateRecoveryLinkParams().
+			WithBody(&models.CreateRecoveryLink{IdentityID: models.UUID(id.ID.String())}))
+		require.NoError(t, err)
+		checkLink(t, rl, time.Now().Add(5*time.Minute+time.Second))
+	})
+
+	t.Run("description=should not allow links which outlive the maximum lifespan", func(t *testing.T) {
+		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.max-lifespan@ory.sh"}`)}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(),
+			&id, identity.ManagerAllowWriteProtectedTraits))
+
+		res := createLink(t, `{"identity_id":"`+id.ID.String()+`","expires_in":"2h"}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, string(ioutilx.MustReadAll(res.Body)), "must not exceed 1h0m0s")
+	})
+
+	// This test must run last because it exhausts the rate limit.
+	t.Run("description=should rate limit the creation of recovery links", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceRecoveryAdminLinkMaxRequests, 1)
+		conf.MustSet(config.ViperKeySelfServiceRecoveryAdminLinkWindow, "1h")
+
+		var res *http.Response
+		for k := 0; k < 2; k++ {
+			res = createLink(t, `{"identity_id":"`+x.NewUUID().String()+`"}`)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Contains(t, string(ioutilx.MustReadAll(res.Body)), string(text.ErrorCodeRateLimitExceeded))
+	})
 }
 
 func TestRecovery(t *testing.T) {
@@ -270,6 +332,52 @@ func TestRecovery(t *testing.T) {
 		})
 	})
 
+	t.Run("description=should not send a recovery link if recovery is disabled for the identity", func(t *testing.T) {
+		email := x.NewUUID().String() + "@ory.sh"
+		i := &identity.Identity{
+			Traits:           identity.Traits(`{"email":"` + email + `"}`),
+			SchemaID:         config.DefaultIdentityTraitsSchemaID,
+			RecoveryDisabled: true,
+		}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+
+		for _, isAPI := range []bool{false, true} {
+			actual := expectSuccess(t, isAPI, func(v url.Values) {
+				v.Set("email", email)
+			})
+			assertx.EqualAsJSON(t, text.NewRecoveryEmailSent(), json.RawMessage(gjson.Get(actual, "messages.0").Raw))
+
+			message, err := reg.CourierPersister().LatestQueuedMessage(context.Background())
+			if err == nil {
+				assert.NotEqual(t, email, message.Recipient)
+			}
+		}
+	})
+
+	t.Run("description=should not be able to use a link sent before recovery was disabled", func(t *testing.T) {
+		email := x.NewUUID().String() + "@ory.sh"
+		i := &identity.Identity{
+			Traits:   identity.Traits(`{"email":"` + email + `"}`),
+			SchemaID: config.DefaultIdentityTraitsSchemaID,
+		}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+
+		expectSuccess(t, false, func(v url.Values) {
+			v.Set("email", email)
+		})
+		recoveryLink := testhelpers.CourierExpectLinkInMessage(t,
+			testhelpers.CourierExpectMessage(t, reg, email, "Recover access to your account"), 1)
+
+		i.RecoveryDisabled = true
+		require.NoError(t, reg.IdentityManager().Update(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+
+		res, err := testhelpers.NewClientWithCookies(t).Get(recoveryLink)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Request.URL.String(), conf.SelfServiceFlowRecoveryUI().String()+"?flow=")
+		assert.NotContains(t, res.Request.URL.String(), conf.SelfServiceFlowSettingsUI().String())
+	})
+
 	t.Run("description=should not be able to use an invalid link", func(t *testing.T) {
 		c := testhelpers.NewClientWithCookies(t)
 		res, err := c.Get(public.URL + link.RouteRecovery + "?token=i-do-not-exist")
@@ -338,4 +446,40 @@ func TestRecovery(t *testing.T) {
 		require.Len(t, sr.Payload.Messages, 1)
 		assert.Contains(t, sr.Payload.Messages[0].Text, "The recovery flow expired")
 	})
+
+	t.Run("description=should not reveal whether an account exists by the response time", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceRecoveryResponseJitterMin, "200ms")
+		conf.MustSet(config.ViperKeySelfServiceRecoveryResponseJitterMax, "250ms")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceRecoveryResponseJitterMin, "0s")
+			conf.MustSet(config.ViperKeySelfServiceRecoveryResponseJitterMax, "0s")
+		})
+
+		measure := func(t *testing.T, email string) []time.Duration {
+			var durations []time.Duration
+			for k := 0; k < 5; k++ {
+				start := time.Now()
+				expectSuccess(t, true, func(v url.Values) {
+					v.Set("email", email)
+				})
+				durations = append(durations, time.Since(start))
+			}
+			return durations
+		}
+
+		mean := func(durations []time.Duration) float64 {
+			var sum time.Duration
+			for _, d := range durations {
+				sum += d
+			}
+			return float64(sum) / float64(len(durations))
+		}
+
+		existing := measure(t, recoveryEmail)
+		unknown := measure(t, x.NewUUID().String()+"@ory.sh")
+		for _, d := range append(existing, unknown...) {
+			assert.True(t, d >= 200*time.Millisecond, "%s", d)
+		}
+		assert.InDelta(t, mean(existing), mean(unknown), float64(50*time.Millisecond), "existing: %v unknown: %v", existing, unknown)
+	})
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
@@ -49,6 +50,7 @@ func (s *Strategy) PopulateVerificationMethod(r *http.Request, req *verification
 
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
 	f.SetField(form.Field{Name: "email", Type: "email", Required: true})
+	f.GroupFields(form.FieldGroupLink)
 
 	req.Methods[s.VerificationStrategyID()] = &verification.FlowMethod{
 		Method: s.VerificationStrategyID(),
@@ -171,7 +173,7 @@ type completeSelfServiceVerificationFlowWithLinkMethod struct {
 //       500: genericError
 func (s *Strategy) handleVerification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	if !s.d.Configuration(r.Context()).SelfServiceStrategy(s.VerificationStrategyID()).Enabled {
-		s.handleVerificationError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Verification using this method is not allowed because it was disabled.")))
+		s.handleVerificationError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowMethodDisabled).WithReasonf("Verification using this method is not allowed because it was disabled.")))
 		return
 	}
 
@@ -181,6 +183,11 @@ func (s *Strategy) handleVerification(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	if err := s.d.StrategyRateLimiter().Allow(r, "verification", s.VerificationStrategyID()); err != nil {
+		s.handleVerificationError(w, r, nil, body, err)
+		return
+	}
+
 	if len(body.Token) > 0 {
 		s.verificationUseToken(w, r, body)
 		return
@@ -192,7 +199,7 @@ func (s *Strategy) handleVerification(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
-	if err := f.Valid(); err != nil {
+	if err := f.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
@@ -226,12 +233,13 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if err := flow.VerifyRequest(r, f.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, body.Body.CSRFToken); err != nil {
+	if err := flow.VerifyRequest(r, f.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, f.CSRFToken, body.Body.CSRFToken); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "verification", f.ID, f.Type).WithStrategy(s.VerificationStrategyID()))
 
-	if err := s.d.LinkSender().SendVerificationLink(r.Context(), f, identity.VerifiableAddressTypeEmail, body.Body.Email); err != nil {
+	if err := s.d.LinkSender().SendVerificationLink(r, f, identity.VerifiableAddressTypeEmail, body.Body.Email); err != nil {
 		if !errors.Is(err, ErrUnknownAddress) {
 			s.handleVerificationError(w, r, f, body, err)
 			return
@@ -311,13 +319,14 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
-	if err := token.Valid(); err != nil {
+	if err := token.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
 
 	f.Messages.Clear()
 	f.State = verification.StatePassedChallenge
+	s.d.VerificationExecutor().PostVerificationHook(r, f)
 	if err := s.d.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
@@ -331,6 +340,7 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSucceeded, "verification", f.ID, f.Type).WithStrategy(s.VerificationStrategyID()).WithIdentity(address.IdentityID))
 
 	http.Redirect(w, r, s.d.Configuration(r.Context()).SelfServiceFlowVerificationReturnTo(f.
 		AppendTo(s.d.Configuration(r.Context()).SelfServiceFlowVerificationUI())).String(), http.StatusFound)
@@ -339,7 +349,7 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 func (s *Strategy) retryVerificationFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) {
 	s.d.Logger().WithRequest(r).WithField("message", message).Debug("A verification flow is being retried because a validation error occurred.")
 
-	req, err := verification.NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), ft)
+	req, err := verification.NewFlow(s.d.Configuration(r.Context()).SelfServiceFlowVerificationRequestLifespan(), flow.CSRFToken(w, r, ft, s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.CSRFHandler(), s.d.GenerateCSRFToken), r, s.d.VerificationStrategies(), ft)
 	if err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
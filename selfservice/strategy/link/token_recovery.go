@@ -74,8 +74,9 @@ func NewRecoveryToken(address *identity.RecoveryAddress, expiresIn time.Duration
 	}
 }
 
-func (f *RecoveryToken) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the token expired more than clockSkew ago.
+func (f *RecoveryToken) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(recovery.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -74,8 +74,9 @@ func NewVerificationToken(address *identity.VerifiableAddress, expiresIn time.Du
 	}
 }
 
-func (f *VerificationToken) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the token expired more than clockSkew ago.
+func (f *VerificationToken) Valid(clockSkew time.Duration) error {
+	if f.ExpiresAt.Add(clockSkew).Before(time.Now()) {
 		return errors.WithStack(verification.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
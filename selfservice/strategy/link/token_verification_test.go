@@ -36,8 +36,8 @@ func TestVerificationToken(t *testing.T) {
 			require.NoError(t, err)
 
 			token := NewSelfServiceVerificationToken(nil, f)
-			require.Error(t, token.Valid())
-			assert.EqualError(t, token.Valid(), f.Valid().Error())
+			require.Error(t, token.Valid(0))
+			assert.EqualError(t, token.Valid(0), f.Valid(0).Error())
 		})
 	})
 }
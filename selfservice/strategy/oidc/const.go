@@ -1,5 +1,12 @@
 package oidc
 
 const (
-	sessionName = "ory_kratos_oidc_auth_code_session"
+	sessionName     = "ory_kratos_oidc_auth_code_session"
+	linkSessionName = "ory_kratos_oidc_link_session"
+)
+
+const (
+	LinkPolicyNever               = "never"
+	LinkPolicyRequireConfirmation = "require_confirmation"
+	LinkPolicyAutoLinkVerified    = "auto_link_verified"
 )
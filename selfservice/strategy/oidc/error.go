@@ -1,6 +1,10 @@
 package oidc
 
-import "github.com/ory/herodot"
+import (
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/text"
+)
 
 var (
 	ErrScopeMissing = herodot.ErrBadRequest.
@@ -11,6 +15,6 @@ var (
 				WithError("authentication failed because id_token is missing").
 				WithReasonf(`Authentication failed because no id_token was returned. Please accept the "openid" permission and try again.`)
 
-	ErrAPIFlowNotSupported = herodot.ErrBadRequest.WithError("API-based flows are not supported for this method").
+	ErrAPIFlowNotSupported = herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCAPIFlowNotSupported).WithError("API-based flows are not supported for this method").
 				WithReasonf("Social Sign In and OpenID Connect are only supported for flows initiated using the Browser endpoint.")
 )
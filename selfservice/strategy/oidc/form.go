@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/url"
 
 	"github.com/imdario/mergo"
 	"github.com/pkg/errors"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/ory/x/decoderx"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
 )
 
 func decoderRegistration(ref string) (decoderx.HTTPDecoderOption, error) {
@@ -78,3 +81,46 @@ func merge(userFormValues string, openIDProviderValues json.RawMessage, option d
 
 	return result.Bytes(), nil
 }
+
+// decodeCustomFields decodes the custom fields (`selfservice.flows.registration.custom_fields`) of the submitted
+// registration form and validates them.
+func decodeCustomFields(userFormValues url.Values, fields []config.SelfServiceCustomField) (json.RawMessage, error) {
+	schema, err := flow.CustomFieldsSchema(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := sjson.SetRawBytes([]byte(`{"type":"object"}`), "properties."+flow.CustomFieldsKey, schema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	option, err := decoderx.HTTPRawJSONSchemaCompiler(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(userFormValues.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	var df struct {
+		CustomFields json.RawMessage `json:"custom_fields"`
+	}
+	if err := decoderx.NewHTTP().Decode(
+		req, &df,
+		decoderx.HTTPFormDecoder(),
+		option,
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+	); err != nil {
+		return nil, err
+	}
+
+	if err := flow.ValidateCustomFields(fields, df.CustomFields); err != nil {
+		return nil, err
+	}
+
+	return df.CustomFields, nil
+}
@@ -2,6 +2,7 @@ package oidc
 
 import (
 	"context"
+	"encoding/json"
 
 	"golang.org/x/oauth2"
 )
@@ -13,6 +14,18 @@ type Provider interface {
 	AuthCodeURLOptions(r ider) []oauth2.AuthCodeOption
 }
 
+// EmailVerificationAsserter is implemented by providers which assert whether they verified the user's email
+// address. Accounts of other providers are never linked automatically.
+type EmailVerificationAsserter interface {
+	AssertsEmailVerification(ctx context.Context) (bool, error)
+}
+
+// NonceVerifier is implemented by providers which return the claims of a verified ID token. If RequiresNonce
+// returns true, a random nonce is sent to the provider and the `nonce` claim of the ID token must match it.
+type NonceVerifier interface {
+	RequiresNonce() bool
+}
+
 type Claims struct {
 	Issuer              string `json:"iss,omitempty"`
 	Subject             string `json:"sub,omitempty"`
@@ -35,4 +48,38 @@ type Claims struct {
 	PhoneNumber         string `json:"phone_number,omitempty"`
 	PhoneNumberVerified bool   `json:"phone_number_verified,omitempty"`
 	UpdatedAt           int64  `json:"updated_at,omitempty"`
+
+	// Raw contains all claims of the ID token. It is empty for providers which do not issue ID tokens.
+	Raw map[string]json.RawMessage `json:"-"`
+}
+
+// Value returns the claim with the given name as a string, or an empty string if it is not set. Claims which
+// are not a field of Claims can only be returned if the provider issued an ID token.
+func (c *Claims) Value(name string) string {
+	raw, ok := c.Raw[name]
+	if !ok {
+		known, err := json.Marshal(c)
+		if err != nil {
+			return ""
+		}
+
+		var claims map[string]json.RawMessage
+		if err := json.Unmarshal(known, &claims); err != nil {
+			return ""
+		}
+
+		if raw, ok = claims[name]; !ok {
+			return ""
+		}
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	if v := string(raw); v != "null" {
+		return v
+	}
+	return ""
 }
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 
 	"github.com/ory/herodot"
 
+	"github.com/ory/kratos/text"
 	"github.com/ory/x/urlx"
 )
 
@@ -65,6 +68,63 @@ type Configuration struct {
 	//
 	// More information: https://openid.net/specs/openid-connect-core-1_0.html#ClaimsParameter
 	RequestedClaims json.RawMessage `json:"requested_claims"`
+
+	// AuthURLParams are additional parameters sent to the provider's authorization endpoint, for example
+	// `prompt: consent` and `access_type: offline` to obtain a refresh token from Google. The allowed
+	// parameters are defined in the configuration schema.
+	AuthURLParams map[string]string `json:"auth_url_params"`
+
+	// Headers are static HTTP headers added to all requests sent to the provider, for example to pass an API key
+	// to an API gateway in front of the provider.
+	Headers map[string]string `json:"headers"`
+
+	// SubjectClaims are the claims which identify the user at this provider. Defaults to `sub`. If more than one
+	// claim is set, the values are joined using `|`, for example to combine `iss` and `sub`.
+	//
+	// Changing this value after users signed up with this provider prevents them from signing in!
+	SubjectClaims []string `json:"subject_claims"`
+
+	// LinkPolicy decides what happens if a user signs in with this provider for the first time and an identity
+	// with the same verified email address exists already. One of:
+	// - never (default)
+	// - require_confirmation
+	// - auto_link_verified
+	LinkPolicy string `json:"link_policy"`
+
+	// DisableNonce stops sending a `nonce` to the provider and verifying the `nonce` claim of its ID tokens. Only
+	// set this for providers which do not support the `nonce` parameter.
+	DisableNonce bool `json:"disable_nonce"`
+
+	// ClockSkew is how long ID tokens are accepted after they expired and before their `iat` and `nbf`
+	// times. It is set from `selfservice.clock_skew_tolerance` and can not be configured per provider.
+	ClockSkew time.Duration `json:"-"`
+}
+
+// Subject returns the subject used to find and link the identity's OpenID Connect credentials.
+func (p Configuration) Subject(claims *Claims) (string, error) {
+	if len(p.SubjectClaims) == 0 {
+		return claims.Subject, nil
+	}
+
+	values := make([]string, len(p.SubjectClaims))
+	for k, name := range p.SubjectClaims {
+		values[k] = claims.Value(name)
+		if values[k] == "" {
+			return "", errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				`The OpenID Connect provider "%s" did not return the claim "%s" which is required to identify the user.`, p.ID, name))
+		}
+	}
+
+	return strings.Join(values, "|"), nil
+}
+
+// AuthCodeURLOptions returns the configured authorization URL parameters.
+func (p Configuration) AuthCodeURLOptions() []oauth2.AuthCodeOption {
+	options := make([]oauth2.AuthCodeOption, 0, len(p.AuthURLParams))
+	for k, v := range p.AuthURLParams {
+		options = append(options, oauth2.SetAuthURLParam(k, v))
+	}
+	return options
 }
 
 func (p Configuration) Redir(public *url.URL) string {
@@ -75,6 +135,9 @@ func (p Configuration) Redir(public *url.URL) string {
 
 type ConfigurationCollection struct {
 	Providers []Configuration `json:"providers"`
+
+	// MaxLinkedProviders is the maximum number of providers an identity can link. Zero disables the limit.
+	MaxLinkedProviders int `json:"max_linked_providers"`
 }
 
 func (c ConfigurationCollection) Provider(id string, public *url.URL) (Provider, error) {
@@ -106,5 +169,5 @@ func (c ConfigurationCollection) Provider(id string, public *url.URL) (Provider,
 			return nil, errors.Errorf("provider type %s is not supported, supported are: %v", p.Provider, providerNames)
 		}
 	}
-	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf(`OpenID Connect Provider "%s" is unknown or has not been configured`, id))
+	return nil, errors.WithStack(herodot.ErrNotFound.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCProviderUnknown).WithReasonf(`OpenID Connect Provider "%s" is unknown or has not been configured`, id))
 }
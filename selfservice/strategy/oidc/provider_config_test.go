@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/herodot"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
@@ -30,3 +34,49 @@ func TestConfig(t *testing.T) {
 	require.Len(t, collection.Providers, 1)
 	assert.Equal(t, "generic", collection.Providers[0].Provider)
 }
+
+func TestConfigurationSubject(t *testing.T) {
+	claims := &oidc.Claims{
+		Issuer:  "https://login.microsoftonline.com/tenant/v2.0",
+		Subject: "pairwise-subject",
+		Email:   "foo@ory.sh",
+		Raw: map[string]json.RawMessage{
+			"oid": json.RawMessage(`"00000000-0000-0000-66f3-3332eca7ea81"`),
+			"num": json.RawMessage(`12345678901234567890`),
+		},
+	}
+
+	for k, tc := range []struct {
+		claims   []string
+		expected string
+	}{
+		{expected: "pairwise-subject"},
+		{claims: []string{"sub"}, expected: "pairwise-subject"},
+		{claims: []string{"oid"}, expected: "00000000-0000-0000-66f3-3332eca7ea81"},
+		{claims: []string{"email"}, expected: "foo@ory.sh"},
+		{claims: []string{"num"}, expected: "12345678901234567890"},
+		{claims: []string{"iss", "sub"}, expected: "https://login.microsoftonline.com/tenant/v2.0|pairwise-subject"},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			actual, err := oidc.Configuration{ID: "microsoft", SubjectClaims: tc.claims}.Subject(claims)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("case=fails if a claim is missing", func(t *testing.T) {
+		_, err := oidc.Configuration{ID: "microsoft", SubjectClaims: []string{"iss", "tid"}}.Subject(claims)
+		var he *herodot.DefaultError
+		require.True(t, errors.As(err, &he), "%+v", err)
+		assert.Contains(t, he.Reason(), `"tid"`)
+	})
+
+	t.Run("case=only knows the claims fields without an id token", func(t *testing.T) {
+		actual, err := oidc.Configuration{ID: "github", SubjectClaims: []string{"email"}}.Subject(&oidc.Claims{Subject: "1", Email: "foo@ory.sh"})
+		require.NoError(t, err)
+		assert.Equal(t, "foo@ory.sh", actual)
+
+		_, err = oidc.Configuration{ID: "github", SubjectClaims: []string{"oid"}}.Subject(&oidc.Claims{Subject: "1"})
+		require.Error(t, err)
+	})
+}
@@ -92,3 +92,8 @@ func (d *ProviderDiscord) Claims(ctx context.Context, exchange *oauth2.Token) (*
 
 	return claims, nil
 }
+
+// AssertsEmailVerification returns true because Discord reports whether the user's email address was verified.
+func (d *ProviderDiscord) AssertsEmailVerification(_ context.Context) (bool, error) {
+	return true, nil
+}
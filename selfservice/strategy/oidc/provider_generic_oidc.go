@@ -2,7 +2,9 @@ package oidc
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
@@ -14,6 +16,7 @@ import (
 )
 
 var _ Provider = new(ProviderGenericOIDC)
+var _ NonceVerifier = new(ProviderGenericOIDC)
 
 type ProviderGenericOIDC struct {
 	p      *gooidc.Provider
@@ -85,10 +88,18 @@ func (g *ProviderGenericOIDC) AuthCodeURLOptions(r ider) []oauth2.AuthCodeOption
 	return options
 }
 
+// RequiresNonce returns true unless `disable_nonce` is set for the provider.
+func (g *ProviderGenericOIDC) RequiresNonce() bool {
+	return !g.config.DisableNonce
+}
+
 func (g *ProviderGenericOIDC) verifyAndDecodeClaimsWithProvider(ctx context.Context, provider *gooidc.Provider, raw string) (*Claims, error) {
 	token, err := provider.
 		Verifier(&gooidc.Config{
 			ClientID: g.config.ClientID,
+			// The verifier has no leeway for `exp` and a fixed one for `nbf`, so the times are checked by
+			// verifyTokenLifetime instead.
+			SkipExpiryCheck: true,
 		}).
 		Verify(ctx, raw)
 	if err != nil {
@@ -100,9 +111,59 @@ func (g *ProviderGenericOIDC) verifyAndDecodeClaimsWithProvider(ctx context.Cont
 		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
 	}
 
+	if err := token.Claims(&claims.Raw); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+	}
+
+	if err := verifyTokenLifetime(claims.Raw, g.config.ClockSkew, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return &claims, nil
 }
 
+// verifyTokenLifetime checks the `exp`, `iat`, and `nbf` claims of an ID token. The clock skew is tolerated in
+// both directions: tokens are accepted for the skew after they expired and before they were issued or become valid.
+func verifyTokenLifetime(raw map[string]json.RawMessage, skew time.Duration, now time.Time) error {
+	exp, ok, err := numericDateClaim(raw, "exp")
+	if err != nil {
+		return err
+	} else if !ok {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The ID token does not contain the exp claim."))
+	} else if now.Add(-skew).After(exp) {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The ID token expired at %s.", exp.UTC()))
+	}
+
+	for _, name := range []string{"iat", "nbf"} {
+		t, ok, err := numericDateClaim(raw, name)
+		if err != nil {
+			return err
+		} else if ok && now.Add(skew).Before(t) {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The %s claim of the ID token lies in the future: %s.", name, t.UTC()))
+		}
+	}
+
+	return nil
+}
+
+func numericDateClaim(raw map[string]json.RawMessage, name string) (time.Time, bool, error) {
+	value, ok := raw[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(value, &n); err != nil {
+		return time.Time{}, false, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The %s claim of the ID token is not a number: %s", name, err))
+	}
+
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The %s claim of the ID token is not a number: %s", name, err))
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
+
 func (g *ProviderGenericOIDC) Claims(ctx context.Context, exchange *oauth2.Token) (*Claims, error) {
 	raw, ok := exchange.Extra("id_token").(string)
 	if !ok || len(raw) == 0 {
@@ -116,3 +177,22 @@ func (g *ProviderGenericOIDC) Claims(ctx context.Context, exchange *oauth2.Token
 
 	return g.verifyAndDecodeClaimsWithProvider(ctx, p, raw)
 }
+
+// AssertsEmailVerification uses the provider's discovery document to check if the `email_verified` claim is
+// supported. Providers which do not list the claim in `claims_supported`, including providers which do not publish
+// `claims_supported` at all, are not trusted to verify email addresses.
+func (g *ProviderGenericOIDC) AssertsEmailVerification(ctx context.Context) (bool, error) {
+	p, err := g.provider(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var discovery struct {
+		ClaimsSupported []string `json:"claims_supported"`
+	}
+	if err := p.Claims(&discovery); err != nil {
+		return false, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Discovery document: %s", err))
+	}
+
+	return stringslice.Has(discovery.ClaimsSupported, "email_verified"), nil
+}
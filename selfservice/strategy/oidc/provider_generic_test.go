@@ -3,13 +3,22 @@ package oidc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -38,10 +47,11 @@ func makeAuthCodeURL(t *testing.T, r *login.Flow) string {
 		IssuerURL:       "https://accounts.google.com",
 		Mapper:          "file://./stub/hydra.schema.json",
 		RequestedClaims: makeOIDCClaims(),
+		AuthURLParams:   map[string]string{"prompt": "consent", "access_type": "offline"},
 	}, public)
 	c, err := p.OAuth2(context.Background())
 	require.NoError(t, err)
-	return c.AuthCodeURL("state", p.AuthCodeURLOptions(r)...)
+	return c.AuthCodeURL("state", append(p.Config().AuthCodeURLOptions(), p.AuthCodeURLOptions(r)...)...)
 }
 
 func TestProviderGenericOIDC_AddAuthCodeURLOptions(t *testing.T) {
@@ -66,4 +76,198 @@ func TestProviderGenericOIDC_AddAuthCodeURLOptions(t *testing.T) {
 		}
 		assert.Contains(t, makeAuthCodeURL(t, r), "claims="+url.QueryEscape(string(makeOIDCClaims())))
 	})
+
+	t.Run("case=expect configured parameters to be set", func(t *testing.T) {
+		r := &login.Flow{
+			ID: x.NewUUID(),
+		}
+		u := makeAuthCodeURL(t, r)
+		assert.Contains(t, u, "prompt=consent")
+		assert.Contains(t, u, "access_type=offline")
+	})
+
+	t.Run("case=expect forced login to take precedence over configured prompt", func(t *testing.T) {
+		r := &login.Flow{
+			ID:     x.NewUUID(),
+			Forced: true,
+		}
+		u := makeAuthCodeURL(t, r)
+		assert.Contains(t, u, "prompt=login")
+		assert.NotContains(t, u, "prompt=consent")
+		assert.Contains(t, u, "access_type=offline")
+	})
+}
+
+func TestHeaderRoundTripper(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "my-api-key" || r.Header.Get("User-Agent") != "my-app/1.0" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 ts.URL,
+			"authorization_endpoint": ts.URL + "/oauth2/auth",
+			"token_endpoint":         ts.URL + "/oauth2/token",
+			"jwks_uri":               ts.URL + "/.well-known/jwks.json",
+		})
+	}))
+	t.Cleanup(ts.Close)
+
+	public, err := url.Parse("https://ory.sh")
+	require.NoError(t, err)
+
+	c := &Configuration{
+		Provider:     "generic",
+		ID:           "gateway",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		IssuerURL:    ts.URL,
+		Headers:      map[string]string{"X-Api-Key": "my-api-key", "User-Agent": "my-app/1.0"},
+	}
+	p := NewProviderGenericOIDC(c, public)
+
+	t.Run("case=discovery fails without headers", func(t *testing.T) {
+		_, err := p.OAuth2(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("case=discovery succeeds with headers", func(t *testing.T) {
+		client := &http.Client{Transport: &headerRoundTripper{headers: c.Headers}}
+		o, err := p.OAuth2(context.WithValue(context.Background(), oauth2.HTTPClient, client))
+		require.NoError(t, err)
+		assert.Equal(t, ts.URL+"/oauth2/token", o.Endpoint.TokenURL)
+	})
+}
+
+func TestProviderGenericOIDC_AssertsEmailVerification(t *testing.T) {
+	newProvider := func(t *testing.T, claimsSupported []string) *ProviderGenericOIDC {
+		var ts *httptest.Server
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                 ts.URL,
+				"authorization_endpoint": ts.URL + "/oauth2/auth",
+				"token_endpoint":         ts.URL + "/oauth2/token",
+				"jwks_uri":               ts.URL + "/.well-known/jwks.json",
+				"claims_supported":       claimsSupported,
+			})
+		}))
+		t.Cleanup(ts.Close)
+
+		public, err := url.Parse("https://ory.sh")
+		require.NoError(t, err)
+		return NewProviderGenericOIDC(&Configuration{
+			Provider:  "generic",
+			ID:        "generic",
+			ClientID:  "client",
+			IssuerURL: ts.URL,
+		}, public)
+	}
+
+	for k, tc := range []struct {
+		claims   []string
+		expected bool
+	}{
+		{claims: nil, expected: false},
+		{claims: []string{"sub", "email", "email_verified"}, expected: true},
+		{claims: []string{"sub", "email"}, expected: false},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			asserts, err := newProvider(t, tc.claims).AssertsEmailVerification(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, asserts)
+		})
+	}
+}
+
+func TestVerifyNonce(t *testing.T) {
+	public, err := url.Parse("https://ory.sh")
+	require.NoError(t, err)
+
+	generic := NewProviderGenericOIDC(&Configuration{ID: "generic", Provider: "generic"}, public)
+	disabled := NewProviderGenericOIDC(&Configuration{ID: "disabled", Provider: "generic", DisableNonce: true}, public)
+	github := NewProviderGitHub(&Configuration{ID: "github", Provider: "github"}, public)
+	gitlab := NewProviderGitLab(&Configuration{ID: "gitlab", Provider: "gitlab"}, public)
+
+	claimsWithNonce := func(nonce string) *Claims {
+		return &Claims{Subject: "foo", Raw: map[string]json.RawMessage{"sub": json.RawMessage(`"foo"`), "nonce": json.RawMessage(fmt.Sprintf("%q", nonce))}}
+	}
+
+	t.Run("case=requires a nonce for providers issuing ID tokens by default", func(t *testing.T) {
+		assert.True(t, requiresNonce(generic))
+		assert.False(t, requiresNonce(disabled))
+		assert.False(t, requiresNonce(github))
+		assert.False(t, requiresNonce(gitlab))
+	})
+
+	t.Run("case=accepts a matching nonce", func(t *testing.T) {
+		require.NoError(t, verifyNonce(generic, &authCodeContainer{Nonce: "nonce"}, claimsWithNonce("nonce")))
+	})
+
+	for k, tc := range []struct {
+		container *authCodeContainer
+		claims    *Claims
+	}{
+		{container: &authCodeContainer{Nonce: "nonce"}, claims: claimsWithNonce("other")},
+		{container: &authCodeContainer{Nonce: "nonce"}, claims: &Claims{Subject: "foo", Raw: map[string]json.RawMessage{"sub": json.RawMessage(`"foo"`)}}},
+		{container: &authCodeContainer{}, claims: claimsWithNonce("")},
+		{container: &authCodeContainer{}, claims: claimsWithNonce("nonce")},
+	} {
+		t.Run(fmt.Sprintf("case=%d/rejects a missing or mismatching nonce", k), func(t *testing.T) {
+			err := verifyNonce(generic, tc.container, tc.claims)
+			var he *herodot.DefaultError
+			require.True(t, errors.As(err, &he), "%+v", err)
+			assert.Equal(t, text.ErrorCodeOIDCNonceMismatch, he.DetailsField[text.ErrorCodeDetailKey])
+		})
+	}
+
+	t.Run("case=ignores the nonce if it is disabled or not supported", func(t *testing.T) {
+		for _, p := range []Provider{disabled, github, gitlab} {
+			require.NoError(t, verifyNonce(p, &authCodeContainer{}, claimsWithNonce("other")), p.Config().ID)
+		}
+	})
+}
+
+func TestVerifyTokenLifetime(t *testing.T) {
+	now := time.Now()
+	skew := 5 * time.Second
+	claims := func(values map[string]time.Time) map[string]json.RawMessage {
+		raw := map[string]json.RawMessage{"sub": json.RawMessage(`"foo"`)}
+		for name, value := range values {
+			raw[name] = json.RawMessage(fmt.Sprintf("%d", value.Unix()))
+		}
+		return raw
+	}
+
+	for k, tc := range []struct {
+		d      string
+		claims map[string]time.Time
+		valid  bool
+	}{
+		{d: "valid token", claims: map[string]time.Time{"exp": now.Add(time.Hour), "iat": now.Add(-time.Second), "nbf": now.Add(-time.Second)}, valid: true},
+		{d: "expired within the skew", claims: map[string]time.Time{"exp": now.Add(-2 * time.Second)}, valid: true},
+		{d: "expired beyond the skew", claims: map[string]time.Time{"exp": now.Add(-time.Minute)}},
+		{d: "issued slightly in the future", claims: map[string]time.Time{"exp": now.Add(time.Hour), "iat": now.Add(2 * time.Second)}, valid: true},
+		{d: "issued in the future beyond the skew", claims: map[string]time.Time{"exp": now.Add(time.Hour), "iat": now.Add(time.Minute)}},
+		{d: "valid slightly in the future", claims: map[string]time.Time{"exp": now.Add(time.Hour), "nbf": now.Add(2 * time.Second)}, valid: true},
+		{d: "valid in the future beyond the skew", claims: map[string]time.Time{"exp": now.Add(time.Hour), "nbf": now.Add(time.Minute)}},
+		{d: "missing exp", claims: map[string]time.Time{"iat": now}},
+	} {
+		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+			err := verifyTokenLifetime(claims(tc.claims), skew, now)
+			if tc.valid {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, herodot.ErrBadRequest), "%+v", err)
+		})
+	}
+
+	t.Run("case=rejects claims which are not numbers", func(t *testing.T) {
+		require.Error(t, verifyTokenLifetime(map[string]json.RawMessage{"exp": json.RawMessage(`"tomorrow"`)}, skew, now))
+	})
 }
@@ -99,3 +99,8 @@ func (g *ProviderGitHub) Claims(ctx context.Context, exchange *oauth2.Token) (*C
 
 	return claims, nil
 }
+
+// AssertsEmailVerification returns true because GitHub reports whether the primary email address was verified.
+func (g *ProviderGitHub) AssertsEmailVerification(_ context.Context) (bool, error) {
+	return true, nil
+}
@@ -72,3 +72,13 @@ func (g *ProviderGitLab) endpoint() (*url.URL, error) {
 	}
 	return url.Parse(e)
 }
+
+// RequiresNonce returns false because the claims are fetched from GitLab's userinfo endpoint instead of an ID token.
+func (g *ProviderGitLab) RequiresNonce() bool {
+	return false
+}
+
+// AssertsEmailVerification returns true because GitLab's userinfo endpoint includes the `email_verified` claim.
+func (g *ProviderGitLab) AssertsEmailVerification(_ context.Context) (bool, error) {
+	return true, nil
+}
@@ -77,3 +77,9 @@ type microsoftUnverifiedClaims struct {
 func (c *microsoftUnverifiedClaims) Valid() error {
 	return nil
 }
+
+// AssertsEmailVerification returns false because the `email` claim of Azure AD is not verified and may be
+// chosen freely by tenant administrators.
+func (m *ProviderMicrosoft) AssertsEmailVerification(_ context.Context) (bool, error) {
+	return false, nil
+}
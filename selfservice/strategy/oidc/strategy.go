@@ -3,6 +3,7 @@ package oidc
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
+	"golang.org/x/oauth2"
 
 	"github.com/ory/x/jsonx"
 
@@ -24,6 +26,7 @@ import (
 
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
@@ -33,6 +36,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -50,14 +54,22 @@ type dependencies interface {
 
 	config.Providers
 
+	event.EmitterProvider
+
 	x.LoggingProvider
+	x.StrategyRateLimiterProvider
 	x.CookieProvider
+	x.HTTPClientProvider
 	x.CSRFTokenGeneratorProvider
 
 	identity.ValidationProvider
+	identity.TraitsTransformerProvider
 	identity.PrivilegedPoolProvider
+	identity.ManagementProvider
 	identity.ActiveCredentialsCounterStrategyProvider
 
+	schema.IdentityTraitsProvider
+
 	session.ManagementProvider
 	session.HandlerProvider
 
@@ -67,6 +79,7 @@ type dependencies interface {
 	login.StrategyProvider
 	login.HandlerProvider
 	login.ErrorHandlerProvider
+	login.AttemptLoggerProvider
 
 	registration.HookExecutorProvider
 	registration.FlowPersistenceProvider
@@ -101,6 +114,10 @@ type authCodeContainer struct {
 	FlowID string     `json:"flow_id"`
 	State  string     `json:"state"`
 	Form   url.Values `json:"form"`
+
+	// Nonce is sent to providers which issue ID tokens and must match the ID token's `nonce` claim. It is
+	// stored with the flow ID and removed once the callback is received, so it can not be reused.
+	Nonce string `json:"nonce,omitempty"`
 }
 
 func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
@@ -112,7 +129,8 @@ func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identi
 			}
 
 			for _, ider := range c.Identifiers {
-				parts := strings.Split(ider, ":")
+				// Composite subjects may contain colons, e.g. when using the issuer URL.
+				parts := strings.SplitN(ider, ":", 2)
 				if len(parts) != 2 {
 					continue
 				}
@@ -145,7 +163,7 @@ func (s *Strategy) setRoutes(r *x.RouterPublic) {
 func NewStrategy(d dependencies) *Strategy {
 	return &Strategy{
 		d:         d,
-		f:         fetcher.NewFetcher(),
+		f:         fetcher.NewFetcher(fetcher.WithClient(d.HTTPClient())),
 		validator: schema.NewValidator(),
 	}
 }
@@ -173,7 +191,7 @@ func (s *Strategy) handleAuth(w http.ResponseWriter, r *http.Request, ps httprou
 		return
 	}
 
-	config, err := provider.OAuth2(r.Context())
+	config, err := provider.OAuth2(s.clientContext(r.Context(), provider))
 	if err != nil {
 		s.handleError(w, r, rid, pid, nil, err)
 		return
@@ -185,23 +203,55 @@ func (s *Strategy) handleAuth(w http.ResponseWriter, r *http.Request, ps httprou
 		return
 	}
 
+	// The submitted anti-CSRF token is checked by the CSRF middleware and is absent if the settings flow
+	// redirects here to link a provider, so only the anti-CSRF token of the flow is verified.
+	if err := flow.VerifyFlowCSRFToken(r, flow.TypeBrowser, s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, flowCSRFToken(req)); err != nil {
+		s.handleError(w, r, rid, pid, nil, err)
+		return
+	}
+
 	if s.alreadyAuthenticated(w, r, req) {
 		return
 	}
 
+	switch f := req.(type) {
+	case *login.Flow:
+		s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "login", f.ID, f.Type).WithStrategy(s.ID().String()))
+	case *registration.Flow:
+		s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "registration", f.ID, f.Type).WithStrategy(s.ID().String()))
+
+		// Custom fields are validated before redirecting to the provider so that missing values, e.g. the
+		// acceptance of the terms of service, are reported without signing in at the provider first.
+		if _, err := decodeCustomFields(r.PostForm, s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields()); err != nil {
+			s.handleError(w, r, rid, pid, nil, err)
+			return
+		}
+	}
+
 	state := x.NewUUID().String()
+	var nonce string
+	if requiresNonce(provider) {
+		nonce = x.NewUUID().String()
+	}
+
 	if err := s.d.ContinuityManager().Pause(r.Context(), w, r, sessionName,
 		continuity.WithPayload(&authCodeContainer{
 			State:  state,
 			FlowID: rid.String(),
 			Form:   r.PostForm,
+			Nonce:  nonce,
 		}),
 		continuity.WithLifespan(time.Minute*30)); err != nil {
 		s.handleError(w, r, rid, pid, nil, err)
 		return
 	}
 
-	http.Redirect(w, r, config.AuthCodeURL(state, provider.AuthCodeURLOptions(req)...), http.StatusFound)
+	// Options of the provider, such as prompting for a forced login, take precedence over the configured parameters.
+	options := append(provider.Config().AuthCodeURLOptions(), provider.AuthCodeURLOptions(req)...)
+	if nonce != "" {
+		options = append(options, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+	http.Redirect(w, r, config.AuthCodeURL(state, options...), http.StatusFound)
 }
 
 func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.UUID) (ider, error) {
@@ -214,7 +264,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, ErrAPIFlowNotSupported
 		}
 
-		if err := ar.Valid(); err != nil {
+		if err := ar.Valid(s.d.Configuration(ctx).SelfServiceClockSkewTolerance()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -225,7 +275,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, ErrAPIFlowNotSupported
 		}
 
-		if err := ar.Valid(); err != nil {
+		if err := ar.Valid(s.d.Configuration(ctx).SelfServiceClockSkewTolerance()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -242,7 +292,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, err
 		}
 
-		if err := ar.Valid(sess); err != nil {
+		if err := ar.Valid(sess, s.d.Configuration(ctx).SelfServiceClockSkewTolerance()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -258,7 +308,7 @@ func (s *Strategy) validateCallback(w http.ResponseWriter, r *http.Request) (ide
 	)
 
 	if state == "" {
-		return nil, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Unable to complete OpenID Connect flow because the OpenID Provider did not return the state query parameter.`))
+		return nil, nil, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCStateMismatch).WithReasonf(`Unable to complete OpenID Connect flow because the OpenID Provider did not return the state query parameter.`))
 	}
 
 	var container authCodeContainer
@@ -267,7 +317,7 @@ func (s *Strategy) validateCallback(w http.ResponseWriter, r *http.Request) (ide
 	}
 
 	if state != container.State {
-		return nil, &container, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Unable to complete OpenID Connect flow because the query state parameter does not match the state parameter from the session cookie.`))
+		return nil, &container, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCStateMismatch).WithReasonf(`Unable to complete OpenID Connect flow because the query state parameter does not match the state parameter from the session cookie.`))
 	}
 
 	req, err := s.validateFlow(r.Context(), r, x.ParseUUID(container.FlowID))
@@ -276,7 +326,7 @@ func (s *Strategy) validateCallback(w http.ResponseWriter, r *http.Request) (ide
 	}
 
 	if r.URL.Query().Get("error") != "" {
-		return req, &container, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Unable to complete OpenID Connect flow because the OpenID Provider returned error "%s": %s`, r.URL.Query().Get("error"), r.URL.Query().Get("error_description")))
+		return req, &container, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCProviderError).WithReasonf(`Unable to complete OpenID Connect flow because the OpenID Provider returned error "%s": %s`, r.URL.Query().Get("error"), r.URL.Query().Get("error_description")))
 	}
 
 	if code == "" {
@@ -309,38 +359,54 @@ func (s *Strategy) handleCallback(w http.ResponseWriter, r *http.Request, ps htt
 	req, container, err := s.validateCallback(w, r)
 	if err != nil {
 		if req != nil {
-			s.handleError(w, r, req.GetID(), pid, nil, err)
+			s.handleCallbackError(w, r, req, pid, err)
 		} else {
 			s.handleError(w, r, x.EmptyUUID, pid, nil, err)
 		}
 		return
 	}
 
+	if err := s.d.StrategyRateLimiter().Allow(r, flowName(req), s.ID().String()); err != nil {
+		s.handleCallbackError(w, r, req, pid, err)
+		return
+	}
+
 	if s.alreadyAuthenticated(w, r, req) {
 		return
 	}
 
 	provider, err := s.provider(r.Context(), pid)
 	if err != nil {
-		s.handleError(w, r, req.GetID(), pid, nil, err)
+		s.handleCallbackError(w, r, req, pid, err)
 		return
 	}
 
-	config, err := provider.OAuth2(context.Background())
+	config, err := provider.OAuth2(s.clientContext(context.Background(), provider))
 	if err != nil {
-		s.handleError(w, r, req.GetID(), pid, nil, err)
+		s.handleCallbackError(w, r, req, pid, err)
 		return
 	}
 
-	token, err := config.Exchange(r.Context(), code)
+	// The token exchange is not idempotent and is thus never retried by the HTTP client.
+	token, err := config.Exchange(s.clientContext(r.Context(), provider), code)
 	if err != nil {
-		s.handleError(w, r, req.GetID(), pid, nil, err)
+		s.handleCallbackError(w, r, req, pid, err)
 		return
 	}
 
-	claims, err := provider.Claims(r.Context(), token)
+	claims, err := provider.Claims(s.clientContext(r.Context(), provider), token)
 	if err != nil {
-		s.handleError(w, r, req.GetID(), pid, nil, err)
+		s.handleCallbackError(w, r, req, pid, err)
+		return
+	}
+
+	if err := verifyNonce(provider, container, claims); err != nil {
+		s.handleCallbackError(w, r, req, pid, err)
+		return
+	}
+
+	if claims.Subject, err = provider.Config().Subject(claims); err != nil {
+		s.handleCallbackError(w, r, req, pid, err)
 		return
 	}
 
@@ -366,6 +432,60 @@ func (s *Strategy) handleCallback(w http.ResponseWriter, r *http.Request, ps htt
 	}
 }
 
+// handleCallbackError handles errors which occur before the callback is processed. Failed login flows are added to
+// the login attempt log.
+func (s *Strategy) handleCallbackError(w http.ResponseWriter, r *http.Request, req ider, provider string, err error) {
+	if _, ok := req.(*login.Flow); ok {
+		s.d.LoginAttemptLogger().Record(r, login.NewAttemptLog(r, s.ID(), "", uuid.Nil, login.AttemptOutcomeFailure, login.AttemptFailureReason(err)))
+	}
+	s.handleError(w, r, req.GetID(), provider, nil, err)
+}
+
+// flowName returns the name of the flow the callback belongs to.
+func flowName(req ider) string {
+	switch req.(type) {
+	case *login.Flow:
+		return "login"
+	case *registration.Flow:
+		return "registration"
+	case *settings.Flow:
+		return "settings"
+	}
+	return ""
+}
+
+// flowCSRFToken returns the anti-CSRF token the flow was initialized with.
+func flowCSRFToken(req ider) string {
+	switch f := req.(type) {
+	case *login.Flow:
+		return f.CSRFToken
+	case *registration.Flow:
+		return f.CSRFToken
+	case *settings.Flow:
+		return f.CSRFToken
+	}
+	return ""
+}
+
+func requiresNonce(provider Provider) bool {
+	v, ok := provider.(NonceVerifier)
+	return ok && v.RequiresNonce()
+}
+
+// verifyNonce makes sure that the ID token was issued for this flow. Flows which were started without a nonce,
+// for example before the nonce was enforced, are rejected as well.
+func verifyNonce(provider Provider, container *authCodeContainer, claims *Claims) error {
+	if !requiresNonce(provider) {
+		return nil
+	}
+
+	nonce := claims.Value("nonce")
+	if container.Nonce == "" || subtle.ConstantTimeCompare([]byte(nonce), []byte(container.Nonce)) != 1 {
+		return errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeOIDCNonceMismatch).WithReasonf(`Unable to complete OpenID Connect flow because the nonce of the ID token does not match the nonce of the flow.`))
+	}
+	return nil
+}
+
 func uid(provider, subject string) string {
 	return fmt.Sprintf("%s:%s", provider, subject)
 }
@@ -387,9 +507,10 @@ func (s *Strategy) populateMethod(r *http.Request, flowID uuid.UUID) (*FlowMetho
 
 	f := form.NewHTMLForm(s.authURL(r.Context(), flowID))
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
-	// does not need sorting because there is only one field
 
-	return NewFlowMethod(f).AddProviders(conf.Providers), nil
+	m := NewFlowMethod(f).AddProviders(conf.Providers)
+	m.GroupFields(form.FieldGroupOIDC)
+	return m, nil
 }
 
 func (s *Strategy) Config(ctx context.Context) (*ConfigurationCollection, error) {
@@ -403,9 +524,47 @@ func (s *Strategy) Config(ctx context.Context) (*ConfigurationCollection, error)
 		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Provider configuration: %s", err))
 	}
 
+	skew := s.d.Configuration(ctx).SelfServiceClockSkewTolerance()
+	for k := range c.Providers {
+		c.Providers[k].ClockSkew = skew
+	}
+
 	return &c, nil
 }
 
+// clientContext returns a context which makes the OAuth2 and OpenID Connect libraries use the
+// retrying HTTP client for requests to the provider. The provider's custom headers are added to every request.
+func (s *Strategy) clientContext(ctx context.Context, provider Provider) context.Context {
+	client := s.d.HTTPClient()
+	if headers := provider.Config().Headers; len(headers) > 0 {
+		client = &http.Client{
+			Transport:     &headerRoundTripper{RoundTripper: client.Transport, headers: headers},
+			CheckRedirect: client.CheckRedirect,
+			Jar:           client.Jar,
+			Timeout:       client.Timeout,
+		}
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, client)
+}
+
+// headerRoundTripper adds static headers to all requests.
+type headerRoundTripper struct {
+	http.RoundTripper
+	headers map[string]string
+}
+
+func (rt *headerRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	for k, v := range rt.headers {
+		r.Header.Set(k, v)
+	}
+
+	if rt.RoundTripper == nil {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	return rt.RoundTripper.RoundTrip(r)
+}
+
 func (s *Strategy) provider(ctx context.Context, id string) (Provider, error) {
 	if c, err := s.Config(ctx); err != nil {
 		return nil, err
@@ -418,7 +577,7 @@ func (s *Strategy) provider(ctx context.Context, id string) (Provider, error) {
 
 func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, rid uuid.UUID, provider string, traits []byte, err error) {
 	if x.IsZeroUUID(rid) {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errorx.WithStrategy(err, s.ID().String()))
 		return
 	}
 
@@ -459,6 +618,7 @@ func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, rid uuid.
 
 			method.Config.UnsetField("provider")
 			method.Config.SetField(form.Field{Name: "provider", Value: provider, Type: "submit"})
+			method.Config.GroupFields(form.FieldGroupOIDC)
 			rr.Methods[s.ID()] = method
 		}
 
@@ -466,5 +626,5 @@ func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, rid uuid.
 		return
 	}
 
-	s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+	s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errorx.WithStrategy(err, s.ID().String()))
 }
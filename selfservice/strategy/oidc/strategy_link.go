@@ -0,0 +1,253 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+)
+
+var _ login.PostHookExecutor = new(Strategy)
+
+// linkContainer is stored while the user confirms linking the provider's account by signing in to the existing
+// identity.
+type linkContainer struct {
+	IdentityID uuid.UUID `json:"identity_id"`
+	Provider   string    `json:"provider"`
+	Subject    string    `json:"subject"`
+}
+
+// addProviderCredentials adds the provider's subject to the identity's OpenID Connect credentials. It fails if the
+// identity already linked the maximum number of providers.
+func (s *Strategy) addProviderCredentials(ctx context.Context, i *identity.Identity, provider, subject string) error {
+	providers, err := s.Config(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkLinkedProvidersLimit(ctx, providers, i); err != nil {
+		return err
+	}
+
+	var conf CredentialsConfig
+	creds, err := i.ParseCredentials(s.ID(), &conf)
+	if errors.Is(err, herodot.ErrNotFound) {
+		if creds, err = NewCredentials(provider, subject); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		creds.Identifiers = append(creds.Identifiers, uid(provider, subject))
+		conf.Providers = append(conf.Providers, ProviderCredentialsConfig{
+			Subject: subject, Provider: provider})
+		creds.Config, err = json.Marshal(conf)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	i.SetCredentials(s.ID(), *creds)
+	return nil
+}
+
+// linkPolicy returns the link policy to apply. Automatic linking is downgraded to a confirmation unless the
+// provider asserted that it verified the email address.
+func (s *Strategy) linkPolicy(r *http.Request, claims *Claims, provider Provider) (string, error) {
+	switch provider.Config().LinkPolicy {
+	case LinkPolicyAutoLinkVerified:
+		asserter, ok := provider.(EmailVerificationAsserter)
+		if !ok || !claims.EmailVerified {
+			return LinkPolicyRequireConfirmation, nil
+		}
+
+		asserts, err := asserter.AssertsEmailVerification(s.clientContext(r.Context(), provider))
+		if err != nil {
+			return "", err
+		} else if !asserts {
+			return LinkPolicyRequireConfirmation, nil
+		}
+		return LinkPolicyAutoLinkVerified, nil
+	case LinkPolicyRequireConfirmation:
+		return LinkPolicyRequireConfirmation, nil
+	default:
+		return LinkPolicyNever, nil
+	}
+}
+
+// linkExistingIdentity links the provider's account to an existing identity with the same verified email address
+// if the provider's link policy allows it. Returns true if the request was handled.
+func (s *Strategy) linkExistingIdentity(w http.ResponseWriter, r *http.Request, a *registration.Flow, claims *Claims, provider Provider) bool {
+	if len(claims.Email) == 0 || provider.Config().LinkPolicy == "" || provider.Config().LinkPolicy == LinkPolicyNever {
+		return false
+	}
+
+	address, err := s.d.PrivilegedIdentityPool().FindVerifiableAddressByValue(r.Context(), identity.VerifiableAddressTypeEmail, claims.Email)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return false
+	} else if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return true
+	} else if !address.Verified {
+		return false
+	}
+
+	policy, err := s.linkPolicy(r, claims, provider)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return true
+	}
+
+	switch policy {
+	case LinkPolicyAutoLinkVerified:
+		s.autoLink(w, r, a, address.IdentityID, claims, provider)
+		return true
+	case LinkPolicyRequireConfirmation:
+		s.requireLinkConfirmation(w, r, a, address.IdentityID, claims, provider)
+		return true
+	}
+
+	return false
+}
+
+func (s *Strategy) autoLink(w http.ResponseWriter, r *http.Request, a *registration.Flow, id uuid.UUID, claims *Claims, provider Provider) {
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	if err := s.addProviderCredentials(r.Context(), i, provider.Config().ID, claims.Subject); err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	if err := s.d.IdentityManager().Update(r.Context(), i, identity.ManagerAllowWriteProtectedTraits); err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("provider", provider.Config().ID).
+		Info("OpenID Connect account was linked to the identity with the same verified email address.")
+
+	// This flow only works for browsers anyways.
+	lf, err := s.d.LoginHandler().NewLoginFlow(w, r, flow.TypeBrowser)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	if err := s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypeOIDC, lf, i); err != nil {
+		s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+}
+
+func (s *Strategy) requireLinkConfirmation(w http.ResponseWriter, r *http.Request, a *registration.Flow, id uuid.UUID, claims *Claims, provider Provider) {
+	// This flow only works for browsers anyways.
+	lf, err := s.d.LoginHandler().NewLoginFlow(w, r, flow.TypeBrowser)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	if err := s.d.ContinuityManager().Pause(r.Context(), w, r, linkSessionName,
+		continuity.WithPayload(&linkContainer{
+			IdentityID: id,
+			Provider:   provider.Config().ID,
+			Subject:    claims.Subject,
+		}),
+		continuity.WithLifespan(s.d.Configuration(r.Context()).SelfServiceFlowLoginRequestLifespan())); err != nil {
+		s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	lf.Messages.Add(text.NewInfoLoginLinkConfirmation(provider.Config().ID))
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), lf); err != nil {
+		s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+		return
+	}
+
+	s.d.Logger().
+		WithRequest(r).
+		WithField("provider", provider.Config().ID).
+		WithField("subject", claims.Subject).
+		Debug("Received successful OpenID Connect callback for an email address of an existing identity. Asking the user to sign in to confirm the link.")
+
+	http.Redirect(w, r, urlx.CopyWithQuery(s.d.Configuration(r.Context()).SelfServiceFlowLoginUI(), url.Values{"flow": {lf.ID.String()}}).String(), http.StatusFound)
+}
+
+// ExecuteLoginPostHook completes a pending link once the user signed in to the identity the provider's account
+// should be linked to.
+func (s *Strategy) ExecuteLoginPostHook(w http.ResponseWriter, r *http.Request, _ *login.Flow, sess *session.Session) error {
+	var c linkContainer
+	if _, err := s.d.ContinuityManager().Continue(r.Context(), w, r, linkSessionName, continuity.WithPayload(&c)); err != nil {
+		if !errors.Is(err, &continuity.ErrNotResumable) {
+			// A failed link must not prevent the sign in.
+			s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to resume pending OpenID Connect account link.")
+		}
+		return nil
+	}
+
+	if c.IdentityID != sess.IdentityID {
+		s.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", sess.IdentityID).
+			WithField("provider", c.Provider).
+			Warn("Discarded pending OpenID Connect account link because the user signed in to another identity.")
+		return nil
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), sess.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), uid(c.Provider, c.Subject)); err == nil {
+		// The account was linked in the meantime.
+		return nil
+	}
+
+	if err := s.addProviderCredentials(r.Context(), i, c.Provider, c.Subject); errors.As(err, new(*schema.ValidationError)) {
+		// A failed link must not prevent the sign in.
+		s.d.Audit().
+			WithRequest(r).
+			WithError(err).
+			WithField("identity_id", i.ID).
+			WithField("provider", c.Provider).
+			Warn("Discarded pending OpenID Connect account link because the identity can not link more providers.")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := s.d.IdentityManager().Update(r.Context(), i, identity.ManagerAllowWriteProtectedTraits); err != nil {
+		return err
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("provider", c.Provider).
+		Info("OpenID Connect account was linked after the user confirmed the link by signing in.")
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
@@ -63,13 +64,13 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login
 			return
 		}
 
-		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		s.handleLoginError(w, r, a, provider, claims, uuid.Nil, err)
 		return
 	}
 
 	var o CredentialsConfig
 	if err := json.NewDecoder(bytes.NewBuffer(c.Config)).Decode(&o); err != nil {
-		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error())))
+		s.handleLoginError(w, r, a, provider, claims, i.ID, errors.WithStack(herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error())))
 		return
 	}
 
@@ -83,5 +84,12 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login
 		}
 	}
 
-	s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to find matching OpenID Connect Credentials.").WithDebugf(`Unable to find credentials that match the given provider "%s" and subject "%s".`, provider.Config().ID, claims.Subject)))
+	s.handleLoginError(w, r, a, provider, claims, i.ID, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to find matching OpenID Connect Credentials.").WithDebugf(`Unable to find credentials that match the given provider "%s" and subject "%s".`, provider.Config().ID, claims.Subject)))
+}
+
+// handleLoginError adds the failed login to the login attempt log and handles the error. Failures of the login hooks
+// are recorded by the login hook executor.
+func (s *Strategy) handleLoginError(w http.ResponseWriter, r *http.Request, a *login.Flow, provider Provider, claims *Claims, identityID uuid.UUID, err error) {
+	s.d.LoginAttemptLogger().Record(r, login.NewAttemptLog(r, s.ID(), uid(provider.Config().ID, claims.Subject), identityID, login.AttemptOutcomeFailure, login.AttemptFailureReason(err)))
+	s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/x"
 )
 
@@ -41,6 +42,11 @@ func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.
 	if err != nil {
 		return err
 	}
+	for _, field := range flow.CustomFormFields(s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields()) {
+		config.SetField(field)
+	}
+	config.GroupFields(form.FieldGroupOIDC)
+
 	sr.Methods[s.ID()] = &registration.FlowMethod{
 		Method: s.ID(),
 		Config: &registration.FlowMethodConfig{FlowMethodConfigurator: config},
@@ -74,6 +80,10 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
+	if s.linkExistingIdentity(w, r, a, claims, provider) {
+		return
+	}
+
 	jn, err := s.f.Fetch(provider.Config().Mapper)
 	if err != nil {
 		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
@@ -127,6 +137,22 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
+	a.CustomFields, err = decodeCustomFields(container.Form, s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields())
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, i.Traits, err)
+		return
+	}
+
+	if err := s.d.RegistrationExecutor().ApplyDefaults(r, s.ID(), i); err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, i.Traits, err)
+		return
+	}
+
+	if err := s.d.IdentityTraitsTransformer().Transform(r.Context(), i); err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, i.Traits, err)
+		return
+	}
+
 	// Validate the identity itself
 	if err := s.d.IdentityValidator().Validate(r.Context(), i); err != nil {
 		s.handleError(w, r, a.GetID(), provider.Config().ID, i.Traits, err)
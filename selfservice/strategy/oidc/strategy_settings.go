@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/gobuffalo/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -16,7 +15,9 @@ import (
 	"github.com/ory/jsonschema/v3"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/form"
@@ -84,6 +85,51 @@ func (s *Strategy) linkedProviders(ctx context.Context, conf *ConfigurationColle
 	return result, nil
 }
 
+// maxLinkedProviders returns the maximum number of providers the identity can link. The limit of the identity's
+// schema takes precedence over the limit of the method. Zero means no limit.
+func (s *Strategy) maxLinkedProviders(ctx context.Context, conf *ConfigurationCollection, confidential *identity.Identity) (int, error) {
+	is, err := s.d.IdentityTraitsSchemas(ctx).GetByID(confidential.SchemaID)
+	if err != nil {
+		return 0, err
+	}
+
+	if is.MaxLinkedOIDCProviders > 0 {
+		return is.MaxLinkedOIDCProviders, nil
+	}
+	return conf.MaxLinkedProviders, nil
+}
+
+func (s *Strategy) countLinkedProviders(confidential *identity.Identity) (int, error) {
+	creds, ok := confidential.GetCredentials(s.ID())
+	if !ok {
+		return 0, nil
+	}
+
+	var linked CredentialsConfig
+	if err := json.Unmarshal(creds.Config, &linked); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return len(linked.Providers), nil
+}
+
+// checkLinkedProvidersLimit returns an error if the identity already linked the maximum number of providers.
+func (s *Strategy) checkLinkedProvidersLimit(ctx context.Context, conf *ConfigurationCollection, confidential *identity.Identity) error {
+	limit, err := s.maxLinkedProviders(ctx, conf, confidential)
+	if err != nil {
+		return err
+	} else if limit == 0 {
+		return nil
+	}
+
+	count, err := s.countLinkedProviders(confidential)
+	if err != nil {
+		return err
+	} else if count >= limit {
+		return schema.NewLinkedProvidersLimitError(limit)
+	}
+	return nil
+}
+
 func (s *Strategy) linkableProviders(ctx context.Context, conf *ConfigurationCollection, confidential *identity.Identity) ([]Provider, error) {
 	var available CredentialsConfig
 	creds, ok := confidential.GetCredentials(s.ID())
@@ -140,6 +186,13 @@ func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity
 		return err
 	}
 
+	// Hide the link buttons once the identity reached the maximum number of linked providers.
+	if err := s.checkLinkedProvidersLimit(r.Context(), conf, confidential); errors.As(err, new(*schema.ValidationError)) {
+		linkable = nil
+	} else if err != nil {
+		return err
+	}
+
 	f := form.NewHTMLForm(urlx.CopyWithQuery(urlx.AppendPaths(
 		s.d.Configuration(r.Context()).SelfPublicURL(), SettingsPath), url.Values{"flow": {sr.ID.String()}}).String())
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
@@ -159,6 +212,7 @@ func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity
 			Value: l.Config().ID,
 		})
 	}
+	f.GroupFields(form.FieldGroupOIDC)
 
 	sr.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
 		Method: s.SettingsStrategyID(),
@@ -243,6 +297,8 @@ func (s *Strategy) completeSettingsFlow(w http.ResponseWriter, r *http.Request,
 		s.handleSettingsError(w, r, ctxUpdate, &p, err)
 	}
 
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "settings", ctxUpdate.Flow.ID, ctxUpdate.Flow.Type).WithStrategy(s.SettingsStrategyID()).WithIdentity(ctxUpdate.Session.IdentityID))
+
 	p.Link = r.Form.Get("link")
 	p.Unlink = r.Form.Get("unlink")
 	if l, u := len(p.Link), len(p.Unlink); l > 0 && u > 0 {
@@ -275,6 +331,10 @@ func (s *Strategy) isLinkable(r *http.Request, ctxUpdate *settings.UpdateContext
 		return nil, err
 	}
 
+	if err := s.checkLinkedProvidersLimit(r.Context(), providers, i); err != nil {
+		return nil, err
+	}
+
 	linkable, err := s.linkableProviders(r.Context(), providers, i)
 	if err != nil {
 		return nil, err
@@ -301,7 +361,7 @@ func (s *Strategy) initLinkProvider(w http.ResponseWriter, r *http.Request, ctxU
 		return
 	}
 
-	if ctxUpdate.Session.AuthenticatedAt.Add(s.d.Configuration(r.Context()).SelfServiceFlowSettingsPrivilegedSessionMaxAge()).Before(time.Now()) {
+	if settings.CredentialsChangeNeedsReAuth(s.d.Configuration(r.Context()), ctxUpdate.Session, identity.CredentialsTypeOIDC) {
 		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(settings.NewFlowNeedsReAuth()))
 		return
 	}
@@ -315,7 +375,7 @@ func (s *Strategy) linkProvider(w http.ResponseWriter, r *http.Request,
 	ctxUpdate *settings.UpdateContext, claims *Claims, provider Provider) {
 	p := &completeSelfServiceBrowserSettingsOIDCFlowPayload{
 		Link: provider.Config().ID, FlowID: ctxUpdate.Flow.ID.String()}
-	if ctxUpdate.Session.AuthenticatedAt.Add(s.d.Configuration(r.Context()).SelfServiceFlowSettingsPrivilegedSessionMaxAge()).Before(time.Now()) {
+	if settings.CredentialsChangeNeedsReAuth(s.d.Configuration(r.Context()), ctxUpdate.Session, identity.CredentialsTypeOIDC) {
 		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(settings.NewFlowNeedsReAuth()))
 		return
 	}
@@ -326,29 +386,11 @@ func (s *Strategy) linkProvider(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	var conf CredentialsConfig
-	creds, err := i.ParseCredentials(s.ID(), &conf)
-	if errors.Is(err, herodot.ErrNotFound) {
-		var err error
-		if creds, err = NewCredentials(provider.Config().ID, claims.Subject); err != nil {
-			s.handleSettingsError(w, r, ctxUpdate, p, err)
-			return
-		}
-	} else if err != nil {
+	if err := s.addProviderCredentials(r.Context(), i, provider.Config().ID, claims.Subject); err != nil {
 		s.handleSettingsError(w, r, ctxUpdate, p, err)
 		return
-	} else {
-		creds.Identifiers = append(creds.Identifiers, uid(provider.Config().ID, claims.Subject))
-		conf.Providers = append(conf.Providers, ProviderCredentialsConfig{
-			Subject: claims.Subject, Provider: provider.Config().ID})
-		creds.Config, err = json.Marshal(conf)
-		if err != nil {
-			s.handleSettingsError(w, r, ctxUpdate, p, err)
-			return
-		}
 	}
 
-	i.Credentials[s.ID()] = *creds
 	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r, s.SettingsStrategyID(), ctxUpdate, i, settings.WithCallback(func(ctxUpdate *settings.UpdateContext) error {
 		return s.PopulateSettingsMethod(r, ctxUpdate.Session.Identity, ctxUpdate.Flow)
 	})); err != nil {
@@ -359,7 +401,7 @@ func (s *Strategy) linkProvider(w http.ResponseWriter, r *http.Request,
 
 func (s *Strategy) unlinkProvider(w http.ResponseWriter, r *http.Request,
 	ctxUpdate *settings.UpdateContext, p *completeSelfServiceBrowserSettingsOIDCFlowPayload) {
-	if ctxUpdate.Session.AuthenticatedAt.Add(s.d.Configuration(r.Context()).SelfServiceFlowSettingsPrivilegedSessionMaxAge()).Before(time.Now()) {
+	if settings.CredentialsChangeNeedsReAuth(s.d.Configuration(r.Context()), ctxUpdate.Session, identity.CredentialsTypeOIDC) {
 		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(settings.NewFlowNeedsReAuth()))
 		return
 	}
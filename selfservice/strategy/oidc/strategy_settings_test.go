@@ -29,6 +29,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -428,6 +429,29 @@ func TestSettingsStrategy(t *testing.T) {
 				"no id_token was returned")
 		})
 
+		t.Run("case=should not be able to link more connections than allowed", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaMaxLinkedOIDCProviders, 2)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyDefaultIdentitySchemaMaxLinkedOIDCProviders, 0)
+			})
+
+			subject = "hackerman+limit+" + testID
+			scope = []string{"openid"}
+
+			agent, provider := "githuber", "google"
+			body, res, _ := link(t, agent, provider)
+			assert.Contains(t, res.Request.URL.String(), uiTS.URL)
+
+			assert.Contains(t, gjson.GetBytes(body, `methods.oidc.config.messages.0.text`).String(),
+				"You can not link more than 2 social sign in providers", "%s", body)
+			assert.EqualValues(t, text.ErrorValidationSettingsLinkedProvidersLimit, gjson.GetBytes(body, `methods.oidc.config.messages.0.id`).Int(), "%s", body)
+			for _, name := range gjson.GetBytes(body, `methods.oidc.config.fields.#.name`).Array() {
+				assert.NotEqual(t, "link", name.String(), "%s", body)
+			}
+
+			checkCredentials(t, false, users[agent].ID, provider, subject)
+		})
+
 		t.Run("case=should link a connection", func(t *testing.T) {
 			t.Cleanup(reset(t))
 
@@ -675,4 +699,28 @@ func TestPopulateSettingsMethod(t *testing.T) {
 			assert.EqualValues(t, tc.e, actual.Fields)
 		})
 	}
+	t.Run("case=should hide link buttons once the limit is reached", func(t *testing.T) {
+		google := identity.Credentials{Type: identity.CredentialsTypeOIDC, Identifiers: []string{"google:1234"},
+			Config: []byte(`{"providers":[{"provider":"google","subject":"1234"}]}`)}
+		csrfOnly := form.Fields{{Name: "csrf_token", Type: "hidden", Required: true, Value: x.FakeCSRFToken}}
+
+		t.Run("source=method", func(t *testing.T) {
+			reg := nreg(t, &oidc.ConfigurationCollection{Providers: defaultConfig, MaxLinkedProviders: 1})
+			i := &identity.Identity{
+				Traits:      []byte(`{"subject":"foo@bar.com"}`),
+				Credentials: map[identity.CredentialsType]identity.Credentials{identity.CredentialsTypeOIDC: google},
+			}
+			assert.EqualValues(t, csrfOnly, populate(t, reg, i, nr()).Fields)
+		})
+
+		t.Run("source=schema", func(t *testing.T) {
+			reg := nreg(t, &oidc.ConfigurationCollection{Providers: defaultConfig, MaxLinkedProviders: 3})
+			reg.Configuration(context.Background()).MustSet(config.ViperKeyDefaultIdentitySchemaMaxLinkedOIDCProviders, 1)
+			i := &identity.Identity{
+				Traits:      []byte(`{"subject":"foo@bar.com"}`),
+				Credentials: map[identity.CredentialsType]identity.Credentials{identity.CredentialsTypeOIDC: google},
+			}
+			assert.EqualValues(t, csrfOnly, populate(t, reg, i, nr()).Fields)
+		})
+	})
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
@@ -339,6 +340,65 @@ func TestStrategy(t *testing.T) {
 		})
 	})
 
+	t.Run("case=register with custom fields", func(t *testing.T) {
+		subject = "custom-fields@ory.sh"
+		scope = []string{"openid"}
+
+		var customFields []json.RawMessage
+		reg.WithHooks(map[string]func(config.SelfServiceHook) interface{}{
+			"test": func(config.SelfServiceHook) interface{} {
+				return registration.PostHookPostPersistExecutorFunc(func(_ http.ResponseWriter, _ *http.Request, a *registration.Flow, _ *session.Session) error {
+					customFields = append(customFields, a.CustomFields)
+					return nil
+				})
+			},
+		})
+		conf.MustSet(config.ViperKeySelfServiceRegistrationCustomFields, []map[string]interface{}{
+			{"name": "accept_terms", "type": "checkbox", "required": true},
+		})
+		conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypeOIDC.String()), []config.SelfServiceHook{
+			{Name: "test"},
+			{Name: "record_consent", Config: json.RawMessage(`{"fields":["accept_terms"]}`)},
+			{Name: "session"},
+		})
+		t.Cleanup(func() {
+			reg.WithHooks(nil)
+			conf.MustSet(config.ViperKeySelfServiceRegistrationCustomFields, nil)
+			conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypeOIDC.String()), []config.SelfServiceHook{{Name: "session"}})
+		})
+
+		t.Run("case=should render the custom fields", func(t *testing.T) {
+			r := newRegistrationFlow(t, returnTS.URL, time.Minute)
+			method, ok := r.Methods[identity.CredentialsTypeOIDC]
+			require.True(t, ok)
+
+			raw, err := json.Marshal(method.Config)
+			require.NoError(t, err)
+			assert.Equal(t, "checkbox", gjson.GetBytes(raw, "fields.#(name==custom_fields.accept_terms).type").String(), "%s", raw)
+		})
+
+		t.Run("case=should fail registration if a required custom field is missing", func(t *testing.T) {
+			r := newRegistrationFlow(t, returnTS.URL, time.Minute)
+			action := afv(t, r.ID, "valid")
+			res, body := makeRequest(t, "valid", action, url.Values{})
+			require.Contains(t, res.Request.URL.String(), uiTS.URL, "%s", body)
+			assert.NotEmpty(t, gjson.GetBytes(body, "methods.oidc.config.fields.#(name==custom_fields.accept_terms).messages.0.text").String(), "%s", body)
+
+			_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypeOIDC, "valid:"+subject)
+			require.Error(t, err)
+		})
+
+		t.Run("case=should pass the custom fields to the hooks", func(t *testing.T) {
+			r := newRegistrationFlow(t, returnTS.URL, time.Minute)
+			action := afv(t, r.ID, "valid")
+			res, body := makeRequest(t, "valid", action, url.Values{"custom_fields.accept_terms": {"true"}})
+			ai(t, res, body)
+
+			require.Len(t, customFields, 1)
+			assert.JSONEq(t, `{"accept_terms":true}`, string(customFields[0]))
+		})
+	})
+
 	t.Run("case=should fail to register if email is already being used by password credentials", func(t *testing.T) {
 		subject = "email-exist-with-password-strategy@ory.sh"
 		scope = []string{"openid"}
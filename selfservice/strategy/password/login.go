@@ -2,11 +2,13 @@ package password
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/ory/x/pkgerx"
 
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/markbates/pkger"
 	"github.com/pkg/errors"
@@ -16,11 +18,14 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -96,9 +101,14 @@ type completeSelfServiceLoginFlowWithPasswordMethodParameters struct {
 //       400: loginFlow
 //       500: genericError
 func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := s.d.StrategyRateLimiter().Allow(r, "login", s.ID().String()); err != nil {
+		s.handleLoginError(w, r, nil, nil, err)
+		return
+	}
+
 	rid := x.ParseUUID(r.URL.Query().Get("flow"))
 	if x.IsZeroUUID(rid) {
-		s.handleLoginError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The flow query parameter is missing or invalid.")))
+		s.handleLoginError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowIDMissing).WithReasonf("The flow query parameter is missing or invalid.")))
 		return
 	}
 
@@ -115,10 +125,11 @@ func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
-	if err := flow.VerifyRequest(r, ar.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+	if err := flow.VerifyRequest(r, ar.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, ar.CSRFToken, p.CSRFToken); err != nil {
 		s.handleLoginError(w, r, ar, &p, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "login", ar.ID, ar.Type).WithStrategy(s.ID().String()))
 
 	if _, err := s.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil && !ar.Forced {
 		if ar.Type == flow.TypeBrowser {
@@ -130,13 +141,20 @@ func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
-	if err := ar.Valid(); err != nil {
+	if err := ar.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
+		s.handleLoginError(w, r, ar, &p, err)
+		return
+	}
+
+	if err := s.d.LoginThrottler().Check(r.Context(), p.Identifier); err != nil {
+		s.d.LoginAttemptLogger().Record(r, login.NewAttemptLog(r, s.ID(), p.Identifier, uuid.Nil, login.AttemptOutcomeFailure, login.AttemptReasonThrottled))
 		s.handleLoginError(w, r, ar, &p, err)
 		return
 	}
 
 	i, c, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), p.Identifier)
 	if err != nil {
+		s.recordLoginFailure(r, p.Identifier, uuid.Nil)
 		s.handleLoginError(w, r, ar, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
 		return
 	}
@@ -144,21 +162,68 @@ func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprou
 	var o CredentialsConfig
 	d := json.NewDecoder(bytes.NewBuffer(c.Config))
 	if err := d.Decode(&o); err != nil {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error()))
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errorx.WithStrategy(herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error()), s.ID().String()))
 		return
 	}
 
 	if err := s.d.Hasher().Compare(r.Context(), []byte(p.Password), []byte(o.HashedPassword)); err != nil {
+		s.recordLoginFailure(r, p.Identifier, i.ID)
 		s.handleLoginError(w, r, ar, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
 		return
 	}
 
+	if err := s.d.LoginThrottler().Reset(r.Context(), p.Identifier); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to reset the failed login attempts.")
+	}
+
+	if s.d.Hasher().NeedsRehash(r.Context(), []byte(o.HashedPassword)) {
+		if err := s.rehash(r.Context(), i.ID, p.Password); err != nil {
+			s.d.Logger().WithError(err).Warn("Unable to upgrade the password hash to the configured hashing parameters.")
+		}
+	}
+
 	if err := s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypePassword, ar, i); err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
 }
 
+// recordLoginFailure counts the failed attempt for login throttling and adds it to the login attempt log.
+// Failing to do so must not reveal anything about the identifier, so errors are only logged.
+func (s *Strategy) recordLoginFailure(r *http.Request, identifier string, identityID uuid.UUID) {
+	s.d.LoginAttemptLogger().Record(r, login.NewAttemptLog(r, s.ID(), identifier, identityID, login.AttemptOutcomeFailure, login.AttemptReasonInvalidCredentials))
+	if err := s.d.LoginThrottler().RecordFailure(r.Context(), identifier); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to record the failed login attempt.")
+	}
+}
+
+// rehash replaces the identity's password hash with one using the configured hashing parameters.
+func (s *Strategy) rehash(ctx context.Context, id uuid.UUID, password string) error {
+	hpw, err := s.d.Hasher().Generate(ctx, []byte(password))
+	if err != nil {
+		return err
+	}
+
+	co, err := json.Marshal(&CredentialsConfig{HashedPassword: string(hpw)})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	c, ok := i.GetCredentials(s.ID())
+	if !ok {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The identity has no %s credentials.", s.ID()))
+	}
+
+	c.Config = co
+	i.SetCredentials(s.ID(), *c)
+	return s.d.PrivilegedIdentityPool().UpdateIdentity(ctx, i)
+}
+
 func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
 	// This block adds the identifier to the method when the request is forced - as a hint for the user.
 	var identifier string
@@ -190,6 +255,7 @@ func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
 			Required: true,
 		}}}
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
+	f.GroupFields(form.FieldGroupPassword)
 
 	sr.Methods[identity.CredentialsTypePassword] = &login.FlowMethod{
 		Method: identity.CredentialsTypePassword,
@@ -403,9 +403,39 @@ func TestCompleteLogin(t *testing.T) {
 					body, err := ioutil.ReadAll(res.Body)
 					require.NoError(t, err)
 					assert.True(t, gjson.GetBytes(body, "forced").Bool())
+					assert.EqualValues(t, text.InfoSelfServiceLoginReAuth, gjson.GetBytes(body, "messages.0.id").Int(), "%s", body)
 					assert.Equal(t, identifier, gjson.GetBytes(body, "methods.password.config.fields.#(name==identifier).value").String(), "%s", body)
 					assert.Empty(t, gjson.GetBytes(body, "methods.password.config.fields.#(name==password).value").String(), "%s", body)
 				})
+
+				t.Run("reject re-authenticating as another identity", func(t *testing.T) {
+					otherIdentifier := x.NewUUID().String()
+					createIdentity(otherIdentifier, pwd)
+
+					body := testhelpers.SubmitLoginForm(t, false, browserClient, publicTS, func(v url.Values) {
+						v.Set("identifier", otherIdentifier)
+						v.Set("password", pwd)
+					}, identity.CredentialsTypePassword, true, http.StatusOK, errTS.URL)
+					assert.Contains(t, body, string(text.ErrorCodeSecurityIdentityMismatch), "%s", body)
+
+					res, err := browserClient.Get(redirTS.URL)
+					require.NoError(t, err)
+					defer res.Body.Close()
+					assert.Equal(t, identifier, gjson.GetBytes(ioutilx.MustReadAll(res.Body), "identity.traits.subject").String())
+				})
+
+				t.Run("refresh the session when re-authenticating as the same identity", func(t *testing.T) {
+					res, err := browserClient.Get(redirTS.URL)
+					require.NoError(t, err)
+					defer res.Body.Close()
+					before := gjson.GetBytes(ioutilx.MustReadAll(res.Body), "authenticated_at").Time()
+
+					time.Sleep(time.Second)
+					body := testhelpers.SubmitLoginForm(t, false, browserClient, publicTS, values,
+						identity.CredentialsTypePassword, true, http.StatusOK, redirTS.URL)
+					assert.Equal(t, identifier, gjson.Get(body, "identity.traits.subject").String(), "%s", body)
+					assert.True(t, gjson.Get(body, "authenticated_at").Time().After(before), "%s", body)
+				})
 			})
 		})
 
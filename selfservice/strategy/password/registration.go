@@ -3,6 +3,7 @@ package password
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/ory/x/pkgerx"
@@ -23,10 +24,12 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -35,9 +38,10 @@ const (
 )
 
 type RegistrationFormPayload struct {
-	Password  string          `json:"password"`
-	Traits    json.RawMessage `json:"traits"`
-	CSRFToken string          `json:"csrf_token"`
+	Password     string          `json:"password"`
+	Traits       json.RawMessage `json:"traits"`
+	CustomFields json.RawMessage `json:"custom_fields"`
+	CSRFToken    string          `json:"csrf_token"`
 }
 
 func (s *Strategy) RegisterRegistrationRoutes(public *x.RouterPublic) {
@@ -63,6 +67,9 @@ func (s *Strategy) handleRegistrationError(w http.ResponseWriter, r *http.Reques
 					// we only set the value and not the whole field because we want to keep types from the initial form generation
 					method.Config.SetValue(field.Name, field.Value)
 				}
+				for _, field := range form.NewHTMLFormFromJSON("", p.CustomFields, flow.CustomFieldsKey).Fields {
+					method.Config.SetValue(field.Name, field.Value)
+				}
 			}
 
 			method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
@@ -71,6 +78,7 @@ func (s *Strategy) handleRegistrationError(w http.ResponseWriter, r *http.Reques
 				s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, identity.CredentialsTypePassword, rr, errors.Wrap(err, errSec.Error()))
 				return
 			}
+			method.Config.GroupFields(form.FieldGroupPassword)
 		}
 	}
 
@@ -84,6 +92,17 @@ func (s *Strategy) decode(p *RegistrationFormPayload, r *http.Request) error {
 		return errors.WithStack(err)
 	}
 
+	// The custom fields' schema is required to decode their values with the correct types.
+	customFields, err := flow.CustomFieldsSchema(s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields())
+	if err != nil {
+		return err
+	}
+
+	raw, err = sjson.SetRawBytes(raw, "properties."+flow.CustomFieldsKey, customFields)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
 	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(raw)
 	if err != nil {
 		return errors.WithStack(err)
@@ -138,9 +157,14 @@ type completeSelfServiceRegistrationFlowWithPasswordMethodParameters struct {
 //       400: registrationFlow
 //       500: genericError
 func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := s.d.StrategyRateLimiter().Allow(r, "registration", s.ID().String()); err != nil {
+		s.handleRegistrationError(w, r, nil, nil, err)
+		return
+	}
+
 	rid := x.ParseUUID(r.URL.Query().Get("flow"))
 	if x.IsZeroUUID(rid) {
-		s.handleRegistrationError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The flow query parameter is missing.")))
+		s.handleRegistrationError(w, r, nil, nil, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeFlowIDMissing).WithReasonf("The flow query parameter is missing.")))
 		return
 	}
 
@@ -150,7 +174,7 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	if err := ar.Valid(); err != nil {
+	if err := ar.Valid(s.d.Configuration(r.Context()).SelfServiceClockSkewTolerance()); err != nil {
 		s.handleRegistrationError(w, r, ar, nil, err)
 		return
 	}
@@ -161,10 +185,11 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	if err := flow.VerifyRequest(r, ar.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+	if err := flow.VerifyRequest(r, ar.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, ar.CSRFToken, p.CSRFToken); err != nil {
 		s.handleRegistrationError(w, r, ar, &p, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "registration", ar.ID, ar.Type).WithStrategy(s.ID().String()))
 
 	if len(p.Password) == 0 {
 		s.handleRegistrationError(w, r, ar, &p, schema.NewRequiredError("#/password", "password"))
@@ -175,6 +200,12 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		p.Traits = json.RawMessage("{}")
 	}
 
+	if err := flow.ValidateCustomFields(s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields(), p.CustomFields); err != nil {
+		s.handleRegistrationError(w, r, ar, &p, err)
+		return
+	}
+	ar.CustomFields = p.CustomFields
+
 	hpw, err := s.d.Hasher().Generate(r.Context(), []byte(p.Password))
 	if err != nil {
 		s.handleRegistrationError(w, r, ar, &p, err)
@@ -191,6 +222,16 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 	i.Traits = identity.Traits(p.Traits)
 	i.SetCredentials(s.ID(), identity.Credentials{Type: s.ID(), Identifiers: []string{}, Config: co})
 
+	if err := s.d.RegistrationExecutor().ApplyDefaults(r, s.ID(), i); err != nil {
+		s.handleRegistrationError(w, r, ar, &p, err)
+		return
+	}
+
+	if err := s.d.IdentityTraitsTransformer().Transform(r.Context(), i); err != nil {
+		s.handleRegistrationError(w, r, ar, &p, err)
+		return
+	}
+
 	if err := s.validateCredentials(r.Context(), i, p.Password); err != nil {
 		s.handleRegistrationError(w, r, ar, &p, err)
 		return
@@ -217,16 +258,43 @@ func (s *Strategy) validateCredentials(ctx context.Context, i *identity.Identity
 
 	for _, id := range c.Identifiers {
 		if err := s.d.PasswordValidator().Validate(ctx, id, pw); err != nil {
+			if errors.Is(err, ErrPasswordNotAllowed) {
+				return s.withPasswordStrength(ctx, schema.NewPasswordNotAllowedError("#/password"), pw, c.Identifiers)
+			}
 			if _, ok := errorsx.Cause(err).(*herodot.DefaultError); ok {
 				return err
 			}
-			return schema.NewPasswordPolicyViolationError("#/password", err.Error())
+			return s.withPasswordStrength(ctx, schema.NewPasswordPolicyViolationError("#/password", err.Error()), pw, c.Identifiers)
 		}
 	}
 
+	conf := s.d.Configuration(ctx).PasswordPolicyConfig()
+	if !conf.StrengthEnabled {
+		return nil
+	}
+
+	if estimate := EstimateStrength(pw, c.Identifiers...); estimate.Score < conf.StrengthMinScore {
+		return s.withPasswordStrength(ctx, schema.NewPasswordPolicyViolationError("#/password",
+			fmt.Sprintf("the password is too weak, its strength is %d but must be at least %d", estimate.Score, conf.StrengthMinScore)), pw, c.Identifiers)
+	}
+
 	return nil
 }
 
+// withPasswordStrength adds the estimated password strength and suggestions for a stronger password to the
+// validation error if `password.strength.enabled` is set.
+func (s *Strategy) withPasswordStrength(ctx context.Context, err error, pw string, identifiers []string) error {
+	if !s.d.Configuration(ctx).PasswordPolicyConfig().StrengthEnabled {
+		return err
+	}
+
+	if e := new(schema.ValidationError); errors.As(err, &e) {
+		estimate := EstimateStrength(pw, identifiers...)
+		e.Messages.Add(text.NewInfoValidationPasswordStrength(estimate.Score, estimate.Suggestions))
+	}
+	return err
+}
+
 func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.Flow) error {
 	action := sr.AppendTo(urlx.AppendPaths(s.d.Configuration(r.Context()).SelfPublicURL(), RouteRegistration))
 
@@ -238,10 +306,14 @@ func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.
 	htmlf.Method = "POST"
 	htmlf.SetCSRF(s.d.GenerateCSRFToken(r))
 	htmlf.SetField(form.Field{Name: "password", Type: "password", Required: true})
+	for _, field := range flow.CustomFormFields(s.d.Configuration(r.Context()).SelfServiceFlowRegistrationCustomFields()) {
+		htmlf.SetField(field)
+	}
 
 	if err := htmlf.SortFields(s.d.Configuration(r.Context()).DefaultIdentityTraitsSchemaURL().String()); err != nil {
 		return err
 	}
+	htmlf.GroupFields(form.FieldGroupPassword)
 
 	sr.Methods[identity.CredentialsTypePassword] = &registration.FlowMethod{
 		Method: identity.CredentialsTypePassword,
@@ -2,6 +2,7 @@ package password_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -32,6 +33,8 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/selfservice/strategy/password"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -218,6 +221,120 @@ func TestRegistration(t *testing.T) {
 			})
 		})
 
+		t.Run("case=should return the password strength if the password failed validation", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyPasswordStrengthEnabled, true)
+			conf.MustSet(config.ViperKeyPasswordStrengthMinScore, 4)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyPasswordStrengthEnabled, false)
+				conf.MustSet(config.ViperKeyPasswordStrengthMinScore, 0)
+			})
+
+			var check = func(t *testing.T, actual string) {
+				messages := gjson.Get(actual, "methods.password.config.fields.#(name==password).messages")
+				assert.Contains(t, messages.Get("#(type==error).text").String(), "the password is too weak", "%s", actual)
+				assert.EqualValues(t, text.InfoValidationPasswordStrength, messages.Get("#(type==info).id").Int(), "%s", actual)
+				assert.EqualValues(t, 3, messages.Get("#(type==info).context.score").Int(), "%s", actual)
+				assert.NotEmpty(t, messages.Get("#(type==info).context.suggestions").Array(), "%s", actual)
+			}
+
+			var values = func(v url.Values) {
+				v.Set("traits.username", "registration-identifier-strength")
+				v.Set("password", "Kr4tosStr3ngth")
+				v.Set("traits.foobar", "bar")
+			}
+
+			t.Run("type=api", func(t *testing.T) {
+				check(t, expectValidationError(t, true, values))
+			})
+
+			t.Run("type=browser", func(t *testing.T) {
+				check(t, expectValidationError(t, false, values))
+			})
+		})
+
+		t.Run("case=custom fields", func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceRegistrationCustomFields, []map[string]interface{}{
+				{"name": "company_name", "type": "text", "max_length": 5},
+				{"name": "accept_terms", "type": "checkbox", "required": true},
+			})
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceRegistrationCustomFields, nil)
+			})
+
+			t.Run("case=should return an error because a custom field failed validation", func(t *testing.T) {
+				var check = func(t *testing.T, actual string) {
+					checkFormContent(t, []byte(actual), "password", "csrf_token", "traits.username", "traits.foobar", "custom_fields.company_name", "custom_fields.accept_terms")
+					assert.Equal(t, "ORY Corp", gjson.Get(actual, "methods.password.config.fields.#(name==custom_fields.company_name).value").String(), "%s", actual)
+					assert.NotEmpty(t, gjson.Get(actual, "methods.password.config.fields.#(name==custom_fields.company_name).messages.0.text").String(), "%s", actual)
+					assert.NotEmpty(t, gjson.Get(actual, "methods.password.config.fields.#(name==custom_fields.accept_terms).messages.0.text").String(), "%s", actual)
+				}
+
+				var values = func(v url.Values) {
+					v.Set("traits.username", "registration-identifier-custom-fields")
+					v.Set("password", x.NewUUID().String())
+					v.Set("traits.foobar", "bar")
+					v.Set("custom_fields.company_name", "ORY Corp")
+				}
+
+				t.Run("type=api", func(t *testing.T) {
+					check(t, expectValidationError(t, true, values))
+				})
+
+				t.Run("type=browser", func(t *testing.T) {
+					check(t, expectValidationError(t, false, values))
+				})
+			})
+
+			t.Run("case=should pass the custom fields to hooks but not store them as traits", func(t *testing.T) {
+				var customFields []json.RawMessage
+				reg.WithHooks(map[string]func(config.SelfServiceHook) interface{}{
+					"test": func(config.SelfServiceHook) interface{} {
+						return registration.PostHookPostPersistExecutorFunc(func(_ http.ResponseWriter, _ *http.Request, a *registration.Flow, _ *session.Session) error {
+							customFields = append(customFields, a.CustomFields)
+							return nil
+						})
+					},
+				})
+				conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{
+					{Name: "test"},
+					{Name: "record_consent", Config: json.RawMessage(`{"fields":["accept_terms"]}`)},
+					{Name: "session"},
+				})
+				t.Cleanup(func() {
+					reg.WithHooks(nil)
+					conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), nil)
+				})
+				require.NoError(t, registration.ValidateCustomFields(context.Background(), reg))
+
+				var values = func(isAPI bool) func(v url.Values) {
+					return func(v url.Values) {
+						v.Set("traits.username", "registration-identifier-custom-fields-browser")
+						if isAPI {
+							v.Set("traits.username", "registration-identifier-custom-fields-api")
+						}
+						v.Set("password", x.NewUUID().String())
+						v.Set("traits.foobar", "bar")
+						v.Set("custom_fields.company_name", "ORY")
+						v.Set("custom_fields.accept_terms", "true")
+					}
+				}
+
+				for _, isAPI := range []bool{true, false} {
+					t.Run(fmt.Sprintf("api=%v", isAPI), func(t *testing.T) {
+						customFields = nil
+
+						actual := expectSuccessfulLogin(t, isAPI, nil, values(isAPI))
+						assert.NotEmpty(t, gjson.Get(actual, "identity.id").String(), "%s", actual)
+						assert.False(t, gjson.Get(actual, "identity.traits.company_name").Exists(), "%s", actual)
+
+						require.Len(t, customFields, 1)
+						assert.Equal(t, "ORY", gjson.GetBytes(customFields[0], "company_name").String())
+						assert.True(t, gjson.GetBytes(customFields[0], "accept_terms").Bool())
+					})
+				}
+			})
+		})
+
 		t.Run("case=should return an error because not passing validation", func(t *testing.T) {
 			var check = func(t *testing.T, actual string) {
 				assert.NotEmpty(t, gjson.Get(actual, "id").String(), "%s", actual)
@@ -431,6 +548,39 @@ func TestRegistration(t *testing.T) {
 			})
 		})
 
+		t.Run("case=should transform the traits before validating them", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+			conf.MustSet(config.ViperKeyIdentityTraitsTransform, map[string]interface{}{
+				"rules": []map[string]interface{}{{"path": "username", "transforms": []string{"trim", "lowercase"}}},
+			})
+			conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{{Name: "session"}})
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyIdentityTraitsTransform, nil)
+				conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), nil)
+			})
+
+			var values = func(isAPI bool) func(v url.Values) {
+				return func(v url.Values) {
+					v.Set("traits.username", " Registration-Identifier-Transform-Browser ")
+					if isAPI {
+						v.Set("traits.username", " Registration-Identifier-Transform-API ")
+					}
+					v.Set("password", x.NewUUID().String())
+					v.Set("traits.foobar", "bar")
+				}
+			}
+
+			t.Run("type=api", func(t *testing.T) {
+				body := expectSuccessfulLogin(t, true, nil, values(true))
+				assert.Equal(t, `registration-identifier-transform-api`, gjson.Get(body, "identity.traits.username").String(), "%s", body)
+			})
+
+			t.Run("type=browser", func(t *testing.T) {
+				body := expectSuccessfulLogin(t, false, nil, values(false))
+				assert.Equal(t, `registration-identifier-transform-browser`, gjson.Get(body, "identity.traits.username").String(), "%s", body)
+			})
+		})
+
 		t.Run("case=should fail to register the same user again", func(t *testing.T) {
 			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
 			conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{{Name: "session"}})
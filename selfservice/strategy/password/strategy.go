@@ -2,7 +2,6 @@ package password
 
 import (
 	"encoding/json"
-	"strings"
 
 	"github.com/pkg/errors"
 	"gopkg.in/go-playground/validator.v9"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
@@ -26,8 +26,11 @@ var _ registration.Strategy = new(Strategy)
 var _ identity.ActiveCredentialsCounter = new(Strategy)
 
 type registrationStrategyDependencies interface {
+	event.EmitterProvider
+
 	x.LoggingProvider
 	x.WriterProvider
+	x.StrategyRateLimiterProvider
 	x.CSRFTokenGeneratorProvider
 	x.CSRFProvider
 
@@ -50,6 +53,8 @@ type registrationStrategyDependencies interface {
 	login.HookExecutorProvider
 	login.FlowPersistenceProvider
 	login.HandlerProvider
+	login.ThrottlerProvider
+	login.AttemptLoggerProvider
 
 	settings.FlowPersistenceProvider
 	settings.HookExecutorProvider
@@ -58,6 +63,7 @@ type registrationStrategyDependencies interface {
 
 	identity.PrivilegedPoolProvider
 	identity.ValidationProvider
+	identity.TraitsTransformerProvider
 
 	session.HandlerProvider
 	session.ManagementProvider
@@ -78,7 +84,7 @@ func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identi
 			}
 
 			if len(c.Identifiers) > 0 && len(c.Identifiers[0]) > 0 &&
-				strings.HasPrefix(conf.HashedPassword, "$argon2id$") {
+				hash.IsSupported([]byte(conf.HashedPassword)) {
 				count++
 			}
 		}
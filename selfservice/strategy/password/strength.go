@@ -0,0 +1,121 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// StrengthEstimate is the estimated strength of a password.
+type StrengthEstimate struct {
+	// Score ranges from 0 (very weak) to 4 (very strong).
+	Score int
+
+	// Suggestions contains guidance on how to choose a stronger password.
+	Suggestions []string
+}
+
+// strengthScoreBits are the lower bounds of the estimated entropy in bits for the scores 1 to 4.
+var strengthScoreBits = []float64{28, 36, 60, 80}
+
+const strengthRecommendedLength = 12
+
+// EstimateStrength estimates the strength of a password in the spirit of zxcvbn, without the need
+// for large dictionaries: The entropy is estimated from the character classes used, while repeated
+// characters, sequences such as "abc" or "321", and parts of userInputs (e.g. the identifier) do not
+// add to the password's strength.
+func EstimateStrength(password string, userInputs ...string) *StrengthEstimate {
+	runes := []rune(password)
+	compPassword := strings.ToLower(password)
+
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther, hasRepeat, hasSequence bool
+	var effective float64
+	for k, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+
+		if k > 0 {
+			switch d := unicode.ToLower(r) - unicode.ToLower(runes[k-1]); {
+			case d == 0:
+				hasRepeat = true
+				continue
+			case d == 1 || d == -1:
+				hasSequence = true
+				continue
+			}
+		}
+		effective++
+	}
+
+	var containsUserInput bool
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len(input) == 0 {
+			continue
+		}
+
+		// Only the local part of email addresses is likely to be used in passwords.
+		if at := strings.LastIndex(input, "@"); at > 0 {
+			input = input[:at]
+		}
+
+		if len(input) >= 3 && strings.Contains(compPassword, input) {
+			containsUserInput = true
+			effective = math.Max(0, effective-float64(len([]rune(input))))
+		}
+	}
+
+	var pool float64
+	for _, class := range []struct {
+		used bool
+		size float64
+	}{{hasLower, 26}, {hasUpper, 26}, {hasDigit, 10}, {hasSymbol, 33}, {hasOther, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+
+	var bits float64
+	if pool > 0 {
+		bits = effective * math.Log2(pool)
+	}
+
+	estimate := &StrengthEstimate{Suggestions: []string{}}
+	for _, min := range strengthScoreBits {
+		if bits >= min {
+			estimate.Score++
+		}
+	}
+
+	if estimate.Score == len(strengthScoreBits) {
+		return estimate
+	}
+
+	if len(runes) < strengthRecommendedLength {
+		estimate.Suggestions = append(estimate.Suggestions, "Use at least 12 characters, for example by adding more words.")
+	}
+	if !hasUpper || !(hasDigit || hasSymbol) {
+		estimate.Suggestions = append(estimate.Suggestions, "Mix uppercase and lowercase letters, numbers, and symbols.")
+	}
+	if hasRepeat {
+		estimate.Suggestions = append(estimate.Suggestions, `Avoid repeated characters such as "aaa".`)
+	}
+	if hasSequence {
+		estimate.Suggestions = append(estimate.Suggestions, `Avoid sequences such as "abc" or "123".`)
+	}
+	if containsUserInput {
+		estimate.Suggestions = append(estimate.Suggestions, "Avoid using your email address or username in the password.")
+	}
+
+	return estimate
+}
@@ -0,0 +1,35 @@
+package password_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/selfservice/strategy/password"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	for k, tc := range []struct {
+		password    string
+		userInputs  []string
+		score       int
+		suggestions int
+	}{
+		{password: "", score: 0, suggestions: 2},
+		{password: "abc123", score: 0, suggestions: 3},
+		{password: "aaaaaaaaaaaaaaaa", score: 0, suggestions: 2},
+		{password: "jzmqfhwk", score: 2, suggestions: 2},
+		{password: "Kr4tosStr3ngth", score: 3, suggestions: 2},
+		{password: "correct horse battery staple", score: 4},
+		{password: "x7!Tq#9vLp@2wZ", score: 4},
+		{password: "Foo.bar1988X", userInputs: []string{"foo.bar1988@ory.sh"}, score: 0, suggestions: 3},
+		{password: "Foo.bar1988X", userInputs: []string{"someone@ory.sh"}, score: 2, suggestions: 2},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			actual := password.EstimateStrength(tc.password, tc.userInputs...)
+			assert.Equal(t, tc.score, actual.Score)
+			assert.Len(t, actual.Suggestions, tc.suggestions, "%v", actual.Suggestions)
+		})
+	}
+}
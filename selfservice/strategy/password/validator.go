@@ -8,7 +8,9 @@ import (
 
 	/* #nosec G505 sha1 is used for k-anonymity */
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -16,12 +18,12 @@ import (
 
 	"github.com/arbovm/levenshtein"
 
-	"github.com/ory/x/httpx"
-
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
 	"github.com/ory/x/stringsx"
+
+	"github.com/ory/kratos/x"
 )
 
 // Validator implements a validation strategy for passwords. One example is that the password
@@ -37,9 +39,17 @@ type ValidationProvider interface {
 	PasswordValidator() Validator
 }
 
+// BlocklistLoader is implemented by validators which reject passwords found in a configured blocklist.
+type BlocklistLoader interface {
+	// LoadBlocklist loads the blocklist. It is safe to call this method multiple times, the blocklist is only loaded once.
+	LoadBlocklist(ctx context.Context) error
+}
+
 var _ Validator = new(DefaultPasswordValidator)
+var _ BlocklistLoader = new(DefaultPasswordValidator)
 var ErrNetworkFailure = errors.New("unable to check if password has been leaked because an unexpected network error occurred")
 var ErrUnexpectedStatusCode = errors.New("unexpected status code")
+var ErrPasswordNotAllowed = errors.New("the password is not allowed")
 
 // DefaultPasswordValidator implements Validator. It is based on best
 // practices as defined in the following blog posts:
@@ -56,17 +66,22 @@ type DefaultPasswordValidator struct {
 	Client *http.Client
 	hashes map[string]int64
 
+	blocklistOnce sync.Once
+	blocklistErr  error
+	blocklist     map[[sha256.Size]byte]struct{}
+
 	minIdentifierPasswordDist            int
 	maxIdentifierPasswordSubstrThreshold float32
 }
 
 type validatorDependencies interface {
 	config.Providers
+	x.HTTPClientProvider
 }
 
 func NewDefaultPasswordValidatorStrategy(reg validatorDependencies) *DefaultPasswordValidator {
 	return &DefaultPasswordValidator{
-		Client:                    httpx.NewResilientClientLatencyToleranceMedium(nil),
+		Client:                    reg.HTTPClient(),
 		reg:                       reg,
 		hashes:                    map[string]int64{},
 		minIdentifierPasswordDist: 5, maxIdentifierPasswordSubstrThreshold: 0.5}
@@ -141,11 +156,53 @@ func (s *DefaultPasswordValidator) fetch(hpw []byte) error {
 	return nil
 }
 
+func blocklistKey(password string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.ToLower(password)))
+}
+
+// LoadBlocklist loads the passwords configured in `password.blocklist.path` and `password.blocklist.passwords`
+// into a set of hashes. The blocklist is only loaded once, subsequent calls return the result of the first call.
+func (s *DefaultPasswordValidator) LoadBlocklist(ctx context.Context) error {
+	s.blocklistOnce.Do(func() {
+		conf := s.reg.Configuration(ctx).PasswordPolicyConfig()
+		blocklist := make(map[[sha256.Size]byte]struct{}, len(conf.BlocklistPasswords))
+		for _, pw := range conf.BlocklistPasswords {
+			blocklist[blocklistKey(pw)] = struct{}{}
+		}
+
+		if len(conf.BlocklistPath) > 0 {
+			contents, err := ioutil.ReadFile(conf.BlocklistPath)
+			if err != nil {
+				s.blocklistErr = errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to read password blocklist from %s: %s", conf.BlocklistPath, err))
+				return
+			}
+
+			for _, pw := range strings.Split(string(contents), "\n") {
+				if pw = strings.TrimSpace(pw); len(pw) > 0 {
+					blocklist[blocklistKey(pw)] = struct{}{}
+				}
+			}
+		}
+
+		s.blocklist = blocklist
+	})
+
+	return s.blocklistErr
+}
+
 func (s *DefaultPasswordValidator) Validate(ctx context.Context, identifier, password string) error {
 	if len(password) < 6 {
 		return errors.Errorf("password length must be at least 6 characters but only got %d", len(password))
 	}
 
+	if err := s.LoadBlocklist(ctx); err != nil {
+		return err
+	}
+
+	if _, ok := s.blocklist[blocklistKey(password)]; ok {
+		return errors.WithStack(ErrPasswordNotAllowed)
+	}
+
 	compIdentifier, compPassword := strings.ToLower(identifier), strings.ToLower(password)
 	dist := levenshtein.Distance(compIdentifier, compPassword)
 	lcs := float32(lcsLength(compIdentifier, compPassword)) / float32(len(compPassword))
@@ -159,6 +159,54 @@ func TestDefaultPasswordValidationStrategy(t *testing.T) {
 	}
 }
 
+func TestPasswordBlocklist(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyIgnoreNetworkErrors, true)
+
+	file, err := ioutil.TempFile(t.TempDir(), "blocklist-*.txt")
+	require.NoError(t, err)
+	_, err = file.WriteString("Hunter2Hunter2\n\n  acme-corporation  \n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	conf.MustSet(config.ViperKeyPasswordBlocklistPath, file.Name())
+	conf.MustSet(config.ViperKeyPasswordBlocklistPasswords, []string{"Summer-Of-2021"})
+
+	s := password.NewDefaultPasswordValidatorStrategy(reg)
+	fakeClient := NewFakeHTTPClient()
+	fakeClient.RespondWithError("Network request failed")
+	s.Client = &fakeClient.Client
+
+	require.NoError(t, s.LoadBlocklist(context.Background()))
+
+	for _, pw := range []string{"hunter2hunter2", "HUNTER2HUNTER2", "Acme-Corporation", "summer-of-2021"} {
+		t.Run("case=rejects "+pw, func(t *testing.T) {
+			err := s.Validate(context.Background(), "", pw)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, password.ErrPasswordNotAllowed), "%+v", err)
+		})
+	}
+
+	t.Run("case=does not check blocked passwords against the remote", func(t *testing.T) {
+		fakeClient.Reset()
+		require.Error(t, s.Validate(context.Background(), "", "hunter2hunter2"))
+		require.Empty(t, fakeClient.RequestedURLs())
+	})
+
+	t.Run("case=accepts passwords not in the blocklist", func(t *testing.T) {
+		require.NoError(t, s.Validate(context.Background(), "", "l3f9toh1uaf81n21"))
+	})
+
+	t.Run("case=fails if the blocklist file does not exist", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyPasswordBlocklistPath, file.Name()+".missing")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyPasswordBlocklistPath, "")
+		})
+
+		require.Error(t, password.NewDefaultPasswordValidatorStrategy(reg).LoadBlocklist(context.Background()))
+	})
+}
+
 type fakeHttpClient struct {
 	http.Client
 
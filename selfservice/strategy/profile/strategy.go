@@ -21,6 +21,7 @@ import (
 
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/event"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
@@ -28,6 +29,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -39,10 +41,13 @@ var _ settings.Strategy = new(Strategy)
 
 type (
 	strategyDependencies interface {
+		event.EmitterProvider
+
 		x.CSRFProvider
 		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.LoggingProvider
+		x.StrategyRateLimiterProvider
 
 		config.Providers
 
@@ -52,6 +57,7 @@ type (
 		session.ManagementProvider
 
 		identity.ValidationProvider
+		identity.TraitsTransformerProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
 
@@ -114,6 +120,7 @@ func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity
 	if err := f.SortFields(traitsSchema.URL); err != nil {
 		return err
 	}
+	f.GroupFields(form.FieldGroupProfile)
 
 	pr.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
 		Method: s.SettingsStrategyID(),
@@ -187,6 +194,11 @@ func (s *Strategy) handleSubmit(w http.ResponseWriter, r *http.Request, ps httpr
 		return
 	}
 
+	if err := s.d.StrategyRateLimiter().Allow(r, "settings", s.SettingsStrategyID()); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, nil, &p, err)
+		return
+	}
+
 	option, err := s.newSettingsProfileDecoder(r.Context(), ctxUpdate.Session.Identity)
 	if err != nil {
 		s.handleSettingsError(w, r, ctxUpdate, nil, &p, err)
@@ -208,13 +220,14 @@ func (s *Strategy) handleSubmit(w http.ResponseWriter, r *http.Request, ps httpr
 }
 
 func (s *Strategy) continueFlow(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceBrowserSettingsProfileStrategyFlow) {
-	if err := flow.VerifyRequest(r, ctxUpdate.Flow.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+	if err := flow.VerifyRequest(r, ctxUpdate.Flow.Type, s.d.Configuration(r.Context()).DisableAPIFlowEnforcement(), s.d.Configuration(r.Context()).SelfServiceCSRFPerFlow(), s.d.GenerateCSRFToken, ctxUpdate.Flow.CSRFToken, p.CSRFToken); err != nil {
 		s.handleSettingsError(w, r, ctxUpdate, nil, p, err)
 		return
 	}
+	s.d.EventEmitter().Emit(r.Context(), event.NewFlowEvent(event.FlowSubmitted, "settings", ctxUpdate.Flow.ID, ctxUpdate.Flow.Type).WithStrategy(s.SettingsStrategyID()).WithIdentity(ctxUpdate.Session.IdentityID))
 
 	if len(p.Traits) == 0 {
-		s.handleSettingsError(w, r, ctxUpdate, nil, p, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Did not receive any value changes.")))
+		s.handleSettingsError(w, r, ctxUpdate, nil, p, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeNoValueChanges).WithReasonf("Did not receive any value changes.")))
 		return
 	}
 
@@ -230,6 +243,11 @@ func (s *Strategy) continueFlow(w http.ResponseWriter, r *http.Request, ctxUpdat
 	}
 
 	update.Traits = identity.Traits(p.Traits)
+	if err := s.d.IdentityTraitsTransformer().Transform(r.Context(), update); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p.Traits, p, err)
+		return
+	}
+
 	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r,
 		settings.StrategyProfile, ctxUpdate, update); err != nil {
 		s.handleSettingsError(w, r, ctxUpdate, p.Traits, p, err)
@@ -298,6 +316,7 @@ func (s *Strategy) hydrateForm(r *http.Request, ar *settings.Flow, ss *session.S
 	if err = ar.Methods[settings.StrategyProfile].Config.SortFields(traitsSchema.URL); err != nil {
 		return err
 	}
+	ar.Methods[settings.StrategyProfile].Config.GroupFields(form.FieldGroupProfile)
 
 	return nil
 }
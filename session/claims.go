@@ -0,0 +1,126 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	claimsMapperDependencies interface {
+		config.Providers
+		x.LoggingProvider
+		x.HTTPClientProvider
+	}
+	ClaimsMapperProvider interface {
+		SessionClaimsMapper() *ClaimsMapper
+	}
+
+	// ClaimsMapper computes custom session claims using the Jsonnet mapper located at
+	// `session.claims.mapper_url`.
+	ClaimsMapper struct {
+		r claimsMapperDependencies
+		f *fetcher.Fetcher
+
+		l       sync.Mutex
+		url     string
+		snippet string
+	}
+)
+
+func NewClaimsMapper(r claimsMapperDependencies) *ClaimsMapper {
+	return &ClaimsMapper{r: r, f: fetcher.NewFetcher(fetcher.WithClient(r.HTTPClient()))}
+}
+
+// Validate fetches the Jsonnet mapper and makes sure that it can be parsed. It is a no-op if no
+// mapper is configured.
+func (m *ClaimsMapper) Validate(ctx context.Context) error {
+	location := m.r.Configuration(ctx).SessionClaimsMapperURL()
+	if location == "" {
+		return nil
+	}
+
+	snippet, err := m.load(location)
+	if err != nil {
+		return err
+	}
+
+	if _, err := jsonnet.SnippetToAST(location, snippet); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the session claims mapper: %s", err))
+	}
+
+	return nil
+}
+
+func (m *ClaimsMapper) load(location string) (string, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.url == location {
+		return m.snippet, nil
+	}
+
+	jn, err := m.f.Fetch(location)
+	if err != nil {
+		return "", err
+	}
+
+	m.url = location
+	m.snippet = jn.String()
+	return m.snippet, nil
+}
+
+// Map evaluates the Jsonnet mapper with the session as the external variable `session`. The mapper is expected
+// to return an object with the key `claims`. If no mapper is configured, nil is returned.
+func (m *ClaimsMapper) Map(ctx context.Context, s *Session) (json.RawMessage, error) {
+	location := m.r.Configuration(ctx).SessionClaimsMapperURL()
+	if location == "" {
+		return nil, nil
+	}
+
+	snippet, err := m.load(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var input bytes.Buffer
+	if err := json.NewEncoder(&input).Encode(s); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("session", input.String())
+	evaluated, err := vm.EvaluateSnippet(location, snippet)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to evaluate the session claims mapper: %s", err))
+	}
+
+	claims := gjson.Get(evaluated, "claims")
+	if !claims.Exists() {
+		return nil, nil
+	} else if !claims.IsObject() {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The session claims mapper did not return an object for key claims."))
+	}
+
+	if max := m.r.Configuration(ctx).SessionClaimsMaxSize(); len(claims.Raw) > max {
+		m.r.Logger().
+			WithField("session_id", s.ID).
+			WithField("claims_size", len(claims.Raw)).
+			WithField("claims_max_size", max).
+			Error("The session claims mapper returned claims exceeding the maximum size. Please check your Jsonnet code!")
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The claims returned by the session claims mapper exceed the maximum size of %d bytes.", max))
+	}
+
+	return json.RawMessage(claims.Raw), nil
+}
@@ -0,0 +1,90 @@
+package session_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
+)
+
+func TestClaimsMapper(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	var newSession = func(email string) *session.Session {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"` + email + `"}`)
+		return session.NewActiveSession(i, conf, time.Now().UTC())
+	}
+
+	var newMapper = func(t *testing.T, jsonnet string) *session.ClaimsMapper {
+		conf.MustSet(config.ViperKeySessionClaimsMapperURL, "base64://"+base64.StdEncoding.EncodeToString([]byte(jsonnet)))
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionClaimsMapperURL, "")
+		})
+		return session.NewClaimsMapper(reg)
+	}
+
+	t.Run("case=no mapper configured", func(t *testing.T) {
+		m := session.NewClaimsMapper(reg)
+		require.NoError(t, m.Validate(ctx))
+
+		claims, err := m.Map(ctx, newSession("foo@ory.sh"))
+		require.NoError(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("case=maps claims", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionClaimsMapperURL, "file://./stub/claims.jsonnet")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionClaimsMapperURL, "")
+		})
+
+		m := session.NewClaimsMapper(reg)
+		require.NoError(t, m.Validate(ctx))
+
+		s := newSession("foo@ory.sh")
+		claims, err := m.Map(ctx, s)
+		require.NoError(t, err)
+		assert.Equal(t, "admin", gjson.GetBytes(claims, "role").String())
+		assert.Equal(t, s.AuthenticatedAt.Unix(), gjson.GetBytes(claims, "authenticated_at").Time().Unix())
+
+		claims, err = m.Map(ctx, newSession("foo@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, "user", gjson.GetBytes(claims, "role").String())
+	})
+
+	t.Run("case=fails validation on invalid jsonnet", func(t *testing.T) {
+		require.Error(t, newMapper(t, "{ claims: ").Validate(ctx))
+	})
+
+	t.Run("case=returns nothing if claims are not set", func(t *testing.T) {
+		claims, err := newMapper(t, "{}").Map(ctx, newSession("foo@ory.sh"))
+		require.NoError(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("case=fails if claims are not an object", func(t *testing.T) {
+		_, err := newMapper(t, "{ claims: 'foo' }").Map(ctx, newSession("foo@ory.sh"))
+		require.Error(t, err)
+	})
+
+	t.Run("case=fails if claims exceed the maximum size", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionClaimsMaxSize, 16)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionClaimsMaxSize, 4096)
+		})
+
+		_, err := newMapper(t, "{ claims: { foo: std.join('', std.makeArray(32, function(i) 'a')) } }").Map(ctx, newSession("foo@ory.sh"))
+		require.Error(t, err)
+	})
+}
@@ -2,27 +2,36 @@ package session
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
 	"github.com/ory/x/decoderx"
+	"github.com/ory/x/logrusx"
 
 	"github.com/ory/x/errorsx"
 
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
 type (
 	handlerDependencies interface {
 		ManagementProvider
+		ClaimsMapperProvider
+		TokenizerProvider
 		PersistenceProvider
+		identity.PoolProvider
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFProvider
+		config.Providers
 	}
 	HandlerProvider interface {
 		SessionHandler() *Handler
@@ -45,6 +54,10 @@ func NewHandler(
 const (
 	RouteWhoami = "/sessions/whoami"
 	RouteRevoke = "/sessions"
+	RouteToken  = "/sessions/token"
+	RouteJWKS   = "/.well-known/jwks.json"
+
+	RouteImpersonate = "/sessions/impersonate"
 	// SessionsWhoisPath  = "/sessions/whois"
 )
 
@@ -58,10 +71,138 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	}
 
 	public.DELETE(RouteRevoke, h.revoke)
+	public.GET(RouteToken, h.token)
+	public.GET(RouteJWKS, h.jwks)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	// admin.GET(SessionsWhoisPath, h.fromPath)
+	admin.POST(RouteImpersonate, h.impersonate)
+}
+
+// swagger:parameters impersonateIdentity
+// nolint:deadcode,unused
+type impersonateIdentityParameters struct {
+	// in: body
+	// required: true
+	Body impersonateIdentity
+}
+
+type impersonateIdentity struct {
+	// IdentityID is the ID of the identity to impersonate.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// Reason explains why the identity is impersonated, e.g. a support ticket. It is written to the audit log.
+	//
+	// required: true
+	Reason string `json:"reason"`
+
+	// Impersonator identifies the administrator, e.g. by their email address. It is required unless
+	// the request is authenticated using an admin API key, in which case the API key is used.
+	Impersonator string `json:"impersonator"`
+}
+
+// An Impersonation Session
+//
+// swagger:model impersonationSession
+type impersonationSession struct {
+	// The session token which can be sent in the `X-Session-Token` header to act as the identity.
+	//
+	// required: true
+	SessionToken string `json:"session_token"`
+
+	// required: true
+	Session *Session `json:"session"`
+}
+
+// swagger:route POST /sessions/impersonate admin impersonateIdentity
+//
+// Issue an Impersonation Session
+//
+// Issues a short-lived session which allows an administrator, for example a member of the support team, to
+// act as the identity. The session contains the `impersonator`, expires after `session.impersonation.lifespan`,
+// is never refreshed, and can not be used to change the identity's settings or credentials. Issuing and using
+// impersonation sessions is written to the audit log.
+//
+// This endpoint is only available if `session.impersonation.enabled` is set.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: impersonationSession
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) impersonate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.r.Configuration(r.Context())
+	if !c.SessionImpersonationEnabled() {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("Session impersonation is disabled.")))
+		return
+	}
+
+	var p impersonateIdentity
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	impersonator := p.Impersonator
+	if actor := x.AuditActor(r.Context()); actor != "" {
+		impersonator = actor
+	}
+
+	if impersonator == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Field impersonator must be set unless the request is authenticated using an admin API key.")))
+		return
+	} else if p.Reason == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Field reason must explain why the identity is impersonated.")))
+		return
+	}
+
+	i, err := h.r.IdentityPool().GetIdentity(r.Context(), p.IdentityID)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	s := NewImpersonationSession(i, impersonator, c, time.Now().UTC())
+	if err := h.r.SessionPersister().CreateSession(r.Context(), s); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.auditImpersonation(r, s).
+		WithField("reason", p.Reason).
+		WithField("expires_at", s.ExpiresAt).
+		Info("Impersonation session was issued.")
+
+	h.r.Writer().WriteCreated(w, r, RouteWhoami, &impersonationSession{
+		SessionToken: s.Token,
+		Session:      s.Declassify(),
+	})
+}
+
+// auditImpersonation returns an audit logger which traces the impersonation session back to the administrator.
+func (h *Handler) auditImpersonation(r *http.Request, s *Session) *logrusx.Logger {
+	l := h.r.Audit().
+		WithRequest(r).
+		WithField("session_id", s.ID).
+		WithField("identity_id", s.IdentityID).
+		WithField("impersonator", s.Impersonator)
+	if id := x.AuditRequestID(r.Context()); id != "" {
+		l = l.WithField("request_id", id)
+	}
+	return l
 }
 
 // swagger:parameters revokeSession
@@ -139,6 +280,9 @@ type whoamiParameters struct {
 //
 // This endpoint is useful for reverse proxies and API Gateways.
 //
+// If the session was issued on the identity's first login, `first_login` is set. Unless `session.first_login_flag`
+// is set to `session`, it is only set in the response to the first request.
+//
 //     Produces:
 //     - application/json
 //
@@ -156,31 +300,156 @@ func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	if err != nil {
 		h.r.Audit().WithRequest(r).WithError(err).Info("No valid session cookie found.")
 		h.r.Writer().WriteError(w, r,
-			errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+			errors.WithStack(herodot.ErrUnauthorized.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithReasonf("No valid session cookie found.")))
 		return
 	}
 
+	if err := h.r.SessionManager().RefreshCookie(r.Context(), w, r, s); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if s.FirstLogin && h.r.Configuration(r.Context()).SessionFirstLoginFlag() == config.SessionFirstLoginFlagRequest {
+		if s.FirstLogin, err = h.r.SessionPersister().ConsumeFirstLogin(r.Context(), s.ID); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	if s.IsImpersonated() {
+		h.auditImpersonation(r, s).Info("Impersonation session was used.")
+		w.Header().Set("X-Kratos-Impersonator", s.Impersonator)
+	}
+
 	// s.Devices = nil
 	s.Identity = s.Identity.CopyWithoutCredentials()
 
+	s.Claims, err = h.r.SessionClaimsMapper().Map(r.Context(), s)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	// The claims mapper has access to the entitlements even if they are not part of the response.
+	if !h.r.Configuration(r.Context()).SessionIncludeEntitlements() {
+		s.Identity.Entitlements = nil
+	}
+
 	// Set userId as the X-Kratos-Authenticated-Identity-Id header.
 	w.Header().Set("X-Kratos-Authenticated-Identity-Id", s.Identity.ID.String())
 
 	h.r.Writer().Write(w, r, s)
 }
 
+// A Session JSON Web Token
+//
+// swagger:model sessionToken
+type sessionToken struct {
+	// The signed JSON Web Token.
+	//
+	// required: true
+	Token string `json:"token"`
+
+	// The time at which the token expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// swagger:route GET /sessions/token public getSessionToken
+//
+// Get a JSON Web Token for the Current Session
+//
+// Uses the HTTP Headers in the GET request to determine the current session and returns a short-lived JSON Web Token
+// signed with the key published at `/.well-known/jwks.json`. API Gateways can verify the token locally instead of
+// calling `/sessions/whoami` on every request. Fetch a new token before the current one expires.
+//
+// This endpoint is only available if `session.jwt.enabled` is set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       sessionToken:
+//
+//     Responses:
+//       200: sessionToken
+//       401: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) token(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrUnauthorized.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	token, expiresAt, err := h.r.SessionTokenizer().Tokenize(r.Context(), s)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if s.IsImpersonated() {
+		h.auditImpersonation(r, s).Info("JSON Web Token was issued for impersonation session.")
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.r.Writer().Write(w, r, &sessionToken{Token: token, ExpiresAt: expiresAt})
+}
+
+// swagger:route GET /.well-known/jwks.json public getSessionJSONWebKeys
+//
+// Get the JSON Web Keys for Session JSON Web Tokens
+//
+// Returns the public keys which can be used to verify the JSON Web Tokens issued by `/sessions/token`.
+//
+// This endpoint is only available if `session.jwt.enabled` is set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: jsonWebKeySet
+//       404: genericError
+//       500: genericError
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	keys, err := h.r.SessionTokenizer().JSONWebKeys(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, keys)
+}
+
 func (h *Handler) IsAuthenticated(wrap httprouter.Handle, onUnauthenticated httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
+		s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+		if err != nil {
 			if onUnauthenticated != nil {
 				onUnauthenticated(w, r, ps)
 				return
 			}
 
-			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed with a valid session. Please log in and try again.").WithDebugf("%+v", err)))
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithReason("This endpoint can only be accessed with a valid session. Please log in and try again.").WithDebugf("%+v", err)))
 			return
 		}
 
+		if err := h.r.SessionManager().RefreshCookie(r.Context(), w, r, s); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if s.IsImpersonated() {
+			h.auditImpersonation(r, s).Info("Impersonation session was used.")
+		}
+
 		wrap(w, r, ps)
 	}
 }
@@ -201,7 +470,7 @@ func (h *Handler) IsNotAuthenticated(wrap httprouter.Handle, onAuthenticated htt
 			return
 		}
 
-		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed without a login session. Please log out and try again.")))
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionAlreadyAvailable).WithReason("This endpoint can only be accessed without a login session. Please log out and try again.")))
 	}
 }
 
@@ -1,8 +1,11 @@
 package session_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +14,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/x/pointerx"
 
@@ -101,6 +105,73 @@ func TestSessionRevoke(t *testing.T) {
 	assert.False(t, actual.IsActive())
 }
 
+func TestSessionImpersonate(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySessionImpersonationLifespan, "10m")
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	impersonate := func(t *testing.T, body interface{}) (*http.Response, []byte) {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		res, err := http.Post(adminTS.URL+RouteImpersonate, "application/json", &b)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		payload, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, payload
+	}
+
+	t.Run("case=disabled", func(t *testing.T) {
+		res, _ := impersonate(t, map[string]interface{}{"identity_id": i.ID, "reason": "ticket-1234", "impersonator": "support@example.org"})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	conf.MustSet(config.ViperKeySessionImpersonationEnabled, true)
+
+	t.Run("case=requires impersonator and reason", func(t *testing.T) {
+		res, body := impersonate(t, map[string]interface{}{"identity_id": i.ID, "reason": "ticket-1234"})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+
+		res, body = impersonate(t, map[string]interface{}{"identity_id": i.ID, "impersonator": "support@example.org"})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+	})
+
+	t.Run("case=unknown identity", func(t *testing.T) {
+		res, _ := impersonate(t, map[string]interface{}{"identity_id": x.NewUUID(), "reason": "ticket-1234", "impersonator": "support@example.org"})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("case=issues a marked session", func(t *testing.T) {
+		res, body := impersonate(t, map[string]interface{}{"identity_id": i.ID, "reason": "ticket-1234", "impersonator": "support@example.org"})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "%s", body)
+		assert.Equal(t, i.ID.String(), gjson.GetBytes(body, "session.identity.id").String(), "%s", body)
+		assert.Equal(t, "support@example.org", gjson.GetBytes(body, "session.impersonator").String(), "%s", body)
+		assert.False(t, gjson.GetBytes(body, "session.identity.credentials").Exists(), "%s", body)
+
+		expiresAt := gjson.GetBytes(body, "session.expires_at").Time()
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiresAt, time.Minute)
+
+		token := gjson.GetBytes(body, "session_token").String()
+		require.NotEmpty(t, token)
+
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Session-Token", token)
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err = ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, "support@example.org", res.Header.Get("X-Kratos-Impersonator"))
+		assert.Equal(t, "support@example.org", gjson.GetBytes(body, "impersonator").String(), "%s", body)
+	})
+}
+
 func TestIsNotAuthenticatedSecurecookie(t *testing.T) {
 	conf, reg := internal.NewFastRegistryWithMocks(t)
 	r := x.NewRouterPublic()
@@ -0,0 +1,269 @@
+package session
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// ErrJWTDisabled is returned when session JSON Web Tokens are requested but `session.jwt.enabled` is not set.
+var ErrJWTDisabled = herodot.ErrNotFound.WithReason("Issuing JSON Web Tokens for sessions is disabled.")
+
+type (
+	tokenizerDependencies interface {
+		config.Providers
+		x.HTTPClientProvider
+		ClaimsMapperProvider
+	}
+	TokenizerProvider interface {
+		SessionTokenizer() *Tokenizer
+	}
+
+	// Tokenizer issues short-lived JSON Web Tokens for sessions which can be verified using the
+	// keys returned by JSONWebKeys.
+	Tokenizer struct {
+		r tokenizerDependencies
+		f *fetcher.Fetcher
+
+		l      sync.Mutex
+		url    string
+		cached *tokenSigner
+	}
+
+	// JSONWebKeySet is a set of public JSON Web Keys as defined in RFC 7517.
+	//
+	// swagger:model jsonWebKeySet
+	JSONWebKeySet struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+
+	// JSONWebKey is a public JSON Web Key as defined in RFC 7517.
+	JSONWebKey struct {
+		KeyType   string `json:"kty"`
+		Use       string `json:"use"`
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+
+		// RSA keys
+		N string `json:"n,omitempty"`
+		E string `json:"e,omitempty"`
+
+		// ECDSA keys
+		Curve string `json:"crv,omitempty"`
+		X     string `json:"x,omitempty"`
+		Y     string `json:"y,omitempty"`
+	}
+)
+
+// registeredClaims can not be overwritten by custom session claims.
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "sid", "aal", "act"}
+
+func NewTokenizer(r tokenizerDependencies) *Tokenizer {
+	return &Tokenizer{r: r, f: fetcher.NewFetcher(fetcher.WithClient(r.HTTPClient()))}
+}
+
+// Validate loads the signing key. It is a no-op if session JSON Web Tokens are disabled.
+func (t *Tokenizer) Validate(ctx context.Context) error {
+	if !t.r.Configuration(ctx).SessionJWTEnabled() {
+		return nil
+	}
+
+	_, err := t.signer(ctx)
+	return err
+}
+
+// Tokenize returns a signed JSON Web Token for the session. The token expires after `session.jwt.lifespan`
+// but never after the session itself.
+func (t *Tokenizer) Tokenize(ctx context.Context, s *Session) (string, time.Time, error) {
+	c := t.r.Configuration(ctx)
+	if !c.SessionJWTEnabled() {
+		return "", time.Time{}, errors.WithStack(ErrJWTDisabled)
+	}
+
+	signer, err := t.signer(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(c.SessionJWTLifespan())
+	if s.ExpiresAt.Before(expiresAt) {
+		expiresAt = s.ExpiresAt
+	}
+
+	claims := jwt.MapClaims{}
+	if c.SessionJWTIncludeClaims() {
+		custom, err := t.r.SessionClaimsMapper().Map(ctx, s)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		if len(custom) > 0 {
+			if err := json.Unmarshal(custom, &claims); err != nil {
+				return "", time.Time{}, errors.WithStack(err)
+			}
+		}
+
+		for _, k := range registeredClaims {
+			delete(claims, k)
+		}
+	}
+
+	if c.SessionJWTIncludeEntitlements() && s.Identity != nil && len(s.Identity.Entitlements) > 0 {
+		claims["entitlements"] = json.RawMessage(s.Identity.Entitlements)
+	}
+
+	claims["iss"] = c.SelfPublicURL().String()
+	claims["sub"] = s.IdentityID.String()
+	claims["sid"] = s.ID.String()
+	claims["aal"] = string(s.AuthenticatorAssuranceLevel())
+	claims["jti"] = x.NewUUID().String()
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+	if s.IsImpersonated() {
+		// The actor claim is defined in RFC 8693 and identifies the party acting on behalf of the subject.
+		claims["act"] = map[string]interface{}{"sub": s.Impersonator}
+	}
+
+	token := jwt.NewWithClaims(signer.method, claims)
+	token.Header["kid"] = signer.kid
+	signed, err := token.SignedString(signer.key)
+	if err != nil {
+		return "", time.Time{}, errors.WithStack(err)
+	}
+
+	return signed, expiresAt, nil
+}
+
+// JSONWebKeys returns the public keys which can be used to verify session JSON Web Tokens.
+func (t *Tokenizer) JSONWebKeys(ctx context.Context) (*JSONWebKeySet, error) {
+	if !t.r.Configuration(ctx).SessionJWTEnabled() {
+		return nil, errors.WithStack(ErrJWTDisabled)
+	}
+
+	signer, err := t.signer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := JSONWebKey{Use: "sig", Algorithm: signer.method.Alg(), KeyID: signer.kid}
+	switch pub := signer.key.Public().(type) {
+	case *rsa.PublicKey:
+		key.KeyType = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		key.KeyType = "EC"
+		key.Curve = pub.Curve.Params().Name
+		key.X = base64.RawURLEncoding.EncodeToString(padLeft(pub.X.Bytes(), size))
+		key.Y = base64.RawURLEncoding.EncodeToString(padLeft(pub.Y.Bytes(), size))
+	}
+
+	return &JSONWebKeySet{Keys: []JSONWebKey{key}}, nil
+}
+
+type tokenSigner struct {
+	key    crypto.Signer
+	kid    string
+	method jwt.SigningMethod
+}
+
+func (t *Tokenizer) signer(ctx context.Context) (*tokenSigner, error) {
+	location := t.r.Configuration(ctx).SessionJWTSigningKeyURL()
+
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if t.url == location && t.cached != nil {
+		return t.cached, nil
+	}
+
+	if location == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Session JSON Web Tokens are enabled but no signing key is configured in session.jwt.signing_key_url."))
+	}
+
+	raw, err := t.f.Fetch(location)
+	if err != nil {
+		return nil, err
+	}
+
+	key, method, err := parseSigningKey(raw.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sum := sha256.Sum256(der)
+
+	t.url = location
+	t.cached = &tokenSigner{key: key, kid: base64.RawURLEncoding.EncodeToString(sum[:]), method: method}
+	return t.cached, nil
+}
+
+func parseSigningKey(raw []byte) (crypto.Signer, jwt.SigningMethod, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the session JSON Web Token signing key: expected a PEM encoded private key."))
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the session JSON Web Token signing key: %s", err))
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return k, jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return k, jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return k, jwt.SigningMethodES512, nil
+		}
+	}
+
+	return nil, nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The session JSON Web Token signing key must be an RSA or an ECDSA (P-256, P-384, P-521) private key."))
+}
+
+func padLeft(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
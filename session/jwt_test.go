@@ -0,0 +1,188 @@
+package session_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
+)
+
+func TestTokenizer(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://auth.ory.sh/")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyURL := func(typ string, der []byte) string {
+		return "base64://" + base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+	}
+
+	newSession := func() *session.Session {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"foo@ory.sh"}`)
+		return session.NewActiveSession(i, conf, time.Now().UTC())
+	}
+
+	newTokenizer := func(t *testing.T, keyURL string) *session.Tokenizer {
+		conf.MustSet(config.ViperKeySessionJWTEnabled, true)
+		conf.MustSet(config.ViperKeySessionJWTSigningKeyURL, keyURL)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionJWTEnabled, false)
+			conf.MustSet(config.ViperKeySessionJWTSigningKeyURL, "")
+			conf.MustSet(config.ViperKeySessionJWTIncludeClaims, false)
+			conf.MustSet(config.ViperKeySessionJWTIncludeEntitlements, false)
+			conf.MustSet(config.ViperKeySessionJWTLifespan, "5m")
+			conf.MustSet(config.ViperKeySessionClaimsMapperURL, "")
+		})
+		return session.NewTokenizer(reg)
+	}
+
+	t.Run("case=disabled", func(t *testing.T) {
+		tk := session.NewTokenizer(reg)
+		require.NoError(t, tk.Validate(ctx))
+
+		_, _, err := tk.Tokenize(ctx, newSession())
+		assert.Error(t, err)
+
+		_, err = tk.JSONWebKeys(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("case=invalid signing key", func(t *testing.T) {
+		tk := newTokenizer(t, "base64://"+base64.StdEncoding.EncodeToString([]byte("not a key")))
+		assert.Error(t, tk.Validate(ctx))
+	})
+
+	t.Run("case=issues tokens verifiable with the published ECDSA key", func(t *testing.T) {
+		tk := newTokenizer(t, keyURL("EC PRIVATE KEY", ecDER))
+		require.NoError(t, tk.Validate(ctx))
+
+		keys, err := tk.JSONWebKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys.Keys, 1)
+		jwk := keys.Keys[0]
+		assert.Equal(t, "EC", jwk.KeyType)
+		assert.Equal(t, "ES256", jwk.Algorithm)
+		assert.Equal(t, "P-256", jwk.Curve)
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		require.NoError(t, err)
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		require.NoError(t, err)
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+		s := newSession()
+		raw, expiresAt, err := tk.Tokenize(ctx, s)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, time.Minute)
+
+		token, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+			assert.Equal(t, jwk.KeyID, token.Header["kid"])
+			return pub, nil
+		})
+		require.NoError(t, err)
+		require.True(t, token.Valid)
+
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, s.IdentityID.String(), claims["sub"])
+		assert.Equal(t, s.ID.String(), claims["sid"])
+		assert.Equal(t, "aal1", claims["aal"])
+		assert.Equal(t, "https://auth.ory.sh/", claims["iss"])
+		assert.EqualValues(t, expiresAt.Unix(), claims["exp"])
+	})
+
+	t.Run("case=token does not outlive the session", func(t *testing.T) {
+		tk := newTokenizer(t, keyURL("EC PRIVATE KEY", ecDER))
+		conf.MustSet(config.ViperKeySessionJWTLifespan, "48h")
+
+		s := newSession()
+		_, expiresAt, err := tk.Tokenize(ctx, s)
+		require.NoError(t, err)
+		assert.Equal(t, s.ExpiresAt.Unix(), expiresAt.Unix())
+	})
+
+	t.Run("case=includes custom claims with RSA key", func(t *testing.T) {
+		tk := newTokenizer(t, keyURL("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)))
+		conf.MustSet(config.ViperKeySessionJWTIncludeClaims, true)
+		conf.MustSet(config.ViperKeySessionClaimsMapperURL, "base64://"+base64.StdEncoding.EncodeToString([]byte(`{claims: {role: "admin", sub: "overwritten"}}`)))
+
+		keys, err := tk.JSONWebKeys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", keys.Keys[0].Algorithm)
+
+		s := newSession()
+		raw, _, err := tk.Tokenize(ctx, s)
+		require.NoError(t, err)
+
+		token, err := jwt.Parse(raw, func(*jwt.Token) (interface{}, error) {
+			return &rsaKey.PublicKey, nil
+		})
+		require.NoError(t, err)
+
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, "admin", claims["role"])
+		assert.Equal(t, s.IdentityID.String(), claims["sub"], "registered claims must not be overwritten")
+	})
+
+	t.Run("case=includes entitlements", func(t *testing.T) {
+		tk := newTokenizer(t, keyURL("EC PRIVATE KEY", ecDER))
+
+		s := newSession()
+		s.Identity.Entitlements = identity.Entitlements(`{"plan":"pro","features":["export"]}`)
+
+		parse := func(t *testing.T) jwt.MapClaims {
+			raw, _, err := tk.Tokenize(ctx, s)
+			require.NoError(t, err)
+
+			token, err := jwt.Parse(raw, func(*jwt.Token) (interface{}, error) {
+				return &ecKey.PublicKey, nil
+			})
+			require.NoError(t, err)
+			return token.Claims.(jwt.MapClaims)
+		}
+
+		assert.NotContains(t, parse(t), "entitlements")
+
+		conf.MustSet(config.ViperKeySessionJWTIncludeEntitlements, true)
+		assert.Equal(t, map[string]interface{}{"plan": "pro", "features": []interface{}{"export"}}, parse(t)["entitlements"])
+	})
+
+	t.Run("case=includes impersonator as actor", func(t *testing.T) {
+		tk := newTokenizer(t, keyURL("EC PRIVATE KEY", ecDER))
+
+		s := newSession()
+		s.Impersonator = "support@example.org"
+
+		raw, _, err := tk.Tokenize(ctx, s)
+		require.NoError(t, err)
+
+		token, err := jwt.Parse(raw, func(*jwt.Token) (interface{}, error) {
+			return &ecKey.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"sub": "support@example.org"}, token.Claims.(jwt.MapClaims)["act"])
+	})
+}
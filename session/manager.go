@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/ory/herodot"
+	"github.com/ory/kratos/text"
 )
 
 // DefaultSessionCookieName returns the default cookie name for the kratos session.
@@ -12,7 +13,11 @@ const DefaultSessionCookieName = "ory_kratos_session"
 
 var (
 	// ErrNoActiveSessionFound is returned when no active cookie session could be found in the request.
-	ErrNoActiveSessionFound = herodot.ErrUnauthorized.WithError("request does not have a valid authentication session").WithReason("No active session was found in this request.")
+	ErrNoActiveSessionFound = herodot.ErrUnauthorized.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionInactive).WithError("request does not have a valid authentication session").WithReason("No active session was found in this request.")
+
+	// ErrImpersonatedSession is returned when an action which is not allowed for impersonation sessions is
+	// performed using one.
+	ErrImpersonatedSession = herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionImpersonated).WithReason("This action can not be performed using an impersonation session.")
 )
 
 // Manager handles identity sessions.
@@ -27,6 +32,15 @@ type Manager interface {
 	// Also regenerates CSRF tokens due to assumed principal change.
 	IssueCookie(context.Context, http.ResponseWriter, *http.Request, *Session) error
 
+	// RefreshCookie extends the session if session refresh is enabled and the session expires within the
+	// refresh window. If the session was sent using a cookie, the cookie is re-issued.
+	RefreshCookie(context.Context, http.ResponseWriter, *http.Request, *Session) error
+
+	// RotateFromRequest revokes the session sent with the request if the given session replaces it, for example
+	// because the identity re-authenticated. This prevents session fixation. It returns true if a session was
+	// revoked and does nothing if `session.rotation.enabled` is false.
+	RotateFromRequest(context.Context, *http.Request, *Session) (bool, error)
+
 	// FetchFromRequest creates an HTTP session using cookies.
 	FetchFromRequest(context.Context, *http.Request) (*Session, error)
 
@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/x"
 )
 
@@ -20,6 +22,7 @@ type (
 	managerHTTPDependencies interface {
 		config.Providers
 		identity.PoolProvider
+		schema.IdentityTraitsProvider
 		x.CookieProvider
 		x.CSRFProvider
 		PersistenceProvider
@@ -59,8 +62,10 @@ func (s *ManagerHTTP) IssueCookie(ctx context.Context, w http.ResponseWriter, r
 	if err != nil {
 		// No session was set prior -> regenerate anti-csrf token
 		_ = s.r.CSRFHandler().RegenerateToken(w, r)
-	} else if old.Identity.ID != session.Identity.ID {
-		// No session was set prior -> regenerate anti-csrf token
+	} else if rotated, err := s.rotate(ctx, old, session); err != nil {
+		return err
+	} else if rotated || old.Identity.ID != session.Identity.ID {
+		// The session was replaced -> regenerate anti-csrf token
 		_ = s.r.CSRFHandler().RegenerateToken(w, r)
 	}
 
@@ -84,6 +89,51 @@ func (s *ManagerHTTP) IssueCookie(ctx context.Context, w http.ResponseWriter, r
 	return nil
 }
 
+func (s *ManagerHTTP) RefreshCookie(ctx context.Context, w http.ResponseWriter, r *http.Request, session *Session) error {
+	if !s.r.Configuration(ctx).SessionRefreshEnabled() {
+		return nil
+	}
+
+	if !session.Refresh(s.r.Configuration(ctx), time.Now().UTC()) {
+		return nil
+	}
+
+	if err := s.r.SessionPersister().ExtendSession(ctx, session.ID, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	// Sessions sent as tokens are not stored in cookies, so there is nothing to re-issue.
+	if _, ok := bearerTokenFromRequest(r); ok || len(r.Header.Get("X-Session-Token")) > 0 {
+		return nil
+	}
+
+	return s.IssueCookie(ctx, w, r, session)
+}
+
+func (s *ManagerHTTP) RotateFromRequest(ctx context.Context, r *http.Request, session *Session) (bool, error) {
+	old, err := s.FetchFromRequest(ctx, r)
+	if errors.Is(err, ErrNoActiveSessionFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return s.rotate(ctx, old, session)
+}
+
+// rotate revokes the previous session if it is replaced by a new session.
+func (s *ManagerHTTP) rotate(ctx context.Context, previous, session *Session) (bool, error) {
+	if !s.r.Configuration(ctx).SessionRotationEnabled() || previous.ID == session.ID {
+		return false, nil
+	}
+
+	if err := s.r.SessionPersister().RevokeSessionByToken(ctx, previous.Token); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return true, nil
+}
+
 func (s *ManagerHTTP) extractToken(r *http.Request) string {
 	if token, ok := bearerTokenFromRequest(r); ok {
 		return token
@@ -120,7 +170,12 @@ func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (*S
 		return nil, err
 	}
 
-	if !se.IsActive() {
+	if !se.IsActive() || !se.SatisfiesPolicy(time.Now().UTC()) || se.Identity.IsScheduledForDeletion() {
+		return nil, errors.WithStack(ErrNoActiveSessionFound)
+	}
+
+	// Identities deactivated for not verifying any of their addresses in time regain access once they verify one.
+	if identity.EnforcedVerificationAction(s.r.IdentityTraitsSchemas(ctx), se.Identity, time.Now().UTC()) == config.VerificationEnforcementDeactivate {
 		return nil, errors.WithStack(ErrNoActiveSessionFound)
 	}
 
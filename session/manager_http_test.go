@@ -3,6 +3,7 @@ package session_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,9 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/ioutilx"
 
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
@@ -135,5 +139,68 @@ func TestManagerHTTP(t *testing.T) {
 			require.NoError(t, err)
 			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
 		})
+
+		t.Run("case=rotation", func(t *testing.T) {
+			fetchSessionID := func(t *testing.T, c *http.Client) string {
+				res, err := c.Get(pts.URL + "/session/get")
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.EqualValues(t, http.StatusOK, res.StatusCode)
+				return gjson.GetBytes(ioutilx.MustReadAll(res.Body), "id").String()
+			}
+
+			for _, tc := range []struct {
+				enabled bool
+			}{{enabled: true}, {enabled: false}} {
+				t.Run(fmt.Sprintf("enabled=%v", tc.enabled), func(t *testing.T) {
+					conf.MustSet(config.ViperKeySessionRotationEnabled, tc.enabled)
+					t.Cleanup(func() {
+						conf.MustSet(config.ViperKeySessionRotationEnabled, true)
+					})
+
+					i := identity.Identity{Traits: []byte("{}")}
+					require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+					s = session.NewActiveSession(&i, conf, time.Now())
+
+					c := testhelpers.NewClientWithCookies(t)
+					testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+					previous := fetchSessionID(t, c)
+
+					// Re-authenticating issues a new session to the same browser.
+					s = session.NewActiveSession(&i, conf, time.Now())
+					testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+					current := fetchSessionID(t, c)
+					assert.NotEqual(t, previous, current)
+
+					actual, err := reg.SessionPersister().GetSession(context.Background(), x.ParseUUID(previous))
+					require.NoError(t, err)
+					assert.Equal(t, !tc.enabled, actual.IsActive(), "the previous session must be revoked if rotation is enabled")
+				})
+			}
+
+			t.Run("case=session token", func(t *testing.T) {
+				i := identity.Identity{Traits: []byte("{}")}
+				require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+
+				previous := session.NewActiveSession(&i, conf, time.Now())
+				require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), previous))
+				current := session.NewActiveSession(&i, conf, time.Now())
+				require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), current))
+
+				r := httptest.NewRequest("POST", "/", nil)
+				r.Header.Set("X-Session-Token", previous.Token)
+
+				rotated, err := reg.SessionManager().RotateFromRequest(context.Background(), r, current)
+				require.NoError(t, err)
+				assert.True(t, rotated)
+
+				rotated, err = reg.SessionManager().RotateFromRequest(context.Background(), r, current)
+				require.NoError(t, err)
+				assert.False(t, rotated, "the previous session is no longer active")
+
+				_, err = reg.SessionManager().FetchFromRequest(context.Background(), r)
+				require.Error(t, err)
+			})
+		})
 	})
 }
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+type sessionContextKey int
+
+const sessionKey sessionContextKey = iota + 1
+
+type (
+	// RequireSessionOption configures the middleware returned by Handler.RequireSession.
+	RequireSessionOption func(o *requireSessionOptions)
+
+	requireSessionOptions struct {
+		minimumAAL        AuthenticatorAssuranceLevel
+		onUnauthenticated func(w http.ResponseWriter, r *http.Request, err error)
+	}
+)
+
+// WithMinimumAAL rejects sessions whose authenticator assurance level is lower than the given level.
+func WithMinimumAAL(aal AuthenticatorAssuranceLevel) RequireSessionOption {
+	return func(o *requireSessionOptions) {
+		o.minimumAAL = aal
+	}
+}
+
+// WithUnauthenticatedHandler replaces the default behavior for requests without a valid session. The error
+// explains why the request was rejected.
+func WithUnauthenticatedHandler(h func(w http.ResponseWriter, r *http.Request, err error)) RequireSessionOption {
+	return func(o *requireSessionOptions) {
+		o.onUnauthenticated = h
+	}
+}
+
+// WithSession returns a context containing the session.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
+
+// FromContext returns the session added by Handler.RequireSession.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey).(*Session)
+	return s, ok
+}
+
+// RequireSession protects the handler using the sessions of this ORY Kratos instance. If the request
+// carries a valid session, the session including its identity is added to the request's context and can
+// be retrieved using FromContext.
+//
+// Otherwise, browsers are redirected to the login UI and all other clients receive a 401 error, unless
+// WithUnauthenticatedHandler is used.
+func (h *Handler) RequireSession(next http.Handler, opts ...RequireSessionOption) http.Handler {
+	o := new(requireSessionOptions)
+	for _, f := range opts {
+		f(o)
+	}
+
+	if o.onUnauthenticated == nil {
+		o.onUnauthenticated = h.onUnauthenticated
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+		if errors.Is(err, ErrNoActiveSessionFound) {
+			o.onUnauthenticated(w, r, err)
+			return
+		} else if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if !s.AuthenticatorAssuranceLevel().Satisfies(o.minimumAAL) {
+			o.onUnauthenticated(w, r, errors.WithStack(herodot.ErrForbidden.
+				WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSessionAALInsufficient).
+				WithReasonf("This endpoint requires authenticator assurance level %s but the session has %s.", o.minimumAAL, s.AuthenticatorAssuranceLevel())))
+			return
+		}
+
+		if err := h.r.SessionManager().RefreshCookie(r.Context(), w, r, s); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if s.IsImpersonated() {
+			h.auditImpersonation(r, s).Info("Impersonation session was used.")
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithSession(r.Context(), s)))
+	})
+}
+
+// onUnauthenticated redirects browsers to the login UI and responds with the error to all other clients.
+func (h *Handler) onUnauthenticated(w http.ResponseWriter, r *http.Request, err error) {
+	if x.IsBrowserRequest(r) {
+		http.Redirect(w, r, h.r.Configuration(r.Context()).SelfServiceFlowLoginUI().String(), http.StatusSeeOther)
+		return
+	}
+
+	h.r.Writer().WriteError(w, r, err)
+}
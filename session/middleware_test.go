@@ -0,0 +1,93 @@
+package session_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	. "github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+func TestRequireSession(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceLoginUI, "https://www.ory.sh/login")
+	testhelpers.SetDefaultIdentitySchema(t, conf, "file://./stub/identity.schema.json")
+
+	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := FromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(s.Identity.ID.String()))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/default", reg.SessionHandler().RequireSession(protected))
+	mux.Handle("/aal2", reg.SessionHandler().RequireSession(protected, WithMinimumAAL(AuthenticatorAssuranceLevel2)))
+	mux.Handle("/custom", reg.SessionHandler().RequireSession(protected, WithUnauthenticatedHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	i := &identity.Identity{ID: x.NewUUID(), Traits: identity.Traits(`{}`)}
+	sessionClient := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, i)
+
+	noRedirectClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	do := func(t *testing.T, c *http.Client, path, accept string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	t.Run("case=adds the session to the context", func(t *testing.T) {
+		res, body := do(t, sessionClient, "/default", "application/json")
+		assert.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, i.ID.String(), body)
+	})
+
+	t.Run("case=responds with 401 to api clients without a session", func(t *testing.T) {
+		res, body := do(t, noRedirectClient, "/default", "application/json")
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body)
+		assert.Equal(t, string(text.ErrorCodeSessionInactive), gjson.Get(body, "error.details.id").String(), body)
+	})
+
+	t.Run("case=redirects browsers without a session to the login ui", func(t *testing.T) {
+		res, body := do(t, noRedirectClient, "/default", "text/html")
+		assert.Equal(t, http.StatusSeeOther, res.StatusCode, body)
+		assert.Equal(t, "https://www.ory.sh/login", res.Header.Get("Location"))
+	})
+
+	t.Run("case=rejects sessions below the minimum aal", func(t *testing.T) {
+		res, body := do(t, sessionClient, "/aal2", "application/json")
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, body)
+		assert.Equal(t, string(text.ErrorCodeSessionAALInsufficient), gjson.Get(body, "error.details.id").String(), body)
+	})
+
+	t.Run("case=uses the custom unauthenticated handler", func(t *testing.T) {
+		res, _ := do(t, noRedirectClient, "/custom", "text/html")
+		assert.Equal(t, http.StatusTeapot, res.StatusCode)
+
+		res, body := do(t, sessionClient, "/custom", "application/json")
+		assert.Equal(t, http.StatusOK, res.StatusCode, body)
+	})
+}
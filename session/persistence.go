@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/gofrs/uuid"
@@ -45,6 +46,13 @@ type Persister interface {
 
 	// RevokeSessionByToken marks a session inactive with the given token.
 	RevokeSessionByToken(ctx context.Context, token string) error
+
+	// ExtendSession sets the expiry of the session with the given ID.
+	ExtendSession(ctx context.Context, sid uuid.UUID, expiresAt time.Time) error
+
+	// ConsumeFirstLogin unsets the first login flag of the session with the given ID. It returns true if the
+	// flag was set, so that only one of several concurrent callers sees it.
+	ConsumeFirstLogin(ctx context.Context, sid uuid.UUID) (bool, error)
 }
 
 func TestPersister(conf *config.Provider, p interface {
@@ -64,6 +72,7 @@ func TestPersister(conf *config.Provider, p interface {
 			var expected Session
 			require.NoError(t, faker.FakeData(&expected))
 			expected.Active = true
+			expected.Impersonator = "support@example.org"
 			require.NoError(t, p.CreateIdentity(ctx, expected.Identity))
 
 			assert.Equal(t, uuid.Nil, expected.ID)
@@ -78,6 +87,7 @@ func TestPersister(conf *config.Provider, p interface {
 				assert.Equal(t, expected.ID, actual.ID)
 				assert.Equal(t, expected.Active, actual.Active)
 				assert.Equal(t, expected.Token, actual.Token)
+				assert.Equal(t, expected.Impersonator, actual.Impersonator)
 				assert.EqualValues(t, expected.ExpiresAt.Unix(), actual.ExpiresAt.Unix())
 				assert.Equal(t, expected.AuthenticatedAt.Unix(), actual.AuthenticatedAt.Unix())
 				assert.Equal(t, expected.IssuedAt.Unix(), actual.IssuedAt.Unix())
@@ -132,6 +142,38 @@ func TestPersister(conf *config.Provider, p interface {
 			assert.False(t, actual.Active)
 		})
 
+		t.Run("case=extend session", func(t *testing.T) {
+			var expected Session
+			require.NoError(t, faker.FakeData(&expected))
+			require.NoError(t, p.CreateIdentity(ctx, expected.Identity))
+			require.NoError(t, p.CreateSession(ctx, &expected))
+
+			expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+			require.NoError(t, p.ExtendSession(ctx, expected.ID, expiresAt))
+
+			actual, err := p.GetSession(ctx, expected.ID)
+			require.NoError(t, err)
+			assert.EqualValues(t, expiresAt.Unix(), actual.ExpiresAt.Unix())
+		})
+
+		t.Run("case=consume first login", func(t *testing.T) {
+			var expected Session
+			require.NoError(t, faker.FakeData(&expected))
+			expected.FirstLogin = true
+			require.NoError(t, p.CreateIdentity(ctx, expected.Identity))
+			require.NoError(t, p.CreateSession(ctx, &expected))
+
+			for _, consumed := range []bool{true, false} {
+				actual, err := p.ConsumeFirstLogin(ctx, expected.ID)
+				require.NoError(t, err)
+				assert.Equal(t, consumed, actual)
+			}
+
+			actual, err := p.GetSession(ctx, expected.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.FirstLogin)
+		})
+
 		t.Run("case=delete session for", func(t *testing.T) {
 			var expected1 Session
 			var expected2 Session
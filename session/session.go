@@ -2,6 +2,7 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ory/kratos/corp"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/ory/x/randx"
 
+	"github.com/ory/kratos/geoip"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/x"
 )
@@ -33,6 +35,26 @@ type Session struct {
 	// required: true
 	Identity *identity.Identity `json:"identity" faker:"identity" db:"-" belongs_to:"identities" fk_id:"IdentityID"`
 
+	// FirstLogin is set if the session was issued on the identity's first login. Depending on
+	// `session.first_login_flag`, it is only set for the first request which checks the session.
+	FirstLogin bool `json:"first_login" faker:"-" db:"first_login"`
+
+	// Impersonator is set if the session was issued by an administrator using `POST /sessions/impersonate`
+	// on the admin API. It identifies the administrator and can be used to show that the session is
+	// being impersonated.
+	Impersonator string `json:"impersonator,omitempty" faker:"-" db:"impersonator"`
+
+	// Country is the ISO 3166-1 country code of the client's IP address when the session was issued. It is
+	// only set if `session.geolocation` is enabled.
+	Country string `json:"country,omitempty" faker:"-" db:"country"`
+
+	// Region is the ISO 3166-2 subdivision code of the client's IP address when the session was issued. It is
+	// only set if `session.geolocation` is enabled.
+	Region string `json:"region,omitempty" faker:"-" db:"region"`
+
+	// Claims contains the custom claims computed by the Jsonnet mapper located at `session.claims.mapper_url`.
+	Claims json.RawMessage `json:"claims,omitempty" faker:"-" db:"-"`
+
 	// IdentityID is a helper struct field for gobuffalo.pop.
 	IdentityID uuid.UUID `json:"-" faker:"-" db:"identity_id"`
 	// CreatedAt is a helper struct field for gobuffalo.pop.
@@ -52,7 +74,7 @@ func NewActiveSession(i *identity.Identity, c interface {
 }, authenticatedAt time.Time) *Session {
 	return &Session{
 		ID:              x.NewUUID(),
-		ExpiresAt:       authenticatedAt.Add(c.SessionLifespan()),
+		ExpiresAt:       authenticatedAt.Add(i.SessionPolicy.EffectiveLifespan(c.SessionLifespan())),
 		AuthenticatedAt: authenticatedAt,
 		IssuedAt:        time.Now().UTC(),
 		Identity:        i,
@@ -62,6 +84,24 @@ func NewActiveSession(i *identity.Identity, c interface {
 	}
 }
 
+// NewImpersonationSession returns an active session for the identity which is issued on behalf of
+// the impersonator and expires after the impersonation lifespan.
+func NewImpersonationSession(i *identity.Identity, impersonator string, c interface {
+	SessionImpersonationLifespan() time.Duration
+}, now time.Time) *Session {
+	return &Session{
+		ID:              x.NewUUID(),
+		ExpiresAt:       now.Add(c.SessionImpersonationLifespan()),
+		AuthenticatedAt: now,
+		IssuedAt:        now,
+		Identity:        i,
+		IdentityID:      i.ID,
+		Token:           randx.MustString(32, randx.AlphaNum),
+		Active:          true,
+		Impersonator:    impersonator,
+	}
+}
+
 type Device struct {
 	UserAgent string      `json:"user_agent"`
 	SeenAt    []time.Time `json:"seen_at" faker:"time_types"`
@@ -72,6 +112,90 @@ func (s *Session) Declassify() *Session {
 	return s
 }
 
+// AuthenticatorAssuranceLevel is the authenticator assurance level (AAL) of a session as defined by NIST SP 800-63B.
+type AuthenticatorAssuranceLevel string
+
+const (
+	// AuthenticatorAssuranceLevel1 is reached by authenticating with a single factor.
+	AuthenticatorAssuranceLevel1 AuthenticatorAssuranceLevel = "aal1"
+	// AuthenticatorAssuranceLevel2 is reached by authenticating with two factors.
+	AuthenticatorAssuranceLevel2 AuthenticatorAssuranceLevel = "aal2"
+)
+
+// AuthenticatorAssuranceLevel returns the session's assurance level. Only the first authentication factor
+// is supported, so sessions are always at level 1.
+func (s *Session) AuthenticatorAssuranceLevel() AuthenticatorAssuranceLevel {
+	return AuthenticatorAssuranceLevel1
+}
+
+// Satisfies returns true if the level is at least the required level.
+func (l AuthenticatorAssuranceLevel) Satisfies(required AuthenticatorAssuranceLevel) bool {
+	return required == "" || l >= required
+}
+
 func (s *Session) IsActive() bool {
 	return s.Active && s.ExpiresAt.After(time.Now())
 }
+
+// SetLocation records the location of the client the session is issued to. A nil location is ignored.
+func (s *Session) SetLocation(loc *geoip.Location) {
+	if loc == nil {
+		return
+	}
+	s.Country = loc.Country
+	s.Region = loc.Region
+}
+
+// IsImpersonated returns true if the session was issued to an administrator impersonating the identity.
+func (s *Session) IsImpersonated() bool {
+	return s.Impersonator != ""
+}
+
+// SatisfiesPolicy returns false if the session is no longer valid under the session policy of its identity,
+// for example because the policy was changed after the session was issued.
+func (s *Session) SatisfiesPolicy(now time.Time) bool {
+	if s.Identity == nil || s.Identity.SessionPolicy == nil || s.IsImpersonated() {
+		return true
+	}
+
+	p := s.Identity.SessionPolicy
+	if lifespan := p.EffectiveLifespan(s.ExpiresAt.Sub(s.AuthenticatedAt)); now.After(s.AuthenticatedAt.Add(lifespan)) {
+		return false
+	}
+
+	return s.AuthenticatorAssuranceLevel().Satisfies(AuthenticatorAssuranceLevel(p.MinimumAAL()))
+}
+
+// Refresh extends the session's expiry by the session lifespan if the session expires within the
+// refresh window. The session is never extended beyond the maximum lifespan counted from the time
+// of authentication and impersonation sessions are never extended. Sessions of identities whose session
+// policy disables refreshing are not extended either. Returns true if the expiry was changed.
+func (s *Session) Refresh(c interface {
+	SessionLifespan() time.Duration
+	SessionRefreshWindow() time.Duration
+	SessionRefreshMaxLifespan() time.Duration
+}, now time.Time) bool {
+	if s.IsImpersonated() || s.ExpiresAt.Sub(now) > c.SessionRefreshWindow() {
+		return false
+	}
+
+	maxLifespan := c.SessionRefreshMaxLifespan()
+	if s.Identity != nil {
+		if s.Identity.SessionPolicy.RefreshDisabled() {
+			return false
+		}
+		maxLifespan = s.Identity.SessionPolicy.EffectiveLifespan(maxLifespan)
+	}
+
+	expiresAt := now.Add(c.SessionLifespan())
+	if max := s.AuthenticatedAt.Add(maxLifespan); expiresAt.After(max) {
+		expiresAt = max
+	}
+
+	if !expiresAt.After(s.ExpiresAt) {
+		return false
+	}
+
+	s.ExpiresAt = expiresAt
+	return true
+}
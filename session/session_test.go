@@ -1,11 +1,13 @@
 package session_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/session"
@@ -20,4 +22,96 @@ func TestSession(t *testing.T) {
 
 	assert.False(t, (&session.Session{ExpiresAt: time.Now().Add(time.Hour)}).IsActive())
 	assert.False(t, (&session.Session{Active: true}).IsActive())
+
+	t.Run("case=refresh", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionLifespan, "24h")
+		conf.MustSet(config.ViperKeySessionRefreshWindow, "1h")
+		conf.MustSet(config.ViperKeySessionRefreshMaxLifespan, "72h")
+
+		now := time.Now().UTC()
+		for k, tc := range []struct {
+			d         string
+			authAt    time.Time
+			expiresAt time.Time
+			refreshed bool
+			expected  time.Time
+		}{
+			{
+				d:         "outside of refresh window",
+				authAt:    now.Add(-time.Hour),
+				expiresAt: now.Add(time.Hour * 2),
+				expected:  now.Add(time.Hour * 2),
+			},
+			{
+				d:         "within refresh window",
+				authAt:    now.Add(-time.Hour * 23),
+				expiresAt: now.Add(time.Minute * 30),
+				refreshed: true,
+				expected:  now.Add(time.Hour * 24),
+			},
+			{
+				d:         "capped by max lifespan",
+				authAt:    now.Add(-time.Hour * 60),
+				expiresAt: now.Add(time.Minute * 30),
+				refreshed: true,
+				expected:  now.Add(time.Hour * 12),
+			},
+			{
+				d:         "max lifespan reached",
+				authAt:    now.Add(-time.Hour*72 + time.Minute*30),
+				expiresAt: now.Add(time.Minute * 30),
+				expected:  now.Add(time.Minute * 30),
+			},
+		} {
+			t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+				s := &session.Session{Active: true, AuthenticatedAt: tc.authAt, ExpiresAt: tc.expiresAt}
+				assert.Equal(t, tc.refreshed, s.Refresh(conf, now))
+				assert.Equal(t, tc.expected, s.ExpiresAt)
+			})
+		}
+	})
+
+	t.Run("case=impersonation", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionImpersonationLifespan, "15m")
+		conf.MustSet(config.ViperKeySessionRefreshWindow, "1h")
+
+		now := time.Now().UTC()
+		s := session.NewImpersonationSession(new(identity.Identity), "support@example.org", conf, now)
+		assert.True(t, s.IsActive())
+		assert.True(t, s.IsImpersonated())
+		assert.Equal(t, now.Add(time.Minute*15), s.ExpiresAt)
+
+		assert.False(t, s.Refresh(conf, now), "impersonation sessions must never be extended")
+		assert.Equal(t, now.Add(time.Minute*15), s.ExpiresAt)
+
+		assert.False(t, session.NewActiveSession(new(identity.Identity), conf, now).IsImpersonated())
+	})
+
+	t.Run("case=identity session policy", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionLifespan, "24h")
+		conf.MustSet(config.ViperKeySessionRefreshWindow, "1h")
+		conf.MustSet(config.ViperKeySessionRefreshMaxLifespan, "72h")
+
+		now := time.Now().UTC()
+		i := &identity.Identity{SessionPolicy: &identity.SessionPolicy{Lifespan: "2h"}}
+
+		s := session.NewActiveSession(i, conf, now)
+		assert.Equal(t, now.Add(time.Hour*2), s.ExpiresAt, "the policy must shorten the lifespan")
+		assert.True(t, s.SatisfiesPolicy(now))
+		assert.False(t, s.Refresh(conf, now.Add(time.Hour*2-time.Minute)), "the session must not be refreshed beyond the policy's lifespan")
+
+		i.SessionPolicy.Lifespan = "1h"
+		assert.False(t, s.SatisfiesPolicy(now.Add(time.Minute*90)), "a stricter policy must apply to existing sessions")
+
+		i.SessionPolicy.Lifespan = "96h"
+		assert.Equal(t, now.Add(time.Hour*24), session.NewActiveSession(i, conf, now).ExpiresAt, "the policy must not extend the lifespan")
+
+		i.SessionPolicy = &identity.SessionPolicy{DisableRefresh: true}
+		s = session.NewActiveSession(i, conf, now)
+		assert.False(t, s.Refresh(conf, now.Add(time.Hour*23+time.Minute*30)))
+		assert.Equal(t, now.Add(time.Hour*24), s.ExpiresAt)
+
+		i.SessionPolicy = &identity.SessionPolicy{RequiredAAL: "aal2"}
+		assert.False(t, s.SatisfiesPolicy(now), "aal1 sessions do not satisfy a policy requiring aal2")
+	})
 }
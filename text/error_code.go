@@ -0,0 +1,104 @@
+package text
+
+// ErrorCode is a stable, machine-readable identifier of an error returned by the self-service flows.
+//
+// Error codes are exposed in the `details.id` field of the error and do not change when the human-readable
+// error reason changes. Clients should use them instead of matching the error reason.
+type ErrorCode string
+
+// ErrorCodeDetailKey is the key of the error's details which contains the ErrorCode.
+const ErrorCodeDetailKey = "id"
+
+const (
+	// ErrorCodeSessionAlreadyAvailable is returned when a flow is initiated or submitted although a valid
+	// session exists and the flow can not be completed while being signed in.
+	ErrorCodeSessionAlreadyAvailable ErrorCode = "session_already_available"
+
+	// ErrorCodeSessionInactive is returned when a valid session is required but none was found.
+	ErrorCodeSessionInactive ErrorCode = "session_inactive"
+
+	// ErrorCodeSessionAALInsufficient is returned when the session's authenticator assurance level is lower
+	// than required.
+	ErrorCodeSessionAALInsufficient ErrorCode = "session_aal_insufficient"
+
+	// ErrorCodeSessionRefreshRequired is returned when the session is too old for privileged operations
+	// and the identity needs to re-authenticate.
+	ErrorCodeSessionRefreshRequired ErrorCode = "session_refresh_required"
+
+	// ErrorCodeSessionImpersonated is returned when an action which is not allowed for impersonation
+	// sessions, such as changing settings or credentials, is performed using one.
+	ErrorCodeSessionImpersonated ErrorCode = "session_impersonated"
+
+	// ErrorCodeFlowExpired is returned when a flow was submitted after it expired.
+	ErrorCodeFlowExpired ErrorCode = "flow_expired"
+
+	// ErrorCodeFlowNotFound is returned when the flow does not exist.
+	ErrorCodeFlowNotFound ErrorCode = "flow_not_found"
+
+	// ErrorCodeFlowIDMissing is returned when the flow ID query parameter is missing or malformed.
+	ErrorCodeFlowIDMissing ErrorCode = "flow_id_missing"
+
+	// ErrorCodeFlowMethodMissing is returned when the submitted method does not exist in the flow.
+	ErrorCodeFlowMethodMissing ErrorCode = "flow_method_missing"
+
+	// ErrorCodeFlowMethodDisabled is returned when the submitted method is disabled.
+	ErrorCodeFlowMethodDisabled ErrorCode = "flow_method_disabled"
+
+	// ErrorCodeSecurityCSRFViolation is returned when the anti-CSRF token is missing or invalid.
+	ErrorCodeSecurityCSRFViolation ErrorCode = "security_csrf_violation"
+
+	// ErrorCodeSecurityIdentityMismatch is returned when a flow was submitted by another identity than
+	// the one which initiated it.
+	ErrorCodeSecurityIdentityMismatch ErrorCode = "security_identity_mismatch"
+
+	// ErrorCodeBrowserFlowRequired is returned when an API flow was used in a browser.
+	ErrorCodeBrowserFlowRequired ErrorCode = "browser_flow_required"
+
+	// ErrorCodeReturnToNotWhitelisted is returned when the requested return_to URL is not whitelisted.
+	ErrorCodeReturnToNotWhitelisted ErrorCode = "return_to_not_whitelisted"
+
+	// ErrorCodeNoValueChanges is returned when a settings flow was submitted without any changes.
+	ErrorCodeNoValueChanges ErrorCode = "no_value_changes"
+
+	// ErrorCodeRecoveryTokenMissing is returned when a recovery link is missing its token.
+	ErrorCodeRecoveryTokenMissing ErrorCode = "recovery_token_missing"
+
+	// ErrorCodeOIDCProviderUnknown is returned when the requested OpenID Connect provider is not configured.
+	ErrorCodeOIDCProviderUnknown ErrorCode = "oidc_provider_unknown"
+
+	// ErrorCodeOIDCProviderError is returned when the OpenID Connect provider responded with an error.
+	ErrorCodeOIDCProviderError ErrorCode = "oidc_provider_error"
+
+	// ErrorCodeOIDCStateMismatch is returned when the OpenID Connect state parameter is missing or invalid.
+	ErrorCodeOIDCStateMismatch ErrorCode = "oidc_state_mismatch"
+
+	// ErrorCodeOIDCNonceMismatch is returned when the nonce claim of the OpenID Connect ID token is missing or invalid.
+	ErrorCodeOIDCNonceMismatch ErrorCode = "oidc_nonce_mismatch"
+
+	// ErrorCodeOIDCAPIFlowNotSupported is returned when an API flow is used with OpenID Connect.
+	ErrorCodeOIDCAPIFlowNotSupported ErrorCode = "oidc_api_flow_not_supported"
+
+	// ErrorCodeRateLimitExceeded is returned when a client sent too many requests in a given amount of time.
+	ErrorCodeRateLimitExceeded ErrorCode = "rate_limit_exceeded"
+
+	// ErrorCodeIdentityScheduledForDeletion is returned when an identity which is scheduled for deletion signs in.
+	ErrorCodeIdentityScheduledForDeletion ErrorCode = "identity_scheduled_for_deletion"
+
+	// ErrorCodeIdentityVerificationRequired is returned when an identity signs in which did not verify any of
+	// its addresses within the grace period of its schema.
+	ErrorCodeIdentityVerificationRequired ErrorCode = "identity_verification_required"
+
+	// ErrorCodeMaintenanceMode is returned when a request which modifies data is sent during maintenance.
+	ErrorCodeMaintenanceMode ErrorCode = "maintenance_mode"
+
+	// ErrorCodeDatabaseUnavailable is returned when a request is rejected because the database is unavailable.
+	ErrorCodeDatabaseUnavailable ErrorCode = "database_unavailable"
+
+	// ErrorCodeClientUpgradeRequired is returned when a native app is older than the minimum version configured
+	// for its client ID.
+	ErrorCodeClientUpgradeRequired ErrorCode = "client_upgrade_required"
+
+	// ErrorCodeInternalServerError is returned instead of the details of an unexpected error if
+	// `serve.redact_internal_errors` is enabled.
+	ErrorCodeInternalServerError ErrorCode = "internal_server_error"
+)
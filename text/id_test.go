@@ -7,7 +7,11 @@ import (
 )
 
 func TestIDs(t *testing.T) {
+	assert.Equal(t, 1000000, int(InfoValidation))
+	assert.Equal(t, 1000001, int(InfoValidationPasswordStrength))
+
 	assert.Equal(t, 1010000, int(InfoSelfServiceLogin))
+	assert.Equal(t, 1010001, int(InfoSelfServiceLoginReAuth))
 
 	assert.Equal(t, 1020000, int(InfoSelfServiceLogout))
 
@@ -36,6 +40,8 @@ func TestIDs(t *testing.T) {
 
 	assert.Equal(t, 4050000, int(ErrorValidationSettings))
 	assert.Equal(t, 4050001, int(ErrorValidationSettingsFlowExpired))
+	assert.Equal(t, 4050002, int(ErrorValidationSettingsEmailChangeCooldown))
+	assert.Equal(t, 4050003, int(ErrorValidationSettingsLinkedProvidersLimit))
 
 	assert.Equal(t, 4060000, int(ErrorValidationRecovery))
 	assert.Equal(t, 4060001, int(ErrorValidationRecoveryRetrySuccess))
@@ -6,7 +6,9 @@ import (
 )
 
 const (
-	InfoSelfServiceLogin ID = 1010000 + iota
+	InfoSelfServiceLogin                 ID = 1010000 + iota // 1010000
+	InfoSelfServiceLoginReAuth                               // 1010001
+	InfoSelfServiceLoginLinkConfirmation                     // 1010002
 )
 
 const (
@@ -14,6 +16,25 @@ const (
 	ErrorValidationLoginFlowExpired                     // 4010001
 )
 
+func NewInfoLoginReAuth() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginReAuth,
+		Type: Info,
+		Text: "Please confirm this action by verifying that it is you.",
+	}
+}
+
+func NewInfoLoginLinkConfirmation(provider string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginLinkConfirmation,
+		Type: Info,
+		Text: fmt.Sprintf("An account with the same email address exists already. Sign in to link it with %s.", provider),
+		Context: context(map[string]interface{}{
+			"provider": provider,
+		}),
+	}
+}
+
 func NewErrorValidationLoginFlowExpired(ago time.Duration) *Message {
 	return &Message{
 		ID:   ErrorValidationLoginFlowExpired,
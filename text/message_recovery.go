@@ -59,7 +59,7 @@ func NewRecoveryEmailSent() *Message {
 func NewErrorValidationRecoveryMissingRecoveryToken() error {
 	return errors.WithStack(herodot.
 		ErrBadRequest.
-		WithDetail("error_id", ErrorValidationRecoveryMissingRecoveryToken).
+		WithDetail(ErrorCodeDetailKey, ErrorCodeRecoveryTokenMissing).
 		WithReason("A recovery request was made but no recovery token was included in the request, please retry the flow."))
 }
 
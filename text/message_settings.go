@@ -13,6 +13,8 @@ const (
 const (
 	ErrorValidationSettings ID = 4050000 + iota
 	ErrorValidationSettingsFlowExpired
+	ErrorValidationSettingsEmailChangeCooldown
+	ErrorValidationSettingsLinkedProvidersLimit
 )
 
 func NewErrorValidationSettingsFlowExpired(ago time.Duration) *Message {
@@ -25,3 +27,25 @@ func NewErrorValidationSettingsFlowExpired(ago time.Duration) *Message {
 		}),
 	}
 }
+
+func NewErrorValidationSettingsEmailChangeCooldown(until time.Time) *Message {
+	return &Message{
+		ID:   ErrorValidationSettingsEmailChangeCooldown,
+		Text: fmt.Sprintf("Your email address was changed recently and can not be changed again before %s.", until.UTC().Format(time.RFC1123)),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"available_at": until,
+		}),
+	}
+}
+
+func NewErrorValidationSettingsLinkedProvidersLimit(limit int) *Message {
+	return &Message{
+		ID:   ErrorValidationSettingsLinkedProvidersLimit,
+		Text: fmt.Sprintf("You can not link more than %d social sign in providers. Please unlink a provider first.", limit),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"limit": limit,
+		}),
+	}
+}
@@ -2,6 +2,12 @@ package text
 
 import (
 	"fmt"
+	"strings"
+)
+
+const (
+	InfoValidation ID = 1000000 + iota
+	InfoValidationPasswordStrength
 )
 
 const (
@@ -13,6 +19,12 @@ const (
 	ErrorValidationPasswordPolicyViolation
 	ErrorValidationInvalidCredentials
 	ErrorValidationDuplicateCredentials
+	ErrorValidationDuplicateTrait
+	ErrorValidationPasswordNotAllowed
+	ErrorValidationTraitsTooLarge
+	ErrorValidationLoginThrottled
+	ErrorValidationSchema
+	ErrorValidationSchemaSummary
 )
 
 func NewValidationErrorGeneric(reason string) *Message {
@@ -87,3 +99,92 @@ func NewErrorValidationDuplicateCredentials() *Message {
 		Context: context(nil),
 	}
 }
+
+func NewErrorValidationDuplicateTrait(key string) *Message {
+	return &Message{
+		ID:   ErrorValidationDuplicateTrait,
+		Text: fmt.Sprintf("The provided %s is already taken.", key),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"key": key,
+		}),
+	}
+}
+
+func NewErrorValidationPasswordNotAllowed() *Message {
+	return &Message{
+		ID:      ErrorValidationPasswordNotAllowed,
+		Text:    "The password is not allowed, please choose a different one.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationTraitsTooLarge(max, actual int) *Message {
+	return &Message{
+		ID:   ErrorValidationTraitsTooLarge,
+		Text: fmt.Sprintf("The submitted data is too large, it must be at most %d bytes but is %d bytes.", max, actual),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"max_size":    max,
+			"actual_size": actual,
+		}),
+	}
+}
+
+func NewErrorValidationLoginThrottled() *Message {
+	return &Message{
+		ID:      ErrorValidationLoginThrottled,
+		Text:    "There were too many failed login attempts for this account, please try again later.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationSchema(reason, pointer, keyword string, expected, actual interface{}) *Message {
+	return &Message{
+		ID:   ErrorValidationSchema,
+		Text: reason,
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"pointer":  pointer,
+			"keyword":  keyword,
+			"expected": expected,
+			"actual":   actual,
+		}),
+	}
+}
+
+func NewErrorValidationSchemaSummary(pointers []string) *Message {
+	message := fmt.Sprintf("The submitted data is invalid, %d values did not pass validation.", len(pointers))
+	if len(pointers) == 1 {
+		message = "The submitted data is invalid, one value did not pass validation."
+	}
+
+	return &Message{
+		ID:   ErrorValidationSchemaSummary,
+		Text: message,
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"pointers": pointers,
+		}),
+	}
+}
+
+func NewInfoValidationPasswordStrength(score int, suggestions []string) *Message {
+	labels := []string{"very weak", "weak", "fair", "strong", "very strong"}
+	message := fmt.Sprintf("The password strength is %s (%d of 4).", labels[score], score)
+	if len(suggestions) > 0 {
+		message += " " + strings.Join(suggestions, " ")
+	}
+
+	return &Message{
+		ID:   InfoValidationPasswordStrength,
+		Text: message,
+		Type: Info,
+		Context: context(map[string]interface{}{
+			"score":       score,
+			"suggestions": suggestions,
+		}),
+	}
+}
@@ -0,0 +1,57 @@
+package x
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ory/x/logrusx"
+)
+
+type auditContextKey int
+
+const (
+	auditActorKey auditContextKey = iota + 1
+	auditRequestIDKey
+)
+
+// RequestIDHeader contains the ID of a request. If the client does not send it, a random ID is generated.
+const RequestIDHeader = "X-Request-Id"
+
+// WithAuditActor returns a context which identifies the caller of the admin API, for example an admin API key.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey, actor)
+}
+
+// AuditActor returns the caller of the admin API or an empty string if unknown.
+func AuditActor(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey).(string)
+	return actor
+}
+
+// AuditRequestID returns the ID of the request or an empty string if unknown.
+func AuditRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(auditRequestIDKey).(string)
+	return id
+}
+
+// AuditContext adds the caller of the admin API and the ID of the request to the audit log entry if they are known.
+func AuditContext(ctx context.Context, l *logrusx.Logger) *logrusx.Logger {
+	if actor := AuditActor(ctx); actor != "" {
+		l = l.WithField("actor", actor)
+	}
+	if id := AuditRequestID(ctx); id != "" {
+		l = l.WithField("request_id", id)
+	}
+	return l
+}
+
+// RequestIDMiddleware adds the request ID to the request's context and the response headers.
+func RequestIDMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(RequestIDHeader)
+	if len(id) == 0 {
+		id = NewUUID().String()
+	}
+
+	w.Header().Set(RequestIDHeader, id)
+	next(w, r.WithContext(context.WithValue(r.Context(), auditRequestIDKey, id)))
+}
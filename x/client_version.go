@@ -0,0 +1,127 @@
+package x
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/text"
+)
+
+var ErrClientUpgradeRequired = herodot.DefaultError{
+	CodeField:    http.StatusUpgradeRequired,
+	StatusField:  http.StatusText(http.StatusUpgradeRequired),
+	ErrorField:   "This version of the app is no longer supported, please upgrade it.",
+	DetailsField: map[string]interface{}{text.ErrorCodeDetailKey: text.ErrorCodeClientUpgradeRequired},
+}
+
+type (
+	clientVersionGateDependencies interface {
+		config.Providers
+		LoggingProvider
+		WriterProvider
+	}
+	ClientVersionGateProvider interface {
+		ClientVersionGate() *ClientVersionGate
+	}
+
+	// ClientVersionGate rejects self-service requests of native apps which are older than the minimum version
+	// configured in `selfservice.minimum_client_versions` for their client ID.
+	ClientVersionGate struct {
+		d clientVersionGateDependencies
+	}
+)
+
+func NewClientVersionGate(d clientVersionGateDependencies) *ClientVersionGate {
+	return &ClientVersionGate{d: d}
+}
+
+func (g *ClientVersionGate) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !strings.HasPrefix(r.URL.Path, "/self-service/") {
+		next(w, r)
+		return
+	}
+
+	conf := g.d.Configuration(r.Context()).SelfServiceMinimumClientVersions()
+	clientID := r.Header.Get(conf.ClientIDHeader)
+	version := r.Header.Get(conf.ClientVersionHeader)
+	if clientID == "" && version == "" {
+		next(w, r)
+		return
+	}
+
+	var minimum string
+	for _, c := range conf.Clients {
+		if c.ID == clientID {
+			minimum = c.MinimumVersion
+			break
+		}
+	}
+
+	current, ok := parseClientVersion(version)
+	if minimum == "" || !ok {
+		if conf.Default == config.MinimumClientVersionsDefaultWarn {
+			g.d.Logger().
+				WithRequest(r).
+				WithField("client_id", clientID).
+				WithField("client_version", version).
+				Warn("A request was sent by an unknown client or without a valid client version.")
+			w.Header().Add("Warning", `299 - "Unknown client or client version"`)
+		}
+		next(w, r)
+		return
+	}
+
+	// The configuration schema guarantees that the minimum version is valid.
+	required, _ := parseClientVersion(minimum)
+	if compareClientVersions(current, required) < 0 {
+		g.d.Writer().WriteError(w, r, errors.WithStack(ErrClientUpgradeRequired.
+			WithReasonf("Client %s must be upgraded to version %s or newer.", clientID, minimum)))
+		return
+	}
+
+	next(w, r)
+}
+
+// parseClientVersion parses dot-separated numeric versions such as `2.4.0`.
+func parseClientVersion(version string) ([]int, bool) {
+	if version == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(version, ".")
+	out := make([]int, len(parts))
+	for k, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		out[k] = n
+	}
+	return out, true
+}
+
+// compareClientVersions returns -1, 0, or 1 if a is lower than, equal to, or greater than b. Missing
+// components count as zero so that `2.4` equals `2.4.0`.
+func compareClientVersions(a, b []int) int {
+	for k := 0; k < len(a) || k < len(b); k++ {
+		var x, y int
+		if k < len(a) {
+			x = a[k]
+		}
+		if k < len(b) {
+			y = b[k]
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/text"
+)
+
+func TestClientVersionGate(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsClients, []map[string]interface{}{
+		{"id": "ios", "minimum_version": "2.4"},
+	})
+
+	do := func(t *testing.T, path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		for k := range header {
+			r.Header.Set(k, header.Get(k))
+		}
+		w := httptest.NewRecorder()
+		reg.ClientVersionGate().ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w
+	}
+
+	client := func(id, version string) http.Header {
+		h := http.Header{}
+		h.Set("X-Kratos-Client-Id", id)
+		h.Set("X-Kratos-Client-Version", version)
+		return h
+	}
+
+	t.Run("case=allows requests without headers", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, "/self-service/login/api", http.Header{}).Code)
+	})
+
+	t.Run("case=ignores other paths", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, "/sessions/whoami", client("ios", "1.0.0")).Code)
+	})
+
+	t.Run("case=rejects outdated clients", func(t *testing.T) {
+		for _, v := range []string{"1.9.9", "2.3", "2.3.99"} {
+			w := do(t, "/self-service/login/api", client("ios", v))
+			assert.Equal(t, http.StatusUpgradeRequired, w.Code, v)
+			assert.Equal(t, string(text.ErrorCodeClientUpgradeRequired), gjson.Get(w.Body.String(), "error.details.id").String(), "%s", w.Body.String())
+		}
+	})
+
+	t.Run("case=allows up-to-date clients", func(t *testing.T) {
+		for _, v := range []string{"2.4", "2.4.0", "2.10", "3"} {
+			assert.Equal(t, http.StatusNoContent, do(t, "/self-service/login/api", client("ios", v)).Code, v)
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+	}{
+		{name: "unknown client", header: client("android", "1.0")},
+		{name: "missing version", header: client("ios", "")},
+		{name: "malformed version", header: client("ios", "2.4-beta")},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsDefault, config.MinimumClientVersionsDefaultAllow)
+			w := do(t, "/self-service/login/api", tc.header)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, w.Header().Get("Warning"))
+
+			conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsDefault, config.MinimumClientVersionsDefaultWarn)
+			w = do(t, "/self-service/login/api", tc.header)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.NotEmpty(t, w.Header().Get("Warning"))
+		})
+	}
+
+	t.Run("case=uses configured headers", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsClientIDHeader, "X-App-Id")
+		conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsVersionHeader, "X-App-Version")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsClientIDHeader, "X-Kratos-Client-Id")
+			conf.MustSet(config.ViperKeySelfServiceMinimumClientVersionsVersionHeader, "X-Kratos-Client-Version")
+		})
+
+		h := http.Header{}
+		h.Set("X-App-Id", "ios")
+		h.Set("X-App-Version", "1.0")
+		assert.Equal(t, http.StatusUpgradeRequired, do(t, "/self-service/settings/api", h).Code)
+	})
+}
@@ -0,0 +1,235 @@
+package x
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/negroni"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	compressorDependencies interface {
+		config.Providers
+	}
+	CompressorProvider interface {
+		PublicCompressor() *Compressor
+	}
+
+	// Compressor compresses responses using gzip if enabled by `serve.public.compression` and accepted by the client.
+	Compressor struct {
+		d compressorDependencies
+	}
+)
+
+// uncompressibleContentTypes are content type prefixes of responses which are already compressed.
+var uncompressibleContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/pdf",
+}
+
+func NewCompressor(d compressorDependencies) *Compressor {
+	return &Compressor{d: d}
+}
+
+// acceptsGzip returns true if the Accept-Encoding header allows gzip. An explicit gzip entry takes precedence
+// over the wildcard.
+func acceptsGzip(header string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if coding == "gzip" {
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// varyAcceptEncoding adds Accept-Encoding to the Vary header so that caches do not serve a compressed response
+// to clients which do not support it. Handlers may have set the Vary header already, for example because the
+// response depends on the Accept header.
+func varyAcceptEncoding(h http.Header) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if f := strings.TrimSpace(field); f == "*" || strings.EqualFold(f, "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	h.Add("Vary", "Accept-Encoding")
+}
+
+func (c *Compressor) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	conf := c.d.Configuration(r.Context())
+	if !conf.PublicCompressionEnabled() {
+		next(w, r)
+		return
+	}
+
+	if !acceptsGzip(r.Header.Get("Accept-Encoding")) || r.Method == http.MethodHead {
+		if nw, ok := w.(negroni.ResponseWriter); ok {
+			nw.Before(func(rw negroni.ResponseWriter) { varyAcceptEncoding(rw.Header()) })
+		} else {
+			varyAcceptEncoding(w.Header())
+		}
+		next(w, r)
+		return
+	}
+
+	cw := &compressWriter{ResponseWriter: w, minSize: conf.PublicCompressionMinSize()}
+	defer cw.Close()
+
+	// Other middlewares expect a negroni.ResponseWriter, for example to log the status code.
+	next(negroni.NewResponseWriter(cw), r)
+}
+
+// compressWriter buffers the response until it is known whether it is large enough to be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	minSize int
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize && len(w.buf) > 0 {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	if w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(w.Header().Get("Content-Type"))
+	for _, prefix := range uncompressibleContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decide writes the status code and the buffered body. If compress is true and the response is eligible,
+// the remaining response is compressed.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	varyAcceptEncoding(w.Header())
+	if w.Header().Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Content sniffing no longer works once the body is compressed.
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && w.shouldCompress() {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.minSize && len(w.buf) > 0)
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes responses which were too small to be compressed and finishes the compressed stream.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return nil
+		}
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
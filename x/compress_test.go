@@ -0,0 +1,126 @@
+package x_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+)
+
+func TestCompressor(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	large := strings.Repeat(`{"type":"input","name":"traits.email"}`, 100)
+
+	do := func(t *testing.T, method, acceptEncoding string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/self-service/login/flows", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		reg.PublicCompressor().ServeHTTP(negroni.NewResponseWriter(w), r, h)
+		return w
+	}
+
+	writeJSON := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	t.Run("case=passes through if disabled", func(t *testing.T) {
+		res := do(t, "GET", "gzip", writeJSON(large))
+		assert.Empty(t, res.Header().Get("Content-Encoding"))
+		assert.Empty(t, res.Header().Get("Vary"))
+		assert.Equal(t, large, res.Body.String())
+	})
+
+	conf.MustSet(config.ViperKeyPublicCompressionEnabled, true)
+	conf.MustSet(config.ViperKeyPublicCompressionMinSize, 1024)
+
+	t.Run("case=compresses large responses", func(t *testing.T) {
+		res := do(t, "GET", "deflate, gzip;q=0.8", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			// Write in chunks smaller than the threshold to ensure the buffered data is not lost.
+			for i := 0; i < 100; i++ {
+				_, _ = w.Write([]byte(`{"type":"input","name":"traits.email"}`))
+			}
+		})
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+		gr, err := gzip.NewReader(res.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(gr)
+		require.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	for _, tc := range []struct {
+		d              string
+		method         string
+		acceptEncoding string
+		h              http.HandlerFunc
+		expectBody     string
+	}{
+		{d: "client does not accept gzip", acceptEncoding: "", h: writeJSON(large), expectBody: large},
+		{d: "client rejects gzip", acceptEncoding: "*, gzip;q=0", h: writeJSON(large), expectBody: large},
+		{d: "response is too small", acceptEncoding: "gzip", h: writeJSON(`{"id":"1234"}`), expectBody: `{"id":"1234"}`},
+		{d: "head requests", method: "HEAD", acceptEncoding: "gzip", h: writeJSON(large), expectBody: large},
+		{d: "response is already compressed", acceptEncoding: "gzip", h: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(large))
+		}, expectBody: large},
+		{d: "response has no body", acceptEncoding: "gzip", h: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}},
+	} {
+		t.Run("case=does not compress if "+tc.d, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = "GET"
+			}
+
+			res := do(t, method, tc.acceptEncoding, tc.h)
+			assert.Empty(t, res.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+			assert.Equal(t, tc.expectBody, res.Body.String())
+		})
+	}
+
+	t.Run("case=keeps the vary header of content negotiation", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyPublicCompressionMinSize, 0)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyPublicCompressionMinSize, 1024)
+		})
+
+		for _, acceptEncoding := range []string{"gzip", "identity"} {
+			t.Run("accept-encoding="+acceptEncoding, func(t *testing.T) {
+				res := do(t, "GET", acceptEncoding, func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Vary", "Accept")
+					reg.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The flow is invalid.")))
+				})
+
+				assert.Equal(t, http.StatusBadRequest, res.Code)
+				assert.ElementsMatch(t, []string{"Accept", "Accept-Encoding"}, res.Header().Values("Vary"))
+			})
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package x
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// CookieChunkSize is the maximum length of a single cookie value. Browsers limit cookies to 4096 bytes
+// including the cookie's name and attributes.
+const CookieChunkSize = 3800
+
+var _ sessions.Store = new(ChunkedCookieStore)
+
+// ChunkedCookieStore is a sessions.CookieStore which splits encoded values exceeding CookieChunkSize
+// across numbered cookies (e.g. `name`, `name_1`, `name_2`) and reassembles them when reading.
+type ChunkedCookieStore struct {
+	*sessions.CookieStore
+	maxChunks int
+
+	// Partitioned sets the Partitioned attribute (CHIPS) on cookies which are Secure and SameSite=None.
+	Partitioned bool
+}
+
+func NewChunkedCookieStore(maxChunks int, keyPairs ...[]byte) *ChunkedCookieStore {
+	if maxChunks < 1 {
+		maxChunks = 1
+	}
+
+	cs := sessions.NewCookieStore(keyPairs...)
+	for _, codec := range cs.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			// The length is limited by the number of chunks instead.
+			sc.MaxLength(0)
+		}
+	}
+
+	return &ChunkedCookieStore{CookieStore: cs, maxChunks: maxChunks}
+}
+
+func chunkName(name string, k int) string {
+	if k == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", name, k)
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *ChunkedCookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+func (s *ChunkedCookieStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	var value string
+	for k := 0; k < s.maxChunks; k++ {
+		c, err := r.Cookie(chunkName(name, k))
+		if err != nil {
+			break
+		}
+		value += c.Value
+	}
+
+	if len(value) == 0 {
+		return session, nil
+	}
+
+	err := securecookie.DecodeMulti(name, value, &session.Values, s.Codecs...)
+	if err == nil {
+		session.IsNew = false
+	}
+	return session, err
+}
+
+// Save adds the chunked session cookies to the response and removes chunks which are no longer needed.
+func (s *ChunkedCookieStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	var value string
+	if session.Options.MaxAge >= 0 {
+		encoded, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value = encoded
+	}
+
+	chunks := []string{""}
+	if len(value) > 0 {
+		chunks = make([]string, 0, len(value)/CookieChunkSize+1)
+		for len(value) > CookieChunkSize {
+			chunks = append(chunks, value[:CookieChunkSize])
+			value = value[CookieChunkSize:]
+		}
+		chunks = append(chunks, value)
+	}
+
+	if len(chunks) > s.maxChunks {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+			"The cookie %s requires %d chunks but at most %d are allowed. Increase the value of session.cookie.max_chunks.",
+			session.Name(), len(chunks), s.maxChunks))
+	}
+
+	for k, chunk := range chunks {
+		s.setCookie(w, sessions.NewCookie(chunkName(session.Name(), k), chunk, session.Options))
+	}
+
+	expired := *session.Options
+	expired.MaxAge = -1
+	for k := len(chunks); k < s.maxChunks; k++ {
+		if _, err := r.Cookie(chunkName(session.Name(), k)); err == nil {
+			s.setCookie(w, sessions.NewCookie(chunkName(session.Name(), k), "", &expired))
+		}
+	}
+
+	return nil
+}
+
+// setCookie works like http.SetCookie but appends the Partitioned attribute, which net/http does not
+// support, if enabled.
+func (s *ChunkedCookieStore) setCookie(w http.ResponseWriter, c *http.Cookie) {
+	v := c.String()
+	if v == "" {
+		return
+	}
+
+	if s.Partitioned && c.Secure && c.SameSite == http.SameSiteNoneMode {
+		v += "; Partitioned"
+	}
+	w.Header().Add("Set-Cookie", v)
+}
@@ -1,15 +1,20 @@
 package x
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/sessions"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
 )
 
 func TestSession(t *testing.T) {
@@ -82,3 +87,99 @@ func TestSession(t *testing.T) {
 		mr(t, id)
 	})
 }
+
+func TestChunkedCookieStore(t *testing.T) {
+	const sid = "test_chunked_session"
+
+	s := NewChunkedCookieStore(3, []byte("cyan cat walking over keyboard"))
+	save := func(t *testing.T, r *http.Request, value string) (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		return w, SessionPersistValues(w, r, s, sid, map[string]interface{}{"value": value})
+	}
+
+	read := func(t *testing.T, cookies []*http.Cookie) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		for _, c := range cookies {
+			if c.MaxAge >= 0 {
+				r.AddCookie(c)
+			}
+		}
+		return SessionGetStringOr(r, s, sid, "value", "")
+	}
+
+	t.Run("case=small values use a single cookie", func(t *testing.T) {
+		w, err := save(t, httptest.NewRequest("GET", "/", nil), "foo")
+		require.NoError(t, err)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, sid, cookies[0].Name)
+		assert.Equal(t, "foo", read(t, cookies))
+	})
+
+	// The encoded value is roughly 1.8 times larger than the raw value.
+	large := strings.Repeat("a", CookieChunkSize)
+	var chunked []*http.Cookie
+	t.Run("case=large values are split across cookies", func(t *testing.T) {
+		w, err := save(t, httptest.NewRequest("GET", "/", nil), large)
+		require.NoError(t, err)
+
+		chunked = w.Result().Cookies()
+		require.Len(t, chunked, 2)
+		assert.Equal(t, []string{sid, sid + "_1"}, []string{chunked[0].Name, chunked[1].Name})
+		for _, c := range chunked {
+			assert.True(t, len(c.Value) <= CookieChunkSize)
+		}
+		assert.Equal(t, large, read(t, chunked))
+	})
+
+	t.Run("case=stale chunks are removed", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		for _, c := range chunked {
+			r.AddCookie(c)
+		}
+
+		w, err := save(t, r, "foo")
+		require.NoError(t, err)
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 2)
+		assert.Equal(t, sid+"_1", cookies[1].Name)
+		assert.Equal(t, -1, cookies[1].MaxAge)
+		assert.Equal(t, "foo", read(t, cookies))
+	})
+
+	t.Run("case=fails if the value requires too many chunks", func(t *testing.T) {
+		_, err := save(t, httptest.NewRequest("GET", "/", nil), strings.Repeat("a", CookieChunkSize*4))
+		var he *herodot.DefaultError
+		require.True(t, errors.As(err, &he), "%+v", err)
+		assert.Contains(t, he.Reason(), "session.cookie.max_chunks")
+	})
+
+	t.Run("case=partitioned", func(t *testing.T) {
+		s := NewChunkedCookieStore(3, []byte("cyan cat walking over keyboard"))
+		s.Partitioned = true
+
+		for _, tc := range []struct {
+			secure      bool
+			sameSite    http.SameSite
+			partitioned bool
+		}{
+			{secure: true, sameSite: http.SameSiteNoneMode, partitioned: true},
+			{secure: false, sameSite: http.SameSiteNoneMode},
+			{secure: true, sameSite: http.SameSiteLaxMode},
+		} {
+			t.Run(fmt.Sprintf("secure=%t/same_site=%d", tc.secure, tc.sameSite), func(t *testing.T) {
+				s.Options.Secure = tc.secure
+				s.Options.SameSite = tc.sameSite
+
+				w := httptest.NewRecorder()
+				require.NoError(t, SessionPersistValues(w, httptest.NewRequest("GET", "/", nil), s, sid, map[string]interface{}{"value": "foo"}))
+
+				header := w.Header().Get("Set-Cookie")
+				assert.Equal(t, tc.partitioned, strings.HasSuffix(header, "; Partitioned"), header)
+				assert.Equal(t, "foo", read(t, w.Result().Cookies()))
+			})
+		}
+	})
+}
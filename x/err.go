@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	"github.com/ory/herodot"
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/kratos/text"
 )
 
 var PseudoPanic = herodot.DefaultError{
@@ -14,6 +17,9 @@ var PseudoPanic = herodot.DefaultError{
 	CodeField:   http.StatusConflict,
 }
 
+// CorrelationIDDetailKey is the key of the error's details which contains the ID the redacted error was logged with.
+const CorrelationIDDetailKey = "correlation_id"
+
 type StatusCodeCarrier interface {
 	StatusCode() int
 }
@@ -25,3 +31,28 @@ func RecoverStatusCode(err error, fallback int) int {
 	}
 	return fallback
 }
+
+// RedactInternalError replaces an internal server error with a generic error and logs the original error. Both share a correlation ID, which is the request ID if the request has one. Errors without
+// a status code are internal server errors. Other errors are returned unchanged.
+func RedactInternalError(l *logrusx.Logger, r *http.Request, err error) error {
+	if RecoverStatusCode(err, http.StatusInternalServerError) != http.StatusInternalServerError {
+		return err
+	}
+
+	id := AuditRequestID(r.Context())
+	if len(id) == 0 {
+		id = NewUUID().String()
+	}
+
+	l.WithRequest(r).WithError(err).WithField(CorrelationIDDetailKey, id).
+		Error("An internal server error occurred, its details were not returned to the client.")
+	return &herodot.DefaultError{
+		CodeField:   http.StatusInternalServerError,
+		StatusField: http.StatusText(http.StatusInternalServerError),
+		ErrorField:  "An internal server error occurred, please contact the system administrator and provide the correlation ID.",
+		DetailsField: map[string]interface{}{
+			text.ErrorCodeDetailKey: text.ErrorCodeInternalServerError,
+			CorrelationIDDetailKey:  id,
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+func TestRedactInternalErrors(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyRedactInternalErrors, true)
+
+	internalErr := errors.New("pq: connection to 10.0.0.3:5432 refused")
+	write := func(t *testing.T, err error, requestID string) gjson.Result {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/identities", nil)
+		r.Header.Set(x.RequestIDHeader, requestID)
+		x.RequestIDMiddleware(w, r, func(w http.ResponseWriter, r *http.Request) {
+			reg.Writer().WriteError(w, r, err)
+		})
+		return gjson.ParseBytes(w.Body.Bytes())
+	}
+
+	t.Run("case=keeps details in dev mode", func(t *testing.T) {
+		res := write(t, internalErr, "")
+		assert.Contains(t, res.Raw, "10.0.0.3")
+	})
+
+	conf.MustSet("dev", false)
+
+	t.Run("case=redacts internal errors", func(t *testing.T) {
+		for _, err := range []error{internalErr, herodot.ErrInternalServerError.WithReason("pq: connection to 10.0.0.3:5432 refused")} {
+			res := write(t, err, "request-1")
+			assert.NotContains(t, res.Raw, "10.0.0.3")
+			assert.EqualValues(t, http.StatusInternalServerError, res.Get("error.code").Int(), "%s", res.Raw)
+			assert.Equal(t, string(text.ErrorCodeInternalServerError), res.Get("error.details.id").String(), "%s", res.Raw)
+			assert.Equal(t, "request-1", res.Get("error.details."+x.CorrelationIDDetailKey).String(), "%s", res.Raw)
+		}
+
+		assert.NotEmpty(t, write(t, internalErr, "").Get("error.details."+x.CorrelationIDDetailKey).String())
+	})
+
+	t.Run("case=does not redact other errors", func(t *testing.T) {
+		res := write(t, herodot.ErrBadRequest.WithReason("The traits are invalid."), "")
+		assert.Equal(t, "The traits are invalid.", res.Get("error.reason").String(), "%s", res.Raw)
+	})
+}
@@ -0,0 +1,199 @@
+package x
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type HTTPClientProvider interface {
+	HTTPClient() *http.Client
+}
+
+// NewHTTPClient returns an HTTP client for outbound requests which retries idempotent requests
+// according to the `http_client` configuration.
+func NewHTTPClient(c *config.Provider) *http.Client {
+	return &http.Client{
+		Transport: &RetryRoundTripper{
+			RoundTripper: newHTTPTransport(c),
+			MaxAttempts:  c.HTTPClientRetryMaxAttempts(),
+			BaseDelay:    c.HTTPClientRetryBaseDelay(),
+			MaxDelay:     c.HTTPClientRetryMaxDelay(),
+			Timeout:      c.HTTPClientTimeout(),
+		},
+	}
+}
+
+// newHTTPTransport returns the default transport, which honors the proxy environment variables, unless a proxy is
+// configured in `http_client.proxy`.
+func newHTTPTransport(c *config.Provider) http.RoundTripper {
+	proxy := c.HTTPClientProxyURL()
+	if proxy == nil {
+		return http.DefaultTransport
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxyFunc(proxy, c.HTTPClientNoProxy())
+	return t
+}
+
+// proxyFunc sends all requests through the proxy except for requests to destinations matching an entry of noProxy.
+func proxyFunc(proxy *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(r *http.Request) (*url.URL, error) {
+		if bypassProxy(r.URL, noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+}
+
+// bypassProxy returns true if the URL matches an entry of noProxy. An entry is either "*", a CIDR range, an IP
+// address, or a host name which also matches its subdomains. IP addresses and host names can be followed by a port.
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if entryIP := net.ParseIP(strings.Trim(entryHost, "[]")); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if entryHost != "" && (host == entryHost || strings.HasSuffix(host, "."+entryHost)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RetryRoundTripper retries idempotent requests which failed because of a network error or
+// because the server responded with HTTP 429 or 5xx. Requests with other methods are sent once.
+type RetryRoundTripper struct {
+	RoundTripper http.RoundTripper
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+
+	// Timeout is the timeout of a single attempt. No timeout is applied if it is zero.
+	Timeout time.Duration
+}
+
+func (rt *RetryRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !isIdempotent(r.Method) || rt.MaxAttempts <= 1 {
+		return rt.attempt(r)
+	}
+
+	var (
+		res *http.Response
+		err error
+	)
+	for attempt := 1; ; attempt++ {
+		res, err = rt.attempt(r)
+		if !shouldRetry(res, err) || attempt >= rt.MaxAttempts {
+			return res, err
+		}
+
+		if res != nil {
+			_ = res.Body.Close()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(rt.backoff(attempt)):
+		}
+	}
+}
+
+func (rt *RetryRoundTripper) attempt(r *http.Request) (*http.Response, error) {
+	if rt.Timeout <= 0 {
+		return rt.RoundTripper.RoundTrip(r)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), rt.Timeout)
+	res, err := rt.RoundTripper.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The context must stay alive until the response body has been consumed.
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// backoff returns the exponential delay before the next attempt with up to 50% jitter.
+func (rt *RetryRoundTripper) backoff(attempt int) time.Duration {
+	delay := rt.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > rt.MaxDelay {
+		delay = rt.MaxDelay
+	}
+
+	if half := int64(delay / 2); half > 0 {
+		/* #nosec G404 jitter does not need to be cryptographically secure */
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,174 @@
+package x
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+func TestRetryRoundTripper(t *testing.T) {
+	var calls int32
+	var failures int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := &http.Client{Transport: &RetryRoundTripper{
+		RoundTripper: http.DefaultTransport,
+		MaxAttempts:  3,
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     time.Millisecond * 10,
+		Timeout:      time.Second,
+	}}
+
+	for _, tc := range []struct {
+		d           string
+		method      string
+		failures    int32
+		expectCode  int
+		expectCalls int32
+	}{
+		{d: "succeeds without retry", method: "GET", failures: 0, expectCode: http.StatusOK, expectCalls: 1},
+		{d: "succeeds after retries", method: "GET", failures: 2, expectCode: http.StatusOK, expectCalls: 3},
+		{d: "gives up after max attempts", method: "GET", failures: 5, expectCode: http.StatusServiceUnavailable, expectCalls: 3},
+		{d: "does not retry non-idempotent requests", method: "POST", failures: 2, expectCode: http.StatusServiceUnavailable, expectCalls: 1},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			atomic.StoreInt32(&failures, tc.failures)
+
+			req, err := http.NewRequest(tc.method, ts.URL, strings.NewReader(""))
+			require.NoError(t, err)
+
+			res, err := c.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, tc.expectCode, res.StatusCode)
+			assert.Equal(t, tc.expectCalls, atomic.LoadInt32(&calls))
+		})
+	}
+
+	t.Run("case=applies timeout per attempt", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond * 100)
+		}))
+		defer slow.Close()
+
+		c := &http.Client{Transport: &RetryRoundTripper{
+			RoundTripper: http.DefaultTransport,
+			MaxAttempts:  2,
+			BaseDelay:    time.Millisecond,
+			MaxDelay:     time.Millisecond,
+			Timeout:      time.Millisecond * 10,
+		}}
+
+		_, err := c.Get(slow.URL)
+		require.Error(t, err)
+	})
+}
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".internal.example.com", "10.0.0.0/8", "192.168.1.1", "hydra:4445", "[::1]"}
+	for k, tc := range []struct {
+		u      string
+		bypass bool
+	}{
+		{u: "http://localhost/foo", bypass: true},
+		{u: "http://localhost:4433/foo", bypass: true},
+		{u: "https://internal.example.com", bypass: true},
+		{u: "https://auth.internal.example.com", bypass: true},
+		{u: "https://notinternal.example.com", bypass: false},
+		{u: "http://10.1.2.3:8080", bypass: true},
+		{u: "http://11.1.2.3", bypass: false},
+		{u: "http://192.168.1.1", bypass: true},
+		{u: "http://192.168.1.2", bypass: false},
+		{u: "http://hydra:4445/oauth2/token", bypass: true},
+		{u: "http://hydra:4444/oauth2/token", bypass: false},
+		{u: "http://[::1]:4433", bypass: true},
+		{u: "https://api.pwnedpasswords.com/range/ABCDE", bypass: false},
+	} {
+		assert.Equal(t, tc.bypass, bypassProxy(urlx.ParseOrPanic(tc.u), noProxy), "%d: %s", k, tc.u)
+	}
+
+	assert.True(t, bypassProxy(urlx.ParseOrPanic("https://www.ory.sh"), []string{"*"}))
+	assert.False(t, bypassProxy(urlx.ParseOrPanic("https://www.ory.sh"), nil))
+}
+
+func TestNewHTTPClientWithProxy(t *testing.T) {
+	var proxyAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer target.Close()
+
+	conf := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	get := func(t *testing.T) string {
+		res, err := NewHTTPClient(conf).Get(target.URL)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("case=sends requests directly if no proxy is configured", func(t *testing.T) {
+		assert.Equal(t, "direct", get(t))
+	})
+
+	t.Run("case=sends requests through the proxy", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyHTTPClientProxyURL, proxy.URL)
+		conf.MustSet(config.ViperKeyHTTPClientProxyUsername, "proxy-user")
+		conf.MustSet(config.ViperKeyHTTPClientProxyPassword, "proxy-secret")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyHTTPClientProxyURL, "")
+		})
+
+		assert.Equal(t, "proxied", get(t))
+		assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("proxy-user:proxy-secret")), proxyAuth)
+
+		conf.MustSet(config.ViperKeyHTTPClientProxyNoProxy, []string{urlx.ParseOrPanic(target.URL).Hostname()})
+		assert.Equal(t, "direct", get(t))
+	})
+
+	t.Run("case=proxy credentials can be part of the URL", func(t *testing.T) {
+		u := urlx.ParseOrPanic(proxy.URL)
+		u.User = url.UserPassword("url-user", "url-secret")
+		conf.MustSet(config.ViperKeyHTTPClientProxyURL, u.String())
+		conf.MustSet(config.ViperKeyHTTPClientProxyUsername, "")
+		conf.MustSet(config.ViperKeyHTTPClientProxyNoProxy, []string{})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyHTTPClientProxyURL, "")
+		})
+
+		assert.Equal(t, "proxied", get(t))
+		assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("url-user:url-secret")), proxyAuth)
+	})
+}
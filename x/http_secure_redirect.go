@@ -13,6 +13,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/text"
 )
 
 type secureRedirectOptions struct {
@@ -84,24 +85,29 @@ func SecureRedirectTo(r *http.Request, defaultReturnTo *url.URL, opts ...SecureR
 	returnTo.Host = stringsx.Coalesce(returnTo.Host, o.defaultReturnTo.Host)
 	returnTo.Scheme = stringsx.Coalesce(returnTo.Scheme, o.defaultReturnTo.Scheme)
 
-	var found bool
-	for _, allowed := range o.whitelist {
+	if !IsWhitelistedRedirectURL(returnTo, o.whitelist) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.
+			WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeReturnToNotWhitelisted).
+			WithReasonf("Requested return_to URL \"%s\" is not whitelisted.", returnTo).
+			WithDebugf("Whitelisted domains are: %v", o.whitelist))
+	}
+
+	return returnTo, nil
+}
+
+// IsWhitelistedRedirectURL returns true if the given URL matches the scheme, host, and path prefix of one of
+// the whitelisted URLs.
+func IsWhitelistedRedirectURL(returnTo *url.URL, whitelist []url.URL) bool {
+	for _, allowed := range whitelist {
 		if strings.EqualFold(allowed.Scheme, returnTo.Scheme) &&
 			strings.EqualFold(allowed.Host, returnTo.Host) &&
 			strings.HasPrefix(
 				stringsx.Coalesce(returnTo.Path, "/"),
 				stringsx.Coalesce(allowed.Path, "/")) {
-			found = true
+			return true
 		}
 	}
-
-	if !found {
-		return nil, errors.WithStack(herodot.ErrBadRequest.
-			WithReasonf("Requested return_to URL \"%s\" is not whitelisted.", returnTo).
-			WithDebugf("Whitelisted domains are: %v", o.whitelist))
-	}
-
-	return returnTo, nil
+	return false
 }
 
 func SecureContentNegotiationRedirection(
@@ -0,0 +1,174 @@
+package x
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	ipFilterDependencies interface {
+		config.Providers
+		LoggingProvider
+		WriterProvider
+	}
+	IPFilterProvider interface {
+		AdminIPFilter() *IPFilter
+	}
+
+	// IPFilter denies requests from clients whose IP address is not allowed by `serve.admin.ip_filter`.
+	IPFilter struct {
+		d ipFilterDependencies
+	}
+)
+
+var ipFilterUnprotectedPaths = []string{
+	healthx.AliveCheckPath,
+	healthx.ReadyCheckPath,
+	healthx.VersionPath,
+}
+
+func NewIPFilter(d ipFilterDependencies) *IPFilter {
+	return &IPFilter{d: d}
+}
+
+// ParseIPNets parses a list of CIDR ranges and IP addresses.
+func ParseIPNets(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, errors.Errorf("unable to parse IP address: %s", v)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the IP filter or the trusted proxies of the public API contain invalid CIDR ranges
+// or IP addresses.
+func (f *IPFilter) Validate(ctx context.Context) error {
+	conf := f.d.Configuration(ctx).AdminIPFilter()
+	public := f.d.Configuration(ctx).PublicClientIP()
+	for _, values := range [][]string{conf.Allow, conf.Deny, conf.TrustedProxies, public.TrustedProxies} {
+		if _, err := ParseIPNets(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clientIP returns the IP address of the client. The client IP header is only honored if the request was
+// sent by a trusted proxy. Addresses in the header are evaluated from right to left, skipping trusted proxies.
+func clientIP(r *http.Request, header string, trusted []*net.IPNet) net.IP {
+	ip := net.ParseIP(remoteIP(r))
+	if ip == nil || !containsIP(trusted, ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(r.Header.Get(header), ",")
+	for k := len(forwarded) - 1; k >= 0; k-- {
+		candidate := strings.TrimSpace(forwarded[k])
+		if len(candidate) == 0 {
+			continue
+		}
+
+		ip = net.ParseIP(candidate)
+		if ip == nil || !containsIP(trusted, ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+// TrustedClientIP returns the IP address of the client, honoring the client IP header of requests sent by one of
+// the trusted proxies. Use `PublicClientIP()` for requests to the public API and the `AdminIPFilter()` for
+// requests to the admin API. It falls back to the address of the connection if the header contains no valid address.
+func TrustedClientIP(r *http.Request, conf *config.ClientIPConfig) string {
+	trusted, err := ParseIPNets(conf.TrustedProxies)
+	if err != nil {
+		return remoteIP(r)
+	}
+
+	if ip := clientIP(r, conf.ClientIPHeader, trusted); ip != nil {
+		return ip.String()
+	}
+	return remoteIP(r)
+}
+
+func (f *IPFilter) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	conf := f.d.Configuration(r.Context()).AdminIPFilter()
+	if len(conf.Allow) == 0 && len(conf.Deny) == 0 {
+		next(w, r)
+		return
+	}
+
+	for _, p := range ipFilterUnprotectedPaths {
+		if r.URL.Path == p {
+			next(w, r)
+			return
+		}
+	}
+
+	allow, err := ParseIPNets(conf.Allow)
+	if err != nil {
+		f.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	deny, err := ParseIPNets(conf.Deny)
+	if err != nil {
+		f.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	trusted, err := ParseIPNets(conf.TrustedProxies)
+	if err != nil {
+		f.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	ip := clientIP(r, conf.ClientIPHeader, trusted)
+	if ip == nil || containsIP(deny, ip) || (len(allow) > 0 && !containsIP(allow, ip)) {
+		f.d.Logger().
+			WithRequest(r).
+			WithField("client_ip", ip.String()).
+			Warn("Denied admin API request from disallowed IP address.")
+		f.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("Access to the admin API is not allowed from this IP address.")))
+		return
+	}
+
+	next(w, r)
+}
@@ -0,0 +1,86 @@
+package x_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/healthx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+)
+
+func TestIPFilter(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	do := func(t *testing.T, path, remoteAddr, forwardedFor string) int {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		w := httptest.NewRecorder()
+		reg.AdminIPFilter().ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w.Code
+	}
+
+	t.Run("case=passes through if not configured", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, "/identities", "203.0.113.1:1234", ""))
+	})
+
+	conf.MustSet(config.ViperKeyAdminIPFilterAllow, []string{"10.0.0.0/8", "192.168.1.15"})
+	conf.MustSet(config.ViperKeyAdminIPFilterDeny, []string{"10.0.13.0/24"})
+	conf.MustSet(config.ViperKeyAdminIPFilterTrustedProxies, []string{"172.16.0.1"})
+	require.NoError(t, reg.AdminIPFilter().Validate(context.Background()))
+
+	for _, tc := range []struct {
+		d            string
+		path         string
+		remoteAddr   string
+		forwardedFor string
+		expected     int
+	}{
+		{d: "allowed range", remoteAddr: "10.1.2.3:1234", expected: http.StatusNoContent},
+		{d: "allowed address", remoteAddr: "192.168.1.15:1234", expected: http.StatusNoContent},
+		{d: "address not allowed", remoteAddr: "192.168.1.16:1234", expected: http.StatusForbidden},
+		{d: "denied range takes precedence", remoteAddr: "10.0.13.7:1234", expected: http.StatusForbidden},
+		{d: "health checks are not filtered", path: healthx.AliveCheckPath, remoteAddr: "203.0.113.1:1234", expected: http.StatusNoContent},
+		{d: "header from untrusted source is ignored", remoteAddr: "203.0.113.1:1234", forwardedFor: "10.1.2.3", expected: http.StatusForbidden},
+		{d: "header from trusted proxy is honored", remoteAddr: "172.16.0.1:1234", forwardedFor: "10.1.2.3", expected: http.StatusNoContent},
+		{d: "spoofed header entries are ignored", remoteAddr: "172.16.0.1:1234", forwardedFor: "10.1.2.3, 203.0.113.1", expected: http.StatusForbidden},
+		{d: "trusted proxies in the header are skipped", remoteAddr: "172.16.0.1:1234", forwardedFor: "10.1.2.3, 172.16.0.1", expected: http.StatusNoContent},
+		{d: "invalid address in header is denied", remoteAddr: "172.16.0.1:1234", forwardedFor: "not-an-ip", expected: http.StatusForbidden},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			path := tc.path
+			if path == "" {
+				path = "/identities"
+			}
+			assert.Equal(t, tc.expected, do(t, path, tc.remoteAddr, tc.forwardedFor))
+		})
+	}
+
+	t.Run("case=invalid configuration is rejected", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyAdminIPFilterDeny, []string{"10.0.13.0/33"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyAdminIPFilterDeny, []string{})
+		})
+		require.Error(t, reg.AdminIPFilter().Validate(context.Background()))
+	})
+
+	t.Run("case=invalid public trusted proxies are rejected", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyPublicTrustedProxies, []string{"not-an-ip"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyPublicTrustedProxies, []string{})
+		})
+		require.Error(t, reg.AdminIPFilter().Validate(context.Background()))
+	})
+}
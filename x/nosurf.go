@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
+	"github.com/ory/kratos/text"
 	"github.com/ory/nosurf"
 	"github.com/ory/x/logrusx"
 	"github.com/ory/x/randx"
@@ -14,7 +15,7 @@ import (
 )
 
 var (
-	ErrInvalidCSRFToken = herodot.ErrForbidden.WithReasonf("A request failed due to a missing or invalid csrf_token value.")
+	ErrInvalidCSRFToken = herodot.ErrForbidden.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityCSRFViolation).WithReasonf("A request failed due to a missing or invalid csrf_token value.")
 	ErrGone             = herodot.DefaultError{
 		CodeField:    http.StatusGone,
 		StatusField:  http.StatusText(http.StatusGone),
@@ -111,7 +112,7 @@ func NewCSRFHandler(
 			WithField("received_token_form", r.PostForm.Get("csrf_token")).
 			Warn("A request failed due to a missing or invalid csrf_token value")
 
-		writer.WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("CSRF token is missing or invalid.")))
+		writer.WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityCSRFViolation).WithReasonf("CSRF token is missing or invalid.")))
 	}))
 	return n
 }
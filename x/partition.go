@@ -0,0 +1,103 @@
+package x
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// DefaultPartitionID is the partition of requests which do not belong to any other partition. It contains all
+// identities created before partitions were enabled.
+const DefaultPartitionID = ""
+
+type (
+	partitionerDependencies interface {
+		config.Providers
+		WriterProvider
+	}
+	PartitionerProvider interface {
+		PublicPartitioner() *Partitioner
+		AdminPartitioner() *Partitioner
+	}
+
+	// Partitioner stores the identity partition of the request, as configured in `identity.partitions`, in the
+	// request context.
+	Partitioner struct {
+		d     partitionerDependencies
+		admin bool
+	}
+
+	partitionContextKey struct{}
+)
+
+var partitionIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// NewPublicPartitioner resolves the partition from the host the request was sent to.
+func NewPublicPartitioner(d partitionerDependencies) *Partitioner {
+	return &Partitioner{d: d}
+}
+
+// NewAdminPartitioner resolves the partition from the request header configured in
+// `identity.partitions.admin_header`.
+func NewAdminPartitioner(d partitionerDependencies) *Partitioner {
+	return &Partitioner{d: d, admin: true}
+}
+
+// WithPartitionID returns a copy of the context which belongs to the given partition.
+func WithPartitionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, partitionContextKey{}, id)
+}
+
+// PartitionID returns the partition of the context or DefaultPartitionID if none was set.
+func PartitionID(ctx context.Context) string {
+	if id, ok := ctx.Value(partitionContextKey{}).(string); ok {
+		return id
+	}
+	return DefaultPartitionID
+}
+
+func (p *Partitioner) partitionID(r *http.Request) (string, error) {
+	conf := p.d.Configuration(r.Context()).IdentityPartitions()
+	if !conf.Enabled {
+		return DefaultPartitionID, nil
+	}
+
+	if p.admin {
+		id := r.Header.Get(conf.AdminHeader)
+		if len(id) > 0 && !partitionIDPattern.MatchString(id) {
+			return "", errors.WithStack(herodot.ErrBadRequest.WithReasonf("The value of header %s is not a valid partition ID.", conf.AdminHeader))
+		}
+		return id, nil
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, h := range conf.PublicHosts {
+		if strings.EqualFold(h.Host, host) {
+			return h.PartitionID, nil
+		}
+	}
+
+	return DefaultPartitionID, nil
+}
+
+func (p *Partitioner) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id, err := p.partitionID(r)
+	if err != nil {
+		p.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	next(w, r.WithContext(WithPartitionID(r.Context(), id)))
+}
@@ -0,0 +1,74 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestPartitioner(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	do := func(t *testing.T, p *x.Partitioner, host, header string) (int, string) {
+		r := httptest.NewRequest("GET", "http://"+host+"/identities", nil)
+		if header != "" {
+			r.Header.Set("X-Kratos-Partition", header)
+		}
+
+		var partition string
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			partition = x.PartitionID(r.Context())
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w.Code, partition
+	}
+
+	conf.MustSet(config.ViperKeyIdentityPartitionsPublicHosts, []map[string]interface{}{
+		{"host": "b2b.example.org", "partition_id": "acme"},
+	})
+
+	t.Run("case=uses the default partition if disabled", func(t *testing.T) {
+		code, partition := do(t, reg.PublicPartitioner(), "b2b.example.org", "")
+		assert.Equal(t, http.StatusNoContent, code)
+		assert.Equal(t, x.DefaultPartitionID, partition)
+
+		code, partition = do(t, reg.AdminPartitioner(), "localhost", "acme")
+		assert.Equal(t, http.StatusNoContent, code)
+		assert.Equal(t, x.DefaultPartitionID, partition)
+	})
+
+	conf.MustSet(config.ViperKeyIdentityPartitionsEnabled, true)
+
+	t.Run("case=resolves the public partition from the host", func(t *testing.T) {
+		for host, expected := range map[string]string{
+			"b2b.example.org":      "acme",
+			"B2B.example.org:4433": "acme",
+			"www.example.org":      x.DefaultPartitionID,
+		} {
+			code, partition := do(t, reg.PublicPartitioner(), host, "other")
+			assert.Equal(t, http.StatusNoContent, code)
+			assert.Equal(t, expected, partition, "%s", host)
+		}
+	})
+
+	t.Run("case=resolves the admin partition from the header", func(t *testing.T) {
+		for header, expected := range map[string]string{
+			"acme": "acme",
+			"":     x.DefaultPartitionID,
+		} {
+			code, partition := do(t, reg.AdminPartitioner(), "b2b.example.org", header)
+			assert.Equal(t, http.StatusNoContent, code)
+			assert.Equal(t, expected, partition, "%s", header)
+		}
+
+		code, _ := do(t, reg.AdminPartitioner(), "localhost", "not a partition")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
@@ -0,0 +1,75 @@
+package x
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/text"
+)
+
+var ErrTooManyRequests = herodot.DefaultError{
+	CodeField:    http.StatusTooManyRequests,
+	StatusField:  http.StatusText(http.StatusTooManyRequests),
+	ErrorField:   "The rate limit was exceeded, please try again later.",
+	DetailsField: map[string]interface{}{text.ErrorCodeDetailKey: text.ErrorCodeRateLimitExceeded},
+}
+
+// RateLimiter is an in-memory fixed window rate limiter. Requests are counted per key, for example
+// the client's IP address. Limits are not shared between multiple instances. Expired windows are removed
+// once per window so that keys which are not used again do not accumulate.
+type RateLimiter struct {
+	sync.Mutex
+	windows   map[string]*rateLimitWindow
+	lastSweep time.Time
+}
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{windows: map[string]*rateLimitWindow{}, lastSweep: time.Now()}
+}
+
+// Allow returns true if the key has been used less than max times in the current window.
+func (l *RateLimiter) Allow(key string, max int, window time.Duration) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= window {
+		w = &rateLimitWindow{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= max {
+		return false
+	}
+
+	w.count++
+	return true
+}
+
+// remoteIP returns the IP address of the connection which sent the request, without the port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
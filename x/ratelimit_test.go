@@ -0,0 +1,38 @@
+package x
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("case=rejects keys above the limit", func(t *testing.T) {
+		l := NewRateLimiter()
+		assert.True(t, l.Allow("a", 2, time.Hour))
+		assert.True(t, l.Allow("a", 2, time.Hour))
+		assert.False(t, l.Allow("a", 2, time.Hour))
+		assert.True(t, l.Allow("b", 2, time.Hour))
+	})
+
+	t.Run("case=resets the count after the window", func(t *testing.T) {
+		l := NewRateLimiter()
+		assert.True(t, l.Allow("a", 1, 10*time.Millisecond))
+		assert.False(t, l.Allow("a", 1, 10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+		assert.True(t, l.Allow("a", 1, 10*time.Millisecond))
+	})
+
+	t.Run("case=evicts expired windows on rollover", func(t *testing.T) {
+		l := NewRateLimiter()
+		assert.True(t, l.Allow("a", 1, 10*time.Millisecond))
+		assert.True(t, l.Allow("b", 1, 10*time.Millisecond))
+		assert.Len(t, l.windows, 2)
+
+		time.Sleep(20 * time.Millisecond)
+		assert.True(t, l.Allow("c", 1, 10*time.Millisecond))
+		assert.Len(t, l.windows, 1)
+		assert.Contains(t, l.windows, "c")
+	})
+}
@@ -0,0 +1,56 @@
+package x
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	securityHeadersDependencies interface {
+		config.Providers
+	}
+	SecurityHeadersProvider interface {
+		SecurityHeaders() *SecurityHeaders
+	}
+
+	// SecurityHeaders adds the security headers configured in `serve.security_headers` to all responses.
+	SecurityHeaders struct {
+		d securityHeadersDependencies
+	}
+)
+
+func NewSecurityHeaders(d securityHeadersDependencies) *SecurityHeaders {
+	return &SecurityHeaders{d: d}
+}
+
+func (s *SecurityHeaders) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	conf := s.d.Configuration(r.Context()).SecurityHeaders()
+	h := w.Header()
+
+	if conf.HSTSEnabled {
+		value := fmt.Sprintf("max-age=%d", int64(conf.HSTSMaxAge.Seconds()))
+		if conf.HSTSIncludeSubdomains {
+			value += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			value += "; preload"
+		}
+		h.Set("Strict-Transport-Security", value)
+	}
+
+	if conf.ContentTypeOptions {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+
+	if conf.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", conf.ReferrerPolicy)
+	}
+
+	if conf.ContentSecurityPolicy != "" {
+		h.Set("Content-Security-Policy", conf.ContentSecurityPolicy)
+	}
+
+	next(w, r)
+}
@@ -0,0 +1,63 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	do := func(t *testing.T) http.Header {
+		w := httptest.NewRecorder()
+		reg.SecurityHeaders().ServeHTTP(w, httptest.NewRequest("GET", "/sessions/whoami", nil), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return w.Header()
+	}
+
+	t.Run("case=does not send hsts in development mode", func(t *testing.T) {
+		h := do(t)
+		assert.Empty(t, h.Get("Strict-Transport-Security"))
+		assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+		assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+		assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", h.Get("Content-Security-Policy"))
+	})
+
+	conf.MustSet("dev", false)
+
+	t.Run("case=sends hsts with defaults", func(t *testing.T) {
+		assert.Equal(t, "max-age=31536000", do(t).Get("Strict-Transport-Security"))
+	})
+
+	t.Run("case=sends configured headers", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySecurityHeadersHSTSMaxAge, "17520h")
+		conf.MustSet(config.ViperKeySecurityHeadersHSTSIncludeSubdomains, true)
+		conf.MustSet(config.ViperKeySecurityHeadersHSTSPreload, true)
+		conf.MustSet(config.ViperKeySecurityHeadersReferrerPolicy, "strict-origin-when-cross-origin")
+		conf.MustSet(config.ViperKeySecurityHeadersContentSecurityPolicy, "default-src 'self'")
+
+		h := do(t)
+		assert.Equal(t, "max-age=63072000; includeSubDomains; preload", h.Get("Strict-Transport-Security"))
+		assert.Equal(t, "strict-origin-when-cross-origin", h.Get("Referrer-Policy"))
+		assert.Equal(t, "default-src 'self'", h.Get("Content-Security-Policy"))
+	})
+
+	t.Run("case=headers can be disabled individually", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySecurityHeadersHSTSEnabled, false)
+		conf.MustSet(config.ViperKeySecurityHeadersContentTypeOptions, false)
+		conf.MustSet(config.ViperKeySecurityHeadersReferrerPolicy, "")
+		conf.MustSet(config.ViperKeySecurityHeadersContentSecurityPolicy, "")
+
+		h := do(t)
+		for _, name := range []string{"Strict-Transport-Security", "X-Content-Type-Options", "Referrer-Policy", "Content-Security-Policy"} {
+			assert.Empty(t, h.Get(name), name)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package x
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/metrics/prometheus"
+)
+
+type (
+	strategyRateLimiterDependencies interface {
+		config.Providers
+		PrometheusManager() *prometheus.MetricsManager
+	}
+	StrategyRateLimiterProvider interface {
+		StrategyRateLimiter() *StrategyRateLimiter
+	}
+
+	// StrategyRateLimiter limits how often a client submits flows of a strategy as configured in
+	// `selfservice.methods.<strategy>.rate_limit`. Requests are counted per flow, strategy and client IP
+	// address, see TrustedClientIP, so that strategies handling several flows, such as `link` for recovery
+	// and verification, enforce the limit for each flow separately.
+	StrategyRateLimiter struct {
+		sync.Mutex
+		d        strategyRateLimiterDependencies
+		limiters map[string]*RateLimiter
+	}
+)
+
+func NewStrategyRateLimiter(d strategyRateLimiterDependencies) *StrategyRateLimiter {
+	return &StrategyRateLimiter{d: d, limiters: map[string]*RateLimiter{}}
+}
+
+func (l *StrategyRateLimiter) limiter(flow, strategy string) *RateLimiter {
+	l.Lock()
+	defer l.Unlock()
+
+	key := flow + "." + strategy
+	if _, ok := l.limiters[key]; !ok {
+		l.limiters[key] = NewRateLimiter()
+	}
+	return l.limiters[key]
+}
+
+// Allow returns ErrTooManyRequests if the client exceeded the rate limit of the strategy in the flow, for
+// example `login` or `recovery`. It always returns nil if no rate limit is configured for the strategy.
+func (l *StrategyRateLimiter) Allow(r *http.Request, flow, strategy string) error {
+	conf := l.d.Configuration(r.Context())
+	maxRequests, window := conf.SelfServiceStrategyRateLimit(strategy)
+	if maxRequests <= 0 {
+		return nil
+	}
+
+	allowed := l.limiter(flow, strategy).Allow(TrustedClientIP(r, conf.PublicClientIP()), maxRequests, window)
+	l.d.PrometheusManager().StrategyRateLimitChecked(strategy, allowed)
+	if !allowed {
+		return errors.WithStack(ErrTooManyRequests)
+	}
+	return nil
+}
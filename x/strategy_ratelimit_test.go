@@ -0,0 +1,91 @@
+package x_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestStrategyRateLimiter(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet("selfservice.methods.password.rate_limit.max_requests", 2)
+	conf.MustSet("selfservice.methods.password.rate_limit.window", "1h")
+
+	allowFlow := func(flow, strategy, ip, forwardedFor string) error {
+		r := httptest.NewRequest("POST", "/self-service/login", nil)
+		r.RemoteAddr = ip + ":1234"
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		return reg.StrategyRateLimiter().Allow(r, flow, strategy)
+	}
+	allowForwarded := func(strategy, ip, forwardedFor string) error {
+		return allowFlow("login", strategy, ip, forwardedFor)
+	}
+	allow := func(strategy, ip string) error {
+		return allowForwarded(strategy, ip, "")
+	}
+
+	t.Run("case=rejects requests above the limit", func(t *testing.T) {
+		require.NoError(t, allow("password", "10.0.0.1"))
+		require.NoError(t, allow("password", "10.0.0.1"))
+		err := allow("password", "10.0.0.1")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, x.ErrTooManyRequests), "%+v", err)
+	})
+
+	t.Run("case=counts clients separately", func(t *testing.T) {
+		assert.NoError(t, allow("password", "10.0.0.2"))
+	})
+
+	t.Run("case=does not limit strategies without a limit", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, allow("oidc", "10.0.0.1"))
+		}
+	})
+
+	t.Run("case=counts flows of the same strategy separately", func(t *testing.T) {
+		conf.MustSet("selfservice.methods.link.rate_limit.max_requests", 1)
+		conf.MustSet("selfservice.methods.link.rate_limit.window", "1h")
+
+		require.NoError(t, allowFlow("recovery", "link", "10.0.0.4", ""))
+		assert.Error(t, allowFlow("recovery", "link", "10.0.0.4", ""))
+		assert.NoError(t, allowFlow("verification", "link", "10.0.0.4", ""))
+	})
+
+	t.Run("case=ignores the forwarded header of untrusted clients", func(t *testing.T) {
+		require.NoError(t, allowForwarded("password", "10.0.0.3", "192.0.2.1"))
+		require.NoError(t, allowForwarded("password", "10.0.0.3", "192.0.2.2"))
+		assert.Error(t, allowForwarded("password", "10.0.0.3", "192.0.2.3"))
+	})
+
+	t.Run("case=ignores the proxies trusted by the admin API", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyAdminIPFilterTrustedProxies, []string{"172.16.0.2"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyAdminIPFilterTrustedProxies, []string{})
+		})
+
+		require.NoError(t, allowForwarded("password", "172.16.0.2", "192.0.2.20"))
+		require.NoError(t, allowForwarded("password", "172.16.0.2", "192.0.2.21"))
+		assert.Error(t, allowForwarded("password", "172.16.0.2", "192.0.2.22"))
+	})
+
+	t.Run("case=counts clients behind a trusted proxy separately", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyPublicTrustedProxies, []string{"172.16.0.1"})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyPublicTrustedProxies, []string{})
+		})
+
+		require.NoError(t, allowForwarded("password", "172.16.0.1", "192.0.2.10"))
+		require.NoError(t, allowForwarded("password", "172.16.0.1", "192.0.2.10"))
+		assert.Error(t, allowForwarded("password", "172.16.0.1", "192.0.2.10"))
+		assert.NoError(t, allowForwarded("password", "172.16.0.1", "192.0.2.11"))
+	})
+}
@@ -0,0 +1,131 @@
+package x
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/text"
+)
+
+var ErrUntrustedOrigin = herodot.ErrForbidden.
+	WithDetail(text.ErrorCodeDetailKey, text.ErrorCodeSecurityCSRFViolation).
+	WithReason("The request was sent from an origin which is not trusted.")
+
+type (
+	trustedClientsDependencies interface {
+		config.Providers
+		LoggingProvider
+		WriterProvider
+	}
+
+	// TrustedClientsCSRFHandler wraps a CSRFHandler and additionally rejects state-changing browser requests
+	// whose origin is neither the public base URL nor one of the clients in `serve.public.trusted_clients` or
+	// the origins in `selfservice.csrf.trusted_origins`.
+	TrustedClientsCSRFHandler struct {
+		CSRFHandler
+		d      trustedClientsDependencies
+		exempt map[string]struct{}
+	}
+)
+
+var _ CSRFHandler = new(TrustedClientsCSRFHandler)
+
+func NewTrustedClientsCSRFHandler(h CSRFHandler, d trustedClientsDependencies) *TrustedClientsCSRFHandler {
+	return &TrustedClientsCSRFHandler{CSRFHandler: h, d: d, exempt: map[string]struct{}{}}
+}
+
+// ExemptPath exempts the path from anti-CSRF token and origin checks.
+func (h *TrustedClientsCSRFHandler) ExemptPath(path string) {
+	h.exempt[path] = struct{}{}
+	h.CSRFHandler.ExemptPath(path)
+}
+
+func (h *TrustedClientsCSRFHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		h.CSRFHandler.ServeHTTP(w, r)
+		return
+	}
+
+	origin := requestOrigin(r)
+	if _, ok := h.exempt[r.URL.Path]; ok || origin == "" || isBearerAuthenticated(r) {
+		h.CSRFHandler.ServeHTTP(w, r)
+		return
+	}
+
+	tc, err := h.d.Configuration(r.Context()).TrustedClients()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	trusted := h.d.Configuration(r.Context()).SelfServiceCSRFTrustedOrigins()
+
+	// Without trusted clients or origins, the anti-CSRF token is the only protection as before.
+	if len(tc.Origins) == 1 && len(trusted) == 0 {
+		h.CSRFHandler.ServeHTTP(w, r)
+		return
+	}
+
+	for _, o := range append(tc.Origins, trusted...) {
+		if matchOrigin(o, origin) {
+			h.CSRFHandler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	h.d.Logger().
+		WithRequest(r).
+		WithField("origin", origin).
+		Warn("Denied state-changing request from an origin which is not trusted.")
+	h.d.Writer().WriteError(w, r, errors.WithStack(ErrUntrustedOrigin))
+}
+
+// requestOrigin returns the Origin header or, if it is not set, the origin of the Referer header. Browsers send
+// the opaque origin `null` for example for requests from sandboxed frames, which is never trusted.
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return strings.ToLower(origin)
+	}
+
+	referer := r.Header.Get("Referer")
+	if referer == "" {
+		return ""
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "null"
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// isBearerAuthenticated returns true for API requests which authenticate using a header. Browsers do not send
+// such headers across origins without a CORS preflight, so these requests can not be forged.
+func isBearerAuthenticated(r *http.Request) bool {
+	if r.Header.Get("X-Session-Token") != "" {
+		return true
+	}
+
+	auth := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	return len(auth) == 2 && strings.EqualFold(auth[0], "bearer") && auth[1] != ""
+}
+
+// matchOrigin returns true if the origin matches the trusted origin, which may contain one wildcard such as
+// `https://*.example.com`.
+func matchOrigin(trusted, origin string) bool {
+	trusted = strings.ToLower(trusted)
+	i := strings.IndexByte(trusted, '*')
+	if i < 0 {
+		return trusted == origin
+	}
+
+	prefix, suffix := trusted[:i], trusted[i+1:]
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
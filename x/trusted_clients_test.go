@@ -0,0 +1,129 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestTrustedClientsCSRFHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://auth.example.com")
+
+	h := x.NewTrustedClientsCSRFHandler(x.NewFakeCSRFHandler(""), reg)
+	h.ExemptPath("/exempt")
+
+	do := func(t *testing.T, method, path, origin string) int {
+		r := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("case=passes through if not configured", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(t, "POST", "/", "https://evil.com"))
+	})
+
+	conf.MustSet(config.ViperKeyPublicTrustedClients, []string{"https://app.example.com"})
+
+	for _, tc := range []struct {
+		d        string
+		method   string
+		path     string
+		origin   string
+		expected int
+	}{
+		{d: "trusted client", method: "POST", origin: "https://app.example.com", expected: http.StatusOK},
+		{d: "public base url", method: "POST", origin: "https://auth.example.com", expected: http.StatusOK},
+		{d: "origin is case insensitive", method: "POST", origin: "https://APP.example.com", expected: http.StatusOK},
+		{d: "untrusted origin", method: "POST", origin: "https://evil.com", expected: http.StatusForbidden},
+		{d: "port must match", method: "POST", origin: "https://app.example.com:8443", expected: http.StatusForbidden},
+		{d: "scheme must match", method: "POST", origin: "http://app.example.com", expected: http.StatusForbidden},
+		{d: "safe methods are not checked", method: "GET", origin: "https://evil.com", expected: http.StatusOK},
+		{d: "requests without origin are not checked", method: "POST", expected: http.StatusOK},
+		{d: "exempt paths are not checked", method: "POST", path: "/exempt", origin: "https://evil.com", expected: http.StatusOK},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			path := tc.path
+			if path == "" {
+				path = "/"
+			}
+			assert.Equal(t, tc.expected, do(t, tc.method, path, tc.origin))
+		})
+	}
+}
+
+func TestTrustedClientsCSRFHandler_TrustedOrigins(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://auth.example.com")
+	conf.MustSet(config.ViperKeySelfServiceLoginUI, "https://login.example.com/login")
+
+	h := x.NewTrustedClientsCSRFHandler(x.NewFakeCSRFHandler(""), reg)
+
+	do := func(t *testing.T, headers map[string]string) int {
+		r := httptest.NewRequest("POST", "/", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("case=passes through if not configured", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(t, map[string]string{"Origin": "https://evil.com"}))
+	})
+
+	t.Run("case=shares the CORS allowed origins if enabled", func(t *testing.T) {
+		conf.MustSet("serve.public.cors.enabled", true)
+		conf.MustSet("serve.public.cors.allowed_origins", []string{"https://*.cors.example.com"})
+		t.Cleanup(func() {
+			conf.MustSet("serve.public.cors.enabled", false)
+			conf.MustSet(config.ViperKeySelfServiceCSRFTrustedOriginsIncludeCORS, false)
+		})
+
+		assert.Equal(t, http.StatusOK, do(t, map[string]string{"Origin": "https://evil.com"}), "CORS origins are not shared by default")
+
+		conf.MustSet(config.ViperKeySelfServiceCSRFTrustedOriginsIncludeCORS, true)
+		assert.Equal(t, http.StatusOK, do(t, map[string]string{"Origin": "https://app.cors.example.com"}))
+		assert.Equal(t, http.StatusForbidden, do(t, map[string]string{"Origin": "https://evil.com"}))
+	})
+
+	conf.MustSet(config.ViperKeySelfServiceCSRFTrustedOrigins, []string{"https://app.example.com", "https://*.apps.example.com"})
+
+	for _, tc := range []struct {
+		d        string
+		headers  map[string]string
+		expected int
+	}{
+		{d: "trusted origin", headers: map[string]string{"Origin": "https://app.example.com"}, expected: http.StatusOK},
+		{d: "wildcard origin", headers: map[string]string{"Origin": "https://foo.apps.example.com"}, expected: http.StatusOK},
+		{d: "wildcard does not match parent", headers: map[string]string{"Origin": "https://apps.example.com"}, expected: http.StatusForbidden},
+		{d: "public base url", headers: map[string]string{"Origin": "https://auth.example.com"}, expected: http.StatusOK},
+		{d: "self-service ui", headers: map[string]string{"Origin": "https://login.example.com"}, expected: http.StatusOK},
+		{d: "untrusted origin", headers: map[string]string{"Origin": "https://evil.com"}, expected: http.StatusForbidden},
+		{d: "opaque origin", headers: map[string]string{"Origin": "null"}, expected: http.StatusForbidden},
+		{d: "trusted referer", headers: map[string]string{"Referer": "https://app.example.com/settings?flow=1"}, expected: http.StatusOK},
+		{d: "untrusted referer", headers: map[string]string{"Referer": "https://evil.com/attack"}, expected: http.StatusForbidden},
+		{d: "origin takes precedence over referer", headers: map[string]string{"Origin": "https://evil.com", "Referer": "https://app.example.com/"}, expected: http.StatusForbidden},
+		{d: "requests without origin and referer are not checked", headers: map[string]string{}, expected: http.StatusOK},
+		{d: "session token requests are exempt", headers: map[string]string{"Origin": "https://evil.com", "X-Session-Token": "token"}, expected: http.StatusOK},
+		{d: "bearer requests are exempt", headers: map[string]string{"Origin": "https://evil.com", "Authorization": "Bearer token"}, expected: http.StatusOK},
+		{d: "basic auth requests are not exempt", headers: map[string]string{"Origin": "https://evil.com", "Authorization": "Basic Zm9vOmJhcg=="}, expected: http.StatusForbidden},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			assert.Equal(t, tc.expected, do(t, tc.headers))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package x
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WebhookHeaderID contains a random ID which is unique for every webhook request. Subscribers reject requests
+	// whose ID they have seen before.
+	WebhookHeaderID = "X-Kratos-Webhook-Id"
+	// WebhookHeaderTimestamp contains the time the webhook request was signed in seconds since the Unix epoch.
+	WebhookHeaderTimestamp = "X-Kratos-Webhook-Timestamp"
+	// WebhookHeaderSignature contains one `v1=<signature>` entry per signing secret, separated by commas.
+	WebhookHeaderSignature = "X-Kratos-Webhook-Signature"
+
+	webhookSignatureVersion = "v1"
+)
+
+// SignWebhook adds the ID, timestamp, and signature headers to a webhook request. The body is signed with each
+// secret so that subscribers keep accepting requests while the secrets are rotated. The signature is the hex
+// encoded HMAC-SHA256 of `<id>.<timestamp>.<body>`. No headers are added if there are no secrets.
+func SignWebhook(h http.Header, body []byte, secrets [][]byte, now time.Time) {
+	if len(secrets) == 0 {
+		return
+	}
+
+	id := NewUUID().String()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signatures := make([]string, len(secrets))
+	for k, secret := range secrets {
+		signatures[k] = webhookSignatureVersion + "=" + hex.EncodeToString(webhookMAC(secret, id, timestamp, body))
+	}
+
+	h.Set(WebhookHeaderID, id)
+	h.Set(WebhookHeaderTimestamp, timestamp)
+	h.Set(WebhookHeaderSignature, strings.Join(signatures, ","))
+}
+
+// VerifyWebhook returns an error if none of the request's signatures was created with one of the secrets or if
+// the request was signed more than tolerance before or after now. It does not check whether the ID was seen before.
+func VerifyWebhook(h http.Header, body []byte, secrets [][]byte, tolerance time.Duration, now time.Time) error {
+	timestamp := h.Get(WebhookHeaderTimestamp)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Errorf("the webhook timestamp header is invalid: %s", timestamp)
+	}
+
+	if d := now.Sub(time.Unix(signedAt, 0)); d > tolerance || d < -tolerance {
+		return errors.Errorf("the webhook was signed at %s which is outside the tolerance of %s", time.Unix(signedAt, 0).UTC(), tolerance)
+	}
+
+	id := h.Get(WebhookHeaderID)
+	if len(id) == 0 {
+		return errors.New("the webhook ID header is missing")
+	}
+
+	for _, entry := range strings.Split(h.Get(WebhookHeaderSignature), ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] != webhookSignatureVersion {
+			continue
+		}
+
+		signature, err := hex.DecodeString(parts[1])
+		if err != nil {
+			continue
+		}
+
+		for _, secret := range secrets {
+			if hmac.Equal(signature, webhookMAC(secret, id, timestamp, body)) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("the webhook signature does not match any of the secrets")
+}
+
+func webhookMAC(secret []byte, id, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(id + "." + timestamp + "."))
+	_, _ = mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,54 @@
+package x
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`[{"type":"flow_succeeded"}]`)
+	current, previous := []byte("current-secret-0123456789"), []byte("previous-secret-0123456789")
+
+	sign := func(secrets ...[]byte) http.Header {
+		h := http.Header{}
+		SignWebhook(h, body, secrets, now)
+		return h
+	}
+
+	t.Run("case=does not sign without secrets", func(t *testing.T) {
+		assert.Empty(t, sign())
+	})
+
+	t.Run("case=signs with every secret", func(t *testing.T) {
+		h := sign(current, previous)
+		assert.NotEmpty(t, h.Get(WebhookHeaderID))
+		assert.Len(t, strings.Split(h.Get(WebhookHeaderSignature), ","), 2)
+
+		for _, secrets := range [][][]byte{{current}, {previous}, {[]byte("unrelated-secret-0123456789"), previous}} {
+			require.NoError(t, VerifyWebhook(h, body, secrets, time.Minute, now))
+		}
+		assert.NotEqual(t, h.Get(WebhookHeaderID), sign(current).Get(WebhookHeaderID), "every request must have a new ID")
+	})
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		h := sign(current)
+		assert.Error(t, VerifyWebhook(h, body, [][]byte{previous}, time.Minute, now), "unknown secret")
+		assert.Error(t, VerifyWebhook(h, []byte(`[]`), [][]byte{current}, time.Minute, now), "modified body")
+		assert.Error(t, VerifyWebhook(h, body, [][]byte{current}, time.Minute, now.Add(2*time.Minute)), "stale request")
+		assert.Error(t, VerifyWebhook(h, body, [][]byte{current}, time.Minute, now.Add(-2*time.Minute)), "request from the future")
+
+		modified := h.Clone()
+		modified.Set(WebhookHeaderID, NewUUID().String())
+		assert.Error(t, VerifyWebhook(modified, body, [][]byte{current}, time.Minute, now), "modified ID")
+
+		modified = h.Clone()
+		modified.Del(WebhookHeaderTimestamp)
+		assert.Error(t, VerifyWebhook(modified, body, [][]byte{current}, time.Minute, now), "missing timestamp")
+	})
+}